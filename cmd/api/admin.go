@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/fraiday-org/api-service/internal/tasks"
+)
+
+// adminTimeout bounds a maintenance command that doesn't take a timeout of its own
+const adminTimeout = 10 * time.Minute
+
+// demoConversation is the exchange each seeded demo session holds, alternating user and assistant
+var demoConversation = []string{
+	"Hi, I'd like to check the status of my order.",
+	"Sure! Could you share your order number?",
+	"It's 10042.",
+	"Thanks. Order 10042 shipped yesterday and should arrive within two days.",
+	"Great, thank you!",
+	"You're welcome. Is there anything else I can help with?",
+}
+
+// parseAdminFlags parses the flags of a maintenance command, printing its usage and exiting on
+// -h or invalid flags.
+func parseAdminFlags(fs *flag.FlagSet, args []string) {
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+}
+
+// newAdminTaskClient connects the task client used by maintenance commands that queue work for
+// the workers.
+func newAdminTaskClient(cfg *config.Config, logger *zap.Logger) *tasks.TaskClient {
+	taskClient, err := tasks.NewTaskClient(cfg.GetRabbitMQURL(), logger, cfg)
+	if err != nil {
+		logger.Fatal("Failed to create task client", zap.Error(err))
+	}
+	return taskClient
+}
+
+// operator names whoever runs a maintenance command in the audit records it leaves.
+func operator() string {
+	host, _ := os.Hostname()
+	return "cli@" + host
+}
+
+// runReplayEvents queues stored events for processing again, so the processors subscribed to them
+// get new deliveries, e.g. after a processor was fixed or added.
+func runReplayEvents(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, args []string) {
+	defer mongoClient.Disconnect(context.Background())
+
+	fs := flag.NewFlagSet("replay-events", flag.ExitOnError)
+	since := fs.String("since", "", "Replay events created from this RFC 3339 time (required)")
+	until := fs.String("until", "", "Replay events created before this RFC 3339 time (default now)")
+	eventType := fs.String("type", "", "Only replay events of this type")
+	limit := fs.Int("limit", 1000, "Maximum number of events to replay")
+	dryRun := fs.Bool("dry-run", false, "Count the events without replaying them")
+	parseAdminFlags(fs, args)
+
+	from, err := time.Parse(time.RFC3339, *since)
+	if err != nil {
+		logger.Fatal("Invalid -since, expected an RFC 3339 time", zap.String("since", *since))
+	}
+	to := time.Now().UTC()
+	if *until != "" {
+		if to, err = time.Parse(time.RFC3339, *until); err != nil {
+			logger.Fatal("Invalid -until, expected an RFC 3339 time", zap.String("until", *until))
+		}
+	}
+
+	taskClient := newAdminTaskClient(cfg, logger)
+	defer taskClient.Close()
+
+	db := mongoClient.Database(cfg.MongoDB)
+	eventPublisherService := service.NewEventPublisherService(
+		service.NewEventService(repository.NewEventRepository(db)),
+		service.NewEventProcessorConfigService(repository.NewEventProcessorConfigRepository(db)),
+		service.NewEventDeliveryTrackingService(repository.NewEventDeliveryRepository(db), repository.NewEventDeliveryAttemptRepository(db)),
+		repository.NewChatSessionRepository(db),
+		repository.NewChatMessageRepository(db),
+		nil, nil, nil, nil,
+		taskClient,
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+	replayed, err := eventPublisherService.ReplayEvents(ctx, from, to, models.EventType(*eventType), *limit, *dryRun)
+	if err != nil {
+		logger.Fatal("Failed to replay events", zap.Int("replayed", replayed), zap.Error(err))
+	}
+	if *dryRun {
+		fmt.Printf("%d events would be replayed\n", replayed)
+		return
+	}
+	fmt.Printf("Replayed %d events\n", replayed)
+}
+
+// runRequeueDLQ requeues the event deliveries that failed after their last retry, for one
+// processor or every active one. Each processor's requeue is a repair action run by the workers,
+// so it is audited like those requested through the API.
+func runRequeueDLQ(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, args []string) {
+	defer mongoClient.Disconnect(context.Background())
+
+	fs := flag.NewFlagSet("requeue-dlq", flag.ExitOnError)
+	processor := fs.String("processor", "", "Only requeue deliveries to this processor ID (default every active processor)")
+	since := fs.Duration("since", 24*time.Hour, "Requeue deliveries that failed within this long")
+	limit := fs.Int("limit", 200, "Maximum number of deliveries to requeue per processor")
+	dryRun := fs.Bool("dry-run", false, "List the deliveries without requeueing them")
+	wait := fs.Duration("wait", time.Minute, "How long to wait for the workers to finish; 0 returns once queued")
+	parseAdminFlags(fs, args)
+
+	taskClient := newAdminTaskClient(cfg, logger)
+	defer taskClient.Close()
+
+	db := mongoClient.Database(cfg.MongoDB)
+	repairService := service.NewRepairService(
+		repository.NewRepairActionRepository(db),
+		repository.NewEventDeliveryRepository(db),
+		repository.NewChatSessionThreadRepository(db),
+		repository.NewChatSessionRepository(db),
+		taskClient,
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+
+	var processorIDs []string
+	if *processor != "" {
+		processorIDs = []string{*processor}
+	} else {
+		active := true
+		configs, err := service.NewEventProcessorConfigService(repository.NewEventProcessorConfigRepository(db)).ListConfigs(ctx, nil, nil, &active, 0, 0)
+		if err != nil {
+			logger.Fatal("Failed to list processors", zap.Error(err))
+		}
+		for _, c := range configs {
+			processorIDs = append(processorIDs, c.ID.Hex())
+		}
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-*since)
+	var actions []*models.RepairAction
+	for _, id := range processorIDs {
+		processorID := service.ParseObjectID(id)
+		if processorID == nil {
+			logger.Fatal("Invalid processor ID", zap.String("processor", id))
+		}
+		action, err := repairService.RequestAction(ctx, models.RepairActionRequeueFailedDeliveries, models.RepairActionParams{
+			ProcessorID: processorID,
+			From:        &from,
+			To:          &to,
+			Limit:       *limit,
+		}, *dryRun, operator())
+		if err != nil {
+			logger.Fatal("Failed to request requeue", zap.String("processor", id), zap.Error(err))
+		}
+		actions = append(actions, action)
+	}
+
+	deadline := time.Now().Add(*wait)
+	for i, action := range actions {
+		for time.Now().Before(deadline) && (action.Status == models.ExecutionStatusPending || action.Status == models.ExecutionStatusRunning) {
+			time.Sleep(time.Second)
+			if latest, err := repairService.GetAction(ctx, action.ID.Hex()); err == nil {
+				action = latest
+			}
+		}
+		fmt.Printf("%s  processor %s  %-9s  matched %d  requeued %d  %s\n",
+			action.ID.Hex(), processorIDs[i], action.Status, action.Matched, action.Affected, action.Error)
+	}
+	if len(actions) == 0 {
+		fmt.Println("No active processors")
+	}
+}
+
+// runCreateAPIKey issues an API key to a client and prints its secret, which can't be shown again.
+func runCreateAPIKey(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, args []string) {
+	defer mongoClient.Disconnect(context.Background())
+
+	fs := flag.NewFlagSet("create-api-key", flag.ExitOnError)
+	client := fs.String("client", "", "Client ID or database ID of the client (required)")
+	name := fs.String("name", "", "Name of the key (required)")
+	scopes := fs.String("scopes", string(models.APIKeyScopeAdmin), "Comma-separated scopes: read:messages, write:messages or admin")
+	tier := fs.String("tier", "", "Rate limit tier (default tier when empty)")
+	expiresIn := fs.Duration("expires-in", 0, "Expire the key after this long; 0 never expires")
+	parseAdminFlags(fs, args)
+
+	var keyScopes []models.APIKeyScope
+	for _, scope := range strings.Split(*scopes, ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			keyScopes = append(keyScopes, models.APIKeyScope(scope))
+		}
+	}
+	var expiresAt *time.Time
+	if *expiresIn > 0 {
+		t := time.Now().UTC().Add(*expiresIn)
+		expiresAt = &t
+	}
+
+	db := mongoClient.Database(cfg.MongoDB)
+	apiKeyService := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), repository.NewClientRepository(db))
+	// Only used to check the tier exists
+	apiKeyService.RateLimits = service.NewRateLimitService(nil, service.ParseRateLimitTiers(cfg.RateLimitTiers), service.RateLimit{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+	key, secret, err := apiKeyService.CreateAPIKey(ctx, *client, *name, keyScopes, *tier, expiresAt)
+	if err != nil {
+		logger.Fatal("Failed to create API key", zap.Error(err))
+	}
+	fmt.Printf("Created API key %s for client %s\n%s\n", key.ID.Hex(), key.ClientID, secret)
+}
+
+// runSeedDemoData creates a demo client with a channel, an admin API key and a few finished
+// conversations, for local development and demos. Messages are stored without publishing events,
+// so no processor or AI workflow runs for them.
+func runSeedDemoData(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, args []string) {
+	defer mongoClient.Disconnect(context.Background())
+
+	fs := flag.NewFlagSet("seed-demo-data", flag.ExitOnError)
+	clientID := fs.String("client", "demo", "Client ID of the demo client")
+	sessions := fs.Int("sessions", 10, "Number of demo conversations")
+	parseAdminFlags(fs, args)
+
+	db := mongoClient.Database(cfg.MongoDB)
+	clientRepo := repository.NewClientRepository(db)
+	chatSessionRepo := repository.NewChatSessionRepository(db)
+	clientService := service.NewClientService(clientRepo)
+	chatMessageService := service.NewChatMessageService(repository.NewChatMessageRepository(db), nil, nil)
+	chatMessageService.ChatSessionRepo = chatSessionRepo
+
+	ctx, cancel := context.WithTimeout(context.Background(), adminTimeout)
+	defer cancel()
+
+	if _, err := clientService.GetClient(ctx, *clientID); err == nil {
+		logger.Fatal("Client already exists, pass -client to seed another", zap.String("client_id", *clientID))
+	}
+	email := *clientID + "@example.com"
+	client, err := clientService.CreateClient(ctx, &dto.ClientCreateOrUpdateRequest{
+		Name:     "Demo",
+		ClientID: clientID,
+		Email:    &email,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create demo client", zap.Error(err))
+	}
+	channel, err := service.NewClientChannelService(repository.NewClientChannelRepository(db), clientRepo).CreateChannel(ctx, client.ClientID, &dto.ClientChannelCreateOrUpdateRequest{
+		ChannelType:   models.ChannelTypeWebhook,
+		ChannelConfig: map[string]interface{}{},
+	})
+	if err != nil {
+		logger.Fatal("Failed to create demo channel", zap.Error(err))
+	}
+	_, secret, err := service.NewAPIKeyService(repository.NewAPIKeyRepository(db), clientRepo).CreateAPIKey(ctx, client.ClientID, "Demo key", []models.APIKeyScope{models.APIKeyScopeAdmin}, "", nil)
+	if err != nil {
+		logger.Fatal("Failed to create demo API key", zap.Error(err))
+	}
+
+	clientObjID, channelObjID := service.ParseObjectID(client.ID), service.ParseObjectID(channel.ID)
+	for i := 0; i < *sessions; i++ {
+		session := &models.ChatSession{
+			SessionID:     fmt.Sprintf("demo-%d-%d", time.Now().Unix(), i+1),
+			Client:        clientObjID,
+			ClientChannel: channelObjID,
+			Participants:  []string{fmt.Sprintf("visitor-%d", i+1)},
+		}
+		if err := chatSessionRepo.Create(ctx, session); err != nil {
+			logger.Fatal("Failed to create demo session", zap.Error(err))
+		}
+		for j, text := range demoConversation {
+			msg := &models.ChatMessage{
+				Sender:     session.Participants[0],
+				SenderType: string(models.SenderTypeUser),
+				SessionID:  session.ID,
+				Text:       text,
+				Category:   models.MessageCategoryMessage,
+			}
+			if j%2 == 1 {
+				msg.Sender, msg.SenderType = "assistant", string(models.SenderTypeAssistant)
+				msg.Config = map[string]interface{}{"ai_response": true}
+				msg.Confidence = 0.6 + float64((i+j)%4)/10
+			}
+			if err := chatMessageService.CreateChatMessage(ctx, msg); err != nil {
+				logger.Fatal("Failed to create demo message", zap.Error(err))
+			}
+		}
+	}
+
+	fmt.Printf("Seeded client %s (channel %s) with %d conversations\nAPI key: %s\n", client.ClientID, channel.ID, *sessions, secret)
+}
@@ -2,40 +2,67 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
-	"os/signal"
+	"strconv"
 	"strings"
-	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 
 	"github.com/fraiday-org/api-service/internal/api"
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/changestream"
 	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/cron"
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/lifecycle"
+	"github.com/fraiday-org/api-service/internal/migrations"
+	"github.com/fraiday-org/api-service/internal/realtime"
+	"github.com/fraiday-org/api-service/internal/redis"
 	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/scheduler"
 	"github.com/fraiday-org/api-service/internal/service"
 	"github.com/fraiday-org/api-service/internal/tasks"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"github.com/fraiday-org/api-service/internal/utils"
 )
 
 func main() {
 	// Parse command line arguments
 	var (
-		mode        = flag.String("mode", "server", "Mode to run: server or worker")
+		mode        = flag.String("mode", "server", "Mode to run: server, worker, changestream, scheduler, or a maintenance command: migrate, replay-events, requeue-dlq, create-api-key or seed-demo-data")
 		queue       = flag.String("queue", "", "Queue name for worker mode")
 		concurrency = flag.Int("concurrency", 1, "Number of concurrent workers")
+		configFile  = flag.String("config", "", "YAML config file; environment variables override it (default $CONFIG_FILE)")
+		validate    = flag.Bool("validate-config", false, "Validate the configuration and exit")
 	)
 	flag.Parse()
 
 	// If no mode specified but args exist, use first arg as mode
-	if len(os.Args) > 1 && *mode == "server" {
-		*mode = os.Args[1]
+	args := flag.Args()
+	if len(args) > 0 && *mode == "server" {
+		*mode = args[0]
+		args = args[1:]
 	}
 
 	// Load config
-	cfg := config.LoadConfig()
+	cfg, err := config.Load(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if *validate {
+		fmt.Println("configuration is valid")
+		return
+	}
 
 	// Set Gin mode
 	gin.SetMode(cfg.GinMode)
@@ -51,41 +78,167 @@ func main() {
 	}
 	defer logger.Sync()
 
+	// Tracing is installed before MongoDB connects so its command monitor reports to it
+	serviceName := "api-service"
+	switch *mode {
+	case "worker":
+		serviceName = "api-service-worker"
+	case "changestream":
+		serviceName = "api-service-changestream"
+	case "scheduler":
+		serviceName = "api-service-scheduler"
+	}
+	shutdownTracing, err := telemetry.Setup(context.Background(), cfg, serviceName)
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+
 	// Connect to MongoDB
-	mongoClient, err := repository.NewMongoClient(cfg.MongoURI)
+	mongoClient, err := repository.NewMongoClient(cfg)
 	if err != nil {
 		logger.Fatal("Failed to connect to MongoDB", zap.Error(err))
 	}
-	defer func() {
-		if err := mongoClient.Disconnect(context.Background()); err != nil {
-			logger.Error("Failed to disconnect from MongoDB", zap.Error(err))
+	repository.SetOperationPolicy(repository.OperationPolicyFromConfig(cfg, logger))
+
+	// Components register start/stop hooks here; MongoDB goes first so it is disconnected last
+	lc := lifecycle.NewManager(logger,
+		cfg.StartupTimeout,
+		cfg.ShutdownTimeout)
+	lc.Append(lifecycle.Hook{
+		Name:   "mongodb",
+		OnStop: mongoClient.Disconnect,
+	})
+	// Spans are flushed once the server or worker has stopped
+	lc.Append(lifecycle.Hook{
+		Name:   "telemetry",
+		OnStop: shutdownTracing,
+	})
+
+	// Migrations run before anything reads the data they change
+	if cfg.MigrateOnStartup && *mode != "migrate" {
+		migrateCtx, cancelMigrate := context.WithTimeout(context.Background(), cfg.MigrationTimeout)
+		err := migrations.NewRunner(mongoClient.Database(cfg.MongoDB), logger, cfg).Run(migrateCtx)
+		cancelMigrate()
+		if err != nil {
+			logger.Fatal("Failed to migrate database", zap.Error(err))
 		}
-	}()
+	}
 
 	// Run based on mode
 	switch *mode {
 	case "server":
-		runServer(cfg, logger, mongoClient)
+		runServer(cfg, logger, mongoClient, lc)
 	case "worker":
-		runWorker(cfg, logger, mongoClient, *queue, *concurrency)
+		runWorker(cfg, logger, mongoClient, lc, *queue, *concurrency)
+	case "changestream":
+		runChangeStream(cfg, logger, mongoClient, lc)
+	case "scheduler":
+		runScheduler(cfg, logger, mongoClient, lc)
+	case "migrate":
+		var command string
+		if len(args) > 0 {
+			command = args[0]
+		}
+		runMigrate(cfg, logger, mongoClient, command)
+	case "replay-events":
+		runReplayEvents(cfg, logger, mongoClient, args)
+	case "requeue-dlq":
+		runRequeueDLQ(cfg, logger, mongoClient, args)
+	case "create-api-key":
+		runCreateAPIKey(cfg, logger, mongoClient, args)
+	case "seed-demo-data":
+		runSeedDemoData(cfg, logger, mongoClient, args)
 	default:
 		logger.Fatal("Invalid mode", zap.String("mode", *mode))
 	}
 }
 
-func runServer(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client) {
+// runMigrate applies pending migrations and creates missing indexes, or with "status" lists the
+// migrations and when each was applied.
+func runMigrate(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, command string) {
+	defer mongoClient.Disconnect(context.Background())
+
+	runner := migrations.NewRunner(mongoClient.Database(cfg.MongoDB), logger, cfg)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.MigrationTimeout)
+	defer cancel()
+
+	switch command {
+	case "", "up":
+		if err := runner.Run(ctx); err != nil {
+			logger.Fatal("Failed to migrate database", zap.Error(err))
+		}
+		logger.Info("Database is up to date")
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			logger.Fatal("Failed to read migration status", zap.Error(err))
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%4d  %-20s  %s\n", status.Version, applied, status.Description)
+		}
+	default:
+		logger.Fatal("Invalid migrate command, use up or status", zap.String("command", command))
+	}
+}
+
+func runServer(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, lc *lifecycle.Manager) {
 	// Set up Gin engine
-	engine := api.SetupRouter(cfg, logger, mongoClient)
+	engine, grpcServer := api.SetupRouter(cfg, logger, mongoClient)
 
-	// Start server
 	addr := fmt.Sprintf(":%s", cfg.AppPort)
-	logger.Info("Starting server", zap.String("addr", addr))
-	if err := engine.Run(addr); err != nil {
+	srv := &http.Server{Addr: addr, Handler: engine}
+	lc.Append(lifecycle.Hook{
+		Name: "http-server",
+		OnStart: func(ctx context.Context) error {
+			// Listen synchronously so a bad port fails startup instead of surfacing later
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return err
+			}
+			logger.Info("Starting server", zap.String("addr", addr))
+			go func() {
+				if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					lc.Fail(fmt.Errorf("http server: %w", err))
+				}
+			}()
+			return nil
+		},
+		OnStop: srv.Shutdown,
+	})
+
+	// The internal gRPC API listens on a port of its own
+	if grpcServer != nil {
+		grpcAddr := fmt.Sprintf(":%s", cfg.GRPCPort)
+		lc.Append(lifecycle.Hook{
+			Name: "grpc-server",
+			OnStart: func(ctx context.Context) error {
+				ln, err := net.Listen("tcp", grpcAddr)
+				if err != nil {
+					return err
+				}
+				logger.Info("Starting gRPC server", zap.String("addr", grpcAddr))
+				go func() {
+					if err := grpcServer.Serve(ln); err != nil {
+						lc.Fail(fmt.Errorf("grpc server: %w", err))
+					}
+				}()
+				return nil
+			},
+			OnStop: grpcServer.Stop,
+		})
+	}
+
+	if err := lc.Run(context.Background()); err != nil {
 		logger.Fatal("Server exited with error", zap.Error(err))
 	}
+	logger.Info("Server stopped")
 }
 
-func runWorker(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, queueName string, concurrency int) {
+func runWorker(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, lc *lifecycle.Manager, queueName string, concurrency int) {
 	if queueName == "" {
 		logger.Fatal("Queue name is required for worker mode")
 	}
@@ -106,11 +259,22 @@ func runWorker(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client
 
 	// Initialize database service
 	databaseService := service.NewDatabaseService(logger, mongoClient, cfg.MongoDB)
+	databaseService.ContextMessages = cfg.SessionContextMaxMessages
+	if cfg.RedisHost != "" && cfg.SessionContextCacheTTL > 0 {
+		// Session contexts are rebuilt from MongoDB only on expiry; each task reads just the new messages
+		redisClient := redis.New(net.JoinHostPort(cfg.RedisHost, strconv.Itoa(cfg.RedisPort)), cfg.RedisPassword, cfg.RedisDB, concurrency)
+		databaseService.ContextCache = service.NewSessionContextCache(redisClient, cfg.SessionContextCacheTTL)
+		lc.Append(lifecycle.Hook{
+			Name:   "redis",
+			OnStop: func(ctx context.Context) error { return redisClient.Close() },
+		})
+	}
 	
 	// Initialize event services (required for EventPublisherService)
 	db := mongoClient.Database(cfg.MongoDB)
 	eventRepo := repository.NewEventRepository(db)
 	eventService := service.NewEventService(eventRepo)
+	eventService.Producer = "api-service-worker"
 	eventProcessorConfigRepo := repository.NewEventProcessorConfigRepository(db)
 	eventProcessorConfigService := service.NewEventProcessorConfigService(eventProcessorConfigRepo)
 	eventDeliveryRepo := repository.NewEventDeliveryRepository(db)
@@ -126,10 +290,62 @@ func runWorker(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client
 	if err != nil {
 		logger.Fatal("Failed to create task client", zap.Error(err))
 	}
-	defer taskClient.Close()
+	lc.Append(lifecycle.Hook{
+		Name:   "task-client",
+		OnStop: func(ctx context.Context) error { return taskClient.Close() },
+	})
+	// Messages created by inbound channels trigger their workflows through it
+	service.SetWorkflowTaskClient(taskClient, logger)
+	service.SetWorkflowBackpressure(service.NewBackpressure(taskClient, cfg, logger))
+
+	// Cache invalidation bus shared with the API servers
+	cacheBus, err := cache.NewBus(rabbitMQURL, cfg.CacheInvalidationExchange, logger)
+	if err != nil {
+		logger.Warn("Failed to create cache invalidation bus for worker", zap.Error(err))
+	} else {
+		eventProcessorConfigService.Invalidator = cacheBus
+		lc.Append(lifecycle.Hook{
+			Name:   "cache-bus",
+			OnStop: func(ctx context.Context) error { return cacheBus.Close() },
+		})
+	}
+
+	// Messages and events written by tasks wake long polls on the API servers
+	notificationHub, err := realtime.NewHub(rabbitMQURL, cfg.SessionNotificationExchange, logger)
+	if err != nil {
+		logger.Warn("Failed to create session notification hub for worker", zap.Error(err))
+	} else {
+		eventService.Notifier = notificationHub
+		lc.Append(lifecycle.Hook{
+			Name:   "notification-hub",
+			OnStop: func(ctx context.Context) error { return notificationHub.Close() },
+		})
+	}
 	
+	// Clients and channels are looked up for nearly every task; the cache drops them when they change
+	clientRepo := repository.NewClientRepository(db)
+	var clientCache *service.MemoryClientCache
+	if cfg.ClientCacheTTL > 0 {
+		clientCache = service.NewMemoryClientCache(clientRepo, repository.NewClientChannelRepository(db), cfg.ClientCacheTTL)
+		databaseService.Clients = clientCache
+		if cacheBus != nil {
+			cacheBus.Subscribe(cache.KindClient, clientCache.Forget)
+			cacheBus.Subscribe(cache.KindClientChannel, clientCache.Forget)
+		}
+	}
+
 	// Initialize services needed for PayloadService first
 	chatSessionService := service.NewChatSessionService(chatSessionRepo)
+	if clientCache != nil {
+		chatSessionService.ThreadManager.Clients = clientCache
+	}
+	
+	// Feature flags decide threading for clients that don't configure it
+	featureFlagService := service.NewFeatureFlagService(repository.NewFeatureFlagRepository(db), logger)
+	chatSessionService.ThreadManager.Flags = featureFlagService
+	if cacheBus != nil {
+		cacheBus.Subscribe(cache.KindFeatureFlag, featureFlagService.Forget)
+	}
 	
 	// Initialize PayloadService with ThreadManagerService from ChatSessionService
 	payloadService := service.NewPayloadService(nil, chatSessionService, chatSessionService.ThreadManager) // ChatMessageService will be set later
@@ -139,9 +355,17 @@ func runWorker(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client
 	
 	// Initialize ChatMessageService with EventPublisherService and PayloadService
 	chatMessageService := service.NewChatMessageService(chatMessageRepo, eventPublisherService, payloadService)
+	chatMessageService.ChatSessionRepo = chatSessionRepo
+	transactor, err := repository.NewTransactor(context.Background(), mongoClient, cfg.MongoTransactions)
+	if err != nil {
+		logger.Warn("Storing AI responses without transactions", zap.Error(err))
+	}
+	chatMessageService.Transactions = transactor
 	
 	// Update PayloadService with ChatMessageService to complete the circular dependency
 	payloadService.ChatMessageService = chatMessageService
+	contactService := service.NewContactService(repository.NewContactRepository(db), clientRepo, chatSessionRepo, chatMessageRepo)
+	payloadService.Contacts = contactService
 	
 	// Initialize task worker
 	taskWorker, err := tasks.NewTaskWorker(rabbitMQURL, logger, cfg.AIServiceURL, cfg.SlackAIToken, databaseService, eventPublisherService, payloadService, chatMessageService, cfg)
@@ -149,28 +373,447 @@ func runWorker(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client
 		logger.Fatal("Failed to create task worker", zap.Error(err))
 	}
 
+	// CSAT service for csat_trigger tasks enqueued by bulk sweeps and for survey reminders and expiry
+	csatSessionRepo := repository.NewCSATSessionRepository(db)
+	csatQuestionRepo := repository.NewCSATQuestionTemplateRepository(db)
+	csatConfigRepo := repository.NewCSATConfigurationRepository(db)
+	csatEventPublisherService := service.NewEventPublisherService(eventService, eventProcessorConfigService, eventDeliveryTrackingService, chatSessionRepo, chatMessageRepo, csatSessionRepo, csatQuestionRepo, csatConfigRepo, payloadService, taskClient)
+	csatService := service.NewCSATService(
+		csatConfigRepo,
+		csatQuestionRepo,
+		csatSessionRepo,
+		repository.NewCSATResponseRepository(db),
+		chatMessageRepo,
+		chatSessionRepo,
+		service.NewChatSessionThreadService(repository.NewChatSessionThreadRepository(db)),
+		csatEventPublisherService,
+		payloadService,
+	)
+	// Questions sent from the worker schedule their own reminders and expiry
+	csatService.TaskClient = taskClient
+	csatService.ConsentChecker = contactService.CSATConsent
+	taskWorker.SetCSATService(csatService)
+	taskWorker.SetRepairService(service.NewRepairService(
+		repository.NewRepairActionRepository(db),
+		eventDeliveryRepo,
+		repository.NewChatSessionThreadRepository(db),
+		chatSessionRepo,
+		taskClient,
+	))
+	// Hooks and callbacks that clients configure can only reach the hosts webhooks may
+	egressPolicy, err := egress.NewPolicy(cfg.DispatchAllowedHosts, cfg.DispatchDeniedHosts, cfg.DispatchAllowPrivateNetworks)
+	if err != nil {
+		logger.Warn("Invalid webhook egress settings, hooks and callbacks can't reach private networks", zap.Error(err))
+	}
+	postProcessingService := service.NewPostProcessingService(clientRepo, chatSessionRepo, logger)
+	postProcessingService.SetEgressPolicy(egressPolicy)
+	taskWorker.SetPostProcessingService(postProcessingService)
+	taskWorker.SetChatWorkflowStateService(service.NewChatWorkflowStateService(repository.NewChatWorkflowStateRepository(db)))
+	deliveryFailureService := service.NewDeliveryFailureService(chatMessageRepo, chatSessionRepo, clientRepo, logger)
+	deliveryFailureService.SetEgressPolicy(egressPolicy)
+	taskWorker.SetDeliveryFailureService(deliveryFailureService)
+	intentService := service.NewIntentService(clientRepo, chatSessionRepo, chatMessageRepo, logger)
+	intentService.AIService = service.NewAIService(logger, cfg.AIServiceURL, cfg.SlackAIToken)
+	intentService.HandoverService = service.NewHandoverService(chatSessionRepo, eventPublisherService)
+	intentService.HandoverService.AssignmentService = service.NewAssignmentService(repository.NewAgentRepository(db), repository.NewAssignmentRuleRepository(db), chatSessionRepo, clientRepo)
+	taskWorker.SetIntentService(intentService)
+	moderationService := service.NewModerationService(clientRepo, chatSessionRepo, logger)
+	moderationService.SetEgressPolicy(egressPolicy)
+	moderationService.HandoverService = intentService.HandoverService
+	taskWorker.SetModerationService(moderationService)
+	escalationService := service.NewEscalationService(clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, logger)
+	escalationService.HandoverService = intentService.HandoverService
+	taskWorker.SetEscalationService(escalationService)
+	aiConfigService := service.NewClientAIConfigService(clientRepo, chatSessionRepo)
+	taskWorker.SetClientAIConfigService(aiConfigService)
+	answerCacheService := service.NewAnswerCacheService(repository.NewAnswerCacheRepository(db), clientRepo, chatSessionRepo)
+	taskWorker.SetAnswerCacheService(answerCacheService)
+	promptTemplateRepo := repository.NewPromptTemplateRepository(db)
+	promptTemplateService := service.NewPromptTemplateService(promptTemplateRepo, clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo)
+	taskWorker.SetPromptTemplateService(promptTemplateService)
+	experimentService := service.NewExperimentService(repository.NewExperimentRepository(db), clientRepo, chatSessionRepo, promptTemplateRepo, db)
+	taskWorker.SetExperimentService(experimentService)
+	escalationService.Experiments = experimentService
+	csatService.ExperimentTagger = experimentService.CSATTag
+	channelCapabilityService := service.NewChannelCapabilityService(chatMessageRepo, chatSessionRepo, repository.NewClientChannelRepository(db))
+	usageService := service.NewUsageService(repository.NewClientUsageRepository(db), clientRepo, chatSessionRepo, logger)
+	usageService.Events = eventPublisherService
+	chatMessageService.Usage = usageService
+	taskWorker.SetUsageService(usageService)
+	if clientCache != nil {
+		postProcessingService.Clients = clientCache
+		usageService.Clients = clientCache
+		intentService.Clients = clientCache
+		moderationService.Clients = clientCache
+		escalationService.Clients = clientCache
+		aiConfigService.Clients = clientCache
+		answerCacheService.Clients = clientCache
+		promptTemplateService.Clients = clientCache
+		experimentService.Clients = clientCache
+		channelCapabilityService.Clients = clientCache
+	}
+	taskWorker.SetChatMessageSuggestionService(service.NewChatMessageSuggestionService(db))
+	if cfg.AIBatchURL != "" {
+		taskWorker.SetSuggestionBatcher(service.NewSuggestionBatcher(service.NewAIService(logger, cfg.AIServiceURL, cfg.SlackAIToken), cfg.AIBatchURL, cfg.AIBatchWindow, cfg.AIBatchMaxSize))
+	}
+	// Credentials in processor and channel configs may be secret:// references
+	secretResolver := service.NewSecretResolver(cfg)
+	taskWorker.SetSecretResolver(secretResolver)
+	slackService := service.NewSlackService(clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, chatMessageRepo, chatSessionService, chatMessageService, logger)
+	// Slack AI workflow tokens are sealed with ENCRYPTION_KEY by the API
+	slackService.Secrets, _ = utils.NewSecretBox(cfg.EncryptionKey)
+	slackService.SecretResolver = secretResolver
+	taskWorker.SetSlackService(slackService)
+	whatsAppService := service.NewWhatsAppService(clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, chatMessageRepo, chatSessionService, chatMessageService, logger)
+	whatsAppService.SecretResolver = secretResolver
+	taskWorker.SetWhatsAppService(whatsAppService)
+	teamsService := service.NewTeamsService(clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, chatMessageRepo, chatSessionService, chatMessageService, logger)
+	teamsService.SecretResolver = secretResolver
+	taskWorker.SetTeamsService(teamsService)
+	emailService := service.NewEmailService(clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, chatMessageRepo, chatSessionService, chatMessageService, logger)
+	emailService.SecretResolver = secretResolver
+	emailService.SetEgressPolicy(egressPolicy)
+	if attachmentRepo, err := repository.NewAttachmentRepository(db); err == nil {
+		emailService.AttachmentService = service.NewAttachmentService(attachmentRepo, cfg.AttachmentBaseURL)
+	}
+	taskWorker.SetEmailService(emailService)
+	sunshineService := service.NewSunshineService(clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, chatMessageRepo, chatSessionService, chatMessageService, logger)
+	sunshineService.SecretResolver = secretResolver
+	taskWorker.SetSunshineService(sunshineService)
+	taskWorker.SetChannelCapabilityService(channelCapabilityService)
+	smsService := service.NewSMSService(clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, chatMessageRepo, chatSessionService, chatMessageService, cfg.PublicAPIURL, logger)
+	smsService.SecretResolver = secretResolver
+	taskWorker.SetSMSService(smsService)
+	chatSessionRecapService := service.NewChatSessionRecapService(repository.NewChatSessionRecapRepository(db))
+	chatSessionRecapService.ChatSessionRepo = chatSessionRepo
+	chatSessionRecapService.ChatMessageRepo = chatMessageRepo
+	chatSessionRecapService.AIService = service.NewAIService(logger, cfg.AIServiceURL, cfg.SlackAIToken)
+	chatSessionRecapService.EventPublisherService = eventPublisherService
+	taskWorker.SetChatSessionRecapService(chatSessionRecapService)
+	scheduledMessageService := service.NewScheduledMessageService(
+		repository.NewScheduledMessageRepository(db),
+		chatMessageService,
+		chatSessionService,
+		service.NewClientService(clientRepo),
+		service.NewClientChannelService(repository.NewClientChannelRepository(db), clientRepo),
+		taskClient,
+	)
+	taskWorker.SetScheduledMessageService(scheduledMessageService)
+	// Scheduled reports read analytics like the API, off the primary when configured
+	analyticsDB, err := repository.AnalyticsDatabase(mongoClient, cfg)
+	if err != nil {
+		logger.Warn("Invalid analytics read preference, reading analytics from the primary", zap.Error(err))
+		analyticsDB = db
+	}
+	analyticsService := service.NewAnalyticsService(analyticsDB, clientRepo)
+	reportService := service.NewReportService(repository.NewReportScheduleRepository(db), clientRepo, repository.NewClientChannelRepository(db), analyticsService, logger)
+	reportService.TaskClient = taskClient
+	reportService.Email = emailService
+	reportService.Webhooks = service.NewWebhookService(logger, nil)
+	reportService.Webhooks.SetEgressPolicy(egressPolicy)
+	reportService.Egress = egressPolicy
+	if clientCache != nil {
+		analyticsService.Clients = clientCache
+	}
+	taskWorker.SetReportService(reportService)
+	simulationService := service.NewSimulationService(repository.NewSimulationRepository(db), clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, chatMessageRepo, eventRepo)
+	simulationService.ChatMessageService = chatMessageService
+	taskWorker.SetSimulationService(simulationService)
+	if messageImportRepo, err := repository.NewMessageImportRepository(db); err != nil {
+		logger.Warn("Failed to open message import storage, message imports won't run", zap.Error(err))
+	} else {
+		messageImportService := service.NewMessageImportService(messageImportRepo, clientRepo, repository.NewClientChannelRepository(db), chatSessionRepo, chatMessageRepo)
+		messageImportService.EventPublisherService = eventPublisherService
+		taskWorker.SetMessageImportService(messageImportService)
+	}
+	sessionLifecycleService := service.NewSessionLifecycleService(chatSessionRepo, clientRepo, eventPublisherService, cfg.SessionAutoCloseMinutes)
+	sessionLifecycleService.RecapTaskClient = taskClient
+	sessionLifecycleService.ThreadManager = chatSessionService.ThreadManager
+	aiFeedbackService := service.NewAIFeedbackService(repository.NewChatMessageFeedbackRepository(db), clientRepo, chatSessionRepo, chatMessageRepo, service.NewAIService(logger, cfg.AIServiceURL, cfg.SlackAIToken), cfg.AIFeedbackURL, cfg.AIFeedbackBatchSize, logger)
+	if clientCache != nil {
+		aiFeedbackService.Clients = clientCache
+	}
+	// Maintenance tasks are enqueued by the scheduler
+	maintenanceService := service.NewMaintenanceService(
+		eventDeliveryRepo,
+		csatService,
+		sessionLifecycleService,
+		usageService,
+		taskClient,
+		logger,
+	)
+	maintenanceService.Feedback = aiFeedbackService
+	maintenanceService.ScheduledMessages = scheduledMessageService
+	taskWorker.SetMaintenanceService(maintenanceService)
+
 	// Set queues and concurrency
 	taskWorker.SetQueues(queues)
 	taskWorker.SetConcurrency(concurrency)
 
-	// Handle shutdown signals
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	// The worker is stopped before the task client and MongoDB so in-flight tasks can still use them
+	lc.Append(lifecycle.Hook{
+		Name: "task-worker",
+		OnStart: func(ctx context.Context) error {
+			if err := taskWorker.Start(); err != nil {
+				return err
+			}
+			go func() {
+				<-taskWorker.Done()
+				lc.Fail(errors.New("all task consumers exited"))
+			}()
+			return nil
+		},
+		OnStop: taskWorker.Stop,
+	})
 
-	go func() {
-		<-c
-		logger.Info("Shutdown signal received")
-		taskWorker.Stop()
-	}()
+	// Workers have no API server, so Prometheus scrapes them on a port of their own
+	if cfg.MetricsPort != "" {
+		metricsAddr := fmt.Sprintf(":%s", cfg.MetricsPort)
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		metricsSrv := &http.Server{Addr: metricsAddr, Handler: mux}
+		lc.Append(lifecycle.Hook{
+			Name: "metrics-server",
+			OnStart: func(ctx context.Context) error {
+				ln, err := net.Listen("tcp", metricsAddr)
+				if err != nil {
+					return err
+				}
+				logger.Info("Serving worker metrics", zap.String("addr", metricsAddr))
+				go func() {
+					if err := metricsSrv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						lc.Fail(fmt.Errorf("metrics server: %w", err))
+					}
+				}()
+				return nil
+			},
+			OnStop: metricsSrv.Shutdown,
+		})
+	}
+
+	// Every worker sweeps; state transitions are guarded so concurrent sweeps don't double-close
+	if cfg.SessionSweepInterval > 0 {
+		sweepCtx, stopSweep := context.WithCancel(context.Background())
+		sweepDone := make(chan struct{})
+		lc.Append(lifecycle.Hook{
+			Name: "session-sweeper",
+			OnStart: func(ctx context.Context) error {
+				go func() {
+					defer close(sweepDone)
+					runSessionSweeper(sweepCtx, sessionLifecycleService, cfg.SessionSweepInterval, logger)
+				}()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				stopSweep()
+				select {
+				case <-sweepDone:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
+	}
 
-	// Start the worker
-	if err := taskWorker.Start(); err != nil {
-		logger.Fatal("Failed to start task worker", zap.Error(err))
+	// Every worker reports; each day is claimed before it is reported so it goes out once
+	if cfg.UsageReportInterval > 0 {
+		reportCtx, stopReports := context.WithCancel(context.Background())
+		reportsDone := make(chan struct{})
+		lc.Append(lifecycle.Hook{
+			Name: "usage-reporter",
+			OnStart: func(ctx context.Context) error {
+				go func() {
+					defer close(reportsDone)
+					runUsageReporter(reportCtx, usageService, cfg.UsageReportInterval, logger)
+				}()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				stopReports()
+				select {
+				case <-reportsDone:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
 	}
 
+	// Every worker schedules; a schedule is advanced before its report is queued so it goes out once
+	if cfg.ReportSchedulerInterval > 0 {
+		schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+		schedulerDone := make(chan struct{})
+		lc.Append(lifecycle.Hook{
+			Name: "report-scheduler",
+			OnStart: func(ctx context.Context) error {
+				go func() {
+					defer close(schedulerDone)
+					runReportScheduler(schedulerCtx, reportService, cfg.ReportSchedulerInterval, logger)
+				}()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				stopScheduler()
+				select {
+				case <-schedulerDone:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+		})
+	}
+
+	if err := lc.Run(context.Background()); err != nil {
+		logger.Fatal("Worker exited with error", zap.Error(err))
+	}
 	logger.Info("Worker stopped")
 }
 
+// runChangeStream publishes events for chat messages and sessions written to MongoDB without going
+// through the services. Its checkpoints are shared, so only one instance should run.
+func runChangeStream(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, lc *lifecycle.Manager) {
+	rabbitMQURL := cfg.GetRabbitMQURL()
+	logger.Info("Starting change stream listener", zap.Duration("grace", cfg.ChangeStreamGrace))
+
+	db := mongoClient.Database(cfg.MongoDB)
+	eventRepo := repository.NewEventRepository(db)
+	eventService := service.NewEventService(eventRepo)
+	eventService.Producer = "api-service-changestream"
+	eventProcessorConfigService := service.NewEventProcessorConfigService(repository.NewEventProcessorConfigRepository(db))
+	eventDeliveryTrackingService := service.NewEventDeliveryTrackingService(repository.NewEventDeliveryRepository(db), repository.NewEventDeliveryAttemptRepository(db))
+	chatSessionRepo := repository.NewChatSessionRepository(db)
+	chatMessageRepo := repository.NewChatMessageRepository(db)
+
+	taskClient, err := tasks.NewTaskClient(rabbitMQURL, logger, cfg)
+	if err != nil {
+		logger.Fatal("Failed to create task client", zap.Error(err))
+	}
+	lc.Append(lifecycle.Hook{
+		Name:   "task-client",
+		OnStop: func(ctx context.Context) error { return taskClient.Close() },
+	})
+
+	// Published events wake long polls on the API servers like any other
+	notificationHub, err := realtime.NewHub(rabbitMQURL, cfg.SessionNotificationExchange, logger)
+	if err != nil {
+		logger.Warn("Failed to create session notification hub for change stream listener", zap.Error(err))
+	} else {
+		eventService.Notifier = notificationHub
+		lc.Append(lifecycle.Hook{
+			Name:   "notification-hub",
+			OnStop: func(ctx context.Context) error { return notificationHub.Close() },
+		})
+	}
+
+	// Message payloads are built the way the worker builds them
+	chatSessionService := service.NewChatSessionService(chatSessionRepo)
+	chatSessionService.ThreadManager.Flags = service.NewFeatureFlagService(repository.NewFeatureFlagRepository(db), logger)
+	payloadService := service.NewPayloadService(nil, chatSessionService, chatSessionService.ThreadManager)
+	eventPublisherService := service.NewEventPublisherService(eventService, eventProcessorConfigService, eventDeliveryTrackingService, chatSessionRepo, chatMessageRepo, nil, nil, nil, payloadService, taskClient)
+	payloadService.ChatMessageService = service.NewChatMessageService(chatMessageRepo, eventPublisherService, payloadService)
+	payloadService.Contacts = service.NewContactService(repository.NewContactRepository(db), repository.NewClientRepository(db), chatSessionRepo, chatMessageRepo)
+
+	listener := changestream.NewListener(db, eventRepo, eventPublisherService, payloadService, logger, cfg.ChangeStreamGrace)
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	listenDone := make(chan struct{})
+	lc.Append(lifecycle.Hook{
+		Name: "change-stream",
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				defer close(listenDone)
+				if err := listener.Run(listenCtx); err != nil {
+					lc.Fail(fmt.Errorf("change stream: %w", err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopListening()
+			select {
+			case <-listenDone:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+
+	if err := lc.Run(context.Background()); err != nil {
+		logger.Fatal("Change stream listener exited with error", zap.Error(err))
+	}
+	logger.Info("Change stream listener stopped")
+}
+
+// runScheduler enqueues maintenance tasks for the workers on their cron schedules. Every replica
+// can run it; only the one holding the scheduler lease enqueues.
+func runScheduler(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, lc *lifecycle.Manager) {
+	schedules := []struct {
+		job  service.MaintenanceJob
+		expr string
+	}{
+		{service.MaintenanceRetryDeliveries, cfg.ScheduleRetryDeliveries},
+		{service.MaintenanceCSATExpiry, cfg.ScheduleCSATExpiry},
+		{service.MaintenanceThreadInactivity, cfg.ScheduleThreadInactivity},
+		{service.MaintenanceUsageRollup, cfg.ScheduleUsageRollup},
+		{service.MaintenanceAIFeedback, cfg.ScheduleAIFeedback},
+		{service.MaintenanceScheduledMessages, cfg.ScheduleScheduledMessages},
+	}
+	var jobs []scheduler.Job
+	for _, s := range schedules {
+		if s.expr == config.ScheduleOff {
+			continue
+		}
+		schedule, err := cron.Parse(s.expr)
+		if err != nil {
+			logger.Fatal("Invalid maintenance schedule", zap.String("job", string(s.job)), zap.Error(err))
+		}
+		jobs = append(jobs, scheduler.Job{Name: string(s.job), Schedule: schedule})
+	}
+	logger.Info("Starting scheduler", zap.Int("jobs", len(jobs)), zap.Duration("lease", cfg.SchedulerLease))
+
+	taskClient, err := tasks.NewTaskClient(cfg.GetRabbitMQURL(), logger, cfg)
+	if err != nil {
+		logger.Fatal("Failed to create task client", zap.Error(err))
+	}
+	lc.Append(lifecycle.Hook{
+		Name:   "task-client",
+		OnStop: func(ctx context.Context) error { return taskClient.Close() },
+	})
+
+	sched := scheduler.New(mongoClient.Database(cfg.MongoDB), taskClient, jobs, cfg.SchedulerLease, logger)
+	schedCtx, stopScheduling := context.WithCancel(context.Background())
+	schedDone := make(chan struct{})
+	lc.Append(lifecycle.Hook{
+		Name: "scheduler",
+		OnStart: func(ctx context.Context) error {
+			go func() {
+				defer close(schedDone)
+				if err := sched.Run(schedCtx); err != nil {
+					lc.Fail(fmt.Errorf("scheduler: %w", err))
+				}
+			}()
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			stopScheduling()
+			select {
+			case <-schedDone:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		},
+	})
+
+	if err := lc.Run(context.Background()); err != nil {
+		logger.Fatal("Scheduler exited with error", zap.Error(err))
+	}
+	logger.Info("Scheduler stopped")
+}
+
 // buildRedisURL is kept for backward compatibility but deprecated
 // Use cfg.GetRabbitMQURL() instead for new implementations
 func buildRedisURL(cfg *config.Config) string {
@@ -195,3 +838,65 @@ func buildRedisURL(cfg *config.Config) string {
 	}
 	return fmt.Sprintf("redis://%s:%d/%d", host, port, db)
 }
+
+// runSessionSweeper runs the session lifecycle sweep every interval until ctx is cancelled.
+func runSessionSweeper(ctx context.Context, svc *service.SessionLifecycleService, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := svc.Sweep(ctx)
+			if err != nil {
+				logger.Error("Session sweep failed", zap.Error(err))
+			}
+			if changed > 0 {
+				logger.Info("Session sweep changed sessions", zap.Int("count", changed))
+			}
+		}
+	}
+}
+
+// runUsageReporter publishes usage_report events for finished days every interval until ctx is
+// cancelled.
+func runUsageReporter(ctx context.Context, svc *service.UsageService, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			published, err := svc.ReportUsage(ctx, time.Now())
+			if err != nil {
+				logger.Error("Usage report failed", zap.Error(err))
+			}
+			if published > 0 {
+				logger.Info("Published usage reports", zap.Int("count", published))
+			}
+		}
+	}
+}
+
+// runReportScheduler queues the scheduled analytics reports that are due every interval until ctx
+// is cancelled.
+func runReportScheduler(ctx context.Context, svc *service.ReportService, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			queued, err := svc.QueueDue(ctx, time.Now())
+			if err != nil {
+				logger.Error("Report scheduling failed", zap.Error(err))
+			}
+			if queued > 0 {
+				logger.Info("Queued scheduled reports", zap.Int("count", queued))
+			}
+		}
+	}
+}
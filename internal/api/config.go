@@ -8,20 +8,24 @@ import (
 	"github.com/fraiday-org/api-service/internal/api/middleware"
 	"github.com/fraiday-org/api-service/internal/api/routes"
 	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/grpcapi"
 	"github.com/fraiday-org/api-service/internal/service"
 )
 
-func SetupRouter(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client) *gin.Engine {
+func SetupRouter(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client) (*gin.Engine, *grpcapi.Server) {
 	engine := gin.New()
+	// The client IP, which rate limits and logs go by, comes from forwarding headers only when a
+	// trusted proxy set them
+	if err := engine.SetTrustedProxies(cfg.TrustedProxyList()); err != nil {
+		logger.Fatal("Invalid trusted proxies", zap.Error(err))
+	}
 
 	// Initialize services
 	metricsService := service.NewMetricsService(logger)
-	
-	// Initialize workflow service with config and logger
-	service.InitializeWorkflowService(cfg, logger)
 
 	// Middleware
 	engine.Use(middleware.RequestID())
+	engine.Use(middleware.Tracing())
 	engine.Use(middleware.Logger(logger))
 	engine.Use(middleware.Recovery(logger))
 	engine.Use(middleware.CORS())
@@ -29,7 +33,7 @@ func SetupRouter(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Clie
 	engine.Use(middleware.MetricsMiddleware(metricsService))
 
 	// Register routes
-	routes.Register(engine, cfg, logger, mongoClient)
+	grpcServer := routes.Register(engine, cfg, logger, mongoClient)
 
-	return engine
+	return engine, grpcServer
 }
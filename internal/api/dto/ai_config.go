@@ -0,0 +1,12 @@
+// Package dto defines request/response payloads for client AI config endpoints.
+package dto
+
+// ClientAIConfigRequest is the payload for PUT /clients/:client_id/ai-config.
+type ClientAIConfigRequest struct {
+	Enabled      *bool    `json:"enabled,omitempty"`
+	Provider     string   `json:"provider,omitempty"`
+	Model        string   `json:"model" binding:"required"`
+	Temperature  *float64 `json:"temperature,omitempty"`
+	SystemPrompt string   `json:"system_prompt,omitempty"`
+	MaxTokens    int      `json:"max_tokens,omitempty"`
+}
@@ -0,0 +1,9 @@
+// Package dto defines request/response payloads for AI feedback endpoints.
+package dto
+
+// AIFeedbackPolicyRequest is the payload for PUT /clients/:client_id/ai-feedback.
+type AIFeedbackPolicyRequest struct {
+	Enabled         *bool `json:"enabled,omitempty"`
+	IncludeComments bool  `json:"include_comments,omitempty"`
+	ContextMessages int   `json:"context_messages,omitempty"`
+}
@@ -1,6 +1,8 @@
 // Package dto defines request/response payloads for analytics endpoints.
 package dto
 
+import "time"
+
 // DashboardMetricsResponse is the response for dashboard analytics metrics.
 type DashboardMetricsResponse struct {
 	Success bool                   `json:"success"`
@@ -18,7 +20,160 @@ type BotEngagementMetricsResponse struct {
 // ContainmentRateResponse is the response for containment rate analytics.
 type ContainmentRateResponse struct {
 	Success  bool                   `json:"success"`
-	Data     []interface{}          `json:"data,omitempty"`
+	Data     []ContainmentPoint     `json:"data,omitempty"`
 	Error    *string                `json:"error,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Summary  *ContainmentStats      `json:"summary,omitempty"`
+	Channels []ChannelContainment   `json:"channels,omitempty"`
+}
+
+// ContainmentStats counts the sessions the bot answered or handed over. ContainmentRate is the
+// fraction of bot-handled sessions that were contained; DeflectionRate the fraction of all of them.
+type ContainmentStats struct {
+	Sessions        int64   `json:"sessions"`
+	BotHandled      int64   `json:"bot_handled"`
+	Contained       int64   `json:"contained"` // Answered by the bot and never handed over
+	HandedOver      int64   `json:"handed_over"`
+	ContainmentRate float64 `json:"containment_rate"`
+	DeflectionRate  float64 `json:"deflection_rate"`
+}
+
+// ContainmentPoint is one bucket of the containment trend. Value is its containment rate in percent.
+type ContainmentPoint struct {
+	Time      time.Time `json:"time"`
+	TimeLabel string    `json:"time_label"`
+	Value     float64   `json:"value"`
+	Unit      string    `json:"unit"`
+	ContainmentStats
+}
+
+// ChannelContainment is the containment of one client channel.
+type ChannelContainment struct {
+	ChannelID   string `json:"channel_id,omitempty"` // Empty for sessions without a channel
+	ChannelType string `json:"channel_type,omitempty"`
+	ContainmentStats
+}
+
+// PerformanceMetricsResponse is the response for response time and resolution metrics.
+type PerformanceMetricsResponse struct {
+	Success bool                `json:"success"`
+	Data    *PerformanceMetrics `json:"data,omitempty"`
+	Error   *string             `json:"error,omitempty"`
+}
+
+// PerformanceMetrics holds response time and resolution metrics over a time range.
+type PerformanceMetrics struct {
+	// FirstResponseTime runs from a session's first user message to the first reply to it, by the
+	// bot or an agent, for sessions created in range
+	FirstResponseTime DurationStats `json:"first_response_time"`
+	// BotResponseLatency runs from a message to the AI response to it, for responses sent in range
+	BotResponseLatency DurationStats `json:"bot_response_latency"`
+	// AverageHandleTime runs from an agent accepting a handover to completing it, for handovers
+	// completed in range
+	AverageHandleTime DurationStats   `json:"average_handle_time"`
+	Resolution        ResolutionStats `json:"resolution"`
+	StartTime         time.Time       `json:"start_time"`
+	EndTime           time.Time       `json:"end_time"`
+}
+
+// DurationStats summarizes a set of durations, in seconds.
+type DurationStats struct {
+	Count          int64   `json:"count"`
+	AverageSeconds float64 `json:"average_seconds"`
+	MinSeconds     float64 `json:"min_seconds"`
+	MaxSeconds     float64 `json:"max_seconds"`
+}
+
+// ResolutionStats counts how the sessions created in range ended up. Rates are fractions of Sessions.
+type ResolutionStats struct {
+	Sessions          int64   `json:"sessions"`
+	Resolved          int64   `json:"resolved"`
+	ResolvedByBot     int64   `json:"resolved_by_bot"` // Closed without a handover to an agent
+	HandedOver        int64   `json:"handed_over"`
+	ResolutionRate    float64 `json:"resolution_rate"`
+	BotResolutionRate float64 `json:"bot_resolution_rate"`
+}
+
+// VolumeHeatmapResponse is the response for the conversation volume heatmap.
+type VolumeHeatmapResponse struct {
+	Success bool           `json:"success"`
+	Data    *VolumeHeatmap `json:"data,omitempty"`
+	Error   *string        `json:"error,omitempty"`
+}
+
+// VolumeHeatmap holds message and session counts per client by day of the week and hour of the
+// day, in Timezone.
+type VolumeHeatmap struct {
+	StartTime time.Time      `json:"start_time"`
+	EndTime   time.Time      `json:"end_time"`
+	Timezone  string         `json:"timezone"`
+	Clients   []ClientVolume `json:"clients"`
+}
+
+// ClientVolume is one client's heatmap. Rows are days of the week from Monday, columns hours of
+// the day from 0.
+type ClientVolume struct {
+	ClientID      string       `json:"client_id"`
+	ClientName    string       `json:"client_name,omitempty"`
+	TotalMessages int64        `json:"total_messages"`
+	TotalSessions int64        `json:"total_sessions"`
+	Messages      [7][24]int64 `json:"messages"`
+	Sessions      [7][24]int64 `json:"sessions"`
+}
+
+// ConfidenceMetricsResponse is the response for AI confidence analytics.
+type ConfidenceMetricsResponse struct {
+	Success bool               `json:"success"`
+	Data    *ConfidenceMetrics `json:"data,omitempty"`
+	Error   *string            `json:"error,omitempty"`
+}
+
+// ConfidenceStats summarises the confidence of a set of AI answers. LowConfidenceRate is the
+// fraction of them at or below the threshold.
+type ConfidenceStats struct {
+	Answers           int64   `json:"answers"`
+	LowConfidence     int64   `json:"low_confidence"`
+	LowConfidenceRate float64 `json:"low_confidence_rate"`
+	AverageConfidence float64 `json:"average_confidence"`
+}
+
+// ConfidenceMetrics holds the confidence of the AI answers sent in a time range.
+type ConfidenceMetrics struct {
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	Threshold   float64   `json:"threshold"`
+	Aggregation string    `json:"aggregation"`
+	ConfidenceStats
+	Distribution []ConfidenceBucket `json:"distribution"`
+	Trend        []ConfidencePoint  `json:"trend"`
+	WeakIntents  []IntentConfidence `json:"weak_intents"` // Intents with the highest low-confidence rate first
+}
+
+// ConfidenceBucket counts the answers scored from Min up to Max; the last bucket includes 1.
+type ConfidenceBucket struct {
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Answers int64   `json:"answers"`
+}
+
+// ConfidencePoint is one bucket of the confidence trend.
+type ConfidencePoint struct {
+	Time      time.Time `json:"time"`
+	TimeLabel string    `json:"time_label"`
+	ConfidenceStats
+}
+
+// IntentConfidence is the confidence of the answers to messages classified with Intent.
+type IntentConfidence struct {
+	Intent string `json:"intent"`
+	ConfidenceStats
+}
+
+// CSATSummary counts CSAT surveys and averages their scored answers.
+type CSATSummary struct {
+	Surveys        int64    `json:"surveys"`
+	Completed      int64    `json:"completed"`
+	CompletionRate float64  `json:"completion_rate"`
+	ScoredAnswers  int64    `json:"scored_answers"`
+	AverageScore   *float64 `json:"average_score,omitempty"` // Absent without scored answers
 }
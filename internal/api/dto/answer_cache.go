@@ -0,0 +1,15 @@
+// Package dto defines request/response payloads for answer cache endpoints.
+package dto
+
+// AnswerCachePolicyRequest is the payload for PUT /clients/:client_id/answer-cache.
+type AnswerCachePolicyRequest struct {
+	Enabled          *bool   `json:"enabled,omitempty"`
+	TTLSeconds       int     `json:"ttl_seconds,omitempty"`
+	KnowledgeVersion string  `json:"knowledge_version,omitempty"`
+	MinConfidence    float64 `json:"min_confidence,omitempty"`
+}
+
+// AnswerCacheInvalidateResponse is the response for DELETE /clients/:client_id/answer-cache/entries.
+type AnswerCacheInvalidateResponse struct {
+	Deleted int64 `json:"deleted"`
+}
@@ -0,0 +1,34 @@
+// Package dto defines request/response payloads for client API key endpoints.
+package dto
+
+import (
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// APIKeyCreate is the payload for POST /clients/:client_id/api-keys.
+type APIKeyCreate struct {
+	Name      string               `json:"name" binding:"required"`
+	Scopes    []models.APIKeyScope `json:"scopes" binding:"required"`
+	Tier      string               `json:"rate_limit_tier,omitempty"` // One of RATE_LIMIT_TIERS; the default tier when empty
+	ExpiresAt *time.Time           `json:"expires_at,omitempty"`
+}
+
+// APIKeyRotate is the payload for POST /clients/:client_id/api-keys/:key_id/rotate.
+// GracePeriodSeconds keeps the old key working for that long after rotation.
+type APIKeyRotate struct {
+	GracePeriodSeconds int `json:"grace_period_seconds,omitempty"`
+}
+
+// APIKeyIssued is returned when a key is created or rotated. Key is shown only this once.
+type APIKeyIssued struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyListResponse is the response for GET /clients/:client_id/api-keys.
+type APIKeyListResponse struct {
+	APIKeys []models.APIKey `json:"api_keys"`
+	Total   int             `json:"total"`
+}
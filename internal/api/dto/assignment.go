@@ -0,0 +1,28 @@
+// Package dto defines request payloads for agent and assignment rule endpoints.
+package dto
+
+// AgentCreateRequest is the payload for POST /clients/:client_id/agents.
+type AgentCreateRequest struct {
+	AgentID       string   `json:"agent_id" binding:"required"`
+	Name          string   `json:"name,omitempty"`
+	Skills        []string `json:"skills,omitempty"`
+	Queues        []string `json:"queues,omitempty"`
+	Status        string   `json:"status,omitempty"`
+	MaxConcurrent int      `json:"max_concurrent,omitempty"`
+}
+
+// AgentAvailabilityRequest is the payload for PUT /clients/:client_id/agents/:agent_id/availability.
+type AgentAvailabilityRequest struct {
+	Status        string `json:"status" binding:"required"`
+	MaxConcurrent *int   `json:"max_concurrent,omitempty"`
+}
+
+// AssignmentRuleCreateRequest is the payload for POST /clients/:client_id/assignment-rules.
+type AssignmentRuleCreateRequest struct {
+	Name           string   `json:"name" binding:"required"`
+	Priority       int      `json:"priority"`
+	Queue          string   `json:"queue,omitempty"`
+	Strategy       string   `json:"strategy" binding:"required"`
+	RequiredSkills []string `json:"required_skills,omitempty"`
+	Enabled        *bool    `json:"enabled,omitempty"`
+}
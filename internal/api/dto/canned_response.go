@@ -0,0 +1,53 @@
+// Package dto defines request/response payloads for canned response endpoints.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// CannedResponseCreate is the payload for POST /clients/:client_id/canned-responses.
+type CannedResponseCreate struct {
+	Title       string                 `json:"title" binding:"required"`
+	Shortcut    string                 `json:"shortcut,omitempty"`
+	Category    string                 `json:"category,omitempty"`
+	Text        string                 `json:"text" binding:"required"`
+	Attachments []models.Attachment    `json:"attachments,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+}
+
+// CannedResponseUpdate is the payload for PUT /clients/:client_id/canned-responses/:canned_response_id.
+type CannedResponseUpdate struct {
+	Title       *string                `json:"title,omitempty"`
+	Shortcut    *string                `json:"shortcut,omitempty"`
+	Category    *string                `json:"category,omitempty"`
+	Text        *string                `json:"text,omitempty"`
+	Attachments []models.Attachment    `json:"attachments,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+}
+
+// CannedResponseListResponse is the response for GET /clients/:client_id/canned-responses.
+type CannedResponseListResponse struct {
+	CannedResponses []models.CannedResponse `json:"canned_responses"`
+	Total           int                     `json:"total"`
+}
+
+// CannedMessageCreate is the payload for POST /messages/canned. The message text and
+// attachments come from the canned response; Variables fill its placeholders.
+type CannedMessageCreate struct {
+	CannedResponseID  string                 `json:"canned_response_id" binding:"required"`
+	Variables         map[string]string      `json:"variables,omitempty"`
+	ExternalID        string                 `json:"external_id,omitempty"`
+	Sender            string                 `json:"sender" binding:"required"`
+	SenderName        string                 `json:"sender_name,omitempty"`
+	SenderType        string                 `json:"sender_type" binding:"required"`
+	SessionID         string                 `json:"session_id" binding:"required"`
+	ClientID          string                 `json:"client_id" binding:"required"`
+	ClientChannelType string                 `json:"client_channel_type" binding:"required"`
+	Data              map[string]interface{} `json:"data,omitempty"`
+	Category          string                 `json:"category,omitempty"`
+	Config            map[string]interface{} `json:"config,omitempty"`
+	ParentMessageID   string                 `json:"parent_message_id,omitempty"`
+}
+
+// UnknownFields returns config keys the API doesn't recognise.
+func (r *CannedMessageCreate) UnknownFields() []string {
+	return unknownMessageConfigKeys(r.Config)
+}
@@ -2,6 +2,9 @@
 package dto
 
 import (
+	"sort"
+	"time"
+
 	"github.com/fraiday-org/api-service/internal/models"
 )
 
@@ -19,6 +22,7 @@ type ChatMessageCreate struct {
 	Data              map[string]interface{} `json:"data,omitempty"`
 	Category          string                 `json:"category" binding:"required"`
 	Config            map[string]interface{} `json:"config,omitempty"`
+	ParentMessageID   string                 `json:"parent_message_id,omitempty"`
 }
 
 // ChatMessageUpdate represents the payload for updating a chat message.
@@ -32,8 +36,48 @@ type ChatMessageUpdate struct {
 	Data        map[string]interface{} `json:"data,omitempty"`
 }
 
+// messageConfigKeys are the message config keys the API acts on.
+var messageConfigKeys = map[string]bool{
+	"ai_enabled":      true,
+	"suggestion_mode": true,
+}
+
+// UnknownFields returns config keys the API doesn't recognise, such as a misspelt "sugestion_mode".
+// Strict binding reports them alongside unknown top-level fields.
+func (r *ChatMessageCreate) UnknownFields() []string {
+	return unknownMessageConfigKeys(r.Config)
+}
+
+// UnknownFields returns config keys the API doesn't recognise.
+func (r *ChatMessageUpdate) UnknownFields() []string {
+	return unknownMessageConfigKeys(r.Config)
+}
+
+func unknownMessageConfigKeys(config map[string]interface{}) []string {
+	var unknown []string
+	for key := range config {
+		if !messageConfigKeys[key] {
+			unknown = append(unknown, "config."+key)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
 // BulkChatMessageCreate represents the payload for bulk-creating chat messages.
 type BulkChatMessageCreate struct {
 	SessionID   string                 `json:"session_id" binding:"required"`
 	Messages    []ChatMessageCreate    `json:"messages" binding:"required"`
 }
+
+// ScheduledMessageCreate represents the payload for scheduling a chat message.
+type ScheduledMessageCreate struct {
+	ChatMessageCreate
+	SendAt time.Time `json:"send_at" binding:"required"`
+}
+
+// ScheduledMessageListResponse is the response for listing scheduled messages.
+type ScheduledMessageListResponse struct {
+	ScheduledMessages []models.ScheduledMessage `json:"scheduled_messages"`
+	Total             int                       `json:"total"`
+}
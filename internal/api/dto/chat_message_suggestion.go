@@ -0,0 +1,33 @@
+// Package dto defines request/response payloads for suggestion review endpoints.
+package dto
+
+// SuggestionAcceptRequest is the payload for POST /suggestions/:suggestion_id/accept.
+// Text is the message the agent actually sent, if they edited the suggestion.
+type SuggestionAcceptRequest struct {
+	Text    string `json:"text,omitempty"`
+	AgentID string `json:"agent_id,omitempty"`
+}
+
+// SuggestionRejectRequest is the payload for POST /suggestions/:suggestion_id/reject.
+type SuggestionRejectRequest struct {
+	Reason  string `json:"reason,omitempty"`
+	AgentID string `json:"agent_id,omitempty"`
+}
+
+// SuggestionStats summarises how agents reviewed a client's suggestions.
+type SuggestionStats struct {
+	ClientID       string  `json:"client_id"`
+	Total          int64   `json:"total"`
+	Accepted       int64   `json:"accepted"`
+	AcceptedEdited int64   `json:"accepted_edited"`
+	Rejected       int64   `json:"rejected"`
+	Pending        int64   `json:"pending"`
+	AcceptanceRate float64 `json:"acceptance_rate"` // Accepted share of reviewed suggestions
+	EditRate       float64 `json:"edit_rate"`       // Edited share of accepted suggestions
+}
+
+// SuggestionStatsResponse is the response for GET /analytics/suggestions.
+type SuggestionStatsResponse struct {
+	Success bool              `json:"success"`
+	Data    []SuggestionStats `json:"data"`
+}
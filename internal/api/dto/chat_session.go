@@ -12,23 +12,31 @@ type ChatSessionCreateResponse struct {
 
 // ChatSessionResponse is the response for getting a session.
 type ChatSessionResponse struct {
-	ID        string     `json:"id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	Active    bool       `json:"active"`
+	ID         string            `json:"id"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+	Active     bool              `json:"active"`
+	State      string            `json:"state"`
+	Tags       []string          `json:"tags"`
+	Attributes map[string]string `json:"attributes"`
+	Version    int64             `json:"version"`
 }
 
 // ChatSessionListItem is an item in the session list.
 type ChatSessionListItem struct {
-	ID            string     `json:"id"`
-	CreatedAt     time.Time  `json:"created_at"`
-	UpdatedAt     time.Time  `json:"updated_at"`
-	SessionID     string     `json:"session_id"`
-	Active        bool       `json:"active"`
-	Client        *string    `json:"client,omitempty"`
-	ClientChannel *string    `json:"client_channel,omitempty"`
-	Participants  []string   `json:"participants,omitempty"`
-	Handover      bool       `json:"handover"`
+	ID            string            `json:"id"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+	SessionID     string            `json:"session_id"`
+	Active        bool              `json:"active"`
+	Client        *string           `json:"client,omitempty"`
+	ClientChannel *string           `json:"client_channel,omitempty"`
+	Participants  []string          `json:"participants,omitempty"`
+	Handover      bool              `json:"handover"`
+	State         string            `json:"state"`
+	Tags          []string          `json:"tags,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+	Test          bool              `json:"test,omitempty"`
 }
 
 // ChatSessionListResponse is the response for listing sessions.
@@ -36,3 +44,25 @@ type ChatSessionListResponse struct {
 	Sessions []ChatSessionListItem `json:"sessions"`
 	Total    int                   `json:"total"`
 }
+
+// ChatSessionTagsUpdateRequest adds and removes session tags. With version set the update is
+// rejected once the session has moved past that version.
+type ChatSessionTagsUpdateRequest struct {
+	Add     []string `json:"add"`
+	Remove  []string `json:"remove"`
+	Version *int64   `json:"version,omitempty"`
+}
+
+// ChatSessionAttributesUpdateRequest sets custom session attributes. A null value removes the attribute.
+// With version set the update is rejected once the session has moved past that version.
+type ChatSessionAttributesUpdateRequest struct {
+	Attributes map[string]*string `json:"attributes" binding:"required"`
+	Version    *int64             `json:"version,omitempty"`
+}
+
+// ChatSessionStateRequest is the payload for session lifecycle actions. State is only read by POST /sessions/:session_id/state.
+type ChatSessionStateRequest struct {
+	State        string     `json:"state,omitempty"`
+	SnoozedUntil *time.Time `json:"snoozed_until,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+}
@@ -3,6 +3,8 @@ package dto
 
 import (
 	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
 )
 
 // ThreadResponse is the response for a single thread.
@@ -13,6 +15,7 @@ type ThreadResponse struct {
 	ChatSessionID   string    `json:"chat_session_id"`
 	Active          bool      `json:"active"`
 	LastActivity    time.Time `json:"last_activity"`
+	MergedInto      string    `json:"merged_into,omitempty"`
 }
 
 // ThreadListResponse is the response for a list of threads.
@@ -20,3 +23,47 @@ type ThreadListResponse struct {
 	Threads []ThreadResponse `json:"threads"`
 	Total   int              `json:"total"`
 }
+
+// ThreadMergeRequest moves a thread's messages into another thread of the same conversation.
+type ThreadMergeRequest struct {
+	TargetThreadID string `json:"target_thread_id" binding:"required"`
+	// PerformedBy names the agent making the change in the audit event
+	PerformedBy string `json:"performed_by,omitempty"`
+}
+
+// ThreadSplitRequest moves the messages of a thread after AfterMessageID into a new thread.
+type ThreadSplitRequest struct {
+	AfterMessageID string `json:"after_message_id" binding:"required"`
+	PerformedBy    string `json:"performed_by,omitempty"`
+}
+
+// ThreadMergeResponse is the response to a merge, with the thread the messages moved into.
+type ThreadMergeResponse struct {
+	Thread         ThreadResponse `json:"thread"`
+	SourceThreadID string         `json:"source_thread_id"`
+	MessagesMoved  int64          `json:"messages_moved"`
+}
+
+// ThreadSplitResponse is the response to a split, with the thread split and the new thread.
+type ThreadSplitResponse struct {
+	Thread        ThreadResponse `json:"thread"`
+	NewThread     ThreadResponse `json:"new_thread"`
+	MessagesMoved int64          `json:"messages_moved"`
+}
+
+// ThreadMessages is a thread of a conversation with its messages, newest first.
+type ThreadMessages struct {
+	Thread   ThreadResponse       `json:"thread"`
+	Messages []models.ChatMessage `json:"messages"`
+}
+
+// ThreadedMessagesResponse is a conversation's messages grouped by thread, for GET /messages with
+// group_by=thread.
+type ThreadedMessagesResponse struct {
+	ParentSessionID string           `json:"parent_session_id"`
+	Threads         []ThreadMessages `json:"threads"`
+	// Messages holds the messages of a conversation without threads
+	Messages []models.ChatMessage `json:"messages,omitempty"`
+	// Total counts the messages returned across threads
+	Total int `json:"total"`
+}
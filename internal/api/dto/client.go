@@ -3,17 +3,23 @@ package dto
 
 // ClientCreateOrUpdateRequest is the payload for creating or updating a client.
 type ClientCreateOrUpdateRequest struct {
-	Name     string  `json:"name" binding:"required"`
-	ClientID *string `json:"client_id,omitempty"`
-	Email    *string `json:"email,omitempty"`
-	IsActive *bool   `json:"is_active,omitempty"`
+	Name                       string  `json:"name" binding:"required"`
+	ClientID                   *string `json:"client_id,omitempty"`
+	Email                      *string `json:"email,omitempty"`
+	IsActive                   *bool   `json:"is_active,omitempty"`
+	Sandbox                    *bool   `json:"sandbox,omitempty"`
+	StrictPayloads             *bool   `json:"strict_payloads,omitempty"`
+	DeliveryFailureCallbackURL *string `json:"delivery_failure_callback_url,omitempty"`
 }
 
 // ClientResponse is the response payload for a client.
 type ClientResponse struct {
-	ID       string  `json:"id"`
-	Name     string  `json:"name"`
-	Email    *string `json:"email,omitempty"`
-	ClientID string  `json:"client_id"`
-	IsActive bool    `json:"is_active"`
+	ID                         string  `json:"id"`
+	Name                       string  `json:"name"`
+	Email                      *string `json:"email,omitempty"`
+	ClientID                   string  `json:"client_id"`
+	IsActive                   bool    `json:"is_active"`
+	Sandbox                    bool    `json:"sandbox"`
+	StrictPayloads             bool    `json:"strict_payloads"`
+	DeliveryFailureCallbackURL string  `json:"delivery_failure_callback_url,omitempty"`
 }
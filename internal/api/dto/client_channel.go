@@ -8,6 +8,23 @@ type ClientChannelCreateOrUpdateRequest struct {
 	ChannelType   models.ChannelType         `json:"channel_type" binding:"required"`
 	ChannelConfig map[string]interface{}     `json:"channel_config" binding:"required"`
 	IsActive      *bool                      `json:"is_active,omitempty"`
+	Sandbox       *bool                      `json:"sandbox,omitempty"`
+	// Capabilities overrides what the channel type can render; omit to keep the current setting
+	Capabilities *models.ChannelCapabilities `json:"capabilities,omitempty"`
+	// ThreadConfig overrides the client's enabled and inactivity_minutes threading settings for
+	// conversations on this channel; omit to keep the current setting
+	ThreadConfig *models.ThreadConfig `json:"thread_config,omitempty"`
+	// SlackAI routes a Slack channel's replies through a Slack AI workflow; omit to keep the
+	// current setting, or send an empty object to remove it
+	SlackAI *SlackAIConfigRequest `json:"slack_ai,omitempty"`
+}
+
+// SlackAIConfigRequest is a Slack channel's AI workflow. The token is encrypted before it is
+// stored and is never returned.
+type SlackAIConfigRequest struct {
+	URL        string `json:"url"`
+	WorkflowID string `json:"workflow_id"`
+	Token      string `json:"token"`
 }
 
 // ClientChannelResponse is the response payload for a client channel.
@@ -16,4 +33,9 @@ type ClientChannelResponse struct {
 	ChannelType   models.ChannelType         `json:"channel_type"`
 	ChannelConfig map[string]interface{}     `json:"channel_config"`
 	IsActive      bool                       `json:"is_active"`
+	Sandbox       bool                       `json:"sandbox"`
+	// Capabilities are the effective capabilities: the channel's override or its type's defaults
+	Capabilities models.ChannelCapabilities `json:"capabilities"`
+	ThreadConfig *models.ThreadConfig       `json:"thread_config,omitempty"`
+	SlackAI      *models.SlackAIConfig      `json:"slack_ai,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,72 @@
+// Package dto defines request/response payloads for contact endpoints.
+package dto
+
+import (
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// ContactCreate is the payload for POST /clients/:client_id/contacts.
+type ContactCreate struct {
+	Identities []models.ContactIdentity `json:"identities,omitempty"`
+	Name       string                   `json:"name,omitempty"`
+	Locale     string                   `json:"locale,omitempty"`
+	Attributes map[string]string        `json:"attributes,omitempty"`
+	Consent    map[string]bool          `json:"consent,omitempty"`
+}
+
+// ContactUpdate is the payload for PUT /clients/:client_id/contacts/:contact_id. Identities,
+// attributes and consent replace the contact's when given.
+type ContactUpdate struct {
+	Identities []models.ContactIdentity `json:"identities,omitempty"`
+	Name       *string                  `json:"name,omitempty"`
+	Locale     *string                  `json:"locale,omitempty"`
+	Attributes map[string]string        `json:"attributes,omitempty"`
+	Consent    map[string]bool          `json:"consent,omitempty"`
+}
+
+// ContactConsentRequest is the payload for PUT /clients/:client_id/contacts/:contact_id/consent.
+// Each purpose given is set, true opting in and false opting out; other purposes are kept.
+type ContactConsentRequest struct {
+	Consent map[string]bool `json:"consent" binding:"required"`
+}
+
+// ContactConsentResponse is the consent state of a contact.
+type ContactConsentResponse struct {
+	ContactID        string          `json:"contact_id"`
+	Consent          map[string]bool `json:"consent"`
+	ConsentUpdatedAt *time.Time      `json:"consent_updated_at,omitempty"`
+}
+
+// ContactListResponse is the response for GET /clients/:client_id/contacts.
+type ContactListResponse struct {
+	Contacts []models.Contact `json:"contacts"`
+	Total    int              `json:"total"`
+}
+
+// ContactMergeRequest is the payload for POST /clients/:client_id/contacts/:contact_id/merge,
+// which merges the source contact into the contact of the path.
+type ContactMergeRequest struct {
+	SourceContactID string `json:"source_contact_id" binding:"required"`
+}
+
+// ContactMergeResponse is the response to a merge.
+type ContactMergeResponse struct {
+	Contact       *models.Contact `json:"contact"`
+	SessionsMoved int64           `json:"sessions_moved"`
+	MessagesMoved int64           `json:"messages_moved"`
+}
+
+// ContactSessionsResponse is the response for GET /clients/:client_id/contacts/:contact_id/sessions.
+type ContactSessionsResponse struct {
+	Sessions []models.ChatSession `json:"sessions"`
+	Total    int64                `json:"total"`
+}
+
+// ContactHistoryPolicyRequest is the payload for PUT /clients/:client_id/contact-history.
+type ContactHistoryPolicyRequest struct {
+	Enabled     *bool `json:"enabled,omitempty"`
+	MaxSessions int   `json:"max_sessions,omitempty"`
+	MaxAgeDays  int   `json:"max_age_days,omitempty"`
+}
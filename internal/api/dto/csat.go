@@ -4,6 +4,7 @@ package dto
 import (
 	"time"
 
+	"github.com/fraiday-org/api-service/internal/models"
 )
 
 // CSATTriggerRequest represents a request to trigger a CSAT survey.
@@ -20,6 +21,39 @@ type CSATTriggerResponse struct {
 	Message       string    `json:"message"`
 }
 
+// CSATBulkTriggerRequest represents a request to trigger CSAT surveys for many sessions.
+// Either SessionIDs or ClientID (optionally narrowed by the other filters) must be set.
+type CSATBulkTriggerRequest struct {
+	Type          string     `json:"type" binding:"required"`
+	SessionIDs    []string   `json:"session_ids,omitempty"`
+	ClientID      string     `json:"client_id,omitempty"`
+	ChannelID     string     `json:"channel_id,omitempty"`
+	CreatedAfter  *time.Time `json:"created_after,omitempty"`
+	CreatedBefore *time.Time `json:"created_before,omitempty"`
+	Active        *bool      `json:"active,omitempty"`
+	Limit         int        `json:"limit,omitempty"`
+	RatePerMinute int        `json:"rate_per_minute,omitempty"`
+	DryRun        bool       `json:"dry_run,omitempty"`
+}
+
+// CSATBulkTriggerSkip describes a session that was not triggered and why.
+type CSATBulkTriggerSkip struct {
+	SessionID string `json:"session_id"`
+	Reason    string `json:"reason"`
+}
+
+// CSATBulkTriggerResponse summarises a bulk CSAT trigger.
+type CSATBulkTriggerResponse struct {
+	Matched             int                   `json:"matched"`
+	Triggered           int                   `json:"triggered"`
+	SkippedCount        int                   `json:"skipped_count"`
+	TriggeredSessionIDs []string              `json:"triggered_session_ids"`
+	Skipped             []CSATBulkTriggerSkip `json:"skipped"`
+	Reasons             map[string]int        `json:"reasons"`
+	SpreadSeconds       int                   `json:"spread_seconds"`
+	DryRun              bool                  `json:"dry_run"`
+}
+
 // CSATResponseRequest represents a request to respond to a CSAT question.
 type CSATResponseRequest struct {
 	SessionID        string `json:"session_id" validate:"required"`
@@ -39,6 +73,9 @@ type CSATConfigurationRequest struct {
 	Type              string                 `json:"type" validate:"required,min=1"`
 	Enabled           bool                   `json:"enabled"`
 	TriggerConditions map[string]interface{} `json:"trigger_conditions,omitempty"`
+	ReminderMinutes   int                    `json:"reminder_minutes,omitempty"`
+	ExpiryHours       int                    `json:"expiry_hours,omitempty"`
+	FallbackLocales   []string               `json:"fallback_locales,omitempty"`
 }
 
 // CSATConfigurationResponse represents a CSAT configuration response.
@@ -49,6 +86,9 @@ type CSATConfigurationResponse struct {
 	Type              string                 `json:"type"`
 	Enabled           bool                   `json:"enabled"`
 	TriggerConditions map[string]interface{} `json:"trigger_conditions,omitempty"`
+	ReminderMinutes   int                    `json:"reminder_minutes,omitempty"`
+	ExpiryHours       int                    `json:"expiry_hours,omitempty"`
+	FallbackLocales   []string               `json:"fallback_locales,omitempty"`
 	CreatedAt         time.Time              `json:"created_at"`
 	UpdatedAt         time.Time              `json:"updated_at"`
 }
@@ -59,6 +99,10 @@ type CSATQuestionRequest struct {
 	Options      []string `json:"options" validate:"required"`
 	Order        int      `json:"order" validate:"required"`
 	Active       bool     `json:"active"`
+	AnswerType   models.CSATAnswerType   `json:"answer_type,omitempty"`
+	MaxLength    int                     `json:"max_length,omitempty"`
+	Translations map[string]models.CSATQuestionTranslation `json:"translations,omitempty"`
+	Branches     []models.CSATBranchRule `json:"branches,omitempty"`
 }
 
 // CSATQuestionsRequest represents a request to update multiple CSAT questions.
@@ -74,6 +118,10 @@ type CSATQuestionResponse struct {
 	Options              []string  `json:"options"`
 	Order                int       `json:"order"`
 	Active               bool      `json:"active"`
+	AnswerType           models.CSATAnswerType   `json:"answer_type,omitempty"`
+	MaxLength            int                     `json:"max_length,omitempty"`
+	Translations         map[string]models.CSATQuestionTranslation `json:"translations,omitempty"`
+	Branches             []models.CSATBranchRule `json:"branches,omitempty"`
 	CreatedAt            time.Time `json:"created_at"`
 	UpdatedAt            time.Time `json:"updated_at"`
 }
@@ -90,12 +138,21 @@ type CSATSessionResponse struct {
 	Status               string     `json:"status"`
 	TriggeredAt          time.Time  `json:"triggered_at"`
 	CompletedAt          *time.Time `json:"completed_at,omitempty"`
+	ExpiredAt            *time.Time `json:"expired_at,omitempty"`
+	Locale               string     `json:"locale,omitempty"`
 	CurrentQuestionIndex int        `json:"current_question_index"`
 	QuestionsSent        []string   `json:"questions_sent"`
+	AverageScore         *float64   `json:"average_score,omitempty"` // Listing only; mean of rating and nps answers
 	CreatedAt            time.Time  `json:"created_at"`
 	UpdatedAt            time.Time  `json:"updated_at"`
 }
 
+// CSATSessionListResponse represents a page of CSAT sessions.
+type CSATSessionListResponse struct {
+	Sessions []CSATSessionResponse `json:"sessions"`
+	Total    int                   `json:"total"`
+}
+
 // CSATAnalyticsResponse represents CSAT analytics data.
 type CSATAnalyticsResponse struct {
 	TotalSurveys    int                    `json:"total_surveys"`
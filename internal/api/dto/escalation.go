@@ -0,0 +1,12 @@
+// Package dto defines request/response payloads for escalation policy endpoints.
+package dto
+
+// EscalationPolicyRequest is the payload for PUT /clients/:client_id/escalation-policy
+// and PUT /clients/:client_id/channels/:channel_id/escalation-policy.
+type EscalationPolicyRequest struct {
+	Enabled                  *bool    `json:"enabled,omitempty"`
+	ConfidenceThreshold      float64  `json:"confidence_threshold"`
+	ConsecutiveLowConfidence int      `json:"consecutive_low_confidence,omitempty"`
+	Keywords                 []string `json:"keywords,omitempty"`
+	TargetQueue              string   `json:"target_queue,omitempty"`
+}
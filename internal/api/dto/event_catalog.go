@@ -0,0 +1,17 @@
+package dto
+
+// EventCatalogResponse lists the events processors and webhooks receive, with JSON Schemas of the
+// envelope they are delivered in and of the data of each event type.
+type EventCatalogResponse struct {
+	Envelope    map[string]interface{} `json:"envelope"`
+	EntityTypes []string               `json:"entity_types"`
+	Events      []EventCatalogEntry    `json:"events"`
+}
+
+// EventCatalogEntry describes one event type.
+type EventCatalogEntry struct {
+	EventType     string                 `json:"event_type"`
+	EntityType    string                 `json:"entity_type"`
+	SchemaVersion int                    `json:"schema_version"`
+	Schema        map[string]interface{} `json:"schema"` // JSON Schema of the envelope's data
+}
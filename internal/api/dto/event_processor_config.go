@@ -10,7 +10,7 @@ import (
 // ProcessorConfigCreate represents the payload for creating an event processor config.
 type ProcessorConfigCreate struct {
 	Name         string                 `json:"name" binding:"required"`
-	ClientID     string                 `json:"client_id" binding:"required"`
+	ClientID     string                 `json:"client_id,omitempty"`
 	ProcessorType models.ProcessorType   `json:"processor_type" binding:"required"`
 	Config       map[string]interface{} `json:"config" binding:"required"`
 	EventTypes   []models.EventType     `json:"event_types" binding:"required"`
@@ -28,6 +28,8 @@ type ProcessorConfigUpdate struct {
 	EntityTypes  []models.EntityType    `json:"entity_types,omitempty"`
 	Description  *string                `json:"description,omitempty"`
 	IsActive     *bool                  `json:"is_active,omitempty"`
+	// Version, when set, rejects the update once the config has moved past that version
+	Version *int64 `json:"version,omitempty"`
 }
 
 // ProcessorConfigResponse represents the response payload for an event processor config.
@@ -41,6 +43,7 @@ type ProcessorConfigResponse struct {
 	EntityTypes  []string               `json:"entity_types"`
 	Description  *string                `json:"description,omitempty"`
 	IsActive     bool                   `json:"is_active"`
+	Version      int64                  `json:"version"`
 	CreatedAt    time.Time              `json:"created_at"`
 	UpdatedAt    time.Time              `json:"updated_at"`
 }
@@ -49,4 +52,14 @@ type ProcessorConfigResponse struct {
 type ProcessorConfigListResponse struct {
 	Configs []ProcessorConfigResponse `json:"configs"`
 	Total   int                       `json:"total"`
-}
\ No newline at end of file
+}
+
+// ProcessorConfigTestResponse is how a processor's endpoint answered a test event.
+type ProcessorConfigTestResponse struct {
+	EventID   string `json:"event_id"`
+	Success   bool   `json:"success"`
+	Status    int    `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Body      string `json:"body"`
+	Error     string `json:"error,omitempty"`
+}
@@ -0,0 +1,45 @@
+// Package dto defines request/response payloads for experiment endpoints.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// ExperimentCreate is the payload for POST /clients/:client_id/experiments.
+type ExperimentCreate struct {
+	Name        string                     `json:"name" binding:"required"`
+	Description string                     `json:"description,omitempty"`
+	Variants    []models.ExperimentVariant `json:"variants" binding:"required"`
+}
+
+// ExperimentUpdate is the payload for PUT /clients/:client_id/experiments/:experiment_id.
+type ExperimentUpdate struct {
+	Name        *string                    `json:"name,omitempty"`
+	Description *string                    `json:"description,omitempty"`
+	Variants    []models.ExperimentVariant `json:"variants,omitempty"`
+}
+
+// ExperimentListResponse is the response for GET /clients/:client_id/experiments.
+type ExperimentListResponse struct {
+	Experiments []models.Experiment `json:"experiments"`
+	Total       int                 `json:"total"`
+}
+
+// ExperimentVariantMetrics holds what one variant of an experiment produced. Low-confidence
+// replies are counted against the default threshold, so variants with their own are comparable.
+type ExperimentVariantMetrics struct {
+	Variant           string  `json:"variant"`
+	Weight            int     `json:"weight"`
+	Sessions          int64   `json:"sessions"`
+	AIReplies         int64   `json:"ai_replies"`
+	AverageConfidence float64 `json:"average_confidence"`
+	LowConfidence     int64   `json:"low_confidence"`
+	LowConfidenceRate float64 `json:"low_confidence_rate"`
+	CSATResponses     int64   `json:"csat_responses"`
+	CSATAverageScore  float64 `json:"csat_average_score"`
+}
+
+// ExperimentMetrics is the response for GET /clients/:client_id/experiments/:experiment_id/metrics.
+type ExperimentMetrics struct {
+	ExperimentID string                     `json:"experiment_id"`
+	Status       models.ExperimentStatus    `json:"status"`
+	Variants     []ExperimentVariantMetrics `json:"variants"`
+}
@@ -0,0 +1,46 @@
+// Package dto defines request/response payloads for feature flag endpoints.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// FeatureFlagCreate is the payload for POST /feature-flags.
+type FeatureFlagCreate struct {
+	Key string `json:"key" binding:"required"`
+	FeatureFlagUpdate
+}
+
+// FeatureFlagUpdate is the payload for PUT /feature-flags/:key; it replaces every setting.
+type FeatureFlagUpdate struct {
+	Description     string   `json:"description,omitempty"`
+	Enabled         bool     `json:"enabled"`
+	Clients         []string `json:"clients,omitempty"`
+	ExcludedClients []string `json:"excluded_clients,omitempty"`
+	Percentage      int      `json:"percentage"`
+}
+
+// ToModel returns the feature flag with key described by the payload.
+func (u FeatureFlagUpdate) ToModel(key string) *models.FeatureFlag {
+	return &models.FeatureFlag{
+		Key:             key,
+		Description:     u.Description,
+		Enabled:         u.Enabled,
+		Clients:         u.Clients,
+		ExcludedClients: u.ExcludedClients,
+		Percentage:      u.Percentage,
+	}
+}
+
+// FeatureFlagListResponse is the response for GET /feature-flags.
+type FeatureFlagListResponse struct {
+	FeatureFlags []models.FeatureFlag `json:"feature_flags"`
+	Total        int                  `json:"total"`
+}
+
+// FeatureFlagEvaluation is the response for GET /feature-flags/:key/evaluate.
+type FeatureFlagEvaluation struct {
+	Key      string `json:"key"`
+	ClientID string `json:"client_id"`
+	Enabled  bool   `json:"enabled"`
+	// Defined is false when no flag has the key; such flags are off
+	Defined bool `json:"defined"`
+}
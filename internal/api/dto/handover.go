@@ -0,0 +1,22 @@
+// Package dto defines request payloads for session handover endpoints.
+package dto
+
+// HandoverCreateRequest is the payload for POST /sessions/:session_id/handover.
+type HandoverCreateRequest struct {
+	Reason      string   `json:"reason,omitempty"`
+	TargetQueue string   `json:"target_queue,omitempty"`
+	TargetAgent string   `json:"target_agent,omitempty"`
+	Skills      []string `json:"skills,omitempty"`
+	RequestedBy string   `json:"requested_by,omitempty"`
+}
+
+// HandoverAgentRequest is the payload for agent accept/complete actions.
+type HandoverAgentRequest struct {
+	AgentID string `json:"agent_id" binding:"required"`
+}
+
+// HandoverDeclineRequest is the payload for POST /sessions/:session_id/handover/decline.
+type HandoverDeclineRequest struct {
+	AgentID string `json:"agent_id" binding:"required"`
+	Reason  string `json:"reason,omitempty"`
+}
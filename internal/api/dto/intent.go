@@ -0,0 +1,16 @@
+// Package dto defines request/response payloads for client intent routing endpoints.
+package dto
+
+// IntentRuleRequest is one routing rule in an IntentRoutingRequest.
+type IntentRuleRequest struct {
+	Intent        string  `json:"intent" binding:"required"`
+	MinConfidence float64 `json:"min_confidence,omitempty"`
+	Action        string  `json:"action" binding:"required"`
+	TargetQueue   string  `json:"target_queue,omitempty"`
+}
+
+// IntentRoutingRequest is the payload for PUT /clients/:client_id/intents.
+type IntentRoutingRequest struct {
+	Enabled *bool               `json:"enabled,omitempty"`
+	Rules   []IntentRuleRequest `json:"rules" binding:"required,dive"`
+}
@@ -0,0 +1,10 @@
+// Package dto defines request/response payloads for message import endpoints.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// MessageImportListResponse is the response for GET /imports/messages.
+type MessageImportListResponse struct {
+	Imports []models.MessageImport `json:"imports"`
+	Total   int                    `json:"total"`
+}
@@ -0,0 +1,12 @@
+// Package dto defines response payloads for the message long-polling endpoint.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// MessagePollResponse is the response for GET /sessions/:session_id/messages/poll.
+// Cursor is passed back as ?after= on the next poll.
+type MessagePollResponse struct {
+	Messages []models.ChatMessage `json:"messages"`
+	Events   []models.Event       `json:"events"`
+	Cursor   string               `json:"cursor"`
+}
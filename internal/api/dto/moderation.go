@@ -0,0 +1,12 @@
+// Package dto defines request/response payloads for client moderation endpoints.
+package dto
+
+// ModerationPolicyRequest is the payload for PUT /clients/:client_id/moderation.
+type ModerationPolicyRequest struct {
+	Enabled         *bool    `json:"enabled,omitempty"`
+	BlockedPhrases  []string `json:"blocked_phrases,omitempty"`
+	BlockedPatterns []string `json:"blocked_patterns,omitempty"`
+	APIURL          string   `json:"api_url,omitempty"`
+	APITimeoutMs    int      `json:"api_timeout_ms,omitempty"`
+	HandoverQueue   string   `json:"handover_queue" binding:"required"`
+}
@@ -0,0 +1,20 @@
+// Package dto defines request/response payloads for client post-processing hook endpoints.
+package dto
+
+// PostProcessingHookRequest is the payload for PUT /clients/:client_id/post-processing-hook.
+type PostProcessingHookRequest struct {
+	URL           string `json:"url" binding:"required"`
+	Secret        string `json:"secret,omitempty"`
+	TimeoutMs     int    `json:"timeout_ms,omitempty"`
+	FailurePolicy string `json:"failure_policy,omitempty"`
+	Enabled       *bool  `json:"enabled,omitempty"`
+}
+
+// PostProcessingHookResponse describes a registered hook. The secret is never returned.
+type PostProcessingHookResponse struct {
+	URL           string `json:"url"`
+	TimeoutMs     int    `json:"timeout_ms"`
+	FailurePolicy string `json:"failure_policy"`
+	Enabled       bool   `json:"enabled"`
+	HasSecret     bool   `json:"has_secret"`
+}
@@ -0,0 +1,33 @@
+// Package dto defines request/response payloads for prompt template endpoints.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// PromptTemplateCreate is the payload for POST /clients/:client_id/prompt-templates. Text becomes
+// the template's version 1.
+type PromptTemplateCreate struct {
+	Name        string            `json:"name" binding:"required"`
+	ChannelType string            `json:"channel_type,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Text        string            `json:"text" binding:"required"`
+	Defaults    map[string]string `json:"defaults,omitempty"`
+	Note        string            `json:"note,omitempty"`
+}
+
+// PromptTemplateVersionCreate is the payload for POST /clients/:client_id/prompt-templates/:template_id/versions.
+type PromptTemplateVersionCreate struct {
+	Text     string            `json:"text" binding:"required"`
+	Defaults map[string]string `json:"defaults,omitempty"`
+	Note     string            `json:"note,omitempty"`
+}
+
+// PromptTemplatePublish is the payload for POST /clients/:client_id/prompt-templates/:template_id/publish.
+type PromptTemplatePublish struct {
+	Version int `json:"version" binding:"required"`
+}
+
+// PromptTemplateListResponse is the response for GET /clients/:client_id/prompt-templates.
+type PromptTemplateListResponse struct {
+	PromptTemplates []models.PromptTemplate `json:"prompt_templates"`
+	Total           int                     `json:"total"`
+}
@@ -0,0 +1,46 @@
+// Package dto defines request/response payloads for operator repair endpoints.
+package dto
+
+import (
+	"time"
+)
+
+// RepairActionRequest is the payload for POST /admin/repairs.
+// Non dry-run requests must set Confirm to guard against accidental bulk changes.
+type RepairActionRequest struct {
+	Action           string     `json:"action" binding:"required"`
+	ProcessorID      string     `json:"processor_id,omitempty"`
+	From             *time.Time `json:"from,omitempty"`
+	To               *time.Time `json:"to,omitempty"`
+	OlderThanMinutes int        `json:"older_than_minutes,omitempty"`
+	Limit            int        `json:"limit,omitempty"`
+	DryRun           bool       `json:"dry_run"`
+	Confirm          bool       `json:"confirm"`
+}
+
+// RepairActionResponse describes a repair action and its outcome.
+type RepairActionResponse struct {
+	ID               string     `json:"id"`
+	Action           string     `json:"action"`
+	Status           string     `json:"status"`
+	DryRun           bool       `json:"dry_run"`
+	RequestedBy      string     `json:"requested_by"`
+	ProcessorID      string     `json:"processor_id,omitempty"`
+	From             *time.Time `json:"from,omitempty"`
+	To               *time.Time `json:"to,omitempty"`
+	OlderThanMinutes int        `json:"older_than_minutes,omitempty"`
+	Limit            int        `json:"limit"`
+	Matched          int        `json:"matched"`
+	Affected         int        `json:"affected"`
+	AffectedIDs      []string   `json:"affected_ids,omitempty"`
+	Error            string     `json:"error,omitempty"`
+	StartedAt        *time.Time `json:"started_at,omitempty"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+// RepairActionListResponse is the response for listing repair actions.
+type RepairActionListResponse struct {
+	Actions []RepairActionResponse `json:"actions"`
+	Total   int                    `json:"total"`
+}
@@ -0,0 +1,63 @@
+// Package dto defines request/response payloads for scheduled report endpoints.
+package dto
+
+import (
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// ReportScheduleCreate is the payload for POST /clients/:client_id/reports.
+type ReportScheduleCreate struct {
+	Name           string                 `json:"name" binding:"required"`
+	Enabled        *bool                  `json:"enabled,omitempty"` // Defaults to true
+	Frequency      models.ReportFrequency `json:"frequency" binding:"required"`
+	Weekday        time.Weekday           `json:"weekday"`
+	Hour           int                    `json:"hour"`
+	Timezone       string                 `json:"timezone,omitempty"`
+	EmailChannelID string                 `json:"email_channel_id,omitempty"`
+	Recipients     []string               `json:"recipients,omitempty"`
+	WebhookURL     string                 `json:"webhook_url,omitempty"`
+}
+
+// ReportScheduleUpdate is the payload for PUT /clients/:client_id/reports/:report_id. Omitted
+// fields are left as they are; an empty email_channel_id or webhook_url turns that delivery off.
+type ReportScheduleUpdate struct {
+	Name           *string                 `json:"name,omitempty"`
+	Enabled        *bool                   `json:"enabled,omitempty"`
+	Frequency      *models.ReportFrequency `json:"frequency,omitempty"`
+	Weekday        *time.Weekday           `json:"weekday,omitempty"`
+	Hour           *int                    `json:"hour,omitempty"`
+	Timezone       *string                 `json:"timezone,omitempty"`
+	EmailChannelID *string                 `json:"email_channel_id,omitempty"`
+	Recipients     []string                `json:"recipients,omitempty"`
+	WebhookURL     *string                 `json:"webhook_url,omitempty"`
+}
+
+// ReportScheduleListResponse is the response for GET /clients/:client_id/reports.
+type ReportScheduleListResponse struct {
+	Reports []models.ReportSchedule `json:"reports"`
+	Total   int                     `json:"total"`
+}
+
+// ReportRunResponse is the response for POST /clients/:client_id/reports/:report_id/run.
+type ReportRunResponse struct {
+	Queued    bool      `json:"queued"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
+
+// AnalyticsReport is the content of a scheduled report: a client's analytics and CSAT results
+// over a day or week.
+type AnalyticsReport struct {
+	ReportID    string                 `json:"report_id"`
+	Name        string                 `json:"name"`
+	ClientID    string                 `json:"client_id"`
+	ClientName  string                 `json:"client_name,omitempty"`
+	Frequency   models.ReportFrequency `json:"frequency"`
+	StartTime   time.Time              `json:"start_time"`
+	EndTime     time.Time              `json:"end_time"`
+	Performance *PerformanceMetrics    `json:"performance"`
+	Containment *ContainmentStats      `json:"containment"`
+	CSAT        *CSATSummary           `json:"csat"`
+}
@@ -0,0 +1,21 @@
+// Package dto defines request/response payloads for role management endpoints.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// RoleAssignmentUpdate is the payload for PUT /role-assignments/:subject.
+type RoleAssignmentUpdate struct {
+	Roles    []models.Role `json:"roles" binding:"required"`
+	ClientID string        `json:"client_id,omitempty"`
+}
+
+// RoleAssignmentListResponse is the response for GET /role-assignments.
+type RoleAssignmentListResponse struct {
+	RoleAssignments []models.RoleAssignment `json:"role_assignments"`
+	Total           int                     `json:"total"`
+}
+
+// APIKeyRolesUpdate is the payload for PUT /clients/:client_id/api-keys/:key_id/roles.
+type APIKeyRolesUpdate struct {
+	Roles []models.Role `json:"roles" binding:"required"`
+}
@@ -0,0 +1,19 @@
+// Package dto defines request/response payloads for simulation endpoints.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// SimulationCreate is the payload for POST /simulations.
+type SimulationCreate struct {
+	ClientID           string                  `json:"client_id" binding:"required"`
+	ChannelID          string                  `json:"channel_id,omitempty"`
+	Name               string                  `json:"name,omitempty"`
+	Script             []models.SimulationStep `json:"script" binding:"required"`
+	StepTimeoutSeconds int                     `json:"step_timeout_seconds,omitempty"` // Defaults to 60
+}
+
+// SimulationListResponse is the response for GET /simulations.
+type SimulationListResponse struct {
+	Simulations []models.Simulation `json:"simulations"`
+	Total       int                 `json:"total"`
+}
@@ -0,0 +1,36 @@
+// Package dto defines request/response payloads for client usage and quota endpoints.
+package dto
+
+import "github.com/fraiday-org/api-service/internal/models"
+
+// UsageQuotaRequest is the payload for PUT /clients/:client_id/quota. Omitted limits are unlimited.
+type UsageQuotaRequest struct {
+	Messages models.QuotaLimit `json:"messages"`
+	AICalls  models.QuotaLimit `json:"ai_calls"`
+}
+
+// ClientUsageResponse is the response payload for GET /clients/:client_id/usage.
+type ClientUsageResponse struct {
+	ClientID string             `json:"client_id"`
+	Period   string             `json:"period"` // The current month, YYYY-MM
+	Quota    *models.UsageQuota `json:"quota,omitempty"`
+	// QuotaStatus is "ok", "soft_limit_reached" or "hard_limit_reached" for each limited metric this month
+	QuotaStatus map[string]string    `json:"quota_status,omitempty"`
+	Months      []models.ClientUsage `json:"months"`
+}
+
+// UsageRecord is one row of a usage export: how much of one metric a client used on one day.
+type UsageRecord struct {
+	Date       string `json:"date"` // YYYY-MM-DD, UTC
+	ClientID   string `json:"client_id"`
+	ClientName string `json:"client_name"`
+	Metric     string `json:"metric"`
+	Quantity   int64  `json:"quantity"`
+}
+
+// UsageExportResponse is the JSON response payload for GET /usage/export.
+type UsageExportResponse struct {
+	StartDate string        `json:"start_date"`
+	EndDate   string        `json:"end_date"`
+	Records   []UsageRecord `json:"records"`
+}
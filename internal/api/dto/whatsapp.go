@@ -0,0 +1,13 @@
+// Package dto defines request/response payloads for the WhatsApp channel connector.
+package dto
+
+// WhatsAppTemplateSendRequest sends an approved template message, e.g. to notify a user outside
+// the 24-hour customer service window.
+type WhatsAppTemplateSendRequest struct {
+	To           string                   `json:"to" binding:"required"`
+	TemplateName string                   `json:"template_name" binding:"required"`
+	Language     string                   `json:"language,omitempty"`
+	Components   []map[string]interface{} `json:"components,omitempty"`
+	// Text is stored as the chat message text in place of the template name
+	Text string `json:"text,omitempty"`
+}
@@ -0,0 +1,68 @@
+// Package handlers provides HTTP handlers for per-client AI model and prompt configuration.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// ClientAIConfigHandler handles configuration of the AI model and prompt a client is answered with.
+type ClientAIConfigHandler struct {
+	Service *service.ClientAIConfigService
+}
+
+// NewClientAIConfigHandler creates a new ClientAIConfigHandler.
+func NewClientAIConfigHandler(svc *service.ClientAIConfigService) *ClientAIConfigHandler {
+	return &ClientAIConfigHandler{Service: svc}
+}
+
+// GetConfig handles GET /clients/:client_id/ai-config
+func (h *ClientAIConfigHandler) GetConfig(c *gin.Context) {
+	config, err := h.Service.GetConfig(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if config == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client has no AI config"})
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+// SetConfig handles PUT /clients/:client_id/ai-config
+func (h *ClientAIConfigHandler) SetConfig(c *gin.Context) {
+	var req dto.ClientAIConfigRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	config := &models.ClientAIConfig{
+		Enabled:      req.Enabled == nil || *req.Enabled,
+		Provider:     req.Provider,
+		Model:        req.Model,
+		Temperature:  req.Temperature,
+		SystemPrompt: req.SystemPrompt,
+		MaxTokens:    req.MaxTokens,
+	}
+	if err := h.Service.SetConfig(c.Request.Context(), c.Param("client_id"), config); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, config)
+}
+
+// DeleteConfig handles DELETE /clients/:client_id/ai-config
+func (h *ClientAIConfigHandler) DeleteConfig(c *gin.Context) {
+	if err := h.Service.DeleteConfig(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
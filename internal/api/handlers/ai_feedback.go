@@ -0,0 +1,65 @@
+// Package handlers provides HTTP handlers for forwarding answer feedback to the AI service.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// AIFeedbackHandler handles a client's AI feedback policy.
+type AIFeedbackHandler struct {
+	Service *service.AIFeedbackService
+}
+
+// NewAIFeedbackHandler creates a new AIFeedbackHandler.
+func NewAIFeedbackHandler(svc *service.AIFeedbackService) *AIFeedbackHandler {
+	return &AIFeedbackHandler{Service: svc}
+}
+
+// GetPolicy handles GET /clients/:client_id/ai-feedback
+func (h *AIFeedbackHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.Service.GetPolicy(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if policy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client has no AI feedback policy"})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetPolicy handles PUT /clients/:client_id/ai-feedback
+func (h *AIFeedbackHandler) SetPolicy(c *gin.Context) {
+	var req dto.AIFeedbackPolicyRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := &models.AIFeedbackPolicy{
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		IncludeComments: req.IncludeComments,
+		ContextMessages: req.ContextMessages,
+	}
+	if err := h.Service.SetPolicy(c.Request.Context(), c.Param("client_id"), policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy handles DELETE /clients/:client_id/ai-feedback
+func (h *AIFeedbackHandler) DeletePolicy(c *gin.Context) {
+	if err := h.Service.DeletePolicy(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -3,9 +3,13 @@ package handlers
 
 import (
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/service"
 )
 
@@ -59,20 +63,103 @@ func (h *AnalyticsHandler) GetBotEngagementMetrics(c *gin.Context) {
 
 // GetContainmentRateMetrics handles GET /analytics/containment-rate
 func (h *AnalyticsHandler) GetContainmentRateMetrics(c *gin.Context) {
-	startDateStr := c.Query("start_date")
-	endDateStr := c.Query("end_date")
-	aggregation := c.DefaultQuery("aggregation", "auto")
-	startDate, err := time.Parse(time.RFC3339, startDateStr)
+	filter, ok := analyticsFilter(c, "start_date", "end_date")
+	if !ok {
+		return
+	}
+	resp, err := h.Service.GetContainmentRateMetrics(c.Request.Context(), filter, c.DefaultQuery("aggregation", service.AggregationAuto))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetPerformanceMetrics handles GET /analytics/performance
+func (h *AnalyticsHandler) GetPerformanceMetrics(c *gin.Context) {
+	filter, ok := analyticsFilter(c, "start_time", "end_time")
+	if !ok {
+		return
+	}
+	metrics, err := h.Service.GetPerformanceMetrics(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.PerformanceMetricsResponse{Success: true, Data: metrics})
+}
+
+// GetVolumeHeatmap handles GET /analytics/volume
+func (h *AnalyticsHandler) GetVolumeHeatmap(c *gin.Context) {
+	filter, ok := analyticsFilter(c, "start_time", "end_time")
+	if !ok {
+		return
+	}
+	loc, err := time.LoadLocation(c.DefaultQuery("timezone", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid timezone"})
+		return
+	}
+	heatmap, err := h.Service.GetVolumeHeatmap(c.Request.Context(), filter, loc)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid start_date"})
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.VolumeHeatmapResponse{Success: true, Data: heatmap})
+}
+
+// GetConfidenceMetrics handles GET /analytics/confidence
+func (h *AnalyticsHandler) GetConfidenceMetrics(c *gin.Context) {
+	filter, ok := analyticsFilter(c, "start_time", "end_time")
+	if !ok {
 		return
 	}
-	endDate := time.Now().UTC()
-	if endDateStr != "" {
-		if t, err := time.Parse(time.RFC3339, endDateStr); err == nil {
-			endDate = t
+	threshold := service.DefaultConfidenceThreshold
+	if v := c.Query("threshold"); v != "" {
+		t, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid threshold"})
+			return
 		}
+		threshold = t
 	}
-	resp := h.Service.GetContainmentRateMetrics(startDate, endDate, aggregation)
-	c.JSON(http.StatusOK, resp)
+	metrics, err := h.Service.GetConfidenceMetrics(c.Request.Context(), filter, threshold, c.DefaultQuery("aggregation", service.AggregationAuto))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ConfidenceMetricsResponse{Success: true, Data: metrics})
+}
+
+// analyticsFilter reads the RFC3339 range in the startParam (required) and endParam (default now)
+// query parameters, and the optional client_id and channel_id. It writes a 400 and returns false
+// when they are invalid.
+func analyticsFilter(c *gin.Context, startParam, endParam string) (service.AnalyticsFilter, bool) {
+	startTime, err := time.Parse(time.RFC3339, c.Query(startParam))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid " + startParam})
+		return service.AnalyticsFilter{}, false
+	}
+	filter := service.AnalyticsFilter{
+		ClientID: c.Query("client_id"),
+		Start:    startTime,
+		End:      time.Now().UTC(),
+	}
+	if client := callerClient(c); client != nil {
+		filter.ClientID = client.ClientID
+	}
+	if endTimeStr := c.Query(endParam); endTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
+			filter.End = t
+		}
+	}
+	if v := c.Query("channel_id"); v != "" {
+		channelID, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid channel_id"})
+			return service.AnalyticsFilter{}, false
+		}
+		filter.ChannelID = &channelID
+	}
+	return filter, true
 }
@@ -0,0 +1,77 @@
+// Package handlers provides HTTP handlers for per-client AI answer caching.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// AnswerCacheHandler handles a client's answer cache policy and its cached answers.
+type AnswerCacheHandler struct {
+	Service *service.AnswerCacheService
+}
+
+// NewAnswerCacheHandler creates a new AnswerCacheHandler.
+func NewAnswerCacheHandler(svc *service.AnswerCacheService) *AnswerCacheHandler {
+	return &AnswerCacheHandler{Service: svc}
+}
+
+// GetPolicy handles GET /clients/:client_id/answer-cache
+func (h *AnswerCacheHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.Service.GetPolicy(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if policy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client has no answer cache policy"})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetPolicy handles PUT /clients/:client_id/answer-cache
+func (h *AnswerCacheHandler) SetPolicy(c *gin.Context) {
+	var req dto.AnswerCachePolicyRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := &models.AnswerCachePolicy{
+		Enabled:          req.Enabled == nil || *req.Enabled,
+		TTLSeconds:       req.TTLSeconds,
+		KnowledgeVersion: req.KnowledgeVersion,
+		MinConfidence:    req.MinConfidence,
+	}
+	if err := h.Service.SetPolicy(c.Request.Context(), c.Param("client_id"), policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy handles DELETE /clients/:client_id/answer-cache
+func (h *AnswerCacheHandler) DeletePolicy(c *gin.Context) {
+	if err := h.Service.DeletePolicy(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// InvalidateEntries handles DELETE /clients/:client_id/answer-cache/entries. The question query
+// parameter limits it to the answers to one question.
+func (h *AnswerCacheHandler) InvalidateEntries(c *gin.Context) {
+	deleted, err := h.Service.Invalidate(c.Request.Context(), c.Param("client_id"), c.Query("question"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.AnswerCacheInvalidateResponse{Deleted: deleted})
+}
@@ -0,0 +1,89 @@
+// Package handlers provides HTTP handlers for client API keys.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// APIKeyHandler handles issuing, listing, rotating and revoking a client's API keys.
+type APIKeyHandler struct {
+	Service *service.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new APIKeyHandler.
+func NewAPIKeyHandler(svc *service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{Service: svc}
+}
+
+// CreateAPIKey handles POST /clients/:client_id/api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req dto.APIKeyCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, secret, err := h.Service.CreateAPIKey(c.Request.Context(), c.Param("client_id"), req.Name, req.Scopes, req.Tier, req.ExpiresAt)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, dto.APIKeyIssued{APIKey: *key, Key: secret})
+}
+
+// ListAPIKeys handles GET /clients/:client_id/api-keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.Service.ListAPIKeys(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.APIKeyListResponse{APIKeys: keys, Total: len(keys)})
+}
+
+// RotateAPIKey handles POST /clients/:client_id/api-keys/:key_id/rotate
+func (h *APIKeyHandler) RotateAPIKey(c *gin.Context) {
+	var req dto.APIKeyRotate
+	if c.Request.ContentLength > 0 {
+		if err := bindJSON(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	grace := time.Duration(req.GracePeriodSeconds) * time.Second
+	key, secret, err := h.Service.RotateAPIKey(c.Request.Context(), c.Param("client_id"), c.Param("key_id"), grace)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, dto.APIKeyIssued{APIKey: *key, Key: secret})
+}
+
+// RevokeAPIKey handles DELETE /clients/:client_id/api-keys/:key_id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.Service.RevokeAPIKey(c.Request.Context(), c.Param("client_id"), c.Param("key_id")); err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// respondAPIKeyError maps API key service errors to HTTP responses.
+func respondAPIKeyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidAPIKeyRequest):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrAPIKeyNotFound), errors.Is(err, service.ErrAPIKeyClientNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
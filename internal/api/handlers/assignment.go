@@ -0,0 +1,123 @@
+// Package handlers provides HTTP handlers for agents, assignment rules and assignment history.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// AssignmentHandler handles agent availability and routing rule endpoints.
+type AssignmentHandler struct {
+	Service *service.AssignmentService
+}
+
+// NewAssignmentHandler creates a new AssignmentHandler.
+func NewAssignmentHandler(svc *service.AssignmentService) *AssignmentHandler {
+	return &AssignmentHandler{Service: svc}
+}
+
+// CreateAgent handles POST /clients/:client_id/agents
+func (h *AssignmentHandler) CreateAgent(c *gin.Context) {
+	var req dto.AgentCreateRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agent := &models.Agent{
+		AgentID:       req.AgentID,
+		Name:          req.Name,
+		Skills:        req.Skills,
+		Queues:        req.Queues,
+		Status:        models.AgentStatus(req.Status),
+		MaxConcurrent: req.MaxConcurrent,
+	}
+	if err := h.Service.RegisterAgent(c.Request.Context(), c.Param("client_id"), agent); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, agent)
+}
+
+// ListAgents handles GET /clients/:client_id/agents
+func (h *AssignmentHandler) ListAgents(c *gin.Context) {
+	agents, err := h.Service.ListAgents(c.Request.Context(), c.Param("client_id"), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, agents)
+}
+
+// UpdateAgentAvailability handles PUT /clients/:client_id/agents/:agent_id/availability
+func (h *AssignmentHandler) UpdateAgentAvailability(c *gin.Context) {
+	var req dto.AgentAvailabilityRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	agent, err := h.Service.SetAvailability(c.Request.Context(), c.Param("client_id"), c.Param("agent_id"), models.AgentStatus(req.Status), req.MaxConcurrent)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, agent)
+}
+
+// CreateAssignmentRule handles POST /clients/:client_id/assignment-rules
+func (h *AssignmentHandler) CreateAssignmentRule(c *gin.Context) {
+	var req dto.AssignmentRuleCreateRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := &models.AssignmentRule{
+		Name:           req.Name,
+		Priority:       req.Priority,
+		Queue:          req.Queue,
+		Strategy:       models.AssignmentStrategy(req.Strategy),
+		RequiredSkills: req.RequiredSkills,
+		Enabled:        req.Enabled == nil || *req.Enabled,
+	}
+	if err := h.Service.CreateRule(c.Request.Context(), c.Param("client_id"), rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, rule)
+}
+
+// ListAssignmentRules handles GET /clients/:client_id/assignment-rules
+func (h *AssignmentHandler) ListAssignmentRules(c *gin.Context) {
+	rules, err := h.Service.ListRules(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rules)
+}
+
+// DeleteAssignmentRule handles DELETE /clients/:client_id/assignment-rules/:rule_id
+func (h *AssignmentHandler) DeleteAssignmentRule(c *gin.Context) {
+	if err := h.Service.DeleteRule(c.Request.Context(), c.Param("client_id"), c.Param("rule_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// ListSessionAssignments handles GET /sessions/:session_id/assignments
+func (h *AssignmentHandler) ListSessionAssignments(c *gin.Context) {
+	assignments, err := h.Service.ListAssignments(c.Request.Context(), c.Param("session_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, assignments)
+}
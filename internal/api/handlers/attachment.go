@@ -0,0 +1,48 @@
+// Package handlers provides HTTP handlers for stored message attachments.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// AttachmentHandler serves files kept in attachment storage.
+type AttachmentHandler struct {
+	Service *service.AttachmentService
+}
+
+// NewAttachmentHandler creates a new AttachmentHandler.
+func NewAttachmentHandler(svc *service.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{Service: svc}
+}
+
+// GetAttachment handles GET /attachments/:attachment_id
+func (h *AttachmentHandler) GetAttachment(c *gin.Context) {
+	file, content, err := h.Service.Open(c.Param("attachment_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	defer content.Close()
+
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Length", strconv.FormatInt(file.Size, 10))
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": file.FileName}))
+	c.Status(http.StatusOK)
+	_, _ = io.Copy(c.Writer, content)
+}
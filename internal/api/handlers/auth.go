@@ -4,17 +4,22 @@ import (
 	"net/http"
 
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/api/middleware"
+	"github.com/fraiday-org/api-service/internal/oidc"
 )
 
 type AuthHandler struct {
-	logger *zap.Logger
+	logger   *zap.Logger
+	verifier *oidc.Verifier
 }
 
-func NewAuthHandler(logger *zap.Logger, db *mongo.Database) *AuthHandler {
+// NewAuthHandler creates an AuthHandler. verifier is nil when OIDC login is not configured.
+func NewAuthHandler(logger *zap.Logger, verifier *oidc.Verifier) *AuthHandler {
 	return &AuthHandler{
-		logger: logger,
+		logger:   logger,
+		verifier: verifier,
 	}
 }
 
@@ -22,3 +27,40 @@ func NewAuthHandler(logger *zap.Logger, db *mongo.Database) *AuthHandler {
 func (h *AuthHandler) Health(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 }
+
+// OIDCConfig handles GET /api/v1/auth/oidc/config. Dashboards read it to start a login with the
+// identity provider; it needs no authentication.
+func (h *AuthHandler) OIDCConfig(c *gin.Context) {
+	if h.verifier == nil {
+		c.JSON(http.StatusOK, gin.H{"enabled": false})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"enabled":   true,
+		"issuer":    h.verifier.Issuer,
+		"audience":  h.verifier.Audience,
+		"discovery": h.verifier.Issuer + "/.well-known/openid-configuration",
+	})
+}
+
+// Me handles GET /api/v1/auth/me, describing the caller's credentials, roles and, for dashboard
+// users, the identity read from their token.
+func (h *AuthHandler) Me(c *gin.Context) {
+	response := gin.H{"auth_type": c.GetString("auth_type")}
+	if value, ok := c.Get(middleware.ContextKeyUser); ok {
+		claims := value.(*oidc.Claims)
+		response["subject"] = claims.Subject
+		response["email"] = claims.Email
+		response["name"] = claims.Name
+		response["expires_at"] = claims.ExpiresAt
+	}
+	if value, ok := c.Get(middleware.ContextKeyPrincipal); ok {
+		// Roles include those assigned through /role-assignments, not only the token's
+		principal := value.(*middleware.Principal)
+		response["roles"] = principal.Roles
+		if len(principal.ClientRefs) > 0 {
+			response["client_id"] = principal.ClientRefs[0]
+		}
+	}
+	c.JSON(http.StatusOK, response)
+}
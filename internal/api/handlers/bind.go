@@ -0,0 +1,58 @@
+// Package handlers provides strict JSON binding shared by the API handlers.
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// strictFieldsHeader opts a single request into rejecting unknown JSON fields
+const strictFieldsHeader = "X-Strict-Fields"
+
+// unknownFieldsReporter is implemented by payloads with free-form maps whose keys are still checked in strict mode.
+type unknownFieldsReporter interface {
+	UnknownFields() []string
+}
+
+// bindJSON binds the request body into obj like ShouldBindJSON, keeping the body so it can be
+// re-checked once the client is known. Requests sent with X-Strict-Fields: true reject unknown fields.
+func bindJSON(c *gin.Context, obj interface{}) error {
+	if err := c.ShouldBindBodyWith(obj, binding.JSON); err != nil {
+		return err
+	}
+	if strings.EqualFold(c.GetHeader(strictFieldsHeader), "true") {
+		return checkUnknownFields(c, obj)
+	}
+	return nil
+}
+
+// checkUnknownFields rejects fields in the request body that obj doesn't declare, naming the first one.
+// obj must already have been bound with bindJSON.
+func checkUnknownFields(c *gin.Context, obj interface{}) error {
+	body, ok := c.Get(gin.BodyBytesKey)
+	if !ok {
+		return nil
+	}
+	raw, _ := body.([]byte)
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	target := reflect.New(reflect.TypeOf(obj).Elem()).Interface()
+	if err := decoder.Decode(target); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+
+	if r, ok := obj.(unknownFieldsReporter); ok {
+		if unknown := r.UnknownFields(); len(unknown) > 0 {
+			return fmt.Errorf("unknown field %q", unknown[0])
+		}
+	}
+	return nil
+}
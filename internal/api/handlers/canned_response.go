@@ -0,0 +1,142 @@
+// Package handlers provides HTTP handlers for client canned responses.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// CannedResponseHandler handles CRUD for a client's canned responses.
+type CannedResponseHandler struct {
+	Service *service.CannedResponseService
+}
+
+// NewCannedResponseHandler creates a new CannedResponseHandler.
+func NewCannedResponseHandler(svc *service.CannedResponseService) *CannedResponseHandler {
+	return &CannedResponseHandler{Service: svc}
+}
+
+// CreateCannedResponse handles POST /clients/:client_id/canned-responses
+func (h *CannedResponseHandler) CreateCannedResponse(c *gin.Context) {
+	var req dto.CannedResponseCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := models.ValidateAttachments(req.Attachments); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := &models.CannedResponse{
+		ClientID:    c.Param("client_id"),
+		Title:       req.Title,
+		Shortcut:    req.Shortcut,
+		Category:    req.Category,
+		Text:        req.Text,
+		Attachments: req.Attachments,
+		Data:        req.Data,
+	}
+	if err := h.Service.CreateCannedResponse(c.Request.Context(), resp); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// ListCannedResponses handles GET /clients/:client_id/canned-responses
+func (h *CannedResponseHandler) ListCannedResponses(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	responses, err := h.Service.ListCannedResponses(c.Request.Context(), c.Param("client_id"), c.Query("category"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.CannedResponseListResponse{
+		CannedResponses: responses,
+		Total:           len(responses),
+	})
+}
+
+// GetCannedResponse handles GET /clients/:client_id/canned-responses/:canned_response_id
+func (h *CannedResponseHandler) GetCannedResponse(c *gin.Context) {
+	resp, err := h.Service.GetCannedResponse(c.Request.Context(), c.Param("client_id"), c.Param("canned_response_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateCannedResponse handles PUT /clients/:client_id/canned-responses/:canned_response_id
+func (h *CannedResponseHandler) UpdateCannedResponse(c *gin.Context) {
+	var req dto.CannedResponseUpdate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := bson.M{}
+	if req.Title != nil {
+		update["title"] = *req.Title
+	}
+	if req.Shortcut != nil {
+		update["shortcut"] = *req.Shortcut
+	}
+	if req.Category != nil {
+		update["category"] = *req.Category
+	}
+	if req.Text != nil {
+		update["text"] = *req.Text
+	}
+	if req.Attachments != nil {
+		if err := models.ValidateAttachments(req.Attachments); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		update["attachments"] = req.Attachments
+	}
+	if req.Data != nil {
+		update["data"] = req.Data
+	}
+	if len(update) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	clientID, id := c.Param("client_id"), c.Param("canned_response_id")
+	if err := h.Service.UpdateCannedResponse(c.Request.Context(), clientID, id, update); err != nil {
+		if errors.Is(err, service.ErrCannedResponseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.Service.GetCannedResponse(c.Request.Context(), clientID, id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// DeleteCannedResponse handles DELETE /clients/:client_id/canned-responses/:canned_response_id
+func (h *CannedResponseHandler) DeleteCannedResponse(c *gin.Context) {
+	if err := h.Service.DeleteCannedResponse(c.Request.Context(), c.Param("client_id"), c.Param("canned_response_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
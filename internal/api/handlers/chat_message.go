@@ -2,6 +2,9 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
 
 	"strconv"
@@ -9,6 +12,7 @@ import (
 	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -20,6 +24,14 @@ type ChatMessageHandler struct {
 	SessionService       *service.ChatSessionService
 	ClientService        *service.ClientService
 	ClientChannelService *service.ClientChannelService
+	// LifecycleService, when set, applies the closed-session policy to incoming messages
+	LifecycleService *service.SessionLifecycleService
+	// CannedResponseService, when set, enables POST /messages/canned
+	CannedResponseService *service.CannedResponseService
+	// Clients, when set, serves the client and channel lookups of every message from memory
+	Clients service.ClientCache
+	// ThreadService, when set, enables group_by=thread on GET /messages
+	ThreadService *service.ChatSessionThreadService
 }
 
 // NewChatMessageHandler creates a new ChatMessageHandler.
@@ -35,62 +47,223 @@ func NewChatMessageHandler(svc *service.ChatMessageService, sessionSvc *service.
 // CreateMessage handles POST /messages
 func (h *ChatMessageHandler) CreateMessage(c *gin.Context) {
 	var req dto.ChatMessageCreate
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	msg, created, ok := h.createMessage(c, &req, &req)
+	if !ok {
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, msg)
+		return
+	}
+	c.JSON(createdStatus(c), msg)
+}
+
+// CreateCannedMessage handles POST /messages/canned
+func (h *ChatMessageHandler) CreateCannedMessage(c *gin.Context) {
+	if h.CannedResponseService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "canned responses are not available"})
+		return
+	}
+
+	var req dto.CannedMessageCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !allowsClient(c, req.ClientID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errClientForbidden})
+		return
+	}
+
+	canned, text, err := h.CannedResponseService.Expand(c.Request.Context(), req.ClientID, req.CannedResponseID, req.Variables)
+	if err != nil {
+		if errors.Is(err, service.ErrCannedResponseNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := req.Category
+	if category == "" {
+		category = string(models.MessageCategoryMessage)
+	}
+	data := req.Data
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	data["canned_response_id"] = canned.ID.Hex()
+
+	msg, created, ok := h.createMessage(c, &dto.ChatMessageCreate{
+		ExternalID:        req.ExternalID,
+		Sender:            req.Sender,
+		SenderName:        req.SenderName,
+		SenderType:        req.SenderType,
+		SessionID:         req.SessionID,
+		ClientID:          req.ClientID,
+		ClientChannelType: req.ClientChannelType,
+		Text:              text,
+		Attachments:       canned.Attachments,
+		Data:              data,
+		Category:          category,
+		Config:            req.Config,
+		ParentMessageID:   req.ParentMessageID,
+	}, &req)
+	if !ok {
+		return
+	}
+	if !created {
+		c.JSON(http.StatusOK, msg)
+		return
+	}
+
+	// The message is already sent; a lost usage count isn't worth failing the request
+	_ = h.CannedResponseService.RecordUsage(c.Request.Context(), canned.ID)
+	c.JSON(createdStatus(c), msg)
+}
+
+// getClient looks the client up through the cache when there is one.
+func (h *ChatMessageHandler) getClient(ctx context.Context, clientID string) (*models.Client, error) {
+	if h.Clients != nil {
+		return h.Clients.GetClient(ctx, clientID)
+	}
+	return h.ClientService.GetClient(ctx, clientID)
+}
+
+// getChannelByType looks the channel of client up through the cache when there is one.
+func (h *ChatMessageHandler) getChannelByType(ctx context.Context, client *models.Client, channelType string) (*models.ClientChannel, error) {
+	if h.Clients != nil {
+		return h.Clients.GetChannelByType(ctx, client.ID, channelType)
+	}
+	return h.ClientChannelService.GetChannelByType(ctx, client.ClientID, channelType)
+}
+
+// createMessage runs the shared create flow for req and writes an error response on failure.
+// bound is the payload as decoded from the request body, used for the client's strict field check.
+// A message whose external_id was already stored on the channel is a redelivery: the stored
+// message is returned with created false, and nothing else happens.
+func (h *ChatMessageHandler) createMessage(c *gin.Context, req *dto.ChatMessageCreate, bound interface{}) (*models.ChatMessage, bool, bool) {
 	// Validate sender type
 	if err := service.ValidateSenderType(req.SenderType); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-		return
+		return nil, false, false
+	}
+	if err := models.ValidateAttachments(req.Attachments); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false, false
+	}
+
+	var parentMessageID *primitive.ObjectID
+	if req.ParentMessageID != "" {
+		parentMessageID = service.ParseObjectID(req.ParentMessageID)
+		if parentMessageID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parent_message_id"})
+			return nil, false, false
+		}
 	}
 
 	// Step 1: Client validation (matching Python logic)
-	client, err := h.ClientService.GetClient(c.Request.Context(), req.ClientID)
+	if !allowsClient(c, req.ClientID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errClientForbidden})
+		return nil, false, false
+	}
+	client, err := h.getClient(c.Request.Context(), req.ClientID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "client not found"})
-		return
+		return nil, false, false
 	}
 	if !client.IsActive {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "client is not active"})
-		return
+		return nil, false, false
+	}
+	if client.StrictPayloads {
+		if err := checkUnknownFields(c, bound); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false, false
+		}
 	}
 
 	// Step 2: Client channel resolution (matching Python logic)
-	clientChannel, err := h.ClientChannelService.GetChannelByType(c.Request.Context(), req.ClientID, req.ClientChannelType)
+	clientChannel, err := h.getChannelByType(c.Request.Context(), client, req.ClientChannelType)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "client channel not found"})
-		return
+		return nil, false, false
 	}
 	if !clientChannel.IsActive {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "client channel is not active"})
-		return
+		return nil, false, false
+	}
+
+	// Redeliveries are answered before they can touch the session
+	var dedupKey string
+	if req.ExternalID != "" {
+		dedupKey = models.MessageDedupKey(client.ID, clientChannel.ID, req.ExternalID)
+		existing, err := h.Service.FindDuplicate(c.Request.Context(), dedupKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return nil, false, false
+		}
+		if existing != nil {
+			telemetry.ObserveMessageDedup(string(clientChannel.ChannelType))
+			return existing, false, true
+		}
 	}
 
 	// Step 3: Get or create session with client/channel association and threading support (matching Python logic)
 	session, effectiveSessionID, err := h.SessionService.GetOrCreateSessionBySessionID(c.Request.Context(), req.SessionID, client, clientChannel)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get or create session"})
-		return
+		return nil, false, false
+	}
+	if h.LifecycleService != nil {
+		session, err = h.LifecycleService.ResolveForMessage(c.Request.Context(), session, client, clientChannel)
+		if err != nil {
+			if errors.Is(err, service.ErrSessionClosed) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return nil, false, false
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve closed session"})
+			return nil, false, false
+		}
+		effectiveSessionID = session.SessionID
 	}
 
 	msg := &models.ChatMessage{
-		ExternalID:  req.ExternalID,
-		Sender:      req.Sender,
-		SenderName:  req.SenderName,
-		SenderType:  req.SenderType,
-		SessionID:   session.ID, // Use the session's MongoDB _id
-		Text:        req.Text,
-		Attachments: req.Attachments,
-		Data:        req.Data,
-		Category:    models.MessageCategory(req.Category),
-		Config:      req.Config,
+		ExternalID:      req.ExternalID,
+		DedupKey:        dedupKey,
+		Sender:          req.Sender,
+		SenderName:      req.SenderName,
+		SenderType:      req.SenderType,
+		SessionID:       session.ID, // Use the session's MongoDB _id
+		ParentMessageID: parentMessageID,
+		Text:            req.Text,
+		Attachments:     req.Attachments,
+		Data:            req.Data,
+		Category:        models.MessageCategory(req.Category),
+		Config:          req.Config,
 	}
 
 	if err := h.Service.CreateChatMessage(c.Request.Context(), msg); err != nil {
+		if errors.Is(err, service.ErrMessageDuplicate) {
+			telemetry.ObserveMessageDedup(string(clientChannel.ChannelType))
+			return msg, false, true
+		}
+		if errors.Is(err, service.ErrParentMessageNotFound) || errors.Is(err, service.ErrParentMessageSession) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return nil, false, false
+		}
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return nil, false, false
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
+		return nil, false, false
 	}
 
 	// Background workflow triggers (AI chat/suggestion) - AFTER message is saved
@@ -100,17 +273,43 @@ func (h *ChatMessageHandler) CreateMessage(c *gin.Context) {
 	if aiOk && aiEnabled && (!suggestionOk || !suggestionMode) {
 		// AI chat workflow - message should now have ID assigned by database
 		messageID := msg.ID.Hex() // msg.ID is now populated after successful creation
-		service.TriggerChatWorkflow(c.Request.Context(), messageID, effectiveSessionID)
+		markDegraded(c, service.TriggerChatWorkflow(c.Request.Context(), messageID, effectiveSessionID))
 	} else if suggestionOk && suggestionMode && (!aiOk || !aiEnabled) {
 		// Suggestion workflow - message should now have ID assigned by database
 		messageID := msg.ID.Hex() // msg.ID is now populated after successful creation
-		service.TriggerSuggestionWorkflow(c.Request.Context(), messageID, effectiveSessionID)
+		markDegraded(c, service.TriggerSuggestionWorkflow(c.Request.Context(), messageID, effectiveSessionID))
+	}
+
+	return msg, true, true
+}
+
+// Headers telling clients that the workflow of a new message was degraded by backpressure
+const (
+	degradedModeHeader = "X-Degraded-Mode"
+	queueDepthHeader   = "X-Queue-Depth"
+)
+
+// markDegraded adds the degraded-mode headers to the response when backpressure degraded the
+// workflow of the message being created.
+func markDegraded(c *gin.Context, degradation service.Degradation) {
+	if !degradation.Degraded() {
+		return
 	}
+	c.Header(degradedModeHeader, degradation.Mode)
+	c.Header(queueDepthHeader, strconv.Itoa(degradation.QueueDepth))
+}
 
-	c.JSON(http.StatusCreated, msg)
+// createdStatus is 201 for a created message, or 202 when its workflow was degraded, since its
+// reply or suggestions are late or won't come.
+func createdStatus(c *gin.Context) int {
+	if c.Writer.Header().Get(degradedModeHeader) != "" {
+		return http.StatusAccepted
+	}
+	return http.StatusCreated
 }
 
-// ListMessages handles GET /messages
+// ListMessages handles GET /messages. With group_by=thread the messages of session_id's
+// conversation are returned grouped by thread.
 func (h *ChatMessageHandler) ListMessages(c *gin.Context) {
 	sessionIDStr := c.Query("session_id")
 	userID := c.Query("user_id")
@@ -122,6 +321,40 @@ func (h *ChatMessageHandler) ListMessages(c *gin.Context) {
 		}
 	}
 
+	// Callers limited to a client list the messages of one of its sessions
+	if callerClient(c) != nil {
+		sessionID := service.ParseObjectID(sessionIDStr)
+		if sessionID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "session_id is required"})
+			return
+		}
+		if !allowsSession(c, h.SessionService, *sessionID) {
+			return
+		}
+	}
+
+	if groupBy := c.Query("group_by"); groupBy != "" {
+		if groupBy != "thread" || h.ThreadService == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported group_by"})
+			return
+		}
+		if sessionIDStr == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "group_by=thread requires session_id"})
+			return
+		}
+		resp, err := h.ThreadService.ListMessagesByThread(c.Request.Context(), sessionIDStr, lastN)
+		if err != nil {
+			if errors.Is(err, service.ErrThreadNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
 	var sessionID *primitive.ObjectID
 	if sessionIDStr != "" {
 		sessionID = service.ParseObjectID(sessionIDStr)
@@ -140,9 +373,36 @@ func (h *ChatMessageHandler) ListMessages(c *gin.Context) {
 	c.JSON(http.StatusOK, messages)
 }
 
-// UpdateMessage handles PUT /messages/:id
+// ListReplies handles GET /messages/:message_id/replies
+func (h *ChatMessageHandler) ListReplies(c *gin.Context) {
+	id := service.ParseObjectID(c.Param("message_id"))
+	if id == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
+		return
+	}
+
+	limit := int64(0)
+	if n := c.Query("limit"); n != "" {
+		if parsed, err := strconv.ParseInt(n, 10, 64); err == nil {
+			limit = parsed
+		}
+	}
+
+	replies, err := h.Service.ListReplies(c.Request.Context(), *id, limit)
+	if err != nil {
+		if errors.Is(err, service.ErrParentMessageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, replies)
+}
+
+// UpdateMessage handles PUT /messages/:message_id
 func (h *ChatMessageHandler) UpdateMessage(c *gin.Context) {
-	idStr := c.Param("id")
+	idStr := c.Param("message_id")
 	id := service.ParseObjectID(idStr)
 	if id == nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message id"})
@@ -150,7 +410,7 @@ func (h *ChatMessageHandler) UpdateMessage(c *gin.Context) {
 	}
 
 	var req dto.ChatMessageUpdate
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -166,6 +426,10 @@ func (h *ChatMessageHandler) UpdateMessage(c *gin.Context) {
 		update["sender_name"] = *req.SenderName
 	}
 	if req.Attachments != nil {
+		if err := models.ValidateAttachments(req.Attachments); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		update["attachments"] = req.Attachments
 	}
 	if req.Category != nil {
@@ -188,7 +452,7 @@ func (h *ChatMessageHandler) UpdateMessage(c *gin.Context) {
 // BulkCreateMessages handles POST /messages/bulk
 func (h *ChatMessageHandler) BulkCreateMessages(c *gin.Context) {
 	var req dto.BulkChatMessageCreate
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -198,24 +462,47 @@ func (h *ChatMessageHandler) BulkCreateMessages(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session_id"})
 		return
 	}
+	if !allowsSession(c, h.SessionService, *sessionID) {
+		return
+	}
 
 	msgs := make([]models.ChatMessage, len(req.Messages))
 	for i, m := range req.Messages {
+		var parentMessageID *primitive.ObjectID
+		if m.ParentMessageID != "" {
+			if parentMessageID = service.ParseObjectID(m.ParentMessageID); parentMessageID == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parent_message_id"})
+				return
+			}
+		}
+		if err := models.ValidateAttachments(m.Attachments); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("messages[%d]: %s", i, err)})
+			return
+		}
 		msgs[i] = models.ChatMessage{
-			ExternalID:  m.ExternalID,
-			Sender:      m.Sender,
-			SenderName:  m.SenderName,
-			SenderType:  m.SenderType,
-			SessionID:   *sessionID,
-			Text:        m.Text,
-			Attachments: m.Attachments,
-			Data:        m.Data,
-			Category:    models.MessageCategory(m.Category),
-			Config:      m.Config,
+			ExternalID:      m.ExternalID,
+			Sender:          m.Sender,
+			SenderName:      m.SenderName,
+			SenderType:      m.SenderType,
+			SessionID:       *sessionID,
+			ParentMessageID: parentMessageID,
+			Text:            m.Text,
+			Attachments:     m.Attachments,
+			Data:            m.Data,
+			Category:        models.MessageCategory(m.Category),
+			Config:          m.Config,
 		}
 	}
 
 	if err := h.Service.BulkCreateChatMessages(c.Request.Context(), msgs); err != nil {
+		if errors.Is(err, service.ErrParentMessageNotFound) || errors.Is(err, service.ErrParentMessageSession) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrQuotaExceeded) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -230,11 +517,11 @@ func (h *ChatMessageHandler) BulkCreateMessages(c *gin.Context) {
 		messageID := latest.ID.Hex()
 		sessionID := latest.SessionID.Hex()
 		if aiOk && aiEnabled && (!suggestionOk || !suggestionMode) {
-			service.TriggerChatWorkflow(c.Request.Context(), messageID, sessionID)
+			markDegraded(c, service.TriggerChatWorkflow(c.Request.Context(), messageID, sessionID))
 		} else if suggestionOk && suggestionMode && (!aiOk || !aiEnabled) {
-			service.TriggerSuggestionWorkflow(c.Request.Context(), messageID, sessionID)
+			markDegraded(c, service.TriggerSuggestionWorkflow(c.Request.Context(), messageID, sessionID))
 		}
 	}
 
-	c.Status(http.StatusCreated)
+	c.Status(createdStatus(c))
 }
@@ -23,7 +23,7 @@ func NewChatMessageFeedbackHandler(svc *service.ChatMessageFeedbackService) *Cha
 func (h *ChatMessageFeedbackHandler) CreateFeedback(c *gin.Context) {
 	messageID := c.Param("message_id")
 	var req dto.ChatMessageFeedbackCreate
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -75,11 +75,11 @@ func (h *ChatMessageFeedbackHandler) UpdateFeedback(c *gin.Context) {
 		Comment  *string                `json:"comment,omitempty"`
 		Metadata map[string]interface{} `json:"metadata,omitempty"`
 	}
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	updated, err := h.Service.UpdateFeedback(c.Request.Context(), feedbackID, req.Rating, req.Comment, req.Metadata)
+	updated, err := h.Service.UpdateFeedback(c.Request.Context(), c.Param("message_id"), feedbackID, req.Rating, req.Comment, req.Metadata)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
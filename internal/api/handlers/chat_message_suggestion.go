@@ -0,0 +1,92 @@
+// Package handlers provides HTTP handlers for reviewing AI suggestions.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// ChatMessageSuggestionHandler handles agent feedback on suggestions and its analytics.
+type ChatMessageSuggestionHandler struct {
+	Service *service.ChatMessageSuggestionService
+}
+
+// NewChatMessageSuggestionHandler creates a new ChatMessageSuggestionHandler.
+func NewChatMessageSuggestionHandler(svc *service.ChatMessageSuggestionService) *ChatMessageSuggestionHandler {
+	return &ChatMessageSuggestionHandler{Service: svc}
+}
+
+// AcceptSuggestion handles POST /suggestions/:suggestion_id/accept
+func (h *ChatMessageSuggestionHandler) AcceptSuggestion(c *gin.Context) {
+	var req dto.SuggestionAcceptRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestion, err := h.Service.AcceptSuggestion(c.Request.Context(), c.Param("suggestion_id"), req.Text, req.AgentID)
+	if err != nil {
+		respondSuggestionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// RejectSuggestion handles POST /suggestions/:suggestion_id/reject
+func (h *ChatMessageSuggestionHandler) RejectSuggestion(c *gin.Context) {
+	var req dto.SuggestionRejectRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	suggestion, err := h.Service.RejectSuggestion(c.Request.Context(), c.Param("suggestion_id"), req.Reason, req.AgentID)
+	if err != nil {
+		respondSuggestionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, suggestion)
+}
+
+// GetSuggestionStats handles GET /analytics/suggestions
+func (h *ChatMessageSuggestionHandler) GetSuggestionStats(c *gin.Context) {
+	startTime, err := time.Parse(time.RFC3339, c.Query("start_time"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "Invalid start_time"})
+		return
+	}
+	endTime := time.Now().UTC()
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		if t, err := time.Parse(time.RFC3339, endTimeStr); err == nil {
+			endTime = t
+		}
+	}
+
+	clientID := c.Query("client_id")
+	if client := callerClient(c); client != nil {
+		clientID = client.ClientID
+	}
+	stats, err := h.Service.AcceptanceStats(c.Request.Context(), clientID, startTime, endTime)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.SuggestionStatsResponse{Success: true, Data: stats})
+}
+
+func respondSuggestionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSuggestionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSuggestionReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -2,13 +2,16 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/service"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // ChatSessionHandler provides HTTP handlers for chat sessions.
@@ -56,6 +59,10 @@ func (h *ChatSessionHandler) ListSessions(c *gin.Context) {
 	if v := c.Query("client_id"); v != "" {
 		clientID = &v
 	}
+	if client := callerClient(c); client != nil {
+		v := client.ID.Hex()
+		clientID = &v
+	}
 	if v := c.Query("client_channel"); v != "" {
 		clientChannel = &v
 	}
@@ -101,9 +108,16 @@ func (h *ChatSessionHandler) ListSessions(c *gin.Context) {
 		Active:        active,
 		StartDate:     startDate,
 		EndDate:       endDate,
+		Attributes:    c.QueryMap("attributes"), // ?attributes[plan]=gold
 		Skip:          skip,
 		Limit:         limit,
 	}
+	if v := c.Query("tags"); v != "" {
+		params.Tags = strings.Split(v, ",")
+	}
+	if v := c.Query("state"); v != "" {
+		params.State = &v
+	}
 	resp, err := h.Service.ListSessions(c.Request.Context(), params)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -111,3 +125,44 @@ func (h *ChatSessionHandler) ListSessions(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, resp)
 }
+
+// UpdateTags handles PATCH /sessions/:session_id/tags
+func (h *ChatSessionHandler) UpdateTags(c *gin.Context) {
+	var req dto.ChatSessionTagsUpdateRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.Service.UpdateTags(c.Request.Context(), c.Param("session_id"), req.Version, req.Add, req.Remove)
+	if err != nil {
+		respondSessionUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// UpdateAttributes handles PATCH /sessions/:session_id/attributes
+func (h *ChatSessionHandler) UpdateAttributes(c *gin.Context) {
+	var req dto.ChatSessionAttributesUpdateRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.Service.UpdateAttributes(c.Request.Context(), c.Param("session_id"), req.Version, req.Attributes)
+	if err != nil {
+		respondSessionUpdateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func respondSessionUpdateError(c *gin.Context, err error) {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "chat session not found"})
+		return
+	}
+	if respondVersionConflict(c, err) {
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
@@ -22,7 +22,7 @@ func NewChatSessionRecapHandler(svc *service.ChatSessionRecapService) *ChatSessi
 func (h *ChatSessionRecapHandler) GenerateRecap(c *gin.Context) {
 	sessionID := c.Param("session_id")
 	var recapData map[string]interface{}
-	if err := c.ShouldBindJSON(&recapData); err != nil {
+	if err := bindJSON(c, &recapData); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
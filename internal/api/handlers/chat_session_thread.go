@@ -2,10 +2,12 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/service"
 )
 
@@ -83,3 +85,66 @@ func (h *ChatSessionThreadHandler) CloseThread(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "info", "message": "No active thread found to close"})
 	}
 }
+
+// ListThreadMessages handles GET /sessions/:session_id/threads/:thread_id/messages
+func (h *ChatSessionThreadHandler) ListThreadMessages(c *gin.Context) {
+	lastN := int64(0)
+	if n := c.Query("last_n"); n != "" {
+		if parsed, err := strconv.ParseInt(n, 10, 64); err == nil {
+			lastN = parsed
+		}
+	}
+	messages, err := h.Service.ListThreadMessages(c.Request.Context(), c.Param("session_id"), c.Param("thread_id"), lastN)
+	if err != nil {
+		threadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, messages)
+}
+
+// MergeThread handles POST /sessions/:session_id/threads/:thread_id/merge
+func (h *ChatSessionThreadHandler) MergeThread(c *gin.Context) {
+	var req dto.ThreadMergeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.Service.MergeThread(c.Request.Context(), c.Param("session_id"), c.Param("thread_id"), req.TargetThreadID, performedBy(c, req.PerformedBy))
+	if err != nil {
+		threadError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// SplitThread handles POST /sessions/:session_id/threads/:thread_id/split
+func (h *ChatSessionThreadHandler) SplitThread(c *gin.Context) {
+	var req dto.ThreadSplitRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.Service.SplitThread(c.Request.Context(), c.Param("session_id"), c.Param("thread_id"), req.AfterMessageID, performedBy(c, req.PerformedBy))
+	if err != nil {
+		threadError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+// performedBy names the caller of a thread change for its audit event: the agent named in the
+// request, or how the request was authenticated and where it came from.
+func performedBy(c *gin.Context, named string) string {
+	if named != "" {
+		return named
+	}
+	return c.GetString("auth_type") + "@" + c.ClientIP()
+}
+
+func threadError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrThreadNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
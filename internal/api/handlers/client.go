@@ -22,7 +22,7 @@ func NewClientHandler(svc *service.ClientService) *ClientHandler {
 // CreateClient handles POST /clients
 func (h *ClientHandler) CreateClient(c *gin.Context) {
 	var req dto.ClientCreateOrUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -48,7 +48,7 @@ func (h *ClientHandler) ListClients(c *gin.Context) {
 func (h *ClientHandler) UpdateClient(c *gin.Context) {
 	clientID := c.Param("client_id")
 	var req dto.ClientCreateOrUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
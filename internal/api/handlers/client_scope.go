@@ -0,0 +1,64 @@
+// Package handlers provides helpers keeping callers that are limited to one client to its records.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fraiday-org/api-service/internal/api/middleware"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errClientForbidden is the answer to callers naming another client's records
+const errClientForbidden = "not allowed for this client"
+
+// callerClient returns the client the caller is limited to, or nil when it may address every client.
+// Authorize has already checked route parameters and the client_id query against it; handlers
+// check what request bodies name and limit listings.
+func callerClient(c *gin.Context) *models.Client {
+	value, ok := c.Get(middleware.ContextKeyClient)
+	if !ok {
+		return nil
+	}
+	return value.(*models.Client)
+}
+
+// allowsClient reports whether the caller may address the client ref, by id or by client_id.
+func allowsClient(c *gin.Context, ref string) bool {
+	client := callerClient(c)
+	return client == nil || ref == client.ID.Hex() || ref == client.ClientID
+}
+
+// allowsOwner reports whether the caller may address records of the client with id owner.
+// Records of no client are only open to callers that may address every client.
+func allowsOwner(c *gin.Context, owner *primitive.ObjectID) bool {
+	client := callerClient(c)
+	return client == nil || (owner != nil && *owner == client.ID)
+}
+
+// allowsSession reports whether the caller may address the chat session with ObjectID sessionID.
+// When it may not, the request is answered: 404 for sessions that don't exist, 403 for those of
+// other clients.
+func allowsSession(c *gin.Context, sessions *service.ChatSessionService, sessionID primitive.ObjectID) bool {
+	if callerClient(c) == nil {
+		return true
+	}
+	session, err := sessions.Repo.GetByID(c.Request.Context(), sessionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !allowsOwner(c, session.Client) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errClientForbidden})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,243 @@
+// Package handlers provides HTTP handlers for client contacts.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// ContactHandler handles a client's contacts and their history.
+type ContactHandler struct {
+	Service *service.ContactService
+}
+
+// NewContactHandler creates a new ContactHandler.
+func NewContactHandler(svc *service.ContactService) *ContactHandler {
+	return &ContactHandler{Service: svc}
+}
+
+// CreateContact handles POST /clients/:client_id/contacts
+func (h *ContactHandler) CreateContact(c *gin.Context) {
+	var req dto.ContactCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contact, err := h.Service.CreateContact(c.Request.Context(), c.Param("client_id"), &req)
+	if err != nil {
+		contactError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, contact)
+}
+
+// ListContacts handles GET /clients/:client_id/contacts. channel_id and external_id together
+// look a contact up by the ID a channel knows it by.
+func (h *ContactHandler) ListContacts(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	var identity *models.ContactIdentity
+	if channelID, externalID := c.Query("channel_id"), c.Query("external_id"); channelID != "" || externalID != "" {
+		id := service.ParseObjectID(channelID)
+		if id == nil || externalID == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "channel_id and external_id must be given together"})
+			return
+		}
+		identity = &models.ContactIdentity{ChannelID: *id, ExternalID: externalID}
+	}
+
+	contacts, err := h.Service.ListContacts(c.Request.Context(), c.Param("client_id"), identity, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ContactListResponse{
+		Contacts: contacts,
+		Total:    len(contacts),
+	})
+}
+
+// GetContact handles GET /clients/:client_id/contacts/:contact_id
+func (h *ContactHandler) GetContact(c *gin.Context) {
+	contact, err := h.Service.GetContact(c.Request.Context(), c.Param("client_id"), c.Param("contact_id"))
+	if err != nil {
+		contactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, contact)
+}
+
+// UpdateContact handles PUT /clients/:client_id/contacts/:contact_id
+func (h *ContactHandler) UpdateContact(c *gin.Context) {
+	var req dto.ContactUpdate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contact, err := h.Service.UpdateContact(c.Request.Context(), c.Param("client_id"), c.Param("contact_id"), &req)
+	if err != nil {
+		contactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, contact)
+}
+
+// DeleteContact handles DELETE /clients/:client_id/contacts/:contact_id
+func (h *ContactHandler) DeleteContact(c *gin.Context) {
+	if err := h.Service.DeleteContact(c.Request.Context(), c.Param("client_id"), c.Param("contact_id")); err != nil {
+		contactError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// MergeContact handles POST /clients/:client_id/contacts/:contact_id/merge
+func (h *ContactHandler) MergeContact(c *gin.Context) {
+	var req dto.ContactMergeRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp, err := h.Service.MergeContacts(c.Request.Context(), c.Param("client_id"), c.Param("contact_id"), req.SourceContactID)
+	if err != nil {
+		contactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ListContactSessions handles GET /clients/:client_id/contacts/:contact_id/sessions
+func (h *ContactHandler) ListContactSessions(c *gin.Context) {
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 64)
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 64)
+
+	sessions, total, err := h.Service.ListSessions(c.Request.Context(), c.Param("client_id"), c.Param("contact_id"), limit, offset)
+	if err != nil {
+		contactError(c, err)
+		return
+	}
+	if sessions == nil {
+		sessions = []models.ChatSession{}
+	}
+	c.JSON(http.StatusOK, dto.ContactSessionsResponse{Sessions: sessions, Total: total})
+}
+
+// ListContactMessages handles GET /clients/:client_id/contacts/:contact_id/messages
+func (h *ContactHandler) ListContactMessages(c *gin.Context) {
+	lastN := int64(0)
+	if n := c.Query("last_n"); n != "" {
+		if parsed, err := strconv.ParseInt(n, 10, 64); err == nil {
+			lastN = parsed
+		}
+	}
+
+	messages, err := h.Service.ListMessages(c.Request.Context(), c.Param("client_id"), c.Param("contact_id"), lastN)
+	if err != nil {
+		contactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, messages)
+}
+
+// GetConsent handles GET /clients/:client_id/contacts/:contact_id/consent
+func (h *ContactHandler) GetConsent(c *gin.Context) {
+	contact, err := h.Service.GetContact(c.Request.Context(), c.Param("client_id"), c.Param("contact_id"))
+	if err != nil {
+		contactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, consentResponse(contact))
+}
+
+// SetConsent handles PUT /clients/:client_id/contacts/:contact_id/consent
+func (h *ContactHandler) SetConsent(c *gin.Context) {
+	var req dto.ContactConsentRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	contact, err := h.Service.SetConsent(c.Request.Context(), c.Param("client_id"), c.Param("contact_id"), req.Consent)
+	if err != nil {
+		contactError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, consentResponse(contact))
+}
+
+func consentResponse(contact *models.Contact) dto.ContactConsentResponse {
+	consent := contact.Consent
+	if consent == nil {
+		consent = map[string]bool{}
+	}
+	return dto.ContactConsentResponse{
+		ContactID:        contact.ID.Hex(),
+		Consent:          consent,
+		ConsentUpdatedAt: contact.ConsentUpdatedAt,
+	}
+}
+
+// GetHistoryPolicy handles GET /clients/:client_id/contact-history
+func (h *ContactHandler) GetHistoryPolicy(c *gin.Context) {
+	policy, err := h.Service.GetHistoryPolicy(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if policy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client has no contact history policy"})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetHistoryPolicy handles PUT /clients/:client_id/contact-history
+func (h *ContactHandler) SetHistoryPolicy(c *gin.Context) {
+	var req dto.ContactHistoryPolicyRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := &models.ContactHistoryPolicy{
+		Enabled:     req.Enabled == nil || *req.Enabled,
+		MaxSessions: req.MaxSessions,
+		MaxAgeDays:  req.MaxAgeDays,
+	}
+	if err := h.Service.SetHistoryPolicy(c.Request.Context(), c.Param("client_id"), policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteHistoryPolicy handles DELETE /clients/:client_id/contact-history
+func (h *ContactHandler) DeleteHistoryPolicy(c *gin.Context) {
+	if err := h.Service.DeleteHistoryPolicy(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func contactError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrContactNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrContactIdentityTaken), errors.Is(err, service.ErrContactMerged):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -2,12 +2,18 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 
 	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/service"
 )
 
@@ -26,13 +32,21 @@ func NewCSATHandler(csatService *service.CSATService) *CSATHandler {
 // TriggerCSAT triggers a CSAT survey for a chat session.
 func (h *CSATHandler) TriggerCSAT(c *gin.Context) {
 	var req dto.CSATTriggerRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if !h.allowsChatSession(c, req.SessionID) {
+		return
+	}
+
 	// Trigger CSAT survey using external session_id and type
 	session, err := h.CSATService.TriggerCSATSurveyBySessionID(c.Request.Context(), req.SessionID, req.Type)
+	if errors.Is(err, service.ErrCSATConsentDenied) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -48,18 +62,97 @@ func (h *CSATHandler) TriggerCSAT(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// BulkTriggerCSAT handles POST /api/v1/csat/trigger/bulk.
+// Sessions are resolved and checked up front; eligible ones are enqueued as throttled csat_trigger tasks.
+func (h *CSATHandler) BulkTriggerCSAT(c *gin.Context) {
+	var req dto.CSATBulkTriggerRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	params := service.BulkCSATTriggerParams{
+		Type:          req.Type,
+		SessionIDs:    req.SessionIDs,
+		CreatedAfter:  req.CreatedAfter,
+		CreatedBefore: req.CreatedBefore,
+		Active:        req.Active,
+		Limit:         req.Limit,
+		RatePerMinute: req.RatePerMinute,
+		DryRun:        req.DryRun,
+	}
+	if req.ClientID != "" {
+		clientID, err := primitive.ObjectIDFromHex(req.ClientID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client_id"})
+			return
+		}
+		params.ClientID = &clientID
+	}
+	if client := callerClient(c); client != nil {
+		if params.ClientID != nil && *params.ClientID != client.ID {
+			c.JSON(http.StatusForbidden, gin.H{"error": errClientForbidden})
+			return
+		}
+		params.ClientID = &client.ID
+	}
+	if req.ChannelID != "" {
+		channelID, err := primitive.ObjectIDFromHex(req.ChannelID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid channel_id"})
+			return
+		}
+		params.ChannelID = &channelID
+	}
+
+	result, err := h.CSATService.BulkTriggerCSAT(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	skipped := make([]dto.CSATBulkTriggerSkip, 0, len(result.Skipped))
+	for _, skip := range result.Skipped {
+		skipped = append(skipped, dto.CSATBulkTriggerSkip{SessionID: skip.SessionID, Reason: skip.Reason})
+	}
+
+	status := http.StatusAccepted
+	if result.DryRun {
+		status = http.StatusOK
+	}
+
+	c.JSON(status, dto.CSATBulkTriggerResponse{
+		Matched:             result.Matched,
+		Triggered:           result.Triggered,
+		SkippedCount:        len(result.Skipped),
+		TriggeredSessionIDs: result.TriggeredSessionIDs,
+		Skipped:             skipped,
+		Reasons:             result.Reasons,
+		SpreadSeconds:       int(result.Duration.Seconds()),
+		DryRun:              result.DryRun,
+	})
+}
+
 // RespondToCSAT handles a user response to a CSAT question.
 func (h *CSATHandler) RespondToCSAT(c *gin.Context) {
 	var req dto.CSATResponseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
+	if !h.allowsChatSession(c, req.SessionID) {
+		return
+	}
+
 	// Process response using external session_id
 	responseID, err := h.CSATService.ProcessResponseBySessionID(c.Request.Context(), req.SessionID, req.CSATQuestionID, req.ResponseValue)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidCSATAnswer) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -86,9 +179,9 @@ func (h *CSATHandler) RespondToCSAT(c *gin.Context) {
 
 // GetCSATSession retrieves a CSAT session by ID.
 func (h *CSATHandler) GetCSATSession(c *gin.Context) {
-	sessionID, err := primitive.ObjectIDFromHex(c.Param("session_id"))
+	sessionID, err := primitive.ObjectIDFromHex(c.Param("csat_session_id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid session_id"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid csat_session_id"})
 		return
 	}
 
@@ -98,13 +191,131 @@ func (h *CSATHandler) GetCSATSession(c *gin.Context) {
 		return
 	}
 
+	c.JSON(http.StatusOK, csatSessionResponse(session))
+}
+
+// ListCSATSessions handles GET /api/v1/csat/sessions
+func (h *CSATHandler) ListCSATSessions(c *gin.Context) {
+	filter, err := csatSessionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	skip := int64(0)
+	limit := int64(20)
+	if v := c.Query("skip"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n >= 0 {
+			skip = n
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil && n > 0 && n <= 100 {
+			limit = n
+		}
+	}
+
+	summaries, total, err := h.CSATService.ListCSATSessions(c.Request.Context(), filter, skip, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp := dto.CSATSessionListResponse{
+		Sessions: make([]dto.CSATSessionResponse, len(summaries)),
+		Total:    int(total),
+	}
+	for i, summary := range summaries {
+		resp.Sessions[i] = csatSessionResponse(&summary.Session)
+		resp.Sessions[i].AverageScore = summary.AverageScore
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ExportCSATResponses handles GET /api/v1/csat/responses/export
+func (h *CSATHandler) ExportCSATResponses(c *gin.Context) {
+	filter, err := csatSessionFilterFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="csat_responses_%s.csv"`, time.Now().UTC().Format("20060102T150405Z")))
+	c.Status(http.StatusOK)
+	if err := h.CSATService.ExportCSATResponses(c.Request.Context(), filter, c.Writer); err != nil {
+		// The header row is already out, so the status can't change; cut the body short instead
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// allowsChatSession reports whether the caller may survey the chat session with external
+// sessionID, answering the request when it may not.
+func (h *CSATHandler) allowsChatSession(c *gin.Context, sessionID string) bool {
+	if callerClient(c) == nil {
+		return true
+	}
+	session, err := h.CSATService.ChatSessionBySessionID(c.Request.Context(), sessionID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+		return false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return false
+	}
+	if !allowsOwner(c, session.Client) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errClientForbidden})
+		return false
+	}
+	return true
+}
+
+// csatSessionFilterFromQuery reads the status, client_id, channel_id, start_date, end_date,
+// min_score and max_score query parameters shared by CSAT session listing and export.
+func csatSessionFilterFromQuery(c *gin.Context) (service.CSATSessionFilter, error) {
+	filter := service.CSATSessionFilter{Status: c.Query("status")}
+	for param, target := range map[string]**primitive.ObjectID{"client_id": &filter.ClientID, "channel_id": &filter.ChannelID} {
+		if v := c.Query(param); v != "" {
+			id, err := primitive.ObjectIDFromHex(v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", param)
+			}
+			*target = &id
+		}
+	}
+	if client := callerClient(c); client != nil {
+		filter.ClientID = &client.ID
+	}
+	for param, target := range map[string]**time.Time{"start_date": &filter.StartDate, "end_date": &filter.EndDate} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s, expected RFC 3339", param)
+			}
+			*target = &t
+		}
+	}
+	for param, target := range map[string]**float64{"min_score": &filter.MinScore, "max_score": &filter.MaxScore} {
+		if v := c.Query(param); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", param)
+			}
+			*target = &f
+		}
+	}
+	return filter, nil
+}
+
+// csatSessionResponse converts a CSAT session to its API representation.
+func csatSessionResponse(session *models.CSATSession) dto.CSATSessionResponse {
 	// Convert ObjectIDs to strings for QuestionsSent
 	var questionsSentStrings []string
 	for _, questionID := range session.QuestionsSent {
 		questionsSentStrings = append(questionsSentStrings, questionID.Hex())
 	}
 
-	response := dto.CSATSessionResponse{
+	return dto.CSATSessionResponse{
 		ID:                   session.ID.Hex(),
 		ChatSessionID:        session.ChatSessionID,
 		CSATConfigurationID:  session.CSATConfigurationID.Hex(),
@@ -115,11 +326,11 @@ func (h *CSATHandler) GetCSATSession(c *gin.Context) {
 		Status:               session.Status,
 		TriggeredAt:          session.TriggeredAt,
 		CompletedAt:          session.CompletedAt,
+		ExpiredAt:            session.ExpiredAt,
+		Locale:               session.Locale,
 		CurrentQuestionIndex: session.CurrentQuestionIndex,
 		QuestionsSent:        questionsSentStrings,
 		CreatedAt:            session.CreatedAt,
 		UpdatedAt:            session.UpdatedAt,
 	}
-
-	c.JSON(http.StatusOK, response)
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
 	"github.com/fraiday-org/api-service/internal/utils"
 )
 
@@ -44,6 +45,9 @@ func (h *CSATHandler) ListCSATConfigurations(c *gin.Context) {
 			Type:              config.Type,
 			Enabled:           config.Enabled,
 			TriggerConditions: config.TriggerConditions,
+			ReminderMinutes:   config.ReminderMinutes,
+			ExpiryHours:       config.ExpiryHours,
+			FallbackLocales:   config.FallbackLocales,
 			CreatedAt:         config.CreatedAt,
 			UpdatedAt:         config.UpdatedAt,
 		}
@@ -68,7 +72,7 @@ func (h *CSATHandler) CreateCSATConfiguration(c *gin.Context) {
 	}
 
 	var req dto.CSATConfigurationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -79,6 +83,15 @@ func (h *CSATHandler) CreateCSATConfiguration(c *gin.Context) {
 		return
 	}
 
+	if req.ReminderMinutes < 0 || req.ExpiryHours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reminder_minutes and expiry_hours must not be negative"})
+		return
+	}
+	if err := service.ValidateCSATFallbackLocales(req.FallbackLocales); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Check if configuration already exists for this type
 	existing, _ := h.CSATService.CSATConfigRepo.GetByClientChannelAndType(c.Request.Context(), clientID, channelID, req.Type)
 	if existing != nil {
@@ -93,6 +106,9 @@ func (h *CSATHandler) CreateCSATConfiguration(c *gin.Context) {
 		Type:              req.Type,
 		Enabled:           req.Enabled,
 		TriggerConditions: req.TriggerConditions,
+		ReminderMinutes:   req.ReminderMinutes,
+		ExpiryHours:       req.ExpiryHours,
+		FallbackLocales:   req.FallbackLocales,
 	}
 
 	if err := h.CSATService.CSATConfigRepo.Create(c.Request.Context(), config); err != nil {
@@ -107,6 +123,9 @@ func (h *CSATHandler) CreateCSATConfiguration(c *gin.Context) {
 		Type:              config.Type,
 		Enabled:           config.Enabled,
 		TriggerConditions: config.TriggerConditions,
+		ReminderMinutes:   config.ReminderMinutes,
+		ExpiryHours:       config.ExpiryHours,
+		FallbackLocales:   config.FallbackLocales,
 		CreatedAt:         config.CreatedAt,
 		UpdatedAt:         config.UpdatedAt,
 	}
@@ -147,6 +166,9 @@ func (h *CSATHandler) GetCSATConfigurationByType(c *gin.Context) {
 		Type:              config.Type,
 		Enabled:           config.Enabled,
 		TriggerConditions: config.TriggerConditions,
+		ReminderMinutes:   config.ReminderMinutes,
+		ExpiryHours:       config.ExpiryHours,
+		FallbackLocales:   config.FallbackLocales,
 		CreatedAt:         config.CreatedAt,
 		UpdatedAt:         config.UpdatedAt,
 	}
@@ -175,7 +197,7 @@ func (h *CSATHandler) UpdateCSATConfigurationByType(c *gin.Context) {
 	}
 
 	var req dto.CSATConfigurationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -186,6 +208,15 @@ func (h *CSATHandler) UpdateCSATConfigurationByType(c *gin.Context) {
 		return
 	}
 
+	if req.ReminderMinutes < 0 || req.ExpiryHours < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reminder_minutes and expiry_hours must not be negative"})
+		return
+	}
+	if err := service.ValidateCSATFallbackLocales(req.FallbackLocales); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Get existing configuration
 	config, err := h.CSATService.CSATConfigRepo.GetByClientChannelAndType(c.Request.Context(), clientID, channelID, csatType)
 	if err != nil {
@@ -196,6 +227,9 @@ func (h *CSATHandler) UpdateCSATConfigurationByType(c *gin.Context) {
 	// Update configuration
 	config.Enabled = req.Enabled
 	config.TriggerConditions = req.TriggerConditions
+	config.ReminderMinutes = req.ReminderMinutes
+	config.ExpiryHours = req.ExpiryHours
+	config.FallbackLocales = req.FallbackLocales
 
 	if err := h.CSATService.CSATConfigRepo.Update(c.Request.Context(), config); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -209,6 +243,9 @@ func (h *CSATHandler) UpdateCSATConfigurationByType(c *gin.Context) {
 		Type:              config.Type,
 		Enabled:           config.Enabled,
 		TriggerConditions: config.TriggerConditions,
+		ReminderMinutes:   config.ReminderMinutes,
+		ExpiryHours:       config.ExpiryHours,
+		FallbackLocales:   config.FallbackLocales,
 		CreatedAt:         config.CreatedAt,
 		UpdatedAt:         config.UpdatedAt,
 	}
@@ -295,6 +332,10 @@ func (h *CSATHandler) GetCSATQuestionsByType(c *gin.Context) {
 			Options:              question.Options,
 			Order:                question.Order,
 			Active:               question.Active,
+			AnswerType:           question.AnswerType,
+			MaxLength:            question.MaxLength,
+			Translations:         question.Translations,
+			Branches:             question.Branches,
 			CreatedAt:            question.CreatedAt,
 			UpdatedAt:            question.UpdatedAt,
 		}
@@ -325,7 +366,7 @@ func (h *CSATHandler) UpdateCSATQuestionsByType(c *gin.Context) {
 	}
 
 	var req dto.CSATQuestionsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -346,10 +387,19 @@ func (h *CSATHandler) UpdateCSATQuestionsByType(c *gin.Context) {
 			Options:             questionReq.Options,
 			Order:               questionReq.Order,
 			Active:              questionReq.Active,
+			AnswerType:          questionReq.AnswerType,
+			MaxLength:           questionReq.MaxLength,
+			Translations:        questionReq.Translations,
+			Branches:            questionReq.Branches,
 		}
 		questions = append(questions, question)
 	}
 
+	if err := service.ValidateCSATQuestions(questions); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Update questions for this configuration (transactional)
 	if err := h.CSATService.CSATQuestionRepo.UpdateQuestionsForConfiguration(c.Request.Context(), config.ID, questions); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -372,6 +422,10 @@ func (h *CSATHandler) UpdateCSATQuestionsByType(c *gin.Context) {
 			Options:              question.Options,
 			Order:                question.Order,
 			Active:               question.Active,
+			AnswerType:           question.AnswerType,
+			MaxLength:            question.MaxLength,
+			Translations:         question.Translations,
+			Branches:             question.Branches,
 			CreatedAt:            question.CreatedAt,
 			UpdatedAt:            question.UpdatedAt,
 		}
@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// DocsHandler serves the API specs generated from the registered routes and events.
+type DocsHandler struct {
+	specs *service.APISpecService
+}
+
+// NewDocsHandler creates a new DocsHandler
+func NewDocsHandler(specs *service.APISpecService) *DocsHandler {
+	return &DocsHandler{specs: specs}
+}
+
+// OpenAPI handles GET /docs/openapi.json
+func (h *DocsHandler) OpenAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.specs.OpenAPI())
+}
+
+// AsyncAPI handles GET /docs/asyncapi.json
+func (h *DocsHandler) AsyncAPI(c *gin.Context) {
+	c.JSON(http.StatusOK, h.specs.AsyncAPI())
+}
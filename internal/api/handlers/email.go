@@ -0,0 +1,65 @@
+// Package handlers provides HTTP handlers for the email channel connector.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+const (
+	// maxSendGridParseBytes bounds Inbound Parse posts, which SendGrid caps at 30MB including attachments
+	maxSendGridParseBytes = 30 << 20
+	// maxSNSBodyBytes bounds SNS notifications, which SNS caps at 256KB
+	maxSNSBodyBytes = 1 << 20
+)
+
+// EmailHandler receives inbound email from SendGrid and SES.
+type EmailHandler struct {
+	Service *service.EmailService
+}
+
+// NewEmailHandler creates a new EmailHandler.
+func NewEmailHandler(svc *service.EmailService) *EmailHandler {
+	return &EmailHandler{Service: svc}
+}
+
+// HandleSendGrid handles POST /channels/email/:channel_id/sendgrid
+func (h *EmailHandler) HandleSendGrid(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxSendGridParseBytes)
+	if err := c.Request.ParseMultipartForm(32 << 20); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid inbound parse request"})
+		return
+	}
+	defer c.Request.MultipartForm.RemoveAll()
+
+	err := h.Service.HandleSendGrid(c.Request.Context(), c.Param("channel_id"), c.Query("token"), c.Request.MultipartForm)
+	h.respond(c, err)
+}
+
+// HandleSES handles POST /channels/email/:channel_id/ses
+func (h *EmailHandler) HandleSES(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSNSBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+	h.respond(c, h.Service.HandleSES(c.Request.Context(), c.Param("channel_id"), c.Query("token"), body))
+}
+
+func (h *EmailHandler) respond(c *gin.Context, err error) {
+	switch {
+	case err == nil:
+		c.Status(http.StatusOK)
+	case errors.Is(err, service.ErrEmailUnauthorized):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrEmailChannelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
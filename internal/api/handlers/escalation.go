@@ -0,0 +1,105 @@
+// Package handlers provides HTTP handlers for client and channel escalation policies.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// EscalationHandler handles configuration of escalation policies.
+type EscalationHandler struct {
+	Service *service.EscalationService
+}
+
+// NewEscalationHandler creates a new EscalationHandler.
+func NewEscalationHandler(svc *service.EscalationService) *EscalationHandler {
+	return &EscalationHandler{Service: svc}
+}
+
+// GetClientPolicy handles GET /clients/:client_id/escalation-policy
+func (h *EscalationHandler) GetClientPolicy(c *gin.Context) {
+	policy, err := h.Service.GetClientPolicy(c.Request.Context(), c.Param("client_id"))
+	respondEscalationPolicy(c, policy, err, "client has no escalation policy")
+}
+
+// SetClientPolicy handles PUT /clients/:client_id/escalation-policy
+func (h *EscalationHandler) SetClientPolicy(c *gin.Context) {
+	policy, ok := bindEscalationPolicy(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.SetClientPolicy(c.Request.Context(), c.Param("client_id"), policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteClientPolicy handles DELETE /clients/:client_id/escalation-policy
+func (h *EscalationHandler) DeleteClientPolicy(c *gin.Context) {
+	if err := h.Service.DeleteClientPolicy(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetChannelPolicy handles GET /clients/:client_id/channels/:channel_id/escalation-policy
+func (h *EscalationHandler) GetChannelPolicy(c *gin.Context) {
+	policy, err := h.Service.GetChannelPolicy(c.Request.Context(), c.Param("client_id"), c.Param("channel_id"))
+	respondEscalationPolicy(c, policy, err, "channel has no escalation policy")
+}
+
+// SetChannelPolicy handles PUT /clients/:client_id/channels/:channel_id/escalation-policy
+func (h *EscalationHandler) SetChannelPolicy(c *gin.Context) {
+	policy, ok := bindEscalationPolicy(c)
+	if !ok {
+		return
+	}
+	if err := h.Service.SetChannelPolicy(c.Request.Context(), c.Param("client_id"), c.Param("channel_id"), policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeleteChannelPolicy handles DELETE /clients/:client_id/channels/:channel_id/escalation-policy
+func (h *EscalationHandler) DeleteChannelPolicy(c *gin.Context) {
+	if err := h.Service.DeleteChannelPolicy(c.Request.Context(), c.Param("client_id"), c.Param("channel_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func bindEscalationPolicy(c *gin.Context) (*models.EscalationPolicy, bool) {
+	var req dto.EscalationPolicyRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &models.EscalationPolicy{
+		Enabled:                  req.Enabled == nil || *req.Enabled,
+		ConfidenceThreshold:      req.ConfidenceThreshold,
+		ConsecutiveLowConfidence: req.ConsecutiveLowConfidence,
+		Keywords:                 req.Keywords,
+		TargetQueue:              req.TargetQueue,
+	}, true
+}
+
+func respondEscalationPolicy(c *gin.Context, policy *models.EscalationPolicy, err error, missing string) {
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if policy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": missing})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
@@ -2,12 +2,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/api/middleware"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/secrets"
 	"github.com/fraiday-org/api-service/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // EventProcessorConfigHandler handles event processor config related HTTP requests.
@@ -22,6 +27,16 @@ func NewEventProcessorConfigHandler(processorConfigService *service.EventProcess
 	}
 }
 
+// errInsecureTLSForbidden is returned when a caller without the system permission turns off
+// verification of a webhook's certificate
+const errInsecureTLSForbidden = "only admins can set tls.insecure_skip_verify"
+
+// isSystemAdmin reports whether the caller holds the system permission.
+func isSystemAdmin(c *gin.Context) bool {
+	value, ok := c.Get(middleware.ContextKeyPrincipal)
+	return ok && value.(*middleware.Principal).Can(models.PermissionSystem)
+}
+
 // CreateProcessorConfig handles POST /api/v1/clients/{client_id}/processor-configs
 func (h *EventProcessorConfigHandler) CreateProcessorConfig(c *gin.Context) {
 	clientID := c.Param("client_id")
@@ -31,16 +46,54 @@ func (h *EventProcessorConfigHandler) CreateProcessorConfig(c *gin.Context) {
 	}
 
 	var req dto.ProcessorConfigCreate
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	req.ClientID = clientID
 
-	// TODO: Convert DTO to service parameters and call appropriate create method
-	// This is a placeholder - needs proper implementation based on processor type
-	c.JSON(http.StatusNotImplemented, gin.H{"error": "CreateProcessorConfig not yet implemented"})
+	clientOID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client_id"})
+		return
+	}
+	if (&models.EventProcessorConfig{Config: req.Config}).InsecureTLS() && !isSystemAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errInsecureTLSForbidden})
+		return
+	}
+
+	config, err := h.processorConfigService.CreateConfig(
+		c.Request.Context(),
+		req.Name,
+		req.Description,
+		clientOID,
+		req.ProcessorType,
+		req.Config,
+		req.EventTypes,
+		req.EntityTypes,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidProcessorConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// New configs start active; honour an explicit is_active=false
+	if req.IsActive != nil && !*req.IsActive {
+		version, err := h.processorConfigService.UpdateConfig(c.Request.Context(), config.ID.Hex(), nil, map[string]interface{}{"is_active": false})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		config.IsActive = false
+		config.Version = version
+	}
+
+	c.JSON(http.StatusCreated, processorConfigResponse(config))
 }
 
 // GetProcessorConfig handles GET /api/v1/clients/{client_id}/processor-configs/{config_id}
@@ -58,9 +111,12 @@ func (h *EventProcessorConfigHandler) GetProcessorConfig(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	if config.ClientID.Hex() != clientID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "processor config not found"})
+		return
+	}
 
-	// TODO: Convert model to DTO response
-	c.JSON(http.StatusOK, config)
+	c.JSON(http.StatusOK, processorConfigResponse(config))
 }
 
 // UpdateProcessorConfig handles PUT /api/v1/clients/{client_id}/processor-configs/{config_id}
@@ -74,19 +130,64 @@ func (h *EventProcessorConfigHandler) UpdateProcessorConfig(c *gin.Context) {
 	}
 
 	var req dto.ProcessorConfigUpdate
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := bindJSON(c, &req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// TODO: Convert DTO to update map and call UpdateConfig
-	err := h.processorConfigService.UpdateConfig(c.Request.Context(), configID, map[string]interface{}{})
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.ProcessorType != nil {
+		updates["processor_type"] = *req.ProcessorType
+	}
+	if req.Config != nil {
+		// A config that already skips verification may be edited by whoever can edit it
+		if (&models.EventProcessorConfig{Config: req.Config}).InsecureTLS() && !isSystemAdmin(c) {
+			current, err := h.processorConfigService.GetConfigByID(c.Request.Context(), configID)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+				return
+			}
+			if !current.InsecureTLS() {
+				c.JSON(http.StatusForbidden, gin.H{"error": errInsecureTLSForbidden})
+				return
+			}
+		}
+		updates["config"] = req.Config
+	}
+	if req.EventTypes != nil {
+		updates["event_types"] = req.EventTypes
+	}
+	if req.EntityTypes != nil {
+		updates["entity_types"] = req.EntityTypes
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	version, err := h.processorConfigService.UpdateConfig(c.Request.Context(), configID, req.Version, updates)
 	if err != nil {
+		if respondVersionConflict(c, err) {
+			return
+		}
+		if errors.Is(err, service.ErrInvalidProcessorConfig) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Config updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Config updated successfully", "version": version})
 }
 
 // DeleteProcessorConfig handles DELETE /api/v1/clients/{client_id}/processor-configs/{config_id}
@@ -132,13 +233,85 @@ func (h *EventProcessorConfigHandler) ListProcessorConfigs(c *gin.Context) {
 		return
 	}
 
-	// TODO: Convert clientID string to ObjectID and call ListConfigs
-	configs, err := h.processorConfigService.ListConfigs(c.Request.Context(), nil, nil, nil, limit, offset)
+	client, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid client_id"})
+		return
+	}
+
+	configs, err := h.processorConfigService.ListConfigs(c.Request.Context(), &client, nil, nil, limit, offset)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	// TODO: Convert models to DTO response
-	c.JSON(http.StatusOK, gin.H{"configs": configs, "total": len(configs)})
-}
\ No newline at end of file
+	response := dto.ProcessorConfigListResponse{Configs: make([]dto.ProcessorConfigResponse, len(configs)), Total: len(configs)}
+	for i := range configs {
+		response.Configs[i] = processorConfigResponse(&configs[i])
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// TestProcessorConfig handles POST /api/v1/events/processor-configs/{config_id}/test and
+// POST /api/v1/clients/{client_id}/processor-configs/{config_id}/test, sending a test event to the
+// processor's endpoint and returning its live response
+func (h *EventProcessorConfigHandler) TestProcessorConfig(c *gin.Context) {
+	config, err := h.processorConfigService.GetConfigByID(c.Request.Context(), c.Param("config_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if clientID := c.Param("client_id"); clientID != "" && config.ClientID.Hex() != clientID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "processor config not found"})
+		return
+	}
+
+	test, err := h.processorConfigService.TestConfig(c.Request.Context(), config)
+	if err != nil {
+		if errors.Is(err, service.ErrProcessorTestUnsupported) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.ProcessorConfigTestResponse{
+		EventID:   test.EventID,
+		Success:   test.Result.Success,
+		Status:    test.Result.ResponseStatus,
+		LatencyMs: test.Latency.Milliseconds(),
+		Body:      test.Result.ResponseBody,
+		Error:     test.Result.ErrorMessage,
+	})
+}
+
+// processorConfigResponse converts an event processor config to its API representation, with its
+// credentials masked.
+func processorConfigResponse(config *models.EventProcessorConfig) dto.ProcessorConfigResponse {
+	eventTypes := make([]string, len(config.EventTypes))
+	for i, eventType := range config.EventTypes {
+		eventTypes[i] = string(eventType)
+	}
+	entityTypes := make([]string, len(config.EntityTypes))
+	for i, entityType := range config.EntityTypes {
+		entityTypes[i] = string(entityType)
+	}
+	response := dto.ProcessorConfigResponse{
+		ID:            config.ID.Hex(),
+		Name:          config.Name,
+		ClientID:      config.ClientID.Hex(),
+		ProcessorType: string(config.ProcessorType),
+		Config:        secrets.MaskConfig(config.Config),
+		EventTypes:    eventTypes,
+		EntityTypes:   entityTypes,
+		IsActive:      config.IsActive,
+		Version:       config.Version,
+		CreatedAt:     config.CreatedAt,
+		UpdatedAt:     config.UpdatedAt,
+	}
+	if config.Description != "" {
+		response.Description = &config.Description
+	}
+	return response
+}
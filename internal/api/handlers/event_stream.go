@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+const (
+	// eventStreamKeepAlive is how long a feed stays silent before it sends a comment, so proxies
+	// don't close idle connections
+	eventStreamKeepAlive = 15 * time.Second
+	// eventStreamRetryMs is how long EventSource clients wait before reconnecting
+	eventStreamRetryMs = 5000
+)
+
+// EventStreamHandler serves the server-sent event feed of a client's events.
+type EventStreamHandler struct {
+	Service *service.EventStreamService
+	logger  *zap.Logger
+}
+
+// NewEventStreamHandler creates a new EventStreamHandler.
+func NewEventStreamHandler(svc *service.EventStreamService, logger *zap.Logger) *EventStreamHandler {
+	return &EventStreamHandler{Service: svc, logger: logger}
+}
+
+// Stream handles GET /clients/:client_id/events/stream?event_types=a,b
+// Each event is sent with its id, its type as the SSE event name and its envelope as data. A
+// client reconnecting with Last-Event-ID (or ?last_event_id=) gets the events it missed.
+func (h *EventStreamHandler) Stream(c *gin.Context) {
+	var eventTypes []string
+	for _, value := range c.QueryArray("event_types") {
+		for _, eventType := range strings.Split(value, ",") {
+			if eventType = strings.TrimSpace(eventType); eventType != "" {
+				eventTypes = append(eventTypes, eventType)
+			}
+		}
+	}
+	lastEventID := c.GetHeader("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = c.Query("last_event_id")
+	}
+
+	ctx := c.Request.Context()
+	stream, err := h.Service.Open(ctx, c.Param("client_id"), eventTypes, lastEventID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEventStreamClientNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrInvalidLastEventID), errors.Is(err, models.ErrUnknownEventType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	// Keeps nginx from buffering the feed
+	header.Set("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", eventStreamRetryMs)
+	c.Writer.Flush()
+
+	for {
+		events, err := stream.Next(ctx, eventStreamKeepAlive)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			h.logger.Error("Failed to read client event feed", zap.String("client_id", c.Param("client_id")), zap.Error(err))
+			fmt.Fprintf(c.Writer, "event: error\ndata: {\"error\":\"failed to read events\"}\n\n")
+			c.Writer.Flush()
+			return
+		}
+
+		if len(events) == 0 {
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+		}
+		for i := range events {
+			data, err := json.Marshal(events[i].Envelope().Map())
+			if err != nil {
+				h.logger.Error("Failed to encode event", zap.String("event_id", events[i].ID.Hex()), zap.Error(err))
+				continue
+			}
+			fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", events[i].ID.Hex(), events[i].EventType, data)
+		}
+		c.Writer.Flush()
+	}
+}
@@ -3,19 +3,23 @@ package handlers
 import (
 	"net/http"
 
+	"github.com/fraiday-org/api-service/internal/service"
 	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
 
 // EventsHandler handles event-related endpoints
 type EventsHandler struct {
-	logger *zap.Logger
+	logger                *zap.Logger
+	eventPublisherService *service.EventPublisherService
 }
 
 // NewEventsHandler creates a new events handler
-func NewEventsHandler(logger *zap.Logger) *EventsHandler {
+func NewEventsHandler(logger *zap.Logger, eventPublisherService *service.EventPublisherService) *EventsHandler {
 	return &EventsHandler{
-		logger: logger,
+		logger:                logger,
+		eventPublisherService: eventPublisherService,
 	}
 }
 
@@ -64,10 +68,26 @@ func (h *EventsHandler) ProcessEvent(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Event processed"})
 }
 
-// GetEventStatus gets event processing status
+// GetEventStatus handles GET /events/:event_id/status
+// Each delivery includes next_retry_at and the retry_policy applied while it is backing off.
 func (h *EventsHandler) GetEventStatus(c *gin.Context) {
 	eventID := c.Param("event_id")
-	h.logger.Info("Getting event status", zap.String("event_id", eventID))
-	// TODO: Implement event status retrieval logic
-	c.JSON(http.StatusOK, gin.H{"status": "processed", "event_id": eventID})
-}
\ No newline at end of file
+	if _, err := primitive.ObjectIDFromHex(eventID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid event_id"})
+		return
+	}
+
+	status, err := h.eventPublisherService.GetEventStatus(c.Request.Context(), eventID)
+	if err != nil {
+		h.logger.Error("Failed to get event status", zap.String("event_id", eventID), zap.Error(err))
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+// GetEventCatalog handles GET /events/catalog
+// It lists every event type with the JSON Schema of its data and of the envelope it is delivered in.
+func (h *EventsHandler) GetEventCatalog(c *gin.Context) {
+	c.JSON(http.StatusOK, service.EventCatalog())
+}
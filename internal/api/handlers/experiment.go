@@ -0,0 +1,149 @@
+// Package handlers provides HTTP handlers for A/B experiments on AI configurations.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// ExperimentHandler handles a client's experiments and their metrics.
+type ExperimentHandler struct {
+	Service *service.ExperimentService
+}
+
+// NewExperimentHandler creates a new ExperimentHandler.
+func NewExperimentHandler(svc *service.ExperimentService) *ExperimentHandler {
+	return &ExperimentHandler{Service: svc}
+}
+
+// CreateExperiment handles POST /clients/:client_id/experiments
+func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
+	var req dto.ExperimentCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	experiment := &models.Experiment{
+		ClientID:    c.Param("client_id"),
+		Name:        req.Name,
+		Description: req.Description,
+		Variants:    req.Variants,
+	}
+	if err := h.Service.CreateExperiment(c.Request.Context(), experiment); err != nil {
+		experimentError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, experiment)
+}
+
+// ListExperiments handles GET /clients/:client_id/experiments
+func (h *ExperimentHandler) ListExperiments(c *gin.Context) {
+	experiments, err := h.Service.ListExperiments(c.Request.Context(), c.Param("client_id"), c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ExperimentListResponse{
+		Experiments: experiments,
+		Total:       len(experiments),
+	})
+}
+
+// GetExperiment handles GET /clients/:client_id/experiments/:experiment_id
+func (h *ExperimentHandler) GetExperiment(c *gin.Context) {
+	experiment, err := h.Service.GetExperiment(c.Request.Context(), c.Param("client_id"), c.Param("experiment_id"))
+	if err != nil {
+		experimentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, experiment)
+}
+
+// UpdateExperiment handles PUT /clients/:client_id/experiments/:experiment_id
+func (h *ExperimentHandler) UpdateExperiment(c *gin.Context) {
+	var req dto.ExperimentUpdate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	update := bson.M{}
+	if req.Name != nil {
+		update["name"] = *req.Name
+	}
+	if req.Description != nil {
+		update["description"] = *req.Description
+	}
+	if req.Variants != nil {
+		update["variants"] = req.Variants
+	}
+	if len(update) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	experiment, err := h.Service.UpdateExperiment(c.Request.Context(), c.Param("client_id"), c.Param("experiment_id"), update)
+	if err != nil {
+		experimentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, experiment)
+}
+
+// DeleteExperiment handles DELETE /clients/:client_id/experiments/:experiment_id
+func (h *ExperimentHandler) DeleteExperiment(c *gin.Context) {
+	if err := h.Service.DeleteExperiment(c.Request.Context(), c.Param("client_id"), c.Param("experiment_id")); err != nil {
+		experimentError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// StartExperiment handles POST /clients/:client_id/experiments/:experiment_id/start
+func (h *ExperimentHandler) StartExperiment(c *gin.Context) {
+	experiment, err := h.Service.StartExperiment(c.Request.Context(), c.Param("client_id"), c.Param("experiment_id"))
+	if err != nil {
+		experimentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, experiment)
+}
+
+// StopExperiment handles POST /clients/:client_id/experiments/:experiment_id/stop
+func (h *ExperimentHandler) StopExperiment(c *gin.Context) {
+	experiment, err := h.Service.StopExperiment(c.Request.Context(), c.Param("client_id"), c.Param("experiment_id"))
+	if err != nil {
+		experimentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, experiment)
+}
+
+// GetMetrics handles GET /clients/:client_id/experiments/:experiment_id/metrics
+func (h *ExperimentHandler) GetMetrics(c *gin.Context) {
+	metrics, err := h.Service.GetMetrics(c.Request.Context(), c.Param("client_id"), c.Param("experiment_id"))
+	if err != nil {
+		experimentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, metrics)
+}
+
+func experimentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrExperimentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrExperimentRunning), errors.Is(err, service.ErrExperimentStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,103 @@
+// Package handlers provides HTTP handlers for feature flags.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// FeatureFlagHandler handles managing feature flags and checking them for a client.
+type FeatureFlagHandler struct {
+	Service *service.FeatureFlagService
+}
+
+// NewFeatureFlagHandler creates a new FeatureFlagHandler.
+func NewFeatureFlagHandler(svc *service.FeatureFlagService) *FeatureFlagHandler {
+	return &FeatureFlagHandler{Service: svc}
+}
+
+// ListFeatureFlags handles GET /feature-flags
+func (h *FeatureFlagHandler) ListFeatureFlags(c *gin.Context) {
+	flags, err := h.Service.ListFlags(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.FeatureFlagListResponse{FeatureFlags: flags, Total: len(flags)})
+}
+
+// CreateFeatureFlag handles POST /feature-flags
+func (h *FeatureFlagHandler) CreateFeatureFlag(c *gin.Context) {
+	var req dto.FeatureFlagCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flag, err := h.Service.CreateFlag(c.Request.Context(), req.ToModel(req.Key))
+	if err != nil {
+		respondFeatureFlagError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, flag)
+}
+
+// GetFeatureFlag handles GET /feature-flags/:key
+func (h *FeatureFlagHandler) GetFeatureFlag(c *gin.Context) {
+	flag, err := h.Service.GetFlag(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		respondFeatureFlagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// UpdateFeatureFlag handles PUT /feature-flags/:key
+func (h *FeatureFlagHandler) UpdateFeatureFlag(c *gin.Context) {
+	var req dto.FeatureFlagUpdate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	flag, err := h.Service.UpdateFlag(c.Request.Context(), req.ToModel(c.Param("key")))
+	if err != nil {
+		respondFeatureFlagError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, flag)
+}
+
+// DeleteFeatureFlag handles DELETE /feature-flags/:key
+func (h *FeatureFlagHandler) DeleteFeatureFlag(c *gin.Context) {
+	if err := h.Service.DeleteFlag(c.Request.Context(), c.Param("key")); err != nil {
+		respondFeatureFlagError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// EvaluateFeatureFlag handles GET /feature-flags/:key/evaluate?client_id=
+func (h *FeatureFlagHandler) EvaluateFeatureFlag(c *gin.Context) {
+	key, clientID := c.Param("key"), c.Query("client_id")
+	enabled, defined := h.Service.Evaluate(c.Request.Context(), key, clientID)
+	c.JSON(http.StatusOK, dto.FeatureFlagEvaluation{Key: key, ClientID: clientID, Enabled: enabled, Defined: defined})
+}
+
+func respondFeatureFlagError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidFeatureFlag):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrFeatureFlagNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrFeatureFlagExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,59 @@
+// Package handlers provides HTTP handlers for generic webhook channels.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// maxGenericWebhookBodyBytes bounds payloads from custom widgets, which carry attachments as URLs
+const maxGenericWebhookBodyBytes = 1 << 20
+
+// GenericWebhookHandler receives payloads for generic_webhook channels.
+type GenericWebhookHandler struct {
+	Service *service.GenericWebhookService
+}
+
+// NewGenericWebhookHandler creates a new GenericWebhookHandler.
+func NewGenericWebhookHandler(svc *service.GenericWebhookService) *GenericWebhookHandler {
+	return &GenericWebhookHandler{Service: svc}
+}
+
+// HandleWebhook handles POST /channels/webhook/:channel_id
+func (h *GenericWebhookHandler) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxGenericWebhookBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+	token := c.GetHeader("X-Webhook-Token")
+	if token == "" {
+		token = c.Query("token")
+	}
+
+	msg, err := h.Service.HandleWebhook(c.Request.Context(), c.Param("channel_id"), token, body)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrGenericWebhookUnauthorized):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrGenericWebhookChannelNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrGenericWebhookUnmapped):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if msg == nil {
+		// The session is closed and the channel's policy drops new messages
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
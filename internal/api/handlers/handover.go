@@ -0,0 +1,129 @@
+// Package handlers provides HTTP handlers for session handover to human agents.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// HandoverHandler handles handover requests and agent responses.
+type HandoverHandler struct {
+	Service *service.HandoverService
+}
+
+// NewHandoverHandler creates a new HandoverHandler.
+func NewHandoverHandler(svc *service.HandoverService) *HandoverHandler {
+	return &HandoverHandler{Service: svc}
+}
+
+// RequestHandover handles POST /sessions/:session_id/handover
+func (h *HandoverHandler) RequestHandover(c *gin.Context) {
+	var req dto.HandoverCreateRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	handover, err := h.Service.RequestHandover(c.Request.Context(), c.Param("session_id"), service.HandoverRequest{
+		Reason:      req.Reason,
+		TargetQueue: req.TargetQueue,
+		TargetAgent: req.TargetAgent,
+		Skills:      req.Skills,
+		RequestedBy: req.RequestedBy,
+	})
+	if err != nil {
+		respondHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, handover)
+}
+
+// GetHandover handles GET /sessions/:session_id/handover
+func (h *HandoverHandler) GetHandover(c *gin.Context) {
+	handover, err := h.Service.GetHandover(c.Request.Context(), c.Param("session_id"))
+	if err != nil {
+		respondHandoverError(c, err)
+		return
+	}
+	if handover == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "session has no handover"})
+		return
+	}
+	c.JSON(http.StatusOK, handover)
+}
+
+// AcceptHandover handles POST /sessions/:session_id/handover/accept
+func (h *HandoverHandler) AcceptHandover(c *gin.Context) {
+	var req dto.HandoverAgentRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	handover, err := h.Service.AcceptHandover(c.Request.Context(), c.Param("session_id"), req.AgentID)
+	if err != nil {
+		respondHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, handover)
+}
+
+// DeclineHandover handles POST /sessions/:session_id/handover/decline
+func (h *HandoverHandler) DeclineHandover(c *gin.Context) {
+	var req dto.HandoverDeclineRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	handover, err := h.Service.DeclineHandover(c.Request.Context(), c.Param("session_id"), req.AgentID, req.Reason)
+	if err != nil {
+		respondHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, handover)
+}
+
+// CompleteHandover handles POST /sessions/:session_id/handover/complete
+func (h *HandoverHandler) CompleteHandover(c *gin.Context) {
+	var req dto.HandoverAgentRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	handover, err := h.Service.CompleteHandover(c.Request.Context(), c.Param("session_id"), req.AgentID)
+	if err != nil {
+		respondHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, handover)
+}
+
+// RouteHandover handles POST /sessions/:session_id/handover/assign
+func (h *HandoverHandler) RouteHandover(c *gin.Context) {
+	handover, err := h.Service.RouteHandover(c.Request.Context(), c.Param("session_id"))
+	if err != nil {
+		respondHandoverError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, handover)
+}
+
+func respondHandoverError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrHandoverTargetRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrHandoverAgentMismatch):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrHandoverInvalidTransition), errors.Is(err, service.ErrNoAgentAvailable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	}
+}
@@ -12,21 +12,24 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/service"
 )
 
 var startTime = time.Now()
 
 type HealthHandler struct {
-	cfg         *config.Config
-	logger      *zap.Logger
-	mongoClient *mongo.Client
+	cfg          *config.Config
+	logger       *zap.Logger
+	mongoClient  *mongo.Client
+	dependencies *service.HealthService
 }
 
-func NewHealthHandler(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client) *HealthHandler {
+func NewHealthHandler(cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client, dependencies *service.HealthService) *HealthHandler {
 	return &HealthHandler{
-		cfg:         cfg,
-		logger:      logger,
-		mongoClient: mongoClient,
+		cfg:          cfg,
+		logger:       logger,
+		mongoClient:  mongoClient,
+		dependencies: dependencies,
 	}
 }
 
@@ -75,37 +78,24 @@ func (h *HealthHandler) Ping(c *gin.Context) {
 	c.JSON(http.StatusOK, resp)
 }
 
-// Readiness check for Kubernetes
+// Readiness probes every dependency and reports each one's status, latency and version. It
+// returns 503 only when a critical dependency is down; a degraded service still takes traffic.
 func (h *HealthHandler) Readiness(c *gin.Context) {
-	now := time.Now().UTC()
-	
-	// Check MongoDB connection
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-	
-	dbReady := true
-	if err := h.mongoClient.Ping(ctx, readpref.Primary()); err != nil {
-		h.logger.Error("MongoDB readiness check failed", zap.Error(err))
-		dbReady = false
+	report := h.dependencies.Check(c.Request.Context())
+	for name, check := range report.Checks {
+		if check.Status == service.DependencyStatusDown {
+			h.logger.Warn("Readiness check failed",
+				zap.String("dependency", name),
+				zap.Bool("critical", check.Critical),
+				zap.String("error", check.Error))
+		}
 	}
-	
-	// Determine overall readiness
-	status := "ready"
+
 	statusCode := http.StatusOK
-	if !dbReady {
-		status = "not_ready"
+	if report.Status == service.HealthStatusNotReady {
 		statusCode = http.StatusServiceUnavailable
 	}
-	
-	resp := gin.H{
-		"status":    status,
-		"timestamp": now.Unix(),
-		"checks": gin.H{
-			"database": dbReady,
-		},
-	}
-	
-	c.JSON(statusCode, resp)
+	c.JSON(statusCode, report)
 }
 
 // Kubernetes health check (simplified)
@@ -0,0 +1,72 @@
+// Package handlers provides HTTP handlers for client intent routing.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// IntentHandler handles configuration of intent-based message routing.
+type IntentHandler struct {
+	Service *service.IntentService
+}
+
+// NewIntentHandler creates a new IntentHandler.
+func NewIntentHandler(svc *service.IntentService) *IntentHandler {
+	return &IntentHandler{Service: svc}
+}
+
+// GetConfig handles GET /clients/:client_id/intents
+func (h *IntentHandler) GetConfig(c *gin.Context) {
+	routing, err := h.Service.GetConfig(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if routing == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client has no intent routing"})
+		return
+	}
+	c.JSON(http.StatusOK, routing)
+}
+
+// SetConfig handles PUT /clients/:client_id/intents
+func (h *IntentHandler) SetConfig(c *gin.Context) {
+	var req dto.IntentRoutingRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	routing := &models.IntentRouting{
+		Enabled: req.Enabled == nil || *req.Enabled,
+		Rules:   make([]models.IntentRule, len(req.Rules)),
+	}
+	for i, rule := range req.Rules {
+		routing.Rules[i] = models.IntentRule{
+			Intent:        rule.Intent,
+			MinConfidence: rule.MinConfidence,
+			Action:        models.IntentAction(rule.Action),
+			TargetQueue:   rule.TargetQueue,
+		}
+	}
+	if err := h.Service.SetConfig(c.Request.Context(), c.Param("client_id"), routing); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, routing)
+}
+
+// DeleteConfig handles DELETE /clients/:client_id/intents
+func (h *IntentHandler) DeleteConfig(c *gin.Context) {
+	if err := h.Service.DeleteConfig(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
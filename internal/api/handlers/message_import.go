@@ -0,0 +1,107 @@
+// Package handlers provides HTTP handlers for message history imports.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// maxMessageImportBytes bounds an import file
+const maxMessageImportBytes = 256 << 20
+
+// MessageImportHandler handles imports of message history from other platforms.
+type MessageImportHandler struct {
+	Service *service.MessageImportService
+}
+
+// NewMessageImportHandler creates a new MessageImportHandler.
+func NewMessageImportHandler(svc *service.MessageImportService) *MessageImportHandler {
+	return &MessageImportHandler{Service: svc}
+}
+
+// CreateImport handles POST /imports/messages?client_id=...&channel_id=...&events=... The NDJSON
+// file is the request body, or the "file" field of a multipart form. The import runs on a worker;
+// poll it with GET /imports/messages/:import_id until its status is completed or failed.
+func (h *MessageImportHandler) CreateImport(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxMessageImportBytes)
+
+	var content io.Reader = c.Request.Body
+	fileName := "messages.ndjson"
+	if strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import file is too large"})
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+			return
+		}
+		file, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"})
+			return
+		}
+		defer file.Close()
+		content, fileName = file, header.Filename
+	}
+
+	imp, err := h.Service.CreateImport(c.Request.Context(), c.Query("client_id"), c.Query("channel_id"), c.Query("events"), fileName, content)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "import file is too large"})
+		case errors.Is(err, service.ErrMessageImportQueueUnavailable):
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, imp)
+}
+
+// ListImports handles GET /imports/messages?client_id=...
+func (h *MessageImportHandler) ListImports(c *gin.Context) {
+	clientID := c.Query("client_id")
+	if clientID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "client_id is required"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	imports, err := h.Service.ListImports(c.Request.Context(), clientID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.MessageImportListResponse{
+		Imports: imports,
+		Total:   len(imports),
+	})
+}
+
+// GetImport handles GET /imports/messages/:import_id
+func (h *MessageImportHandler) GetImport(c *gin.Context) {
+	imp, err := h.Service.GetImport(c.Request.Context(), c.Param("import_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrMessageImportNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, imp)
+}
@@ -0,0 +1,57 @@
+// Package handlers provides the HTTP long-polling fallback for session updates.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+const (
+	defaultPollWait = 30 * time.Second
+	maxPollWait     = 60 * time.Second
+)
+
+// MessagePollHandler serves long-poll requests for new session messages and events.
+type MessagePollHandler struct {
+	Service *service.MessagePollService
+}
+
+// NewMessagePollHandler creates a new MessagePollHandler.
+func NewMessagePollHandler(svc *service.MessagePollService) *MessagePollHandler {
+	return &MessagePollHandler{Service: svc}
+}
+
+// Poll handles GET /sessions/:session_id/messages/poll?after=<cursor>&wait=30s
+func (h *MessagePollHandler) Poll(c *gin.Context) {
+	wait := defaultPollWait
+	if w := c.Query("wait"); w != "" {
+		parsed, err := time.ParseDuration(w)
+		if err != nil {
+			// Bare numbers are seconds
+			secs, convErr := strconv.Atoi(w)
+			if convErr != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid wait"})
+				return
+			}
+			parsed = time.Duration(secs) * time.Second
+		}
+		wait = min(max(parsed, 0), maxPollWait)
+	}
+
+	resp, err := h.Service.Poll(c.Request.Context(), c.Param("session_id"), c.Query("after"), wait)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPollCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
@@ -0,0 +1,68 @@
+// Package handlers provides HTTP handlers for client moderation policies.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// ModerationHandler handles configuration of AI response moderation.
+type ModerationHandler struct {
+	Service *service.ModerationService
+}
+
+// NewModerationHandler creates a new ModerationHandler.
+func NewModerationHandler(svc *service.ModerationService) *ModerationHandler {
+	return &ModerationHandler{Service: svc}
+}
+
+// GetPolicy handles GET /clients/:client_id/moderation
+func (h *ModerationHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.Service.GetPolicy(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if policy == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client has no moderation policy"})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetPolicy handles PUT /clients/:client_id/moderation
+func (h *ModerationHandler) SetPolicy(c *gin.Context) {
+	var req dto.ModerationPolicyRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	policy := &models.ModerationPolicy{
+		Enabled:         req.Enabled == nil || *req.Enabled,
+		BlockedPhrases:  req.BlockedPhrases,
+		BlockedPatterns: req.BlockedPatterns,
+		APIURL:          req.APIURL,
+		APITimeoutMs:    req.APITimeoutMs,
+		HandoverQueue:   req.HandoverQueue,
+	}
+	if err := h.Service.SetPolicy(c.Request.Context(), c.Param("client_id"), policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, policy)
+}
+
+// DeletePolicy handles DELETE /clients/:client_id/moderation
+func (h *ModerationHandler) DeletePolicy(c *gin.Context) {
+	if err := h.Service.DeletePolicy(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
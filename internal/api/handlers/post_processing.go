@@ -0,0 +1,77 @@
+// Package handlers provides HTTP handlers for client post-processing hooks.
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// PostProcessingHandler handles registration of synchronous post-processing webhooks.
+type PostProcessingHandler struct {
+	Service *service.PostProcessingService
+}
+
+// NewPostProcessingHandler creates a new PostProcessingHandler.
+func NewPostProcessingHandler(svc *service.PostProcessingService) *PostProcessingHandler {
+	return &PostProcessingHandler{Service: svc}
+}
+
+// GetHook handles GET /clients/:client_id/post-processing-hook
+func (h *PostProcessingHandler) GetHook(c *gin.Context) {
+	hook, err := h.Service.GetHook(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if hook == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "client has no post-processing hook"})
+		return
+	}
+	c.JSON(http.StatusOK, toPostProcessingHookResponse(hook))
+}
+
+// SetHook handles PUT /clients/:client_id/post-processing-hook
+func (h *PostProcessingHandler) SetHook(c *gin.Context) {
+	var req dto.PostProcessingHookRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	hook := &models.PostProcessingHook{
+		URL:           req.URL,
+		Secret:        req.Secret,
+		TimeoutMs:     req.TimeoutMs,
+		FailurePolicy: models.PostProcessingFailurePolicy(req.FailurePolicy),
+		Enabled:       req.Enabled == nil || *req.Enabled,
+	}
+	if err := h.Service.SetHook(c.Request.Context(), c.Param("client_id"), hook); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, toPostProcessingHookResponse(hook))
+}
+
+// DeleteHook handles DELETE /clients/:client_id/post-processing-hook
+func (h *PostProcessingHandler) DeleteHook(c *gin.Context) {
+	if err := h.Service.DeleteHook(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func toPostProcessingHookResponse(hook *models.PostProcessingHook) dto.PostProcessingHookResponse {
+	return dto.PostProcessingHookResponse{
+		URL:           hook.URL,
+		TimeoutMs:     hook.TimeoutMs,
+		FailurePolicy: string(hook.FailurePolicy),
+		Enabled:       hook.Enabled,
+		HasSecret:     hook.Secret != "",
+	}
+}
@@ -0,0 +1,141 @@
+// Package handlers provides HTTP handlers for versioned prompt templates.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// PromptTemplateHandler handles a client's prompt templates, their versions and publishing.
+type PromptTemplateHandler struct {
+	Service *service.PromptTemplateService
+}
+
+// NewPromptTemplateHandler creates a new PromptTemplateHandler.
+func NewPromptTemplateHandler(svc *service.PromptTemplateService) *PromptTemplateHandler {
+	return &PromptTemplateHandler{Service: svc}
+}
+
+// CreateTemplate handles POST /clients/:client_id/prompt-templates
+func (h *PromptTemplateHandler) CreateTemplate(c *gin.Context) {
+	var req dto.PromptTemplateCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template := &models.PromptTemplate{
+		ClientID:    c.Param("client_id"),
+		Name:        req.Name,
+		ChannelType: req.ChannelType,
+		Description: req.Description,
+	}
+	first := models.PromptTemplateVersion{
+		Text:      req.Text,
+		Defaults:  req.Defaults,
+		Note:      req.Note,
+		CreatedBy: performedBy(c, ""),
+	}
+	if err := h.Service.CreateTemplate(c.Request.Context(), template, first); err != nil {
+		promptTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+// ListTemplates handles GET /clients/:client_id/prompt-templates
+func (h *PromptTemplateHandler) ListTemplates(c *gin.Context) {
+	templates, err := h.Service.ListTemplates(c.Request.Context(), c.Param("client_id"), c.Query("name"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.PromptTemplateListResponse{
+		PromptTemplates: templates,
+		Total:           len(templates),
+	})
+}
+
+// GetTemplate handles GET /clients/:client_id/prompt-templates/:template_id
+func (h *PromptTemplateHandler) GetTemplate(c *gin.Context) {
+	template, err := h.Service.GetTemplate(c.Request.Context(), c.Param("client_id"), c.Param("template_id"))
+	if err != nil {
+		promptTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// DeleteTemplate handles DELETE /clients/:client_id/prompt-templates/:template_id
+func (h *PromptTemplateHandler) DeleteTemplate(c *gin.Context) {
+	if err := h.Service.DeleteTemplate(c.Request.Context(), c.Param("client_id"), c.Param("template_id")); err != nil {
+		promptTemplateError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// AddVersion handles POST /clients/:client_id/prompt-templates/:template_id/versions
+func (h *PromptTemplateHandler) AddVersion(c *gin.Context) {
+	var req dto.PromptTemplateVersionCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	version := models.PromptTemplateVersion{
+		Text:      req.Text,
+		Defaults:  req.Defaults,
+		Note:      req.Note,
+		CreatedBy: performedBy(c, ""),
+	}
+	template, err := h.Service.AddVersion(c.Request.Context(), c.Param("client_id"), c.Param("template_id"), version)
+	if err != nil {
+		promptTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, template)
+}
+
+// Publish handles POST /clients/:client_id/prompt-templates/:template_id/publish
+func (h *PromptTemplateHandler) Publish(c *gin.Context) {
+	var req dto.PromptTemplatePublish
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.Service.Publish(c.Request.Context(), c.Param("client_id"), c.Param("template_id"), req.Version, performedBy(c, ""))
+	if err != nil {
+		promptTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+// Rollback handles POST /clients/:client_id/prompt-templates/:template_id/rollback
+func (h *PromptTemplateHandler) Rollback(c *gin.Context) {
+	template, err := h.Service.Rollback(c.Request.Context(), c.Param("client_id"), c.Param("template_id"), performedBy(c, ""))
+	if err != nil {
+		promptTemplateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, template)
+}
+
+func promptTemplateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrPromptTemplateNotFound), errors.Is(err, service.ErrPromptVersionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPromptTemplateExists), errors.Is(err, service.ErrPromptTemplateConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
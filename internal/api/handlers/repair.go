@@ -0,0 +1,115 @@
+// Package handlers provides HTTP handlers for operator repair endpoints.
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// RepairHandler handles operator repair actions.
+type RepairHandler struct {
+	RepairService *service.RepairService
+}
+
+// NewRepairHandler creates a new RepairHandler.
+func NewRepairHandler(repairService *service.RepairService) *RepairHandler {
+	return &RepairHandler{RepairService: repairService}
+}
+
+// CreateRepairAction handles POST /admin/repairs
+func (h *RepairHandler) CreateRepairAction(c *gin.Context) {
+	var req dto.RepairActionRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !req.DryRun && !req.Confirm {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "confirm must be true for non dry-run repair actions"})
+		return
+	}
+
+	params := models.RepairActionParams{
+		From:             req.From,
+		To:               req.To,
+		OlderThanMinutes: req.OlderThanMinutes,
+		Limit:            req.Limit,
+	}
+	if req.ProcessorID != "" {
+		processorID, err := primitive.ObjectIDFromHex(req.ProcessorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid processor_id"})
+			return
+		}
+		params.ProcessorID = &processorID
+	}
+
+	requestedBy := c.GetString("auth_type") + "@" + c.ClientIP()
+	action, err := h.RepairService.RequestAction(c.Request.Context(), models.RepairActionType(req.Action), params, req.DryRun, requestedBy)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, toRepairActionResponse(action))
+}
+
+// ListRepairActions handles GET /admin/repairs
+func (h *RepairHandler) ListRepairActions(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	actions, err := h.RepairService.ListActions(c.Request.Context(), c.Query("action"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	resp := dto.RepairActionListResponse{Actions: make([]dto.RepairActionResponse, 0, len(actions))}
+	for i := range actions {
+		resp.Actions = append(resp.Actions, toRepairActionResponse(&actions[i]))
+	}
+	resp.Total = len(resp.Actions)
+	c.JSON(http.StatusOK, resp)
+}
+
+// GetRepairAction handles GET /admin/repairs/:action_id
+func (h *RepairHandler) GetRepairAction(c *gin.Context) {
+	action, err := h.RepairService.GetAction(c.Request.Context(), c.Param("action_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, toRepairActionResponse(action))
+}
+
+func toRepairActionResponse(action *models.RepairAction) dto.RepairActionResponse {
+	resp := dto.RepairActionResponse{
+		ID:               action.ID.Hex(),
+		Action:           string(action.Action),
+		Status:           string(action.Status),
+		DryRun:           action.DryRun,
+		RequestedBy:      action.RequestedBy,
+		From:             action.Params.From,
+		To:               action.Params.To,
+		OlderThanMinutes: action.Params.OlderThanMinutes,
+		Limit:            action.Params.Limit,
+		Matched:          action.Matched,
+		Affected:         action.Affected,
+		AffectedIDs:      action.AffectedIDs,
+		Error:            action.Error,
+		StartedAt:        action.StartedAt,
+		CompletedAt:      action.CompletedAt,
+		CreatedAt:        action.CreatedAt,
+	}
+	if action.Params.ProcessorID != nil {
+		resp.ProcessorID = action.Params.ProcessorID.Hex()
+	}
+	return resp
+}
@@ -0,0 +1,122 @@
+// Package handlers provides HTTP handlers for scheduled analytics reports.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// ReportHandler handles a client's report schedules.
+type ReportHandler struct {
+	Service *service.ReportService
+}
+
+// NewReportHandler creates a new ReportHandler.
+func NewReportHandler(svc *service.ReportService) *ReportHandler {
+	return &ReportHandler{Service: svc}
+}
+
+// CreateReport handles POST /clients/:client_id/reports
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	var req dto.ReportScheduleCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule := &models.ReportSchedule{
+		ClientID:   c.Param("client_id"),
+		Name:       req.Name,
+		Enabled:    req.Enabled == nil || *req.Enabled,
+		Frequency:  req.Frequency,
+		Weekday:    req.Weekday,
+		Hour:       req.Hour,
+		Timezone:   req.Timezone,
+		Recipients: req.Recipients,
+		WebhookURL: req.WebhookURL,
+	}
+	if req.EmailChannelID != "" {
+		if schedule.EmailChannel = service.ParseObjectID(req.EmailChannelID); schedule.EmailChannel == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid email_channel_id"})
+			return
+		}
+	}
+	if err := h.Service.CreateSchedule(c.Request.Context(), schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, schedule)
+}
+
+// ListReports handles GET /clients/:client_id/reports
+func (h *ReportHandler) ListReports(c *gin.Context) {
+	schedules, err := h.Service.ListSchedules(c.Request.Context(), c.Param("client_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ReportScheduleListResponse{
+		Reports: schedules,
+		Total:   len(schedules),
+	})
+}
+
+// GetReport handles GET /clients/:client_id/reports/:report_id
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	schedule, err := h.Service.GetSchedule(c.Request.Context(), c.Param("client_id"), c.Param("report_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// UpdateReport handles PUT /clients/:client_id/reports/:report_id
+func (h *ReportHandler) UpdateReport(c *gin.Context) {
+	var req dto.ReportScheduleUpdate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	schedule, err := h.Service.UpdateSchedule(c.Request.Context(), c.Param("client_id"), c.Param("report_id"), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrReportScheduleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+// DeleteReport handles DELETE /clients/:client_id/reports/:report_id
+func (h *ReportHandler) DeleteReport(c *gin.Context) {
+	if err := h.Service.DeleteSchedule(c.Request.Context(), c.Param("client_id"), c.Param("report_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// RunReport handles POST /clients/:client_id/reports/:report_id/run, queueing the report over the
+// period up to now
+func (h *ReportHandler) RunReport(c *gin.Context) {
+	start, end, err := h.Service.RunNow(c.Request.Context(), c.Param("client_id"), c.Param("report_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrReportScheduleNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, dto.ReportRunResponse{Queued: true, StartTime: start, EndTime: end})
+}
@@ -0,0 +1,82 @@
+// Package handlers provides HTTP handlers for role management.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// RoleHandler handles assigning roles to dashboard users and client API keys.
+type RoleHandler struct {
+	Service       *service.RoleService
+	APIKeyService *service.APIKeyService
+}
+
+// NewRoleHandler creates a new RoleHandler.
+func NewRoleHandler(svc *service.RoleService, apiKeyService *service.APIKeyService) *RoleHandler {
+	return &RoleHandler{Service: svc, APIKeyService: apiKeyService}
+}
+
+// ListRoleAssignments handles GET /role-assignments
+func (h *RoleHandler) ListRoleAssignments(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	assignments, err := h.Service.ListAssignments(c.Request.Context(), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.RoleAssignmentListResponse{RoleAssignments: assignments, Total: len(assignments)})
+}
+
+// SetRoleAssignment handles PUT /role-assignments/:subject
+func (h *RoleHandler) SetRoleAssignment(c *gin.Context) {
+	var req dto.RoleAssignmentUpdate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	assignment, err := h.Service.SetAssignment(c.Request.Context(), c.Param("subject"), req.Roles, req.ClientID)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRoles) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, assignment)
+}
+
+// DeleteRoleAssignment handles DELETE /role-assignments/:subject
+func (h *RoleHandler) DeleteRoleAssignment(c *gin.Context) {
+	if err := h.Service.DeleteAssignment(c.Request.Context(), c.Param("subject")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// SetAPIKeyRoles handles PUT /clients/:client_id/api-keys/:key_id/roles
+func (h *RoleHandler) SetAPIKeyRoles(c *gin.Context) {
+	var req dto.APIKeyRolesUpdate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	key, err := h.APIKeyService.SetAPIKeyRoles(c.Request.Context(), c.Param("client_id"), c.Param("key_id"), req.Roles)
+	if err != nil {
+		respondAPIKeyError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, key)
+}
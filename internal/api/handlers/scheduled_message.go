@@ -0,0 +1,114 @@
+// Package handlers provides HTTP handlers for scheduled chat messages.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// ScheduledMessageHandler handles scheduling, listing and cancelling messages.
+type ScheduledMessageHandler struct {
+	Service *service.ScheduledMessageService
+}
+
+// NewScheduledMessageHandler creates a new ScheduledMessageHandler.
+func NewScheduledMessageHandler(svc *service.ScheduledMessageService) *ScheduledMessageHandler {
+	return &ScheduledMessageHandler{Service: svc}
+}
+
+// ScheduleMessage handles POST /messages/schedule
+func (h *ScheduledMessageHandler) ScheduleMessage(c *gin.Context) {
+	var req dto.ScheduledMessageCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !allowsClient(c, req.ClientID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errClientForbidden})
+		return
+	}
+
+	var parentMessageID *primitive.ObjectID
+	if req.ParentMessageID != "" {
+		parentMessageID = service.ParseObjectID(req.ParentMessageID)
+		if parentMessageID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid parent_message_id"})
+			return
+		}
+	}
+
+	msg := &models.ScheduledMessage{
+		SendAt:            req.SendAt,
+		SessionID:         req.SessionID,
+		ClientID:          req.ClientID,
+		ClientChannelType: req.ClientChannelType,
+		ExternalID:        req.ExternalID,
+		Sender:            req.Sender,
+		SenderName:        req.SenderName,
+		SenderType:        req.SenderType,
+		Text:              req.Text,
+		Attachments:       req.Attachments,
+		Data:              req.Data,
+		Category:          models.MessageCategory(req.Category),
+		Config:            req.Config,
+		ParentMessageID:   parentMessageID,
+	}
+
+	if err := h.Service.Schedule(c.Request.Context(), msg); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, msg)
+}
+
+// ListScheduledMessages handles GET /messages/scheduled
+func (h *ScheduledMessageHandler) ListScheduledMessages(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	clientID := c.Query("client_id")
+	if client := callerClient(c); client != nil {
+		clientID = client.ClientID
+	}
+
+	messages, err := h.Service.ListScheduledMessages(c.Request.Context(), clientID, c.Query("session_id"), c.Query("status"), limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.ScheduledMessageListResponse{
+		ScheduledMessages: messages,
+		Total:             len(messages),
+	})
+}
+
+// GetScheduledMessage handles GET /messages/scheduled/:scheduled_id
+func (h *ScheduledMessageHandler) GetScheduledMessage(c *gin.Context) {
+	msg, err := h.Service.GetScheduledMessage(c.Request.Context(), c.Param("scheduled_id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+// CancelScheduledMessage handles POST /messages/scheduled/:scheduled_id/cancel
+func (h *ScheduledMessageHandler) CancelScheduledMessage(c *gin.Context) {
+	msg, err := h.Service.Cancel(c.Request.Context(), c.Param("scheduled_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrScheduledMessageNotPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
@@ -0,0 +1,86 @@
+// Package handlers provides HTTP handlers for chat session lifecycle transitions.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// SessionLifecycleHandler handles session close, reopen and state changes.
+type SessionLifecycleHandler struct {
+	Service *service.SessionLifecycleService
+}
+
+// NewSessionLifecycleHandler creates a new SessionLifecycleHandler.
+func NewSessionLifecycleHandler(svc *service.SessionLifecycleService) *SessionLifecycleHandler {
+	return &SessionLifecycleHandler{Service: svc}
+}
+
+// CloseSession handles POST /sessions/:session_id/close
+func (h *SessionLifecycleHandler) CloseSession(c *gin.Context) {
+	var req dto.ChatSessionStateRequest
+	if err := bindJSON(c, &req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.Service.Close(c.Request.Context(), c.Param("session_id"), req.Reason)
+	if err != nil {
+		respondSessionStateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// ReopenSession handles POST /sessions/:session_id/reopen
+func (h *SessionLifecycleHandler) ReopenSession(c *gin.Context) {
+	var req dto.ChatSessionStateRequest
+	if err := bindJSON(c, &req); err != nil && c.Request.ContentLength > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := h.Service.Reopen(c.Request.Context(), c.Param("session_id"), req.Reason)
+	if err != nil {
+		respondSessionStateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+// SetSessionState handles POST /sessions/:session_id/state
+func (h *SessionLifecycleHandler) SetSessionState(c *gin.Context) {
+	var req dto.ChatSessionStateRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.State == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "state is required"})
+		return
+	}
+
+	session, err := h.Service.SetState(c.Request.Context(), c.Param("session_id"), models.SessionState(req.State), req.SnoozedUntil, req.Reason)
+	if err != nil {
+		respondSessionStateError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, session)
+}
+
+func respondSessionStateError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSessionInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSessionSnoozeUntil):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,83 @@
+// Package handlers provides HTTP handlers for conversation simulations.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// SimulationHandler handles simulations that replay scripted conversations through the chat workflow.
+type SimulationHandler struct {
+	Service *service.SimulationService
+}
+
+// NewSimulationHandler creates a new SimulationHandler.
+func NewSimulationHandler(svc *service.SimulationService) *SimulationHandler {
+	return &SimulationHandler{Service: svc}
+}
+
+// CreateSimulation handles POST /simulations. The simulation runs on a worker; poll it with
+// GET /simulations/:simulation_id until its status is completed or failed.
+func (h *SimulationHandler) CreateSimulation(c *gin.Context) {
+	var req dto.SimulationCreate
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !allowsClient(c, req.ClientID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": errClientForbidden})
+		return
+	}
+
+	sim, err := h.Service.CreateSimulation(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrSimulationQueueUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, sim)
+}
+
+// ListSimulations handles GET /simulations?client_id=...
+func (h *SimulationHandler) ListSimulations(c *gin.Context) {
+	clientID := c.Query("client_id")
+	if clientID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "client_id is required"})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+
+	sims, err := h.Service.ListSimulations(c.Request.Context(), clientID, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, dto.SimulationListResponse{
+		Simulations: sims,
+		Total:       len(sims),
+	})
+}
+
+// GetSimulation handles GET /simulations/:simulation_id
+func (h *SimulationHandler) GetSimulation(c *gin.Context) {
+	sim, err := h.Service.GetSimulation(c.Request.Context(), c.Param("simulation_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrSimulationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sim)
+}
@@ -0,0 +1,58 @@
+// Package handlers provides HTTP handlers for the Slack channel connector.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// maxSlackBodyBytes bounds inbound Slack requests; event payloads are a few KB
+const maxSlackBodyBytes = 1 << 20
+
+// SlackHandler receives requests from the Slack Events API.
+type SlackHandler struct {
+	Service *service.SlackService
+}
+
+// NewSlackHandler creates a new SlackHandler.
+func NewSlackHandler(svc *service.SlackService) *SlackHandler {
+	return &SlackHandler{Service: svc}
+}
+
+// HandleEvents handles POST /channels/slack/events
+func (h *SlackHandler) HandleEvents(c *gin.Context) {
+	// Slack retries events it didn't see acknowledged in 3 seconds; the first delivery already created the message
+	if c.GetHeader("X-Slack-Retry-Num") != "" {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSlackBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+
+	challenge, err := h.Service.HandleEvents(c.Request.Context(), c.GetHeader("X-Slack-Request-Timestamp"), c.GetHeader("X-Slack-Signature"), body)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrSlackSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSlackChannelNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if challenge != "" {
+		c.JSON(http.StatusOK, gin.H{"challenge": challenge})
+		return
+	}
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,80 @@
+// Package handlers provides HTTP handlers for the Twilio SMS channel connector.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// emptyTwiML acknowledges an inbound message without an immediate reply; replies are sent
+// through the REST API once the AI or an agent answers.
+const emptyTwiML = `<?xml version="1.0" encoding="UTF-8"?><Response></Response>`
+
+// SMSHandler receives Twilio messaging webhooks.
+type SMSHandler struct {
+	Service *service.SMSService
+}
+
+// NewSMSHandler creates a new SMSHandler.
+func NewSMSHandler(svc *service.SMSService) *SMSHandler {
+	return &SMSHandler{Service: svc}
+}
+
+// HandleInbound handles POST /channels/twilio/sms
+func (h *SMSHandler) HandleInbound(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form body"})
+		return
+	}
+	err := h.Service.HandleInbound(c.Request.Context(), twilioRequestURL(c), c.GetHeader("X-Twilio-Signature"), c.Request.PostForm)
+	if err != nil {
+		respondSMSError(c, err)
+		return
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", []byte(emptyTwiML))
+}
+
+// HandleStatus handles POST /channels/twilio/sms/status
+func (h *SMSHandler) HandleStatus(c *gin.Context) {
+	if err := c.Request.ParseForm(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid form body"})
+		return
+	}
+	err := h.Service.HandleStatus(c.Request.Context(), twilioRequestURL(c), c.GetHeader("X-Twilio-Signature"), c.Request.PostForm)
+	if err != nil {
+		respondSMSError(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func respondSMSError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrSMSSignature):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrSMSChannelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+// twilioRequestURL rebuilds the public URL Twilio signed, honouring the headers set by TLS-terminating proxies.
+func twilioRequestURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return scheme + "://" + host + c.Request.URL.RequestURI()
+}
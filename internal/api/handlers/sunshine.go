@@ -0,0 +1,47 @@
+// Package handlers provides HTTP handlers for the Sunshine Conversations channel connector.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// maxSunshineBodyBytes bounds webhook requests; Sunshine batches events but media arrives as URLs
+const maxSunshineBodyBytes = 1 << 20
+
+// SunshineHandler receives Sunshine Conversations webhooks.
+type SunshineHandler struct {
+	Service *service.SunshineService
+}
+
+// NewSunshineHandler creates a new SunshineHandler.
+func NewSunshineHandler(svc *service.SunshineService) *SunshineHandler {
+	return &SunshineHandler{Service: svc}
+}
+
+// HandleWebhook handles POST /channels/sunshine/webhook
+func (h *SunshineHandler) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSunshineBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+
+	if err := h.Service.HandleWebhook(c.Request.Context(), c.GetHeader("X-API-Key"), body); err != nil {
+		switch {
+		case errors.Is(err, service.ErrSunshineUnauthorized):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrSunshineChannelNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,47 @@
+// Package handlers provides HTTP handlers for the Microsoft Teams channel connector.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// maxTeamsBodyBytes bounds inbound activities; cards submitted back to the bot are small
+const maxTeamsBodyBytes = 1 << 20
+
+// TeamsHandler is the messaging endpoint of Teams bots.
+type TeamsHandler struct {
+	Service *service.TeamsService
+}
+
+// NewTeamsHandler creates a new TeamsHandler.
+func NewTeamsHandler(svc *service.TeamsService) *TeamsHandler {
+	return &TeamsHandler{Service: svc}
+}
+
+// HandleActivity handles POST /channels/teams/messages
+func (h *TeamsHandler) HandleActivity(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxTeamsBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+
+	if err := h.Service.HandleActivity(c.Request.Context(), c.GetHeader("Authorization"), body); err != nil {
+		switch {
+		case errors.Is(err, service.ErrTeamsUnauthorized):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrTeamsChannelNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusOK)
+}
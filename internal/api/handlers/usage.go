@@ -0,0 +1,129 @@
+// Package handlers provides HTTP handlers for client usage and quotas.
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// UsageHandler handles client usage reports and quota configuration.
+type UsageHandler struct {
+	Service *service.UsageService
+}
+
+// NewUsageHandler creates a new UsageHandler.
+func NewUsageHandler(svc *service.UsageService) *UsageHandler {
+	return &UsageHandler{Service: svc}
+}
+
+// GetUsage handles GET /clients/:client_id/usage
+func (h *UsageHandler) GetUsage(c *gin.Context) {
+	months := 3
+	if v := c.Query("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > service.MaxUsageMonths {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("months must be between 1 and %d", service.MaxUsageMonths)})
+			return
+		}
+		months = n
+	}
+
+	usage, err := h.Service.GetUsage(c.Request.Context(), c.Param("client_id"), months)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, usage)
+}
+
+// ExportUsage handles GET /usage/export. It returns daily usage per client and metric between
+// start_date and end_date (YYYY-MM-DD, inclusive; the current month by default) as CSV, or as JSON
+// with format=json. client_id narrows the export to one client.
+func (h *UsageHandler) ExportUsage(c *gin.Context) {
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for param, target := range map[string]*time.Time{"start_date": &start, "end_date": &end} {
+		if v := c.Query(param); v != "" {
+			t, err := time.Parse("2006-01-02", v)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("invalid %s, expected YYYY-MM-DD", param)})
+				return
+			}
+			*target = t
+		}
+	}
+	if end.Before(start) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must not be before start_date"})
+		return
+	}
+	if end.Sub(start) >= service.MaxUsageExportDays*24*time.Hour {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("exports cover at most %d days", service.MaxUsageExportDays)})
+		return
+	}
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	records, err := h.Service.UsageRecords(c.Request.Context(), c.Query("client_id"), start, end)
+	if err != nil {
+		if errors.Is(err, service.ErrUsageClientNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	if format == "json" {
+		c.JSON(http.StatusOK, dto.UsageExportResponse{
+			StartDate: start.Format("2006-01-02"),
+			EndDate:   end.Format("2006-01-02"),
+			Records:   records,
+		})
+		return
+	}
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="usage_%s_%s.csv"`, start.Format("20060102"), end.Format("20060102")))
+	c.Status(http.StatusOK)
+	if err := service.WriteUsageCSV(c.Writer, records); err != nil {
+		_ = c.Error(err)
+		c.Abort()
+	}
+}
+
+// SetQuota handles PUT /clients/:client_id/quota
+func (h *UsageHandler) SetQuota(c *gin.Context) {
+	var req dto.UsageQuotaRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	quota := &models.UsageQuota{Messages: req.Messages, AICalls: req.AICalls}
+	if err := h.Service.SetQuota(c.Request.Context(), c.Param("client_id"), quota); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, quota)
+}
+
+// DeleteQuota handles DELETE /clients/:client_id/quota
+func (h *UsageHandler) DeleteQuota(c *gin.Context) {
+	if err := h.Service.DeleteQuota(c.Request.Context(), c.Param("client_id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
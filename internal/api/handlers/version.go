@@ -0,0 +1,22 @@
+// Package handlers provides the response to updates made at a stale version.
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// respondVersionConflict answers 409 with the current version when err is a version conflict, so
+// the client can re-read and retry. It reports whether it answered.
+func respondVersionConflict(c *gin.Context, err error) bool {
+	var conflict *service.VersionConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": conflict.Error(), "current_version": conflict.Current})
+	return true
+}
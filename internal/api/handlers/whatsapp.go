@@ -0,0 +1,83 @@
+// Package handlers provides HTTP handlers for the WhatsApp channel connector.
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// maxWhatsAppBodyBytes bounds inbound webhook requests; media arrives as ids, not content
+const maxWhatsAppBodyBytes = 1 << 20
+
+// WhatsAppHandler receives WhatsApp Cloud API webhooks and sends template messages.
+type WhatsAppHandler struct {
+	Service *service.WhatsAppService
+}
+
+// NewWhatsAppHandler creates a new WhatsAppHandler.
+func NewWhatsAppHandler(svc *service.WhatsAppService) *WhatsAppHandler {
+	return &WhatsAppHandler{Service: svc}
+}
+
+// VerifyWebhook handles GET /channels/whatsapp/webhook
+func (h *WhatsAppHandler) VerifyWebhook(c *gin.Context) {
+	challenge, err := h.Service.VerifyWebhook(c.Request.Context(), c.Query("hub.mode"), c.Query("hub.verify_token"), c.Query("hub.challenge"))
+	if err != nil {
+		if errors.Is(err, service.ErrWhatsAppVerifyToken) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	// Meta expects the challenge echoed as the raw body
+	c.String(http.StatusOK, challenge)
+}
+
+// HandleWebhook handles POST /channels/whatsapp/webhook
+func (h *WhatsAppHandler) HandleWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWhatsAppBodyBytes))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+		return
+	}
+
+	if err := h.Service.HandleWebhook(c.Request.Context(), c.GetHeader("X-Hub-Signature-256"), body); err != nil {
+		switch {
+		case errors.Is(err, service.ErrWhatsAppSignature):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		case errors.Is(err, service.ErrWhatsAppChannelNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// SendTemplate handles POST /clients/:client_id/channels/:channel_id/whatsapp/templates
+func (h *WhatsAppHandler) SendTemplate(c *gin.Context) {
+	var req dto.WhatsAppTemplateSendRequest
+	if err := bindJSON(c, &req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	msg, err := h.Service.SendTemplateMessage(c.Request.Context(), c.Param("client_id"), c.Param("channel_id"), &req)
+	if err != nil {
+		if errors.Is(err, service.ErrWhatsAppChannelNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, msg)
+}
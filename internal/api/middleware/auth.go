@@ -6,21 +6,84 @@ import (
 	"os"
 	"strings"
 
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/oidc"
+	"github.com/fraiday-org/api-service/internal/service"
 	"github.com/gin-gonic/gin"
-	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 )
 
-func AuthMiddleware(logger *zap.Logger, db *mongo.Database) gin.HandlerFunc {
+const (
+	// ContextKeyAPIKey holds the *models.APIKey of requests authenticated with a client API key
+	ContextKeyAPIKey = "api_key"
+	// ContextKeyUser holds the *oidc.Claims of requests authenticated with an OIDC token
+	ContextKeyUser = "oidc_user"
+)
+
+// inboundRoutes are the routes channel providers call. Providers send no API credentials, so the
+// handlers authenticate each call with the signature or token of the channel it is for.
+var inboundRoutes = []string{
+	"/api/v1/channels/slack/events",
+	"/api/v1/channels/whatsapp/webhook",
+	"/api/v1/channels/teams/messages",
+	"/api/v1/channels/email/:channel_id/sendgrid",
+	"/api/v1/channels/email/:channel_id/ses",
+	"/api/v1/channels/twilio/sms",
+	"/api/v1/channels/twilio/sms/status",
+	"/api/v1/channels/sunshine/webhook",
+	"/api/v1/channels/webhook/:channel_id",
+}
+
+// isPublicPath reports whether path, a request path or a registered route, can be called without
+// credentials.
+func isPublicPath(path string) bool {
+	if path == "/api/v1/health" || path == "/api/v1/ping" || path == "/api/v1/readiness" || path == "/api/v1/healthz" || path == "/api/v1/metrics" || path == "/metrics" || path == "/api/v1/auth/oidc/config" || strings.HasPrefix(path, "/docs") {
+		return true
+	}
+	for _, route := range inboundRoutes {
+		if matchesRoute(route, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesRoute reports whether path matches route, whose :parameters match any one segment.
+func matchesRoute(route, path string) bool {
+	routeSegments := strings.Split(route, "/")
+	pathSegments := strings.Split(path, "/")
+	if len(routeSegments) != len(pathSegments) {
+		return false
+	}
+	for i, segment := range routeSegments {
+		if strings.HasPrefix(segment, ":") {
+			if pathSegments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// AuthMiddleware identifies the caller and stores their Principal for Authorize. It accepts the
+// admin API key, as a bearer token or basic credentials, client API keys issued by apiKeys and,
+// when verifier is set, OIDC tokens of dashboard users, whose roles come from the token and from
+// roles.
+func AuthMiddleware(logger *zap.Logger, apiKeys *service.APIKeyService, verifier *oidc.Verifier, roles *service.RoleService) gin.HandlerFunc {
 	adminAPIKey := os.Getenv("ADMIN_API_KEY")
 	if adminAPIKey == "" {
 		adminAPIKey = "sample-api-key" // fallback
 	}
+	admin := &Principal{Roles: []models.Role{models.RoleAdmin}}
 
 	return func(c *gin.Context) {
 		// Allow unauthenticated access to health endpoints
 		path := c.Request.URL.Path
-		if path == "/api/v1/health" || path == "/api/v1/ping" || path == "/api/v1/readiness" || path == "/api/v1/healthz" || path == "/api/v1/metrics" || strings.HasPrefix(path, "/docs") {
+		if isPublicPath(path) {
 			c.Next()
 			return
 		}
@@ -36,9 +99,38 @@ func AuthMiddleware(logger *zap.Logger, db *mongo.Database) gin.HandlerFunc {
 			apiKey := strings.TrimPrefix(authHeader, "Bearer ")
 			if apiKey == adminAPIKey {
 				c.Set("auth_type", "api_key")
+				c.Set(ContextKeyPrincipal, admin)
 				c.Next()
 				return
 			}
+			if verifier != nil && oidc.LooksLikeJWT(apiKey) {
+				claims, err := verifier.Verify(c.Request.Context(), apiKey)
+				if err != nil {
+					logger.Warn("OIDC token rejected", zap.String("path", path), zap.Error(err))
+					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+					return
+				}
+				principal := userPrincipal(c, claims, roles)
+				if len(principal.Roles) == 0 {
+					logger.Warn("OIDC user has no roles", zap.String("path", path), zap.String("subject", claims.Subject))
+					c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "user has no roles"})
+					return
+				}
+				c.Set("auth_type", "oidc")
+				c.Set(ContextKeyUser, claims)
+				c.Set(ContextKeyPrincipal, principal)
+				c.Next()
+				return
+			}
+			if apiKeys != nil {
+				if key, err := apiKeys.AuthenticateAPIKey(c.Request.Context(), apiKey); err == nil {
+					c.Set("auth_type", "client_api_key")
+					c.Set(ContextKeyAPIKey, key)
+					c.Set(ContextKeyPrincipal, APIKeyPrincipal(key))
+					c.Next()
+					return
+				}
+			}
 		}
 
 		// Check for Basic Auth (for AI service communication)
@@ -50,6 +142,7 @@ func AuthMiddleware(logger *zap.Logger, db *mongo.Database) gin.HandlerFunc {
 				// Expected format: "username:password" or just validate the token
 				if credentials == adminAPIKey || strings.Contains(credentials, adminAPIKey) {
 					c.Set("auth_type", "basic")
+					c.Set(ContextKeyPrincipal, admin)
 					c.Next()
 					return
 				}
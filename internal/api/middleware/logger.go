@@ -3,6 +3,7 @@ package middleware
 import (
 	"time"
 
+	"github.com/fraiday-org/api-service/internal/telemetry"
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
 )
@@ -18,14 +19,13 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 		latency := time.Since(start)
 		status := c.Writer.Status()
 
-		logger.Info("request",
+		telemetry.Logger(c.Request.Context(), logger).Info("request",
 			zap.String("method", c.Request.Method),
 			zap.String("path", path),
 			zap.String("query", raw),
 			zap.Int("status", status),
 			zap.Duration("latency", latency),
 			zap.String("client_ip", c.ClientIP()),
-			zap.String("request_id", c.GetString("request_id")),
 		)
 	}
 }
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/oidc"
+	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// RateLimit limits the routes it is attached to. Client API keys are held to their tier and
+// dashboard users to the default tier, and every caller is also limited by client IP. Admins
+// are not limited. The headers describe whichever limit is closest to running out. When the
+// bucket store fails the request is let through, so an outage doesn't take the API down with it.
+func RateLimit(limiter *service.RateLimitService, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if value, ok := c.Get(ContextKeyPrincipal); ok && value.(*Principal).Can(models.PermissionSystem) {
+			c.Next()
+			return
+		}
+
+		buckets := map[string]service.RateLimit{"ip:" + c.ClientIP(): limiter.IPLimit}
+		if value, ok := c.Get(ContextKeyAPIKey); ok {
+			key := value.(*models.APIKey)
+			buckets["api_key:"+key.ID.Hex()] = limiter.Tier(key.Tier)
+		} else if value, ok := c.Get(ContextKeyUser); ok {
+			buckets["user:"+value.(*oidc.Claims).Subject] = limiter.Tier(service.DefaultRateLimitTier)
+		}
+
+		var tightest, rejected *service.RateLimitResult
+		for bucket, limit := range buckets {
+			if limit.Requests <= 0 {
+				continue
+			}
+			result, err := limiter.Take(c.Request.Context(), bucket, limit)
+			if err != nil {
+				logger.Warn("Rate limit check failed, allowing request", zap.String("bucket", bucket), zap.Error(err))
+				continue
+			}
+			if !result.Allowed && (rejected == nil || result.RetryAfter > rejected.RetryAfter) {
+				rejected = result
+			}
+			if tightest == nil || result.Remaining < tightest.Remaining {
+				tightest = result
+			}
+		}
+		if rejected != nil {
+			tightest = rejected
+		}
+		if tightest == nil {
+			c.Next()
+			return
+		}
+
+		header := c.Writer.Header()
+		header.Set("X-RateLimit-Limit", strconv.Itoa(tightest.Limit))
+		header.Set("X-RateLimit-Remaining", strconv.Itoa(tightest.Remaining))
+		header.Set("X-RateLimit-Reset", strconv.FormatInt(int64(math.Ceil(float64(tightest.ResetAt.UnixMilli())/1000)), 10))
+		if rejected != nil {
+			header.Set("Retry-After", strconv.Itoa(int(math.Ceil(rejected.RetryAfter.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "rate limit exceeded"})
+			return
+		}
+		c.Next()
+	}
+}
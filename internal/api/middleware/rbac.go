@@ -0,0 +1,217 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/oidc"
+	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyPrincipal holds the *Principal of every authenticated request
+const ContextKeyPrincipal = "principal"
+
+// ContextKeyClient holds the *models.Client that a caller limited to one client is limited to
+const ContextKeyClient = "client"
+
+// Ownership finds the clients that requests address; service.ClientOwnership implements it.
+type Ownership interface {
+	// Client returns the client ref names, by id or by client_id.
+	Client(ctx context.Context, ref string) (*models.Client, error)
+	// Owner returns the id or client_id of the client owning the record that route parameter
+	// param names, and false for parameters that don't name records of a single client.
+	Owner(ctx context.Context, param, value string) (string, bool, error)
+}
+
+// RoutePermissions maps "METHOD /route/path" to the permission the route requires.
+type RoutePermissions map[string]models.Permission
+
+// Principal is what an authenticated caller may do.
+type Principal struct {
+	Roles []models.Role
+	// Permissions are granted on top of Roles; API key scopes end up here
+	Permissions []models.Permission
+	// ClientRefs, when set, limit the caller to one client, addressed by any of these forms
+	ClientRefs []string
+}
+
+// Can reports whether the principal holds permission.
+func (p *Principal) Can(permission models.Permission) bool {
+	for _, role := range p.Roles {
+		if role.Grants(permission) {
+			return true
+		}
+	}
+	for _, granted := range p.Permissions {
+		if granted == permission {
+			return true
+		}
+	}
+	return permission == models.PermissionAuthenticated
+}
+
+// AllowsClient reports whether the principal may address the client ref.
+func (p *Principal) AllowsClient(ref string) bool {
+	if len(p.ClientRefs) == 0 {
+		return true
+	}
+	for _, allowed := range p.ClientRefs {
+		if allowed == ref {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyPrincipal grants a client API key its scopes' permissions and its roles, within its client.
+func APIKeyPrincipal(key *models.APIKey) *Principal {
+	principal := &Principal{Roles: key.Roles, ClientRefs: []string{key.Client.Hex(), key.ClientID}}
+	for _, scope := range key.Scopes {
+		principal.Permissions = append(principal.Permissions, scope.Permissions()...)
+	}
+	return principal
+}
+
+// userPrincipal combines the known roles of an OIDC token with the roles assigned to the user.
+// A client named by the token takes precedence over the assigned one.
+func userPrincipal(c *gin.Context, claims *oidc.Claims, roles *service.RoleService) *Principal {
+	principal := &Principal{}
+	seen := map[models.Role]bool{}
+	add := func(role models.Role) {
+		if role.IsValid() && !seen[role] {
+			seen[role] = true
+			principal.Roles = append(principal.Roles, role)
+		}
+	}
+	for _, role := range claims.Roles {
+		add(models.Role(role))
+	}
+	clientID := claims.ClientID
+	if roles != nil {
+		assigned, assignedClient := roles.AssignmentFor(c.Request.Context(), claims.Subject)
+		for _, role := range assigned {
+			add(role)
+		}
+		if clientID == "" {
+			clientID = assignedClient
+		}
+	}
+	if clientID != "" {
+		principal.ClientRefs = []string{clientID}
+	}
+	return principal
+}
+
+// Authorize enforces the permission each route declares in permissions. Routes missing from
+// permissions need PermissionSystem, so only admins reach them. Callers limited to a client can
+// only address that client and the records ownership finds it owns; without ownership they are
+// only checked on routes with a :client_id parameter.
+func Authorize(permissions RoutePermissions, ownership Ownership) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(ContextKeyPrincipal)
+		if !ok || c.FullPath() == "" {
+			// Public route, or no route matched and the request ends in 404
+			c.Next()
+			return
+		}
+		principal := value.(*Principal)
+
+		permission, declared := permissions[c.Request.Method+" "+c.FullPath()]
+		if !declared {
+			permission = models.PermissionSystem
+		}
+		if !principal.Can(permission) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "missing permission " + string(permission)})
+			return
+		}
+		if len(principal.ClientRefs) > 0 && ownership != nil {
+			if !limitToClient(c, principal, ownership) {
+				return
+			}
+		} else if clientRef := c.Param("client_id"); clientRef != "" && !principal.AllowsClient(clientRef) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed for this client"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// limitToClient keeps a caller limited to a client to that client: the client named by :client_id
+// or the client_id query, and the records other route parameters name, which end in 404 when
+// they don't exist. The client is stored under ContextKeyClient for handlers, which limit
+// listings and the records named in request bodies to it.
+func limitToClient(c *gin.Context, principal *Principal, ownership Ownership) bool {
+	ctx := c.Request.Context()
+	client, err := ownership.Client(ctx, principal.ClientRefs[0])
+	if errors.Is(err, service.ErrOwnerNotFound) {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "client not found"})
+		return false
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to look up client"})
+		return false
+	}
+	allowed := func(ref string) bool {
+		return ref == client.ID.Hex() || ref == client.ClientID
+	}
+
+	for _, ref := range []string{c.Param("client_id"), c.Query("client_id")} {
+		if ref != "" && !allowed(ref) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed for this client"})
+			return false
+		}
+	}
+	for _, param := range c.Params {
+		owner, owned, err := ownership.Owner(ctx, param.Key, param.Value)
+		switch {
+		case errors.Is(err, service.ErrOwnerNotFound):
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": param.Key + " not found"})
+			return false
+		case err != nil:
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to look up " + param.Key})
+			return false
+		case owned && !allowed(owner):
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "not allowed for this client"})
+			return false
+		}
+	}
+	c.Set(ContextKeyClient, client)
+	return true
+}
+
+// UndeclaredRoutes returns the registered routes that need credentials but have no entry in
+// permissions, and the entries that match no registered route.
+func UndeclaredRoutes(routes gin.RoutesInfo, permissions RoutePermissions) (undeclared, unknown []string) {
+	registered := map[string]bool{}
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if _, ok := permissions[key]; !ok && !isPublicPath(route.Path) {
+			undeclared = append(undeclared, key)
+		}
+	}
+	for key := range permissions {
+		if !registered[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	return undeclared, unknown
+}
+
+// APIRoutes describes the registered routes with the permission each requires, for the API specs.
+func APIRoutes(routes gin.RoutesInfo, permissions RoutePermissions) []service.APIRoute {
+	described := make([]service.APIRoute, 0, len(routes))
+	for _, route := range routes {
+		described = append(described, service.APIRoute{
+			Method:     route.Method,
+			Path:       route.Path,
+			Handler:    route.Handler,
+			Permission: permissions[route.Method+" "+route.Path],
+			Public:     isPublicPath(route.Path),
+		})
+	}
+	return described
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// fakeOwnership knows a fixed set of clients and the owners of a fixed set of records.
+type fakeOwnership struct {
+	clients []*models.Client
+	// owners maps "param=value" to the owning client's id
+	owners map[string]string
+}
+
+func (f *fakeOwnership) Client(ctx context.Context, ref string) (*models.Client, error) {
+	for _, client := range f.clients {
+		if ref == client.ID.Hex() || ref == client.ClientID {
+			return client, nil
+		}
+	}
+	return nil, service.ErrOwnerNotFound
+}
+
+func (f *fakeOwnership) Owner(ctx context.Context, param, value string) (string, bool, error) {
+	if param != "session_id" && param != "message_id" {
+		return "", false, nil
+	}
+	owner, ok := f.owners[param+"="+value]
+	if !ok {
+		return "", true, service.ErrOwnerNotFound
+	}
+	return owner, true, nil
+}
+
+// authorizeRouter serves a few client and record routes to principal behind Authorize.
+func authorizeRouter(principal *Principal, ownership Ownership) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(ContextKeyPrincipal, principal)
+		c.Next()
+	})
+	r.Use(Authorize(RoutePermissions{
+		"GET /api/v1/clients/:client_id":   models.PermissionClientsRead,
+		"GET /api/v1/sessions":             models.PermissionSessionsRead,
+		"GET /api/v1/sessions/:session_id": models.PermissionSessionsRead,
+		"PUT /api/v1/messages/:message_id": models.PermissionMessagesWrite,
+	}, ownership))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	r.GET("/api/v1/clients/:client_id", ok)
+	r.GET("/api/v1/sessions", ok)
+	r.GET("/api/v1/sessions/:session_id", ok)
+	r.PUT("/api/v1/messages/:message_id", ok)
+	return r
+}
+
+// TestAuthorizeCrossClientKey tests that a client API key only reaches its own client's records,
+// on routes with and without a :client_id parameter
+func TestAuthorizeCrossClientKey(t *testing.T) {
+	acme := &models.Client{ID: primitive.NewObjectID(), ClientID: "acme"}
+	globex := &models.Client{ID: primitive.NewObjectID(), ClientID: "globex"}
+	acmeSession, globexSession := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	acmeMessage, globexMessage := primitive.NewObjectID().Hex(), primitive.NewObjectID().Hex()
+	ownership := &fakeOwnership{
+		clients: []*models.Client{acme, globex},
+		owners: map[string]string{
+			"session_id=" + acmeSession:   acme.ID.Hex(),
+			"session_id=" + globexSession: globex.ID.Hex(),
+			"message_id=" + acmeMessage:   acme.ID.Hex(),
+			"message_id=" + globexMessage: globex.ID.Hex(),
+		},
+	}
+	key := &models.APIKey{
+		Client:   acme.ID,
+		ClientID: acme.ClientID,
+		Scopes:   []models.APIKeyScope{models.APIKeyScopeAdmin},
+	}
+	r := authorizeRouter(APIKeyPrincipal(key), ownership)
+
+	cases := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/api/v1/clients/acme", http.StatusOK},
+		{"GET", "/api/v1/clients/" + acme.ID.Hex(), http.StatusOK},
+		{"GET", "/api/v1/clients/globex", http.StatusForbidden},
+		{"GET", "/api/v1/sessions?client_id=acme", http.StatusOK},
+		{"GET", "/api/v1/sessions?client_id=" + globex.ID.Hex(), http.StatusForbidden},
+		{"GET", "/api/v1/sessions/" + acmeSession, http.StatusOK},
+		{"GET", "/api/v1/sessions/" + globexSession, http.StatusForbidden},
+		{"GET", "/api/v1/sessions/" + primitive.NewObjectID().Hex(), http.StatusNotFound},
+		{"PUT", "/api/v1/messages/" + acmeMessage, http.StatusOK},
+		{"PUT", "/api/v1/messages/" + globexMessage, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.want, w.Code, "%s %s", tc.method, tc.path)
+	}
+}
+
+// TestAuthorizeClientUser tests that an OIDC user limited to a client by its client_id is kept to
+// that client's records too
+func TestAuthorizeClientUser(t *testing.T) {
+	acme := &models.Client{ID: primitive.NewObjectID(), ClientID: "acme"}
+	globex := &models.Client{ID: primitive.NewObjectID(), ClientID: "globex"}
+	globexSession := primitive.NewObjectID().Hex()
+	ownership := &fakeOwnership{
+		clients: []*models.Client{acme, globex},
+		owners:  map[string]string{"session_id=" + globexSession: globex.ID.Hex()},
+	}
+	user := &Principal{Roles: []models.Role{models.RoleAgent}, ClientRefs: []string{"acme"}}
+	r := authorizeRouter(user, ownership)
+
+	for path, want := range map[string]int{
+		"/api/v1/clients/" + acme.ID.Hex():  http.StatusOK,
+		"/api/v1/sessions/" + globexSession: http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, want, w.Code, path)
+	}
+}
+
+// TestAuthorizeAdmin tests that callers without a client reach every client's records
+func TestAuthorizeAdmin(t *testing.T) {
+	globexSession := primitive.NewObjectID().Hex()
+	admin := &Principal{Roles: []models.Role{models.RoleAdmin}}
+	r := authorizeRouter(admin, &fakeOwnership{})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/sessions/"+globexSession, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -1,18 +1,23 @@
 package middleware
 
 import (
+	"github.com/fraiday-org/api-service/internal/telemetry"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// RequestID assigns each request an ID, reusing the caller's X-Request-ID when it is well formed.
+// The ID is returned in the response and carried by the request context into the tasks, events
+// and webhook deliveries the request causes.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
+		requestID := c.GetHeader(telemetry.RequestIDHeader)
+		if !telemetry.ValidRequestID(requestID) {
 			requestID = uuid.New().String()
 		}
 		c.Set("request_id", requestID)
-		c.Writer.Header().Set("X-Request-ID", requestID)
+		c.Request = c.Request.WithContext(telemetry.WithRequestID(c.Request.Context(), requestID))
+		c.Writer.Header().Set(telemetry.RequestIDHeader, requestID)
 		c.Next()
 	}
 }
@@ -1,28 +1,106 @@
 package routes
 
 import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.uber.org/zap"
 
 	"github.com/fraiday-org/api-service/internal/api/handlers"
 	"github.com/fraiday-org/api-service/internal/api/middleware"
+	"github.com/fraiday-org/api-service/internal/cache"
 	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/grpcapi"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/oidc"
+	"github.com/fraiday-org/api-service/internal/realtime"
 	"github.com/fraiday-org/api-service/internal/repository"
 	"github.com/fraiday-org/api-service/internal/service"
 	"github.com/fraiday-org/api-service/internal/tasks"
+	"github.com/fraiday-org/api-service/internal/utils"
 )
 
-func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client) {
+// Register registers the REST routes on r. When GRPC_PORT is set it also returns the internal gRPC
+// API over the same services, for the caller to serve.
+func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient *mongo.Client) *grpcapi.Server {
 	db := mongoClient.Database(cfg.MongoDB)
+	// Reports and exports read here, off the primary when MONGODB_ANALYTICS_READ_PREFERENCE allows
+	analyticsDB, err := repository.AnalyticsDatabase(mongoClient, cfg)
+	if err != nil {
+		logger.Warn("Invalid analytics read preference, reading analytics from the primary", zap.Error(err))
+		analyticsDB = db
+	}
+
+
+	// Client API keys, checked by the auth middleware alongside the admin key
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, repository.NewClientRepository(db))
+
+	// Rate limits for message creation, which triggers the AI workflow, shared across instances
+	rateLimitRepo := repository.NewRateLimitRepository(db)
+	rateLimitTiers := service.ParseRateLimitTiers(cfg.RateLimitTiers)
+	if _, ok := rateLimitTiers[service.DefaultRateLimitTier]; !ok {
+		logger.Warn("RATE_LIMIT_TIERS has no default tier, keys without a tier are not limited")
+	}
+	rateLimitService := service.NewRateLimitService(rateLimitRepo, rateLimitTiers, service.RateLimit{Requests: cfg.RateLimitIPPerMinute, Window: time.Minute})
+	apiKeyService.RateLimits = rateLimitService
+	rateLimit := middleware.RateLimit(rateLimitService, logger)
 
+	// Roles assigned to dashboard users, on top of those their identity provider sends
+	roleAssignmentRepo := repository.NewRoleAssignmentRepository(db)
+	roleService := service.NewRoleService(roleAssignmentRepo)
 
-	// Auth middleware (protects all except /auth/login, /health, /ping, /docs)
-	r.Use(middleware.AuthMiddleware(logger, db))
+	// OIDC login for dashboard users, alongside API keys for integrations
+	var oidcVerifier *oidc.Verifier
+	if cfg.OIDCIssuer != "" {
+		if cfg.OIDCAudience == "" {
+			logger.Warn("OIDC_ISSUER is set without OIDC_AUDIENCE, OIDC login is disabled")
+		} else {
+			oidcVerifier = oidc.NewVerifier(cfg.OIDCIssuer, cfg.OIDCAudience)
+			oidcVerifier.RolesClaim = cfg.OIDCRolesClaim
+			oidcVerifier.RoleMapping = oidc.ParseRoleMapping(cfg.OIDCRoleMapping)
+			oidcVerifier.ClientClaim = cfg.OIDCClientClaim
+		}
+	}
 
+	// Auth middleware (protects all except /auth/login, /health, /ping, /docs), then the
+	// permission each route declares in routePermissions, within the caller's client
+	clientOwnership := service.NewClientOwnership(db)
+	r.Use(middleware.AuthMiddleware(logger, apiKeyService, oidcVerifier, roleService))
+	r.Use(middleware.Authorize(routePermissions, clientOwnership))
+	// API specs, built once every route below is registered
+	apiSpecService := service.NewAPISpecService(cfg.Version)
+	docsHandler := handlers.NewDocsHandler(apiSpecService)
+	r.GET("/api/v1/docs/openapi.json", docsHandler.OpenAPI)
+	r.GET("/api/v1/docs/asyncapi.json", docsHandler.AsyncAPI)
+	defer func() {
+		apiSpecService.SetRoutes(middleware.APIRoutes(r.Routes(), routePermissions))
+		undeclared, unknown := middleware.UndeclaredRoutes(r.Routes(), routePermissions)
+		for _, route := range undeclared {
+			logger.Warn("Route has no permission declaration, only admins can call it", zap.String("route", route))
+		}
+		for _, route := range unknown {
+			logger.Warn("Permission declared for a route that is not registered", zap.String("route", route))
+		}
+	}()
 
 	// Health and Monitoring
-	healthHandler := handlers.NewHealthHandler(cfg, logger, mongoClient)
+	healthService := service.NewHealthService(cfg.Version, cfg.ReadinessTimeout,
+		service.MongoProbe(mongoClient),
+		service.RabbitMQProbe(cfg.GetRabbitMQURL()),
+	)
+	if cfg.RedisHost != "" {
+		healthService.Probes = append(healthService.Probes, service.RedisProbe(net.JoinHostPort(cfg.RedisHost, strconv.Itoa(cfg.RedisPort)), cfg.RedisPassword))
+	}
+	if cfg.AIServiceURL != "" {
+		healthService.Probes = append(healthService.Probes, service.AIServiceProbe(service.NewAIService(logger, cfg.AIServiceURL, cfg.SlackAIToken)))
+	}
+	healthHandler := handlers.NewHealthHandler(cfg, logger, mongoClient, healthService)
 	r.GET("/api/v1/health", healthHandler.Health)
 	r.GET("/api/v1/ping", healthHandler.Ping)
 	r.GET("/api/v1/readiness", healthHandler.Readiness)
@@ -31,6 +109,12 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	// Metrics
 	metricsHandler := handlers.NewMetricsHandler(logger)
 	r.GET("/api/v1/metrics", metricsHandler.GetMetrics)
+	r.GET("/metrics", metricsHandler.GetMetrics)
+
+	// Auth
+	authHandler := handlers.NewAuthHandler(logger, oidcVerifier)
+	r.GET("/api/v1/auth/oidc/config", authHandler.OIDCConfig)
+	r.GET("/api/v1/auth/me", authHandler.Me)
 
 	// Clients (moved up for use in message creation)
 	clientRepo := repository.NewClientRepository(db)
@@ -40,18 +124,43 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	// Client Channels
 	clientChannelRepo := repository.NewClientChannelRepository(db)
 	clientChannelService := service.NewClientChannelService(clientChannelRepo, clientRepo)
+	// Channel credentials are sealed with ENCRYPTION_KEY; without one none can be stored
+	clientChannelService.Secrets, _ = utils.NewSecretBox(cfg.EncryptionKey)
 	clientChannelHandler := handlers.NewClientChannelHandler(logger)
 
+	// Every message looks up its client and channel; the cache drops them when they change
+	var clientCache *service.MemoryClientCache
+	if cfg.ClientCacheTTL > 0 {
+		clientCache = service.NewMemoryClientCache(clientRepo, clientChannelRepo, cfg.ClientCacheTTL)
+	}
+
 	// Chat Sessions
 	chatSessionRepo := repository.NewChatSessionRepository(db)
 	chatSessionService := service.NewChatSessionService(chatSessionRepo)
 	chatSessionHandler := handlers.NewChatSessionHandler(chatSessionService)
 
+	// Feature flags roll features out to some clients first; threading falls back to its flag
+	featureFlagRepo := repository.NewFeatureFlagRepository(db)
+	featureFlagService := service.NewFeatureFlagService(featureFlagRepo, logger)
+	chatSessionService.ThreadManager.Flags = featureFlagService
+	if clientCache != nil {
+		chatSessionService.ThreadManager.Clients = clientCache
+		clientOwnership.Clients = clientCache
+	}
+
 	// Initialize event services for chat message events
 	eventRepo := repository.NewEventRepository(db)
 	eventService := service.NewEventService(eventRepo)
 	eventProcessorConfigRepo := repository.NewEventProcessorConfigRepository(db)
 	eventProcessorConfigService := service.NewEventProcessorConfigService(eventProcessorConfigRepo)
+	// Credentials in processor and channel configs may be secret:// references, resolved when used
+	secretResolver := service.NewSecretResolver(cfg)
+	// Sends test events to webhook processors
+	eventProcessorConfigService.Dispatcher = service.NewProcessorDispatchService(logger, nil)
+	eventProcessorConfigService.Dispatcher.SecretResolver = secretResolver
+	if err := eventProcessorConfigService.Dispatcher.Configure(cfg); err != nil {
+		logger.Warn("Invalid webhook dispatch settings, test events use the defaults", zap.Error(err))
+	}
 	eventDeliveryRepo := repository.NewEventDeliveryRepository(db)
 	eventDeliveryAttemptRepo := repository.NewEventDeliveryAttemptRepository(db)
 	eventDeliveryTrackingService := service.NewEventDeliveryTrackingService(eventDeliveryRepo, eventDeliveryAttemptRepo)
@@ -65,6 +174,50 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	if err != nil {
 		logger.Warn("Failed to create task client for API server, events will be processed directly", zap.Error(err))
 		taskClient = nil
+	} else {
+		// New messages enqueue their chat and suggestion workflows through it
+		service.SetWorkflowTaskClient(taskClient, logger)
+		service.SetWorkflowBackpressure(service.NewBackpressure(taskClient, cfg, logger))
+	}
+
+	// Cache invalidation bus; without it caches on other nodes only catch up on expiry
+	cacheBus, err := cache.NewBus(rabbitMQURL, cfg.CacheInvalidationExchange, logger)
+	if err != nil {
+		logger.Warn("Failed to create cache invalidation bus for API server", zap.Error(err))
+		// Changes made on this node still drop its own cached clients
+		if clientCache != nil {
+			clientService.Invalidator = clientCache
+			clientChannelService.Invalidator = clientCache
+		}
+	} else {
+		clientService.Invalidator = cacheBus
+		clientChannelService.Invalidator = cacheBus
+		eventProcessorConfigService.Invalidator = cacheBus
+		featureFlagService.Invalidator = cacheBus
+		cacheBus.Subscribe(cache.KindFeatureFlag, featureFlagService.Forget)
+		if clientCache != nil {
+			cacheBus.Subscribe(cache.KindClient, clientCache.Forget)
+			cacheBus.Subscribe(cache.KindClientChannel, clientCache.Forget)
+		}
+	}
+
+	// Usage metering and quotas; message creation is checked against them
+	usageService := service.NewUsageService(repository.NewClientUsageRepository(db), clientRepo, chatSessionRepo, logger)
+	if cacheBus != nil {
+		usageService.Invalidator = cacheBus
+	} else if clientCache != nil {
+		usageService.Invalidator = clientCache
+	}
+	if clientCache != nil {
+		usageService.Clients = clientCache
+	}
+
+	// Session notifications wake long-poll requests when a session is written to on any node
+	notificationHub, err := realtime.NewHub(rabbitMQURL, cfg.SessionNotificationExchange, logger)
+	if err != nil {
+		logger.Warn("Failed to create session notification hub, long polls fall back to rechecking", zap.Error(err))
+	} else {
+		eventService.Notifier = notificationHub
 	}
 	
 	// Initialize PayloadService with ThreadManagerService from ChatSessionService first
@@ -74,20 +227,83 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	eventPublisherService := service.NewEventPublisherService(eventService, eventProcessorConfigService, eventDeliveryTrackingService, chatSessionRepo, chatMsgRepo, nil, nil, nil, payloadService, taskClient)
 	
 	chatMsgService := service.NewChatMessageService(chatMsgRepo, eventPublisherService, payloadService)
+	chatMsgService.ChatSessionRepo = chatSessionRepo
+	chatMsgService.Usage = usageService
+	transactor, err := repository.NewTransactor(context.Background(), mongoClient, cfg.MongoTransactions)
+	if err != nil {
+		logger.Warn("Storing messages without transactions", zap.Error(err))
+	}
+	chatMsgService.Transactions = transactor
+	contactService := service.NewContactService(repository.NewContactRepository(db), clientRepo, chatSessionRepo, chatMsgRepo)
+	chatMsgService.Contacts = contactService
+	payloadService.Contacts = contactService
+	if cacheBus != nil {
+		contactService.Invalidator = cacheBus
+	}
 	
 	// Update PayloadService with ChatMessageService
 	payloadService.ChatMessageService = chatMsgService
 	
 	chatMsgHandler := handlers.NewChatMessageHandler(chatMsgService, chatSessionService, clientService, clientChannelService)
+	if clientCache != nil {
+		chatMsgHandler.Clients = clientCache
+	}
 
-	r.POST("/api/v1/messages", chatMsgHandler.CreateMessage)
+	r.POST("/api/v1/messages", rateLimit, chatMsgHandler.CreateMessage)
 	r.GET("/api/v1/messages", chatMsgHandler.ListMessages)
-	r.PUT("/api/v1/messages/:id", chatMsgHandler.UpdateMessage)
-	r.POST("/api/v1/messages/bulk", chatMsgHandler.BulkCreateMessages)
+	r.PUT("/api/v1/messages/:message_id", chatMsgHandler.UpdateMessage)
+	r.GET("/api/v1/messages/:message_id/replies", chatMsgHandler.ListReplies)
+	r.POST("/api/v1/messages/bulk", rateLimit, chatMsgHandler.BulkCreateMessages)
+
+	// Canned responses, and sending one as a message
+	cannedResponseService := service.NewCannedResponseService(repository.NewCannedResponseRepository(db), clientRepo)
+	chatMsgHandler.CannedResponseService = cannedResponseService
+	cannedResponseHandler := handlers.NewCannedResponseHandler(cannedResponseService)
+	r.POST("/api/v1/messages/canned", rateLimit, chatMsgHandler.CreateCannedMessage)
+	r.POST("/api/v1/clients/:client_id/canned-responses", cannedResponseHandler.CreateCannedResponse)
+	r.GET("/api/v1/clients/:client_id/canned-responses", cannedResponseHandler.ListCannedResponses)
+	r.GET("/api/v1/clients/:client_id/canned-responses/:canned_response_id", cannedResponseHandler.GetCannedResponse)
+	r.PUT("/api/v1/clients/:client_id/canned-responses/:canned_response_id", cannedResponseHandler.UpdateCannedResponse)
+	r.DELETE("/api/v1/clients/:client_id/canned-responses/:canned_response_id", cannedResponseHandler.DeleteCannedResponse)
+
+	// Contacts, the end users of a client, and their history across sessions
+	contactHandler := handlers.NewContactHandler(contactService)
+	r.POST("/api/v1/clients/:client_id/contacts", contactHandler.CreateContact)
+	r.GET("/api/v1/clients/:client_id/contacts", contactHandler.ListContacts)
+	r.GET("/api/v1/clients/:client_id/contacts/:contact_id", contactHandler.GetContact)
+	r.PUT("/api/v1/clients/:client_id/contacts/:contact_id", contactHandler.UpdateContact)
+	r.DELETE("/api/v1/clients/:client_id/contacts/:contact_id", contactHandler.DeleteContact)
+	r.POST("/api/v1/clients/:client_id/contacts/:contact_id/merge", contactHandler.MergeContact)
+	r.GET("/api/v1/clients/:client_id/contacts/:contact_id/sessions", contactHandler.ListContactSessions)
+	r.GET("/api/v1/clients/:client_id/contacts/:contact_id/messages", contactHandler.ListContactMessages)
+	r.GET("/api/v1/clients/:client_id/contacts/:contact_id/consent", contactHandler.GetConsent)
+	r.PUT("/api/v1/clients/:client_id/contacts/:contact_id/consent", contactHandler.SetConsent)
+	// Whether workers give the AI a returning contact's earlier sessions
+	r.GET("/api/v1/clients/:client_id/contact-history", contactHandler.GetHistoryPolicy)
+	r.PUT("/api/v1/clients/:client_id/contact-history", contactHandler.SetHistoryPolicy)
+	r.DELETE("/api/v1/clients/:client_id/contact-history", contactHandler.DeleteHistoryPolicy)
+
+	// Scheduled messages (delivered by scheduled_message tasks)
+	var scheduledMsgTaskClient service.ScheduledMessageTaskClient
+	if taskClient != nil {
+		scheduledMsgTaskClient = taskClient
+	}
+	scheduledMsgService := service.NewScheduledMessageService(repository.NewScheduledMessageRepository(db), chatMsgService, chatSessionService, clientService, clientChannelService, scheduledMsgTaskClient)
+	scheduledMsgHandler := handlers.NewScheduledMessageHandler(scheduledMsgService)
+	r.POST("/api/v1/messages/schedule", rateLimit, scheduledMsgHandler.ScheduleMessage)
+	r.GET("/api/v1/messages/scheduled", scheduledMsgHandler.ListScheduledMessages)
+	r.GET("/api/v1/messages/scheduled/:scheduled_id", scheduledMsgHandler.GetScheduledMessage)
+	r.POST("/api/v1/messages/scheduled/:scheduled_id/cancel", scheduledMsgHandler.CancelScheduledMessage)
 
 	// Chat Message Feedback
 	chatMsgFeedbackRepo := repository.NewChatMessageFeedbackRepository(db)
 	chatMsgFeedbackService := service.NewChatMessageFeedbackService(chatMsgFeedbackRepo)
+	// Feedback on AI answers is queued here and forwarded to the AI service by workers
+	aiFeedbackService := service.NewAIFeedbackService(chatMsgFeedbackRepo, clientRepo, chatSessionRepo, chatMsgRepo, service.NewAIService(logger, cfg.AIServiceURL, cfg.SlackAIToken), cfg.AIFeedbackURL, cfg.AIFeedbackBatchSize, logger)
+	if cacheBus != nil {
+		aiFeedbackService.Invalidator = cacheBus
+	}
+	chatMsgFeedbackService.Forwarding = aiFeedbackService
 	chatMsgFeedbackHandler := handlers.NewChatMessageFeedbackHandler(chatMsgFeedbackService)
 
 	r.POST("/api/v1/messages/:message_id/feedbacks", chatMsgFeedbackHandler.CreateFeedback)
@@ -97,16 +313,137 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	r.POST("/api/v1/sessions", chatSessionHandler.CreateSession)
 	r.GET("/api/v1/sessions/:session_id", chatSessionHandler.GetSession)
 	r.GET("/api/v1/sessions", chatSessionHandler.ListSessions)
+	r.PATCH("/api/v1/sessions/:session_id/tags", chatSessionHandler.UpdateTags)
+	r.PATCH("/api/v1/sessions/:session_id/attributes", chatSessionHandler.UpdateAttributes)
+
+	// Session lifecycle: open -> pending/snoozed -> closed -> reopened
+	sessionLifecycleService := service.NewSessionLifecycleService(chatSessionRepo, clientRepo, eventPublisherService, cfg.SessionAutoCloseMinutes)
+	if taskClient != nil {
+		sessionLifecycleService.RecapTaskClient = taskClient
+	}
+	sessionLifecycleService.ThreadManager = chatSessionService.ThreadManager
+	sessionLifecycleService.DefaultClosedSessionPolicy = models.ClosedSessionPolicy(cfg.ClosedSessionMessagePolicy)
+	chatMsgHandler.LifecycleService = sessionLifecycleService
+	sessionLifecycleHandler := handlers.NewSessionLifecycleHandler(sessionLifecycleService)
+	r.POST("/api/v1/sessions/:session_id/close", sessionLifecycleHandler.CloseSession)
+	r.POST("/api/v1/sessions/:session_id/reopen", sessionLifecycleHandler.ReopenSession)
+	r.POST("/api/v1/sessions/:session_id/state", sessionLifecycleHandler.SetSessionState)
+
+	// Native Slack connector; replies go out through slack event processors on the workers
+	slackService := service.NewSlackService(clientRepo, clientChannelRepo, chatSessionRepo, chatMsgRepo, chatSessionService, chatMsgService, logger)
+	slackService.LifecycleService = sessionLifecycleService
+	slackService.SecretResolver = secretResolver
+	slackHandler := handlers.NewSlackHandler(slackService)
+	r.POST("/api/v1/channels/slack/events", slackHandler.HandleEvents)
+
+	// Native WhatsApp Cloud API and email connectors; inbound media is kept in GridFS attachment storage
+	whatsAppService := service.NewWhatsAppService(clientRepo, clientChannelRepo, chatSessionRepo, chatMsgRepo, chatSessionService, chatMsgService, logger)
+	whatsAppService.LifecycleService = sessionLifecycleService
+	whatsAppService.SecretResolver = secretResolver
+	emailService := service.NewEmailService(clientRepo, clientChannelRepo, chatSessionRepo, chatMsgRepo, chatSessionService, chatMsgService, logger)
+	emailService.LifecycleService = sessionLifecycleService
+	emailService.SecretResolver = secretResolver
+	if attachmentRepo, err := repository.NewAttachmentRepository(db); err != nil {
+		logger.Warn("Failed to open attachment storage, inbound media will not be kept", zap.Error(err))
+	} else {
+		attachmentService := service.NewAttachmentService(attachmentRepo, cfg.AttachmentBaseURL)
+		whatsAppService.AttachmentService = attachmentService
+		emailService.AttachmentService = attachmentService
+		r.GET("/api/v1/attachments/:attachment_id", handlers.NewAttachmentHandler(attachmentService).GetAttachment)
+	}
+	whatsAppHandler := handlers.NewWhatsAppHandler(whatsAppService)
+	r.GET("/api/v1/channels/whatsapp/webhook", whatsAppHandler.VerifyWebhook)
+	r.POST("/api/v1/channels/whatsapp/webhook", whatsAppHandler.HandleWebhook)
+	r.POST("/api/v1/clients/:client_id/channels/:channel_id/whatsapp/templates", whatsAppHandler.SendTemplate)
+
+	// Native Microsoft Teams connector; this is the messaging endpoint registered on each Azure bot
+	teamsService := service.NewTeamsService(clientRepo, clientChannelRepo, chatSessionRepo, chatMsgRepo, chatSessionService, chatMsgService, logger)
+	teamsService.LifecycleService = sessionLifecycleService
+	teamsService.SecretResolver = secretResolver
+	r.POST("/api/v1/channels/teams/messages", handlers.NewTeamsHandler(teamsService).HandleActivity)
+
+	// Native email connector; each channel's inbound_token authenticates its provider's posts
+	emailHandler := handlers.NewEmailHandler(emailService)
+	r.POST("/api/v1/channels/email/:channel_id/sendgrid", emailHandler.HandleSendGrid)
+	r.POST("/api/v1/channels/email/:channel_id/ses", emailHandler.HandleSES)
+
+	// Twilio SMS connector; status callbacks record delivery of the replies the workers send
+	smsService := service.NewSMSService(clientRepo, clientChannelRepo, chatSessionRepo, chatMsgRepo, chatSessionService, chatMsgService, cfg.PublicAPIURL, logger)
+	smsService.LifecycleService = sessionLifecycleService
+	smsService.SecretResolver = secretResolver
+	smsService.EventPublisher = eventPublisherService
+	smsHandler := handlers.NewSMSHandler(smsService)
+	r.POST("/api/v1/channels/twilio/sms", smsHandler.HandleInbound)
+	r.POST(service.SMSStatusCallbackPath, smsHandler.HandleStatus)
+
+	// Sunshine Conversations connector; handover to Zendesk agents happens by passing switchboard control
+	sunshineService := service.NewSunshineService(clientRepo, clientChannelRepo, chatSessionRepo, chatMsgRepo, chatSessionService, chatMsgService, logger)
+	sunshineService.LifecycleService = sessionLifecycleService
+	sunshineService.SecretResolver = secretResolver
+	r.POST("/api/v1/channels/sunshine/webhook", handlers.NewSunshineHandler(sunshineService).HandleWebhook)
+
+	// Generic webhook channels map custom widget payloads to messages with per-channel JSONPaths
+	genericWebhookService := service.NewGenericWebhookService(clientRepo, clientChannelRepo, chatMsgRepo, chatSessionService, chatMsgService, logger)
+	genericWebhookService.LifecycleService = sessionLifecycleService
+	genericWebhookService.SecretResolver = secretResolver
+	r.POST("/api/v1/channels/webhook/:channel_id", handlers.NewGenericWebhookHandler(genericWebhookService).HandleWebhook)
+
+	// Long-polling fallback for clients that can't hold a realtime connection
+	messagePollService := service.NewMessagePollService(chatSessionRepo, chatMsgRepo, eventRepo)
+	if notificationHub != nil {
+		messagePollService.Subscriber = notificationHub
+	}
+	messagePollHandler := handlers.NewMessagePollHandler(messagePollService)
+	r.GET("/api/v1/sessions/:session_id/messages/poll", messagePollHandler.Poll)
+
+	// Internal gRPC API for services that create messages and follow sessions at high rates
+	var grpcServer *grpcapi.Server
+	if cfg.GRPCPort != "" {
+		grpcServer = grpcapi.NewServer(logger, chatMsgService, chatSessionService, clientService, clientChannelService, messagePollService, apiKeyService)
+		grpcServer.AdminAPIKey = cfg.AdminAPIKey
+		grpcServer.LifecycleService = sessionLifecycleService
+		if clientCache != nil {
+			grpcServer.ClientCache = clientCache
+		}
+	}
+
+	// Session handover to human agents, routed by per-client assignment rules
+	assignmentService := service.NewAssignmentService(repository.NewAgentRepository(db), repository.NewAssignmentRuleRepository(db), chatSessionRepo, clientRepo)
+	handoverService := service.NewHandoverService(chatSessionRepo, eventPublisherService)
+	handoverService.AssignmentService = assignmentService
+	handoverHandler := handlers.NewHandoverHandler(handoverService)
+	assignmentHandler := handlers.NewAssignmentHandler(assignmentService)
+	r.POST("/api/v1/sessions/:session_id/handover", handoverHandler.RequestHandover)
+	r.GET("/api/v1/sessions/:session_id/handover", handoverHandler.GetHandover)
+	r.POST("/api/v1/sessions/:session_id/handover/accept", handoverHandler.AcceptHandover)
+	r.POST("/api/v1/sessions/:session_id/handover/decline", handoverHandler.DeclineHandover)
+	r.POST("/api/v1/sessions/:session_id/handover/complete", handoverHandler.CompleteHandover)
+	r.POST("/api/v1/sessions/:session_id/handover/assign", handoverHandler.RouteHandover)
+	r.GET("/api/v1/sessions/:session_id/assignments", assignmentHandler.ListSessionAssignments)
+	r.POST("/api/v1/clients/:client_id/agents", assignmentHandler.CreateAgent)
+	r.GET("/api/v1/clients/:client_id/agents", assignmentHandler.ListAgents)
+	r.PUT("/api/v1/clients/:client_id/agents/:agent_id/availability", assignmentHandler.UpdateAgentAvailability)
+	r.POST("/api/v1/clients/:client_id/assignment-rules", assignmentHandler.CreateAssignmentRule)
+	r.GET("/api/v1/clients/:client_id/assignment-rules", assignmentHandler.ListAssignmentRules)
+	r.DELETE("/api/v1/clients/:client_id/assignment-rules/:rule_id", assignmentHandler.DeleteAssignmentRule)
 
 	// Chat Session Threads
 	chatSessionThreadRepo := repository.NewChatSessionThreadRepository(db)
 	chatSessionThreadService := service.NewChatSessionThreadService(chatSessionThreadRepo)
+	chatSessionThreadService.ChatSessionRepo = chatSessionRepo
+	chatSessionThreadService.ChatMessageRepo = chatMsgRepo
+	chatSessionThreadService.ThreadManager = chatSessionService.ThreadManager
+	chatSessionThreadService.EventPublisherService = eventPublisherService
+	chatMsgHandler.ThreadService = chatSessionThreadService
 	chatSessionThreadHandler := handlers.NewChatSessionThreadHandler(chatSessionThreadService)
 
 	r.POST("/api/v1/sessions/:session_id/threads", chatSessionThreadHandler.CreateThread)
 	r.GET("/api/v1/sessions/:session_id/threads", chatSessionThreadHandler.ListThreads)
 	r.GET("/api/v1/sessions/:session_id/active_thread", chatSessionThreadHandler.GetActiveThread)
 	r.POST("/api/v1/sessions/:session_id/close_thread", chatSessionThreadHandler.CloseThread)
+	r.GET("/api/v1/sessions/:session_id/threads/:thread_id/messages", chatSessionThreadHandler.ListThreadMessages)
+	r.POST("/api/v1/sessions/:session_id/threads/:thread_id/merge", chatSessionThreadHandler.MergeThread)
+	r.POST("/api/v1/sessions/:session_id/threads/:thread_id/split", chatSessionThreadHandler.SplitThread)
 
 	// Chat Session Recap
 	chatSessionRecapRepo := repository.NewChatSessionRecapRepository(db)
@@ -117,18 +454,201 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	r.GET("/api/v1/sessions/:session_id/recap", chatSessionRecapHandler.GetLatestRecap)
 
 	// Analytics
-	analyticsService := service.NewAnalyticsService()
+	analyticsService := service.NewAnalyticsService(analyticsDB, clientRepo)
+	if clientCache != nil {
+		analyticsService.Clients = clientCache
+	}
 	analyticsHandler := handlers.NewAnalyticsHandler(analyticsService)
 
 	r.GET("/api/v1/analytics/dashboard", analyticsHandler.GetDashboardMetrics)
 	r.GET("/api/v1/analytics/bot-engagement", analyticsHandler.GetBotEngagementMetrics)
 	r.GET("/api/v1/analytics/containment-rate", analyticsHandler.GetContainmentRateMetrics)
+	r.GET("/api/v1/analytics/performance", analyticsHandler.GetPerformanceMetrics)
+	r.GET("/api/v1/analytics/volume", analyticsHandler.GetVolumeHeatmap)
+	r.GET("/api/v1/analytics/confidence", analyticsHandler.GetConfidenceMetrics)
+
+	// Scheduled analytics reports; workers queue and deliver them as report_generation tasks
+	reportService := service.NewReportService(repository.NewReportScheduleRepository(db), clientRepo, clientChannelRepo, analyticsService, logger)
+	if taskClient != nil {
+		reportService.TaskClient = taskClient
+	}
+	if policy, err := egress.NewPolicy(cfg.DispatchAllowedHosts, cfg.DispatchDeniedHosts, cfg.DispatchAllowPrivateNetworks); err == nil {
+		reportService.Egress = policy
+	}
+	reportHandler := handlers.NewReportHandler(reportService)
+	r.POST("/api/v1/clients/:client_id/reports", reportHandler.CreateReport)
+	r.GET("/api/v1/clients/:client_id/reports", reportHandler.ListReports)
+	r.GET("/api/v1/clients/:client_id/reports/:report_id", reportHandler.GetReport)
+	r.PUT("/api/v1/clients/:client_id/reports/:report_id", reportHandler.UpdateReport)
+	r.DELETE("/api/v1/clients/:client_id/reports/:report_id", reportHandler.DeleteReport)
+	r.POST("/api/v1/clients/:client_id/reports/:report_id/run", reportHandler.RunReport)
+
+	// Simulations replay scripted conversations through the real chat workflow on workers
+	simulationService := service.NewSimulationService(repository.NewSimulationRepository(db), clientRepo, clientChannelRepo, chatSessionRepo, chatMsgRepo, eventRepo)
+	if taskClient != nil {
+		simulationService.TaskClient = taskClient
+	}
+	simulationHandler := handlers.NewSimulationHandler(simulationService)
+	r.POST("/api/v1/simulations", simulationHandler.CreateSimulation)
+	r.GET("/api/v1/simulations", simulationHandler.ListSimulations)
+	r.GET("/api/v1/simulations/:simulation_id", simulationHandler.GetSimulation)
+
+	// Message history imports; workers store the uploaded rows as message_import tasks
+	if messageImportRepo, err := repository.NewMessageImportRepository(db); err != nil {
+		logger.Warn("Failed to open message import storage, message imports are disabled", zap.Error(err))
+	} else {
+		messageImportService := service.NewMessageImportService(messageImportRepo, clientRepo, clientChannelRepo, chatSessionRepo, chatMsgRepo)
+		if taskClient != nil {
+			messageImportService.TaskClient = taskClient
+		}
+		messageImportHandler := handlers.NewMessageImportHandler(messageImportService)
+		r.POST("/api/v1/imports/messages", messageImportHandler.CreateImport)
+		r.GET("/api/v1/imports/messages", messageImportHandler.ListImports)
+		r.GET("/api/v1/imports/messages/:import_id", messageImportHandler.GetImport)
+	}
+
+	// Agent feedback on AI suggestions
+	suggestionService := service.NewChatMessageSuggestionService(db)
+	suggestionService.EventPublisherService = eventPublisherService
+	suggestionService.AnalyticsDB = analyticsDB
+	suggestionHandler := handlers.NewChatMessageSuggestionHandler(suggestionService)
+	r.POST("/api/v1/suggestions/:suggestion_id/accept", suggestionHandler.AcceptSuggestion)
+	r.POST("/api/v1/suggestions/:suggestion_id/reject", suggestionHandler.RejectSuggestion)
+	r.GET("/api/v1/analytics/suggestions", suggestionHandler.GetSuggestionStats)
 
 	// Client endpoints (using services defined earlier)
 	r.POST("/api/v1/clients", clientHandler.CreateClient)
 	r.GET("/api/v1/clients", clientHandler.ListClients)
 	r.PUT("/api/v1/clients/:client_id", clientHandler.UpdateClient)
 
+	// Client API keys: scoped, hashed at rest, rotated with an optional grace period
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	r.POST("/api/v1/clients/:client_id/api-keys", apiKeyHandler.CreateAPIKey)
+	r.GET("/api/v1/clients/:client_id/api-keys", apiKeyHandler.ListAPIKeys)
+	r.POST("/api/v1/clients/:client_id/api-keys/:key_id/rotate", apiKeyHandler.RotateAPIKey)
+	r.DELETE("/api/v1/clients/:client_id/api-keys/:key_id", apiKeyHandler.RevokeAPIKey)
+
+	// Role management for dashboard users and API keys
+	roleHandler := handlers.NewRoleHandler(roleService, apiKeyService)
+	r.PUT("/api/v1/clients/:client_id/api-keys/:key_id/roles", roleHandler.SetAPIKeyRoles)
+	r.GET("/api/v1/role-assignments", roleHandler.ListRoleAssignments)
+	r.PUT("/api/v1/role-assignments/:subject", roleHandler.SetRoleAssignment)
+	r.DELETE("/api/v1/role-assignments/:subject", roleHandler.DeleteRoleAssignment)
+
+	// Feature flag administration
+	featureFlagHandler := handlers.NewFeatureFlagHandler(featureFlagService)
+	r.GET("/api/v1/feature-flags", featureFlagHandler.ListFeatureFlags)
+	r.POST("/api/v1/feature-flags", featureFlagHandler.CreateFeatureFlag)
+	r.GET("/api/v1/feature-flags/:key", featureFlagHandler.GetFeatureFlag)
+	r.PUT("/api/v1/feature-flags/:key", featureFlagHandler.UpdateFeatureFlag)
+	r.DELETE("/api/v1/feature-flags/:key", featureFlagHandler.DeleteFeatureFlag)
+	r.GET("/api/v1/feature-flags/:key/evaluate", featureFlagHandler.EvaluateFeatureFlag)
+
+	// Synchronous post-processing webhook run by workers on every AI response
+	postProcessingService := service.NewPostProcessingService(clientRepo, chatSessionRepo, logger)
+	if cacheBus != nil {
+		postProcessingService.Invalidator = cacheBus
+	}
+	postProcessingHandler := handlers.NewPostProcessingHandler(postProcessingService)
+	r.GET("/api/v1/clients/:client_id/post-processing-hook", postProcessingHandler.GetHook)
+	r.PUT("/api/v1/clients/:client_id/post-processing-hook", postProcessingHandler.SetHook)
+	r.DELETE("/api/v1/clients/:client_id/post-processing-hook", postProcessingHandler.DeleteHook)
+
+	// Intent routing rules applied by workers before the AI answers
+	intentService := service.NewIntentService(clientRepo, chatSessionRepo, chatMsgRepo, logger)
+	if cacheBus != nil {
+		intentService.Invalidator = cacheBus
+	}
+	intentHandler := handlers.NewIntentHandler(intentService)
+	r.GET("/api/v1/clients/:client_id/intents", intentHandler.GetConfig)
+	r.PUT("/api/v1/clients/:client_id/intents", intentHandler.SetConfig)
+	r.DELETE("/api/v1/clients/:client_id/intents", intentHandler.DeleteConfig)
+
+	// Moderation policy applied by workers before an AI response is sent
+	moderationService := service.NewModerationService(clientRepo, chatSessionRepo, logger)
+	if cacheBus != nil {
+		moderationService.Invalidator = cacheBus
+	}
+	moderationHandler := handlers.NewModerationHandler(moderationService)
+	r.GET("/api/v1/clients/:client_id/moderation", moderationHandler.GetPolicy)
+	r.PUT("/api/v1/clients/:client_id/moderation", moderationHandler.SetPolicy)
+	r.DELETE("/api/v1/clients/:client_id/moderation", moderationHandler.DeletePolicy)
+
+	// Model and prompt config sent by workers with each client's AI requests
+	aiConfigService := service.NewClientAIConfigService(clientRepo, chatSessionRepo)
+	if cacheBus != nil {
+		aiConfigService.Invalidator = cacheBus
+	}
+	aiConfigHandler := handlers.NewClientAIConfigHandler(aiConfigService)
+	r.GET("/api/v1/clients/:client_id/ai-config", aiConfigHandler.GetConfig)
+	r.PUT("/api/v1/clients/:client_id/ai-config", aiConfigHandler.SetConfig)
+	r.DELETE("/api/v1/clients/:client_id/ai-config", aiConfigHandler.DeleteConfig)
+
+	// Cached AI answers to repeated questions, reused by workers instead of asking the AI again
+	answerCacheService := service.NewAnswerCacheService(repository.NewAnswerCacheRepository(db), clientRepo, chatSessionRepo)
+	if cacheBus != nil {
+		answerCacheService.Invalidator = cacheBus
+	}
+	answerCacheHandler := handlers.NewAnswerCacheHandler(answerCacheService)
+	r.GET("/api/v1/clients/:client_id/answer-cache", answerCacheHandler.GetPolicy)
+	r.PUT("/api/v1/clients/:client_id/answer-cache", answerCacheHandler.SetPolicy)
+	r.DELETE("/api/v1/clients/:client_id/answer-cache", answerCacheHandler.DeletePolicy)
+	r.DELETE("/api/v1/clients/:client_id/answer-cache/entries", answerCacheHandler.InvalidateEntries)
+
+	// Opt-in forwarding of answer feedback to the AI service
+	aiFeedbackHandler := handlers.NewAIFeedbackHandler(aiFeedbackService)
+	r.GET("/api/v1/clients/:client_id/ai-feedback", aiFeedbackHandler.GetPolicy)
+	r.PUT("/api/v1/clients/:client_id/ai-feedback", aiFeedbackHandler.SetPolicy)
+	r.DELETE("/api/v1/clients/:client_id/ai-feedback", aiFeedbackHandler.DeletePolicy)
+
+	// Versioned prompt templates rendered by workers into each client's AI requests
+	promptTemplateRepo := repository.NewPromptTemplateRepository(db)
+	promptTemplateService := service.NewPromptTemplateService(promptTemplateRepo, clientRepo, clientChannelRepo, chatSessionRepo)
+	promptTemplateHandler := handlers.NewPromptTemplateHandler(promptTemplateService)
+	r.POST("/api/v1/clients/:client_id/prompt-templates", promptTemplateHandler.CreateTemplate)
+	r.GET("/api/v1/clients/:client_id/prompt-templates", promptTemplateHandler.ListTemplates)
+	r.GET("/api/v1/clients/:client_id/prompt-templates/:template_id", promptTemplateHandler.GetTemplate)
+	r.DELETE("/api/v1/clients/:client_id/prompt-templates/:template_id", promptTemplateHandler.DeleteTemplate)
+	r.POST("/api/v1/clients/:client_id/prompt-templates/:template_id/versions", promptTemplateHandler.AddVersion)
+	r.POST("/api/v1/clients/:client_id/prompt-templates/:template_id/publish", promptTemplateHandler.Publish)
+	r.POST("/api/v1/clients/:client_id/prompt-templates/:template_id/rollback", promptTemplateHandler.Rollback)
+
+	// Experiments splitting a client's sessions between AI configurations, compared by their metrics
+	experimentService := service.NewExperimentService(repository.NewExperimentRepository(db), clientRepo, chatSessionRepo, promptTemplateRepo, analyticsDB)
+	if clientCache != nil {
+		experimentService.Clients = clientCache
+	}
+	experimentHandler := handlers.NewExperimentHandler(experimentService)
+	r.POST("/api/v1/clients/:client_id/experiments", experimentHandler.CreateExperiment)
+	r.GET("/api/v1/clients/:client_id/experiments", experimentHandler.ListExperiments)
+	r.GET("/api/v1/clients/:client_id/experiments/:experiment_id", experimentHandler.GetExperiment)
+	r.PUT("/api/v1/clients/:client_id/experiments/:experiment_id", experimentHandler.UpdateExperiment)
+	r.DELETE("/api/v1/clients/:client_id/experiments/:experiment_id", experimentHandler.DeleteExperiment)
+	r.POST("/api/v1/clients/:client_id/experiments/:experiment_id/start", experimentHandler.StartExperiment)
+	r.POST("/api/v1/clients/:client_id/experiments/:experiment_id/stop", experimentHandler.StopExperiment)
+	r.GET("/api/v1/clients/:client_id/experiments/:experiment_id/metrics", experimentHandler.GetMetrics)
+
+	// Escalation policies evaluated by workers in the chat workflow; a channel's overrides its client's
+	escalationService := service.NewEscalationService(clientRepo, clientChannelRepo, chatSessionRepo, logger)
+	if cacheBus != nil {
+		escalationService.Invalidator = cacheBus
+	}
+	escalationHandler := handlers.NewEscalationHandler(escalationService)
+	r.GET("/api/v1/clients/:client_id/escalation-policy", escalationHandler.GetClientPolicy)
+	r.PUT("/api/v1/clients/:client_id/escalation-policy", escalationHandler.SetClientPolicy)
+	r.DELETE("/api/v1/clients/:client_id/escalation-policy", escalationHandler.DeleteClientPolicy)
+	r.GET("/api/v1/clients/:client_id/channels/:channel_id/escalation-policy", escalationHandler.GetChannelPolicy)
+	r.PUT("/api/v1/clients/:client_id/channels/:channel_id/escalation-policy", escalationHandler.SetChannelPolicy)
+	r.DELETE("/api/v1/clients/:client_id/channels/:channel_id/escalation-policy", escalationHandler.DeleteChannelPolicy)
+
+	// Monthly usage per client; quotas and the cross-client billing export are for system
+	// administrators only
+	usageHandler := handlers.NewUsageHandler(usageService)
+	r.GET("/api/v1/clients/:client_id/usage", usageHandler.GetUsage)
+	r.PUT("/api/v1/clients/:client_id/quota", usageHandler.SetQuota)
+	r.DELETE("/api/v1/clients/:client_id/quota", usageHandler.DeleteQuota)
+	r.GET("/api/v1/usage/export", usageHandler.ExportUsage)
+
 	// Client Channel endpoints (using handler defined earlier)
 	r.POST("/api/v1/clients/:client_id/channels", clientChannelHandler.CreateChannel)
 	r.GET("/api/v1/clients/:client_id/channels", clientChannelHandler.ListChannels)
@@ -139,7 +659,7 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	r.PUT("/api/v1/clients/:client_id/channels/:channel_id/config", clientChannelHandler.UpdateChannelConfig)
 
 	// Events
-	eventsHandler := handlers.NewEventsHandler(logger)
+	eventsHandler := handlers.NewEventsHandler(logger, eventPublisherService)
 	r.POST("/api/v1/events/processor-configs", eventsHandler.CreateEventProcessorConfig)
 	r.GET("/api/v1/events/processor-configs", eventsHandler.ListEventProcessorConfigs)
 	r.GET("/api/v1/events/processor-configs/:config_id", eventsHandler.GetEventProcessorConfig)
@@ -147,6 +667,22 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	r.DELETE("/api/v1/events/processor-configs/:config_id", eventsHandler.DeleteEventProcessorConfig)
 	r.POST("/api/v1/events/process", eventsHandler.ProcessEvent)
 	r.GET("/api/v1/events/:event_id/status", eventsHandler.GetEventStatus)
+	r.GET("/api/v1/events/catalog", eventsHandler.GetEventCatalog)
+
+	// Server-sent event feed of a client's events, for integrators that can't take webhooks
+	eventStreamHandler := handlers.NewEventStreamHandler(service.NewEventStreamService(eventRepo, clientRepo), logger)
+	r.GET("/api/v1/clients/:client_id/events/stream", eventStreamHandler.Stream)
+
+	// Operator repair actions (tracked as repair_action tasks)
+	var repairTaskClient service.RepairTaskClient
+	if taskClient != nil {
+		repairTaskClient = taskClient
+	}
+	repairService := service.NewRepairService(repository.NewRepairActionRepository(db), eventDeliveryRepo, chatSessionThreadRepo, chatSessionRepo, repairTaskClient)
+	repairHandler := handlers.NewRepairHandler(repairService)
+	r.POST("/api/v1/admin/repairs", repairHandler.CreateRepairAction)
+	r.GET("/api/v1/admin/repairs", repairHandler.ListRepairActions)
+	r.GET("/api/v1/admin/repairs/:action_id", repairHandler.GetRepairAction)
 
 	// Event Processor Configs (Client-specific) - reuse existing services
 	eventProcessorConfigHandler := handlers.NewEventProcessorConfigHandler(eventProcessorConfigService)
@@ -156,6 +692,8 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	r.GET("/api/v1/clients/:client_id/processor-configs/:config_id", eventProcessorConfigHandler.GetProcessorConfig)
 	r.PUT("/api/v1/clients/:client_id/processor-configs/:config_id", eventProcessorConfigHandler.UpdateProcessorConfig)
 	r.DELETE("/api/v1/clients/:client_id/processor-configs/:config_id", eventProcessorConfigHandler.DeleteProcessorConfig)
+	r.POST("/api/v1/clients/:client_id/processor-configs/:config_id/test", eventProcessorConfigHandler.TestProcessorConfig)
+	r.POST("/api/v1/events/processor-configs/:config_id/test", eventProcessorConfigHandler.TestProcessorConfig)
 
 
 	// CSAT (Customer Satisfaction)
@@ -178,12 +716,21 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 		csatEventPublisherService,
 		payloadService,
 	)
+	if taskClient != nil {
+		csatService.TaskClient = taskClient
+	}
+	csatService.AnalyticsDB = analyticsDB
+	csatService.ConsentChecker = contactService.CSATConsent
+	csatService.ExperimentTagger = experimentService.CSATTag
 	csatHandler := handlers.NewCSATHandler(csatService)
 
 	// CSAT API endpoints
 	r.POST("/api/v1/csat/trigger", csatHandler.TriggerCSAT)
+	r.POST("/api/v1/csat/trigger/bulk", csatHandler.BulkTriggerCSAT)
 	r.POST("/api/v1/csat/respond", csatHandler.RespondToCSAT)
-	r.GET("/api/v1/csat/sessions/:session_id", csatHandler.GetCSATSession)
+	r.GET("/api/v1/csat/sessions", csatHandler.ListCSATSessions)
+	r.GET("/api/v1/csat/sessions/:csat_session_id", csatHandler.GetCSATSession)
+	r.GET("/api/v1/csat/responses/export", csatHandler.ExportCSATResponses)
 	
 	// Multi-CSAT configuration management
 	r.GET("/api/v1/clients/:client_id/channels/:channel_id/csat/configs", csatHandler.ListCSATConfigurations)
@@ -195,4 +742,209 @@ func Register(r *gin.Engine, cfg *config.Config, logger *zap.Logger, mongoClient
 	// Type-specific question management
 	r.GET("/api/v1/clients/:client_id/channels/:channel_id/csat/configs/:type/questions", csatHandler.GetCSATQuestionsByType)
 	r.PUT("/api/v1/clients/:client_id/channels/:channel_id/csat/configs/:type/questions", csatHandler.UpdateCSATQuestionsByType)
+
+	return grpcServer
+}
+
+// routePermissions declares the permission every protected route requires. Routes left out can
+// only be called by admins; Register logs them at startup.
+var routePermissions = middleware.RoutePermissions{
+	"GET /api/v1/auth/me":            models.PermissionAuthenticated,
+	"GET /api/v1/docs/openapi.json":  models.PermissionAuthenticated,
+	"GET /api/v1/docs/asyncapi.json": models.PermissionAuthenticated,
+
+	// Messages
+	"POST /api/v1/messages":                                                   models.PermissionMessagesWrite,
+	"GET /api/v1/messages":                                                    models.PermissionMessagesRead,
+	"PUT /api/v1/messages/:message_id":                                                models.PermissionMessagesWrite,
+	"GET /api/v1/messages/:message_id/replies":                                models.PermissionMessagesRead,
+	"POST /api/v1/messages/bulk":                                              models.PermissionMessagesWrite,
+	"POST /api/v1/messages/canned":                                            models.PermissionMessagesWrite,
+	"POST /api/v1/messages/schedule":                                          models.PermissionMessagesWrite,
+	"GET /api/v1/messages/scheduled":                                          models.PermissionMessagesRead,
+	"GET /api/v1/messages/scheduled/:scheduled_id":                            models.PermissionMessagesRead,
+	"POST /api/v1/messages/scheduled/:scheduled_id/cancel":                    models.PermissionMessagesWrite,
+	"POST /api/v1/messages/:message_id/feedbacks":                             models.PermissionMessagesWrite,
+	"GET /api/v1/messages/:message_id/feedbacks":                              models.PermissionMessagesRead,
+	"PATCH /api/v1/messages/:message_id/feedbacks/:feedback_id":               models.PermissionMessagesWrite,
+	"GET /api/v1/sessions/:session_id/messages/poll":                          models.PermissionMessagesRead,
+	"GET /api/v1/clients/:client_id/events/stream":                            models.PermissionMessagesRead,
+	"GET /api/v1/attachments/:attachment_id":                                  models.PermissionMessagesRead,
+	"POST /api/v1/suggestions/:suggestion_id/accept":                          models.PermissionMessagesWrite,
+	"POST /api/v1/suggestions/:suggestion_id/reject":                          models.PermissionMessagesWrite,
+	"POST /api/v1/csat/respond":                                               models.PermissionMessagesWrite,
+	"POST /api/v1/clients/:client_id/channels/:channel_id/whatsapp/templates": models.PermissionMessagesWrite,
+
+	// Sessions, handovers and CSAT surveys
+	"POST /api/v1/sessions":                                        models.PermissionSessionsWrite,
+	"GET /api/v1/sessions/:session_id":                             models.PermissionSessionsRead,
+	"GET /api/v1/sessions":                                         models.PermissionSessionsRead,
+	"PATCH /api/v1/sessions/:session_id/tags":                      models.PermissionSessionsWrite,
+	"PATCH /api/v1/sessions/:session_id/attributes":                models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/close":                      models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/reopen":                     models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/state":                      models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/handover":                   models.PermissionSessionsWrite,
+	"GET /api/v1/sessions/:session_id/handover":                    models.PermissionSessionsRead,
+	"POST /api/v1/sessions/:session_id/handover/accept":            models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/handover/decline":           models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/handover/complete":          models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/handover/assign":            models.PermissionSessionsWrite,
+	"GET /api/v1/sessions/:session_id/assignments":                 models.PermissionSessionsRead,
+	"PUT /api/v1/clients/:client_id/agents/:agent_id/availability": models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/threads":                    models.PermissionSessionsWrite,
+	"GET /api/v1/sessions/:session_id/threads":                     models.PermissionSessionsRead,
+	"GET /api/v1/sessions/:session_id/active_thread":               models.PermissionSessionsRead,
+	"POST /api/v1/sessions/:session_id/close_thread":               models.PermissionSessionsWrite,
+	"GET /api/v1/sessions/:session_id/threads/:thread_id/messages":  models.PermissionMessagesRead,
+	"POST /api/v1/sessions/:session_id/threads/:thread_id/merge":    models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/threads/:thread_id/split":    models.PermissionSessionsWrite,
+	"POST /api/v1/sessions/:session_id/recap":                      models.PermissionSessionsWrite,
+	"GET /api/v1/sessions/:session_id/recap":                       models.PermissionSessionsRead,
+	"POST /api/v1/csat/trigger":                                    models.PermissionSessionsWrite,
+	"POST /api/v1/csat/trigger/bulk":                               models.PermissionSessionsWrite,
+	"GET /api/v1/csat/sessions":                                    models.PermissionSessionsRead,
+	"GET /api/v1/csat/sessions/:csat_session_id":                        models.PermissionSessionsRead,
+	"GET /api/v1/csat/responses/export":                            models.PermissionSessionsRead,
+
+	// Analytics
+	"GET /api/v1/analytics/dashboard":        models.PermissionAnalyticsRead,
+	"GET /api/v1/analytics/bot-engagement":   models.PermissionAnalyticsRead,
+	"GET /api/v1/analytics/containment-rate": models.PermissionAnalyticsRead,
+	"GET /api/v1/analytics/performance":      models.PermissionAnalyticsRead,
+	"GET /api/v1/analytics/volume":           models.PermissionAnalyticsRead,
+	"GET /api/v1/analytics/confidence":       models.PermissionAnalyticsRead,
+	"GET /api/v1/analytics/suggestions":      models.PermissionAnalyticsRead,
+
+	// Client configuration
+	"PUT /api/v1/clients/:client_id":                                                   models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/api-keys":                                         models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/api-keys":                                          models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/api-keys/:key_id/rotate":                          models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/api-keys/:key_id":                               models.PermissionClientsWrite,
+	"PUT /api/v1/clients/:client_id/api-keys/:key_id/roles":                            models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/canned-responses":                                 models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/canned-responses":                                  models.PermissionClientsRead,
+	"GET /api/v1/clients/:client_id/canned-responses/:canned_response_id":              models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/canned-responses/:canned_response_id":              models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/canned-responses/:canned_response_id":           models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/contacts":                                         models.PermissionSessionsWrite,
+	"GET /api/v1/clients/:client_id/contacts":                                          models.PermissionSessionsRead,
+	"GET /api/v1/clients/:client_id/contacts/:contact_id":                              models.PermissionSessionsRead,
+	"PUT /api/v1/clients/:client_id/contacts/:contact_id":                              models.PermissionSessionsWrite,
+	"DELETE /api/v1/clients/:client_id/contacts/:contact_id":                           models.PermissionSessionsWrite,
+	"POST /api/v1/clients/:client_id/contacts/:contact_id/merge":                       models.PermissionSessionsWrite,
+	"GET /api/v1/clients/:client_id/contacts/:contact_id/sessions":                     models.PermissionSessionsRead,
+	"GET /api/v1/clients/:client_id/contacts/:contact_id/messages":                     models.PermissionMessagesRead,
+	"GET /api/v1/clients/:client_id/contacts/:contact_id/consent":                      models.PermissionSessionsRead,
+	"PUT /api/v1/clients/:client_id/contacts/:contact_id/consent":                      models.PermissionSessionsWrite,
+	"GET /api/v1/clients/:client_id/contact-history":                                   models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/contact-history":                                   models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/contact-history":                                models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/agents":                                           models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/agents":                                            models.PermissionClientsRead,
+	"POST /api/v1/clients/:client_id/assignment-rules":                                 models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/assignment-rules":                                  models.PermissionClientsRead,
+	"DELETE /api/v1/clients/:client_id/assignment-rules/:rule_id":                      models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/post-processing-hook":                              models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/post-processing-hook":                              models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/post-processing-hook":                           models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/intents":                                           models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/intents":                                           models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/intents":                                        models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/moderation":                                        models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/moderation":                                        models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/moderation":                                     models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/ai-config":                                         models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/ai-config":                                         models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/ai-config":                                      models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/answer-cache":                                      models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/answer-cache":                                      models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/answer-cache":                                   models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/answer-cache/entries":                           models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/ai-feedback":                                       models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/ai-feedback":                                       models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/ai-feedback":                                    models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/prompt-templates":                                 models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/prompt-templates":                                  models.PermissionClientsRead,
+	"GET /api/v1/clients/:client_id/prompt-templates/:template_id":                     models.PermissionClientsRead,
+	"DELETE /api/v1/clients/:client_id/prompt-templates/:template_id":                  models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/prompt-templates/:template_id/versions":           models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/prompt-templates/:template_id/publish":            models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/prompt-templates/:template_id/rollback":           models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/experiments":                                      models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/experiments":                                       models.PermissionClientsRead,
+	"GET /api/v1/clients/:client_id/experiments/:experiment_id":                        models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/experiments/:experiment_id":                        models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/experiments/:experiment_id":                     models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/experiments/:experiment_id/start":                 models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/experiments/:experiment_id/stop":                  models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/experiments/:experiment_id/metrics":                models.PermissionClientsRead,
+	"GET /api/v1/clients/:client_id/usage":                                             models.PermissionClientsRead,
+	"POST /api/v1/clients/:client_id/reports":                                          models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/reports":                                           models.PermissionClientsRead,
+	"GET /api/v1/clients/:client_id/reports/:report_id":                                models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/reports/:report_id":                                models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/reports/:report_id":                             models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/reports/:report_id/run":                           models.PermissionClientsWrite,
+	"POST /api/v1/simulations":                                                         models.PermissionMessagesWrite,
+	"GET /api/v1/simulations":                                                          models.PermissionMessagesRead,
+	"GET /api/v1/simulations/:simulation_id":                                           models.PermissionMessagesRead,
+	"POST /api/v1/imports/messages":                                                    models.PermissionMessagesWrite,
+	"GET /api/v1/imports/messages":                                                     models.PermissionMessagesRead,
+	"GET /api/v1/imports/messages/:import_id":                                          models.PermissionMessagesRead,
+	"GET /api/v1/clients/:client_id/escalation-policy":                                 models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/escalation-policy":                                 models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/escalation-policy":                              models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/channels/:channel_id/escalation-policy":            models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/channels/:channel_id/escalation-policy":            models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/channels/:channel_id/escalation-policy":         models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/channels":                                         models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/channels":                                          models.PermissionClientsRead,
+	"GET /api/v1/clients/:client_id/channels/:channel_id":                              models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/channels/:channel_id":                              models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/channels/:channel_id":                           models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/channels/:channel_id/config":                       models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/channels/:channel_id/config":                       models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/processor-configs":                                models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/processor-configs":                                 models.PermissionClientsRead,
+	"GET /api/v1/clients/:client_id/processor-configs/:config_id":                      models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/processor-configs/:config_id":                      models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/processor-configs/:config_id":                   models.PermissionClientsWrite,
+	"POST /api/v1/clients/:client_id/processor-configs/:config_id/test":                models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/channels/:channel_id/csat/configs":                 models.PermissionClientsRead,
+	"POST /api/v1/clients/:client_id/channels/:channel_id/csat/configs":                models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/channels/:channel_id/csat/configs/:type":           models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/channels/:channel_id/csat/configs/:type":           models.PermissionClientsWrite,
+	"DELETE /api/v1/clients/:client_id/channels/:channel_id/csat/configs/:type":        models.PermissionClientsWrite,
+	"GET /api/v1/clients/:client_id/channels/:channel_id/csat/configs/:type/questions": models.PermissionClientsRead,
+	"PUT /api/v1/clients/:client_id/channels/:channel_id/csat/configs/:type/questions": models.PermissionClientsWrite,
+
+	// System administration: clients, events, repairs, roles, feature flags and channel provider callbacks
+	"POST /api/v1/clients":                               models.PermissionSystem,
+	"GET /api/v1/clients":                                models.PermissionSystem,
+	"PUT /api/v1/clients/:client_id/quota":               models.PermissionSystem,
+	"DELETE /api/v1/clients/:client_id/quota":            models.PermissionSystem,
+	"GET /api/v1/usage/export":                           models.PermissionSystem,
+	"GET /api/v1/role-assignments":                       models.PermissionSystem,
+	"PUT /api/v1/role-assignments/:subject":              models.PermissionSystem,
+	"DELETE /api/v1/role-assignments/:subject":           models.PermissionSystem,
+	"GET /api/v1/feature-flags":                          models.PermissionSystem,
+	"POST /api/v1/feature-flags":                         models.PermissionSystem,
+	"GET /api/v1/feature-flags/:key":                     models.PermissionSystem,
+	"PUT /api/v1/feature-flags/:key":                     models.PermissionSystem,
+	"DELETE /api/v1/feature-flags/:key":                  models.PermissionSystem,
+	"GET /api/v1/feature-flags/:key/evaluate":            models.PermissionSystem,
+	"POST /api/v1/events/processor-configs":                 models.PermissionSystem,
+	"GET /api/v1/events/processor-configs":                  models.PermissionSystem,
+	"GET /api/v1/events/processor-configs/:config_id":       models.PermissionSystem,
+	"PUT /api/v1/events/processor-configs/:config_id":       models.PermissionSystem,
+	"DELETE /api/v1/events/processor-configs/:config_id":    models.PermissionSystem,
+	"POST /api/v1/events/processor-configs/:config_id/test": models.PermissionSystem,
+	"POST /api/v1/events/process":                        models.PermissionSystem,
+	"GET /api/v1/events/:event_id/status":                models.PermissionSystem,
+	"GET /api/v1/events/catalog":                         models.PermissionAuthenticated,
+	"POST /api/v1/admin/repairs":                         models.PermissionSystem,
+	"GET /api/v1/admin/repairs":                          models.PermissionSystem,
+	"GET /api/v1/admin/repairs/:action_id":               models.PermissionSystem,
 }
@@ -0,0 +1,219 @@
+// Package botframework authenticates requests from the Bot Framework connector service and
+// obtains the tokens bots need to call it back.
+package botframework
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// OpenIDMetadataURL describes the keys that sign tokens sent by the Bot Framework channels
+	OpenIDMetadataURL = "https://login.botframework.com/v1/.well-known/openidconfiguration"
+	// Issuer is the iss claim of tokens sent by the Bot Framework channels
+	Issuer = "https://api.botframework.com"
+	// keyRefreshInterval is how often signing keys are reloaded; Microsoft rotates them every few weeks
+	keyRefreshInterval = 24 * time.Hour
+	// minKeyRefreshInterval limits reloads triggered by tokens signed with an unknown key
+	minKeyRefreshInterval = time.Minute
+	clockSkew             = 5 * time.Minute
+)
+
+var ErrUnauthorized = errors.New("invalid bot framework token")
+
+// Claims are the verified claims of a connector token.
+type Claims struct {
+	// Audience is the app id of the bot the token was issued for
+	Audience   string
+	ServiceURL string
+	ExpiresAt  time.Time
+}
+
+type signingKey struct {
+	key          *rsa.PublicKey
+	endorsements []string
+}
+
+// Authenticator verifies the bearer tokens the Bot Framework sends with every activity.
+type Authenticator struct {
+	MetadataURL string
+	httpClient  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]signingKey
+	fetchedAt time.Time
+}
+
+// NewAuthenticator creates an Authenticator using the public Bot Framework metadata.
+func NewAuthenticator() *Authenticator {
+	return &Authenticator{
+		MetadataURL: OpenIDMetadataURL,
+		httpClient:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Verify checks the signature, issuer and lifetime of the token in authorization, a
+// "Bearer <jwt>" header value. When channelID is set, the signing key must be endorsed for it.
+func (a *Authenticator) Verify(ctx context.Context, authorization, channelID string) (*Claims, error) {
+	token, ok := strings.CutPrefix(authorization, "Bearer ")
+	if !ok {
+		return nil, ErrUnauthorized
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrUnauthorized
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "RS256" {
+		return nil, ErrUnauthorized
+	}
+	key, err := a.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	if channelID != "" && !contains(key.endorsements, channelID) {
+		return nil, ErrUnauthorized
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrUnauthorized
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key.key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrUnauthorized
+	}
+
+	var claims struct {
+		Iss        string  `json:"iss"`
+		Aud        string  `json:"aud"`
+		Exp        float64 `json:"exp"`
+		Nbf        float64 `json:"nbf"`
+		ServiceURL string  `json:"serviceurl"`
+	}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrUnauthorized
+	}
+	now := time.Now()
+	expiresAt := time.Unix(int64(claims.Exp), 0)
+	if claims.Iss != Issuer || claims.Aud == "" || now.After(expiresAt.Add(clockSkew)) ||
+		(claims.Nbf != 0 && now.Add(clockSkew).Before(time.Unix(int64(claims.Nbf), 0))) {
+		return nil, ErrUnauthorized
+	}
+	return &Claims{Audience: claims.Aud, ServiceURL: claims.ServiceURL, ExpiresAt: expiresAt}, nil
+}
+
+// key returns the signing key kid, reloading the key set when it is stale or doesn't have kid.
+func (a *Authenticator) key(ctx context.Context, kid string) (signingKey, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key, ok := a.keys[kid]
+	stale := time.Since(a.fetchedAt) > keyRefreshInterval
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(a.fetchedAt) < minKeyRefreshInterval {
+		return signingKey{}, ErrUnauthorized
+	}
+
+	keys, err := a.fetchKeys(ctx)
+	if err != nil {
+		if ok {
+			// Keep serving the known key rather than failing every request while metadata is down
+			return key, nil
+		}
+		return signingKey{}, err
+	}
+	a.keys, a.fetchedAt = keys, time.Now()
+	if key, ok = keys[kid]; !ok {
+		return signingKey{}, ErrUnauthorized
+	}
+	return key, nil
+}
+
+func (a *Authenticator) fetchKeys(ctx context.Context) (map[string]signingKey, error) {
+	var metadata struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := a.getJSON(ctx, a.MetadataURL, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to load bot framework metadata: %w", err)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty          string   `json:"kty"`
+			Kid          string   `json:"kid"`
+			N            string   `json:"n"`
+			E            string   `json:"e"`
+			Endorsements []string `json:"endorsements"`
+		} `json:"keys"`
+	}
+	if err := a.getJSON(ctx, metadata.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to load bot framework signing keys: %w", err)
+	}
+
+	keys := make(map[string]signingKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = signingKey{
+			key:          &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())},
+			endorsements: k.Endorsements,
+		}
+	}
+	return keys, nil
+}
+
+func (a *Authenticator) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
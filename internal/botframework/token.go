@@ -0,0 +1,101 @@
+package botframework
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TokenURLTemplate is the token endpoint, formatted with the bot's tenant
+	TokenURLTemplate = "https://login.microsoftonline.com/%s/oauth2/v2.0/token"
+	// defaultTenant issues tokens for multi-tenant bots
+	defaultTenant  = "botframework.com"
+	connectorScope = "https://api.botframework.com/.default"
+	// tokenRefreshMargin renews tokens before they expire mid-request
+	tokenRefreshMargin = 5 * time.Minute
+)
+
+type cachedToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// TokenSource exchanges bot app credentials for connector access tokens and caches them until
+// shortly before they expire.
+type TokenSource struct {
+	TokenURLTemplate string
+	httpClient       *http.Client
+
+	mu     sync.Mutex
+	tokens map[string]cachedToken
+}
+
+// NewTokenSource creates a TokenSource using the Microsoft identity platform.
+func NewTokenSource() *TokenSource {
+	return &TokenSource{
+		TokenURLTemplate: TokenURLTemplate,
+		httpClient:       &http.Client{Timeout: 10 * time.Second},
+		tokens:           make(map[string]cachedToken),
+	}
+}
+
+// Token returns an access token for the bot appID. An empty tenantID uses the multi-tenant endpoint.
+func (s *TokenSource) Token(ctx context.Context, appID, appPassword, tenantID string) (string, error) {
+	if tenantID == "" {
+		tenantID = defaultTenant
+	}
+	cacheKey := tenantID + "/" + appID
+
+	s.mu.Lock()
+	cached, ok := s.tokens[cacheKey]
+	s.mu.Unlock()
+	if ok && time.Until(cached.expiresAt) > tokenRefreshMargin {
+		return cached.value, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {appID},
+		"client_secret": {appPassword},
+		"scope":         {connectorScope},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf(s.TokenURLTemplate, url.PathEscape(tenantID)), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		AccessToken      string `json:"access_token"`
+		ExpiresIn        int    `json:"expires_in"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode token response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || body.AccessToken == "" {
+		return "", fmt.Errorf("token request returned status %d: %s %s", resp.StatusCode, body.Error, body.ErrorDescription)
+	}
+
+	s.mu.Lock()
+	s.tokens[cacheKey] = cachedToken{
+		value:     body.AccessToken,
+		expiresAt: time.Now().Add(time.Duration(body.ExpiresIn) * time.Second),
+	}
+	s.mu.Unlock()
+	return body.AccessToken, nil
+}
@@ -0,0 +1,196 @@
+// Package cache provides cluster-wide invalidation for in-process caches.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Kinds of cached documents that can be invalidated.
+const (
+	KindClient               = "client"                 // Key: client_id
+	KindClientChannel        = "client_channel"         // Key: hex ObjectID of the owning client
+	KindEventProcessorConfig = "event_processor_config" // Key: hex ObjectID of the config
+	KindFeatureFlag          = "feature_flag"           // Key: flag key
+)
+
+// Invalidation is the message broadcast when a cached document changes.
+type Invalidation struct {
+	Kind   string    `json:"kind"`
+	Key    string    `json:"key"`
+	Origin string    `json:"origin"`
+	At     time.Time `json:"at"`
+}
+
+// Handler drops the cached entry described by an invalidation.
+type Handler func(Invalidation)
+
+// Bus broadcasts invalidations over a RabbitMQ fanout exchange. Every API and
+// worker process binds its own exclusive queue to the exchange, so a change made
+// on any node reaches all caches in the cluster.
+type Bus struct {
+	conn     *amqp.Connection
+	channel  *amqp.Channel
+	exchange string
+	origin   string
+	logger   *zap.Logger
+
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewBus connects to RabbitMQ, declares the fanout exchange and starts consuming invalidations.
+func NewBus(rabbitMQURL, exchange string, logger *zap.Logger) (*Bus, error) {
+	conn, err := amqp.Dial(rabbitMQURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	b := &Bus{
+		conn:     conn,
+		channel:  channel,
+		exchange: exchange,
+		origin:   primitive.NewObjectID().Hex(),
+		logger:   logger,
+		handlers: make(map[string][]Handler),
+	}
+
+	deliveries, err := b.bind()
+	if err != nil {
+		b.Close()
+		return nil, err
+	}
+	go b.consume(deliveries)
+
+	return b, nil
+}
+
+// bind declares the exchange and a server-named queue that is removed when this process disconnects.
+func (b *Bus) bind() (<-chan amqp.Delivery, error) {
+	if err := b.channel.ExchangeDeclare(
+		b.exchange, // name
+		"fanout",   // type
+		true,       // durable
+		false,      // auto-deleted
+		false,      // internal
+		false,      // no-wait
+		nil,        // arguments
+	); err != nil {
+		return nil, fmt.Errorf("failed to declare exchange %s: %w", b.exchange, err)
+	}
+
+	queue, err := b.channel.QueueDeclare(
+		"",    // name
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		nil,   // arguments
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to declare invalidation queue: %w", err)
+	}
+
+	if err := b.channel.QueueBind(queue.Name, "", b.exchange, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to bind invalidation queue: %w", err)
+	}
+
+	deliveries, err := b.channel.Consume(
+		queue.Name, // queue
+		"",         // consumer
+		true,       // auto-ack
+		true,       // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume invalidation queue: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (b *Bus) consume(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		var inv Invalidation
+		if err := json.Unmarshal(d.Body, &inv); err != nil {
+			b.logger.Warn("Dropping malformed cache invalidation", zap.Error(err))
+			continue
+		}
+		// Local handlers already ran when this process published it
+		if inv.Origin == b.origin {
+			continue
+		}
+		b.dispatch(inv)
+	}
+}
+
+// Subscribe registers h to run for every invalidation of kind, whether published locally or by another node.
+func (b *Bus) Subscribe(kind string, h Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[kind] = append(b.handlers[kind], h)
+}
+
+// Invalidate drops the entry from local caches, then broadcasts it to the rest of the cluster.
+func (b *Bus) Invalidate(ctx context.Context, kind, key string) error {
+	inv := Invalidation{
+		Kind:   kind,
+		Key:    key,
+		Origin: b.origin,
+		At:     time.Now().UTC(),
+	}
+	b.dispatch(inv)
+
+	body, err := json.Marshal(inv)
+	if err != nil {
+		return fmt.Errorf("failed to marshal invalidation: %w", err)
+	}
+	err = b.channel.PublishWithContext(ctx,
+		b.exchange, // exchange
+		"",         // routing key
+		false,      // mandatory
+		false,      // immediate
+		amqp.Publishing{
+			ContentType: "application/json",
+			Body:        body,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish invalidation: %w", err)
+	}
+	return nil
+}
+
+func (b *Bus) dispatch(inv Invalidation) {
+	b.mu.RLock()
+	handlers := b.handlers[inv.Kind]
+	b.mu.RUnlock()
+	for _, h := range handlers {
+		h(inv)
+	}
+}
+
+// Close stops consuming and closes the RabbitMQ connection.
+func (b *Bus) Close() error {
+	if b.channel != nil {
+		b.channel.Close()
+	}
+	if b.conn != nil {
+		return b.conn.Close()
+	}
+	return nil
+}
@@ -0,0 +1,342 @@
+// Package changestream publishes entity events for writes made straight to MongoDB, such as bulk
+// imports and manual fixes, which bypass the services that normally publish them.
+package changestream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+const (
+	// checkpointCollection holds the resume token of each watched collection, keyed by its name
+	checkpointCollection = "change_stream_checkpoints"
+	// checkpointInterval bounds how many changes are looked at again after a restart
+	checkpointInterval = 5 * time.Second
+	// codeChangeStreamHistoryLost means the oplog no longer reaches back to the resume token
+	codeChangeStreamHistoryLost = 286
+)
+
+// Listener watches chat_messages and chat_sessions and publishes the events their services
+// publish. Writes made through the services already have their events, so each change is looked
+// at only once Grace has passed, and skipped when its event exists by then.
+type Listener struct {
+	Grace time.Duration
+
+	db        *mongo.Database
+	events    *repository.EventRepository
+	publisher *service.EventPublisherService
+	payloads  *service.PayloadService
+	logger    *zap.Logger
+}
+
+// NewListener creates a Listener publishing through publisher.
+func NewListener(db *mongo.Database, events *repository.EventRepository, publisher *service.EventPublisherService, payloads *service.PayloadService, logger *zap.Logger, grace time.Duration) *Listener {
+	return &Listener{
+		Grace:     grace,
+		db:        db,
+		events:    events,
+		publisher: publisher,
+		payloads:  payloads,
+		logger:    logger,
+	}
+}
+
+// change is the part of a change stream event the listener reads.
+type change struct {
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument      bson.Raw `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+// at returns when the change was committed.
+func (c change) at() time.Time {
+	return time.Unix(int64(c.ClusterTime.T), 0)
+}
+
+// stream is a watched collection and how its changes become events.
+type stream struct {
+	collection string
+	pipeline   mongo.Pipeline
+	handle     func(ctx context.Context, c change) error
+}
+
+// Run watches every collection until ctx is done. A change whose event can't be published stops
+// the listener, so it is retried from the last checkpoint rather than skipped.
+func (l *Listener) Run(ctx context.Context) error {
+	streams := []stream{
+		{
+			collection: models.ChatMessage{}.TableName(),
+			pipeline:   mongo.Pipeline{{{Key: "$match", Value: bson.M{"operationType": "insert"}}}},
+			handle:     l.handleMessage,
+		},
+		{
+			collection: "chat_sessions",
+			pipeline: mongo.Pipeline{{{Key: "$match", Value: bson.M{"$or": bson.A{
+				bson.M{"operationType": "insert"},
+				bson.M{"operationType": "update", "updateDescription.updatedFields.state": bson.M{"$exists": true}},
+			}}}}},
+			handle: l.handleSession,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(streams))
+	for _, s := range streams {
+		go func(s stream) { errs <- l.watch(ctx, s) }(s)
+	}
+
+	var first error
+	for range streams {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			cancel()
+		}
+	}
+	return first
+}
+
+// watch follows one collection, starting over from the present when its checkpoint has fallen
+// out of the oplog.
+func (l *Listener) watch(ctx context.Context, s stream) error {
+	for {
+		err := l.follow(ctx, s)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var cmdErr mongo.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != codeChangeStreamHistoryLost {
+			return err
+		}
+		l.logger.Error("Change stream checkpoint is older than the oplog, changes since it were missed",
+			zap.String("collection", s.collection))
+		if err := l.saveCheckpoint(ctx, s.collection, nil); err != nil {
+			return err
+		}
+	}
+}
+
+func (l *Listener) follow(ctx context.Context, s stream) error {
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	token, err := l.loadCheckpoint(ctx, s.collection)
+	if err != nil {
+		return err
+	}
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+
+	cs, err := l.db.Collection(s.collection).Watch(ctx, s.pipeline, opts)
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", s.collection, err)
+	}
+	defer cs.Close(context.Background())
+	l.logger.Info("Watching collection for changes", zap.String("collection", s.collection), zap.Bool("resumed", token != nil))
+
+	// Only changes that were fully handled are checkpointed
+	var handled bson.Raw
+	lastSaved := time.Now()
+	defer func() {
+		if handled == nil {
+			return
+		}
+		saveCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := l.saveCheckpoint(saveCtx, s.collection, handled); err != nil {
+			l.logger.Warn("Failed to save change stream checkpoint", zap.String("collection", s.collection), zap.Error(err))
+		}
+	}()
+
+	for cs.Next(ctx) {
+		var c change
+		if err := cs.Decode(&c); err != nil {
+			return fmt.Errorf("failed to decode change on %s: %w", s.collection, err)
+		}
+		if !l.waitGrace(ctx, c) {
+			return ctx.Err()
+		}
+		if err := s.handle(ctx, c); err != nil {
+			return fmt.Errorf("failed to publish event for %s %s: %w", s.collection, c.DocumentKey.ID.Hex(), err)
+		}
+
+		handled = cs.ResumeToken()
+		if time.Since(lastSaved) >= checkpointInterval {
+			if err := l.saveCheckpoint(ctx, s.collection, handled); err != nil {
+				return err
+			}
+			lastSaved = time.Now()
+		}
+	}
+	return cs.Err()
+}
+
+// waitGrace sleeps until Grace has passed since the change, giving the service that made it time
+// to publish its own event. It returns false when ctx ends first.
+func (l *Listener) waitGrace(ctx context.Context, c change) bool {
+	wait := time.Until(c.at().Add(l.Grace))
+	if wait <= 0 {
+		return true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (l *Listener) handleMessage(ctx context.Context, c change) error {
+	messageID := c.DocumentKey.ID.Hex()
+	published, err := l.published(ctx, bson.M{
+		"event_type":  models.EventTypeChatMessageCreated,
+		"entity_type": models.EntityTypeChatMessage,
+		"entity_id":   messageID,
+	})
+	if err != nil || published {
+		return err
+	}
+
+	var msg models.ChatMessage
+	if err := bson.Unmarshal(c.FullDocument, &msg); err != nil {
+		return fmt.Errorf("failed to decode message: %w", err)
+	}
+	payload, err := l.payloads.CreateChatMessagePayload(ctx, messageID)
+	if err != nil {
+		l.logger.Warn("Failed to create message payload, publishing a minimal one", zap.String("message_id", messageID), zap.Error(err))
+		payload = map[string]interface{}{
+			"id":          messageID,
+			"sender":      msg.Sender,
+			"sender_type": msg.SenderType,
+			"text":        msg.Text,
+			"category":    string(msg.Category),
+		}
+	}
+
+	sessionID := msg.SessionID.Hex()
+	_, err = l.publisher.PublishChatMessageEvent(ctx, models.EventTypeChatMessageCreated, messageID, &sessionID, payload)
+	return err
+}
+
+func (l *Listener) handleSession(ctx context.Context, c change) error {
+	if c.FullDocument == nil {
+		// Deleted before it could be looked up
+		return nil
+	}
+	var session models.ChatSession
+	if err := bson.Unmarshal(c.FullDocument, &session); err != nil {
+		return fmt.Errorf("failed to decode session: %w", err)
+	}
+	sessionID := c.DocumentKey.ID.Hex()
+
+	if c.OperationType == "insert" {
+		published, err := l.published(ctx, bson.M{
+			"event_type":  models.EventTypeChatSessionCreated,
+			"entity_type": models.EntityTypeChatSession,
+			"entity_id":   sessionID,
+		})
+		if err != nil || published {
+			return err
+		}
+		data := map[string]interface{}{
+			"session_id":   session.SessionID,
+			"state":        session.CurrentState(),
+			"participants": session.Participants,
+			"created_at":   session.CreatedAt,
+		}
+		if session.Client != nil {
+			data["client"] = session.Client.Hex()
+		}
+		if session.ClientChannel != nil {
+			data["client_channel"] = session.ClientChannel.Hex()
+		}
+		_, err = l.publisher.PublishChatSessionEvent(ctx, models.EventTypeChatSessionCreated, sessionID, data)
+		return err
+	}
+
+	// The lifecycle service publishes state changes right after making them; without pre-images
+	// the previous state isn't known, so "from" is left out
+	to, _ := c.UpdateDescription.UpdatedFields["state"].(string)
+	published, err := l.published(ctx, bson.M{
+		"event_type":  models.EventTypeSessionStateChanged,
+		"entity_type": models.EntityTypeChatSession,
+		"entity_id":   sessionID,
+		"data.to":     to,
+		"created_at":  bson.M{"$gte": c.at().Add(-l.Grace)},
+	})
+	if err != nil || published {
+		return err
+	}
+	data := map[string]interface{}{
+		"session_id": session.SessionID,
+		"to":         to,
+		"source":     "change_stream",
+	}
+	if reason, ok := c.UpdateDescription.UpdatedFields["state_reason"].(string); ok {
+		data["reason"] = reason
+	}
+	_, err = l.publisher.PublishChatSessionEvent(ctx, models.EventTypeSessionStateChanged, sessionID, data)
+	return err
+}
+
+// published reports whether an event matching filter exists.
+func (l *Listener) published(ctx context.Context, filter bson.M) (bool, error) {
+	count, err := l.events.Count(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (l *Listener) loadCheckpoint(ctx context.Context, collection string) (bson.Raw, error) {
+	var checkpoint struct {
+		ResumeToken bson.Raw `bson:"resume_token"`
+	}
+	err := l.db.Collection(checkpointCollection).FindOne(ctx, bson.M{"_id": collection}).Decode(&checkpoint)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load change stream checkpoint for %s: %w", collection, err)
+	}
+	return checkpoint.ResumeToken, nil
+}
+
+// saveCheckpoint records token as the place to resume collection from; a nil token starts over
+// from the present.
+func (l *Listener) saveCheckpoint(ctx context.Context, collection string, token bson.Raw) error {
+	checkpoints := l.db.Collection(checkpointCollection)
+	var err error
+	if token == nil {
+		_, err = checkpoints.DeleteOne(ctx, bson.M{"_id": collection})
+	} else {
+		_, err = checkpoints.UpdateOne(ctx,
+			bson.M{"_id": collection},
+			bson.M{"$set": bson.M{"resume_token": token, "updated_at": time.Now().UTC()}},
+			options.Update().SetUpsert(true),
+		)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save change stream checkpoint for %s: %w", collection, err)
+	}
+	return nil
+}
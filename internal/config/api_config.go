@@ -1,16 +1,20 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/url"
 	"os"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
+// ScheduleOff disables a maintenance job in place of its cron schedule
+const ScheduleOff = "off"
+
 type Config struct {
 	// Application settings
 	ProjectName string
@@ -20,9 +24,54 @@ type Config struct {
 	GinMode     string
 	LogLevel    string
 
+	// Lifecycle
+	StartupTimeout  time.Duration
+	ShutdownTimeout time.Duration
+
+	// MetricsPort is where workers serve /metrics; empty disables it. The API serves it on AppPort.
+	MetricsPort string
+	// GRPCPort is where the API serves the internal gRPC API; empty disables it
+	GRPCPort string
+	// ReadinessTimeout bounds each dependency probe of the readiness check
+	ReadinessTimeout time.Duration
+
 	// Database
 	MongoURI string
 	MongoDB  string
+	// Client settings; zero values keep what the URI sets, or the driver default
+	MongoMaxPoolSize            int
+	MongoMinPoolSize            int
+	MongoMaxConnIdleTime        time.Duration
+	MongoConnectTimeout         time.Duration
+	MongoSocketTimeout          time.Duration
+	MongoServerSelectionTimeout time.Duration
+	MongoReadPreference         string
+	MongoReadConcern            string
+	// MongoCompressors is a comma-separated list of snappy, zlib and zstd, in order of preference
+	MongoCompressors string
+	// MongoAnalyticsReadPreference is used by reports and exports, so they can run on secondaries
+	// without loading the primary
+	MongoAnalyticsReadPreference string
+	// MongoTransactions stores a message and the records and events it brings along in one
+	// transaction, when the deployment is a replica set or sharded cluster
+	MongoTransactions bool
+	// Repository operations: each attempt is bounded by MongoOperationTimeout, transient failures
+	// are retried MongoRetryAttempts times starting MongoRetryBackoff apart, and operations slower
+	// than MongoSlowQueryThreshold are logged. Zero disables each of them.
+	MongoOperationTimeout   time.Duration
+	MongoRetryAttempts      int
+	MongoRetryBackoff       time.Duration
+	MongoSlowQueryThreshold time.Duration
+
+	// MigrateOnStartup applies pending migrations and creates missing indexes before serving;
+	// otherwise they are left to "api-service migrate"
+	MigrateOnStartup bool
+	MigrationTimeout time.Duration
+	// DeliveryAttemptRetention is how long webhook delivery attempts are kept; 0 keeps them
+	DeliveryAttemptRetention time.Duration
+	// ChangeStreamGrace is how long the change stream listener leaves a service to publish the
+	// event for its own write before publishing it itself
+	ChangeStreamGrace time.Duration
 
 	// RabbitMQ/Queue settings
 	CeleryBrokerURL    string
@@ -34,14 +83,123 @@ type Config struct {
 	RabbitMQVHost      string
 	CeleryDefaultQueue string
 	CeleryEventsQueue  string
+	// CeleryMessageProtocol is the Celery message protocol tasks are published in, 1 or 2; workers
+	// read both
+	CeleryMessageProtocol int
+	// CeleryTaskExchange, when set, is the exchange tasks are published to, with their queue's name as
+	// routing key; queues are bound to it. Empty publishes straight to the queues.
+	CeleryTaskExchange string
+	// CeleryTaskExchangeType is the type of CeleryTaskExchange: direct or topic
+	CeleryTaskExchangeType string
+	// TaskPublishConfirmTimeout is how long publishing a task waits for the broker to confirm it
+	TaskPublishConfirmTimeout time.Duration
+	// TaskPublishRetryBuffer is how many unconfirmed tasks are kept in memory to publish again; 0
+	// disables retries
+	TaskPublishRetryBuffer int
+	// TaskPublishMaxRetries is how often a buffered task is published again before it is dropped
+	TaskPublishMaxRetries int
+	// BackpressureShedDepth is the depth of the workflow queue above which suggestion workflows are
+	// shed; 0 disables shedding
+	BackpressureShedDepth int
+	// BackpressureDegradedDepth is the depth of the workflow queue above which new messages are
+	// answered with 202 and degraded-mode headers; 0 disables it
+	BackpressureDegradedDepth int
+	// BackpressureCheckInterval is how often the depth of the workflow queue is checked
+	BackpressureCheckInterval time.Duration
+
+	// Cache
+	CacheInvalidationExchange string
+	// ClientCacheTTL is how long clients and channels are served from memory; 0 disables the cache
+	ClientCacheTTL time.Duration
+
+	// Realtime
+	SessionNotificationExchange string
+
+	// Sessions
+	SessionAutoCloseMinutes    int
+	SessionSweepInterval       time.Duration
+	ClosedSessionMessagePolicy string
+
+	// UsageReportInterval is how often workers publish usage_report events for days not reported yet
+	UsageReportInterval time.Duration
+	// ReportSchedulerInterval is how often workers queue the scheduled analytics reports that are due
+	ReportSchedulerInterval time.Duration
+
+	// Scheduler mode enqueues maintenance jobs on these five-field cron schedules, in UTC; "off"
+	// disables a job
+	ScheduleRetryDeliveries   string
+	ScheduleCSATExpiry        string
+	ScheduleThreadInactivity  string
+	ScheduleUsageRollup       string
+	ScheduleAIFeedback        string
+	ScheduleScheduledMessages string
+	// SchedulerLease is how long a scheduler instance stays leader without renewing its lease
+	SchedulerLease time.Duration
 
 	// External services
 	SlackAIServiceURL       string
 	SlackAIToken            string
-	SlackAIServiceWorkflowID string
 	AIServiceURL            string
+	// AIBatchURL is the AI service's batch endpoint; when set, workers batch suggestion tasks per
+	// client, sending what arrives within AIBatchWindow, up to AIBatchMaxSize, in one request
+	AIBatchURL     string
+	AIBatchWindow  time.Duration
+	AIBatchMaxSize int
+	// AIFeedbackURL is the AI service's feedback endpoint; when set, answer feedback of clients that
+	// opt in is forwarded to it in batches of up to AIFeedbackBatchSize
+	AIFeedbackURL       string
+	AIFeedbackBatchSize int
 	EncryptionKey           string
 	AdminAPIKey             string
+	SandboxWebhookSinkURL   string
+	AttachmentBaseURL       string
+	PublicAPIURL            string
+
+	// Webhook dispatch egress: an optional proxy for all webhook requests, and comma-separated
+	// host names, *.domain patterns, IPs or CIDRs webhooks may (allowlist) or may not (denylist)
+	// reach. Private addresses are blocked unless allowed.
+	DispatchProxyURL             string
+	DispatchAllowedHosts         string
+	DispatchDeniedHosts          string
+	DispatchAllowPrivateNetworks bool
+	// Connection pooling of webhook dispatch; MaxConnsPerHost 0 means no limit
+	DispatchMaxIdleConns        int
+	DispatchMaxIdleConnsPerHost int
+	DispatchMaxConnsPerHost     int
+	DispatchIdleConnTimeout     time.Duration
+	DispatchHTTP2               bool
+
+	// Secret references (secret://...) in processor and channel configuration are resolved
+	// with these providers; env is always available, vault and aws when configured
+	SecretsCacheTTL    time.Duration
+	SecretsEnvPrefix   string
+	SecretsPathPrefix  string // Prefix of client secret paths in Vault and AWS Secrets Manager
+	VaultAddr          string
+	VaultToken         string
+	VaultMount         string
+	AWSRegion          string
+	AWSAccessKeyID     string
+	AWSSecretAccessKey string
+	AWSSessionToken    string
+
+	// OIDC login for dashboard users; disabled when OIDCIssuer is empty
+	OIDCIssuer      string
+	OIDCAudience    string
+	OIDCRolesClaim  string
+	OIDCRoleMapping string
+	OIDCClientClaim string
+
+	// Rate limiting of message creation and AI-triggering routes; tiers are
+	// "name=requests_per_minute" pairs, and 0 disables a limit
+	RateLimitTiers       string
+	RateLimitIPPerMinute int
+	// TrustedProxies are the comma-separated IPs or CIDRs of proxies whose X-Forwarded-For and
+	// X-Real-IP headers give the client IP; with none, the client IP is the peer address
+	TrustedProxies string
+
+	// Tracing; spans are exported over OTLP/HTTP when an endpoint is set. The exporter reads
+	// the other standard OTEL_* variables itself
+	OTelExporterEndpoint string
 
 	// AWS Bedrock
 	AWSBedrockAccessKeyID     string
@@ -54,64 +212,218 @@ type Config struct {
 	RedisPort     int
 	RedisDB       int
 	RedisPassword string
+	// SessionContextCacheTTL is how long workers keep a session's AI context in Redis before
+	// rebuilding it; 0 disables the cache
+	SessionContextCacheTTL time.Duration
+	// SessionContextMaxMessages bounds how many recent messages an AI context holds
+	SessionContextMaxMessages int
 
 	// Feature flags
 	EnableClientChannelRouting   bool
 	EnableConfigurableWorkflows  bool
 }
 
-func LoadConfig() *Config {
+// Load reads the configuration and validates it. Each setting is read from its environment
+// variable (.env included), then from the YAML config file at path, or at CONFIG_FILE when path is
+// empty, and otherwise takes its default. The error lists every problem found.
+func Load(path string) (*Config, error) {
 	// Load .env if present
 	_ = godotenv.Load(".env")
-	mongoURI := getEnv("MONGODB_URI", "mongodb://localhost:27017/fraiday-backend")
+
+	src := &source{}
+	if path == "" {
+		path = os.Getenv("CONFIG_FILE")
+	}
+	if path != "" {
+		values, err := readConfigFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src.file = values
+	}
+
+	cfg := src.load()
+	problems := src.problems
+	var invalid *ValidationError
+	if err := cfg.Validate(); errors.As(err, &invalid) {
+		problems = append(problems, invalid.Problems...)
+	}
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return cfg, nil
+}
+
+// LoadConfig loads the configuration like Load, exiting when it is invalid. It is meant for
+// callers that have no way to report the error.
+func LoadConfig() *Config {
+	cfg, err := Load("")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return cfg
+}
+
+// load builds the configuration from s.
+func (s *source) load() *Config {
+	mongoURI := s.getEnvURL("MONGODB_URI", "mongodb://localhost:27017/fraiday-backend", "mongodb", "mongodb+srv")
 	
 	cfg := &Config{
 		// Application settings
-		ProjectName: getEnv("PROJECT_NAME", "API Service"),
-		Version:     getEnv("VERSION", "1.0.0"),
-		AppPort:     getEnv("APP_PORT", "8000"),
-		AppEnv:      getEnv("APP_ENV", "development"),
-		GinMode:     getEnv("GIN_MODE", "debug"),
-		LogLevel:    getEnv("LOG_LEVEL", "INFO"),
+		ProjectName: s.getEnv("PROJECT_NAME", "API Service"),
+		Version:     s.getEnv("VERSION", "1.0.0"),
+		AppPort:     s.getEnv("APP_PORT", "8000"),
+		AppEnv:      s.getEnv("APP_ENV", "development"),
+		GinMode:     s.getEnv("GIN_MODE", "debug"),
+		LogLevel:    s.getEnv("LOG_LEVEL", "INFO"),
+
+		// Lifecycle
+		StartupTimeout:  s.getEnvDuration("STARTUP_TIMEOUT_SECONDS", time.Second, 30*time.Second),
+		ShutdownTimeout: s.getEnvDuration("SHUTDOWN_TIMEOUT_SECONDS", time.Second, 30*time.Second),
+
+		MetricsPort:      s.getEnv("METRICS_PORT", "9090"),
+		GRPCPort:         s.getEnv("GRPC_PORT", ""),
+		ReadinessTimeout: s.getEnvDuration("READINESS_TIMEOUT_SECONDS", time.Second, 2*time.Second),
 
 		// Database
 		MongoURI: mongoURI,
-		MongoDB:  extractDatabaseFromURI(mongoURI),
+		MongoDB:  s.extractDatabaseFromURI(mongoURI),
+
+		MongoMaxPoolSize:             s.getEnvInt("MONGODB_MAX_POOL_SIZE", 0),
+		MongoMinPoolSize:             s.getEnvInt("MONGODB_MIN_POOL_SIZE", 0),
+		MongoMaxConnIdleTime:         s.getEnvDuration("MONGODB_MAX_CONN_IDLE_SECONDS", time.Second, 0),
+		MongoConnectTimeout:          s.getEnvDuration("MONGODB_CONNECT_TIMEOUT_SECONDS", time.Second, 0),
+		MongoSocketTimeout:           s.getEnvDuration("MONGODB_SOCKET_TIMEOUT_SECONDS", time.Second, 0),
+		MongoServerSelectionTimeout:  s.getEnvDuration("MONGODB_SERVER_SELECTION_TIMEOUT_SECONDS", time.Second, 0),
+		MongoReadPreference:          s.getEnv("MONGODB_READ_PREFERENCE", ""),
+		MongoReadConcern:             s.getEnv("MONGODB_READ_CONCERN", ""),
+		MongoCompressors:             s.getEnv("MONGODB_COMPRESSORS", ""),
+		MongoAnalyticsReadPreference: s.getEnv("MONGODB_ANALYTICS_READ_PREFERENCE", "secondaryPreferred"),
+		MongoTransactions:            s.getEnvBool("MONGODB_TRANSACTIONS", true),
+		MongoOperationTimeout:        s.getEnvDuration("MONGODB_OPERATION_TIMEOUT_SECONDS", time.Second, 30*time.Second),
+		MongoRetryAttempts:           s.getEnvInt("MONGODB_RETRY_ATTEMPTS", 2),
+		MongoRetryBackoff:            s.getEnvDuration("MONGODB_RETRY_BACKOFF_MS", time.Millisecond, 100*time.Millisecond),
+		MongoSlowQueryThreshold:      s.getEnvDuration("MONGODB_SLOW_QUERY_MS", time.Millisecond, 500*time.Millisecond),
+
+		MigrateOnStartup:         s.getEnvBool("MIGRATE_ON_STARTUP", true),
+		MigrationTimeout:         s.getEnvDuration("MIGRATION_TIMEOUT_SECONDS", time.Second, 5*time.Minute),
+		DeliveryAttemptRetention: s.getEnvDuration("DELIVERY_ATTEMPT_RETENTION_DAYS", 24*time.Hour, 90*24*time.Hour),
+		ChangeStreamGrace:        s.getEnvDuration("CHANGE_STREAM_GRACE_SECONDS", time.Second, 10*time.Second),
 
 		// RabbitMQ/Queue settings
-		CeleryBrokerURL:    getEnv("CELERY_BROKER_URL", ""),
-		RabbitMQURL:        getEnv("RABBITMQ_URL", ""),
-		RabbitMQHost:       getEnv("RABBITMQ_HOST", "localhost"),
-		RabbitMQPort:       getEnvInt("RABBITMQ_PORT", 5672),
-		RabbitMQUser:       getEnv("RABBITMQ_USER", "guest"),
-		RabbitMQPassword:   getEnv("RABBITMQ_PASSWORD", "guest"),
-		RabbitMQVHost:      getEnv("RABBITMQ_VHOST", "/"),
-		CeleryDefaultQueue: getEnv("CELERY_DEFAULT_QUEUE", "chat_workflow"),
-		CeleryEventsQueue:  getEnv("CELERY_EVENTS_QUEUE", "events"),
+		CeleryBrokerURL:    s.getEnvURL("CELERY_BROKER_URL", "", "amqp", "amqps"),
+		RabbitMQURL:        s.getEnvURL("RABBITMQ_URL", "", "amqp", "amqps"),
+		RabbitMQHost:       s.getEnv("RABBITMQ_HOST", "localhost"),
+		RabbitMQPort:       s.getEnvInt("RABBITMQ_PORT", 5672),
+		RabbitMQUser:       s.getEnv("RABBITMQ_USER", "guest"),
+		RabbitMQPassword:   s.getEnv("RABBITMQ_PASSWORD", "guest"),
+		RabbitMQVHost:      s.getEnv("RABBITMQ_VHOST", "/"),
+		CeleryDefaultQueue: s.getEnv("CELERY_DEFAULT_QUEUE", "chat_workflow"),
+		CeleryEventsQueue:  s.getEnv("CELERY_EVENTS_QUEUE", "events"),
+
+		CeleryMessageProtocol:     s.getEnvInt("CELERY_MESSAGE_PROTOCOL", 1),
+		CeleryTaskExchange:        s.getEnv("CELERY_TASK_EXCHANGE", ""),
+		CeleryTaskExchangeType:    s.getEnv("CELERY_TASK_EXCHANGE_TYPE", "direct"),
+		TaskPublishConfirmTimeout: s.getEnvDuration("TASK_PUBLISH_CONFIRM_TIMEOUT_SECONDS", time.Second, 5*time.Second),
+		TaskPublishRetryBuffer:    s.getEnvInt("TASK_PUBLISH_RETRY_BUFFER", 1000),
+		TaskPublishMaxRetries:     s.getEnvInt("TASK_PUBLISH_MAX_RETRIES", 5),
+		BackpressureShedDepth:     s.getEnvInt("BACKPRESSURE_SHED_SUGGESTIONS_DEPTH", 0),
+		BackpressureDegradedDepth: s.getEnvInt("BACKPRESSURE_DEGRADED_DEPTH", 0),
+		BackpressureCheckInterval: s.getEnvDuration("BACKPRESSURE_CHECK_INTERVAL_SECONDS", time.Second, 5*time.Second),
+
+		// Cache
+		CacheInvalidationExchange: s.getEnv("CACHE_INVALIDATION_EXCHANGE", "cache_invalidation"),
+		ClientCacheTTL:            s.getEnvDuration("CLIENT_CACHE_TTL_SECONDS", time.Second, 30*time.Second),
+
+		// Realtime
+		SessionNotificationExchange: s.getEnv("SESSION_NOTIFICATION_EXCHANGE", "session_notifications"),
+
+		// Sessions
+		SessionAutoCloseMinutes:    s.getEnvInt("SESSION_AUTO_CLOSE_MINUTES", 0),
+		SessionSweepInterval:       s.getEnvDuration("SESSION_SWEEP_INTERVAL_SECONDS", time.Second, time.Minute),
+		ClosedSessionMessagePolicy: s.getEnv("CLOSED_SESSION_MESSAGE_POLICY", "reopen"),
+
+		UsageReportInterval:     s.getEnvDuration("USAGE_REPORT_INTERVAL_SECONDS", time.Second, time.Hour),
+		ReportSchedulerInterval: s.getEnvDuration("REPORT_SCHEDULER_INTERVAL_SECONDS", time.Second, time.Minute),
+
+		ScheduleRetryDeliveries:   s.getEnv("SCHEDULE_RETRY_DELIVERIES", "*/5 * * * *"),
+		ScheduleCSATExpiry:        s.getEnv("SCHEDULE_CSAT_EXPIRY", "*/10 * * * *"),
+		ScheduleThreadInactivity:  s.getEnv("SCHEDULE_THREAD_INACTIVITY", "*/15 * * * *"),
+		ScheduleUsageRollup:       s.getEnv("SCHEDULE_USAGE_ROLLUP", "15 * * * *"),
+		ScheduleAIFeedback:        s.getEnv("SCHEDULE_AI_FEEDBACK", "*/5 * * * *"),
+		ScheduleScheduledMessages: s.getEnv("SCHEDULE_SCHEDULED_MESSAGES", "*/5 * * * *"),
+		SchedulerLease:            s.getEnvDuration("SCHEDULER_LEASE_SECONDS", time.Second, 30*time.Second),
 
 		// External services
-		SlackAIServiceURL:       getEnv("SLACK_AI_SERVICE_URL", ""),
-		SlackAIToken:            getEnv("SLACK_AI_TOKEN", ""),
-		SlackAIServiceWorkflowID: getEnv("SLACK_AI_SERVICE_WORKFLOW_ID", ""),
-		AIServiceURL:            getEnv("SLACK_AI_SERVICE_URL", ""),
-		EncryptionKey:           getEnv("ENCRYPTION_KEY", ""),
-		AdminAPIKey:             getEnv("ADMIN_API_KEY", ""),
+		SlackAIServiceURL:       s.getEnvURL("SLACK_AI_SERVICE_URL", "", "http", "https"),
+		SlackAIToken:            s.getEnv("SLACK_AI_TOKEN", ""),
+		AIServiceURL:            s.getEnv("SLACK_AI_SERVICE_URL", ""),
+		AIBatchURL:              s.getEnvURL("AI_SERVICE_BATCH_URL", "", "http", "https"),
+		AIBatchWindow:           s.getEnvDuration("AI_BATCH_WINDOW_MS", time.Millisecond, 200*time.Millisecond),
+		AIBatchMaxSize:          s.getEnvInt("AI_BATCH_MAX_SIZE", 20),
+		AIFeedbackURL:           s.getEnvURL("AI_SERVICE_FEEDBACK_URL", "", "http", "https"),
+		AIFeedbackBatchSize:     s.getEnvInt("AI_FEEDBACK_BATCH_SIZE", 50),
+		EncryptionKey:           s.getEnv("ENCRYPTION_KEY", ""),
+		AdminAPIKey:             s.getEnv("ADMIN_API_KEY", ""),
+		SandboxWebhookSinkURL:   s.getEnvURL("SANDBOX_WEBHOOK_SINK_URL", "", "http", "https"),
+		AttachmentBaseURL:       s.getEnvURL("ATTACHMENT_BASE_URL", "", "http", "https"),
+		PublicAPIURL:            s.getEnvURL("PUBLIC_API_URL", "", "http", "https"),
+
+		// Webhook dispatch egress
+		DispatchProxyURL:             s.getEnvURL("DISPATCH_PROXY_URL", "", "http", "https", "socks5"),
+		DispatchAllowedHosts:         s.getEnv("DISPATCH_ALLOWED_HOSTS", ""),
+		DispatchDeniedHosts:          s.getEnv("DISPATCH_DENIED_HOSTS", ""),
+		DispatchAllowPrivateNetworks: s.getEnvBool("DISPATCH_ALLOW_PRIVATE_NETWORKS", false),
+		DispatchMaxIdleConns:         s.getEnvInt("DISPATCH_MAX_IDLE_CONNS", 200),
+		DispatchMaxIdleConnsPerHost:  s.getEnvInt("DISPATCH_MAX_IDLE_CONNS_PER_HOST", 32),
+		DispatchMaxConnsPerHost:      s.getEnvInt("DISPATCH_MAX_CONNS_PER_HOST", 0),
+		DispatchIdleConnTimeout:      s.getEnvDuration("DISPATCH_IDLE_CONN_TIMEOUT_SECONDS", time.Second, 90*time.Second),
+		DispatchHTTP2:                s.getEnvBool("DISPATCH_HTTP2", true),
+
+		// Secrets providers
+		SecretsCacheTTL:    s.getEnvDuration("SECRETS_CACHE_TTL_SECONDS", time.Second, 5*time.Minute),
+		SecretsEnvPrefix:   s.getEnv("SECRETS_ENV_PREFIX", "SECRET_"),
+		SecretsPathPrefix:  s.getEnv("SECRETS_PATH_PREFIX", "fraiday/clients"),
+		VaultAddr:          s.getEnvURL("VAULT_ADDR", "", "https", "http"),
+		VaultToken:         s.getEnv("VAULT_TOKEN", ""),
+		VaultMount:         s.getEnv("VAULT_KV_MOUNT", "secret"),
+		AWSRegion:          s.getEnv("AWS_REGION", ""),
+		AWSAccessKeyID:     s.getEnv("AWS_ACCESS_KEY_ID", ""),
+		AWSSecretAccessKey: s.getEnv("AWS_SECRET_ACCESS_KEY", ""),
+		AWSSessionToken:    s.getEnv("AWS_SESSION_TOKEN", ""),
+
+		// OIDC login
+		OIDCIssuer:      s.getEnvURL("OIDC_ISSUER", "", "https", "http"),
+		OIDCAudience:    s.getEnv("OIDC_AUDIENCE", ""),
+		OIDCRolesClaim:  s.getEnv("OIDC_ROLES_CLAIM", "roles"),
+		OIDCRoleMapping: s.getEnv("OIDC_ROLE_MAPPING", ""),
+		OIDCClientClaim: s.getEnv("OIDC_CLIENT_CLAIM", ""),
+
+		// Rate limiting
+		RateLimitTiers:       s.getEnv("RATE_LIMIT_TIERS", "default=120,premium=1200"),
+		RateLimitIPPerMinute: s.getEnvInt("RATE_LIMIT_IP_PER_MINUTE", 300),
+		TrustedProxies:       s.getEnv("TRUSTED_PROXIES", ""),
+
+		// Tracing
+		OTelExporterEndpoint: s.getEnvURL("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", s.getEnvURL("OTEL_EXPORTER_OTLP_ENDPOINT", "", "http", "https"), "http", "https"),
 
 		// AWS Bedrock
-		AWSBedrockAccessKeyID:     getEnv("AWS_BEDROCK_ACCESS_KEY_ID", ""),
-		AWSBedrockSecretAccessKey: getEnv("AWS_BEDROCK_SECRET_ACCESS_KEY", ""),
-		AWSBedrockRegion:          getEnv("AWS_BEDROCK_REGION", ""),
-		AWSBedrockRuntime:         getEnv("AWS_BEDROCK_RUNTIME", "bedrock-runtime"),
+		AWSBedrockAccessKeyID:     s.getEnv("AWS_BEDROCK_ACCESS_KEY_ID", ""),
+		AWSBedrockSecretAccessKey: s.getEnv("AWS_BEDROCK_SECRET_ACCESS_KEY", ""),
+		AWSBedrockRegion:          s.getEnv("AWS_BEDROCK_REGION", ""),
+		AWSBedrockRuntime:         s.getEnv("AWS_BEDROCK_RUNTIME", "bedrock-runtime"),
 
 		// Redis
-		RedisHost:     getEnv("REDIS_HOST", ""),
-		RedisPort:     getEnvInt("REDIS_PORT", 6379),
-		RedisDB:       getEnvInt("REDIS_DB", 0),
-		RedisPassword: getEnv("REDIS_PASSWORD", ""),
+		RedisHost:                 s.getEnv("REDIS_HOST", ""),
+		RedisPort:                 s.getEnvInt("REDIS_PORT", 6379),
+		RedisDB:                   s.getEnvInt("REDIS_DB", 0),
+		RedisPassword:             s.getEnv("REDIS_PASSWORD", ""),
+		SessionContextCacheTTL:    s.getEnvDuration("SESSION_CONTEXT_CACHE_TTL_SECONDS", time.Second, 10*time.Minute),
+		SessionContextMaxMessages: s.getEnvInt("SESSION_CONTEXT_MAX_MESSAGES", 50),
 
 		// Feature flags
-		EnableClientChannelRouting:  getEnvBool("ENABLE_CLIENT_CHANNEL_ROUTING", false),
-		EnableConfigurableWorkflows: getEnvBool("ENABLE_CONFIGURABLE_WORKFLOWS", false),
+		EnableClientChannelRouting:  s.getEnvBool("ENABLE_CLIENT_CHANNEL_ROUTING", false),
+		EnableConfigurableWorkflows: s.getEnvBool("ENABLE_CONFIGURABLE_WORKFLOWS", false),
 	}
 
 	return cfg
@@ -129,46 +441,21 @@ func (c *Config) GetRabbitMQURL() string {
 	return fmt.Sprintf("amqp://%s:%s@%s:%d%s", c.RabbitMQUser, c.RabbitMQPassword, c.RabbitMQHost, c.RabbitMQPort, c.RabbitMQVHost)
 }
 
-func getEnv(key, defaultVal string) string {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultVal
-	}
-	return val
-}
-
-// Helper to get int envs
-func getEnvInt(key string, defaultVal int) int {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultVal
-	}
-	i, err := strconv.Atoi(val)
-	if err != nil {
-		log.Printf("Invalid int for %s: %v, using default %d", key, err, defaultVal)
-		return defaultVal
-	}
-	return i
-}
-
-// Helper to get bool envs
-func getEnvBool(key string, defaultVal bool) bool {
-	val := os.Getenv(key)
-	if val == "" {
-		return defaultVal
-	}
-	b, err := strconv.ParseBool(val)
-	if err != nil {
-		log.Printf("Invalid bool for %s: %v, using default %t", key, err, defaultVal)
-		return defaultVal
+// TrustedProxyList returns the entries of TrustedProxies, or nil when no proxy is trusted.
+func (c *Config) TrustedProxyList() []string {
+	var proxies []string
+	for _, entry := range strings.Split(c.TrustedProxies, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			proxies = append(proxies, entry)
+		}
 	}
-	return b
+	return proxies
 }
 
 // extractDatabaseFromURI extracts the database name from a MongoDB URI
-func extractDatabaseFromURI(mongoURI string) string {
+func (s *source) extractDatabaseFromURI(mongoURI string) string {
 	// First check if there's an explicit MONGODB_DB environment variable
-	if dbName := getEnv("MONGODB_DB", ""); dbName != "" {
+	if dbName := s.getEnv("MONGODB_DB", ""); dbName != "" {
 		return dbName
 	}
 
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// source looks settings up by their environment variable name, first in the environment and then
+// in the config file. Values that don't parse are recorded as problems rather than replaced by
+// defaults, so they can all be reported at once.
+type source struct {
+	file     map[string]string
+	problems []string
+}
+
+// readConfigFile reads a YAML file of settings keyed by environment variable name, as in
+// "MONGODB_URI: mongodb://...". Keys are case-insensitive.
+func readConfigFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		switch value.(type) {
+		case nil:
+			continue
+		case map[string]interface{}, []interface{}:
+			return nil, fmt.Errorf("config file %s: %s must be a single value", path, key)
+		}
+		values[strings.ToUpper(key)] = fmt.Sprint(value)
+	}
+	return values, nil
+}
+
+func (s *source) lookup(key string) (string, bool) {
+	if val := os.Getenv(key); val != "" {
+		return val, true
+	}
+	val := s.file[key]
+	return val, val != ""
+}
+
+func (s *source) addProblem(format string, args ...interface{}) {
+	s.problems = append(s.problems, fmt.Sprintf(format, args...))
+}
+
+func (s *source) getEnv(key, defaultVal string) string {
+	if val, ok := s.lookup(key); ok {
+		return val
+	}
+	return defaultVal
+}
+
+func (s *source) getEnvInt(key string, defaultVal int) int {
+	val, ok := s.lookup(key)
+	if !ok {
+		return defaultVal
+	}
+	i, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		s.addProblem("%s: %q is not an integer", key, val)
+		return defaultVal
+	}
+	return i
+}
+
+func (s *source) getEnvBool(key string, defaultVal bool) bool {
+	val, ok := s.lookup(key)
+	if !ok {
+		return defaultVal
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(val))
+	if err != nil {
+		s.addProblem("%s: %q is not a boolean", key, val)
+		return defaultVal
+	}
+	return b
+}
+
+// getEnvDuration reads a duration written either as a Go duration ("90s", "2m") or, as older
+// settings are, a bare number of unit.
+func (s *source) getEnvDuration(key string, unit, defaultVal time.Duration) time.Duration {
+	val, ok := s.lookup(key)
+	if !ok {
+		return defaultVal
+	}
+	val = strings.TrimSpace(val)
+	if n, err := strconv.Atoi(val); err == nil {
+		return time.Duration(n) * unit
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil {
+		s.addProblem("%s: %q is not a duration", key, val)
+		return defaultVal
+	}
+	return d
+}
+
+// getEnvURL reads an absolute URL with one of schemes. The URL is kept as written, since callers
+// append paths to it.
+func (s *source) getEnvURL(key, defaultVal string, schemes ...string) string {
+	val, ok := s.lookup(key)
+	if !ok {
+		return defaultVal
+	}
+	u, err := url.Parse(val)
+	if err != nil || u.Host == "" {
+		s.addProblem("%s: %q is not an absolute URL", key, redactURL(val))
+		return val
+	}
+	for _, scheme := range schemes {
+		if u.Scheme == scheme {
+			return val
+		}
+	}
+	s.addProblem("%s: scheme must be one of %s, got %q", key, strings.Join(schemes, ", "), u.Scheme)
+	return val
+}
+
+// redactURL hides the password of a URL so it can be shown in error messages.
+func redactURL(raw string) string {
+	if u, err := url.Parse(raw); err == nil {
+		return u.Redacted()
+	}
+	if at := strings.LastIndex(raw, "@"); at >= 0 {
+		return "..." + raw[at:]
+	}
+	return raw
+}
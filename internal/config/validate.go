@@ -0,0 +1,294 @@
+package config
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/cron"
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// ValidationError lists every problem found in a configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks settings that parsed but can't work, such as out of range ports or unknown
+// modes. It returns a *ValidationError listing every problem, or nil.
+func (c *Config) Validate() error {
+	var problems []string
+	add := func(problem string) {
+		problems = append(problems, problem)
+	}
+
+	if !validPort(c.AppPort) {
+		add("APP_PORT: must be a port number between 1 and 65535")
+	}
+	if c.MetricsPort != "" && !validPort(c.MetricsPort) {
+		add("METRICS_PORT: must be a port number between 1 and 65535, or empty to disable worker metrics")
+	}
+	if c.GRPCPort != "" && !validPort(c.GRPCPort) {
+		add("GRPC_PORT: must be a port number between 1 and 65535, or empty to disable the gRPC API")
+	} else if c.GRPCPort != "" && c.GRPCPort == c.AppPort {
+		add("GRPC_PORT: must differ from APP_PORT")
+	}
+	switch c.GinMode {
+	case "debug", "release", "test":
+	default:
+		add("GIN_MODE: must be debug, release or test")
+	}
+	switch strings.ToUpper(c.LogLevel) {
+	case "DEBUG", "INFO", "WARN", "WARNING", "ERROR":
+	default:
+		add("LOG_LEVEL: must be DEBUG, INFO, WARN or ERROR")
+	}
+
+	if c.StartupTimeout <= 0 {
+		add("STARTUP_TIMEOUT_SECONDS: must be positive")
+	}
+	if c.ShutdownTimeout <= 0 {
+		add("SHUTDOWN_TIMEOUT_SECONDS: must be positive")
+	}
+	if c.ReadinessTimeout <= 0 {
+		add("READINESS_TIMEOUT_SECONDS: must be positive")
+	}
+
+	if c.MongoURI == "" {
+		add("MONGODB_URI: is required")
+	}
+	if c.MongoDB == "" {
+		add("MONGODB_DB: no database named in MONGODB_URI or MONGODB_DB")
+	}
+	if c.MongoMaxPoolSize < 0 {
+		add("MONGODB_MAX_POOL_SIZE: must not be negative")
+	}
+	if c.MongoMinPoolSize < 0 {
+		add("MONGODB_MIN_POOL_SIZE: must not be negative")
+	} else if c.MongoMaxPoolSize > 0 && c.MongoMinPoolSize > c.MongoMaxPoolSize {
+		add("MONGODB_MIN_POOL_SIZE: must not exceed MONGODB_MAX_POOL_SIZE")
+	}
+	if c.MongoMaxConnIdleTime < 0 {
+		add("MONGODB_MAX_CONN_IDLE_SECONDS: must not be negative")
+	}
+	if c.MongoConnectTimeout < 0 {
+		add("MONGODB_CONNECT_TIMEOUT_SECONDS: must not be negative")
+	}
+	if c.MongoSocketTimeout < 0 {
+		add("MONGODB_SOCKET_TIMEOUT_SECONDS: must not be negative")
+	}
+	if c.MongoServerSelectionTimeout < 0 {
+		add("MONGODB_SERVER_SELECTION_TIMEOUT_SECONDS: must not be negative")
+	}
+	if c.MongoReadPreference != "" && !validReadPreference(c.MongoReadPreference) {
+		add("MONGODB_READ_PREFERENCE: must be primary, primaryPreferred, secondary, secondaryPreferred or nearest")
+	}
+	if c.MongoAnalyticsReadPreference != "" && !validReadPreference(c.MongoAnalyticsReadPreference) {
+		add("MONGODB_ANALYTICS_READ_PREFERENCE: must be primary, primaryPreferred, secondary, secondaryPreferred or nearest")
+	}
+	switch c.MongoReadConcern {
+	case "", "local", "available", "majority", "linearizable", "snapshot":
+	default:
+		add("MONGODB_READ_CONCERN: must be local, available, majority, linearizable or snapshot")
+	}
+	for _, compressor := range strings.Split(c.MongoCompressors, ",") {
+		switch strings.TrimSpace(compressor) {
+		case "", "snappy", "zlib", "zstd":
+		default:
+			add("MONGODB_COMPRESSORS: " + strconv.Quote(strings.TrimSpace(compressor)) + " is not snappy, zlib or zstd")
+		}
+	}
+	if c.MongoOperationTimeout < 0 {
+		add("MONGODB_OPERATION_TIMEOUT_SECONDS: must not be negative")
+	}
+	if c.MongoRetryAttempts < 0 {
+		add("MONGODB_RETRY_ATTEMPTS: must not be negative")
+	}
+	if c.MongoRetryBackoff < 0 {
+		add("MONGODB_RETRY_BACKOFF_MS: must not be negative")
+	}
+	if c.MongoSlowQueryThreshold < 0 {
+		add("MONGODB_SLOW_QUERY_MS: must not be negative")
+	}
+	if c.MigrationTimeout <= 0 {
+		add("MIGRATION_TIMEOUT_SECONDS: must be positive")
+	}
+	if c.DeliveryAttemptRetention < 0 {
+		add("DELIVERY_ATTEMPT_RETENTION_DAYS: must be 0 (keep forever) or positive")
+	}
+	if c.ChangeStreamGrace < 0 {
+		add("CHANGE_STREAM_GRACE_SECONDS: must not be negative")
+	}
+
+	if c.CeleryBrokerURL == "" && c.RabbitMQURL == "" {
+		if c.RabbitMQHost == "" {
+			add("RABBITMQ_HOST: is required unless CELERY_BROKER_URL or RABBITMQ_URL is set")
+		}
+		if c.RabbitMQPort < 1 || c.RabbitMQPort > 65535 {
+			add("RABBITMQ_PORT: must be between 1 and 65535")
+		}
+	}
+	if c.CeleryDefaultQueue == "" {
+		add("CELERY_DEFAULT_QUEUE: is required")
+	}
+	if c.CeleryEventsQueue == "" {
+		add("CELERY_EVENTS_QUEUE: is required")
+	}
+	if c.CeleryMessageProtocol != 1 && c.CeleryMessageProtocol != 2 {
+		add("CELERY_MESSAGE_PROTOCOL: must be 1 or 2")
+	}
+	if c.CeleryTaskExchange != "" && c.CeleryTaskExchangeType != "direct" && c.CeleryTaskExchangeType != "topic" {
+		add("CELERY_TASK_EXCHANGE_TYPE: must be direct or topic")
+	}
+	if c.TaskPublishConfirmTimeout <= 0 {
+		add("TASK_PUBLISH_CONFIRM_TIMEOUT_SECONDS: must be positive")
+	}
+	if c.TaskPublishRetryBuffer < 0 {
+		add("TASK_PUBLISH_RETRY_BUFFER: must be 0 (disabled) or positive")
+	}
+	if c.TaskPublishMaxRetries < 1 {
+		add("TASK_PUBLISH_MAX_RETRIES: must be positive")
+	}
+	if c.BackpressureShedDepth < 0 {
+		add("BACKPRESSURE_SHED_SUGGESTIONS_DEPTH: must be 0 (disabled) or positive")
+	}
+	if c.BackpressureDegradedDepth < 0 {
+		add("BACKPRESSURE_DEGRADED_DEPTH: must be 0 (disabled) or positive")
+	}
+	if c.BackpressureShedDepth > 0 && c.BackpressureDegradedDepth > 0 && c.BackpressureShedDepth > c.BackpressureDegradedDepth {
+		add("BACKPRESSURE_SHED_SUGGESTIONS_DEPTH: must not exceed BACKPRESSURE_DEGRADED_DEPTH, so suggestions are shed first")
+	}
+	if c.BackpressureCheckInterval <= 0 {
+		add("BACKPRESSURE_CHECK_INTERVAL_SECONDS: must be positive")
+	}
+
+	if c.SessionAutoCloseMinutes < 0 {
+		add("SESSION_AUTO_CLOSE_MINUTES: must be 0 (disabled) or positive")
+	}
+	if c.SessionSweepInterval < 0 {
+		add("SESSION_SWEEP_INTERVAL_SECONDS: must be 0 (disabled) or positive")
+	}
+	if c.UsageReportInterval < 0 {
+		add("USAGE_REPORT_INTERVAL_SECONDS: must be 0 (disabled) or positive")
+	}
+	if c.ReportSchedulerInterval < 0 {
+		add("REPORT_SCHEDULER_INTERVAL_SECONDS: must be 0 (disabled) or positive")
+	}
+	for _, schedule := range []struct{ key, expr string }{
+		{"SCHEDULE_RETRY_DELIVERIES", c.ScheduleRetryDeliveries},
+		{"SCHEDULE_CSAT_EXPIRY", c.ScheduleCSATExpiry},
+		{"SCHEDULE_THREAD_INACTIVITY", c.ScheduleThreadInactivity},
+		{"SCHEDULE_USAGE_ROLLUP", c.ScheduleUsageRollup},
+		{"SCHEDULE_AI_FEEDBACK", c.ScheduleAIFeedback},
+		{"SCHEDULE_SCHEDULED_MESSAGES", c.ScheduleScheduledMessages},
+	} {
+		if schedule.expr == ScheduleOff {
+			continue
+		}
+		if sched, err := cron.Parse(schedule.expr); err != nil || sched.Next(time.Now()).IsZero() {
+			add(schedule.key + ": must be a five-field cron expression that can fall due, or off")
+		}
+	}
+	if c.SchedulerLease < 5*time.Second {
+		add("SCHEDULER_LEASE_SECONDS: must be at least 5 seconds")
+	}
+	switch models.ClosedSessionPolicy(c.ClosedSessionMessagePolicy) {
+	case models.ClosedSessionPolicyReject, models.ClosedSessionPolicyReopen, models.ClosedSessionPolicyNewThread:
+	default:
+		add("CLOSED_SESSION_MESSAGE_POLICY: must be reject, reopen or new_thread")
+	}
+
+	if c.OIDCIssuer != "" && c.OIDCAudience == "" {
+		add("OIDC_AUDIENCE: is required when OIDC_ISSUER is set")
+	}
+
+	for _, entry := range strings.Split(c.RateLimitTiers, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if n, err := strconv.Atoi(strings.TrimSpace(value)); !ok || strings.TrimSpace(name) == "" || err != nil || n < 0 {
+			add("RATE_LIMIT_TIERS: " + strconv.Quote(entry) + " is not a name=requests_per_minute pair")
+		}
+	}
+	if c.ClientCacheTTL < 0 {
+		add("CLIENT_CACHE_TTL_SECONDS: must be 0 (disabled) or positive")
+	}
+	if c.RateLimitIPPerMinute < 0 {
+		add("RATE_LIMIT_IP_PER_MINUTE: must be 0 (disabled) or positive")
+	}
+	for _, proxy := range c.TrustedProxyList() {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			add("TRUSTED_PROXIES: " + strconv.Quote(proxy) + " is not an IP or CIDR")
+		}
+	}
+
+	if c.RedisHost != "" && (c.RedisPort < 1 || c.RedisPort > 65535) {
+		add("REDIS_PORT: must be between 1 and 65535")
+	}
+	if c.AIBatchURL != "" {
+		if c.AIBatchWindow <= 0 {
+			add("AI_BATCH_WINDOW_MS: must be positive")
+		}
+		if c.AIBatchMaxSize < 1 {
+			add("AI_BATCH_MAX_SIZE: must be positive")
+		}
+	}
+	if _, err := egress.NewPolicy(c.DispatchAllowedHosts, c.DispatchDeniedHosts, c.DispatchAllowPrivateNetworks); err != nil {
+		add("DISPATCH_ALLOWED_HOSTS, DISPATCH_DENIED_HOSTS: " + err.Error())
+	}
+	if c.DispatchMaxIdleConns < 0 || c.DispatchMaxIdleConnsPerHost < 0 || c.DispatchMaxConnsPerHost < 0 {
+		add("DISPATCH_MAX_IDLE_CONNS, DISPATCH_MAX_IDLE_CONNS_PER_HOST, DISPATCH_MAX_CONNS_PER_HOST: must not be negative")
+	}
+	if c.DispatchMaxConnsPerHost > 0 && c.DispatchMaxIdleConnsPerHost > c.DispatchMaxConnsPerHost {
+		add("DISPATCH_MAX_IDLE_CONNS_PER_HOST: must not exceed DISPATCH_MAX_CONNS_PER_HOST")
+	}
+	if c.DispatchIdleConnTimeout < 0 {
+		add("DISPATCH_IDLE_CONN_TIMEOUT_SECONDS: must not be negative")
+	}
+	if c.SecretsCacheTTL < 0 {
+		add("SECRETS_CACHE_TTL_SECONDS: must be 0 (disabled) or positive")
+	}
+	if c.SecretsEnvPrefix == "" {
+		add("SECRETS_ENV_PREFIX: must not be empty")
+	}
+	if c.VaultAddr != "" && c.VaultToken == "" {
+		add("VAULT_TOKEN: required when VAULT_ADDR is set")
+	}
+	if c.AWSRegion != "" && (c.AWSAccessKeyID == "" || c.AWSSecretAccessKey == "") {
+		add("AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY: required when AWS_REGION is set")
+	}
+	if c.AIFeedbackURL != "" && c.AIFeedbackBatchSize < 1 {
+		add("AI_FEEDBACK_BATCH_SIZE: must be positive")
+	}
+	if c.SessionContextCacheTTL < 0 {
+		add("SESSION_CONTEXT_CACHE_TTL_SECONDS: must be 0 (disabled) or positive")
+	}
+	if c.SessionContextMaxMessages < 1 {
+		add("SESSION_CONTEXT_MAX_MESSAGES: must be positive")
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+func validReadPreference(mode string) bool {
+	switch mode {
+	case "primary", "primaryPreferred", "secondary", "secondaryPreferred", "nearest":
+		return true
+	}
+	return false
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n >= 1 && n <= 65535
+}
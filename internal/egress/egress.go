@@ -0,0 +1,210 @@
+// Package egress decides which hosts outbound deliveries may reach, so a webhook URL chosen by a
+// client can't be used to call the deployment's own network (SSRF).
+//
+// A Policy checks a destination by name and by every address it resolves to, and its dialer
+// connects only to the addresses it checked, so a name can't be re-pointed at an internal address
+// between the check and the connection.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ErrBlocked is returned for destinations the policy doesn't allow.
+var ErrBlocked = errors.New("destination not allowed")
+
+// privateNetworks are the ranges blocked unless private networks are allowed: RFC 1918, carrier
+// grade NAT, loopback, link-local (including cloud metadata endpoints), unique local and
+// unspecified addresses.
+var privateNetworks = mustParseCIDRs(
+	"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "100.64.0.0/10",
+	"127.0.0.0/8", "169.254.0.0/16", "0.0.0.0/8",
+	"::1/128", "fe80::/10", "fc00::/7", "::/128",
+)
+
+// Policy is an allowlist and denylist of destination hosts.
+type Policy struct {
+	allow        hostList
+	deny         hostList
+	allowPrivate bool
+	resolver     *net.Resolver
+}
+
+// NewPolicy creates a Policy from comma-separated host entries. An entry is a host name, a
+// "*.example.com" pattern matching subdomains, an IP address or a CIDR range. With an allowlist
+// only matching destinations may be reached; denied destinations never may. Private addresses are
+// blocked unless allowPrivate is set or the destination is on the allowlist.
+func NewPolicy(allow, deny string, allowPrivate bool) (*Policy, error) {
+	allowList, err := parseHostList(allow)
+	if err != nil {
+		return nil, fmt.Errorf("allowlist: %w", err)
+	}
+	denyList, err := parseHostList(deny)
+	if err != nil {
+		return nil, fmt.Errorf("denylist: %w", err)
+	}
+	return &Policy{allow: allowList, deny: denyList, allowPrivate: allowPrivate, resolver: net.DefaultResolver}, nil
+}
+
+// Check resolves host and returns its addresses if the policy allows reaching it.
+func (p *Policy) Check(ctx context.Context, host string) ([]net.IP, error) {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := p.resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+
+	if p.deny.matchesName(host) {
+		return nil, fmt.Errorf("%w: %s is denied", ErrBlocked, host)
+	}
+	allowed := p.allow.matchesName(host)
+	for _, ip := range ips {
+		if p.deny.matchesIP(ip) {
+			return nil, fmt.Errorf("%w: %s resolves to denied address %s", ErrBlocked, host, ip)
+		}
+		ipAllowed := allowed || p.allow.matchesIP(ip)
+		if len(p.allow) > 0 && !ipAllowed {
+			return nil, fmt.Errorf("%w: %s is not on the allowlist", ErrBlocked, host)
+		}
+		if !ipAllowed && !p.allowPrivate && isPrivate(ip) {
+			return nil, fmt.Errorf("%w: %s resolves to private address %s", ErrBlocked, host, ip)
+		}
+	}
+	return ips, nil
+}
+
+// DialContext wraps dial so it only connects to checked addresses of the host it is given.
+func (p *Policy) DialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		ips, err := p.Check(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		var dialErr error
+		for _, ip := range ips {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			dialErr = err
+		}
+		return nil, dialErr
+	}
+}
+
+// Transport wraps base so every request, redirects included, is checked against the policy before
+// it is sent. This covers requests sent through a proxy, whose connections go to the proxy.
+func (p *Policy) Transport(base http.RoundTripper) http.RoundTripper {
+	return &transport{policy: p, base: base}
+}
+
+type transport struct {
+	policy *Policy
+	base   http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if _, err := t.policy.Check(req.Context(), req.URL.Hostname()); err != nil {
+		return nil, err
+	}
+	return t.base.RoundTrip(req)
+}
+
+func isPrivate(ip net.IP) bool {
+	if ip.IsMulticast() || ip.IsUnspecified() {
+		return true
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostList is a parsed list of host entries.
+type hostList []hostEntry
+
+type hostEntry struct {
+	name    string // Exact host name, or the suffix of a "*." pattern
+	pattern bool
+	network *net.IPNet
+}
+
+func parseHostList(s string) (hostList, error) {
+	var list hostList
+	for _, item := range strings.Split(s, ",") {
+		item = strings.ToLower(strings.TrimSpace(item))
+		switch {
+		case item == "":
+			continue
+		case strings.Contains(item, "/"):
+			_, network, err := net.ParseCIDR(item)
+			if err != nil {
+				return nil, fmt.Errorf("%q is not a valid CIDR range", item)
+			}
+			list = append(list, hostEntry{network: network})
+		case net.ParseIP(item) != nil:
+			ip := net.ParseIP(item)
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			list = append(list, hostEntry{network: &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}})
+		case strings.HasPrefix(item, "*."):
+			list = append(list, hostEntry{name: item[1:], pattern: true})
+		case strings.ContainsAny(item, "*:@ "):
+			return nil, fmt.Errorf("%q is not a host name, IP address or CIDR range", item)
+		default:
+			list = append(list, hostEntry{name: item})
+		}
+	}
+	return list, nil
+}
+
+func (l hostList) matchesName(host string) bool {
+	for _, entry := range l {
+		if entry.network == nil && (host == entry.name || entry.pattern && strings.HasSuffix(host, entry.name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l hostList) matchesIP(ip net.IP) bool {
+	for _, entry := range l {
+		if entry.network != nil && entry.network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
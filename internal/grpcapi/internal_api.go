@@ -0,0 +1,315 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin/binding"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+)
+
+// subscriptionWait is how long one poll of a subscription waits for the session to change
+const subscriptionWait = 30 * time.Second
+
+// CreateMessages implements InternalAPI.CreateMessages.
+func (s *Server) CreateMessages(stream grpc.BidiStreamingServer[structpb.Struct, structpb.Struct]) error {
+	ctx := stream.Context()
+	for index := 0; ; index++ {
+		in, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result := map[string]interface{}{"index": index}
+		msg, created, err := s.createMessage(ctx, in)
+		if err != nil {
+			st := status.Convert(err)
+			result["code"] = st.Code().String()
+			result["error"] = st.Message()
+		} else {
+			result["created"] = created
+			result["message"] = msg
+		}
+		out, err := toStruct(result)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+	}
+}
+
+// createMessage runs the create flow of POST /messages for one request. A message whose
+// external_id was already stored on the channel is returned with created false.
+func (s *Server) createMessage(ctx context.Context, in *structpb.Struct) (*models.ChatMessage, bool, error) {
+	var req dto.ChatMessageCreate
+	if err := fromStruct(in, &req, false); err != nil {
+		return nil, false, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := binding.Validator.ValidateStruct(&req); err != nil {
+		return nil, false, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := service.ValidateSenderType(req.SenderType); err != nil {
+		return nil, false, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := models.ValidateAttachments(req.Attachments); err != nil {
+		return nil, false, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if !allowsClient(ctx, req.ClientID) {
+		return nil, false, status.Error(codes.PermissionDenied, "not allowed for this client")
+	}
+
+	var parentMessageID *primitive.ObjectID
+	if req.ParentMessageID != "" {
+		parentMessageID = service.ParseObjectID(req.ParentMessageID)
+		if parentMessageID == nil {
+			return nil, false, status.Error(codes.InvalidArgument, "invalid parent_message_id")
+		}
+	}
+
+	client, err := s.getClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, false, status.Error(codes.NotFound, "client not found")
+	}
+	if !client.IsActive {
+		return nil, false, status.Error(codes.FailedPrecondition, "client is not active")
+	}
+	if client.StrictPayloads {
+		if err := fromStruct(in, &dto.ChatMessageCreate{}, true); err != nil {
+			return nil, false, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if unknown := req.UnknownFields(); len(unknown) > 0 {
+			return nil, false, status.Errorf(codes.InvalidArgument, "unknown field %q", unknown[0])
+		}
+	}
+
+	clientChannel, err := s.getChannelByType(ctx, client, req.ClientChannelType)
+	if err != nil {
+		return nil, false, status.Error(codes.NotFound, "client channel not found")
+	}
+	if !clientChannel.IsActive {
+		return nil, false, status.Error(codes.FailedPrecondition, "client channel is not active")
+	}
+
+	// Redeliveries are answered before they can touch the session
+	var dedupKey string
+	if req.ExternalID != "" {
+		dedupKey = models.MessageDedupKey(client.ID, clientChannel.ID, req.ExternalID)
+		existing, err := s.Messages.FindDuplicate(ctx, dedupKey)
+		if err != nil {
+			return nil, false, status.Error(codes.Internal, err.Error())
+		}
+		if existing != nil {
+			telemetry.ObserveMessageDedup(string(clientChannel.ChannelType))
+			return existing, false, nil
+		}
+	}
+
+	session, effectiveSessionID, err := s.Sessions.GetOrCreateSessionBySessionID(ctx, req.SessionID, client, clientChannel)
+	if err != nil {
+		return nil, false, status.Error(codes.Internal, "failed to get or create session")
+	}
+	if s.LifecycleService != nil {
+		session, err = s.LifecycleService.ResolveForMessage(ctx, session, client, clientChannel)
+		if err != nil {
+			if errors.Is(err, service.ErrSessionClosed) {
+				return nil, false, status.Error(codes.FailedPrecondition, err.Error())
+			}
+			return nil, false, status.Error(codes.Internal, "failed to resolve closed session")
+		}
+		effectiveSessionID = session.SessionID
+	}
+
+	msg := &models.ChatMessage{
+		ExternalID:      req.ExternalID,
+		DedupKey:        dedupKey,
+		Sender:          req.Sender,
+		SenderName:      req.SenderName,
+		SenderType:      req.SenderType,
+		SessionID:       session.ID,
+		ParentMessageID: parentMessageID,
+		Text:            req.Text,
+		Attachments:     req.Attachments,
+		Data:            req.Data,
+		Category:        models.MessageCategory(req.Category),
+		Config:          req.Config,
+	}
+	if err := s.Messages.CreateChatMessage(ctx, msg); err != nil {
+		switch {
+		case errors.Is(err, service.ErrMessageDuplicate):
+			telemetry.ObserveMessageDedup(string(clientChannel.ChannelType))
+			return msg, false, nil
+		case errors.Is(err, service.ErrParentMessageNotFound), errors.Is(err, service.ErrParentMessageSession):
+			return nil, false, status.Error(codes.InvalidArgument, err.Error())
+		case errors.Is(err, service.ErrQuotaExceeded):
+			return nil, false, status.Error(codes.ResourceExhausted, err.Error())
+		}
+		return nil, false, status.Error(codes.Internal, err.Error())
+	}
+
+	aiEnabled, aiOk := msg.Config["ai_enabled"].(bool)
+	suggestionMode, suggestionOk := msg.Config["suggestion_mode"].(bool)
+	if aiOk && aiEnabled && (!suggestionOk || !suggestionMode) {
+		service.TriggerChatWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	} else if suggestionOk && suggestionMode && (!aiOk || !aiEnabled) {
+		service.TriggerSuggestionWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	}
+	return msg, true, nil
+}
+
+// GetSession implements InternalAPI.GetSession.
+func (s *Server) GetSession(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error) {
+	session, err := s.lookupSession(ctx, req.GetFields()["session_id"].GetStringValue())
+	if err != nil {
+		return nil, err
+	}
+	return toStruct(session)
+}
+
+// SubscribeEvents implements InternalAPI.SubscribeEvents.
+func (s *Server) SubscribeEvents(req *structpb.Struct, stream grpc.ServerStreamingServer[structpb.Struct]) error {
+	ctx := stream.Context()
+	session, err := s.lookupSession(ctx, req.GetFields()["session_id"].GetStringValue())
+	if err != nil {
+		return err
+	}
+
+	cursor := req.GetFields()["after"].GetStringValue()
+	for {
+		resp, err := s.Poll.Poll(ctx, session.ID.Hex(), cursor, subscriptionWait)
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidPollCursor) {
+				return status.Error(codes.InvalidArgument, err.Error())
+			}
+			return status.Error(codes.Internal, err.Error())
+		}
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		if err := sendPollResponse(stream, resp); err != nil {
+			return err
+		}
+		if resp.Cursor != "" {
+			cursor = resp.Cursor
+		}
+	}
+}
+
+// sendPollResponse streams the messages and events of resp, oldest first, each with the cursor
+// to resume after it.
+func sendPollResponse(stream grpc.ServerStreamingServer[structpb.Struct], resp *dto.MessagePollResponse) error {
+	type item struct {
+		id    primitive.ObjectID
+		value map[string]interface{}
+	}
+	items := make([]item, 0, len(resp.Messages)+len(resp.Events))
+	for i := range resp.Messages {
+		items = append(items, item{resp.Messages[i].ID, map[string]interface{}{"type": "message", "message": &resp.Messages[i]}})
+	}
+	for i := range resp.Events {
+		items = append(items, item{resp.Events[i].ID, map[string]interface{}{"type": "event", "event": &resp.Events[i]}})
+	}
+	// Both lists are in id order; merge them
+	for i := 1; i < len(items); i++ {
+		for j := i; j > 0 && items[j].id.Hex() < items[j-1].id.Hex(); j-- {
+			items[j], items[j-1] = items[j-1], items[j]
+		}
+	}
+
+	for _, it := range items {
+		it.value["cursor"] = it.id.Hex()
+		out, err := toStruct(it.value)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := stream.Send(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lookupSession finds the session named by ref and checks the caller may see it.
+func (s *Server) lookupSession(ctx context.Context, ref string) (*models.ChatSession, error) {
+	if ref == "" {
+		return nil, status.Error(codes.InvalidArgument, "session_id is required")
+	}
+	session, err := s.Sessions.LookupSession(ctx, ref)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "chat session not found")
+	}
+	clientRef := ""
+	if session.Client != nil {
+		clientRef = session.Client.Hex()
+	}
+	if !allowsClient(ctx, clientRef) {
+		// Sessions of other clients don't exist as far as the caller is concerned
+		return nil, status.Error(codes.NotFound, "chat session not found")
+	}
+	return session, nil
+}
+
+// getClient looks the client up through the cache when there is one.
+func (s *Server) getClient(ctx context.Context, clientID string) (*models.Client, error) {
+	if s.ClientCache != nil {
+		return s.ClientCache.GetClient(ctx, clientID)
+	}
+	return s.Clients.GetClient(ctx, clientID)
+}
+
+// getChannelByType looks the channel of client up through the cache when there is one.
+func (s *Server) getChannelByType(ctx context.Context, client *models.Client, channelType string) (*models.ClientChannel, error) {
+	if s.ClientCache != nil {
+		return s.ClientCache.GetChannelByType(ctx, client.ID, channelType)
+	}
+	return s.ClientChannels.GetChannelByType(ctx, client.ClientID, channelType)
+}
+
+// fromStruct decodes in into v through its JSON form, so v's json tags apply. With strict set,
+// fields v doesn't declare are rejected.
+func fromStruct(in *structpb.Struct, v interface{}, strict bool) error {
+	raw, err := in.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+// toStruct encodes v as a Struct through its JSON form.
+func toStruct(v interface{}) (*structpb.Struct, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	out := &structpb.Struct{}
+	if err := out.UnmarshalJSON(raw); err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,240 @@
+// Package grpcapi serves the internal gRPC API on a port of its own, over the services the REST
+// API uses. The RPCs are described in internal_api.proto.
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/fraiday-org/api-service/internal/api/middleware"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+const serviceName = "fraiday.internal.v1.InternalAPI"
+
+// methodPermissions declares the permission each RPC requires. RPCs left out need
+// PermissionSystem, as routes missing from the REST permissions do.
+var methodPermissions = map[string]models.Permission{
+	"/" + serviceName + "/CreateMessages":  models.PermissionMessagesWrite,
+	"/" + serviceName + "/GetSession":      models.PermissionSessionsRead,
+	"/" + serviceName + "/SubscribeEvents": models.PermissionMessagesRead,
+}
+
+// Server implements the internal gRPC API.
+type Server struct {
+	Messages       *service.ChatMessageService
+	Sessions       *service.ChatSessionService
+	Clients        *service.ClientService
+	ClientChannels *service.ClientChannelService
+	Poll           *service.MessagePollService
+	APIKeys        *service.APIKeyService
+	// AdminAPIKey, when set, is accepted with admin rights next to client API keys
+	AdminAPIKey string
+	// LifecycleService, when set, applies the closed-session policy to incoming messages
+	LifecycleService *service.SessionLifecycleService
+	// ClientCache, when set, serves the client and channel lookups of every message from memory
+	ClientCache service.ClientCache
+
+	logger *zap.Logger
+	grpc   *grpc.Server
+}
+
+type principalKey struct{}
+
+// NewServer creates a Server. Its RPCs are served once Serve is called.
+func NewServer(logger *zap.Logger, messages *service.ChatMessageService, sessions *service.ChatSessionService, clients *service.ClientService, clientChannels *service.ClientChannelService, poll *service.MessagePollService, apiKeys *service.APIKeyService) *Server {
+	s := &Server{
+		Messages:       messages,
+		Sessions:       sessions,
+		Clients:        clients,
+		ClientChannels: clientChannels,
+		Poll:           poll,
+		APIKeys:        apiKeys,
+		logger:         logger,
+	}
+	s.grpc = grpc.NewServer(
+		grpc.ChainUnaryInterceptor(s.unaryInterceptor),
+		grpc.ChainStreamInterceptor(s.streamInterceptor),
+	)
+	s.grpc.RegisterService(&internalAPIServiceDesc, s)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(s.grpc, healthServer)
+	return s
+}
+
+// Serve accepts connections on ln until Stop is called.
+func (s *Server) Serve(ln net.Listener) error {
+	return s.grpc.Serve(ln)
+}
+
+// Stop lets running RPCs finish until ctx is done, then cancels them. Subscriptions only end when
+// cancelled, so they are cut at the deadline.
+func (s *Server) Stop(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.grpc.Stop()
+	}
+	return nil
+}
+
+func (s *Server) unaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic in gRPC handler", zap.String("method", info.FullMethod), zap.Any("panic", r), zap.String("stack", string(debug.Stack())))
+			err = status.Error(codes.Internal, "internal error")
+		}
+		s.logCall(info.FullMethod, start, err)
+	}()
+
+	ctx, err = s.authorize(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	start := time.Now()
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.Error("Panic in gRPC handler", zap.String("method", info.FullMethod), zap.Any("panic", r), zap.String("stack", string(debug.Stack())))
+			err = status.Error(codes.Internal, "internal error")
+		}
+		s.logCall(info.FullMethod, start, err)
+	}()
+
+	ctx, err := s.authorize(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authorizedStream{ServerStream: stream, ctx: ctx})
+}
+
+func (s *Server) logCall(method string, start time.Time, err error) {
+	s.logger.Info("gRPC call",
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)),
+	)
+}
+
+// authorize identifies the caller from the bearer token in the authorization metadata and checks
+// it holds the method's permission. Health checks need no credentials.
+func (s *Server) authorize(ctx context.Context, method string) (context.Context, error) {
+	if strings.HasPrefix(method, "/grpc.health.v1.Health/") {
+		return ctx, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return nil, status.Error(codes.Unauthenticated, "missing bearer token")
+	}
+	token := strings.TrimPrefix(values[0], "Bearer ")
+
+	var principal *middleware.Principal
+	if s.AdminAPIKey != "" && token == s.AdminAPIKey {
+		principal = &middleware.Principal{Roles: []models.Role{models.RoleAdmin}}
+	} else if s.APIKeys != nil {
+		if key, err := s.APIKeys.AuthenticateAPIKey(ctx, token); err == nil {
+			principal = middleware.APIKeyPrincipal(key)
+		}
+	}
+	if principal == nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+
+	permission, declared := methodPermissions[method]
+	if !declared {
+		permission = models.PermissionSystem
+	}
+	if !principal.Can(permission) {
+		return nil, status.Error(codes.PermissionDenied, "missing permission "+string(permission))
+	}
+	return context.WithValue(ctx, principalKey{}, principal), nil
+}
+
+// allowsClient reports whether the caller of ctx may address the client ref.
+func allowsClient(ctx context.Context, ref string) bool {
+	principal, ok := ctx.Value(principalKey{}).(*middleware.Principal)
+	return ok && principal.AllowsClient(ref)
+}
+
+// authorizedStream carries the context authorize returned to the stream's handler.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+// internalAPIServer is what internalAPIServiceDesc dispatches to, as protoc-gen-go-grpc would
+// generate it for internal_api.proto.
+type internalAPIServer interface {
+	CreateMessages(stream grpc.BidiStreamingServer[structpb.Struct, structpb.Struct]) error
+	GetSession(ctx context.Context, req *structpb.Struct) (*structpb.Struct, error)
+	SubscribeEvents(req *structpb.Struct, stream grpc.ServerStreamingServer[structpb.Struct]) error
+}
+
+var internalAPIServiceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*internalAPIServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "GetSession", Handler: getSessionHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "CreateMessages", Handler: createMessagesHandler, ServerStreams: true, ClientStreams: true},
+		{StreamName: "SubscribeEvents", Handler: subscribeEventsHandler, ServerStreams: true},
+	},
+	Metadata: "internal_api.proto",
+}
+
+func getSessionHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(structpb.Struct)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(internalAPIServer).GetSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/GetSession"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(internalAPIServer).GetSession(ctx, req.(*structpb.Struct))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func createMessagesHandler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(internalAPIServer).CreateMessages(&grpc.GenericServerStream[structpb.Struct, structpb.Struct]{ServerStream: stream})
+}
+
+func subscribeEventsHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(structpb.Struct)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(internalAPIServer).SubscribeEvents(in, &grpc.GenericServerStream[structpb.Struct, structpb.Struct]{ServerStream: stream})
+}
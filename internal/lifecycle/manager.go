@@ -0,0 +1,158 @@
+// Package lifecycle orders the startup and shutdown of long-lived dependencies.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Hook is a named dependency with optional start and stop functions.
+// OnStart must not block; long-running work belongs in a goroutine that reports
+// unexpected exits through Manager.Fail.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Manager starts hooks in registration order and stops them in reverse order,
+// each within a bounded timeout.
+type Manager struct {
+	logger       *zap.Logger
+	startTimeout time.Duration
+	stopTimeout  time.Duration
+
+	mu      sync.Mutex
+	hooks   []Hook
+	started int
+	failed  chan error
+}
+
+// NewManager creates a new lifecycle Manager.
+func NewManager(logger *zap.Logger, startTimeout, stopTimeout time.Duration) *Manager {
+	return &Manager{
+		logger:       logger,
+		startTimeout: startTimeout,
+		stopTimeout:  stopTimeout,
+		failed:       make(chan error, 1),
+	}
+}
+
+// Append registers a hook. Hooks appended later are started later and stopped earlier.
+func (m *Manager) Append(hook Hook) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hooks = append(m.hooks, hook)
+}
+
+// Fail reports that a running component exited unexpectedly and triggers shutdown.
+// Only the first failure is kept.
+func (m *Manager) Fail(err error) {
+	select {
+	case m.failed <- err:
+	default:
+	}
+}
+
+// Start runs each OnStart in order. If one fails, the hooks already started are stopped.
+func (m *Manager) Start(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := append([]Hook(nil), m.hooks...)
+	m.mu.Unlock()
+
+	for i, hook := range hooks {
+		if hook.OnStart != nil {
+			m.logger.Info("Starting component", zap.String("component", hook.Name))
+			if err := m.call(ctx, m.startTimeout, hook.OnStart); err != nil {
+				err = fmt.Errorf("failed to start %s: %w", hook.Name, err)
+				m.logger.Error("Startup failed, rolling back", zap.String("component", hook.Name), zap.Error(err))
+				if stopErr := m.Stop(context.Background()); stopErr != nil {
+					return errors.Join(err, stopErr)
+				}
+				return err
+			}
+		}
+		m.mu.Lock()
+		m.started = i + 1
+		m.mu.Unlock()
+	}
+	return nil
+}
+
+// Stop runs OnStop for every started hook in reverse order. A hook that exceeds the
+// stop timeout is abandoned so the remaining hooks still get to run.
+func (m *Manager) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	hooks := append([]Hook(nil), m.hooks[:m.started]...)
+	m.started = 0
+	m.mu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hook := hooks[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		m.logger.Info("Stopping component", zap.String("component", hook.Name))
+		if err := m.call(ctx, m.stopTimeout, hook.OnStop); err != nil {
+			m.logger.Error("Failed to stop component", zap.String("component", hook.Name), zap.Error(err))
+			errs = append(errs, fmt.Errorf("failed to stop %s: %w", hook.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Run starts all hooks, blocks until SIGINT/SIGTERM, ctx cancellation or a Fail call,
+// then stops them. It returns the failure that triggered shutdown, if any.
+func (m *Manager) Run(ctx context.Context) error {
+	if err := m.Start(ctx); err != nil {
+		return err
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	var runErr error
+	select {
+	case sig := <-signals:
+		m.logger.Info("Shutdown signal received", zap.String("signal", sig.String()))
+	case <-ctx.Done():
+		m.logger.Info("Context cancelled, shutting down")
+	case runErr = <-m.failed:
+		m.logger.Error("Component failed, shutting down", zap.Error(runErr))
+	}
+
+	if err := m.Stop(context.Background()); err != nil {
+		return errors.Join(runErr, err)
+	}
+	return runErr
+}
+
+// call runs fn with a deadline and returns once fn finishes or the deadline passes,
+// whichever comes first, so a hook that ignores its context cannot hang the process.
+func (m *Manager) call(ctx context.Context, timeout time.Duration, fn func(context.Context) error) error {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,79 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+func recordingHook(name string, calls *[]string, startErr error) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			*calls = append(*calls, "start:"+name)
+			return startErr
+		},
+		OnStop: func(ctx context.Context) error {
+			*calls = append(*calls, "stop:"+name)
+			return nil
+		},
+	}
+}
+
+// TestManagerOrdering tests that hooks start in order and stop in reverse order
+func TestManagerOrdering(t *testing.T) {
+	var calls []string
+	m := NewManager(zap.NewNop(), time.Second, time.Second)
+	m.Append(recordingHook("a", &calls, nil))
+	m.Append(recordingHook("b", &calls, nil))
+
+	assert.NoError(t, m.Start(context.Background()))
+	assert.NoError(t, m.Stop(context.Background()))
+	assert.Equal(t, []string{"start:a", "start:b", "stop:b", "stop:a"}, calls)
+}
+
+// TestManagerStartRollback tests that a failed start stops only the hooks already started
+func TestManagerStartRollback(t *testing.T) {
+	var calls []string
+	m := NewManager(zap.NewNop(), time.Second, time.Second)
+	m.Append(recordingHook("a", &calls, nil))
+	m.Append(recordingHook("b", &calls, errors.New("boom")))
+	m.Append(recordingHook("c", &calls, nil))
+
+	assert.Error(t, m.Start(context.Background()))
+	assert.Equal(t, []string{"start:a", "start:b", "stop:a"}, calls)
+}
+
+// TestManagerStopTimeout tests that a hung stop hook is abandoned and later hooks still run
+func TestManagerStopTimeout(t *testing.T) {
+	stopped := false
+	m := NewManager(zap.NewNop(), time.Second, 20*time.Millisecond)
+	m.Append(Hook{Name: "last", OnStop: func(ctx context.Context) error {
+		stopped = true
+		return nil
+	}})
+	m.Append(Hook{Name: "hung", OnStop: func(ctx context.Context) error {
+		select {}
+	}})
+
+	assert.NoError(t, m.Start(context.Background()))
+	err := m.Stop(context.Background())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.True(t, stopped)
+}
+
+// TestManagerRunFail tests that Fail triggers shutdown and is returned from Run
+func TestManagerRunFail(t *testing.T) {
+	var calls []string
+	m := NewManager(zap.NewNop(), time.Second, time.Second)
+	m.Append(recordingHook("a", &calls, nil))
+
+	failure := errors.New("consumer died")
+	m.Fail(failure)
+	assert.ErrorIs(t, m.Run(context.Background()), failure)
+	assert.Equal(t, []string{"start:a", "stop:a"}, calls)
+}
@@ -0,0 +1,184 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// MongoDB error codes for an index that exists with other options
+const (
+	codeIndexOptionsConflict  = 85
+	codeIndexKeySpecsConflict = 86
+)
+
+// Index is an index the service needs on a collection.
+type Index struct {
+	Collection string
+	Model      mongo.IndexModel
+}
+
+// RequiredIndexes lists the indexes behind the service's queries. Delivery attempts expire after
+// deliveryAttemptRetention, unless it is 0.
+func RequiredIndexes(deliveryAttemptRetention time.Duration) []Index {
+	indexes := []Index{
+		// Keys are looked up by hash on every request; listing is per client
+		{models.APIKey{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "key_hash", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		{models.APIKey{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "created_at", Value: -1}}}},
+		// Buckets are dropped once they have refilled
+		{models.RateLimitBucket{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)}},
+		{models.RoleAssignment{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "subject", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		{models.FeatureFlag{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		// Usage counters are upserted per client and month, and reported and exported by month
+		{models.ClientUsage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "period", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		{models.ClientUsage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "period", Value: 1}, {Key: "client", Value: 1}}}},
+		// A client has one template per name and channel type, the client-wide one without a type
+		{models.PromptTemplate{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client_id", Value: 1}, {Key: "name", Value: 1}, {Key: "channel_type", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		// Workers look up a client's running experiment for every message; only one may run at a time
+		{models.Experiment{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client_id", Value: 1}, {Key: "status", Value: 1}}}},
+		{models.Experiment{}.TableName(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "client_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": models.ExperimentStatusRunning}),
+		}},
+		// Cached answers are looked up by key for every message and dropped once they expire
+		{models.AnswerCacheEntry{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client_id", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		{models.AnswerCacheEntry{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client_id", Value: 1}, {Key: "question", Value: 1}}}},
+		{models.AnswerCacheEntry{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)}},
+		// Feedback waiting to be forwarded to the AI service is swept by when it is due
+		{models.ChatMessageFeedback{}.TableName(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "next_forward_at", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"forward_status": models.FeedbackForwardPending}),
+		}},
+		// Experiment metrics group the replies and CSAT responses tagged with a variant
+		{models.ChatMessage{}.TableName(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "config.experiment.experiment_id", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"config.experiment.experiment_id": bson.M{"$type": "string"}}),
+		}},
+		{models.CSATResponse{}.TableName(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "experiment.experiment_id", Value: 1}},
+			Options: options.Index().SetPartialFilterExpression(bson.M{"experiment.experiment_id": bson.M{"$type": "string"}}),
+		}},
+
+		// Sessions are looked up by session_id, filtered by tag and custom attribute, and swept
+		// for snoozes that ended
+		{"chat_sessions", mongo.IndexModel{Keys: bson.D{{Key: "session_id", Value: 1}}}},
+		{"chat_sessions", mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "tags", Value: 1}, {Key: "updated_at", Value: -1}}}},
+		{"chat_sessions", mongo.IndexModel{Keys: bson.D{{Key: "attributes.$**", Value: 1}}}},
+		{"chat_sessions", mongo.IndexModel{Keys: bson.D{{Key: "state", Value: 1}, {Key: "snoozed_until", Value: 1}}}},
+		// Performance analytics select sessions by client and creation time
+		{"chat_sessions", mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "created_at", Value: -1}}}},
+		{"chat_sessions", mongo.IndexModel{Keys: bson.D{{Key: "created_at", Value: -1}}}},
+		{"chat_session_threads", mongo.IndexModel{Keys: bson.D{{Key: "parent_session_id", Value: 1}, {Key: "last_activity", Value: -1}}}},
+		{"chat_session_threads", mongo.IndexModel{Keys: bson.D{{Key: "thread_session_id", Value: 1}}}},
+		{"chat_session_threads", mongo.IndexModel{Keys: bson.D{{Key: "chat_session_id", Value: 1}}}},
+
+		// Contacts are found by channel identity on every user message and listed per client; sessions
+		// and messages are listed and relinked per contact. Contacts without identities are left out
+		// of the unique index.
+		{models.Contact{}.TableName(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "client", Value: 1}, {Key: "identity_keys", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"identity_keys": bson.M{"$type": "string"}}),
+		}},
+		{models.Contact{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "last_seen_at", Value: -1}}}},
+		{"chat_sessions", mongo.IndexModel{Keys: bson.D{{Key: "contact", Value: 1}, {Key: "created_at", Value: -1}}, Options: options.Index().SetSparse(true)}},
+		{models.ChatMessage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "contact", Value: 1}}, Options: options.Index().SetSparse(true)}},
+		// The latest recap of each of a returning contact's earlier sessions goes into the AI context
+		{"chat_session_recaps", mongo.IndexModel{Keys: bson.D{{Key: "session_id", Value: 1}, {Key: "created_at", Value: -1}}}},
+		// Simulations are listed per client, newest first
+		{models.Simulation{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "created_at", Value: -1}}}},
+		// Message imports are listed per client, newest first
+		{models.MessageImport{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "created_at", Value: -1}}}},
+
+		// Messages are listed per session, newest first, and replies per parent
+		{models.ChatMessage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "session", Value: 1}, {Key: "created_at", Value: -1}}}},
+		{models.ChatMessage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "parent_message", Value: 1}}}},
+		{models.ChatMessage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "text", Value: "text"}}}},
+		// AI response latency is measured over the assistant's messages in a time range
+		{models.ChatMessage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "sender_type", Value: 1}, {Key: "created_at", Value: -1}}}},
+		{models.ChatMessage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "created_at", Value: -1}}}},
+		// A channel's external_id stores one message; redeliveries get the stored one back
+		{models.ChatMessage{}.TableName(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "dedup_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"dedup_key": bson.M{"$type": "string"}}),
+		}},
+		{models.ScheduledMessage{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "send_at", Value: 1}}}},
+		// Each message has one chat workflow state, dropped a while after its last step
+		{models.ChatWorkflowState{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "message_id", Value: 1}, {Key: "suggestion_mode", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		{models.ChatWorkflowState{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)}},
+
+		// The change stream listener checks whether an entity's event was already published
+		{models.Event{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "entity_id", Value: 1}, {Key: "event_type", Value: 1}}}},
+		{models.Event{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "event_type", Value: 1}, {Key: "created_at", Value: -1}}}},
+		// Client event feeds tail a client's events in id order
+		{models.Event{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "_id", Value: 1}}}},
+
+		// Deliveries are listed per event and processor, and retried by status
+		{models.EventDelivery{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "event", Value: 1}}}},
+		{models.EventDelivery{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "event_processor_config", Value: 1}, {Key: "created_at", Value: -1}}}},
+		{models.EventDelivery{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "next_retry_at", Value: 1}}}},
+		{models.EventDeliveryAttempt{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "event_delivery", Value: 1}, {Key: "created_at", Value: -1}}}},
+		{models.CSATSession{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "session_id", Value: 1}}}},
+		{models.CSATSession{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client", Value: 1}, {Key: "triggered_at", Value: -1}}}},
+		{models.CSATResponse{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "csat_session", Value: 1}}}},
+		// Schedulers look for enabled schedules that are due
+		{models.ReportSchedule{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "enabled", Value: 1}, {Key: "next_run_at", Value: 1}}}},
+		{models.ReportSchedule{}.TableName(), mongo.IndexModel{Keys: bson.D{{Key: "client_id", Value: 1}, {Key: "name", Value: 1}}}},
+	}
+	if deliveryAttemptRetention > 0 {
+		indexes = append(indexes, Index{models.EventDeliveryAttempt{}.TableName(), mongo.IndexModel{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(deliveryAttemptRetention / time.Second)),
+		}})
+	}
+	return indexes
+}
+
+// ensureIndexes creates missing indexes. Creating an index that exists is a no-op; when a TTL
+// index exists with another expiry, the expiry is changed in place. An index that can't be
+// created doesn't stop the others.
+func ensureIndexes(ctx context.Context, db *mongo.Database, indexes []Index, logger *zap.Logger) error {
+	var errs []error
+	for _, index := range indexes {
+		if err := ensureIndex(ctx, db, index, logger); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func ensureIndex(ctx context.Context, db *mongo.Database, index Index, logger *zap.Logger) error {
+	_, err := db.Collection(index.Collection).Indexes().CreateOne(ctx, index.Model)
+	if err == nil {
+		return nil
+	}
+	if !isIndexConflict(err) || index.Model.Options == nil || index.Model.Options.ExpireAfterSeconds == nil {
+		return fmt.Errorf("failed to create index %v on %s: %w", index.Model.Keys, index.Collection, err)
+	}
+
+	expireAfter := *index.Model.Options.ExpireAfterSeconds
+	err = db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: index.Collection},
+		{Key: "index", Value: bson.D{{Key: "keyPattern", Value: index.Model.Keys}, {Key: "expireAfterSeconds", Value: expireAfter}}},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to change expiry of index %v on %s: %w", index.Model.Keys, index.Collection, err)
+	}
+	logger.Info("Changed index expiry", zap.String("collection", index.Collection), zap.Int32("expire_after_seconds", expireAfter))
+	return nil
+}
+
+func isIndexConflict(err error) bool {
+	var cmdErr mongo.CommandError
+	if !errors.As(err, &cmdErr) {
+		return false
+	}
+	return cmdErr.Code == codeIndexOptionsConflict || cmdErr.Code == codeIndexKeySpecsConflict
+}
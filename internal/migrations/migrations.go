@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// All lists the service's migrations in the order they run. Append new migrations with the next
+// version; never renumber or remove one that has shipped.
+var All = []Migration{
+	{
+		Version:     1,
+		Description: "copy thread_config out of client config",
+		Up:          copyClientThreadConfig,
+	},
+	{
+		Version:     2,
+		Description: "set the state of sessions created before lifecycle states",
+		Up:          backfillSessionState,
+	},
+	{
+		Version:     3,
+		Description: "store thread_config with typed enabled and inactivity_minutes",
+		Up:          normalizeThreadConfig,
+	},
+}
+
+// copyClientThreadConfig gives clients that only have config.thread_config a root thread_config,
+// which is where ThreadManagerService reads it. The nested copy is kept for older readers.
+func copyClientThreadConfig(ctx context.Context, db *mongo.Database) error {
+	filter := bson.M{
+		"thread_config":        bson.M{"$exists": false},
+		"config.thread_config": bson.M{"$type": "object"},
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"thread_config": "$config.thread_config"}}}}
+	_, err := db.Collection("clients").UpdateMany(ctx, filter, update)
+	return err
+}
+
+// backfillSessionState marks sessions without a state as open, which is how CurrentState reads
+// them, so the stored state agrees with what the API reports.
+func backfillSessionState(ctx context.Context, db *mongo.Database) error {
+	filter := bson.M{"state": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"state": models.SessionStateOpen}}
+	_, err := db.Collection("chat_sessions").UpdateMany(ctx, filter, update)
+	return err
+}
+
+// normalizeThreadConfig rewrites the thread_config of clients and channels to what
+// models.ThreadConfig decodes: enabled is kept only when it is a boolean, and inactivity_minutes is
+// converted to an integer and kept only when positive, so a number stored as a string or a double
+// still applies. Other keys, which nothing reads, are dropped, and a thread_config that isn't a
+// document is removed.
+func normalizeThreadConfig(ctx context.Context, db *mongo.Database) error {
+	minutes := bson.M{"$convert": bson.M{
+		"input":   "$thread_config.inactivity_minutes",
+		"to":      "int",
+		"onError": nil,
+		"onNull":  nil,
+	}}
+	normalized := bson.M{
+		"enabled": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{bson.M{"$type": "$thread_config.enabled"}, "bool"}},
+			"$thread_config.enabled",
+			"$$REMOVE",
+		}},
+		// null sorts below numbers, so unconvertible values are removed too
+		"inactivity_minutes": bson.M{"$cond": bson.A{bson.M{"$gt": bson.A{minutes, 0}}, minutes, "$$REMOVE"}},
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.M{"thread_config": normalized}}}}
+
+	for _, collection := range []string{"clients", "client_channels"} {
+		coll := db.Collection(collection)
+		if _, err := coll.UpdateMany(ctx, bson.M{"thread_config": bson.M{"$type": "object"}}, update); err != nil {
+			return err
+		}
+		invalid := bson.M{"thread_config": bson.M{"$exists": true, "$not": bson.M{"$type": bson.A{"object", "null"}}}}
+		if _, err := coll.UpdateMany(ctx, invalid, bson.M{"$unset": bson.M{"thread_config": ""}}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
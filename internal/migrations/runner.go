@@ -0,0 +1,207 @@
+// Package migrations creates the MongoDB indexes the service relies on and applies versioned
+// changes to stored data, recording which versions have been applied.
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/config"
+)
+
+const (
+	// appliedCollection holds one document per applied migration, keyed by version
+	appliedCollection = "schema_migrations"
+	// lockCollection holds the lock that keeps instances starting together from migrating at once
+	lockCollection = "schema_migration_lock"
+	lockID         = "migrations"
+	// lockLease bounds how long a crashed runner holds the lock when its context has no deadline
+	lockLease        = 10 * time.Minute
+	lockPollInterval = 2 * time.Second
+)
+
+// Migration is a versioned change to stored data. Up runs again if it failed part way, so it
+// must be safe to repeat.
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(ctx context.Context, db *mongo.Database) error
+}
+
+// AppliedMigration records a migration that has been applied.
+type AppliedMigration struct {
+	Version     int       `bson:"_id"`
+	Description string    `bson:"description"`
+	AppliedAt   time.Time `bson:"applied_at"`
+	DurationMS  int64     `bson:"duration_ms"`
+}
+
+// MigrationStatus is a known migration and when it was applied, if it has been.
+type MigrationStatus struct {
+	Version     int
+	Description string
+	AppliedAt   *time.Time
+}
+
+// Runner applies migrations and creates indexes on a database.
+type Runner struct {
+	Indexes    []Index
+	Migrations []Migration
+
+	db     *mongo.Database
+	logger *zap.Logger
+	owner  string
+}
+
+// NewRunner creates a Runner for the service's indexes and migrations.
+func NewRunner(db *mongo.Database, logger *zap.Logger, cfg *config.Config) *Runner {
+	return &Runner{
+		Indexes:    RequiredIndexes(cfg.DeliveryAttemptRetention),
+		Migrations: All,
+		db:         db,
+		logger:     logger,
+		owner:      primitive.NewObjectID().Hex(),
+	}
+}
+
+// Run applies pending migrations in version order, then creates missing indexes. Migrations run
+// first so they can fix data a new unique index would reject. Runners on other instances wait
+// until this one is done.
+func (r *Runner) Run(ctx context.Context) error {
+	if err := r.checkVersions(); err != nil {
+		return err
+	}
+	if err := r.lock(ctx); err != nil {
+		return err
+	}
+	defer r.unlock()
+
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range r.Migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		if err := r.apply(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return ensureIndexes(ctx, r.db, r.Indexes, r.logger)
+}
+
+// Status lists every known migration and when it was applied.
+func (r *Runner) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := r.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]MigrationStatus, 0, len(r.Migrations))
+	for _, m := range r.Migrations {
+		status := MigrationStatus{Version: m.Version, Description: m.Description}
+		if record, ok := applied[m.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// checkVersions rejects migrations that are not in strictly increasing version order, which would
+// make the order they run in depend on when each instance was deployed.
+func (r *Runner) checkVersions() error {
+	for i := 1; i < len(r.Migrations); i++ {
+		if r.Migrations[i].Version <= r.Migrations[i-1].Version {
+			return fmt.Errorf("migration %d must come before migration %d", r.Migrations[i].Version, r.Migrations[i-1].Version)
+		}
+	}
+	return nil
+}
+
+func (r *Runner) applied(ctx context.Context) (map[int]AppliedMigration, error) {
+	cursor, err := r.db.Collection(appliedCollection).Find(ctx, bson.M{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []AppliedMigration
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+	applied := make(map[int]AppliedMigration, len(records))
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	return applied, nil
+}
+
+func (r *Runner) apply(ctx context.Context, m Migration) error {
+	r.logger.Info("Applying migration", zap.Int("version", m.Version), zap.String("description", m.Description))
+	start := time.Now()
+	if err := m.Up(ctx, r.db); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+	}
+
+	record := AppliedMigration{
+		Version:     m.Version,
+		Description: m.Description,
+		AppliedAt:   time.Now().UTC(),
+		DurationMS:  time.Since(start).Milliseconds(),
+	}
+	if _, err := r.db.Collection(appliedCollection).InsertOne(ctx, record); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+	r.logger.Info("Applied migration", zap.Int("version", m.Version), zap.Int64("duration_ms", record.DurationMS))
+	return nil
+}
+
+// lock takes the migration lock, waiting while another runner holds it. A lock whose lease ran
+// out, because its runner crashed, is taken over.
+func (r *Runner) lock(ctx context.Context) error {
+	locks := r.db.Collection(lockCollection)
+	for {
+		now := time.Now().UTC()
+		until := now.Add(lockLease)
+		if deadline, ok := ctx.Deadline(); ok {
+			until = deadline.UTC()
+		}
+		_, err := locks.UpdateOne(ctx,
+			bson.M{"_id": lockID, "locked_until": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{"owner": r.owner, "locked_until": until}},
+			options.Update().SetUpsert(true),
+		)
+		if err == nil {
+			return nil
+		}
+		// The upsert collides with the lock document while someone else holds it
+		if !mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("failed to take migration lock: %w", err)
+		}
+
+		r.logger.Info("Waiting for migrations running on another instance")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for migration lock: %w", ctx.Err())
+		case <-time.After(lockPollInterval):
+		}
+	}
+}
+
+func (r *Runner) unlock() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	_, err := r.db.Collection(lockCollection).DeleteOne(ctx, bson.M{"_id": lockID, "owner": r.owner})
+	if err != nil {
+		r.logger.Warn("Failed to release migration lock", zap.Error(err))
+	}
+}
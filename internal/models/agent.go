@@ -0,0 +1,89 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Agent is a human agent that can receive handed-over sessions for a client
+type Agent struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID       primitive.ObjectID `bson:"client" json:"client_id"`
+	AgentID        string             `bson:"agent_id" json:"agent_id"` // Identifier in the client's agent desk
+	Name           string             `bson:"name,omitempty" json:"name,omitempty"`
+	Skills         []string           `bson:"skills,omitempty" json:"skills,omitempty"`
+	Queues         []string           `bson:"queues,omitempty" json:"queues,omitempty"`
+	Status         AgentStatus        `bson:"status" json:"status"`
+	MaxConcurrent  int                `bson:"max_concurrent" json:"max_concurrent"`
+	ActiveSessions int                `bson:"active_sessions" json:"active_sessions"`
+	LastAssignedAt *time.Time         `bson:"last_assigned_at,omitempty" json:"last_assigned_at,omitempty"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for Agent
+func (Agent) TableName() string {
+	return "agents"
+}
+
+// BeforeCreate sets timestamps before creating
+func (a *Agent) BeforeCreate() {
+	now := time.Now().UTC()
+	a.CreatedAt = now
+	a.UpdatedAt = now
+	if a.ID.IsZero() {
+		a.ID = primitive.NewObjectID()
+	}
+}
+
+// HasSkills reports whether the agent has every skill in skills
+func (a *Agent) HasSkills(skills []string) bool {
+	for _, skill := range skills {
+		if !slices.Contains(a.Skills, skill) {
+			return false
+		}
+	}
+	return true
+}
+
+// AssignmentRule selects agents for handovers of a client. Rules are evaluated by ascending
+// priority and the first enabled rule whose queue matches the handover is used.
+type AssignmentRule struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID       primitive.ObjectID `bson:"client" json:"client_id"`
+	Name           string             `bson:"name" json:"name"`
+	Priority       int                `bson:"priority" json:"priority"`
+	Queue          string             `bson:"queue,omitempty" json:"queue,omitempty"` // Empty matches any queue
+	Strategy       AssignmentStrategy `bson:"strategy" json:"strategy"`
+	RequiredSkills []string           `bson:"required_skills,omitempty" json:"required_skills,omitempty"`
+	Enabled        bool               `bson:"enabled" json:"enabled"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for AssignmentRule
+func (AssignmentRule) TableName() string {
+	return "assignment_rules"
+}
+
+// BeforeCreate sets timestamps before creating
+func (r *AssignmentRule) BeforeCreate() {
+	now := time.Now().UTC()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+	if r.ID.IsZero() {
+		r.ID = primitive.NewObjectID()
+	}
+}
+
+// SessionAssignment is one entry in a session's agent assignment history
+type SessionAssignment struct {
+	AgentID    string              `bson:"agent_id" json:"agent_id"`
+	RuleID     *primitive.ObjectID `bson:"rule,omitempty" json:"rule_id,omitempty"`
+	Strategy   AssignmentStrategy  `bson:"strategy,omitempty" json:"strategy,omitempty"`
+	Queue      string              `bson:"queue,omitempty" json:"queue,omitempty"`
+	AssignedAt time.Time           `bson:"assigned_at" json:"assigned_at"`
+	ReleasedAt *time.Time          `bson:"released_at,omitempty" json:"released_at,omitempty"`
+}
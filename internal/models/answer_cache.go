@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AnswerCacheEntry is an AI answer kept for a client's question, reused until ExpiresAt.
+type AnswerCacheEntry struct {
+	ID       primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID string             `bson:"client_id" json:"client_id"`
+	// Key hashes everything the answer depends on; see AnswerCacheService
+	Key              string `bson:"key" json:"key"`
+	Question         string `bson:"question" json:"question"` // Normalized
+	KnowledgeVersion string `bson:"knowledge_version,omitempty" json:"knowledge_version,omitempty"`
+	SuggestionMode   bool   `bson:"suggestion_mode,omitempty" json:"suggestion_mode,omitempty"`
+	// Response is the AI service's answer, as JSON
+	Response   string     `bson:"response" json:"-"`
+	Confidence float64    `bson:"confidence" json:"confidence"`
+	Hits       int64      `bson:"hits" json:"hits"`
+	LastHitAt  *time.Time `bson:"last_hit_at,omitempty" json:"last_hit_at,omitempty"`
+	ExpiresAt  time.Time  `bson:"expires_at" json:"expires_at"`
+	CreatedAt  time.Time  `bson:"created_at" json:"created_at"`
+}
+
+// TableName returns the collection name for AnswerCacheEntry
+func (AnswerCacheEntry) TableName() string {
+	return "answer_cache_entries"
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// APIKeyScope is a permission granted to a client API key.
+type APIKeyScope string
+
+const (
+	APIKeyScopeReadMessages  APIKeyScope = "read:messages"
+	APIKeyScopeWriteMessages APIKeyScope = "write:messages"
+	// APIKeyScopeAdmin grants every scope, including managing the client's own resources
+	APIKeyScopeAdmin APIKeyScope = "admin"
+)
+
+// IsValid reports whether s is a known scope.
+func (s APIKeyScope) IsValid() bool {
+	switch s {
+	case APIKeyScopeReadMessages, APIKeyScopeWriteMessages, APIKeyScopeAdmin:
+		return true
+	}
+	return false
+}
+
+// APIKey is a client-scoped credential. Only a SHA-256 hash of the key is stored; the key itself
+// is shown once, when it is issued.
+type APIKey struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	Client     primitive.ObjectID  `bson:"client" json:"client"`
+	ClientID   string              `bson:"client_id" json:"client_id"` // The client's own client_id, for routes that address it that way
+	Name       string              `bson:"name" json:"name"`
+	Prefix     string              `bson:"prefix" json:"prefix"` // Leading characters of the key, to tell keys apart
+	KeyHash    string              `bson:"key_hash" json:"-"`
+	Scopes     []APIKeyScope       `bson:"scopes" json:"scopes"`
+	Roles      []Role              `bson:"roles,omitempty" json:"roles,omitempty"` // Granted on top of the scopes
+	Tier       string              `bson:"rate_limit_tier,omitempty" json:"rate_limit_tier,omitempty"`
+	LastUsedAt *time.Time          `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	ExpiresAt  *time.Time          `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt  *time.Time          `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	ReplacedBy *primitive.ObjectID `bson:"replaced_by,omitempty" json:"replaced_by,omitempty"` // Key issued when this one was rotated
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for APIKey
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// BeforeCreate sets timestamps before creating
+func (k *APIKey) BeforeCreate() {
+	now := time.Now().UTC()
+	k.CreatedAt = now
+	k.UpdatedAt = now
+}
+
+// HasScope reports whether the key grants scope. The admin scope grants every scope.
+func (k *APIKey) HasScope(scope APIKeyScope) bool {
+	for _, s := range k.Scopes {
+		if s == scope || s == APIKeyScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// IsActive reports whether the key can still be used at now.
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil && !now.Before(*k.RevokedAt) {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// BelongsTo reports whether the key was issued to the client addressed by ref, which is either
+// the client's ObjectID or its client_id.
+func (k *APIKey) BelongsTo(ref string) bool {
+	return ref != "" && (ref == k.Client.Hex() || ref == k.ClientID)
+}
@@ -0,0 +1,186 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Attachment types
+const (
+	AttachmentTypeFile     = "file"
+	AttachmentTypeImage    = "image"
+	AttachmentTypeCarousel = "carousel"
+	AttachmentTypeButtons  = "buttons"
+)
+
+// ErrInvalidAttachment is returned for attachments that don't fit their type.
+var ErrInvalidAttachment = errors.New("invalid attachment")
+
+// Attachment represents a file, image, carousel or set of buttons attached to a chat message.
+type Attachment struct {
+	FileName string             `bson:"file_name,omitempty" json:"file_name,omitempty"`
+	FileType string             `bson:"file_type,omitempty" json:"file_type,omitempty"`
+	FileSize int64              `bson:"file_size,omitempty" json:"file_size,omitempty"`
+	FileURL  string             `bson:"file_url,omitempty" json:"file_url,omitempty"`
+	Type     string             `bson:"type,omitempty" json:"type,omitempty"` // AttachmentType*
+	Carousel *Carousel          `bson:"carousel,omitempty" json:"carousel,omitempty"`
+	Buttons  []AttachmentButton `bson:"buttons,omitempty" json:"buttons,omitempty"`
+}
+
+// Carousel is a row of cards. Messages stored before attachments were typed keep the AI service's
+// buttons here rather than in Attachment.Buttons.
+type Carousel struct {
+	Items   []CarouselItem     `bson:"items,omitempty" json:"items,omitempty"`
+	Buttons []AttachmentButton `bson:"buttons,omitempty" json:"buttons,omitempty"`
+}
+
+// CarouselItem is one card of a carousel.
+type CarouselItem struct {
+	Title            string             `bson:"title" json:"title"`
+	Description      string             `bson:"description,omitempty" json:"description,omitempty"`
+	MediaURL         string             `bson:"media_url,omitempty" json:"media_url,omitempty"`
+	MediaType        string             `bson:"media_type,omitempty" json:"media_type,omitempty"`
+	DefaultActionURL string             `bson:"default_action_url,omitempty" json:"default_action_url,omitempty"`
+	Buttons          []AttachmentButton `bson:"buttons,omitempty" json:"buttons,omitempty"`
+}
+
+// AttachmentButton is a button under a message or carousel card. A button with a URL opens it;
+// otherwise choosing it replies with its Payload, or its Title when it has none.
+type AttachmentButton struct {
+	Type    string `bson:"type,omitempty" json:"type,omitempty"` // e.g. "postback" or "url"
+	Title   string `bson:"title" json:"title"`
+	Payload string `bson:"payload,omitempty" json:"payload,omitempty"`
+	URL     string `bson:"url,omitempty" json:"url,omitempty"`
+}
+
+// ButtonFromMap reads a button as the AI service and older messages spell it, with its title
+// also under "text" or "label" and its payload under "value".
+func ButtonFromMap(m map[string]interface{}) AttachmentButton {
+	field := func(keys ...string) string {
+		for _, key := range keys {
+			switch v := m[key].(type) {
+			case string:
+				if v != "" {
+					return v
+				}
+			case int32, int64, float64:
+				return fmt.Sprint(v)
+			}
+		}
+		return ""
+	}
+	return AttachmentButton{
+		Type:    field("type"),
+		Title:   field("title", "text", "label"),
+		Payload: field("payload", "value"),
+		URL:     field("url"),
+	}
+}
+
+// UnmarshalBSON accepts the spellings of ButtonFromMap, so messages stored before buttons were
+// typed still decode.
+func (b *AttachmentButton) UnmarshalBSON(data []byte) error {
+	var m bson.M
+	if err := bson.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*b = ButtonFromMap(m)
+	return nil
+}
+
+// UnmarshalJSON accepts the spellings of ButtonFromMap.
+func (b *AttachmentButton) UnmarshalJSON(data []byte) error {
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*b = ButtonFromMap(m)
+	return nil
+}
+
+// AllButtons returns the buttons of the attachment itself followed by those of its carousel.
+func (a *Attachment) AllButtons() []AttachmentButton {
+	buttons := append([]AttachmentButton{}, a.Buttons...)
+	if a.Carousel != nil {
+		buttons = append(buttons, a.Carousel.Buttons...)
+	}
+	return buttons
+}
+
+// CarouselItems returns the cards of the attachment's carousel, if it has one.
+func (a *Attachment) CarouselItems() []CarouselItem {
+	if a.Carousel == nil {
+		return nil
+	}
+	return a.Carousel.Items
+}
+
+// Validate checks that the attachment has what its type needs: a URL for files and images, cards
+// with titles for carousels, and buttons with titles for buttons.
+func (a *Attachment) Validate() error {
+	switch a.Type {
+	case "", AttachmentTypeFile, AttachmentTypeImage:
+		if a.FileURL == "" {
+			return fmt.Errorf("%w: file_url is required for %s attachments", ErrInvalidAttachment, a.typeName())
+		}
+	case AttachmentTypeCarousel:
+		if len(a.CarouselItems()) == 0 {
+			return fmt.Errorf("%w: a carousel needs items", ErrInvalidAttachment)
+		}
+		for i, item := range a.Carousel.Items {
+			if strings.TrimSpace(item.Title) == "" {
+				return fmt.Errorf("%w: carousel item %d has no title", ErrInvalidAttachment, i)
+			}
+			if item.MediaURL != "" && !isHTTPURL(item.MediaURL) {
+				return fmt.Errorf("%w: carousel item %d has an invalid media_url", ErrInvalidAttachment, i)
+			}
+			if err := validateButtons(item.Buttons, fmt.Sprintf("carousel item %d ", i)); err != nil {
+				return err
+			}
+		}
+	case AttachmentTypeButtons:
+		if len(a.AllButtons()) == 0 {
+			return fmt.Errorf("%w: a buttons attachment needs buttons", ErrInvalidAttachment)
+		}
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidAttachment, a.Type)
+	}
+	return validateButtons(a.AllButtons(), "")
+}
+
+func (a *Attachment) typeName() string {
+	if a.Type == "" {
+		return AttachmentTypeFile
+	}
+	return a.Type
+}
+
+func validateButtons(buttons []AttachmentButton, owner string) error {
+	for i, b := range buttons {
+		if strings.TrimSpace(b.Title) == "" {
+			return fmt.Errorf("%w: %sbutton %d has no title", ErrInvalidAttachment, owner, i)
+		}
+		if b.URL != "" && !isHTTPURL(b.URL) {
+			return fmt.Errorf("%w: %sbutton %d has an invalid url", ErrInvalidAttachment, owner, i)
+		}
+	}
+	return nil
+}
+
+func isHTTPURL(u string) bool {
+	return strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://")
+}
+
+// ValidateAttachments validates each attachment, naming the first invalid one.
+func ValidateAttachments(attachments []Attachment) error {
+	for i := range attachments {
+		if err := attachments[i].Validate(); err != nil {
+			return fmt.Errorf("attachments[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CannedResponse is a reusable, client-scoped message template. Text may contain
+// {{name}} placeholders that are filled in when the response is sent.
+type CannedResponse struct {
+	ID           primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID     string                 `bson:"client_id" json:"client_id"`
+	Title        string                 `bson:"title" json:"title"`
+	Shortcut     string                 `bson:"shortcut,omitempty" json:"shortcut,omitempty"`
+	Category     string                 `bson:"category,omitempty" json:"category,omitempty"`
+	Text         string                 `bson:"text" json:"text"`
+	Placeholders []string               `bson:"placeholders,omitempty" json:"placeholders,omitempty"` // Derived from Text on every write
+	Attachments  []Attachment           `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Data         map[string]interface{} `bson:"data,omitempty" json:"data,omitempty"`
+	UsageCount   int64                  `bson:"usage_count" json:"usage_count"`
+	LastUsedAt   *time.Time             `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt    time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time              `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for CannedResponse
+func (CannedResponse) TableName() string {
+	return "canned_responses"
+}
+
+// BeforeCreate sets timestamps before creating
+func (r *CannedResponse) BeforeCreate() {
+	now := time.Now().UTC()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+}
@@ -26,34 +26,48 @@ const (
 	// Custom client types are prefixed with "client:"
 )
 
-// Attachment represents a file/image attached to a chat message.
-type Attachment struct {
-	FileName string                   `bson:"file_name,omitempty" json:"file_name,omitempty"`
-	FileType string                   `bson:"file_type,omitempty" json:"file_type,omitempty"`
-	FileSize int64                    `bson:"file_size,omitempty" json:"file_size,omitempty"`
-	FileURL  string                   `bson:"file_url,omitempty" json:"file_url,omitempty"`
-	Type     string                   `bson:"type,omitempty" json:"type,omitempty"` // "file", "image", "carousel", "buttons"
-	Carousel map[string]interface{}   `bson:"carousel,omitempty" json:"carousel,omitempty"`
-	Buttons  []map[string]interface{} `bson:"buttons,omitempty" json:"buttons,omitempty"` // For postback/reply buttons
-}
+// MessageDeliveryStatusFailed marks a message the channel processor never accepted.
+const MessageDeliveryStatusFailed = "failed_delivery"
+
+// Delivery statuses reported back by channels that confirm delivery, such as SMS.
+const (
+	MessageDeliveryStatusSent      = "sent"
+	MessageDeliveryStatusDelivered = "delivered"
+	MessageDeliveryStatusRead      = "read"
+)
 
 // ChatMessage represents a chat message document in MongoDB.
 type ChatMessage struct {
-	ID             primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
-	ExternalID     string                 `bson:"external_id,omitempty" json:"external_id,omitempty"`
-	Sender         string                 `bson:"sender" json:"sender"`
-	SenderName     string                 `bson:"sender_name,omitempty" json:"sender_name,omitempty"`
-	SenderType     string                 `bson:"sender_type" json:"sender_type"`
-	SessionID      primitive.ObjectID     `bson:"session,omitempty" json:"session"` // Reference to ChatSession
-	Text           string                 `bson:"text" json:"text"`
-	Attachments    []Attachment           `bson:"attachments,omitempty" json:"attachments,omitempty"`
-	Data           map[string]interface{} `bson:"data,omitempty" json:"data,omitempty"`
-	Category       MessageCategory        `bson:"category" json:"category"`
-	Config         map[string]interface{} `bson:"config,omitempty" json:"config,omitempty"`
-	Confidence     float64                `bson:"confidence_score,omitempty" json:"confidence_score,omitempty"`
-	Edit           bool                   `bson:"edit,omitempty" json:"edit,omitempty"`
-	CreatedAt      time.Time              `bson:"created_at,omitempty" json:"created_at,omitempty"`
-	UpdatedAt      time.Time              `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+	ID              primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
+	ExternalID      string                 `bson:"external_id,omitempty" json:"external_id,omitempty"`
+	DedupKey        string                 `bson:"dedup_key,omitempty" json:"-"` // MessageDedupKey of inbound messages with an external_id, AIReplyDedupKey of AI replies
+	Sender          string                 `bson:"sender" json:"sender"`
+	SenderName      string                 `bson:"sender_name,omitempty" json:"sender_name,omitempty"`
+	SenderType      string                 `bson:"sender_type" json:"sender_type"`
+	SessionID       primitive.ObjectID     `bson:"session,omitempty" json:"session"`                            // Reference to ChatSession
+	ParentMessageID *primitive.ObjectID    `bson:"parent_message,omitempty" json:"parent_message_id,omitempty"` // Reference to the ChatMessage this replies to
+	ReplyCount      int                    `bson:"reply_count,omitempty" json:"reply_count"`
+	Text            string                 `bson:"text" json:"text"`
+	Attachments     []Attachment           `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Citations       []Citation             `bson:"citations,omitempty" json:"citations,omitempty"` // Knowledge sources of AI replies
+	Data            map[string]interface{} `bson:"data,omitempty" json:"data,omitempty"`
+	Category        MessageCategory        `bson:"category" json:"category"`
+	Config          map[string]interface{} `bson:"config,omitempty" json:"config,omitempty"`
+	Confidence      float64                `bson:"confidence_score,omitempty" json:"confidence_score,omitempty"`
+	Edit            bool                   `bson:"edit,omitempty" json:"edit,omitempty"`
+	DeliveryStatus  string                 `bson:"delivery_status,omitempty" json:"delivery_status,omitempty"` // MessageDeliveryStatus*; failed once every delivery attempt has failed
+	DeliveryError   string                 `bson:"delivery_error,omitempty" json:"delivery_error,omitempty"`
+	Intent          string                 `bson:"intent,omitempty" json:"intent,omitempty"` // Set by intent routing before the AI answers
+	IntentConfidence float64               `bson:"intent_confidence,omitempty" json:"intent_confidence,omitempty"`
+	Contact         *primitive.ObjectID    `bson:"contact,omitempty" json:"contact_id,omitempty"` // The Contact who sent a user message
+	CreatedAt       time.Time              `bson:"created_at,omitempty" json:"created_at,omitempty"`
+	UpdatedAt       time.Time              `bson:"updated_at,omitempty" json:"updated_at,omitempty"`
+}
+
+// MessageDedupKey identifies an inbound message by the ID its channel gave it, so a redelivered
+// message can be told apart from a new one.
+func MessageDedupKey(clientID, channelID primitive.ObjectID, externalID string) string {
+	return clientID.Hex() + ":" + channelID.Hex() + ":" + externalID
 }
 
 // TableName returns the MongoDB collection name for ChatMessage.
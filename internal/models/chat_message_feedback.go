@@ -7,6 +7,16 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// FeedbackForwardStatus tracks the forwarding of a feedback to the AI service.
+type FeedbackForwardStatus string
+
+const (
+	FeedbackForwardPending FeedbackForwardStatus = "pending"
+	FeedbackForwardSent    FeedbackForwardStatus = "sent"
+	FeedbackForwardFailed  FeedbackForwardStatus = "failed"  // Gave up after too many attempts
+	FeedbackForwardSkipped FeedbackForwardStatus = "skipped" // The client opted out before it was sent
+)
+
 // ChatMessageFeedback represents feedback for a chat message.
 type ChatMessageFeedback struct {
 	ID            primitive.ObjectID     `bson:"_id,omitempty" json:"id"`
@@ -16,4 +26,16 @@ type ChatMessageFeedback struct {
 	Metadata      map[string]interface{} `bson:"metadata" json:"metadata"`
 	CreatedAt     time.Time              `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time              `bson:"updated_at" json:"updated_at"`
+	// Forwarding to the AI service, set only for clients that opt in
+	ClientID        string                `bson:"client_id,omitempty" json:"client_id,omitempty"`
+	ForwardStatus   FeedbackForwardStatus `bson:"forward_status,omitempty" json:"forward_status,omitempty"`
+	ForwardAttempts int                   `bson:"forward_attempts,omitempty" json:"forward_attempts,omitempty"`
+	NextForwardAt   *time.Time            `bson:"next_forward_at,omitempty" json:"next_forward_at,omitempty"`
+	ForwardedAt     *time.Time            `bson:"forwarded_at,omitempty" json:"forwarded_at,omitempty"`
+	ForwardError    string                `bson:"forward_error,omitempty" json:"forward_error,omitempty"`
+}
+
+// TableName returns the collection name for ChatMessageFeedback
+func (ChatMessageFeedback) TableName() string {
+	return "chat_message_feedback"
 }
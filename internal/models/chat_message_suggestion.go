@@ -17,10 +17,34 @@ type ChatMessageSuggestion struct {
 	Metadata       map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
 	IsUsed         bool             `bson:"is_used" json:"is_used"`
 	UsedAt         *time.Time       `bson:"used_at,omitempty" json:"used_at,omitempty"`
+	// MessageID is the user message the suggestion answers
+	MessageID      *primitive.ObjectID `bson:"message,omitempty" json:"message_id,omitempty"`
+	Test           bool                `bson:"test,omitempty" json:"test,omitempty"` // Sandbox session; excluded from analytics
+	Status          SuggestionStatus `bson:"status,omitempty" json:"status,omitempty"`
+	FinalText       string           `bson:"final_text,omitempty" json:"final_text,omitempty"` // Text the agent sent, when edited
+	Edited          bool             `bson:"edited,omitempty" json:"edited,omitempty"`
+	Edits           []SuggestionEdit `bson:"edits,omitempty" json:"edits,omitempty"`
+	RejectionReason string           `bson:"rejection_reason,omitempty" json:"rejection_reason,omitempty"`
+	ReviewedBy      string           `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time       `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
 	CreatedAt      time.Time        `bson:"created_at" json:"created_at"`
 	UpdatedAt      time.Time        `bson:"updated_at" json:"updated_at"`
 }
 
+// SuggestionEdit is one word-level change between a suggestion and the text the agent sent.
+type SuggestionEdit struct {
+	Op   string `bson:"op" json:"op"` // "equal", "insert" or "delete"
+	Text string `bson:"text" json:"text"`
+}
+
+// CurrentStatus returns the suggestion's review status. Suggestions stored before reviews existed are pending.
+func (cms *ChatMessageSuggestion) CurrentStatus() SuggestionStatus {
+	if cms.Status == "" {
+		return SuggestionStatusPending
+	}
+	return cms.Status
+}
+
 // TableName returns the collection name for ChatMessageSuggestion
 func (ChatMessageSuggestion) TableName() string {
 	return "chat_message_suggestions"
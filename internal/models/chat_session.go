@@ -17,4 +17,31 @@ type ChatSession struct {
 	Client        *primitive.ObjectID  `bson:"client,omitempty" json:"client,omitempty"`
 	ClientChannel *primitive.ObjectID  `bson:"client_channel,omitempty" json:"client_channel,omitempty"`
 	Participants  []string             `bson:"participants,omitempty" json:"participants,omitempty"`
+	Contact       *primitive.ObjectID  `bson:"contact,omitempty" json:"contact_id,omitempty"` // The Contact of the session's first user message
+	Handover      *SessionHandover     `bson:"handover,omitempty" json:"handover,omitempty"`
+	Assignments   []SessionAssignment  `bson:"assignments,omitempty" json:"assignments,omitempty"`
+	Tags          []string             `bson:"tags,omitempty" json:"tags,omitempty"`
+	Attributes    map[string]string    `bson:"attributes,omitempty" json:"attributes,omitempty"`
+	Test          bool                 `bson:"test,omitempty" json:"test,omitempty"` // Created in sandbox mode; excluded from analytics and billing
+	Simulation    *primitive.ObjectID  `bson:"simulation,omitempty" json:"simulation_id,omitempty"` // The Simulation replayed in this sandbox session
+	Import        *primitive.ObjectID  `bson:"import,omitempty" json:"import_id,omitempty"` // The MessageImport that created this session
+	State          SessionState `bson:"state,omitempty" json:"state,omitempty"`
+	StateChangedAt *time.Time   `bson:"state_changed_at,omitempty" json:"state_changed_at,omitempty"`
+	StateReason    string       `bson:"state_reason,omitempty" json:"state_reason,omitempty"`
+	SnoozedUntil   *time.Time   `bson:"snoozed_until,omitempty" json:"snoozed_until,omitempty"`
+	ClosedAt       *time.Time   `bson:"closed_at,omitempty" json:"closed_at,omitempty"`
+	LastActivityAt *time.Time   `bson:"last_activity_at,omitempty" json:"last_activity_at,omitempty"`
+	// LowConfidenceStreak counts consecutive low-confidence AI answers for escalation
+	LowConfidenceStreak int `bson:"low_confidence_streak,omitempty" json:"low_confidence_streak,omitempty"`
+	// Version goes up with every change to the tags, attributes, state, handover or assignments,
+	// so clients can make their update conditional on the version they read
+	Version int64 `bson:"version,omitempty" json:"version"`
+}
+
+// CurrentState returns the session's lifecycle state. Sessions created before states existed are open.
+func (s *ChatSession) CurrentState() SessionState {
+	if s.State == "" {
+		return SessionStateOpen
+	}
+	return s.State
 }
@@ -16,4 +16,6 @@ type ChatSessionThread struct {
 	ChatSessionID    primitive.ObjectID `bson:"chat_session_id" json:"chat_session_id"`
 	Active           bool               `bson:"active" json:"active"`
 	LastActivity     time.Time          `bson:"last_activity" json:"last_activity"`
+	// MergedInto is the thread_id of the thread this thread's messages were merged into
+	MergedInto       string             `bson:"merged_into,omitempty" json:"merged_into,omitempty"`
 }
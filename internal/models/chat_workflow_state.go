@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatWorkflowStateRetention is how long the state of a chat workflow is kept after its last step
+const ChatWorkflowStateRetention = 30 * 24 * time.Hour
+
+// ChatWorkflowStep names a step of the chat workflow, in the order they run
+type ChatWorkflowStep string
+
+const (
+	ChatWorkflowStepFetchContext ChatWorkflowStep = "fetch_context"
+	ChatWorkflowStepBeforeAI     ChatWorkflowStep = "before_ai"
+	ChatWorkflowStepAICall       ChatWorkflowStep = "ai_call"
+	ChatWorkflowStepAfterAI      ChatWorkflowStep = "after_ai"
+	ChatWorkflowStepPersist      ChatWorkflowStep = "persist"
+	ChatWorkflowStepPublish      ChatWorkflowStep = "publish"
+)
+
+// ChatWorkflowDraft is the reply a chat workflow drafted from the AI's answer.
+type ChatWorkflowDraft struct {
+	Text         string       `bson:"text" json:"text"`
+	Confidence   float64      `bson:"confidence" json:"confidence"`
+	Attachments  []Attachment `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Citations    []Citation   `bson:"citations,omitempty" json:"citations,omitempty"`
+	CloseSession bool         `bson:"close_session" json:"close_session"`
+	AnswerData   interface{}  `bson:"answer_data,omitempty" json:"answer_data,omitempty"`
+	// Prompt is the prompt template version the AI was asked with
+	Prompt *PromptVersionRef `bson:"prompt,omitempty" json:"prompt,omitempty"`
+	// Experiment is the experiment variant the reply was generated in
+	Experiment *ExperimentAssignment `bson:"experiment,omitempty" json:"experiment,omitempty"`
+	// Cached is set when the answer came from the answer cache instead of the AI
+	Cached bool `bson:"cached,omitempty" json:"cached,omitempty"`
+}
+
+// ChatWorkflowState checkpoints the chat workflow of one message, so a retried task resumes after
+// the last step it completed instead of asking the AI again.
+type ChatWorkflowState struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	MessageID      string             `bson:"message_id" json:"message_id"`
+	SuggestionMode bool               `bson:"suggestion_mode" json:"suggestion_mode"`
+	SessionID      string             `bson:"session_id" json:"session_id"`
+	Status         ExecutionStatus    `bson:"status" json:"status"`
+	// Step is the last step that completed
+	Step     ChatWorkflowStep `bson:"step,omitempty" json:"step,omitempty"`
+	Attempts int              `bson:"attempts" json:"attempts"`
+	// AIResponse is the AI service's answer, JSON encoded as it was received
+	AIResponse string             `bson:"ai_response,omitempty" json:"-"`
+	Draft      *ChatWorkflowDraft `bson:"draft,omitempty" json:"draft,omitempty"`
+	// ResponseMessageID is the stored reply, once persisted
+	ResponseMessageID *primitive.ObjectID `bson:"response_message,omitempty" json:"response_message_id,omitempty"`
+	// StoppedBy names the step or hook that ended the workflow without a reply
+	StoppedBy string    `bson:"stopped_by,omitempty" json:"stopped_by,omitempty"`
+	Error     string    `bson:"error,omitempty" json:"error,omitempty"`
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for ChatWorkflowState
+func (ChatWorkflowState) TableName() string {
+	return "chat_workflow_states"
+}
+
+// Completed reports whether step already completed in this workflow.
+func (s *ChatWorkflowState) Completed(step ChatWorkflowStep) bool {
+	return s.Step != "" && chatWorkflowStepIndex(step) <= chatWorkflowStepIndex(s.Step)
+}
+
+func chatWorkflowStepIndex(step ChatWorkflowStep) int {
+	for i, s := range []ChatWorkflowStep{
+		ChatWorkflowStepFetchContext,
+		ChatWorkflowStepBeforeAI,
+		ChatWorkflowStepAICall,
+		ChatWorkflowStepAfterAI,
+		ChatWorkflowStepPersist,
+		ChatWorkflowStepPublish,
+	} {
+		if s == step {
+			return i
+		}
+	}
+	return -1
+}
+
+// AIReplyDedupKey identifies the AI reply to a message, so a retried workflow finds the reply it
+// already stored instead of storing a second one.
+func AIReplyDedupKey(messageID string, suggestionMode bool) string {
+	if suggestionMode {
+		return "ai_suggestion:" + messageID
+	}
+	return "ai_reply:" + messageID
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxCitations is how many sources are kept for one message
+	MaxCitations = 20
+	// maxCitationSnippet bounds the excerpt of a source kept with a citation
+	maxCitationSnippet = 1000
+)
+
+// ErrInvalidCitation is returned for citations that can't be shown as a source.
+var ErrInvalidCitation = errors.New("invalid citation")
+
+// Citation is a knowledge source an AI answer was drawn from, so UIs can link to it.
+type Citation struct {
+	Title    string   `bson:"title,omitempty" json:"title,omitempty"`
+	URL      string   `bson:"url,omitempty" json:"url,omitempty"`
+	Snippet  string   `bson:"snippet,omitempty" json:"snippet,omitempty"`
+	SourceID string   `bson:"source_id,omitempty" json:"source_id,omitempty"` // The document's ID in the knowledge base
+	Score    *float64 `bson:"score,omitempty" json:"score,omitempty"`         // Retrieval relevance, as reported by the AI service
+}
+
+// Validate checks that the citation names its source by title or URL, and that a URL is one a
+// browser can open. A long snippet is cut short rather than rejected.
+func (c *Citation) Validate() error {
+	c.Title, c.URL = strings.TrimSpace(c.Title), strings.TrimSpace(c.URL)
+	if c.Title == "" && c.URL == "" {
+		return fmt.Errorf("%w: a title or url is required", ErrInvalidCitation)
+	}
+	if c.URL != "" && !isHTTPURL(c.URL) {
+		return fmt.Errorf("%w: url must be an http or https URL", ErrInvalidCitation)
+	}
+	if runes := []rune(c.Snippet); len(runes) > maxCitationSnippet {
+		c.Snippet = string(runes[:maxCitationSnippet])
+	}
+	return nil
+}
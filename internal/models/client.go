@@ -2,6 +2,8 @@
 package models
 
 import (
+	"errors"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -14,6 +16,142 @@ type Client struct {
 	ClientKey    string                `bson:"client_key" json:"client_key"`
 	IsActive     bool                  `bson:"is_active" json:"is_active"`
 	Config       map[string]interface{} `bson:"config,omitempty" json:"config,omitempty"`
-	ThreadConfig map[string]interface{} `bson:"thread_config,omitempty" json:"thread_config,omitempty"`
+	ThreadConfig *ThreadConfig          `bson:"thread_config,omitempty" json:"thread_config,omitempty"`
 	ChatConfig   map[string]interface{} `bson:"chat_config,omitempty" json:"chat_config,omitempty"`
+	Sandbox      bool                   `bson:"sandbox,omitempty" json:"sandbox,omitempty"`
+	// StrictPayloads rejects unknown JSON fields in message payloads sent for this client
+	StrictPayloads bool `bson:"strict_payloads,omitempty" json:"strict_payloads,omitempty"`
+	// DeliveryFailureCallbackURL is notified when an AI message can't be delivered to the channel
+	DeliveryFailureCallbackURL string `bson:"delivery_failure_callback_url,omitempty" json:"delivery_failure_callback_url,omitempty"`
+	// PostProcessingHook, when enabled, reviews every AI response before it is saved
+	PostProcessingHook *PostProcessingHook `bson:"post_processing_hook,omitempty" json:"post_processing_hook,omitempty"`
+	// IntentRouting, when enabled, classifies each message before the chat workflow answers it
+	IntentRouting *IntentRouting `bson:"intent_routing,omitempty" json:"intent_routing,omitempty"`
+	// Moderation, when enabled, screens every AI response before it is saved and sent
+	Moderation *ModerationPolicy `bson:"moderation,omitempty" json:"moderation,omitempty"`
+	// Escalation decides when the chat workflow hands a session to a human; channels can override it
+	Escalation *EscalationPolicy `bson:"escalation,omitempty" json:"escalation,omitempty"`
+	// Quota caps the client's monthly usage; without one usage is only metered
+	Quota *UsageQuota `bson:"quota,omitempty" json:"quota,omitempty"`
+	// ContactHistory, when enabled, gives the AI recaps of a returning contact's earlier sessions
+	ContactHistory *ContactHistoryPolicy `bson:"contact_history,omitempty" json:"contact_history,omitempty"`
+	// AIConfig, when enabled, picks the model and prompt the AI service answers the client with
+	AIConfig *ClientAIConfig `bson:"ai_config,omitempty" json:"ai_config,omitempty"`
+	// AnswerCache, when enabled, answers questions asked before from earlier AI answers
+	AnswerCache *AnswerCachePolicy `bson:"answer_cache,omitempty" json:"answer_cache,omitempty"`
+	// AIFeedback, when enabled, forwards feedback on the client's AI answers to the AI service
+	AIFeedback *AIFeedbackPolicy `bson:"ai_feedback,omitempty" json:"ai_feedback,omitempty"`
+}
+
+// DefaultThreadInactivityMinutes is how long threads stay active without messages when neither
+// their channel nor their client sets inactivity_minutes
+const DefaultThreadInactivityMinutes = 1440
+
+// ThreadConfig holds a client's threading settings, or a channel's overrides of them. Fields left
+// unset defer to the next level: the channel's to the client's, the client's to the threading
+// feature flag and DefaultThreadInactivityMinutes.
+type ThreadConfig struct {
+	Enabled           *bool `bson:"enabled,omitempty" json:"enabled,omitempty"`
+	InactivityMinutes int   `bson:"inactivity_minutes,omitempty" json:"inactivity_minutes,omitempty"`
+}
+
+// Validate checks the settings that are set.
+func (tc *ThreadConfig) Validate() error {
+	if tc.InactivityMinutes < 0 {
+		return errors.New("thread_config.inactivity_minutes must not be negative")
+	}
+	return nil
+}
+
+// IntentRouting maps classified message intents to chat workflow actions.
+// The first matching rule wins; messages matching no rule get an AI answer.
+type IntentRouting struct {
+	Enabled bool         `bson:"enabled" json:"enabled"`
+	Rules   []IntentRule `bson:"rules" json:"rules"`
+}
+
+// IntentRule routes messages classified as Intent with at least MinConfidence.
+type IntentRule struct {
+	Intent        string       `bson:"intent" json:"intent"` // "*" matches any intent
+	MinConfidence float64      `bson:"min_confidence" json:"min_confidence"`
+	Action        IntentAction `bson:"action" json:"action"`
+	TargetQueue   string       `bson:"target_queue,omitempty" json:"target_queue,omitempty"` // Required for handover
+}
+
+// Match returns the first rule matching intent at confidence, or nil.
+func (r *IntentRouting) Match(intent string, confidence float64) *IntentRule {
+	for i := range r.Rules {
+		rule := &r.Rules[i]
+		if (rule.Intent == intent || rule.Intent == "*") && confidence >= rule.MinConfidence {
+			return rule
+		}
+	}
+	return nil
+}
+
+// EscalationPolicy hands a session over to a human when the user asks for one or the AI keeps
+// answering with low confidence. Without a TargetQueue only escalation events are published.
+type EscalationPolicy struct {
+	Enabled                  bool     `bson:"enabled" json:"enabled"`
+	ConfidenceThreshold      float64  `bson:"confidence_threshold" json:"confidence_threshold"`             // Answers at or below this are low confidence
+	ConsecutiveLowConfidence int      `bson:"consecutive_low_confidence" json:"consecutive_low_confidence"` // Low-confidence answers in a row before escalating
+	Keywords                 []string `bson:"keywords,omitempty" json:"keywords,omitempty"`                 // Matched case-insensitively against user messages
+	TargetQueue              string   `bson:"target_queue,omitempty" json:"target_queue,omitempty"`
+}
+
+// ModerationPolicy screens AI responses against blocked phrases, regular expressions and,
+// optionally, an external moderation API. A blocked response is never sent; the session
+// is handed over to HandoverQueue instead.
+type ModerationPolicy struct {
+	Enabled         bool     `bson:"enabled" json:"enabled"`
+	BlockedPhrases  []string `bson:"blocked_phrases,omitempty" json:"blocked_phrases,omitempty"`   // Matched case-insensitively
+	BlockedPatterns []string `bson:"blocked_patterns,omitempty" json:"blocked_patterns,omitempty"` // Go regexp syntax
+	APIURL          string   `bson:"api_url,omitempty" json:"api_url,omitempty"`
+	APITimeoutMs    int      `bson:"api_timeout_ms,omitempty" json:"api_timeout_ms,omitempty"`
+	HandoverQueue   string   `bson:"handover_queue" json:"handover_queue"`
+}
+
+// ClientAIConfig is the model and prompt a client's chat replies and suggestions are generated
+// with. Fields left unset keep the AI service's defaults.
+type ClientAIConfig struct {
+	Enabled      bool     `bson:"enabled" json:"enabled"`
+	Provider     string   `bson:"provider,omitempty" json:"provider,omitempty"`
+	Model        string   `bson:"model" json:"model"`
+	Temperature  *float64 `bson:"temperature,omitempty" json:"temperature,omitempty"`
+	SystemPrompt string   `bson:"system_prompt,omitempty" json:"system_prompt,omitempty"`
+	MaxTokens    int      `bson:"max_tokens,omitempty" json:"max_tokens,omitempty"`
+}
+
+// AnswerCachePolicy lets the chat workflow reuse the AI's answer to a question the client was
+// asked before. Answers are keyed on the normalized question and KnowledgeVersion, so changing the
+// version when the client's knowledge base changes retires every earlier answer at once.
+type AnswerCachePolicy struct {
+	Enabled          bool    `bson:"enabled" json:"enabled"`
+	TTLSeconds       int     `bson:"ttl_seconds" json:"ttl_seconds"`
+	KnowledgeVersion string  `bson:"knowledge_version,omitempty" json:"knowledge_version,omitempty"`
+	MinConfidence    float64 `bson:"min_confidence" json:"min_confidence"` // Answers below it aren't cached
+}
+
+// AIFeedbackPolicy opts a client in to sending the feedback its AI answers get to the AI service,
+// along with the conversation leading up to each answer, to improve the model.
+type AIFeedbackPolicy struct {
+	Enabled         bool `bson:"enabled" json:"enabled"`
+	IncludeComments bool `bson:"include_comments" json:"include_comments"` // Comments may hold personal data, so they're opt-in too
+	ContextMessages int  `bson:"context_messages" json:"context_messages"` // Messages up to and including the rated answer
+}
+
+// PostProcessingHook is a client webhook called synchronously with each AI response.
+// It can allow, modify or veto the response.
+type PostProcessingHook struct {
+	URL           string                      `bson:"url" json:"url"`
+	Secret        string                      `bson:"secret,omitempty" json:"-"` // Signs requests with HMAC-SHA256
+	TimeoutMs     int                         `bson:"timeout_ms" json:"timeout_ms"`
+	FailurePolicy PostProcessingFailurePolicy `bson:"failure_policy" json:"failure_policy"`
+	Enabled       bool                        `bson:"enabled" json:"enabled"`
+}
+
+// IsSandbox reports whether traffic for the client on channel runs in sandbox mode:
+// AI calls are stubbed, webhooks go to the sandbox sink and sessions are tagged as test data.
+func (c *Client) IsSandbox(channel *ClientChannel) bool {
+	return (c != nil && c.Sandbox) || (channel != nil && channel.Sandbox)
 }
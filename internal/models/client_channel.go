@@ -1,8 +1,13 @@
 package models
 
 import (
+	"errors"
+	"fmt"
+	"net/mail"
 	"time"
 
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -13,6 +18,11 @@ type ClientChannel struct {
 	ChannelConfig map[string]interface{} `bson:"channel_config" json:"channel_config" validate:"required"`
 	ClientID      primitive.ObjectID     `bson:"client" json:"client_id" validate:"required"`
 	IsActive      bool                  `bson:"is_active" json:"is_active"`
+	Sandbox       bool                  `bson:"sandbox,omitempty" json:"sandbox,omitempty"`
+	Escalation    *EscalationPolicy      `bson:"escalation,omitempty" json:"escalation,omitempty"` // Overrides the client's policy
+	Capabilities  *ChannelCapabilities   `bson:"capabilities,omitempty" json:"capabilities,omitempty"` // Overrides the defaults of the channel type
+	ThreadConfig  *ThreadConfig          `bson:"thread_config,omitempty" json:"thread_config,omitempty"` // Overrides the client's thread_config settings it sets
+	SlackAI       *SlackAIConfig         `bson:"slack_ai,omitempty" json:"slack_ai,omitempty"`       // Slack channels only
 	CreatedAt     time.Time             `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time             `bson:"updated_at" json:"updated_at"`
 }
@@ -38,4 +48,184 @@ func (cc *ClientChannel) BeforeCreate() {
 // BeforeUpdate sets the updated timestamp before updating
 func (cc *ClientChannel) BeforeUpdate() {
 	cc.UpdatedAt = time.Now().UTC()
-}
\ No newline at end of file
+}
+
+// SlackAIConfig sends the replies of a Slack channel through a Slack AI workflow instead of the
+// AI service, so each Slack workspace can use its own workflow and credentials.
+type SlackAIConfig struct {
+	URL        string `bson:"url" json:"url"`
+	WorkflowID string `bson:"workflow_id" json:"workflow_id"`
+	Token      string `bson:"token" json:"-"` // Sealed with the deployment's ENCRYPTION_KEY
+}
+
+// Validate checks that the workflow can be called.
+func (c *SlackAIConfig) Validate() error {
+	if !isHTTPURL(c.URL) {
+		return errors.New("slack_ai.url must be an http or https URL")
+	}
+	if c.WorkflowID == "" {
+		return errors.New("slack_ai.workflow_id is required")
+	}
+	if c.Token == "" {
+		return errors.New("slack_ai.token is required")
+	}
+	return nil
+}
+
+// ChannelCapabilities describes the rich content a channel can render. Attachments a channel
+// can't render are turned into text before replies are dispatched to it.
+type ChannelCapabilities struct {
+	SupportsCarousel bool `bson:"supports_carousel" json:"supports_carousel"`
+	SupportsButtons  bool `bson:"supports_buttons" json:"supports_buttons"`
+	MaxButtons       int  `bson:"max_buttons,omitempty" json:"max_buttons,omitempty"` // 0 means no limit
+	SupportsFiles    bool `bson:"supports_files" json:"supports_files"`                 // Images and files sent as attachments rather than links
+}
+
+// fullChannelCapabilities applies to channel types without a registered entry, such as webhooks
+// whose widget renders whatever it receives.
+var fullChannelCapabilities = ChannelCapabilities{SupportsCarousel: true, SupportsButtons: true, SupportsFiles: true}
+
+// defaultChannelCapabilities is what each native connector can render
+var defaultChannelCapabilities = map[ChannelType]ChannelCapabilities{
+	ChannelTypeSlack:    {SupportsCarousel: true, SupportsButtons: true, MaxButtons: 25, SupportsFiles: true},
+	ChannelTypeSunshine: {SupportsCarousel: true, SupportsButtons: true, SupportsFiles: true},
+	ChannelTypeWhatsApp: {SupportsButtons: true, MaxButtons: 10},
+	ChannelTypeTeams:    {SupportsCarousel: true, SupportsButtons: true, SupportsFiles: true},
+	ChannelTypeEmail:    {SupportsCarousel: true, SupportsButtons: true, SupportsFiles: true},
+	ChannelTypeSMS:      {},
+}
+
+// DefaultChannelCapabilities returns the capabilities of channels of type t that don't override them.
+func DefaultChannelCapabilities(t ChannelType) ChannelCapabilities {
+	if caps, ok := defaultChannelCapabilities[t]; ok {
+		return caps
+	}
+	return fullChannelCapabilities
+}
+
+// EffectiveCapabilities returns the channel's own capabilities, or the defaults of its type.
+func (cc *ClientChannel) EffectiveCapabilities() ChannelCapabilities {
+	if cc.Capabilities != nil {
+		return *cc.Capabilities
+	}
+	return DefaultChannelCapabilities(cc.ChannelType)
+}
+
+// requiredChannelConfig lists the channel_config keys each native connector can't work without
+var requiredChannelConfig = map[ChannelType][]string{
+	ChannelTypeWhatsApp:       {"phone_number_id", "access_token", "app_secret", "verify_token"},
+	ChannelTypeTeams:          {"app_id", "app_password"},
+	ChannelTypeEmail:          {"address", "inbound_token"},
+	ChannelTypeSMS:            {"account_sid", "auth_token", "phone_number"},
+	ChannelTypeSunshine:       {"app_id", "key_id", "secret", "webhook_secret"},
+	ChannelTypeGenericWebhook: {"inbound_token"},
+}
+
+// emailProviderConfig lists the channel_config keys each outbound email provider needs
+var emailProviderConfig = map[string][]string{
+	"smtp":     {"smtp_host"},
+	"sendgrid": {"sendgrid_api_key"},
+}
+
+// WebhookMappingFields are the message fields a generic_webhook channel's channel_config.mapping
+// can fill from the payload. Each is a JSONPath; text and session_key are required.
+var WebhookMappingFields = []string{"text", "session_key", "sender", "sender_name", "external_id", "attachments"}
+
+// ValidateConfig checks that channel_config holds what the native connector of the channel type needs.
+// Types without a native connector accept any config.
+func (cc *ClientChannel) ValidateConfig() error {
+	if cc.Capabilities != nil && cc.Capabilities.MaxButtons < 0 {
+		return errors.New("capabilities.max_buttons must not be negative")
+	}
+	if cc.ThreadConfig != nil {
+		if err := cc.ThreadConfig.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := secrets.ValidateRefs(cc.ChannelConfig); err != nil {
+		return fmt.Errorf("channel_config: %w", err)
+	}
+	if cc.SlackAI != nil {
+		if cc.ChannelType != ChannelTypeSlack {
+			return errors.New("slack_ai is only supported on slack channels")
+		}
+		if err := cc.SlackAI.Validate(); err != nil {
+			return err
+		}
+	}
+	required, ok := requiredChannelConfig[cc.ChannelType]
+	if !ok {
+		return nil
+	}
+	for _, key := range required {
+		if v, _ := cc.ChannelConfig[key].(string); v == "" {
+			return fmt.Errorf("channel_config.%s is required for %s channels", key, cc.ChannelType)
+		}
+	}
+	if cc.ChannelType == ChannelTypeEmail {
+		provider, _ := cc.ChannelConfig["provider"].(string)
+		providerRequired, ok := emailProviderConfig[provider]
+		if !ok {
+			return errors.New("channel_config.provider must be smtp or sendgrid")
+		}
+		for _, key := range providerRequired {
+			if v, _ := cc.ChannelConfig[key].(string); v == "" {
+				return fmt.Errorf("channel_config.%s is required for the %s provider", key, provider)
+			}
+		}
+		if _, err := mail.ParseAddress(cc.ChannelConfig["address"].(string)); err != nil {
+			return fmt.Errorf("channel_config.address is not a valid email address: %w", err)
+		}
+	}
+	if cc.ChannelType == ChannelTypeGenericWebhook {
+		if err := validateWebhookMapping(cc.ChannelConfig["mapping"]); err != nil {
+			return err
+		}
+	}
+	if v, ok := cc.ChannelConfig["ai_enabled"]; ok {
+		if _, isBool := v.(bool); !isBool {
+			return errors.New("channel_config.ai_enabled must be a boolean")
+		}
+	}
+	return nil
+}
+
+// validateWebhookMapping checks that every path of a generic_webhook mapping compiles. Extra
+// metadata paths may be given under mapping.metadata, keyed by the name to store them under.
+func validateWebhookMapping(raw interface{}) error {
+	mapping, ok := raw.(map[string]interface{})
+	if !ok {
+		return errors.New("channel_config.mapping is required for generic_webhook channels")
+	}
+	for _, field := range []string{"text", "session_key"} {
+		if v, _ := mapping[field].(string); v == "" {
+			return fmt.Errorf("channel_config.mapping.%s is required", field)
+		}
+	}
+	for _, field := range WebhookMappingFields {
+		path, ok := mapping[field]
+		if !ok {
+			continue
+		}
+		s, isString := path.(string)
+		if !isString {
+			return fmt.Errorf("channel_config.mapping.%s must be a JSONPath string", field)
+		}
+		if _, err := utils.CompileJSONPath(s); err != nil {
+			return fmt.Errorf("channel_config.mapping.%s: %w", field, err)
+		}
+	}
+	if raw, ok := mapping["metadata"]; ok {
+		metadata, isMap := raw.(map[string]interface{})
+		if !isMap {
+			return errors.New("channel_config.mapping.metadata must map names to JSONPaths")
+		}
+		for name, path := range metadata {
+			s, _ := path.(string)
+			if _, err := utils.CompileJSONPath(s); err != nil {
+				return fmt.Errorf("channel_config.mapping.metadata.%s: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Usage metrics metered per client
+const (
+	UsageMetricMessages           = "messages"
+	UsageMetricAICalls            = "ai_calls"
+	UsageMetricAIPromptTokens     = "ai_prompt_tokens"
+	UsageMetricAICompletionTokens = "ai_completion_tokens"
+	UsageMetricWebhookDeliveries  = "webhook_deliveries"
+)
+
+// ClientUsage counts what a client used in one calendar month (UTC), in total and per day.
+type ClientUsage struct {
+	ID     primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	Client primitive.ObjectID `bson:"client" json:"client"`
+	Period string             `bson:"period" json:"period"` // Month as YYYY-MM
+	// Totals and Daily map metrics to counts; Daily is keyed by day of the month, "01" to "31"
+	Totals    map[string]int64            `bson:"totals" json:"totals"`
+	Daily     map[string]map[string]int64 `bson:"daily,omitempty" json:"daily,omitempty"`
+	UpdatedAt time.Time                   `bson:"updated_at" json:"updated_at"`
+	// ReportedDays lists the days whose usage_report event was published
+	ReportedDays []string `bson:"reported_days,omitempty" json:"-"`
+}
+
+// TableName returns the collection name for ClientUsage
+func (ClientUsage) TableName() string {
+	return "client_usage"
+}
+
+// UsageQuota caps a client's monthly messages and AI calls. Past a soft limit usage carries on
+// and is reported; at a hard limit new messages are rejected and AI calls skipped.
+type UsageQuota struct {
+	Messages QuotaLimit `bson:"messages" json:"messages"`
+	AICalls  QuotaLimit `bson:"ai_calls" json:"ai_calls"`
+}
+
+// QuotaLimit is a monthly limit on one metric. Zero means no limit.
+type QuotaLimit struct {
+	Soft int64 `bson:"soft,omitempty" json:"soft,omitempty"`
+	Hard int64 `bson:"hard,omitempty" json:"hard,omitempty"`
+}
+
+// Limit returns the limit on metric; a nil quota limits nothing.
+func (q *UsageQuota) Limit(metric string) QuotaLimit {
+	if q == nil {
+		return QuotaLimit{}
+	}
+	switch metric {
+	case UsageMetricMessages:
+		return q.Messages
+	case UsageMetricAICalls:
+		return q.AICalls
+	}
+	return QuotaLimit{}
+}
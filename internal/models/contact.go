@@ -0,0 +1,127 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Contact is an end user of a client: the person on the other side of the client's sessions,
+// known on each channel by the channel's own ID for them. Messages from the user and the sessions
+// they start are linked to the contact, which gives the user's history across sessions.
+type Contact struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ClientID   primitive.ObjectID `bson:"client" json:"client_id"`
+	Identities []ContactIdentity  `bson:"identities,omitempty" json:"identities"`
+	// IdentityKeys is derived from Identities on every write; a unique index on it keeps an
+	// identity from belonging to two contacts of a client
+	IdentityKeys     []string            `bson:"identity_keys,omitempty" json:"-"`
+	Name             string              `bson:"name,omitempty" json:"name,omitempty"`
+	Locale           string              `bson:"locale,omitempty" json:"locale,omitempty"`
+	Attributes       map[string]string   `bson:"attributes,omitempty" json:"attributes,omitempty"`
+	Consent          map[string]bool     `bson:"consent,omitempty" json:"consent,omitempty"` // Keyed by purpose, such as ConsentMarketing
+	ConsentUpdatedAt *time.Time          `bson:"consent_updated_at,omitempty" json:"consent_updated_at,omitempty"`
+	MergedInto       *primitive.ObjectID `bson:"merged_into,omitempty" json:"merged_into,omitempty"`
+	LastSeenAt       *time.Time          `bson:"last_seen_at,omitempty" json:"last_seen_at,omitempty"`
+	CreatedAt        time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Consent purposes the service enforces. A contact opts out of a purpose with a false flag; a
+// purpose without a flag is allowed.
+const (
+	ConsentMarketing    = "marketing"     // CSAT surveys
+	ConsentTranscripts  = "transcripts"   // Left to downstream systems, which get the flags in webhook payloads
+	ConsentAIProcessing = "ai_processing" // AI answers and suggestions
+)
+
+// OptedOut reports whether the contact has opted out of purpose.
+func (c *Contact) OptedOut(purpose string) bool {
+	granted, ok := c.Consent[purpose]
+	return ok && !granted
+}
+
+// ContactIdentity is how a channel identifies a contact: the sender of the contact's messages on it.
+type ContactIdentity struct {
+	ChannelID  primitive.ObjectID `bson:"channel" json:"channel_id"`
+	ExternalID string             `bson:"external_id" json:"external_id"`
+}
+
+// Key returns the identity as stored in Contact.IdentityKeys.
+func (i ContactIdentity) Key() string {
+	return i.ChannelID.Hex() + ":" + i.ExternalID
+}
+
+// TableName returns the collection name for Contact
+func (Contact) TableName() string {
+	return "contacts"
+}
+
+// BeforeCreate sets timestamps before creating
+func (c *Contact) BeforeCreate() {
+	now := time.Now().UTC()
+	c.CreatedAt = now
+	c.UpdatedAt = now
+	c.IdentityKeys = IdentityKeys(c.Identities)
+	if len(c.Consent) > 0 {
+		c.ConsentUpdatedAt = &now
+	}
+}
+
+// IdentityKeys returns the keys of identities.
+func IdentityKeys(identities []ContactIdentity) []string {
+	keys := make([]string, 0, len(identities))
+	for _, identity := range identities {
+		keys = append(keys, identity.Key())
+	}
+	return keys
+}
+
+// ValidateIdentities checks that every identity names a channel and an ID, once.
+func ValidateIdentities(identities []ContactIdentity) error {
+	seen := map[string]bool{}
+	for _, identity := range identities {
+		if identity.ChannelID.IsZero() || identity.ExternalID == "" {
+			return errors.New("identities need a channel_id and an external_id")
+		}
+		if seen[identity.Key()] {
+			return errors.New("identities must not repeat")
+		}
+		seen[identity.Key()] = true
+	}
+	return nil
+}
+
+// Bounds of ContactHistoryPolicy
+const (
+	DefaultContactHistorySessions = 3
+	MaxContactHistorySessions     = 10
+	DefaultContactHistoryAgeDays  = 90
+)
+
+// ContactHistoryPolicy bounds the history of earlier sessions the AI is given for a returning
+// contact: the recaps of up to MaxSessions sessions started in the last MaxAgeDays days.
+type ContactHistoryPolicy struct {
+	Enabled     bool `bson:"enabled" json:"enabled"`
+	MaxSessions int  `bson:"max_sessions" json:"max_sessions"`
+	MaxAgeDays  int  `bson:"max_age_days" json:"max_age_days"`
+}
+
+// Validate fills in the default bounds and checks the ones that are set.
+func (p *ContactHistoryPolicy) Validate() error {
+	if p.MaxSessions == 0 {
+		p.MaxSessions = DefaultContactHistorySessions
+	}
+	if p.MaxAgeDays == 0 {
+		p.MaxAgeDays = DefaultContactHistoryAgeDays
+	}
+	if p.MaxSessions < 0 || p.MaxSessions > MaxContactHistorySessions {
+		return fmt.Errorf("max_sessions must be between 1 and %d", MaxContactHistorySessions)
+	}
+	if p.MaxAgeDays < 0 {
+		return errors.New("max_age_days must not be negative")
+	}
+	return nil
+}
@@ -15,6 +15,12 @@ type CSATConfiguration struct {
 	Type             string                 `bson:"type" json:"type" validate:"required"`
 	Enabled          bool                   `bson:"enabled" json:"enabled"`
 	TriggerConditions map[string]interface{} `bson:"trigger_conditions,omitempty" json:"trigger_conditions,omitempty"`
+	// ReminderMinutes re-sends an unanswered question after this many minutes; 0 disables reminders
+	ReminderMinutes  int                    `bson:"reminder_minutes,omitempty" json:"reminder_minutes,omitempty"`
+	// ExpiryHours closes a survey still unfinished this many hours after it was triggered; 0 never expires
+	ExpiryHours      int                    `bson:"expiry_hours,omitempty" json:"expiry_hours,omitempty"`
+	// FallbackLocales are tried in order when a question has no translation for the session's language
+	FallbackLocales  []string               `bson:"fallback_locales,omitempty" json:"fallback_locales,omitempty"`
 	CreatedAt        time.Time              `bson:"created_at" json:"created_at"`
 	UpdatedAt        time.Time              `bson:"updated_at" json:"updated_at"`
 }
@@ -15,10 +15,72 @@ type CSATQuestionTemplate struct {
 	Options              []string           `bson:"options" json:"options" validate:"required"`
 	Order                int                `bson:"order" json:"order" validate:"required"`
 	Active               bool               `bson:"active" json:"active"`
+	AnswerType           CSATAnswerType     `bson:"answer_type,omitempty" json:"answer_type,omitempty"`
+	MaxLength            int                `bson:"max_length,omitempty" json:"max_length,omitempty"` // free_text only; 0 uses the default
+	Branches             []CSATBranchRule   `bson:"branches,omitempty" json:"branches,omitempty"`
+	// Translations holds the question in other languages, keyed by locale such as "fr" or "pt-BR"
+	Translations         map[string]CSATQuestionTranslation `bson:"translations,omitempty" json:"translations,omitempty"`
 	CreatedAt            time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt            time.Time          `bson:"updated_at" json:"updated_at"`
 }
 
+// CSATQuestionTranslation is a question's text and options in one locale. Options map to the
+// question's own options by position, so answers are stored in the untranslated form; when
+// empty, the untranslated options are shown.
+type CSATQuestionTranslation struct {
+	QuestionText string   `bson:"question_text" json:"question_text"`
+	Options      []string `bson:"options,omitempty" json:"options,omitempty"`
+}
+
+// CSATAnswerType decides which answers a CSAT question accepts. Questions without one accept any value.
+type CSATAnswerType string
+
+const (
+	CSATAnswerTypeRating         CSATAnswerType = "rating"          // whole number from 1 to 5
+	CSATAnswerTypeNPS            CSATAnswerType = "nps"             // whole number from 0 to 10
+	CSATAnswerTypeMultipleChoice CSATAnswerType = "multiple_choice" // one of the question's options
+	CSATAnswerTypeFreeText       CSATAnswerType = "free_text"       // any non-empty text up to MaxLength
+)
+
+// Score ranges of the numeric answer types.
+const (
+	CSATRatingMin = 1
+	CSATRatingMax = 5
+	CSATNPSMin    = 0
+	CSATNPSMax    = 10
+)
+
+// CSATFreeTextDefaultMaxLength caps free_text answers whose question sets no max_length.
+const CSATFreeTextDefaultMaxLength = 1000
+
+// CSATBranchOperator compares a CSAT answer with a branch rule's value.
+type CSATBranchOperator string
+
+const (
+	CSATBranchOperatorEq  CSATBranchOperator = "eq"
+	CSATBranchOperatorNeq CSATBranchOperator = "neq"
+	CSATBranchOperatorLt  CSATBranchOperator = "lt"
+	CSATBranchOperatorLte CSATBranchOperator = "lte"
+	CSATBranchOperatorGt  CSATBranchOperator = "gt"
+	CSATBranchOperatorGte CSATBranchOperator = "gte"
+	CSATBranchOperatorIn  CSATBranchOperator = "in"
+	// CSATBranchOperatorAny matches every answer and acts as the else branch
+	CSATBranchOperatorAny CSATBranchOperator = "any"
+)
+
+// CSATBranchRule picks the question asked after an answer. Rules are evaluated in order and the
+// first match wins; when none matches the survey continues with the next question by order.
+// Targets are referenced by order because question IDs change whenever the set is replaced.
+type CSATBranchRule struct {
+	Operator CSATBranchOperator `bson:"operator" json:"operator"`
+	// Value is compared numerically by lt, lte, gt and gte, and case-insensitively by eq and neq
+	Value  string   `bson:"value,omitempty" json:"value,omitempty"`
+	Values []string `bson:"values,omitempty" json:"values,omitempty"`
+	// NextOrder is the order of the question to ask next; ignored when End is set
+	NextOrder int  `bson:"next_order,omitempty" json:"next_order,omitempty"`
+	End       bool `bson:"end,omitempty" json:"end,omitempty"`
+}
+
 // TableName returns the MongoDB collection name for CSATQuestionTemplate.
 func (CSATQuestionTemplate) TableName() string {
 	return "csat_question_templates"
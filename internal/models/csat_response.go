@@ -13,9 +13,15 @@ type CSATResponse struct {
 	CSATSession      primitive.ObjectID `bson:"csat_session" json:"csat_session" validate:"required"`
 	QuestionTemplate primitive.ObjectID `bson:"question_template" json:"question_template" validate:"required"`
 	ResponseValue    string             `bson:"response_value" json:"response_value" validate:"required"`
-	RespondedAt      time.Time          `bson:"responded_at" json:"responded_at"`
-	CreatedAt        time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+	// NormalizedValue is the answer in canonical form: the score for rating and nps questions,
+	// the option as configured for multiple_choice and the trimmed text otherwise
+	NormalizedValue string                `bson:"normalized_value,omitempty" json:"normalized_value,omitempty"`
+	Score           *float64              `bson:"score,omitempty" json:"score,omitempty"` // rating and nps answers only
+	AnswerType      CSATAnswerType        `bson:"answer_type,omitempty" json:"answer_type,omitempty"`
+	Experiment      *ExperimentAssignment `bson:"experiment,omitempty" json:"experiment,omitempty"` // variant the chat session was in
+	RespondedAt     time.Time             `bson:"responded_at" json:"responded_at"`
+	CreatedAt       time.Time             `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time             `bson:"updated_at" json:"updated_at"`
 }
 
 // TableName returns the MongoDB collection name for CSATResponse.
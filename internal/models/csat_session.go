@@ -16,9 +16,12 @@ type CSATSession struct {
 	ClientChannel        primitive.ObjectID     `bson:"client_channel" json:"client_channel" validate:"required"`
 	ThreadSessionID      *string                `bson:"thread_session_id,omitempty" json:"thread_session_id,omitempty"`
 	ThreadContext        bool                   `bson:"thread_context" json:"thread_context"`
-	Status               string                 `bson:"status" json:"status"` // "pending", "in_progress", "completed", "abandoned"
+	Locale               string                 `bson:"locale,omitempty" json:"locale,omitempty"` // chat session's language when the survey was triggered
+	Status               string                 `bson:"status" json:"status"` // "pending", "in_progress", "completed", "abandoned", "expired"
 	TriggeredAt          time.Time              `bson:"triggered_at" json:"triggered_at"`
 	CompletedAt          *time.Time             `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	ReminderSentAt       *time.Time             `bson:"reminder_sent_at,omitempty" json:"reminder_sent_at,omitempty"`
+	ExpiredAt            *time.Time             `bson:"expired_at,omitempty" json:"expired_at,omitempty"`
 	CurrentQuestionIndex int                    `bson:"current_question_index" json:"current_question_index"`
 	QuestionsSent        []primitive.ObjectID   `bson:"questions_sent" json:"questions_sent"`
 	CreatedAt            time.Time              `bson:"created_at" json:"created_at"`
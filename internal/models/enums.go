@@ -22,6 +22,8 @@ const (
 type ExecutionStatus string
 
 const (
+	ExecutionStatusPending   ExecutionStatus = "pending"
+	ExecutionStatusRunning   ExecutionStatus = "running"
 	ExecutionStatusCompleted ExecutionStatus = "completed"
 	ExecutionStatusFailed    ExecutionStatus = "failed"
 )
@@ -33,6 +35,12 @@ const (
 	ChannelTypeWebhook  ChannelType = "webhook"
 	ChannelTypeSlack    ChannelType = "slack"
 	ChannelTypeSunshine ChannelType = "sunshine"
+	ChannelTypeWhatsApp ChannelType = "whatsapp"
+	ChannelTypeTeams    ChannelType = "teams"
+	ChannelTypeEmail    ChannelType = "email"
+	ChannelTypeSMS      ChannelType = "sms"
+	// ChannelTypeGenericWebhook accepts any JSON payload, mapped to messages with JSONPath expressions
+	ChannelTypeGenericWebhook ChannelType = "generic_webhook"
 )
 
 // EventType represents the type of system event
@@ -40,29 +48,63 @@ type EventType string
 
 const (
 	// Chat Session Events
-	EventTypeChatSessionCreated  EventType = "chat_session_created"
-	EventTypeChatSessionInactive EventType = "chat_session_inactive"
+	EventTypeChatSessionCreated      EventType = "chat_session_created"
+	EventTypeChatSessionInactive     EventType = "chat_session_inactive"
+	EventTypeSessionStateChanged     EventType = "session_state_changed"
+	EventTypeSessionReopened         EventType = "session_reopened"
+	EventTypeChatSessionRecapCreated EventType = "chat_session_recap_created"
+	EventTypeThreadClosed            EventType = "thread_closed"
+	EventTypeThreadMerged            EventType = "thread_merged"
+	EventTypeThreadSplit             EventType = "thread_split"
+	EventTypeMessagesImported        EventType = "messages_imported"
+
+	// Handover Events
+	EventTypeHandoverRequested EventType = "handover_requested"
+	EventTypeHandoverAssigned  EventType = "handover_assigned"
+	EventTypeHandoverAccepted  EventType = "handover_accepted"
+	EventTypeHandoverDeclined  EventType = "handover_declined"
+	EventTypeHandoverCompleted EventType = "handover_completed"
 
 	// Chat Message Events
-	EventTypeChatMessageCreated EventType = "chat_message_created"
+	EventTypeChatMessageCreated         EventType = "chat_message_created"
+	EventTypeChatMessageDeliveryUpdated EventType = "chat_message_delivery_updated"
 
 	// Chat Workflow Events
-	EventTypeChatWorkflowProcessing EventType = "chat_workflow_processing"
-	EventTypeChatWorkflowCompleted  EventType = "chat_workflow_completed"
-	EventTypeChatWorkflowError      EventType = "chat_workflow_error"
-	EventTypeChatWorkflowHandover   EventType = "chat_workflow_handover"
+	EventTypeChatWorkflowProcessing    EventType = "chat_workflow_processing"
+	EventTypeChatWorkflowCompleted     EventType = "chat_workflow_completed"
+	EventTypeChatWorkflowError         EventType = "chat_workflow_error"
+	EventTypeChatWorkflowHandover      EventType = "chat_workflow_handover"
+	EventTypeChatWorkflowVetoed        EventType = "chat_workflow_vetoed"
+	EventTypeChatWorkflowRouted        EventType = "chat_workflow_routed"
+	EventTypeChatWorkflowModerated     EventType = "chat_workflow_moderated"
+	EventTypeChatWorkflowEscalated     EventType = "chat_workflow_escalated"
+	EventTypeChatWorkflowQuotaExceeded EventType = "chat_workflow_quota_exceeded"
+	EventTypeChatWorkflowOptedOut      EventType = "chat_workflow_opted_out"
 
 	// Chat Message Suggestion Events
-	EventTypeChatSuggestionCreated EventType = "chat_suggestion_created"
+	EventTypeChatSuggestionCreated  EventType = "chat_suggestion_created"
+	EventTypeChatSuggestionAccepted EventType = "chat_suggestion_accepted"
+	EventTypeChatSuggestionRejected EventType = "chat_suggestion_rejected"
 
 	// AI Service Events
 	EventTypeAIRequestSent     EventType = "ai_request_sent"
 	EventTypeAIResponseReceived EventType = "ai_response_received"
 
+	// Usage Events
+	EventTypeUsageReport EventType = "usage_report"
+
+	// Report Events
+	EventTypeAnalyticsReport EventType = "analytics_report"
+
+	// Processor Test Events
+	EventTypeWebhookTest EventType = "webhook_test"
+
 	// CSAT Events
 	EventTypeCSATTriggered    EventType = "csat_triggered"
 	EventTypeCSATMessageSent  EventType = "csat_message_sent"
 	EventTypeCSATCompleted    EventType = "csat_completed"
+	EventTypeCSATReminderSent EventType = "csat_reminder_sent"
+	EventTypeCSATExpired      EventType = "csat_expired"
 )
 
 // EntityType represents the type of entity in events
@@ -76,8 +118,27 @@ const (
 	EntityTypeCSATSession   EntityType = "csat_session"
 	EntityTypeCSATQuestion  EntityType = "csat_question"
 	EntityTypeCSATResponse  EntityType = "csat_response"
+	EntityTypeClient        EntityType = "client"
 )
 
+// EntityTypes returns every entity type events are published on.
+func EntityTypes() []EntityType {
+	return []EntityType{
+		EntityTypeChatSession, EntityTypeChatMessage, EntityTypeChatSuggestion, EntityTypeAIService,
+		EntityTypeCSATSession, EntityTypeCSATQuestion, EntityTypeCSATResponse, EntityTypeClient,
+	}
+}
+
+// IsValid reports whether t is a known entity type.
+func (t EntityType) IsValid() bool {
+	switch t {
+	case EntityTypeChatSession, EntityTypeChatMessage, EntityTypeChatSuggestion, EntityTypeAIService,
+		EntityTypeCSATSession, EntityTypeCSATQuestion, EntityTypeCSATResponse, EntityTypeClient:
+		return true
+	}
+	return false
+}
+
 // DeliveryStatus represents the status of event delivery
 type DeliveryStatus string
 
@@ -94,6 +155,12 @@ type ProcessorType string
 const (
 	ProcessorTypeHTTPWebhook ProcessorType = "http_webhook"
 	ProcessorTypeAMQP        ProcessorType = "amqp"
+	ProcessorTypeSlack       ProcessorType = "slack"    // Posts replies to Slack using the session's slack channel config
+	ProcessorTypeWhatsApp    ProcessorType = "whatsapp" // Sends replies through the WhatsApp Cloud API of the session's channel
+	ProcessorTypeTeams       ProcessorType = "teams"    // Sends replies to the Teams conversation through the Bot Framework
+	ProcessorTypeEmail       ProcessorType = "email"    // Emails replies through the SMTP server or API of the session's email channel
+	ProcessorTypeSMS         ProcessorType = "sms"      // Texts replies through the Twilio account of the session's sms channel
+	ProcessorTypeSunshine    ProcessorType = "sunshine" // Sends replies to Sunshine Conversations and passes control on handover
 )
 
 // AttemptStatus represents the status of a delivery attempt
@@ -102,4 +169,108 @@ type AttemptStatus string
 const (
 	AttemptStatusSuccess AttemptStatus = "success"
 	AttemptStatusFailure AttemptStatus = "failure"
-)
\ No newline at end of file
+)
+
+// RepairActionType represents an operator repair action
+type RepairActionType string
+
+const (
+	RepairActionRequeueFailedDeliveries RepairActionType = "requeue_failed_deliveries"
+	RepairActionResetStuckDeliveries    RepairActionType = "reset_stuck_deliveries"
+	RepairActionCloseOrphanThreads      RepairActionType = "close_orphan_threads"
+)
+
+// ScheduledMessageStatus represents the lifecycle of a scheduled message
+type ScheduledMessageStatus string
+
+const (
+	ScheduledMessageStatusPending   ScheduledMessageStatus = "pending"
+	ScheduledMessageStatusSending   ScheduledMessageStatus = "sending"
+	ScheduledMessageStatusSent      ScheduledMessageStatus = "sent"
+	ScheduledMessageStatusCancelled ScheduledMessageStatus = "cancelled"
+	ScheduledMessageStatusFailed    ScheduledMessageStatus = "failed"
+)
+
+// HandoverStatus represents the state of a session handover to a human agent
+type HandoverStatus string
+
+const (
+	HandoverStatusRequested HandoverStatus = "requested"
+	HandoverStatusAccepted  HandoverStatus = "accepted"
+	HandoverStatusDeclined  HandoverStatus = "declined"
+	HandoverStatusCompleted HandoverStatus = "completed"
+)
+
+// SessionState represents where a chat session is in its lifecycle
+type SessionState string
+
+const (
+	SessionStateOpen     SessionState = "open"
+	SessionStatePending  SessionState = "pending"  // Waiting on the end user
+	SessionStateSnoozed  SessionState = "snoozed"  // Parked until snoozed_until
+	SessionStateClosed   SessionState = "closed"
+	SessionStateReopened SessionState = "reopened"
+)
+
+// ClosedSessionPolicy decides what happens to a message sent to a closed session
+type ClosedSessionPolicy string
+
+const (
+	ClosedSessionPolicyReject    ClosedSessionPolicy = "reject"
+	ClosedSessionPolicyReopen    ClosedSessionPolicy = "reopen"
+	ClosedSessionPolicyNewThread ClosedSessionPolicy = "new_thread"
+)
+
+// AgentStatus represents a human agent's availability for handovers
+type AgentStatus string
+
+const (
+	AgentStatusAvailable AgentStatus = "available"
+	AgentStatusBusy      AgentStatus = "busy"
+	AgentStatusOffline   AgentStatus = "offline"
+)
+
+// AssignmentStrategy represents how an agent is picked among eligible candidates
+type AssignmentStrategy string
+
+const (
+	AssignmentStrategyRoundRobin  AssignmentStrategy = "round_robin"
+	AssignmentStrategyLeastBusy   AssignmentStrategy = "least_busy"
+	AssignmentStrategySkillsMatch AssignmentStrategy = "skills_match"
+)
+
+// PostProcessingFailurePolicy decides what happens to an AI response when the post-processing hook
+// times out, errors or replies with something unusable
+type PostProcessingFailurePolicy string
+
+const (
+	PostProcessingFailOpen   PostProcessingFailurePolicy = "open"   // Keep the original response
+	PostProcessingFailClosed PostProcessingFailurePolicy = "closed" // Drop the response as if vetoed
+)
+
+// PostProcessingAction is a post-processing hook's verdict on an AI response
+type PostProcessingAction string
+
+const (
+	PostProcessingActionAllow  PostProcessingAction = "allow"
+	PostProcessingActionModify PostProcessingAction = "modify"
+	PostProcessingActionVeto   PostProcessingAction = "veto"
+)
+
+// IntentAction is what the chat workflow does with a message whose intent matched a routing rule
+type IntentAction string
+
+const (
+	IntentActionAIAnswer IntentAction = "ai_answer" // Answer with the AI as usual
+	IntentActionSkipBot  IntentAction = "skip_bot"  // Store the message without an AI answer
+	IntentActionHandover IntentAction = "handover"  // Skip the AI and request a handover to a human agent
+)
+
+// SuggestionStatus is an agent's verdict on an AI suggestion
+type SuggestionStatus string
+
+const (
+	SuggestionStatusPending  SuggestionStatus = "pending"
+	SuggestionStatusAccepted SuggestionStatus = "accepted"
+	SuggestionStatusRejected SuggestionStatus = "rejected"
+)
@@ -8,14 +8,82 @@ import (
 
 // Event represents a system event with parent-child relationships
 type Event struct {
-	ID         primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
-	EventType  EventType             `bson:"event_type" json:"event_type" validate:"required"`
-	EntityType EntityType            `bson:"entity_type" json:"entity_type" validate:"required"`
-	EntityID   string                `bson:"entity_id" json:"entity_id" validate:"required"`
-	ParentID   string                `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
-	Data       map[string]interface{} `bson:"data" json:"data"`
-	CreatedAt  time.Time             `bson:"created_at" json:"created_at"`
-	UpdatedAt  time.Time             `bson:"updated_at" json:"updated_at"`
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	EventType  EventType          `bson:"event_type" json:"event_type" validate:"required"`
+	EntityType EntityType         `bson:"entity_type" json:"entity_type" validate:"required"`
+	EntityID   string             `bson:"entity_id" json:"entity_id" validate:"required"`
+	ParentID   string             `bson:"parent_id,omitempty" json:"parent_id,omitempty"`
+	RequestID  string             `bson:"request_id,omitempty" json:"request_id,omitempty"` // Request that caused the event
+	// ClientID is the client the event concerns, when it could be told at publish time
+	ClientID *primitive.ObjectID `bson:"client,omitempty" json:"client_id,omitempty"`
+	// SchemaVersion is the version of the event type's schema the data was published with; 0 for
+	// events published before schemas were versioned
+	SchemaVersion int                    `bson:"schema_version,omitempty" json:"schema_version,omitempty"`
+	Producer      string                 `bson:"producer,omitempty" json:"producer,omitempty"` // Process that published the event
+	Data          map[string]interface{} `bson:"data" json:"data"`
+	CreatedAt     time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time              `bson:"updated_at" json:"updated_at"`
+}
+
+// DefaultEventProducer is the producer of events published by a process that doesn't name itself.
+const DefaultEventProducer = "api-service"
+
+// EventEnvelope is the form in which events are handed to processors and webhooks. Consumers
+// should check SchemaVersion before reading Data, which has the schema of EventType at that version.
+type EventEnvelope struct {
+	SchemaVersion int                    `json:"schema_version"`
+	EventID       string                 `json:"event_id"`
+	EventType     EventType              `json:"event_type"`
+	EntityType    EntityType             `json:"entity_type"`
+	EntityID      string                 `json:"entity_id"`
+	ParentID      string                 `json:"parent_id"`
+	OccurredAt    time.Time              `json:"occurred_at"`
+	Producer      string                 `json:"producer"`
+	RequestID     string                 `json:"request_id,omitempty"`
+	Data          map[string]interface{} `json:"data"`
+}
+
+// Envelope returns the envelope of the event.
+func (e *Event) Envelope() EventEnvelope {
+	producer := e.Producer
+	if producer == "" {
+		producer = DefaultEventProducer
+	}
+	return EventEnvelope{
+		SchemaVersion: e.SchemaVersion,
+		EventID:       e.ID.Hex(),
+		EventType:     e.EventType,
+		EntityType:    e.EntityType,
+		EntityID:      e.EntityID,
+		ParentID:      e.ParentID,
+		OccurredAt:    e.CreatedAt,
+		Producer:      producer,
+		RequestID:     e.RequestID,
+		Data:          e.Data,
+	}
+}
+
+// Map returns the envelope as the map processors are dispatched, so delivery-specific fields can
+// be added next to it.
+func (e EventEnvelope) Map() map[string]interface{} {
+	m := map[string]interface{}{
+		"schema_version": e.SchemaVersion,
+		"event_id":       e.EventID,
+		"event_type":     e.EventType,
+		"entity_type":    e.EntityType,
+		"entity_id":      e.EntityID,
+		"occurred_at":    e.OccurredAt.Format(time.RFC3339Nano),
+		"producer":       e.Producer,
+		"parent_id":      nil,
+		"data":           e.Data,
+	}
+	if e.ParentID != "" {
+		m["parent_id"] = e.ParentID
+	}
+	if e.RequestID != "" {
+		m["request_id"] = e.RequestID
+	}
+	return m
 }
 
 // TableName returns the collection name for Event
@@ -39,4 +107,4 @@ func (e *Event) BeforeCreate() {
 // BeforeUpdate sets the updated timestamp before updating
 func (e *Event) BeforeUpdate() {
 	e.UpdatedAt = time.Now().UTC()
-}
\ No newline at end of file
+}
@@ -15,10 +15,33 @@ type EventDelivery struct {
 	MaxAttempts            int                   `bson:"max_attempts" json:"max_attempts"`
 	CurrentAttempts        int                   `bson:"current_attempts" json:"current_attempts"`
 	RequestPayload         map[string]interface{} `bson:"request_payload,omitempty" json:"request_payload,omitempty"`
+	NextRetryAt            *time.Time            `bson:"next_retry_at,omitempty" json:"next_retry_at,omitempty"`
+	RetryPolicy            *DeliveryRetryPolicy  `bson:"retry_policy,omitempty" json:"retry_policy,omitempty"`
 	CreatedAt              time.Time             `bson:"created_at" json:"created_at"`
 	UpdatedAt              time.Time             `bson:"updated_at" json:"updated_at"`
 }
 
+// DeliveryRetryPolicy describes the backoff applied between delivery attempts
+type DeliveryRetryPolicy struct {
+	Strategy         string `bson:"strategy" json:"strategy"` // "exponential"
+	BaseDelaySeconds int    `bson:"base_delay_seconds" json:"base_delay_seconds"`
+	MaxRetries       int    `bson:"max_retries" json:"max_retries"`
+}
+
+// DefaultDeliveryRetryPolicy returns the policy used by the worker for deliver_to_processor tasks (60s, 120s, 240s)
+func DefaultDeliveryRetryPolicy() DeliveryRetryPolicy {
+	return DeliveryRetryPolicy{
+		Strategy:         "exponential",
+		BaseDelaySeconds: 60,
+		MaxRetries:       3,
+	}
+}
+
+// Backoff returns the delay before the given retry (zero-based)
+func (p DeliveryRetryPolicy) Backoff(retry int) time.Duration {
+	return time.Duration(p.BaseDelaySeconds*(1<<retry)) * time.Second
+}
+
 // TableName returns the collection name for EventDelivery
 func (EventDelivery) TableName() string {
 	return "event_deliveries"
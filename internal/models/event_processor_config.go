@@ -1,9 +1,13 @@
 package models
 
 import (
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
+	"github.com/fraiday-org/api-service/internal/secrets"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -19,6 +23,7 @@ type EventProcessorConfig struct {
 	EventTypes    []EventType           `bson:"event_types" json:"event_types"`           // Which events this processor handles
 	EntityTypes   []EntityType          `bson:"entity_types" json:"entity_types"`         // Which entity types this processor handles
 	IsActive      bool                  `bson:"is_active" json:"is_active"`
+	Version       int64                 `bson:"version,omitempty" json:"version"` // Goes up with every update
 	CreatedAt     time.Time             `bson:"created_at" json:"created_at"`
 	UpdatedAt     time.Time             `bson:"updated_at" json:"updated_at"`
 }
@@ -58,11 +63,50 @@ type BaseProcessorConfig struct {
 	// Common fields can be added here if needed
 }
 
+// MaxWebhookTimeout is the longest timeout, in seconds, a webhook processor may set
+const MaxWebhookTimeout = 300
+
 // HttpWebhookConfig represents HTTP webhook processor configuration.
 type HttpWebhookConfig struct {
 	WebhookURL string            `json:"webhook_url" bson:"webhook_url"`
 	Headers    map[string]string `json:"headers" bson:"headers"`
 	Timeout    int               `json:"timeout" bson:"timeout"` // in seconds
+	TLS        *WebhookTLSConfig `json:"tls,omitempty" bson:"tls,omitempty"`
+	// ProxyURL, when set, replaces the deployment's dispatch proxy for this webhook
+	ProxyURL string `json:"proxy_url,omitempty" bson:"proxy_url,omitempty"`
+}
+
+// WebhookTLSConfig configures the TLS connection to a webhook endpoint that requires mutual TLS
+// or is served with a certificate from a private CA.
+type WebhookTLSConfig struct {
+	// ClientCert is the PEM client certificate chain, or a secret reference to it
+	ClientCert string `json:"client_cert,omitempty" bson:"client_cert,omitempty"`
+	// ClientKey is a secret reference to the PEM private key of ClientCert
+	ClientKey string `json:"client_key,omitempty" bson:"client_key,omitempty"`
+	// CABundle holds PEM certificates trusted in addition to the system roots, or a secret reference to them
+	CABundle string `json:"ca_bundle,omitempty" bson:"ca_bundle,omitempty"`
+	// InsecureSkipVerify turns off verification of the endpoint's certificate; only admins may set it
+	InsecureSkipVerify bool `json:"insecure_skip_verify,omitempty" bson:"insecure_skip_verify,omitempty"`
+}
+
+// Validate checks that the client certificate and key come together, that the key is kept in a
+// secrets store rather than in the config, and that inline certificates are PEM.
+func (c *WebhookTLSConfig) Validate() error {
+	if (c.ClientCert == "") != (c.ClientKey == "") {
+		return errors.New("tls.client_cert and tls.client_key must be set together")
+	}
+	if c.ClientKey != "" && !secrets.IsRef(c.ClientKey) {
+		return fmt.Errorf("tls.client_key must be a %s reference", secrets.Scheme)
+	}
+	for field, value := range map[string]string{"client_cert": c.ClientCert, "ca_bundle": c.CABundle} {
+		if value == "" || secrets.IsRef(value) {
+			continue
+		}
+		if block, _ := pem.Decode([]byte(value)); block == nil || block.Type != "CERTIFICATE" {
+			return fmt.Errorf("tls.%s must be PEM certificates or a %s reference", field, secrets.Scheme)
+		}
+	}
+	return nil
 }
 
 // AmqpConfig represents AMQP processor configuration.
@@ -74,6 +118,21 @@ type AmqpConfig struct {
 	RoutingKey string  `json:"routing_key" bson:"routing_key"`
 	Username   *string `json:"username,omitempty" bson:"username,omitempty"`
 	Password   *string `json:"password,omitempty" bson:"password,omitempty"`
+	// Batch, when set, publishes events in batches with publisher confirms
+	Batch *AmqpBatchConfig `json:"batch,omitempty" bson:"batch,omitempty"`
+}
+
+// Bounds of AMQP batch settings
+const (
+	MaxAmqpBatchSize          = 1000
+	MaxAmqpBatchFlushInterval = 5 * time.Second
+)
+
+// AmqpBatchConfig groups the events of an AMQP processor into batches of up to MaxSize, published
+// together once full or FlushInterval after the first event.
+type AmqpBatchConfig struct {
+	MaxSize       int           `json:"max_size" bson:"max_size"`
+	FlushInterval time.Duration `json:"flush_interval_ms" bson:"flush_interval_ms"`
 }
 
 // GetHttpWebhookConfig extracts HTTP webhook configuration from the config map.
@@ -94,17 +153,48 @@ func (epc *EventProcessorConfig) GetHttpWebhookConfig() (*HttpWebhookConfig, err
 			}
 		}
 	}
-	if timeout, ok := epc.Config["timeout"].(int); ok {
+	if timeout, ok := configInt(epc.Config["timeout"]); ok {
 		config.Timeout = timeout
-	} else if timeout, ok := epc.Config["timeout"].(float64); ok {
-		config.Timeout = int(timeout)
 	} else {
 		config.Timeout = 10 // default timeout
 	}
+	if proxyURL, ok := epc.Config["proxy_url"].(string); ok {
+		config.ProxyURL = proxyURL
+	}
+	if raw, ok := epc.Config["tls"]; ok && raw != nil {
+		tlsMap, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("tls must be an object")
+		}
+		config.TLS = &WebhookTLSConfig{}
+		for field, target := range map[string]*string{
+			"client_cert": &config.TLS.ClientCert,
+			"client_key":  &config.TLS.ClientKey,
+			"ca_bundle":   &config.TLS.CABundle,
+		} {
+			if v, ok := tlsMap[field]; ok {
+				if *target, ok = v.(string); !ok {
+					return nil, fmt.Errorf("tls.%s must be a string", field)
+				}
+			}
+		}
+		if v, ok := tlsMap["insecure_skip_verify"]; ok {
+			if config.TLS.InsecureSkipVerify, ok = v.(bool); !ok {
+				return nil, errors.New("tls.insecure_skip_verify must be a boolean")
+			}
+		}
+	}
 
 	return config, nil
 }
 
+// InsecureTLS reports whether the config turns off verification of a webhook's certificate.
+func (epc *EventProcessorConfig) InsecureTLS() bool {
+	tlsMap, _ := epc.Config["tls"].(map[string]interface{})
+	insecure, _ := tlsMap["insecure_skip_verify"].(bool)
+	return insecure
+}
+
 // GetAmqpConfig extracts AMQP configuration from the config map.
 func (epc *EventProcessorConfig) GetAmqpConfig() (*AmqpConfig, error) {
 	if epc.ProcessorType != ProcessorTypeAMQP {
@@ -139,19 +229,86 @@ func (epc *EventProcessorConfig) GetAmqpConfig() (*AmqpConfig, error) {
 	if password, ok := epc.Config["password"].(string); ok {
 		config.Password = &password
 	}
+	if raw, ok := epc.Config["batch"]; ok && raw != nil {
+		batch, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, errors.New("batch must be an object")
+		}
+		config.Batch = &AmqpBatchConfig{MaxSize: 100, FlushInterval: 50 * time.Millisecond}
+		if v, ok := batch["max_size"]; ok {
+			n, ok := configInt(v)
+			if !ok {
+				return nil, errors.New("batch.max_size must be a number")
+			}
+			config.Batch.MaxSize = n
+		}
+		if v, ok := batch["flush_interval_ms"]; ok {
+			n, ok := configInt(v)
+			if !ok {
+				return nil, errors.New("batch.flush_interval_ms must be a number")
+			}
+			config.Batch.FlushInterval = time.Duration(n) * time.Millisecond
+		}
+	}
 
 	return config, nil
 }
 
+// configInt reads a number from a processor config. Configs read back from Mongo hold int32 or
+// int64 rather than JSON's float64.
+func configInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
 // ValidateConfig validates the config against the appropriate schema based on processor_type
 func (epc *EventProcessorConfig) ValidateConfig() error {
+	if err := secrets.ValidateRefs(epc.Config); err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
 	switch epc.ProcessorType {
 	case ProcessorTypeHTTPWebhook:
-		_, err := epc.GetHttpWebhookConfig()
-		return err
+		config, err := epc.GetHttpWebhookConfig()
+		if err != nil {
+			return err
+		}
+		if config.Timeout < 1 || config.Timeout > MaxWebhookTimeout {
+			return fmt.Errorf("timeout must be between 1 and %d seconds", MaxWebhookTimeout)
+		}
+		if config.ProxyURL != "" && !secrets.IsRef(config.ProxyURL) {
+			proxy, err := url.Parse(config.ProxyURL)
+			if err != nil || proxy.Host == "" || (proxy.Scheme != "http" && proxy.Scheme != "https" && proxy.Scheme != "socks5") {
+				return errors.New("proxy_url must be an http, https or socks5 URL")
+			}
+		}
+		if config.TLS != nil {
+			return config.TLS.Validate()
+		}
+		return nil
 	case ProcessorTypeAMQP:
-		_, err := epc.GetAmqpConfig()
-		return err
+		config, err := epc.GetAmqpConfig()
+		if err != nil || config.Batch == nil {
+			return err
+		}
+		if config.Batch.MaxSize < 1 || config.Batch.MaxSize > MaxAmqpBatchSize {
+			return fmt.Errorf("batch.max_size must be between 1 and %d", MaxAmqpBatchSize)
+		}
+		if config.Batch.FlushInterval < time.Millisecond || config.Batch.FlushInterval > MaxAmqpBatchFlushInterval {
+			return fmt.Errorf("batch.flush_interval_ms must be between 1 and %d", MaxAmqpBatchFlushInterval.Milliseconds())
+		}
+		return nil
+	case ProcessorTypeSlack, ProcessorTypeWhatsApp, ProcessorTypeTeams, ProcessorTypeEmail, ProcessorTypeSMS, ProcessorTypeSunshine:
+		// Credentials come from each session's ClientChannel
+		return nil
 	default:
 		return fmt.Errorf("unsupported processor type: %s", epc.ProcessorType)
 	}
@@ -0,0 +1,382 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnknownEventType is returned when publishing an event type without a registered schema.
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// EventSchema describes the data of an event type. Version goes up whenever the data changes in a
+// way its consumers have to know about: a field removed, renamed or given another meaning.
+// Adding an optional field doesn't change the version.
+type EventSchema struct {
+	EventType  EventType
+	EntityType EntityType // Type of the entity events of the type are published on
+	Version    int
+	newData    func() interface{}
+}
+
+func schema[T any](entityType EntityType, version int) EventSchema {
+	return EventSchema{EntityType: entityType, Version: version, newData: func() interface{} { return new(T) }}
+}
+
+// eventSchemas holds the data struct of every event type. Fields without omitempty are required;
+// other fields are optional, and fields no struct names are passed on as they are.
+var eventSchemas = map[EventType]EventSchema{
+	EventTypeChatSessionCreated:      schema[ChatSessionCreatedData](EntityTypeChatSession, 1),
+	EventTypeChatSessionInactive:     schema[ChatSessionEventData](EntityTypeChatSession, 1),
+	EventTypeSessionStateChanged:     schema[SessionStateChangedData](EntityTypeChatSession, 1),
+	EventTypeSessionReopened:         schema[ChatSessionEventData](EntityTypeChatSession, 1),
+	EventTypeChatSessionRecapCreated: schema[ChatSessionRecapData](EntityTypeChatSession, 1),
+	EventTypeThreadClosed:            schema[ThreadEventData](EntityTypeChatSession, 1),
+	EventTypeThreadMerged:            schema[ThreadEventData](EntityTypeChatSession, 1),
+	EventTypeThreadSplit:             schema[ThreadEventData](EntityTypeChatSession, 1),
+	EventTypeMessagesImported:        schema[MessagesImportedData](EntityTypeChatSession, 1),
+
+	EventTypeHandoverRequested: schema[HandoverEventData](EntityTypeChatSession, 1),
+	EventTypeHandoverAssigned:  schema[HandoverEventData](EntityTypeChatSession, 1),
+	EventTypeHandoverAccepted:  schema[HandoverEventData](EntityTypeChatSession, 1),
+	EventTypeHandoverDeclined:  schema[HandoverEventData](EntityTypeChatSession, 1),
+	EventTypeHandoverCompleted: schema[HandoverEventData](EntityTypeChatSession, 1),
+
+	EventTypeChatMessageCreated:         schema[ChatMessageData](EntityTypeChatMessage, 1),
+	EventTypeChatMessageDeliveryUpdated: schema[ChatMessageDeliveryData](EntityTypeChatMessage, 1),
+
+	EventTypeChatWorkflowProcessing:    schema[ChatWorkflowData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowCompleted:     schema[ChatWorkflowAnswerData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowError:         schema[ChatWorkflowData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowHandover:      schema[ChatWorkflowAnswerData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowVetoed:        schema[ChatWorkflowData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowRouted:        schema[ChatWorkflowRoutedData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowModerated:     schema[ChatWorkflowModeratedData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowEscalated:     schema[ChatWorkflowEscalatedData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowQuotaExceeded: schema[ChatWorkflowData](EntityTypeChatMessage, 1),
+	EventTypeChatWorkflowOptedOut:      schema[ChatWorkflowOptedOutData](EntityTypeChatMessage, 1),
+
+	EventTypeChatSuggestionCreated:  schema[ChatSuggestionCreatedData](EntityTypeChatSuggestion, 1),
+	EventTypeChatSuggestionAccepted: schema[ChatSuggestionReviewData](EntityTypeChatSuggestion, 1),
+	EventTypeChatSuggestionRejected: schema[ChatSuggestionReviewData](EntityTypeChatSuggestion, 1),
+
+	EventTypeAIRequestSent:      schema[AIServiceEventData](EntityTypeAIService, 1),
+	EventTypeAIResponseReceived: schema[AIServiceEventData](EntityTypeAIService, 1),
+
+	EventTypeUsageReport:     schema[UsageReportData](EntityTypeClient, 1),
+	EventTypeAnalyticsReport: schema[AnalyticsReportData](EntityTypeClient, 1),
+	EventTypeWebhookTest:     schema[WebhookTestData](EntityTypeClient, 1),
+
+	EventTypeCSATTriggered:    schema[CSATTriggeredData](EntityTypeCSATSession, 1),
+	EventTypeCSATMessageSent:  schema[CSATMessageData](EntityTypeCSATQuestion, 1),
+	EventTypeCSATCompleted:    schema[CSATCompletedData](EntityTypeCSATSession, 1),
+	EventTypeCSATReminderSent: schema[CSATMessageData](EntityTypeCSATQuestion, 1),
+	EventTypeCSATExpired:      schema[CSATExpiredData](EntityTypeCSATSession, 1),
+}
+
+// EventSchemaFor returns the schema of eventType.
+func EventSchemaFor(eventType EventType) (EventSchema, error) {
+	s, ok := eventSchemas[eventType]
+	if !ok {
+		return EventSchema{}, fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+	}
+	s.EventType = eventType
+	return s, nil
+}
+
+// EventSchemas returns the schemas of all event types, ordered by event type.
+func EventSchemas() []EventSchema {
+	schemas := make([]EventSchema, 0, len(eventSchemas))
+	for eventType, s := range eventSchemas {
+		s.EventType = eventType
+		schemas = append(schemas, s)
+	}
+	sort.Slice(schemas, func(i, j int) bool { return schemas[i].EventType < schemas[j].EventType })
+	return schemas
+}
+
+// DataType returns the Go type of the data of the schema's event type.
+func (s EventSchema) DataType() reflect.Type {
+	return reflect.TypeOf(s.newData()).Elem()
+}
+
+// Validate checks that data has the required fields of the schema and that the fields the schema
+// names have the right types.
+func (s EventSchema) Validate(data map[string]interface{}) error {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("data can't be encoded: %w", err)
+	}
+	typed := s.newData()
+	if err := json.Unmarshal(encoded, typed); err != nil {
+		return fmt.Errorf("data doesn't match the schema: %w", err)
+	}
+
+	t := s.DataType()
+	for i := 0; i < t.NumField(); i++ {
+		name, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name == "" || name == "-" || strings.Contains(opts, "omitempty") {
+			continue
+		}
+		if v, ok := data[name]; !ok || v == nil || v == "" {
+			return fmt.Errorf("data is missing %s", name)
+		}
+	}
+	return nil
+}
+
+// ChatSessionEventData is the data of chat session events that only name their session.
+type ChatSessionEventData struct {
+	SessionID string `json:"session_id"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ChatSessionCreatedData is the data of chat_session_created.
+type ChatSessionCreatedData struct {
+	SessionID     string     `json:"session_id"`
+	State         string     `json:"state,omitempty"`
+	Participants  []string   `json:"participants,omitempty"`
+	Client        string     `json:"client,omitempty"`
+	ClientChannel string     `json:"client_channel,omitempty"`
+	CreatedAt     *time.Time `json:"created_at,omitempty"`
+}
+
+// SessionStateChangedData is the data of session_state_changed.
+type SessionStateChangedData struct {
+	SessionID string `json:"session_id"`
+	From      string `json:"from,omitempty"` // Not known to the change stream
+	To        string `json:"to"`
+	Reason    string `json:"reason,omitempty"`
+	Source    string `json:"source,omitempty"`
+}
+
+// ChatSessionRecapData is the data of chat_session_recap_created.
+type ChatSessionRecapData struct {
+	SessionID  string   `json:"session_id"`
+	RecapID    string   `json:"recap_id"`
+	Summary    string   `json:"summary,omitempty"`
+	Topics     []string `json:"topics,omitempty"`
+	Resolution string   `json:"resolution,omitempty"`
+	Sentiment  string   `json:"sentiment,omitempty"`
+}
+
+// ThreadEventData is the data of thread_closed, thread_merged and thread_split. SessionID is the
+// thread's own session; the source fields name the thread messages were moved from.
+type ThreadEventData struct {
+	SessionID       string     `json:"session_id"`
+	ParentSessionID string     `json:"parent_session_id"`
+	ThreadID        string     `json:"thread_id"`
+	LastActivity    *time.Time `json:"last_activity,omitempty"`
+	Reason          string     `json:"reason,omitempty"`
+	SourceThreadID  string     `json:"source_thread_id,omitempty"`
+	SourceSessionID string     `json:"source_session_id,omitempty"`
+	AfterMessageID  string     `json:"after_message_id,omitempty"`
+	MessagesMoved   int64      `json:"messages_moved,omitempty"`
+	PerformedBy     string     `json:"performed_by,omitempty"`
+}
+
+// MessagesImportedData is the data of messages_imported.
+type MessagesImportedData struct {
+	ImportID       string `json:"import_id"`
+	SessionID      string `json:"session_id"`
+	SessionCreated bool   `json:"session_created,omitempty"`
+	Messages       int    `json:"messages,omitempty"`
+}
+
+// HandoverEventData is the data of handover events: the session's handover as it is after the change.
+type HandoverEventData struct {
+	SessionID string           `json:"session_id"`
+	Handover  *SessionHandover `json:"handover"`
+}
+
+// ChatMessageData is the data of chat_message_created: the message as webhooks receive it.
+type ChatMessageData struct {
+	ID          string                 `json:"id"`
+	SessionID   string                 `json:"session_id,omitempty"`
+	ExternalID  string                 `json:"external_id,omitempty"`
+	Sender      string                 `json:"sender,omitempty"`
+	SenderName  string                 `json:"sender_name,omitempty"`
+	SenderType  string                 `json:"sender_type,omitempty"`
+	Text        string                 `json:"text,omitempty"`
+	Category    string                 `json:"category,omitempty"`
+	Attachments []Attachment           `json:"attachments,omitempty"`
+	Citations   []Citation             `json:"citations,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	Config      map[string]interface{} `json:"config,omitempty"`
+	Confidence  float64                `json:"confidence,omitempty"`
+	ReplyCount  int                    `json:"reply_count,omitempty"`
+	CreatedAt   *time.Time             `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time             `json:"updated_at,omitempty"`
+}
+
+// ChatMessageDeliveryData is the data of chat_message_delivery_updated.
+type ChatMessageDeliveryData struct {
+	DeliveryStatus string `json:"delivery_status"`
+	Channel        string `json:"channel"`
+	ExternalID     string `json:"external_id,omitempty"`
+	ErrorCode      string `json:"error_code,omitempty"`
+}
+
+// ChatWorkflowData is the data of chat workflow events that end or report on a turn without an
+// answer: processing, error, vetoed and quota_exceeded.
+type ChatWorkflowData struct {
+	SessionID string `json:"session_id"`
+	Status    string `json:"status,omitempty"`
+	Stage     string `json:"stage,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// ChatWorkflowAnswerData is the data of chat_workflow_completed and chat_workflow_handover: the
+// user's message and the AI's answer, and for handovers the escalation that caused them.
+type ChatWorkflowAnswerData struct {
+	SessionID   string                 `json:"session_id"`
+	UserMessage map[string]interface{} `json:"user_message"`
+	AIMessage   map[string]interface{} `json:"ai_message"`
+	Escalation  map[string]interface{} `json:"escalation,omitempty"`
+	Prompt      *PromptVersionRef      `json:"prompt,omitempty"`
+	Cached      bool                   `json:"cached,omitempty"`
+}
+
+// ChatWorkflowRoutedData is the data of chat_workflow_routed.
+type ChatWorkflowRoutedData struct {
+	SessionID  string  `json:"session_id"`
+	Intent     string  `json:"intent"`
+	Confidence float64 `json:"confidence,omitempty"`
+	Action     string  `json:"action,omitempty"`
+}
+
+// ChatWorkflowModeratedData is the data of chat_workflow_moderated.
+type ChatWorkflowModeratedData struct {
+	SessionID string `json:"session_id"`
+	Rule      string `json:"rule,omitempty"`
+	Match     string `json:"match,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+	Handover  bool   `json:"handover,omitempty"`
+}
+
+// ChatWorkflowEscalatedData is the data of chat_workflow_escalated.
+type ChatWorkflowEscalatedData struct {
+	SessionID   string  `json:"session_id"`
+	Trigger     string  `json:"trigger"`
+	Keyword     string  `json:"keyword,omitempty"`
+	Confidence  float64 `json:"confidence,omitempty"`
+	Streak      int     `json:"streak,omitempty"`
+	TargetQueue string  `json:"target_queue,omitempty"`
+	Handover    bool    `json:"handover,omitempty"`
+}
+
+// ChatWorkflowOptedOutData is the data of chat_workflow_opted_out.
+type ChatWorkflowOptedOutData struct {
+	SessionID string `json:"session_id"`
+	Purpose   string `json:"purpose"`
+}
+
+// ChatSuggestionCreatedData is the data of chat_suggestion_created.
+type ChatSuggestionCreatedData struct {
+	ID             string            `json:"id"`
+	MessageID      string            `json:"message_id,omitempty"`
+	SessionID      string            `json:"session_id,omitempty"`
+	Content        string            `json:"content,omitempty"`
+	SuggestionType string            `json:"suggestion_type,omitempty"`
+	Prompt         *PromptVersionRef `json:"prompt,omitempty"`
+}
+
+// ChatSuggestionReviewData is the data of chat_suggestion_accepted and chat_suggestion_rejected.
+type ChatSuggestionReviewData struct {
+	SessionID      string           `json:"session_id"`
+	SuggestionText string           `json:"suggestion_text,omitempty"`
+	ReviewedBy     string           `json:"reviewed_by,omitempty"`
+	Edited         bool             `json:"edited,omitempty"`
+	FinalText      string           `json:"final_text,omitempty"`
+	Edits          []SuggestionEdit `json:"edits,omitempty"`
+	Reason         string           `json:"reason,omitempty"`
+}
+
+// AIServiceEventData is the data of AI service events.
+type AIServiceEventData struct {
+	MessageID string `json:"message_id,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+}
+
+// UsageReportData is the data of usage_report: a day's usage and the month's up to that day.
+type UsageReportData struct {
+	Date        string                 `json:"date"`
+	Period      string                 `json:"period"`
+	ClientID    string                 `json:"client_id,omitempty"`
+	Usage       map[string]int64       `json:"usage,omitempty"`
+	MonthToDate map[string]int64       `json:"month_to_date,omitempty"`
+	Quota       map[string]interface{} `json:"quota,omitempty"`
+}
+
+// AnalyticsReportData is the data of analytics_report. The metrics sections are those of the
+// analytics report endpoint.
+type AnalyticsReportData struct {
+	ReportID    string                 `json:"report_id"`
+	Name        string                 `json:"name,omitempty"`
+	ClientID    string                 `json:"client_id"`
+	ClientName  string                 `json:"client_name,omitempty"`
+	Frequency   string                 `json:"frequency,omitempty"`
+	StartTime   *time.Time             `json:"start_time,omitempty"`
+	EndTime     *time.Time             `json:"end_time,omitempty"`
+	Performance map[string]interface{} `json:"performance,omitempty"`
+	Containment map[string]interface{} `json:"containment,omitempty"`
+	CSAT        map[string]interface{} `json:"csat,omitempty"`
+}
+
+// WebhookTestData is the data of webhook_test.
+type WebhookTestData struct {
+	ProcessorID string `json:"processor_id"`
+	Message     string `json:"message,omitempty"`
+}
+
+// CSATTriggeredData is the data of csat_triggered.
+type CSATTriggeredData struct {
+	CSATSessionID   string `json:"csat_session_id"`
+	ChatSessionID   string `json:"chat_session_id"`
+	ClientID        string `json:"client_id,omitempty"`
+	ChannelID       string `json:"channel_id,omitempty"`
+	ThreadContext   bool   `json:"thread_context,omitempty"`
+	ThreadSessionID string `json:"thread_session_id,omitempty"`
+}
+
+// CSATMessageData is the data of csat_message_sent and csat_reminder_sent: the question message
+// to show the user.
+type CSATMessageData struct {
+	CSATSessionID string                 `json:"csat_session_id"`
+	QuestionID    string                 `json:"question_id"`
+	ChatSessionID string                 `json:"chat_session_id"`
+	MessageType   string                 `json:"message_type,omitempty"`
+	ChatMessage   map[string]interface{} `json:"chat_message,omitempty"`
+	Locale        string                 `json:"locale,omitempty"`
+}
+
+// CSATCompletedData is the data of csat_completed.
+type CSATCompletedData struct {
+	CSATSessionID  string                   `json:"csat_session_id"`
+	ChatSessionID  string                   `json:"chat_session_id"`
+	ClientID       string                   `json:"client_id,omitempty"`
+	ChannelID      string                   `json:"channel_id,omitempty"`
+	CSATType       string                   `json:"csat_type,omitempty"`
+	Locale         string                   `json:"locale,omitempty"`
+	TriggeredAt    *time.Time               `json:"triggered_at,omitempty"`
+	CompletedAt    *time.Time               `json:"completed_at,omitempty"`
+	Responses      []map[string]interface{} `json:"responses,omitempty"`
+	AggregateScore *float64                 `json:"aggregate_score,omitempty"`
+	ScoresByType   map[string]float64       `json:"scores_by_type,omitempty"`
+	MessageType    string                   `json:"message_type,omitempty"`
+	ChatMessage    map[string]interface{}   `json:"chat_message,omitempty"`
+}
+
+// CSATExpiredData is the data of csat_expired.
+type CSATExpiredData struct {
+	CSATSessionID     string     `json:"csat_session_id"`
+	ChatSessionID     string     `json:"chat_session_id"`
+	ExpiredAt         *time.Time `json:"expired_at,omitempty"`
+	QuestionsAnswered int        `json:"questions_answered,omitempty"`
+	MessageType       string     `json:"message_type,omitempty"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"hash/fnv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ExperimentStatus is the lifecycle state of an experiment
+type ExperimentStatus string
+
+const (
+	ExperimentStatusDraft   ExperimentStatus = "draft"
+	ExperimentStatusRunning ExperimentStatus = "running"
+	ExperimentStatusStopped ExperimentStatus = "stopped"
+)
+
+// Experiment splits a client's sessions between variants of its bot configuration. A client runs
+// at most one experiment at a time, and its variants can't change once it has started, so a
+// session stays in the same variant for as long as the experiment runs.
+type Experiment struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID    string              `bson:"client_id" json:"client_id"`
+	Name        string              `bson:"name" json:"name"`
+	Description string              `bson:"description,omitempty" json:"description,omitempty"`
+	Status      ExperimentStatus    `bson:"status" json:"status"`
+	Variants    []ExperimentVariant `bson:"variants" json:"variants"`
+	StartedAt   *time.Time          `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	StoppedAt   *time.Time          `bson:"stopped_at,omitempty" json:"stopped_at,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// ExperimentVariant is one configuration under test. Settings left unset keep what the client
+// uses outside the experiment, so a variant with none of them is the control group.
+type ExperimentVariant struct {
+	Name string `bson:"name" json:"name"`
+	// Weight is the variant's share of sessions relative to the other variants' weights
+	Weight   int               `bson:"weight" json:"weight"`
+	AIConfig *ClientAIConfig   `bson:"ai_config,omitempty" json:"ai_config,omitempty"`
+	Prompt   *ExperimentPrompt `bson:"prompt,omitempty" json:"prompt,omitempty"`
+	// ConfidenceThreshold replaces the escalation policy's low-confidence threshold
+	ConfidenceThreshold *float64 `bson:"confidence_threshold,omitempty" json:"confidence_threshold,omitempty"`
+}
+
+// ExperimentPrompt pins a prompt template version for a variant, in place of the published one.
+type ExperimentPrompt struct {
+	TemplateID string `bson:"template_id" json:"template_id"`
+	Version    int    `bson:"version" json:"version"`
+}
+
+// ExperimentAssignment tags what an experiment's variant produced, such as AI replies and the
+// CSAT responses of the sessions they were sent in.
+type ExperimentAssignment struct {
+	ExperimentID string `bson:"experiment_id" json:"experiment_id"`
+	Variant      string `bson:"variant" json:"variant"`
+}
+
+// TableName returns the collection name for Experiment
+func (Experiment) TableName() string {
+	return "experiments"
+}
+
+// BeforeCreate sets timestamps before creating
+func (e *Experiment) BeforeCreate() {
+	now := time.Now().UTC()
+	e.CreatedAt = now
+	e.UpdatedAt = now
+}
+
+// Assign returns the variant of the session with the given ID. Sessions are split by a hash of
+// both IDs, so each lands in the same variant every time without the assignment being stored.
+func (e *Experiment) Assign(sessionID string) *ExperimentVariant {
+	total := 0
+	for _, v := range e.Variants {
+		total += v.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	h := fnv.New32a()
+	h.Write([]byte(e.ID.Hex() + ":" + sessionID))
+	point := int(h.Sum32() % uint32(total))
+	for i := range e.Variants {
+		if point < e.Variants[i].Weight {
+			return &e.Variants[i]
+		}
+		point -= e.Variants[i].Weight
+	}
+	return nil
+}
+
+// Assignment tags what variant produced for the experiment.
+func (e *Experiment) Assignment(variant *ExperimentVariant) *ExperimentAssignment {
+	return &ExperimentAssignment{ExperimentID: e.ID.Hex(), Variant: variant.Name}
+}
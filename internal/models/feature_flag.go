@@ -0,0 +1,65 @@
+package models
+
+import (
+	"hash/fnv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Well-known feature flags checked by the services.
+const (
+	// FeatureFlagThreading turns threading on for clients whose thread_config doesn't say
+	FeatureFlagThreading = "threading"
+)
+
+// FeatureFlag turns a feature on for some clients: those listed, then a stable percentage of
+// the rest.
+type FeatureFlag struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Key         string             `bson:"key" json:"key"`
+	Description string             `bson:"description,omitempty" json:"description,omitempty"`
+	// Enabled is a kill switch; a disabled flag is off for every client
+	Enabled bool `bson:"enabled" json:"enabled"`
+	// Clients always get the feature, by client_id
+	Clients []string `bson:"clients,omitempty" json:"clients,omitempty"`
+	// ExcludedClients never get the feature, whatever the percentage
+	ExcludedClients []string `bson:"excluded_clients,omitempty" json:"excluded_clients,omitempty"`
+	// Percentage of the other clients, 0 to 100, that get the feature
+	Percentage int       `bson:"percentage" json:"percentage"`
+	CreatedAt  time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for FeatureFlag
+func (FeatureFlag) TableName() string {
+	return "feature_flags"
+}
+
+// EnabledFor reports whether the flag is on for the client with clientID. A client's place in the
+// percentage rollout depends only on the flag key and its client_id, so raising the percentage
+// keeps the feature on for the clients that already had it.
+func (f *FeatureFlag) EnabledFor(clientID string) bool {
+	if !f.Enabled {
+		return false
+	}
+	for _, id := range f.ExcludedClients {
+		if id == clientID {
+			return false
+		}
+	}
+	for _, id := range f.Clients {
+		if id == clientID {
+			return true
+		}
+	}
+	if f.Percentage >= 100 {
+		return true
+	}
+	if f.Percentage <= 0 || clientID == "" {
+		return false
+	}
+	h := fnv.New32a()
+	h.Write([]byte(f.Key + ":" + clientID))
+	return int(h.Sum32()%100) < f.Percentage
+}
@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// SessionHandover tracks the transfer of a chat session to a human agent.
+// Only the latest handover is kept on the session; earlier ones are in the event history.
+type SessionHandover struct {
+	Status        HandoverStatus `bson:"status" json:"status"`
+	Reason        string         `bson:"reason,omitempty" json:"reason,omitempty"`
+	TargetQueue   string         `bson:"target_queue,omitempty" json:"target_queue,omitempty"`
+	TargetAgent   string         `bson:"target_agent,omitempty" json:"target_agent,omitempty"`
+	Skills        []string       `bson:"skills,omitempty" json:"skills,omitempty"` // Skills wanted for this handover, used by skills_match routing
+	RequestedBy   string         `bson:"requested_by,omitempty" json:"requested_by,omitempty"`
+	Agent         string         `bson:"agent,omitempty" json:"agent,omitempty"` // Agent that accepted or declined
+	DeclineReason string         `bson:"decline_reason,omitempty" json:"decline_reason,omitempty"`
+	RequestedAt   time.Time      `bson:"requested_at" json:"requested_at"`
+	AcceptedAt    *time.Time     `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	DeclinedAt    *time.Time     `bson:"declined_at,omitempty" json:"declined_at,omitempty"`
+	CompletedAt   *time.Time     `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+}
+
+// IsOpen reports whether the handover still needs or has an agent.
+func (h *SessionHandover) IsOpen() bool {
+	return h != nil && (h.Status == HandoverStatusRequested || h.Status == HandoverStatusAccepted)
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MessageImportStatus is where a message import is in its run.
+type MessageImportStatus string
+
+const (
+	MessageImportStatusQueued    MessageImportStatus = "queued"
+	MessageImportStatusRunning   MessageImportStatus = "running"
+	MessageImportStatusCompleted MessageImportStatus = "completed"
+	MessageImportStatusFailed    MessageImportStatus = "failed"
+)
+
+// Which events an import publishes for what it stored
+const (
+	MessageImportEventsNone    = "none"    // Imported sessions and messages publish nothing
+	MessageImportEventsSummary = "summary" // One messages_imported event per session once the import is done
+)
+
+// MessageImport backfills a client's conversation history from another platform. The uploaded
+// NDJSON file holds one MessageImportRow per line; a worker stores them in batches as messages of
+// the channel, keeping their timestamps.
+type MessageImport struct {
+	ID              primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	ClientID        primitive.ObjectID   `bson:"client" json:"client_id"`
+	ChannelID       primitive.ObjectID   `bson:"client_channel" json:"channel_id"`
+	Status          MessageImportStatus  `bson:"status" json:"status"`
+	Events          string               `bson:"events" json:"events"`
+	FileID          primitive.ObjectID   `bson:"file" json:"-"`
+	FileName        string               `bson:"file_name,omitempty" json:"file_name,omitempty"`
+	FileSize        int64                `bson:"file_size" json:"file_size"`
+	Processed       int                  `bson:"processed" json:"processed"` // Rows read so far
+	Imported        int                  `bson:"imported" json:"imported"`
+	Skipped         int                  `bson:"skipped" json:"skipped"` // Rows whose external_id was already stored
+	Failed          int                  `bson:"failed" json:"failed"`
+	SessionsCreated int                  `bson:"sessions_created" json:"sessions_created"`
+	Errors          []MessageImportError `bson:"errors" json:"errors"` // The first rows that failed
+	Error           string               `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt       *time.Time           `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt     *time.Time           `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	CreatedAt       time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// MessageImportError is why a row of an import's file was not imported.
+type MessageImportError struct {
+	Line  int    `bson:"line" json:"line"`
+	Error string `bson:"error" json:"error"`
+}
+
+// MessageImportProgress is what a batch of rows added to an import's counts.
+type MessageImportProgress struct {
+	Processed       int
+	Imported        int
+	Skipped         int
+	Failed          int
+	SessionsCreated int
+	Errors          []MessageImportError
+}
+
+// MessageImportRow is one line of an import file: a message of the session with session_id,
+// created with the session when the client has none by that ID.
+type MessageImportRow struct {
+	SessionID   string                 `json:"session_id"`
+	ExternalID  string                 `json:"external_id,omitempty"`
+	Sender      string                 `json:"sender"`
+	SenderName  string                 `json:"sender_name,omitempty"`
+	SenderType  string                 `json:"sender_type"`
+	Text        string                 `json:"text"`
+	Attachments []Attachment           `json:"attachments,omitempty"`
+	Data        map[string]interface{} `json:"data,omitempty"`
+	Category    MessageCategory        `json:"category,omitempty"`
+	CreatedAt   *time.Time             `json:"created_at"`
+}
+
+// TableName returns the collection name for MessageImport
+func (MessageImport) TableName() string {
+	return "message_imports"
+}
+
+// BeforeCreate sets timestamps before creating
+func (m *MessageImport) BeforeCreate() {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Names of the prompt templates the chat workflow renders
+const (
+	PromptTemplateChat        = "chat"
+	PromptTemplateSuggestions = "suggestions"
+)
+
+// PromptTemplate is a client's versioned prompt for the AI service. Versions are only added; one
+// of them is published at a time. A template with a ChannelType is the variant of the
+// client-wide template of the same name for channels of that type.
+type PromptTemplate struct {
+	ID          primitive.ObjectID      `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID    string                  `bson:"client_id" json:"client_id"`
+	Name        string                  `bson:"name" json:"name"`
+	ChannelType string                  `bson:"channel_type,omitempty" json:"channel_type,omitempty"`
+	Description string                  `bson:"description,omitempty" json:"description,omitempty"`
+	Versions    []PromptTemplateVersion `bson:"versions" json:"versions"`
+	// ActiveVersion is the published version; 0 until one is published
+	ActiveVersion int                 `bson:"active_version" json:"active_version"`
+	Publications  []PromptPublication `bson:"publications,omitempty" json:"publications,omitempty"` // Oldest first
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// PromptTemplateVersion is one revision of a prompt template's text. Text may contain {{name}}
+// placeholders, filled in from the conversation or Defaults when the prompt is rendered.
+type PromptTemplateVersion struct {
+	Version   int               `bson:"version" json:"version"`
+	Text      string            `bson:"text" json:"text"`
+	Variables []string          `bson:"variables,omitempty" json:"variables,omitempty"` // Derived from Text
+	Defaults  map[string]string `bson:"defaults,omitempty" json:"defaults,omitempty"`
+	Note      string            `bson:"note,omitempty" json:"note,omitempty"`
+	CreatedBy string            `bson:"created_by,omitempty" json:"created_by,omitempty"`
+	CreatedAt time.Time         `bson:"created_at" json:"created_at"`
+}
+
+// PromptPublication records a version going live, by publishing or by rolling back to it.
+type PromptPublication struct {
+	Version     int       `bson:"version" json:"version"`
+	Rollback    bool      `bson:"rollback,omitempty" json:"rollback,omitempty"`
+	PublishedBy string    `bson:"published_by,omitempty" json:"published_by,omitempty"`
+	PublishedAt time.Time `bson:"published_at" json:"published_at"`
+}
+
+// PromptVersionRef names the prompt template version an AI answer was generated with.
+type PromptVersionRef struct {
+	TemplateID  string `bson:"template_id" json:"template_id"`
+	Name        string `bson:"name" json:"name"`
+	Version     int    `bson:"version" json:"version"`
+	ChannelType string `bson:"channel_type,omitempty" json:"channel_type,omitempty"`
+}
+
+// TableName returns the collection name for PromptTemplate
+func (PromptTemplate) TableName() string {
+	return "prompt_templates"
+}
+
+// BeforeCreate sets timestamps before creating
+func (t *PromptTemplate) BeforeCreate() {
+	now := time.Now().UTC()
+	t.CreatedAt = now
+	t.UpdatedAt = now
+}
+
+// Version returns the given version of the template, or nil if it has none by that number.
+func (t *PromptTemplate) Version(version int) *PromptTemplateVersion {
+	for i := range t.Versions {
+		if t.Versions[i].Version == version {
+			return &t.Versions[i]
+		}
+	}
+	return nil
+}
+
+// Ref names version of the template.
+func (t *PromptTemplate) Ref(version int) *PromptVersionRef {
+	return &PromptVersionRef{TemplateID: t.ID.Hex(), Name: t.Name, Version: version, ChannelType: t.ChannelType}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// RateLimitBucket is the token bucket of one rate-limited caller, such as an API key or an IP.
+type RateLimitBucket struct {
+	Key       string    `bson:"_id" json:"key"`
+	Tokens    float64   `bson:"tokens" json:"tokens"`
+	Allowed   bool      `bson:"allowed" json:"allowed"` // Whether the last request took a token
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+	// ExpiresAt is when the bucket is full again and no longer needs to be stored
+	ExpiresAt time.Time `bson:"expires_at" json:"expires_at"`
+}
+
+// TableName returns the collection name for RateLimitBucket
+func (RateLimitBucket) TableName() string {
+	return "rate_limit_buckets"
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Role is a named set of permissions held by a dashboard user or API key.
+type Role string
+
+const (
+	// RoleAdmin may call every route, including system administration
+	RoleAdmin Role = "admin"
+	// RoleClientAdmin manages a client's configuration as well as its conversations
+	RoleClientAdmin Role = "client_admin"
+	// RoleAgent handles conversations: messages, sessions and handovers
+	RoleAgent Role = "agent"
+	// RoleReadOnly may only read
+	RoleReadOnly Role = "read_only"
+)
+
+// Permission is what a route requires of its caller.
+type Permission string
+
+const (
+	// PermissionAuthenticated is held by every authenticated caller
+	PermissionAuthenticated Permission = "authenticated"
+	PermissionMessagesRead  Permission = "messages:read"
+	PermissionMessagesWrite Permission = "messages:write"
+	PermissionSessionsRead  Permission = "sessions:read"
+	PermissionSessionsWrite Permission = "sessions:write"
+	PermissionClientsRead   Permission = "clients:read"
+	PermissionClientsWrite  Permission = "clients:write"
+	PermissionAnalyticsRead Permission = "analytics:read"
+	// PermissionSystem covers cross-client administration: clients, events, repairs and roles
+	PermissionSystem Permission = "system"
+)
+
+// rolePermissions lists what each role grants; admin grants everything and is not listed.
+var rolePermissions = map[Role][]Permission{
+	RoleClientAdmin: {
+		PermissionMessagesRead, PermissionMessagesWrite, PermissionSessionsRead, PermissionSessionsWrite,
+		PermissionClientsRead, PermissionClientsWrite, PermissionAnalyticsRead,
+	},
+	RoleAgent: {
+		PermissionMessagesRead, PermissionMessagesWrite, PermissionSessionsRead, PermissionSessionsWrite,
+		PermissionClientsRead, PermissionAnalyticsRead,
+	},
+	RoleReadOnly: {
+		PermissionMessagesRead, PermissionSessionsRead, PermissionClientsRead, PermissionAnalyticsRead,
+	},
+}
+
+// IsValid reports whether r is a known role.
+func (r Role) IsValid() bool {
+	if r == RoleAdmin {
+		return true
+	}
+	_, ok := rolePermissions[r]
+	return ok
+}
+
+// Grants reports whether the role grants p.
+func (r Role) Grants(p Permission) bool {
+	if r == RoleAdmin || p == PermissionAuthenticated {
+		return r.IsValid()
+	}
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
+
+// Permissions returns the permissions of a client API key scope. The admin scope grants what
+// the client_admin role does.
+func (s APIKeyScope) Permissions() []Permission {
+	switch s {
+	case APIKeyScopeReadMessages:
+		return []Permission{PermissionMessagesRead}
+	case APIKeyScopeWriteMessages:
+		return []Permission{PermissionMessagesWrite}
+	case APIKeyScopeAdmin:
+		return rolePermissions[RoleClientAdmin]
+	}
+	return nil
+}
+
+// RoleAssignment grants roles to a dashboard user, identified by the subject of their OIDC
+// token, on top of the roles their identity provider sends.
+type RoleAssignment struct {
+	ID      primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Subject string             `bson:"subject" json:"subject"`
+	Roles   []Role             `bson:"roles" json:"roles"`
+	// ClientID limits the user to one client, by ObjectID or client_id
+	ClientID  string    `bson:"client_id,omitempty" json:"client_id,omitempty"`
+	CreatedAt time.Time `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for RoleAssignment
+func (RoleAssignment) TableName() string {
+	return "role_assignments"
+}
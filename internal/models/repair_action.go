@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RepairActionParams holds the inputs for a repair action; which fields apply depends on the action
+type RepairActionParams struct {
+	ProcessorID      *primitive.ObjectID `bson:"processor_id,omitempty" json:"processor_id,omitempty"`
+	From             *time.Time          `bson:"from,omitempty" json:"from,omitempty"`
+	To               *time.Time          `bson:"to,omitempty" json:"to,omitempty"`
+	OlderThanMinutes int                 `bson:"older_than_minutes,omitempty" json:"older_than_minutes,omitempty"`
+	Limit            int                 `bson:"limit" json:"limit"`
+}
+
+// RepairAction is both the tracked task and the audit entry for an operator repair
+type RepairAction struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Action      RepairActionType   `bson:"action" json:"action"`
+	Params      RepairActionParams `bson:"params" json:"params"`
+	DryRun      bool               `bson:"dry_run" json:"dry_run"`
+	Status      ExecutionStatus    `bson:"status" json:"status"`
+	RequestedBy string             `bson:"requested_by" json:"requested_by"`
+	Matched     int                `bson:"matched" json:"matched"`
+	Affected    int                `bson:"affected" json:"affected"`
+	AffectedIDs []string           `bson:"affected_ids,omitempty" json:"affected_ids,omitempty"` // In dry-run, the IDs that would be affected
+	Error       string             `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt   *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt *time.Time         `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for RepairAction
+func (RepairAction) TableName() string {
+	return "repair_actions"
+}
+
+// BeforeCreate sets timestamps before creating
+func (r *RepairAction) BeforeCreate() {
+	now := time.Now().UTC()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ReportFrequency is how often a scheduled report is sent.
+type ReportFrequency string
+
+const (
+	ReportFrequencyDaily  ReportFrequency = "daily"
+	ReportFrequencyWeekly ReportFrequency = "weekly"
+)
+
+// ReportSchedule sends a client a summary of its analytics and CSAT results at a fixed local time,
+// by email through one of its email channels, to a webhook, or both. Each report covers the day or
+// week up to its run.
+type ReportSchedule struct {
+	ID           primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	ClientID     string              `bson:"client_id" json:"client_id"`
+	Name         string              `bson:"name" json:"name"`
+	Enabled      bool                `bson:"enabled" json:"enabled"`
+	Frequency    ReportFrequency     `bson:"frequency" json:"frequency"`
+	Weekday      time.Weekday        `bson:"weekday" json:"weekday"`                       // Weekly reports only: 0 (Sunday) to 6
+	Hour         int                 `bson:"hour" json:"hour"`                             // Local hour of the day the report is sent
+	Timezone     string              `bson:"timezone,omitempty" json:"timezone,omitempty"` // IANA name; UTC when empty
+	EmailChannel *primitive.ObjectID `bson:"email_channel,omitempty" json:"email_channel_id,omitempty"`
+	Recipients   []string            `bson:"recipients,omitempty" json:"recipients,omitempty"`
+	WebhookURL   string              `bson:"webhook_url,omitempty" json:"webhook_url,omitempty"`
+	NextRunAt    time.Time           `bson:"next_run_at" json:"next_run_at"`
+	LastRunAt    *time.Time          `bson:"last_run_at,omitempty" json:"last_run_at,omitempty"`
+	LastError    string              `bson:"last_error,omitempty" json:"last_error,omitempty"` // Cleared by the next successful run
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for ReportSchedule
+func (ReportSchedule) TableName() string {
+	return "report_schedules"
+}
+
+// BeforeCreate sets timestamps before creating
+func (s *ReportSchedule) BeforeCreate() {
+	now := time.Now().UTC()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+}
+
+// Location returns the schedule's time zone, falling back to UTC when it is empty or unknown.
+func (s *ReportSchedule) Location() *time.Location {
+	if loc, err := time.LoadLocation(s.Timezone); err == nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// NextRun returns the first time after after that the report is due.
+func (s *ReportSchedule) NextRun(after time.Time) time.Time {
+	local := after.In(s.Location())
+	next := time.Date(local.Year(), local.Month(), local.Day(), s.Hour, 0, 0, 0, local.Location())
+	if s.Frequency == ReportFrequencyWeekly {
+		next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(after) {
+		if s.Frequency == ReportFrequencyWeekly {
+			next = next.AddDate(0, 0, 7)
+		} else {
+			next = next.AddDate(0, 0, 1)
+		}
+	}
+	return next.UTC()
+}
+
+// Period returns the range a report run at runAt covers: the day or week before it.
+func (s *ReportSchedule) Period(runAt time.Time) (time.Time, time.Time) {
+	local := runAt.In(s.Location())
+	if s.Frequency == ReportFrequencyWeekly {
+		return local.AddDate(0, 0, -7).UTC(), runAt.UTC()
+	}
+	return local.AddDate(0, 0, -1).UTC(), runAt.UTC()
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ScheduledMessage is a chat message held back until SendAt, then created through the normal message flow.
+// Session, client and channel are kept as the caller's identifiers and resolved at send time.
+type ScheduledMessage struct {
+	ID                primitive.ObjectID     `bson:"_id,omitempty" json:"id,omitempty"`
+	Status            ScheduledMessageStatus `bson:"status" json:"status"`
+	SendAt            time.Time              `bson:"send_at" json:"send_at"`
+	SessionID         string                 `bson:"session_id" json:"session_id"`
+	ClientID          string                 `bson:"client_id" json:"client_id"`
+	ClientChannelType string                 `bson:"client_channel_type" json:"client_channel_type"`
+	ExternalID        string                 `bson:"external_id,omitempty" json:"external_id,omitempty"`
+	Sender            string                 `bson:"sender" json:"sender"`
+	SenderName        string                 `bson:"sender_name,omitempty" json:"sender_name,omitempty"`
+	SenderType        string                 `bson:"sender_type" json:"sender_type"`
+	Text              string                 `bson:"text" json:"text"`
+	Attachments       []Attachment           `bson:"attachments,omitempty" json:"attachments,omitempty"`
+	Data              map[string]interface{} `bson:"data,omitempty" json:"data,omitempty"`
+	Category          MessageCategory        `bson:"category" json:"category"`
+	Config            map[string]interface{} `bson:"config,omitempty" json:"config,omitempty"`
+	ParentMessageID   *primitive.ObjectID    `bson:"parent_message,omitempty" json:"parent_message_id,omitempty"`
+	MessageID         *primitive.ObjectID    `bson:"message,omitempty" json:"message_id,omitempty"` // Set once the chat message has been created
+	Error             string                 `bson:"error,omitempty" json:"error,omitempty"`
+	SentAt            *time.Time             `bson:"sent_at,omitempty" json:"sent_at,omitempty"`
+	CancelledAt       *time.Time             `bson:"cancelled_at,omitempty" json:"cancelled_at,omitempty"`
+	CreatedAt         time.Time              `bson:"created_at" json:"created_at"`
+	UpdatedAt         time.Time              `bson:"updated_at" json:"updated_at"`
+}
+
+// TableName returns the collection name for ScheduledMessage
+func (ScheduledMessage) TableName() string {
+	return "scheduled_messages"
+}
+
+// BeforeCreate sets timestamps before creating
+func (m *ScheduledMessage) BeforeCreate() {
+	now := time.Now().UTC()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SimulationStatus is where a simulation is in its run.
+type SimulationStatus string
+
+const (
+	SimulationStatusQueued    SimulationStatus = "queued"
+	SimulationStatusRunning   SimulationStatus = "running"
+	SimulationStatusCompleted SimulationStatus = "completed"
+	SimulationStatusFailed    SimulationStatus = "failed"
+)
+
+// Turn outcomes other than the chat workflow events that end a turn without an answer
+const (
+	SimulationOutcomeAnswered = "answered"
+	SimulationOutcomeNoAnswer = "no_answer"
+	SimulationOutcomeTimeout  = "timeout"
+	SimulationOutcomeError    = "error"
+)
+
+// Simulation replays a script of user messages through the chat workflow in a sandbox session of
+// its own, one at a time, and records how the AI answered each of them. Unlike other sandbox
+// sessions, the session of a simulation gets answers from the real AI service.
+type Simulation struct {
+	ID                 primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ClientID           primitive.ObjectID  `bson:"client" json:"client_id"`
+	ChannelID          *primitive.ObjectID `bson:"client_channel,omitempty" json:"channel_id,omitempty"`
+	Name               string              `bson:"name,omitempty" json:"name,omitempty"`
+	Status             SimulationStatus    `bson:"status" json:"status"`
+	Script             []SimulationStep    `bson:"script" json:"script"`
+	StepTimeoutSeconds int                 `bson:"step_timeout_seconds" json:"step_timeout_seconds"`
+	ChatSessionID      primitive.ObjectID  `bson:"chat_session" json:"chat_session_id"`
+	SessionID          string              `bson:"session_id" json:"session_id"`
+	Turns              []SimulationTurn    `bson:"turns" json:"turns"`
+	Report             *SimulationReport   `bson:"report,omitempty" json:"report,omitempty"`
+	Error              string              `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt          *time.Time          `bson:"started_at,omitempty" json:"started_at,omitempty"`
+	CompletedAt        *time.Time          `bson:"completed_at,omitempty" json:"completed_at,omitempty"`
+	CreatedAt          time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt          time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// SimulationStep is one user message of a simulation's script.
+type SimulationStep struct {
+	Text   string                 `bson:"text" json:"text"`
+	Expect *SimulationExpectation `bson:"expect,omitempty" json:"expect,omitempty"`
+}
+
+// SimulationExpectation is what the answer to a step must satisfy for the step to pass.
+type SimulationExpectation struct {
+	Contains      []string `bson:"contains,omitempty" json:"contains,omitempty"` // Matched case-insensitively
+	MinConfidence float64  `bson:"min_confidence,omitempty" json:"min_confidence,omitempty"`
+}
+
+// Check returns how an answer with confidence falls short of the expectation.
+func (e *SimulationExpectation) Check(answer string, confidence float64) []string {
+	var failures []string
+	lower := strings.ToLower(answer)
+	for _, want := range e.Contains {
+		if !strings.Contains(lower, strings.ToLower(want)) {
+			failures = append(failures, fmt.Sprintf("answer does not contain %q", want))
+		}
+	}
+	if confidence < e.MinConfidence {
+		failures = append(failures, fmt.Sprintf("confidence %.2f is below %.2f", confidence, e.MinConfidence))
+	}
+	return failures
+}
+
+// SimulationTurn records how the chat workflow handled one step.
+type SimulationTurn struct {
+	Step            int                 `bson:"step" json:"step"`
+	MessageID       primitive.ObjectID  `bson:"message" json:"message_id"`
+	Outcome         string              `bson:"outcome" json:"outcome"` // SimulationOutcome*, or the chat workflow event that ended the turn
+	Answer          string              `bson:"answer,omitempty" json:"answer,omitempty"`
+	AnswerMessageID *primitive.ObjectID `bson:"answer_message,omitempty" json:"answer_message_id,omitempty"`
+	Confidence      float64             `bson:"confidence" json:"confidence"`
+	LatencyMs       int64               `bson:"latency_ms" json:"latency_ms"`
+	Passed          bool                `bson:"passed" json:"passed"`
+	Failures        []string            `bson:"failures,omitempty" json:"failures,omitempty"`
+	Error           string              `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// SimulationReport sums up the turns of a finished simulation. Passed is set when every step was
+// answered and met its expectations.
+type SimulationReport struct {
+	Steps         int     `bson:"steps" json:"steps"`
+	Answered      int     `bson:"answered" json:"answered"`
+	Failed        int     `bson:"failed" json:"failed"`
+	Passed        bool    `bson:"passed" json:"passed"`
+	AvgConfidence float64 `bson:"avg_confidence" json:"avg_confidence"`
+	MinConfidence float64 `bson:"min_confidence" json:"min_confidence"`
+	AvgLatencyMs  int64   `bson:"avg_latency_ms" json:"avg_latency_ms"`
+	P95LatencyMs  int64   `bson:"p95_latency_ms" json:"p95_latency_ms"`
+	MaxLatencyMs  int64   `bson:"max_latency_ms" json:"max_latency_ms"`
+	DurationMs    int64   `bson:"duration_ms" json:"duration_ms"`
+}
+
+// TableName returns the collection name for Simulation
+func (Simulation) TableName() string {
+	return "simulations"
+}
+
+// BeforeCreate sets timestamps before creating
+func (s *Simulation) BeforeCreate() {
+	now := time.Now().UTC()
+	s.CreatedAt = now
+	s.UpdatedAt = now
+}
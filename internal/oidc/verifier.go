@@ -0,0 +1,289 @@
+// Package oidc verifies the ID and access tokens that dashboard users obtain from the company
+// identity provider, and maps their claims to API roles.
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// keyRefreshInterval is how often signing keys are reloaded from the provider
+	keyRefreshInterval = 12 * time.Hour
+	// minKeyRefreshInterval limits reloads triggered by tokens signed with an unknown key
+	minKeyRefreshInterval = time.Minute
+	clockSkew             = 2 * time.Minute
+)
+
+var ErrInvalidToken = errors.New("invalid OIDC token")
+
+// Claims are the verified claims of a user token.
+type Claims struct {
+	Subject string
+	Email   string
+	Name    string
+	// Roles are the API roles mapped from the provider's roles claim
+	Roles []string
+	// ClientID is the client the user is limited to, when the provider sends one
+	ClientID  string
+	ExpiresAt time.Time
+}
+
+// Verifier checks RS256 tokens issued by one OIDC provider for one audience. Signing keys are
+// discovered from the provider's /.well-known/openid-configuration.
+type Verifier struct {
+	Issuer   string
+	Audience string
+	// RolesClaim is the claim holding the user's roles or groups; dots address nested claims,
+	// as in "realm_access.roles"
+	RolesClaim string
+	// RoleMapping maps provider role names to API roles. Unmapped names are dropped; when the
+	// mapping is empty, provider roles are used as they are.
+	RoleMapping map[string]string
+	// ClientClaim, when set, names the claim holding the client the user belongs to
+	ClientClaim string
+	httpClient  *http.Client
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// NewVerifier creates a Verifier for tokens from issuer with the given audience.
+func NewVerifier(issuer, audience string) *Verifier {
+	return &Verifier{
+		Issuer:     strings.TrimSuffix(issuer, "/"),
+		Audience:   audience,
+		RolesClaim: "roles",
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// ParseRoleMapping reads a role mapping written as "provider_role=api_role,..."; entries
+// without "=" are ignored.
+func ParseRoleMapping(s string) map[string]string {
+	mapping := map[string]string{}
+	for _, entry := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(entry, "=")
+		if from, to = strings.TrimSpace(from), strings.TrimSpace(to); ok && from != "" && to != "" {
+			mapping[from] = to
+		}
+	}
+	return mapping
+}
+
+// LooksLikeJWT reports whether token has the three dot-separated segments of a JWT, so that
+// other bearer credentials are not sent to the provider's key set.
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify checks the signature, issuer, audience and lifetime of token and returns its claims.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "RS256" {
+		return nil, ErrInvalidToken
+	}
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var raw map[string]interface{}
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, ErrInvalidToken
+	}
+	iss, _ := raw["iss"].(string)
+	exp, _ := raw["exp"].(float64)
+	nbf, _ := raw["nbf"].(float64)
+	now := time.Now()
+	expiresAt := time.Unix(int64(exp), 0)
+	if strings.TrimSuffix(iss, "/") != v.Issuer || !hasAudience(raw["aud"], v.Audience) || exp == 0 ||
+		now.After(expiresAt.Add(clockSkew)) || (nbf != 0 && now.Add(clockSkew).Before(time.Unix(int64(nbf), 0))) {
+		return nil, ErrInvalidToken
+	}
+
+	claims := &Claims{ExpiresAt: expiresAt, Roles: v.mapRoles(claimStrings(lookupClaim(raw, v.RolesClaim)))}
+	claims.Subject, _ = raw["sub"].(string)
+	claims.Email, _ = raw["email"].(string)
+	claims.Name, _ = raw["name"].(string)
+	if v.ClientClaim != "" {
+		claims.ClientID, _ = lookupClaim(raw, v.ClientClaim).(string)
+	}
+	if claims.Subject == "" {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// mapRoles translates provider roles to API roles, dropping duplicates.
+func (v *Verifier) mapRoles(providerRoles []string) []string {
+	roles := make([]string, 0, len(providerRoles))
+	seen := map[string]bool{}
+	for _, role := range providerRoles {
+		if len(v.RoleMapping) > 0 {
+			role = v.RoleMapping[role]
+		}
+		if role != "" && !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// key returns the signing key kid, reloading the key set when it is stale or doesn't have kid.
+func (v *Verifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	key, ok := v.keys[kid]
+	stale := time.Since(v.fetchedAt) > keyRefreshInterval
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(v.fetchedAt) < minKeyRefreshInterval {
+		return nil, ErrInvalidToken
+	}
+
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		if ok {
+			// Keep serving the known key rather than failing every request while the provider is down
+			return key, nil
+		}
+		return nil, err
+	}
+	v.keys, v.fetchedAt = keys, time.Now()
+	if key, ok = keys[kid]; !ok {
+		return nil, ErrInvalidToken
+	}
+	return key, nil
+}
+
+func (v *Verifier) fetchKeys(ctx context.Context) (map[string]*rsa.PublicKey, error) {
+	var metadata struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, v.Issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("failed to load OIDC provider metadata: %w", err)
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := v.getJSON(ctx, metadata.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to load OIDC signing keys: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+	}
+	return keys, nil
+}
+
+func (v *Verifier) getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s returned status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(out)
+}
+
+// hasAudience reports whether the aud claim, a string or a list of strings, contains audience.
+func hasAudience(aud interface{}, audience string) bool {
+	for _, a := range claimStrings(aud) {
+		if a == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupClaim returns the claim at a dotted path, or nil.
+func lookupClaim(claims map[string]interface{}, path string) interface{} {
+	var value interface{} = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+	return value
+}
+
+// claimStrings reads a claim that is either a string or a list of strings.
+func claimStrings(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func decodeSegment(segment string, out interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
@@ -0,0 +1,185 @@
+// Package realtime notifies waiting API requests when a chat session gets new messages or events.
+package realtime
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.uber.org/zap"
+)
+
+// Notification is broadcast whenever something is written to a session.
+// Key is either the session's hex ObjectID or its external session_id, depending on the writer.
+type Notification struct {
+	Key string    `json:"key"`
+	At  time.Time `json:"at"`
+}
+
+// Hub broadcasts session notifications over a RabbitMQ fanout exchange. Every API and worker
+// process binds its own queue, so a write on any node wakes requests waiting on any other.
+type Hub struct {
+	conn     *amqp.Connection
+	channel  *amqp.Channel
+	exchange string
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	waiters map[string]map[chan struct{}]struct{}
+}
+
+// NewHub connects to RabbitMQ, declares the fanout exchange and starts consuming notifications.
+func NewHub(rabbitMQURL, exchange string, logger *zap.Logger) (*Hub, error) {
+	conn, err := amqp.Dial(rabbitMQURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+
+	h := &Hub{
+		conn:     conn,
+		channel:  channel,
+		exchange: exchange,
+		logger:   logger,
+		waiters:  make(map[string]map[chan struct{}]struct{}),
+	}
+
+	deliveries, err := h.bind()
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	go h.consume(deliveries)
+
+	return h, nil
+}
+
+// bind declares the exchange and a server-named queue that is removed when this process disconnects.
+func (h *Hub) bind() (<-chan amqp.Delivery, error) {
+	if err := h.channel.ExchangeDeclare(
+		h.exchange, // name
+		"fanout",   // type
+		true,       // durable
+		false,      // auto-deleted
+		false,      // internal
+		false,      // no-wait
+		nil,        // arguments
+	); err != nil {
+		return nil, fmt.Errorf("failed to declare exchange %s: %w", h.exchange, err)
+	}
+
+	queue, err := h.channel.QueueDeclare(
+		"",    // name
+		false, // durable
+		true,  // delete when unused
+		true,  // exclusive
+		false, // no-wait
+		amqp.Table{"x-message-ttl": int32(60000)}, // stale notifications are useless to pollers
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to declare notification queue: %w", err)
+	}
+
+	if err := h.channel.QueueBind(queue.Name, "", h.exchange, false, nil); err != nil {
+		return nil, fmt.Errorf("failed to bind notification queue: %w", err)
+	}
+
+	deliveries, err := h.channel.Consume(
+		queue.Name, // queue
+		"",         // consumer
+		true,       // auto-ack
+		true,       // exclusive
+		false,      // no-local
+		false,      // no-wait
+		nil,        // args
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume notification queue: %w", err)
+	}
+	return deliveries, nil
+}
+
+func (h *Hub) consume(deliveries <-chan amqp.Delivery) {
+	for d := range deliveries {
+		var n Notification
+		if err := json.Unmarshal(d.Body, &n); err != nil {
+			h.logger.Warn("Dropping malformed session notification", zap.Error(err))
+			continue
+		}
+		h.wake(n.Key)
+	}
+}
+
+// Subscribe returns a channel that receives a value when key is notified, and a func that
+// unsubscribes. Notifications arriving while the previous one is unread are coalesced.
+func (h *Hub) Subscribe(key string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	if h.waiters[key] == nil {
+		h.waiters[key] = make(map[chan struct{}]struct{})
+	}
+	h.waiters[key][ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.waiters[key], ch)
+		if len(h.waiters[key]) == 0 {
+			delete(h.waiters, key)
+		}
+	}
+}
+
+// Notify broadcasts that key has something new. Waiters on every node, including this one, are
+// woken when the notification comes back from the exchange.
+func (h *Hub) Notify(ctx context.Context, key string) error {
+	body, err := json.Marshal(Notification{Key: key, At: time.Now().UTC()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+	err = h.channel.PublishWithContext(ctx,
+		h.exchange, // exchange
+		"",         // routing key
+		false,      // mandatory
+		false,      // immediate
+		amqp.Publishing{
+			ContentType: "application/json",
+			Body:        body,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish notification: %w", err)
+	}
+	return nil
+}
+
+func (h *Hub) wake(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.waiters[key] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Close stops consuming and closes the RabbitMQ connection.
+func (h *Hub) Close() error {
+	if h.channel != nil {
+		h.channel.Close()
+	}
+	if h.conn != nil {
+		return h.conn.Close()
+	}
+	return nil
+}
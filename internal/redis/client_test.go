@@ -0,0 +1,310 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer answers RESP commands with the raw replies of its handler. A handler returning ""
+// closes the connection without answering.
+type fakeServer struct {
+	listener net.Listener
+	handler  func(args []string) string
+	// dials counts the connections accepted
+	dials atomic.Int32
+	// commands records every command received, in order, across connections
+	mu       sync.Mutex
+	commands [][]string
+}
+
+func newFakeServer(t *testing.T, handler func(args []string) string) *fakeServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	s := &fakeServer{listener: listener, handler: handler}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.listener.Addr().String() }
+
+func (s *fakeServer) serve() {
+	for {
+		nc, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.dials.Add(1)
+		go s.handle(nc)
+	}
+}
+
+func (s *fakeServer) handle(nc net.Conn) {
+	defer nc.Close()
+	reader := bufio.NewReader(nc)
+	for {
+		// Commands are arrays of bulk strings, which readReply parses like replies
+		request, err := readReply(reader)
+		if err != nil {
+			return
+		}
+		items, _ := request.([]interface{})
+		args := make([]string, len(items))
+		for i, item := range items {
+			args[i], _ = item.(string)
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, args)
+		s.mu.Unlock()
+
+		reply := s.handler(args)
+		if reply == "" {
+			return
+		}
+		if _, err := nc.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *fakeServer) received() [][]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]string(nil), s.commands...)
+}
+
+// ok answers every command with +OK
+func ok([]string) string { return "+OK\r\n" }
+
+// TestReadReply tests parsing of every RESP2 reply type and of malformed replies
+func TestReadReply(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  interface{}
+	}{
+		{"simple string", "+OK\r\n", "OK"},
+		{"error", "-ERR unknown command\r\n", Error("ERR unknown command")},
+		{"integer", ":42\r\n", int64(42)},
+		{"negative integer", ":-7\r\n", int64(-7)},
+		{"bulk string", "$5\r\nhello\r\n", "hello"},
+		{"bulk string with CRLF", "$7\r\nhel\r\nlo\r\n", "hel\r\nlo"},
+		{"empty bulk string", "$0\r\n\r\n", ""},
+		{"null bulk string", "$-1\r\n", nil},
+		{"array", "*3\r\n$3\r\nfoo\r\n:1\r\n$-1\r\n", []interface{}{"foo", int64(1), nil}},
+		{"nested array", "*2\r\n*1\r\n+a\r\n*0\r\n", []interface{}{[]interface{}{"a"}, []interface{}{}}},
+		{"null array", "*-1\r\n", nil},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			reply, err := readReply(bufio.NewReader(strings.NewReader(tc.input)))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, reply)
+		})
+	}
+
+	malformed := map[string]string{
+		"empty line":          "\r\n",
+		"unknown type":        "!oops\r\n",
+		"bad integer":         ":forty-two\r\n",
+		"bad bulk length":     "$x\r\nhello\r\n",
+		"bad array length":    "*x\r\n",
+		"truncated bulk":      "$10\r\nshort\r\n",
+		"truncated array":     "*2\r\n+a\r\n",
+		"missing line ending": "+OK",
+		"no input":            "",
+	}
+	for name, input := range malformed {
+		t.Run(name, func(t *testing.T) {
+			_, err := readReply(bufio.NewReader(strings.NewReader(input)))
+			assert.Error(t, err)
+		})
+	}
+}
+
+// TestPipeline tests that commands are encoded as RESP arrays and their replies returned in order,
+// with error replies in the slot of the command that failed
+func TestPipeline(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		switch args[0] {
+		case "GET":
+			return "$5\r\nvalue\r\n"
+		case "INCR":
+			return ":1\r\n"
+		}
+		return "-ERR unknown command '" + args[0] + "'\r\n"
+	})
+	client := New(server.addr(), "", 0, 1)
+	defer client.Close()
+
+	replies, err := client.Pipeline(context.Background(), []string{"GET", "key with spaces"}, []string{"BOGUS"}, []string{"INCR", "n"})
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"value", Error("ERR unknown command 'BOGUS'"), int64(1)}, replies)
+	assert.Equal(t, [][]string{{"GET", "key with spaces"}, {"BOGUS"}, {"INCR", "n"}}, server.received())
+
+	// Do surfaces error replies as errors
+	_, err = client.Do(context.Background(), "BOGUS")
+	var replyErr Error
+	require.ErrorAs(t, err, &replyErr)
+	assert.Equal(t, "redis: ERR unknown command 'BOGUS'", err.Error())
+
+	// The connection survives error replies and is reused
+	reply, err := client.Do(context.Background(), "GET", "key")
+	require.NoError(t, err)
+	assert.Equal(t, "value", reply)
+	assert.Equal(t, int32(1), server.dials.Load())
+}
+
+// TestConnectionSetup tests that new connections authenticate and select the database first
+func TestConnectionSetup(t *testing.T) {
+	server := newFakeServer(t, ok)
+	client := New(server.addr(), "s3cret", 2, 1)
+	defer client.Close()
+
+	_, err := client.Do(context.Background(), "PING")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"AUTH", "s3cret"}, {"SELECT", "2"}, {"PING"}}, server.received())
+}
+
+// TestConnectionSetupFailure tests that a refused AUTH fails the command and drops the connection
+func TestConnectionSetupFailure(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "AUTH" {
+			return "-WRONGPASS invalid username-password pair\r\n"
+		}
+		return "+OK\r\n"
+	})
+	client := New(server.addr(), "wrong", 0, 1)
+	defer client.Close()
+
+	_, err := client.Do(context.Background(), "PING")
+	assert.EqualError(t, err, "redis: WRONGPASS invalid username-password pair")
+	assert.Len(t, client.idle, 0)
+
+	// Each attempt dials again rather than reusing the refused connection
+	_, err = client.Do(context.Background(), "PING")
+	assert.Error(t, err)
+	assert.Equal(t, int32(2), server.dials.Load())
+}
+
+// TestPoolExhaustion tests that commands beyond the pool size dial connections of their own, and
+// that only poolSize of them are kept once returned
+func TestPoolExhaustion(t *testing.T) {
+	release := make(chan struct{})
+	var waiting sync.WaitGroup
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "BLOCK" {
+			waiting.Done()
+			<-release
+		}
+		return "+OK\r\n"
+	})
+	const poolSize, concurrent = 2, 5
+	client := New(server.addr(), "", 0, poolSize)
+	defer client.Close()
+
+	waiting.Add(concurrent)
+	errs := make(chan error, concurrent)
+	for i := 0; i < concurrent; i++ {
+		go func() {
+			_, err := client.Do(context.Background(), "BLOCK")
+			errs <- err
+		}()
+	}
+	// Every command is in flight at once, each on its own connection
+	waiting.Wait()
+	assert.Equal(t, int32(concurrent), server.dials.Load())
+	close(release)
+	for i := 0; i < concurrent; i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.Len(t, client.idle, poolSize)
+
+	// Later commands reuse the pooled connections
+	for i := 0; i < 3; i++ {
+		_, err := client.Do(context.Background(), "PING")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, int32(concurrent), server.dials.Load())
+}
+
+// TestConnectionDropped tests that a connection the server drops before replying isn't reused
+func TestConnectionDropped(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		if args[0] == "HALF" {
+			// Hangs up without answering
+			return ""
+		}
+		return "+OK\r\n"
+	})
+	client := New(server.addr(), "", 0, 1)
+	defer client.Close()
+
+	_, err := client.Do(context.Background(), "HALF")
+	assert.Error(t, err)
+	assert.Len(t, client.idle, 0)
+
+	_, err = client.Do(context.Background(), "PING")
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), server.dials.Load())
+}
+
+// TestTimeout tests that a server that never answers fails the command at the context's deadline
+func TestTimeout(t *testing.T) {
+	server := newFakeServer(t, func(args []string) string {
+		time.Sleep(time.Second)
+		return "+OK\r\n"
+	})
+	client := New(server.addr(), "", 0, 1)
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := client.Do(ctx, "PING")
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	assert.True(t, netErr.Timeout())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+	assert.Len(t, client.idle, 0)
+}
+
+// TestDialFailure tests that an unreachable server fails the command
+func TestDialFailure(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	client := New(addr, "", 0, 1)
+	_, err = client.Do(context.Background(), "PING")
+	assert.Error(t, err)
+	assert.Len(t, client.idle, 0)
+}
+
+// TestClose tests that Close closes the idle connections
+func TestClose(t *testing.T) {
+	server := newFakeServer(t, ok)
+	client := New(server.addr(), "", 0, 1)
+
+	_, err := client.Do(context.Background(), "PING")
+	require.NoError(t, err)
+	require.Len(t, client.idle, 1)
+	cn := <-client.idle
+	client.idle <- cn
+
+	require.NoError(t, client.Close())
+	assert.Len(t, client.idle, 0)
+	_, err = fmt.Fprint(cn, "*1\r\n$4\r\nPING\r\n")
+	assert.Error(t, err, "writing to a closed connection")
+}
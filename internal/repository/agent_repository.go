@@ -0,0 +1,195 @@
+// Package repository provides data access layer for human agents and assignment rules.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AgentRepository handles database operations for agents.
+type AgentRepository struct {
+	collection *Collection
+}
+
+// NewAgentRepository creates a new AgentRepository.
+func NewAgentRepository(db *mongo.Database) *AgentRepository {
+	return &AgentRepository{
+		collection: newCollection(db, models.Agent{}.TableName()),
+	}
+}
+
+// Create inserts a new agent.
+func (r *AgentRepository) Create(ctx context.Context, agent *models.Agent) error {
+	agent.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, agent); err != nil {
+		return fmt.Errorf("failed to insert agent: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves an agent by its ID.
+func (r *AgentRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Agent, error) {
+	var agent models.Agent
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&agent)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("agent not found")
+		}
+		return nil, fmt.Errorf("failed to find agent: %w", err)
+	}
+	return &agent, nil
+}
+
+// GetByAgentID retrieves a client's agent by its external agent ID.
+func (r *AgentRepository) GetByAgentID(ctx context.Context, clientID primitive.ObjectID, agentID string) (*models.Agent, error) {
+	var agent models.Agent
+	err := r.collection.FindOne(ctx, bson.M{"client": clientID, "agent_id": agentID}).Decode(&agent)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("agent not found")
+		}
+		return nil, fmt.Errorf("failed to find agent: %w", err)
+	}
+	return &agent, nil
+}
+
+// List retrieves agents matching filter.
+func (r *AgentRepository) List(ctx context.Context, filter map[string]interface{}) ([]models.Agent, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "agent_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find agents: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	agents := make([]models.Agent, 0)
+	if err := cursor.All(ctx, &agents); err != nil {
+		return nil, fmt.Errorf("failed to decode agents: %w", err)
+	}
+	return agents, nil
+}
+
+// Update modifies an existing agent.
+func (r *AgentRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	update["updated_at"] = time.Now().UTC()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return fmt.Errorf("failed to update agent: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("agent not found")
+	}
+	return nil
+}
+
+// Reserve takes one session slot on an available agent with spare capacity.
+// It returns false if the agent became unavailable or full since it was picked.
+func (r *AgentRepository) Reserve(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	now := time.Now().UTC()
+	filter := bson.M{
+		"_id":    id,
+		"status": models.AgentStatusAvailable,
+		"$expr":  bson.M{"$lt": bson.A{"$active_sessions", "$max_concurrent"}},
+	}
+	update := bson.M{
+		"$inc": bson.M{"active_sessions": 1},
+		"$set": bson.M{"last_assigned_at": now, "updated_at": now},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve agent: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// Acquire takes one session slot on an agent regardless of status or capacity.
+// Used when an agent picks up a session themselves rather than being routed to it.
+func (r *AgentRepository) Acquire(ctx context.Context, id primitive.ObjectID) error {
+	now := time.Now().UTC()
+	update := bson.M{
+		"$inc": bson.M{"active_sessions": 1},
+		"$set": bson.M{"last_assigned_at": now, "updated_at": now},
+	}
+	if _, err := r.collection.UpdateByID(ctx, id, update); err != nil {
+		return fmt.Errorf("failed to acquire agent: %w", err)
+	}
+	return nil
+}
+
+// Release frees one session slot on a client's agent.
+func (r *AgentRepository) Release(ctx context.Context, clientID primitive.ObjectID, agentID string) error {
+	filter := bson.M{
+		"client":          clientID,
+		"agent_id":        agentID,
+		"active_sessions": bson.M{"$gt": 0},
+	}
+	update := bson.M{
+		"$inc": bson.M{"active_sessions": -1},
+		"$set": bson.M{"updated_at": time.Now().UTC()},
+	}
+
+	if _, err := r.collection.UpdateOne(ctx, filter, update); err != nil {
+		return fmt.Errorf("failed to release agent: %w", err)
+	}
+	return nil
+}
+
+// AssignmentRuleRepository handles database operations for assignment rules.
+type AssignmentRuleRepository struct {
+	collection *Collection
+}
+
+// NewAssignmentRuleRepository creates a new AssignmentRuleRepository.
+func NewAssignmentRuleRepository(db *mongo.Database) *AssignmentRuleRepository {
+	return &AssignmentRuleRepository{
+		collection: newCollection(db, models.AssignmentRule{}.TableName()),
+	}
+}
+
+// Create inserts a new assignment rule.
+func (r *AssignmentRuleRepository) Create(ctx context.Context, rule *models.AssignmentRule) error {
+	rule.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, rule); err != nil {
+		return fmt.Errorf("failed to insert assignment rule: %w", err)
+	}
+	return nil
+}
+
+// ListByClient retrieves a client's assignment rules in evaluation order.
+func (r *AssignmentRuleRepository) ListByClient(ctx context.Context, clientID primitive.ObjectID) ([]models.AssignmentRule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "priority", Value: 1}, {Key: "created_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"client": clientID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find assignment rules: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	rules := make([]models.AssignmentRule, 0)
+	if err := cursor.All(ctx, &rules); err != nil {
+		return nil, fmt.Errorf("failed to decode assignment rules: %w", err)
+	}
+	return rules, nil
+}
+
+// Delete removes a client's assignment rule.
+func (r *AssignmentRuleRepository) Delete(ctx context.Context, clientID, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "client": clientID})
+	if err != nil {
+		return fmt.Errorf("failed to delete assignment rule: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("assignment rule not found")
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+// Package repository provides data access layer for cached AI answers.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AnswerCacheRepository handles database operations for cached AI answers.
+type AnswerCacheRepository struct {
+	collection *Collection
+}
+
+// NewAnswerCacheRepository creates a new AnswerCacheRepository.
+func NewAnswerCacheRepository(db *mongo.Database) *AnswerCacheRepository {
+	return &AnswerCacheRepository{
+		collection: newCollection(db, models.AnswerCacheEntry{}.TableName()),
+	}
+}
+
+// Hit returns the client's unexpired entry for key, counting the hit, or nil when there is none.
+func (r *AnswerCacheRepository) Hit(ctx context.Context, clientID, key string) (*models.AnswerCacheEntry, error) {
+	now := time.Now().UTC()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var entry models.AnswerCacheEntry
+	err := r.collection.FindOneAndUpdate(ctx,
+		// Expired entries linger until the TTL monitor removes them
+		bson.M{"client_id": clientID, "key": key, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$inc": bson.M{"hits": 1}, "$set": bson.M{"last_hit_at": now}},
+		opts,
+	).Decode(&entry)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find cached answer: %w", err)
+	}
+	return &entry, nil
+}
+
+// Put stores entry, replacing the client's entry for the same key.
+func (r *AnswerCacheRepository) Put(ctx context.Context, entry *models.AnswerCacheEntry) error {
+	entry.CreatedAt = time.Now().UTC()
+	entry.Hits, entry.LastHitAt = 0, nil
+	_, err := r.collection.UpdateOne(ctx,
+		bson.M{"client_id": entry.ClientID, "key": entry.Key},
+		bson.M{"$set": bson.M{
+			"question":          entry.Question,
+			"knowledge_version": entry.KnowledgeVersion,
+			"suggestion_mode":   entry.SuggestionMode,
+			"response":          entry.Response,
+			"confidence":        entry.Confidence,
+			"hits":              entry.Hits,
+			"last_hit_at":       entry.LastHitAt,
+			"expires_at":        entry.ExpiresAt,
+			"created_at":        entry.CreatedAt,
+		}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to store cached answer: %w", err)
+	}
+	return nil
+}
+
+// DeleteMany removes the cached answers matching filter and reports how many there were.
+func (r *AnswerCacheRepository) DeleteMany(ctx context.Context, filter bson.M) (int64, error) {
+	result, err := r.collection.DeleteMany(ctx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete cached answers: %w", err)
+	}
+	return result.DeletedCount, nil
+}
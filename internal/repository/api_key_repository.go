@@ -0,0 +1,109 @@
+// Package repository provides data access layer for client API keys.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyRepository handles database operations for client API keys.
+type APIKeyRepository struct {
+	collection *Collection
+}
+
+// NewAPIKeyRepository creates a new APIKeyRepository.
+func NewAPIKeyRepository(db *mongo.Database) *APIKeyRepository {
+	return &APIKeyRepository{
+		collection: newCollection(db, models.APIKey{}.TableName()),
+	}
+}
+
+// Create inserts a new API key.
+func (r *APIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	key.ID = primitive.NewObjectID()
+	key.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, key); err != nil {
+		return fmt.Errorf("failed to insert API key: %w", err)
+	}
+	return nil
+}
+
+// GetByHash retrieves the API key with the given key hash.
+func (r *APIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.collection.FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("API key not found")
+		}
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+	return &key, nil
+}
+
+// GetByID retrieves one of a client's API keys.
+func (r *APIKeyRepository) GetByID(ctx context.Context, clientID, id primitive.ObjectID) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "client": clientID}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("API key not found")
+		}
+		return nil, fmt.Errorf("failed to find API key: %w", err)
+	}
+	return &key, nil
+}
+
+// ListByClient retrieves a client's API keys, newest first.
+func (r *APIKeyRepository) ListByClient(ctx context.Context, clientID primitive.ObjectID) ([]models.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"client": clientID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find API keys: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := make([]models.APIKey, 0)
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("failed to decode API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Update modifies an API key.
+func (r *APIKeyRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	update["updated_at"] = time.Now().UTC()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return fmt.Errorf("failed to update API key: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("API key not found")
+	}
+	return nil
+}
+
+// TouchLastUsed records when a key was last used. Updates to the same key within interval are
+// skipped so busy keys don't cost a write per request.
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id primitive.ObjectID, now time.Time, interval time.Duration) error {
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"last_used_at": bson.M{"$exists": false}},
+			bson.M{"last_used_at": bson.M{"$lt": now.Add(-interval)}},
+		},
+	}
+	if _, err := r.collection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"last_used_at": now}}); err != nil {
+		return fmt.Errorf("failed to record API key usage: %w", err)
+	}
+	return nil
+}
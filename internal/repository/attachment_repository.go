@@ -0,0 +1,80 @@
+// Package repository provides MongoDB GridFS storage for message attachment files.
+package repository
+
+import (
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// StoredAttachment describes a file in attachment storage.
+type StoredAttachment struct {
+	ID          primitive.ObjectID
+	FileName    string
+	ContentType string
+	Size        int64
+}
+
+// AttachmentRepository stores attachment files in the "attachments" GridFS bucket.
+type AttachmentRepository struct {
+	Bucket *gridfs.Bucket
+}
+
+func NewAttachmentRepository(db *mongo.Database) (*AttachmentRepository, error) {
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName("attachments"))
+	if err != nil {
+		return nil, err
+	}
+	return &AttachmentRepository{Bucket: bucket}, nil
+}
+
+// Upload streams content into storage and returns the stored file.
+func (r *AttachmentRepository) Upload(fileName, contentType string, metadata bson.M, content io.Reader) (*StoredAttachment, error) {
+	meta := bson.M{"content_type": contentType}
+	for k, v := range metadata {
+		meta[k] = v
+	}
+	stream, err := r.Bucket.OpenUploadStream(fileName, options.GridFSUpload().SetMetadata(meta))
+	if err != nil {
+		return nil, err
+	}
+	size, err := io.Copy(stream, content)
+	if err != nil {
+		_ = stream.Abort()
+		return nil, err
+	}
+	if err := stream.Close(); err != nil {
+		return nil, err
+	}
+	return &StoredAttachment{
+		ID:          stream.FileID.(primitive.ObjectID),
+		FileName:    fileName,
+		ContentType: contentType,
+		Size:        size,
+	}, nil
+}
+
+// Open returns the stored file and a reader for its content; the caller closes the reader.
+func (r *AttachmentRepository) Open(id primitive.ObjectID) (*StoredAttachment, io.ReadCloser, error) {
+	stream, err := r.Bucket.OpenDownloadStream(id)
+	if err != nil {
+		return nil, nil, err
+	}
+	file := stream.GetFile()
+	var meta struct {
+		ContentType string `bson:"content_type"`
+	}
+	if file.Metadata != nil {
+		_ = bson.Unmarshal(file.Metadata, &meta)
+	}
+	return &StoredAttachment{
+		ID:          id,
+		FileName:    file.Name,
+		ContentType: meta.ContentType,
+		Size:        file.Length,
+	}, stream, nil
+}
@@ -0,0 +1,111 @@
+// Package repository provides data access layer for canned responses.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CannedResponseRepository handles database operations for canned responses.
+type CannedResponseRepository struct {
+	collection *Collection
+}
+
+// NewCannedResponseRepository creates a new CannedResponseRepository.
+func NewCannedResponseRepository(db *mongo.Database) *CannedResponseRepository {
+	return &CannedResponseRepository{
+		collection: newCollection(db, models.CannedResponse{}.TableName()),
+	}
+}
+
+// Create inserts a new canned response.
+func (r *CannedResponseRepository) Create(ctx context.Context, resp *models.CannedResponse) error {
+	resp.ID = primitive.NewObjectID()
+	resp.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, resp); err != nil {
+		return fmt.Errorf("failed to insert canned response: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a client's canned response by its ID.
+func (r *CannedResponseRepository) GetByID(ctx context.Context, clientID string, id primitive.ObjectID) (*models.CannedResponse, error) {
+	var resp models.CannedResponse
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "client_id": clientID}).Decode(&resp)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("canned response not found")
+		}
+		return nil, fmt.Errorf("failed to find canned response: %w", err)
+	}
+	return &resp, nil
+}
+
+// List retrieves a client's canned responses, most used first.
+func (r *CannedResponseRepository) List(ctx context.Context, filter bson.M, limit, offset int) ([]models.CannedResponse, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "usage_count", Value: -1}, {Key: "title", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find canned responses: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	responses := make([]models.CannedResponse, 0)
+	if err := cursor.All(ctx, &responses); err != nil {
+		return nil, fmt.Errorf("failed to decode canned responses: %w", err)
+	}
+	return responses, nil
+}
+
+// Update modifies a client's canned response.
+func (r *CannedResponseRepository) Update(ctx context.Context, clientID string, id primitive.ObjectID, update bson.M) error {
+	update["updated_at"] = time.Now().UTC()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id, "client_id": clientID}, bson.M{"$set": update})
+	if err != nil {
+		return fmt.Errorf("failed to update canned response: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("canned response not found")
+	}
+	return nil
+}
+
+// Delete removes a client's canned response.
+func (r *CannedResponseRepository) Delete(ctx context.Context, clientID string, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "client_id": clientID})
+	if err != nil {
+		return fmt.Errorf("failed to delete canned response: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("canned response not found")
+	}
+	return nil
+}
+
+// IncrementUsage bumps the usage counter and records when the response was last sent.
+func (r *CannedResponseRepository) IncrementUsage(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$inc": bson.M{"usage_count": 1},
+		"$set": bson.M{"last_used_at": time.Now().UTC()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record canned response usage: %w", err)
+	}
+	return nil
+}
@@ -13,12 +13,12 @@ import (
 )
 
 type ChatMessageFeedbackRepository struct {
-	Collection *mongo.Collection
+	Collection *Collection
 }
 
 func NewChatMessageFeedbackRepository(db *mongo.Database) *ChatMessageFeedbackRepository {
 	return &ChatMessageFeedbackRepository{
-		Collection: db.Collection("chat_message_feedback"),
+		Collection: newCollection(db, models.ChatMessageFeedback{}.TableName()),
 	}
 }
 
@@ -50,13 +50,60 @@ func (r *ChatMessageFeedbackRepository) ListFeedbacksByMessageID(ctx context.Con
 	return feedbacks, cur.Err()
 }
 
-func (r *ChatMessageFeedbackRepository) UpdateFeedback(ctx context.Context, feedbackID primitive.ObjectID, update bson.M) (*models.ChatMessageFeedback, error) {
+func (r *ChatMessageFeedbackRepository) UpdateFeedback(ctx context.Context, messageID, feedbackID primitive.ObjectID, update bson.M) (*models.ChatMessageFeedback, error) {
 	update["updated_at"] = time.Now()
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	var updated models.ChatMessageFeedback
-	err := r.Collection.FindOneAndUpdate(ctx, bson.M{"_id": feedbackID}, bson.M{"$set": update}, opts).Decode(&updated)
+	err := r.Collection.FindOneAndUpdate(ctx, bson.M{"_id": feedbackID, "chat_message_id": messageID}, bson.M{"$set": update}, opts).Decode(&updated)
 	if err != nil {
 		return nil, err
 	}
 	return &updated, nil
 }
+
+// QueueForward marks a feedback for forwarding to the AI service on behalf of clientID, starting
+// afresh if it was forwarded before.
+func (r *ChatMessageFeedbackRepository) QueueForward(ctx context.Context, id primitive.ObjectID, clientID string, now time.Time) error {
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$set": bson.M{
+			"client_id":        clientID,
+			"forward_status":   models.FeedbackForwardPending,
+			"forward_attempts": 0,
+			"next_forward_at":  now,
+		},
+		"$unset": bson.M{"forwarded_at": "", "forward_error": ""},
+	})
+	return err
+}
+
+// ListDueForwards returns up to limit feedbacks waiting to be forwarded whose next attempt is due
+// by now, longest waiting first.
+func (r *ChatMessageFeedbackRepository) ListDueForwards(ctx context.Context, now time.Time, limit int64) ([]models.ChatMessageFeedback, error) {
+	filter := bson.M{
+		"forward_status":  models.FeedbackForwardPending,
+		"next_forward_at": bson.M{"$lte": now},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "next_forward_at", Value: 1}}).SetLimit(limit)
+	cur, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var feedbacks []models.ChatMessageFeedback
+	if err := cur.All(ctx, &feedbacks); err != nil {
+		return nil, err
+	}
+	return feedbacks, nil
+}
+
+// UpdateForward sets the forwarding fields of a feedback, unless it was changed since it was read
+// at updatedAt; a changed feedback is forwarded again in its new form. It reports whether the
+// feedback was updated.
+func (r *ChatMessageFeedbackRepository) UpdateForward(ctx context.Context, id primitive.ObjectID, updatedAt time.Time, set bson.M) (bool, error) {
+	res, err := r.Collection.UpdateOne(ctx, bson.M{"_id": id, "updated_at": updatedAt}, bson.M{"$set": set})
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
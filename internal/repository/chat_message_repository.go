@@ -16,13 +16,13 @@ import (
 
 // ChatMessageRepository handles CRUD operations for chat messages.
 type ChatMessageRepository struct {
-	Collection *mongo.Collection
+	Collection *Collection
 }
 
 // NewChatMessageRepository creates a new repository for chat messages.
 func NewChatMessageRepository(db *mongo.Database) *ChatMessageRepository {
 	return &ChatMessageRepository{
-		Collection: db.Collection("chat_messages"),
+		Collection: newCollection(db, "chat_messages"),
 	}
 }
 
@@ -102,6 +102,28 @@ func (r *ChatMessageRepository) BulkCreate(ctx context.Context, msgs []models.Ch
 	return nil
 }
 
+// Import inserts messages backfilled by an import, keeping their CreatedAt. Messages are inserted
+// independently of each other; the write errors of those that failed are returned by index.
+func (r *ChatMessageRepository) Import(ctx context.Context, msgs []models.ChatMessage) (map[int]mongo.WriteError, error) {
+	docs := make([]interface{}, len(msgs))
+	for i := range msgs {
+		msgs[i].ID = primitive.NewObjectID()
+		msgs[i].UpdatedAt = msgs[i].CreatedAt
+		docs[i] = msgs[i]
+	}
+
+	_, err := r.Collection.InsertMany(ctx, docs, options.InsertMany().SetOrdered(false))
+	var bulkErr mongo.BulkWriteException
+	if errors.As(err, &bulkErr) && bulkErr.WriteConcernError == nil {
+		failed := make(map[int]mongo.WriteError, len(bulkErr.WriteErrors))
+		for _, we := range bulkErr.WriteErrors {
+			failed[we.Index] = we.WriteError
+		}
+		return failed, nil
+	}
+	return nil, err
+}
+
 // GetByID retrieves a chat message by its ObjectID.
 func (r *ChatMessageRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.ChatMessage, error) {
 	var msg models.ChatMessage
@@ -111,3 +133,99 @@ func (r *ChatMessageRepository) GetByID(ctx context.Context, id primitive.Object
 	}
 	return &msg, nil
 }
+
+// ListReplies retrieves direct replies to a message, oldest first.
+func (r *ChatMessageRepository) ListReplies(ctx context.Context, parentID primitive.ObjectID, limit int64) ([]models.ChatMessage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(limit)
+	}
+	cursor, err := r.Collection.Find(ctx, bson.M{"parent_message": parentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	messages := make([]models.ChatMessage, 0)
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// IncrementReplyCount bumps the denormalized reply counter on a parent message.
+func (r *ChatMessageRepository) IncrementReplyCount(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.Collection.UpdateByID(ctx, id, bson.M{
+		"$inc": bson.M{"reply_count": 1},
+		"$set": bson.M{"updated_at": time.Now().UTC()},
+	})
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return errors.New("chat message not found")
+	}
+	return nil
+}
+
+// ListSince returns up to limit messages of a session with an _id after the given one, oldest first.
+func (r *ChatMessageRepository) ListSince(ctx context.Context, sessionID, after primitive.ObjectID, limit int64) ([]models.ChatMessage, error) {
+	filter := bson.M{"session": sessionID}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	opts := options.Find().SetSort(bson.D{{"_id", 1}}).SetLimit(limit)
+	cursor, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var messages []models.ChatMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// afterFilter matches the messages of a session that come after msg, by created_at and then _id.
+// A nil msg matches every message of the session.
+func afterFilter(sessionID primitive.ObjectID, msg *models.ChatMessage) bson.M {
+	filter := bson.M{"session": sessionID}
+	if msg != nil {
+		filter["$or"] = bson.A{
+			bson.M{"created_at": bson.M{"$gt": msg.CreatedAt}},
+			bson.M{"created_at": msg.CreatedAt, "_id": bson.M{"$gt": msg.ID}},
+		}
+	}
+	return filter
+}
+
+// CountAfter counts the messages of a session that come after msg, or all of them when msg is nil.
+func (r *ChatMessageRepository) CountAfter(ctx context.Context, sessionID primitive.ObjectID, msg *models.ChatMessage) (int64, error) {
+	return r.Collection.CountDocuments(ctx, afterFilter(sessionID, msg))
+}
+
+// MoveAfter moves the messages of session from that come after msg, or all of them when msg is
+// nil, to session to, and returns how many it moved.
+func (r *ChatMessageRepository) MoveAfter(ctx context.Context, from, to primitive.ObjectID, msg *models.ChatMessage) (int64, error) {
+	res, err := r.Collection.UpdateMany(ctx, afterFilter(from, msg), bson.M{"$set": bson.M{"session": to, "updated_at": time.Now().UTC()}})
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// ReassignContact links the messages of contact from to contact to, or unlinks them when to is
+// nil, and returns how many it changed.
+func (r *ChatMessageRepository) ReassignContact(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	update := bson.M{"$unset": bson.M{"contact": ""}}
+	if to != nil {
+		update = bson.M{"$set": bson.M{"contact": *to}}
+	}
+	res, err := r.Collection.UpdateMany(ctx, bson.M{"contact": from}, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
@@ -13,12 +13,12 @@ import (
 )
 
 type ChatSessionRecapRepository struct {
-	Collection *mongo.Collection
+	Collection *Collection
 }
 
 func NewChatSessionRecapRepository(db *mongo.Database) *ChatSessionRecapRepository {
 	return &ChatSessionRecapRepository{
-		Collection: db.Collection("chat_session_recaps"),
+		Collection: newCollection(db, "chat_session_recaps"),
 	}
 }
 
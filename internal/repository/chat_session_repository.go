@@ -13,12 +13,12 @@ import (
 )
 
 type ChatSessionRepository struct {
-	Collection *mongo.Collection
+	Collection *Collection
 }
 
 func NewChatSessionRepository(db *mongo.Database) *ChatSessionRepository {
 	return &ChatSessionRepository{
-		Collection: db.Collection("chat_sessions"),
+		Collection: newCollection(db, "chat_sessions"),
 	}
 }
 
@@ -91,3 +91,239 @@ func (r *ChatSessionRepository) ListWithFilters(ctx context.Context, filter bson
 	}
 	return sessions, count, nil
 }
+
+// UpdateHandover replaces the session's handover only if its current handover status is one of from.
+// allowNone also matches sessions that have never been handed over. Returns false when the guard fails.
+func (r *ChatSessionRepository) UpdateHandover(ctx context.Context, id primitive.ObjectID, from []models.HandoverStatus, allowNone bool, handover *models.SessionHandover) (bool, error) {
+	conditions := bson.A{}
+	if len(from) > 0 {
+		conditions = append(conditions, bson.M{"handover.status": bson.M{"$in": from}})
+	}
+	if allowNone {
+		conditions = append(conditions, bson.M{"handover": bson.M{"$exists": false}})
+	}
+
+	filter := bson.M{"_id": id, "$or": conditions}
+	update := bumpVersion(bson.M{"$set": bson.M{"handover": handover, "updated_at": time.Now()}})
+	res, err := r.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+// AddAssignment appends an agent assignment to the session's history.
+func (r *ChatSessionRepository) AddAssignment(ctx context.Context, id primitive.ObjectID, assignment models.SessionAssignment) error {
+	update := bumpVersion(bson.M{
+		"$push": bson.M{"assignments": assignment},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+	_, err := r.Collection.UpdateByID(ctx, id, update)
+	return err
+}
+
+// ReleaseAssignment marks the agent's open assignment on the session as released.
+func (r *ChatSessionRepository) ReleaseAssignment(ctx context.Context, id primitive.ObjectID, agentID string, releasedAt time.Time) error {
+	filter := bson.M{
+		"_id": id,
+		"assignments": bson.M{"$elemMatch": bson.M{
+			"agent_id":    agentID,
+			"released_at": bson.M{"$exists": false},
+		}},
+	}
+	update := bumpVersion(bson.M{"$set": bson.M{
+		"assignments.$.released_at": releasedAt,
+		"updated_at":                time.Now(),
+	}})
+	_, err := r.Collection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// UpdateTags adds and removes tags on a session and returns the updated session. With version set
+// the update only applies at that version, and fails with a VersionConflictError otherwise. Tags
+// both added and removed end up removed.
+func (r *ChatSessionRepository) UpdateTags(ctx context.Context, id primitive.ObjectID, version *int64, add, remove []string) (*models.ChatSession, error) {
+	// MongoDB rejects $addToSet and $pull on the same field in one update, so both go in one
+	// pipeline stage. The tags are literals, so a tag starting with $ isn't read as a field path.
+	tags := bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}
+	if len(add) > 0 {
+		tags = bson.M{"$setUnion": bson.A{tags, bson.M{"$literal": add}}}
+	}
+	if len(remove) > 0 {
+		tags = bson.M{"$setDifference": bson.A{tags, bson.M{"$literal": remove}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tags": tags, "updated_at": time.Now()}}},
+		bumpVersionStage(),
+	}
+	return r.findOneAndUpdateVersioned(ctx, id, version, pipeline)
+}
+
+// UpdateAttributes sets and unsets custom attributes on a session and returns the updated session.
+func (r *ChatSessionRepository) UpdateAttributes(ctx context.Context, id primitive.ObjectID, set map[string]string, unset []string) (*models.ChatSession, error) {
+	return r.UpdateAttributesAtVersion(ctx, id, nil, set, unset)
+}
+
+// UpdateAttributesAtVersion is UpdateAttributes that, with version set, only applies at that
+// version and fails with a VersionConflictError otherwise.
+func (r *ChatSessionRepository) UpdateAttributesAtVersion(ctx context.Context, id primitive.ObjectID, version *int64, set map[string]string, unset []string) (*models.ChatSession, error) {
+	fields := bson.M{"updated_at": time.Now()}
+	for key, value := range set {
+		fields["attributes."+key] = value
+	}
+	update := bson.M{"$set": fields}
+	if len(unset) > 0 {
+		removed := bson.M{}
+		for _, key := range unset {
+			removed["attributes."+key] = ""
+		}
+		update["$unset"] = removed
+	}
+	return r.updateVersioned(ctx, id, version, update)
+}
+
+// updateVersioned applies update to the session at version, or at any version when it is nil, and
+// moves it to the next version.
+func (r *ChatSessionRepository) updateVersioned(ctx context.Context, id primitive.ObjectID, version *int64, update bson.M) (*models.ChatSession, error) {
+	return r.findOneAndUpdateVersioned(ctx, id, version, bumpVersion(update))
+}
+
+// findOneAndUpdateVersioned applies update, which must move the session to its next version, to
+// the session at version, or at any version when it is nil.
+func (r *ChatSessionRepository) findOneAndUpdateVersioned(ctx context.Context, id primitive.ObjectID, version *int64, update interface{}) (*models.ChatSession, error) {
+	var session models.ChatSession
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.Collection.FindOneAndUpdate(ctx, versionFilter(bson.M{"_id": id}, version), update, opts).Decode(&session)
+	if err == mongo.ErrNoDocuments && version != nil {
+		return nil, versionConflict(ctx, r.Collection, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *ChatSessionRepository) findOneAndUpdate(ctx context.Context, id primitive.ObjectID, update bson.M) (*models.ChatSession, error) {
+	var session models.ChatSession
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if err := r.Collection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update, opts).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UpdateState applies set to a session only if it is still in state from, where "" matches sessions
+// created before lifecycle states existed. Returns nil when the guard fails.
+func (r *ChatSessionRepository) UpdateState(ctx context.Context, id primitive.ObjectID, from models.SessionState, set bson.M) (*models.ChatSession, error) {
+	filter := bson.M{"_id": id, "state": from}
+	if from == "" {
+		filter["state"] = bson.M{"$exists": false}
+	}
+	set["updated_at"] = time.Now()
+
+	var session models.ChatSession
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := r.Collection.FindOneAndUpdate(ctx, filter, bumpVersion(bson.M{"$set": set}), opts).Decode(&session)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// SetContact links a session to a contact unless it is linked already.
+func (r *ChatSessionRepository) SetContact(ctx context.Context, id, contactID primitive.ObjectID) error {
+	_, err := r.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "contact": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"contact": contactID, "updated_at": time.Now()}})
+	return err
+}
+
+// ReassignContact links the sessions of contact from to contact to, or unlinks them when to is
+// nil, and returns how many it changed.
+func (r *ChatSessionRepository) ReassignContact(ctx context.Context, from primitive.ObjectID, to *primitive.ObjectID) (int64, error) {
+	update := bson.M{"$unset": bson.M{"contact": ""}}
+	if to != nil {
+		update = bson.M{"$set": bson.M{"contact": *to}}
+	}
+	res, err := r.Collection.UpdateMany(ctx, bson.M{"contact": from}, update)
+	if err != nil {
+		return 0, err
+	}
+	return res.ModifiedCount, nil
+}
+
+// CreateImported inserts a session backfilled by an import, keeping the CreatedAt it was given.
+func (r *ChatSessionRepository) CreateImported(ctx context.Context, session *models.ChatSession) error {
+	session.ID = primitive.NewObjectID()
+	session.UpdatedAt = time.Now()
+	_, err := r.Collection.InsertOne(ctx, session)
+	return err
+}
+
+// ExtendImported widens a session created by the import importID to span messages from first to
+// last. Sessions the import only added messages to are left alone.
+func (r *ChatSessionRepository) ExtendImported(ctx context.Context, id, importID primitive.ObjectID, first, last time.Time) error {
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": id, "import": importID}, bson.M{
+		"$min": bson.M{"created_at": first},
+		"$max": bson.M{"last_activity_at": last, "closed_at": last},
+	})
+	return err
+}
+
+// Touch records activity on a session.
+func (r *ChatSessionRepository) Touch(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	_, err := r.Collection.UpdateByID(ctx, id, bson.M{"$set": bson.M{"last_activity_at": at}})
+	return err
+}
+
+// UpdateLowConfidenceStreak extends the session's run of low-confidence AI answers, or ends it,
+// and returns the new streak length.
+func (r *ChatSessionRepository) UpdateLowConfidenceStreak(ctx context.Context, id primitive.ObjectID, low bool) (int, error) {
+	update := bson.M{"$set": bson.M{"low_confidence_streak": 0}}
+	if low {
+		update = bson.M{"$inc": bson.M{"low_confidence_streak": 1}}
+	}
+	session, err := r.findOneAndUpdate(ctx, id, update)
+	if err != nil {
+		return 0, err
+	}
+	return session.LowConfidenceStreak, nil
+}
+
+// ListInactive returns up to limit open, pending or reopened sessions of a client with no activity since cutoff.
+func (r *ChatSessionRepository) ListInactive(ctx context.Context, clientID primitive.ObjectID, cutoff time.Time, limit int64) ([]models.ChatSession, error) {
+	filter := bson.M{
+		"client": clientID,
+		"active": true,
+		"state": bson.M{"$nin": []models.SessionState{models.SessionStateClosed, models.SessionStateSnoozed}},
+		"$or": bson.A{
+			bson.M{"last_activity_at": bson.M{"$lt": cutoff}},
+			bson.M{"last_activity_at": bson.M{"$exists": false}, "updated_at": bson.M{"$lt": cutoff}},
+		},
+	}
+	return r.find(ctx, filter, options.Find().SetLimit(limit).SetSort(bson.D{{Key: "updated_at", Value: 1}}))
+}
+
+// ListSnoozeExpired returns up to limit snoozed sessions whose snooze ended before now.
+func (r *ChatSessionRepository) ListSnoozeExpired(ctx context.Context, now time.Time, limit int64) ([]models.ChatSession, error) {
+	filter := bson.M{
+		"state":         models.SessionStateSnoozed,
+		"snoozed_until": bson.M{"$lte": now},
+	}
+	return r.find(ctx, filter, options.Find().SetLimit(limit).SetSort(bson.D{{Key: "snoozed_until", Value: 1}}))
+}
+
+func (r *ChatSessionRepository) find(ctx context.Context, filter bson.M, opts *options.FindOptions) ([]models.ChatSession, error) {
+	cur, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	var sessions []models.ChatSession
+	if err := cur.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
@@ -13,12 +13,12 @@ import (
 )
 
 type ChatSessionThreadRepository struct {
-	Collection *mongo.Collection
+	Collection *Collection
 }
 
 func NewChatSessionThreadRepository(db *mongo.Database) *ChatSessionThreadRepository {
 	return &ChatSessionThreadRepository{
-		Collection: db.Collection("chat_session_threads"),
+		Collection: newCollection(db, "chat_session_threads"),
 	}
 }
 
@@ -82,3 +82,78 @@ func (r *ChatSessionThreadRepository) CloseThread(ctx context.Context, sessionID
 	}
 	return res.ModifiedCount > 0, nil
 }
+
+// ListActive returns up to limit active threads in id order, starting after the thread with id
+// after unless it is zero.
+func (r *ChatSessionThreadRepository) ListActive(ctx context.Context, after primitive.ObjectID, limit int64) ([]models.ChatSessionThread, error) {
+	filter := bson.M{"active": true}
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}).SetLimit(limit)
+	cur, err := r.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var threads []models.ChatSessionThread
+	if err := cur.All(ctx, &threads); err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
+// CloseByID marks a single thread inactive.
+func (r *ChatSessionThreadRepository) CloseByID(ctx context.Context, id primitive.ObjectID) (bool, error) {
+	res, err := r.Collection.UpdateOne(ctx, bson.M{"_id": id, "active": true}, bson.M{"$set": bson.M{"active": false}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// GetByThreadID returns the thread of a conversation, identified by its parent session_id, with the given thread_id.
+func (r *ChatSessionThreadRepository) GetByThreadID(ctx context.Context, parentSessionID, threadID string) (*models.ChatSessionThread, error) {
+	var thread models.ChatSessionThread
+	err := r.Collection.FindOne(ctx, bson.M{"parent_session_id": parentSessionID, "thread_id": threadID}).Decode(&thread)
+	if err != nil {
+		return nil, err
+	}
+	return &thread, nil
+}
+
+// ListByParentSessionID returns the threads of a conversation, identified by its parent session_id,
+// most recently active first.
+func (r *ChatSessionThreadRepository) ListByParentSessionID(ctx context.Context, parentSessionID string) ([]models.ChatSessionThread, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "last_activity", Value: -1}})
+	cur, err := r.Collection.Find(ctx, bson.M{"parent_session_id": parentSessionID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	var threads []models.ChatSessionThread
+	if err := cur.All(ctx, &threads); err != nil {
+		return nil, err
+	}
+	return threads, nil
+}
+
+// SetActivity sets whether a thread is active and when it was last active.
+func (r *ChatSessionThreadRepository) SetActivity(ctx context.Context, id primitive.ObjectID, active bool, lastActivity time.Time) error {
+	_, err := r.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"active": active, "last_activity": lastActivity}})
+	return err
+}
+
+// MarkMerged retires a thread that was merged into the thread with thread_id into. It reports
+// false when the thread was already merged.
+func (r *ChatSessionThreadRepository) MarkMerged(ctx context.Context, id primitive.ObjectID, into string) (bool, error) {
+	res, err := r.Collection.UpdateOne(ctx,
+		bson.M{"_id": id, "merged_into": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"active": false, "merged_into": into}})
+	if err != nil {
+		return false, err
+	}
+	return res.ModifiedCount > 0, nil
+}
@@ -0,0 +1,69 @@
+// Package repository provides data access layer for chat workflow checkpoints.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ChatWorkflowStateRepository handles database operations for chat workflow states.
+type ChatWorkflowStateRepository struct {
+	collection *Collection
+}
+
+// NewChatWorkflowStateRepository creates a new ChatWorkflowStateRepository.
+func NewChatWorkflowStateRepository(db *mongo.Database) *ChatWorkflowStateRepository {
+	return &ChatWorkflowStateRepository{
+		collection: newCollection(db, models.ChatWorkflowState{}.TableName()),
+	}
+}
+
+// Begin counts an attempt at the workflow of a message and marks it running, creating its state
+// on the first attempt, and returns the stored state.
+func (r *ChatWorkflowStateRepository) Begin(ctx context.Context, messageID, sessionID string, suggestionMode bool) (*models.ChatWorkflowState, error) {
+	now := time.Now().UTC()
+	update := bson.M{
+		"$set": bson.M{
+			"status":     models.ExecutionStatusRunning,
+			"expires_at": now.Add(models.ChatWorkflowStateRetention),
+			"updated_at": now,
+		},
+		"$inc": bson.M{"attempts": 1},
+		"$setOnInsert": bson.M{
+			"message_id":      messageID,
+			"suggestion_mode": suggestionMode,
+			"session_id":      sessionID,
+			"created_at":      now,
+		},
+	}
+	filter := bson.M{"message_id": messageID, "suggestion_mode": suggestionMode}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var state models.ChatWorkflowState
+	if err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&state); err != nil {
+		return nil, fmt.Errorf("failed to save chat workflow state: %w", err)
+	}
+	return &state, nil
+}
+
+// Update modifies a chat workflow state.
+func (r *ChatWorkflowStateRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	now := time.Now().UTC()
+	update["updated_at"] = now
+	update["expires_at"] = now.Add(models.ChatWorkflowStateRetention)
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return fmt.Errorf("failed to update chat workflow state: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("chat workflow state not found")
+	}
+	return nil
+}
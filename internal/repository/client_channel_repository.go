@@ -13,12 +13,12 @@ import (
 )
 
 type ClientChannelRepository struct {
-	Collection *mongo.Collection
+	Collection *Collection
 }
 
 func NewClientChannelRepository(db *mongo.Database) *ClientChannelRepository {
 	return &ClientChannelRepository{
-		Collection: db.Collection("client_channels"),
+		Collection: newCollection(db, "client_channels"),
 	}
 }
 
@@ -12,12 +12,12 @@ import (
 )
 
 type ClientRepository struct {
-	Collection *mongo.Collection
+	Collection *Collection
 }
 
 func NewClientRepository(db *mongo.Database) *ClientRepository {
 	return &ClientRepository{
-		Collection: db.Collection("clients"),
+		Collection: newCollection(db, "clients"),
 	}
 }
 
@@ -57,6 +57,16 @@ func (r *ClientRepository) Update(ctx context.Context, clientID string, update b
 	return &updated, nil
 }
 
+// GetByID retrieves a client by its MongoDB ObjectID
+func (r *ClientRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Client, error) {
+	var client models.Client
+	err := r.Collection.FindOne(ctx, bson.M{"_id": id}).Decode(&client)
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
 func (r *ClientRepository) GetByClientID(ctx context.Context, clientID string) (*models.Client, error) {
 	var client models.Client
 	err := r.Collection.FindOne(ctx, bson.M{"client_id": clientID}).Decode(&client)
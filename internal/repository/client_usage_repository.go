@@ -0,0 +1,146 @@
+// Package repository provides data access layer for client usage counters.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ClientUsageRepository stores per-client usage counters, one document per client and month.
+type ClientUsageRepository struct {
+	collection *Collection
+}
+
+// NewClientUsageRepository creates a new ClientUsageRepository.
+func NewClientUsageRepository(db *mongo.Database) *ClientUsageRepository {
+	return &ClientUsageRepository{
+		collection: newCollection(db, models.ClientUsage{}.TableName()),
+	}
+}
+
+// Increment adds counts to their metrics in the month of now, both to the monthly totals and to
+// the day's counts, and returns the new monthly totals. The month's document is created on first
+// use.
+func (r *ClientUsageRepository) Increment(ctx context.Context, clientID primitive.ObjectID, counts map[string]int64, now time.Time) (map[string]int64, error) {
+	now = now.UTC()
+	filter := bson.M{"client": clientID, "period": now.Format("2006-01")}
+	inc := bson.M{}
+	for metric, n := range counts {
+		inc["totals."+metric] = n
+		inc["daily."+now.Format("02")+"."+metric] = n
+	}
+	update := bson.M{
+		"$inc": inc,
+		"$set": bson.M{"updated_at": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var usage models.ClientUsage
+	err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&usage)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another increment created the month's document at the same time; it exists now
+		err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&usage)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to increment usage: %w", err)
+	}
+	return usage.Totals, nil
+}
+
+// Get retrieves a client's usage in period, or nil when nothing was used.
+func (r *ClientUsageRepository) Get(ctx context.Context, clientID primitive.ObjectID, period string) (*models.ClientUsage, error) {
+	var usage models.ClientUsage
+	err := r.collection.FindOne(ctx, bson.M{"client": clientID, "period": period}).Decode(&usage)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get usage: %w", err)
+	}
+	return &usage, nil
+}
+
+// ListSince retrieves a client's usage from period on, newest month first.
+func (r *ClientUsageRepository) ListSince(ctx context.Context, clientID primitive.ObjectID, period string) ([]models.ClientUsage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "period", Value: -1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"client": clientID, "period": bson.M{"$gte": period}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	usage := []models.ClientUsage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, fmt.Errorf("failed to decode usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ListPeriods retrieves usage of the months from through to, ordered by month then client. A nil
+// clientID lists every client.
+func (r *ClientUsageRepository) ListPeriods(ctx context.Context, clientID *primitive.ObjectID, from, to string) ([]models.ClientUsage, error) {
+	filter := bson.M{"period": bson.M{"$gte": from, "$lte": to}}
+	if clientID != nil {
+		filter["client"] = *clientID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "period", Value: 1}, {Key: "client", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list usage: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	usage := []models.ClientUsage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, fmt.Errorf("failed to decode usage: %w", err)
+	}
+	return usage, nil
+}
+
+// ListUnreported retrieves usage of period with counts on day ("01" to "31") that were not reported yet.
+func (r *ClientUsageRepository) ListUnreported(ctx context.Context, period, day string) ([]models.ClientUsage, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"period":        period,
+		"daily." + day:  bson.M{"$exists": true},
+		"reported_days": bson.M{"$ne": day},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unreported usage: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	usage := []models.ClientUsage{}
+	if err := cursor.All(ctx, &usage); err != nil {
+		return nil, fmt.Errorf("failed to decode usage: %w", err)
+	}
+	return usage, nil
+}
+
+// MarkReported records that day of a usage document was reported. It returns false when the day
+// was marked already, so of concurrent reporters only one reports each day.
+func (r *ClientUsageRepository) MarkReported(ctx context.Context, id primitive.ObjectID, day string) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "reported_days": bson.M{"$ne": day}},
+		bson.M{"$push": bson.M{"reported_days": day}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark usage reported: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// UnmarkReported undoes MarkReported, so day is reported again later.
+func (r *ClientUsageRepository) UnmarkReported(ctx context.Context, id primitive.ObjectID, day string) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$pull": bson.M{"reported_days": day}})
+	if err != nil {
+		return fmt.Errorf("failed to unmark usage reported: %w", err)
+	}
+	return nil
+}
@@ -13,13 +13,13 @@ import (
 
 // ClientUserTypeRepository handles database operations for client user types.
 type ClientUserTypeRepository struct {
-	Collection *mongo.Collection
+	Collection *Collection
 }
 
 // NewClientUserTypeRepository creates a new ClientUserTypeRepository.
 func NewClientUserTypeRepository(db *mongo.Database) *ClientUserTypeRepository {
 	return &ClientUserTypeRepository{
-		Collection: db.Collection("client_user_types"),
+		Collection: newCollection(db, "client_user_types"),
 	}
 }
 
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/topology"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/config"
+)
+
+// OperationPolicy is how repositories run each MongoDB operation.
+type OperationPolicy struct {
+	// Timeout bounds every attempt of an operation; 0 leaves it to the caller's context. It
+	// covers the first batch of a find or aggregate, not iterating the cursor.
+	Timeout time.Duration
+	// RetryAttempts is how often an operation that failed with a transient error is tried again.
+	// Writes are only retried when the server can't have applied them.
+	RetryAttempts int
+	// RetryBackoff is the wait before the first retry, doubling with every further one
+	RetryBackoff time.Duration
+	// SlowThreshold logs operations, retries included, that take longer; 0 logs none
+	SlowThreshold time.Duration
+	Logger        *zap.Logger
+}
+
+var operationPolicy atomic.Pointer[OperationPolicy]
+
+// SetOperationPolicy sets the policy of every repository's operations, including those of
+// repositories created before.
+func SetOperationPolicy(p OperationPolicy) {
+	operationPolicy.Store(&p)
+}
+
+// OperationPolicyFromConfig returns the operation policy cfg describes.
+func OperationPolicyFromConfig(cfg *config.Config, logger *zap.Logger) OperationPolicy {
+	return OperationPolicy{
+		Timeout:       cfg.MongoOperationTimeout,
+		RetryAttempts: cfg.MongoRetryAttempts,
+		RetryBackoff:  cfg.MongoRetryBackoff,
+		SlowThreshold: cfg.MongoSlowQueryThreshold,
+		Logger:        logger,
+	}
+}
+
+func currentPolicy() OperationPolicy {
+	if p := operationPolicy.Load(); p != nil {
+		return *p
+	}
+	return OperationPolicy{}
+}
+
+// Collection is a MongoDB collection whose operations follow the OperationPolicy. It has the
+// methods of mongo.Collection, so repositories use it the same way.
+type Collection struct {
+	*mongo.Collection
+}
+
+func newCollection(db *mongo.Database, name string, opts ...*options.CollectionOptions) *Collection {
+	return &Collection{Collection: db.Collection(name, opts...)}
+}
+
+// Errors of a server that is stepping down or shutting down; the operation wasn't applied
+var notAppliedCodes = map[int32]bool{
+	91:    true, // ShutdownInProgress
+	189:   true, // PrimarySteppedDown
+	10107: true, // NotWritablePrimary
+	11600: true, // InterruptedAtShutdown
+	11602: true, // InterruptedDueToReplStateChange
+	13435: true, // NotPrimaryNoSecondaryOk
+	13436: true, // NotPrimaryOrSecondary
+}
+
+// retryable reports whether an operation that failed with err may be tried again. A read can be
+// retried after any network error; a write only when it can't have reached the server or the
+// server rejected the whole command.
+func retryable(err error, write bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var selectionErr topology.ServerSelectionError
+	if errors.As(err, &selectionErr) {
+		return true
+	}
+	// Write and write concern errors of single documents may come after others were applied
+	var commandErr mongo.CommandError
+	if errors.As(err, &commandErr) && notAppliedCodes[commandErr.Code] {
+		return true
+	}
+	return !write && mongo.IsNetworkError(err)
+}
+
+// run runs op under the operation policy. Operations in a transaction are not retried here, since
+// the transaction is retried as a whole.
+func run[T any](ctx context.Context, c *Collection, name string, write bool, op func(ctx context.Context) (T, error)) (T, error) {
+	policy := currentPolicy()
+	inTransaction := mongo.SessionFromContext(ctx) != nil
+
+	start := time.Now()
+	backoff := policy.RetryBackoff
+	attempt := 1
+	var result T
+	var err error
+	for ; ; attempt++ {
+		opCtx, cancel := ctx, context.CancelFunc(func() {})
+		if policy.Timeout > 0 {
+			opCtx, cancel = context.WithTimeout(ctx, policy.Timeout)
+		}
+		result, err = op(opCtx)
+		cancel()
+		if err == nil || inTransaction || attempt > policy.RetryAttempts || !retryable(err, write) {
+			break
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return result, err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+
+	if elapsed := time.Since(start); policy.SlowThreshold > 0 && elapsed > policy.SlowThreshold && policy.Logger != nil {
+		policy.Logger.Warn("Slow MongoDB operation",
+			zap.String("collection", c.Name()),
+			zap.String("operation", name),
+			zap.Duration("duration", elapsed),
+			zap.Int("attempts", attempt),
+			zap.Error(err))
+	}
+	return result, err
+}
+
+// singleResult runs an operation answering with one document. The document is read before the
+// attempt's context ends, so it can still be decoded afterwards.
+func singleResult(ctx context.Context, c *Collection, name string, write bool, op func(ctx context.Context) *mongo.SingleResult) *mongo.SingleResult {
+	res, _ := run(ctx, c, name, write, func(ctx context.Context) (*mongo.SingleResult, error) {
+		res := op(ctx)
+		return res, res.Err()
+	})
+	return res
+}
+
+// FindOne is mongo.Collection.FindOne under the operation policy.
+func (c *Collection) FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+	return singleResult(ctx, c, "findOne", false, func(ctx context.Context) *mongo.SingleResult {
+		return c.Collection.FindOne(ctx, filter, opts...)
+	})
+}
+
+// FindOneAndUpdate is mongo.Collection.FindOneAndUpdate under the operation policy.
+func (c *Collection) FindOneAndUpdate(ctx context.Context, filter, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	return singleResult(ctx, c, "findOneAndUpdate", true, func(ctx context.Context) *mongo.SingleResult {
+		return c.Collection.FindOneAndUpdate(ctx, filter, update, opts...)
+	})
+}
+
+// FindOneAndReplace is mongo.Collection.FindOneAndReplace under the operation policy.
+func (c *Collection) FindOneAndReplace(ctx context.Context, filter, replacement interface{}, opts ...*options.FindOneAndReplaceOptions) *mongo.SingleResult {
+	return singleResult(ctx, c, "findOneAndReplace", true, func(ctx context.Context) *mongo.SingleResult {
+		return c.Collection.FindOneAndReplace(ctx, filter, replacement, opts...)
+	})
+}
+
+// FindOneAndDelete is mongo.Collection.FindOneAndDelete under the operation policy.
+func (c *Collection) FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult {
+	return singleResult(ctx, c, "findOneAndDelete", true, func(ctx context.Context) *mongo.SingleResult {
+		return c.Collection.FindOneAndDelete(ctx, filter, opts...)
+	})
+}
+
+// Find is mongo.Collection.Find under the operation policy.
+func (c *Collection) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error) {
+	return run(ctx, c, "find", false, func(ctx context.Context) (*mongo.Cursor, error) {
+		return c.Collection.Find(ctx, filter, opts...)
+	})
+}
+
+// Aggregate is mongo.Collection.Aggregate under the operation policy. It is retried like a write,
+// since a pipeline may write with $out or $merge.
+func (c *Collection) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	return run(ctx, c, "aggregate", true, func(ctx context.Context) (*mongo.Cursor, error) {
+		return c.Collection.Aggregate(ctx, pipeline, opts...)
+	})
+}
+
+// CountDocuments is mongo.Collection.CountDocuments under the operation policy.
+func (c *Collection) CountDocuments(ctx context.Context, filter interface{}, opts ...*options.CountOptions) (int64, error) {
+	return run(ctx, c, "countDocuments", false, func(ctx context.Context) (int64, error) {
+		return c.Collection.CountDocuments(ctx, filter, opts...)
+	})
+}
+
+// EstimatedDocumentCount is mongo.Collection.EstimatedDocumentCount under the operation policy.
+func (c *Collection) EstimatedDocumentCount(ctx context.Context, opts ...*options.EstimatedDocumentCountOptions) (int64, error) {
+	return run(ctx, c, "estimatedDocumentCount", false, func(ctx context.Context) (int64, error) {
+		return c.Collection.EstimatedDocumentCount(ctx, opts...)
+	})
+}
+
+// Distinct is mongo.Collection.Distinct under the operation policy.
+func (c *Collection) Distinct(ctx context.Context, fieldName string, filter interface{}, opts ...*options.DistinctOptions) ([]interface{}, error) {
+	return run(ctx, c, "distinct", false, func(ctx context.Context) ([]interface{}, error) {
+		return c.Collection.Distinct(ctx, fieldName, filter, opts...)
+	})
+}
+
+// InsertOne is mongo.Collection.InsertOne under the operation policy.
+func (c *Collection) InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+	return run(ctx, c, "insertOne", true, func(ctx context.Context) (*mongo.InsertOneResult, error) {
+		return c.Collection.InsertOne(ctx, document, opts...)
+	})
+}
+
+// InsertMany is mongo.Collection.InsertMany under the operation policy.
+func (c *Collection) InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error) {
+	return run(ctx, c, "insertMany", true, func(ctx context.Context) (*mongo.InsertManyResult, error) {
+		return c.Collection.InsertMany(ctx, documents, opts...)
+	})
+}
+
+// UpdateOne is mongo.Collection.UpdateOne under the operation policy.
+func (c *Collection) UpdateOne(ctx context.Context, filter, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return run(ctx, c, "updateOne", true, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		return c.Collection.UpdateOne(ctx, filter, update, opts...)
+	})
+}
+
+// UpdateByID is mongo.Collection.UpdateByID under the operation policy.
+func (c *Collection) UpdateByID(ctx context.Context, id, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return run(ctx, c, "updateByID", true, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		return c.Collection.UpdateByID(ctx, id, update, opts...)
+	})
+}
+
+// UpdateMany is mongo.Collection.UpdateMany under the operation policy.
+func (c *Collection) UpdateMany(ctx context.Context, filter, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	return run(ctx, c, "updateMany", true, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		return c.Collection.UpdateMany(ctx, filter, update, opts...)
+	})
+}
+
+// ReplaceOne is mongo.Collection.ReplaceOne under the operation policy.
+func (c *Collection) ReplaceOne(ctx context.Context, filter, replacement interface{}, opts ...*options.ReplaceOptions) (*mongo.UpdateResult, error) {
+	return run(ctx, c, "replaceOne", true, func(ctx context.Context) (*mongo.UpdateResult, error) {
+		return c.Collection.ReplaceOne(ctx, filter, replacement, opts...)
+	})
+}
+
+// DeleteOne is mongo.Collection.DeleteOne under the operation policy.
+func (c *Collection) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return run(ctx, c, "deleteOne", true, func(ctx context.Context) (*mongo.DeleteResult, error) {
+		return c.Collection.DeleteOne(ctx, filter, opts...)
+	})
+}
+
+// DeleteMany is mongo.Collection.DeleteMany under the operation policy.
+func (c *Collection) DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	return run(ctx, c, "deleteMany", true, func(ctx context.Context) (*mongo.DeleteResult, error) {
+		return c.Collection.DeleteMany(ctx, filter, opts...)
+	})
+}
+
+// BulkWrite is mongo.Collection.BulkWrite under the operation policy.
+func (c *Collection) BulkWrite(ctx context.Context, models []mongo.WriteModel, opts ...*options.BulkWriteOptions) (*mongo.BulkWriteResult, error) {
+	return run(ctx, c, "bulkWrite", true, func(ctx context.Context) (*mongo.BulkWriteResult, error) {
+		return c.Collection.BulkWrite(ctx, models, opts...)
+	})
+}
@@ -0,0 +1,124 @@
+// Package repository provides data access layer for contacts.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ContactRepository handles database operations for contacts.
+type ContactRepository struct {
+	collection *Collection
+}
+
+// NewContactRepository creates a new ContactRepository.
+func NewContactRepository(db *mongo.Database) *ContactRepository {
+	return &ContactRepository{
+		collection: newCollection(db, models.Contact{}.TableName()),
+	}
+}
+
+// Create inserts a new contact. It fails with a duplicate key error when one of the contact's
+// identities belongs to another contact of the client.
+func (r *ContactRepository) Create(ctx context.Context, contact *models.Contact) error {
+	contact.ID = primitive.NewObjectID()
+	contact.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, contact); err != nil {
+		return fmt.Errorf("failed to insert contact: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a client's contact by its ID.
+func (r *ContactRepository) GetByID(ctx context.Context, clientID, id primitive.ObjectID) (*models.Contact, error) {
+	var contact models.Contact
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id, "client": clientID}).Decode(&contact); err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// SeeByIdentity returns the client's contact with identity and records that it was seen at.
+func (r *ContactRepository) SeeByIdentity(ctx context.Context, clientID primitive.ObjectID, identity models.ContactIdentity, at time.Time) (*models.Contact, error) {
+	var contact models.Contact
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"client": clientID, "identity_keys": identity.Key()},
+		bson.M{"$set": bson.M{"last_seen_at": at}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&contact)
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// List retrieves contacts matching filter, most recently seen first.
+func (r *ContactRepository) List(ctx context.Context, filter bson.M, limit, offset int) ([]models.Contact, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "last_seen_at", Value: -1}, {Key: "_id", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find contacts: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	contacts := make([]models.Contact, 0)
+	if err := cursor.All(ctx, &contacts); err != nil {
+		return nil, fmt.Errorf("failed to decode contacts: %w", err)
+	}
+	return contacts, nil
+}
+
+// Update applies update to a client's contact that hasn't been merged and returns the updated contact.
+func (r *ContactRepository) Update(ctx context.Context, clientID, id primitive.ObjectID, update bson.M) (*models.Contact, error) {
+	update["updated_at"] = time.Now().UTC()
+
+	var contact models.Contact
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "client": clientID, "merged_into": bson.M{"$exists": false}},
+		bson.M{"$set": update},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&contact)
+	if err != nil {
+		return nil, err
+	}
+	return &contact, nil
+}
+
+// MarkMerged retires a contact merged into another, giving up its identities so the other
+// contact can take them. It reports false when the contact was already merged.
+func (r *ContactRepository) MarkMerged(ctx context.Context, id, into primitive.ObjectID) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "merged_into": bson.M{"$exists": false}},
+		bson.M{
+			"$set":   bson.M{"merged_into": into, "updated_at": time.Now().UTC()},
+			"$unset": bson.M{"identities": "", "identity_keys": ""},
+		})
+	if err != nil {
+		return false, fmt.Errorf("failed to merge contact: %w", err)
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// Delete removes a client's contact.
+func (r *ContactRepository) Delete(ctx context.Context, clientID, id primitive.ObjectID) (bool, error) {
+	res, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "client": clientID})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete contact: %w", err)
+	}
+	return res.DeletedCount > 0, nil
+}
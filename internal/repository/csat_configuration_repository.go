@@ -13,13 +13,13 @@ import (
 
 // CSATConfigurationRepository encapsulates database operations for CSAT configurations.
 type CSATConfigurationRepository struct {
-	collection *mongo.Collection
+	collection *Collection
 }
 
 // NewCSATConfigurationRepository creates a new CSATConfigurationRepository.
 func NewCSATConfigurationRepository(db *mongo.Database) *CSATConfigurationRepository {
 	return &CSATConfigurationRepository{
-		collection: db.Collection("csat_configurations"),
+		collection: newCollection(db, "csat_configurations"),
 	}
 }
 
@@ -14,13 +14,13 @@ import (
 
 // CSATQuestionTemplateRepository encapsulates database operations for CSAT question templates.
 type CSATQuestionTemplateRepository struct {
-	collection *mongo.Collection
+	collection *Collection
 }
 
 // NewCSATQuestionTemplateRepository creates a new CSATQuestionTemplateRepository.
 func NewCSATQuestionTemplateRepository(db *mongo.Database) *CSATQuestionTemplateRepository {
 	return &CSATQuestionTemplateRepository{
-		collection: db.Collection("csat_question_templates"),
+		collection: newCollection(db, "csat_question_templates"),
 	}
 }
 
@@ -147,3 +147,21 @@ func (r *CSATQuestionTemplateRepository) List(ctx context.Context, filter map[st
 	
 	return templates, nil
 }
+
+// GetByIDs retrieves CSAT question templates by ID, including inactive ones.
+func (r *CSATQuestionTemplateRepository) GetByIDs(ctx context.Context, ids []primitive.ObjectID) ([]models.CSATQuestionTemplate, error) {
+	var templates []models.CSATQuestionTemplate
+	if len(ids) == 0 {
+		return templates, nil
+	}
+	cursor, err := r.collection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get CSAT question templates: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, fmt.Errorf("failed to decode CSAT question templates: %w", err)
+	}
+	return templates, nil
+}
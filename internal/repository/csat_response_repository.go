@@ -9,17 +9,18 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // CSATResponseRepository encapsulates database operations for CSAT responses.
 type CSATResponseRepository struct {
-	collection *mongo.Collection
+	collection *Collection
 }
 
 // NewCSATResponseRepository creates a new CSATResponseRepository.
 func NewCSATResponseRepository(db *mongo.Database) *CSATResponseRepository {
 	return &CSATResponseRepository{
-		collection: db.Collection("csat_responses"),
+		collection: newCollection(db, "csat_responses"),
 	}
 }
 
@@ -124,3 +125,45 @@ func (r *CSATResponseRepository) List(ctx context.Context, filter map[string]int
 	
 	return responses, nil
 }
+
+// ListBySessions retrieves the responses of the given CSAT sessions, oldest first.
+func (r *CSATResponseRepository) ListBySessions(ctx context.Context, sessionIDs []primitive.ObjectID) ([]models.CSATResponse, error) {
+	var responses []models.CSATResponse
+	if len(sessionIDs) == 0 {
+		return responses, nil
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "responded_at", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"csat_session": bson.M{"$in": sessionIDs}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list CSAT responses: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &responses); err != nil {
+		return nil, fmt.Errorf("failed to decode CSAT responses: %w", err)
+	}
+	return responses, nil
+}
+
+// SessionIDsWithScore returns the CSAT sessions with at least one scored response within
+// [minScore, maxScore]; a nil bound is open.
+func (r *CSATResponseRepository) SessionIDsWithScore(ctx context.Context, minScore, maxScore *float64) ([]primitive.ObjectID, error) {
+	score := bson.M{"$ne": nil}
+	if minScore != nil {
+		score["$gte"] = *minScore
+	}
+	if maxScore != nil {
+		score["$lte"] = *maxScore
+	}
+	values, err := r.collection.Distinct(ctx, "csat_session", bson.M{"score": score})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find scored CSAT sessions: %w", err)
+	}
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
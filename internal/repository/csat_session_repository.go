@@ -4,6 +4,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"regexp"
 
 	"github.com/fraiday-org/api-service/internal/models"
 	"go.mongodb.org/mongo-driver/bson"
@@ -14,13 +15,13 @@ import (
 
 // CSATSessionRepository encapsulates database operations for CSAT sessions.
 type CSATSessionRepository struct {
-	collection *mongo.Collection
+	collection *Collection
 }
 
 // NewCSATSessionRepository creates a new CSATSessionRepository.
 func NewCSATSessionRepository(db *mongo.Database) *CSATSessionRepository {
 	return &CSATSessionRepository{
-		collection: db.Collection("csat_sessions"),
+		collection: newCollection(db, "csat_sessions"),
 	}
 }
 
@@ -150,3 +151,40 @@ func (r *CSATSessionRepository) GetActiveByBaseSessionID(ctx context.Context, ba
 	}
 	return &session, nil
 }
+
+// ExistsForBaseSession reports whether any CSAT session of the given configuration was ever
+// created for the base session ID, including threaded sessions ("base#thread").
+func (r *CSATSessionRepository) ExistsForBaseSession(ctx context.Context, baseSessionID string, configID primitive.ObjectID) (bool, error) {
+	filter := bson.M{
+		"chat_session_id": bson.M{
+			"$regex": "^" + regexp.QuoteMeta(baseSessionID) + "(#.*)?$",
+		},
+		"csat_configuration_id": configID,
+	}
+
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return false, fmt.Errorf("failed to count CSAT sessions: %w", err)
+	}
+	return count > 0, nil
+}
+
+// ListWithFilters retrieves a page of CSAT sessions matching filter and the total number of matches.
+func (r *CSATSessionRepository) ListWithFilters(ctx context.Context, filter bson.M, skip, limit int64, sort bson.D) ([]models.CSATSession, int64, error) {
+	opts := options.Find().SetSkip(skip).SetLimit(limit).SetSort(sort)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list CSAT sessions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.CSATSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode CSAT sessions: %w", err)
+	}
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count CSAT sessions: %w", err)
+	}
+	return sessions, count, nil
+}
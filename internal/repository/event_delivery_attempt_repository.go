@@ -15,13 +15,13 @@ import (
 
 // EventDeliveryAttemptRepository handles database operations for event delivery attempts.
 type EventDeliveryAttemptRepository struct {
-	collection *mongo.Collection
+	collection *Collection
 }
 
 // NewEventDeliveryAttemptRepository creates a new EventDeliveryAttemptRepository.
 func NewEventDeliveryAttemptRepository(db *mongo.Database) *EventDeliveryAttemptRepository {
 	return &EventDeliveryAttemptRepository{
-		collection: db.Collection("event_delivery_attempts"),
+		collection: newCollection(db, "event_delivery_attempts"),
 	}
 }
 
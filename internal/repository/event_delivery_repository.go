@@ -15,13 +15,13 @@ import (
 
 // EventDeliveryRepository handles database operations for event deliveries.
 type EventDeliveryRepository struct {
-	collection *mongo.Collection
+	collection *Collection
 }
 
 // NewEventDeliveryRepository creates a new EventDeliveryRepository.
 func NewEventDeliveryRepository(db *mongo.Database) *EventDeliveryRepository {
 	return &EventDeliveryRepository{
-		collection: db.Collection("event_deliveries"),
+		collection: newCollection(db, "event_deliveries"),
 	}
 }
 
@@ -122,13 +122,13 @@ func (r *EventDeliveryRepository) Delete(ctx context.Context, id primitive.Objec
 
 // GetByEventID retrieves event deliveries for a specific event.
 func (r *EventDeliveryRepository) GetByEventID(ctx context.Context, eventID primitive.ObjectID) ([]models.EventDelivery, error) {
-	filter := bson.M{"event_id": eventID}
+	filter := bson.M{"event": eventID}
 	return r.List(ctx, filter, 0, 0)
 }
 
 // GetByProcessorConfigID retrieves event deliveries for a specific processor configuration.
 func (r *EventDeliveryRepository) GetByProcessorConfigID(ctx context.Context, configID primitive.ObjectID) ([]models.EventDelivery, error) {
-	filter := bson.M{"event_processor_config_id": configID}
+	filter := bson.M{"event_processor_config": configID}
 	return r.List(ctx, filter, 0, 0)
 }
 
@@ -189,6 +189,69 @@ func (r *EventDeliveryRepository) UpdateStatus(ctx context.Context, id primitive
 	return r.Update(ctx, id, update)
 }
 
+// SetNextRetry records when the next delivery attempt is scheduled and the backoff policy applied.
+func (r *EventDeliveryRepository) SetNextRetry(ctx context.Context, id primitive.ObjectID, nextRetryAt time.Time, policy models.DeliveryRetryPolicy) error {
+	return r.Update(ctx, id, bson.M{
+		"next_retry_at": nextRetryAt,
+		"retry_policy":  policy,
+	})
+}
+
+// ClearNextRetry removes the scheduled retry time once no further attempts are pending.
+func (r *EventDeliveryRepository) ClearNextRetry(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{
+			"$unset": bson.M{"next_retry_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to clear next retry: %w", err)
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("event delivery not found")
+	}
+
+	return nil
+}
+
+// ListOverdueRetries retrieves up to limit pending deliveries whose scheduled retry is older than
+// before, oldest first.
+func (r *EventDeliveryRepository) ListOverdueRetries(ctx context.Context, before time.Time, limit int64) ([]models.EventDelivery, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "next_retry_at", Value: 1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"status":        models.DeliveryStatusPending,
+		"next_retry_at": bson.M{"$lt": before},
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find overdue deliveries: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var deliveries []models.EventDelivery
+	if err = cursor.All(ctx, &deliveries); err != nil {
+		return nil, fmt.Errorf("failed to decode event deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ClaimRetry moves the scheduled retry of a pending delivery from nextRetryAt to retryAt. It
+// reports false when the delivery was retried or rescheduled in the meantime.
+func (r *EventDeliveryRepository) ClaimRetry(ctx context.Context, id primitive.ObjectID, nextRetryAt, retryAt time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.DeliveryStatusPending, "next_retry_at": nextRetryAt},
+		bson.M{"$set": bson.M{"next_retry_at": retryAt, "updated_at": time.Now()}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim delivery retry: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
 // Count returns the total number of event deliveries matching the filter.
 func (r *EventDeliveryRepository) Count(ctx context.Context, filter map[string]interface{}) (int64, error) {
 	count, err := r.collection.CountDocuments(ctx, filter)
@@ -15,13 +15,13 @@ import (
 
 // EventProcessorConfigRepository handles database operations for event processor configurations.
 type EventProcessorConfigRepository struct {
-	collection *mongo.Collection
+	collection *Collection
 }
 
 // NewEventProcessorConfigRepository creates a new EventProcessorConfigRepository.
 func NewEventProcessorConfigRepository(db *mongo.Database) *EventProcessorConfigRepository {
 	return &EventProcessorConfigRepository{
-		collection: db.Collection("event_processor_configs"),
+		collection: newCollection(db, "event_processor_configs"),
 	}
 }
 
@@ -84,24 +84,37 @@ func (r *EventProcessorConfigRepository) List(
 	return configs, nil
 }
 
-// Update modifies an existing event processor configuration.
-func (r *EventProcessorConfigRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+// Update modifies an existing event processor configuration and returns its new version. With
+// version set the update only applies at that version, and fails with a VersionConflictError
+// otherwise.
+func (r *EventProcessorConfigRepository) Update(ctx context.Context, id primitive.ObjectID, version *int64, update bson.M) (int64, error) {
 	update["updated_at"] = time.Now().UTC()
 
-	result, err := r.collection.UpdateOne(
+	var updated struct {
+		Version int64 `bson:"version"`
+	}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"version": 1})
+	err := r.collection.FindOneAndUpdate(
 		ctx,
-		bson.M{"_id": id},
-		bson.M{"$set": update},
-	)
-	if err != nil {
-		return fmt.Errorf("failed to update event processor config: %w", err)
+		versionFilter(bson.M{"_id": id}, version),
+		bumpVersion(bson.M{"$set": update}),
+		opts,
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments && version != nil {
+		if err = versionConflict(ctx, r.collection, id); err != mongo.ErrNoDocuments {
+			return 0, err
+		}
 	}
-
-	if result.MatchedCount == 0 {
-		return fmt.Errorf("event processor config not found")
+	if err == mongo.ErrNoDocuments {
+		return 0, fmt.Errorf("event processor config not found")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to update event processor config: %w", err)
 	}
 
-	return nil
+	return updated.Version, nil
 }
 
 // Delete removes an event processor configuration from the database.
@@ -15,13 +15,13 @@ import (
 
 // EventRepository handles database operations for events.
 type EventRepository struct {
-	collection *mongo.Collection
+	collection *Collection
 }
 
 // NewEventRepository creates a new EventRepository.
 func NewEventRepository(db *mongo.Database) *EventRepository {
 	return &EventRepository{
-		collection: db.Collection("events"),
+		collection: newCollection(db, "events"),
 	}
 }
 
@@ -128,4 +128,24 @@ func (r *EventRepository) Count(ctx context.Context, filter map[string]interface
 	}
 
 	return count, nil
-}
\ No newline at end of file
+}
+// ListSince returns up to limit events matching filter with an _id after the given one, oldest first.
+func (r *EventRepository) ListSince(ctx context.Context, filter bson.M, after primitive.ObjectID, limit int64) ([]models.Event, error) {
+	if !after.IsZero() {
+		filter["_id"] = bson.M{"$gt": after}
+	}
+	opts := options.Find().SetSort(bson.D{{"_id", 1}}).SetLimit(limit)
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find events: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var events []models.Event
+	if err = cursor.All(ctx, &events); err != nil {
+		return nil, fmt.Errorf("failed to decode events: %w", err)
+	}
+
+	return events, nil
+}
@@ -0,0 +1,109 @@
+// Package repository provides data access layer for experiments.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ExperimentRepository handles database operations for experiments.
+type ExperimentRepository struct {
+	collection *Collection
+}
+
+// NewExperimentRepository creates a new ExperimentRepository.
+func NewExperimentRepository(db *mongo.Database) *ExperimentRepository {
+	return &ExperimentRepository{
+		collection: newCollection(db, models.Experiment{}.TableName()),
+	}
+}
+
+// Create inserts a new experiment.
+func (r *ExperimentRepository) Create(ctx context.Context, experiment *models.Experiment) error {
+	experiment.ID = primitive.NewObjectID()
+	experiment.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, experiment); err != nil {
+		return fmt.Errorf("failed to insert experiment: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a client's experiment by its ID.
+func (r *ExperimentRepository) GetByID(ctx context.Context, clientID string, id primitive.ObjectID) (*models.Experiment, error) {
+	var experiment models.Experiment
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "client_id": clientID}).Decode(&experiment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("experiment not found")
+		}
+		return nil, fmt.Errorf("failed to find experiment: %w", err)
+	}
+	return &experiment, nil
+}
+
+// FindRunning returns the client's running experiment, or nil when it has none.
+func (r *ExperimentRepository) FindRunning(ctx context.Context, clientID string) (*models.Experiment, error) {
+	var experiment models.Experiment
+	err := r.collection.FindOne(ctx, bson.M{"client_id": clientID, "status": models.ExperimentStatusRunning}).Decode(&experiment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find experiment: %w", err)
+	}
+	return &experiment, nil
+}
+
+// List retrieves a client's experiments, newest first.
+func (r *ExperimentRepository) List(ctx context.Context, filter bson.M) ([]models.Experiment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find experiments: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	experiments := make([]models.Experiment, 0)
+	if err := cursor.All(ctx, &experiments); err != nil {
+		return nil, fmt.Errorf("failed to decode experiments: %w", err)
+	}
+	return experiments, nil
+}
+
+// UpdateInStatus applies set to an experiment that is still in status and returns it updated, or
+// nil when it isn't in that status anymore.
+func (r *ExperimentRepository) UpdateInStatus(ctx context.Context, clientID string, id primitive.ObjectID, status models.ExperimentStatus, set bson.M) (*models.Experiment, error) {
+	set["updated_at"] = time.Now().UTC()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var experiment models.Experiment
+	err := r.collection.FindOneAndUpdate(ctx,
+		bson.M{"_id": id, "client_id": clientID, "status": status},
+		bson.M{"$set": set},
+		opts,
+	).Decode(&experiment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to update experiment: %w", err)
+	}
+	return &experiment, nil
+}
+
+// Delete removes a client's experiment unless it is running.
+func (r *ExperimentRepository) Delete(ctx context.Context, clientID string, id primitive.ObjectID) (bool, error) {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "client_id": clientID, "status": bson.M{"$ne": models.ExperimentStatusRunning}})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete experiment: %w", err)
+	}
+	return result.DeletedCount > 0, nil
+}
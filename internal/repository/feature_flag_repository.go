@@ -0,0 +1,103 @@
+// Package repository provides data access layer for feature flags.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FeatureFlagRepository handles database operations for feature flags.
+type FeatureFlagRepository struct {
+	collection *Collection
+}
+
+// NewFeatureFlagRepository creates a new FeatureFlagRepository.
+func NewFeatureFlagRepository(db *mongo.Database) *FeatureFlagRepository {
+	return &FeatureFlagRepository{
+		collection: newCollection(db, models.FeatureFlag{}.TableName()),
+	}
+}
+
+// Create inserts a new feature flag. A duplicate key is reported as a mongo duplicate key error.
+func (r *FeatureFlagRepository) Create(ctx context.Context, flag *models.FeatureFlag) error {
+	now := time.Now().UTC()
+	flag.CreatedAt = now
+	flag.UpdatedAt = now
+	result, err := r.collection.InsertOne(ctx, flag)
+	if err != nil {
+		return fmt.Errorf("failed to create feature flag: %w", err)
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		flag.ID = oid
+	}
+	return nil
+}
+
+// GetByKey retrieves a feature flag by key.
+func (r *FeatureFlagRepository) GetByKey(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	err := r.collection.FindOne(ctx, bson.M{"key": key}).Decode(&flag)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("feature flag not found")
+		}
+		return nil, fmt.Errorf("failed to find feature flag: %w", err)
+	}
+	return &flag, nil
+}
+
+// List retrieves every feature flag ordered by key.
+func (r *FeatureFlagRepository) List(ctx context.Context) ([]models.FeatureFlag, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "key", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find feature flags: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	flags := make([]models.FeatureFlag, 0)
+	if err := cursor.All(ctx, &flags); err != nil {
+		return nil, fmt.Errorf("failed to decode feature flags: %w", err)
+	}
+	return flags, nil
+}
+
+// Update replaces the settings of a feature flag and returns the stored flag.
+func (r *FeatureFlagRepository) Update(ctx context.Context, flag *models.FeatureFlag) (*models.FeatureFlag, error) {
+	update := bson.M{"$set": bson.M{
+		"description":      flag.Description,
+		"enabled":          flag.Enabled,
+		"clients":          flag.Clients,
+		"excluded_clients": flag.ExcludedClients,
+		"percentage":       flag.Percentage,
+		"updated_at":       time.Now().UTC(),
+	}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated models.FeatureFlag
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"key": flag.Key}, update, opts).Decode(&updated); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("feature flag not found")
+		}
+		return nil, fmt.Errorf("failed to update feature flag: %w", err)
+	}
+	return &updated, nil
+}
+
+// Delete removes a feature flag.
+func (r *FeatureFlagRepository) Delete(ctx context.Context, key string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"key": key})
+	if err != nil {
+		return fmt.Errorf("failed to delete feature flag: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("feature flag not found")
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+// Package repository provides data access layer for message imports and their files.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxStoredImportErrors caps how many failed rows an import keeps
+const maxStoredImportErrors = 100
+
+// MessageImportRepository handles database operations for message imports, and keeps their
+// uploaded files in the "message_imports" GridFS bucket until they have run.
+type MessageImportRepository struct {
+	collection *Collection
+	bucket     *gridfs.Bucket
+}
+
+// NewMessageImportRepository creates a new MessageImportRepository.
+func NewMessageImportRepository(db *mongo.Database) (*MessageImportRepository, error) {
+	bucket, err := gridfs.NewBucket(db, options.GridFSBucket().SetName(models.MessageImport{}.TableName()))
+	if err != nil {
+		return nil, err
+	}
+	return &MessageImportRepository{
+		collection: newCollection(db, models.MessageImport{}.TableName()),
+		bucket:     bucket,
+	}, nil
+}
+
+// UploadFile streams an import file into storage and returns its ID and size.
+func (r *MessageImportRepository) UploadFile(fileName string, content io.Reader) (primitive.ObjectID, int64, error) {
+	stream, err := r.bucket.OpenUploadStream(fileName)
+	if err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	size, err := io.Copy(stream, content)
+	if err != nil {
+		_ = stream.Abort()
+		return primitive.NilObjectID, 0, err
+	}
+	if err := stream.Close(); err != nil {
+		return primitive.NilObjectID, 0, err
+	}
+	return stream.FileID.(primitive.ObjectID), size, nil
+}
+
+// OpenFile returns a reader for an import file; the caller closes it.
+func (r *MessageImportRepository) OpenFile(id primitive.ObjectID) (io.ReadCloser, error) {
+	return r.bucket.OpenDownloadStream(id)
+}
+
+// DeleteFile removes an import file.
+func (r *MessageImportRepository) DeleteFile(id primitive.ObjectID) error {
+	return r.bucket.Delete(id)
+}
+
+// Create inserts a new message import.
+func (r *MessageImportRepository) Create(ctx context.Context, imp *models.MessageImport) error {
+	if imp.ID.IsZero() {
+		imp.ID = primitive.NewObjectID()
+	}
+	if imp.Errors == nil {
+		imp.Errors = []models.MessageImportError{}
+	}
+	imp.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, imp); err != nil {
+		return fmt.Errorf("failed to insert message import: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a message import by its ID.
+func (r *MessageImportRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.MessageImport, error) {
+	var imp models.MessageImport
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&imp); err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// List retrieves message imports matching filter, newest first.
+func (r *MessageImportRepository) List(ctx context.Context, filter bson.M, limit, offset int) ([]models.MessageImport, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message imports: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	imports := make([]models.MessageImport, 0)
+	if err := cursor.All(ctx, &imports); err != nil {
+		return nil, fmt.Errorf("failed to decode message imports: %w", err)
+	}
+	return imports, nil
+}
+
+// Start marks a queued import as running. It returns false when the import isn't queued, so a
+// redelivered task doesn't run it twice.
+func (r *MessageImportRepository) Start(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.MessageImportStatusQueued},
+		bson.M{"$set": bson.M{"status": models.MessageImportStatusRunning, "started_at": at, "updated_at": at}})
+	if err != nil {
+		return false, fmt.Errorf("failed to start message import: %w", err)
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// AddProgress adds the counts of a batch to an import, keeping the first failed rows.
+func (r *MessageImportRepository) AddProgress(ctx context.Context, id primitive.ObjectID, progress models.MessageImportProgress) error {
+	update := bson.M{
+		"$inc": bson.M{
+			"processed":        progress.Processed,
+			"imported":         progress.Imported,
+			"skipped":          progress.Skipped,
+			"failed":           progress.Failed,
+			"sessions_created": progress.SessionsCreated,
+		},
+		"$set": bson.M{"updated_at": time.Now().UTC()},
+	}
+	if len(progress.Errors) > 0 {
+		update["$push"] = bson.M{"errors": bson.M{"$each": progress.Errors, "$slice": maxStoredImportErrors}}
+	}
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("failed to record message import progress: %w", err)
+	}
+	return nil
+}
+
+// Finish records how an import ended.
+func (r *MessageImportRepository) Finish(ctx context.Context, id primitive.ObjectID, status models.MessageImportStatus, reason string) error {
+	now := time.Now().UTC()
+	set := bson.M{"status": status, "completed_at": now, "updated_at": now}
+	if reason != "" {
+		set["error"] = reason
+	}
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("failed to finish message import: %w", err)
+	}
+	return nil
+}
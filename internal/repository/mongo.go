@@ -2,16 +2,29 @@ package repository
 
 import (
 	"context"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 )
 
-func NewMongoClient(uri string) (*mongo.Client, error) {
+// NewMongoClient connects to cfg.MongoURI with the pool, timeout, read and compression settings
+// of cfg. Settings left unset keep what the URI says.
+func NewMongoClient(cfg *config.Config) (*mongo.Client, error) {
+	opts, err := clientOptions(cfg)
+	if err != nil {
+		return nil, err
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -21,3 +34,67 @@ func NewMongoClient(uri string) (*mongo.Client, error) {
 	}
 	return client, nil
 }
+
+func clientOptions(cfg *config.Config) (*options.ClientOptions, error) {
+	opts := options.Client().ApplyURI(cfg.MongoURI).SetMonitor(telemetry.MongoMonitor())
+	if cfg.MongoMaxPoolSize > 0 {
+		opts.SetMaxPoolSize(uint64(cfg.MongoMaxPoolSize))
+	}
+	if cfg.MongoMinPoolSize > 0 {
+		opts.SetMinPoolSize(uint64(cfg.MongoMinPoolSize))
+	}
+	if cfg.MongoMaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(cfg.MongoMaxConnIdleTime)
+	}
+	if cfg.MongoConnectTimeout > 0 {
+		opts.SetConnectTimeout(cfg.MongoConnectTimeout)
+	}
+	if cfg.MongoSocketTimeout > 0 {
+		opts.SetSocketTimeout(cfg.MongoSocketTimeout)
+	}
+	if cfg.MongoServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(cfg.MongoServerSelectionTimeout)
+	}
+	if cfg.MongoReadPreference != "" {
+		rp, err := readPreference(cfg.MongoReadPreference)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetReadPreference(rp)
+	}
+	if cfg.MongoReadConcern != "" {
+		opts.SetReadConcern(readconcern.New(readconcern.Level(cfg.MongoReadConcern)))
+	}
+	if cfg.MongoCompressors != "" {
+		var compressors []string
+		for _, c := range strings.Split(cfg.MongoCompressors, ",") {
+			if c = strings.TrimSpace(c); c != "" {
+				compressors = append(compressors, c)
+			}
+		}
+		opts.SetCompressors(compressors)
+	}
+	return opts, opts.Validate()
+}
+
+// AnalyticsDatabase returns the database for reports and exports. Their reads use
+// cfg.MongoAnalyticsReadPreference, so with secondaryPreferred they run on a secondary when one is
+// up and may see slightly stale data.
+func AnalyticsDatabase(client *mongo.Client, cfg *config.Config) (*mongo.Database, error) {
+	if cfg.MongoAnalyticsReadPreference == "" {
+		return client.Database(cfg.MongoDB), nil
+	}
+	rp, err := readPreference(cfg.MongoAnalyticsReadPreference)
+	if err != nil {
+		return nil, err
+	}
+	return client.Database(cfg.MongoDB, options.Database().SetReadPreference(rp)), nil
+}
+
+func readPreference(mode string) (*readpref.ReadPref, error) {
+	m, err := readpref.ModeFromString(mode)
+	if err != nil {
+		return nil, err
+	}
+	return readpref.New(m)
+}
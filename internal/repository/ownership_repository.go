@@ -0,0 +1,36 @@
+// Package repository provides lookups of the client that records belong to.
+package repository
+
+import (
+	"context"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OwnershipRepository reads the reference a record keeps to its client, or to the parent record
+// that belongs to one, without decoding the rest of the record.
+type OwnershipRepository struct {
+	db *mongo.Database
+}
+
+// NewOwnershipRepository creates a new OwnershipRepository.
+func NewOwnershipRepository(db *mongo.Database) *OwnershipRepository {
+	return &OwnershipRepository{db: db}
+}
+
+// Reference returns field, in dotted form for nested fields, of the record id in collection. It
+// returns mongo.ErrNoDocuments when there is no such record and bsoncore.ErrElementNotFound when
+// the record has no such field.
+func (r *OwnershipRepository) Reference(ctx context.Context, collection, field string, id primitive.ObjectID) (bson.RawValue, error) {
+	raw, err := newCollection(r.db, collection).
+		FindOne(ctx, bson.M{"_id": id}, options.FindOne().SetProjection(bson.M{field: 1})).
+		DecodeBytes()
+	if err != nil {
+		return bson.RawValue{}, err
+	}
+	return raw.LookupErr(strings.Split(field, ".")...)
+}
@@ -0,0 +1,129 @@
+// Package repository provides data access layer for prompt templates.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// PromptTemplateRepository handles database operations for prompt templates.
+type PromptTemplateRepository struct {
+	collection *Collection
+}
+
+// NewPromptTemplateRepository creates a new PromptTemplateRepository.
+func NewPromptTemplateRepository(db *mongo.Database) *PromptTemplateRepository {
+	return &PromptTemplateRepository{
+		collection: newCollection(db, models.PromptTemplate{}.TableName()),
+	}
+}
+
+// Create inserts a new prompt template.
+func (r *PromptTemplateRepository) Create(ctx context.Context, template *models.PromptTemplate) error {
+	template.ID = primitive.NewObjectID()
+	template.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, template); err != nil {
+		return fmt.Errorf("failed to insert prompt template: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a client's prompt template by its ID.
+func (r *PromptTemplateRepository) GetByID(ctx context.Context, clientID string, id primitive.ObjectID) (*models.PromptTemplate, error) {
+	var template models.PromptTemplate
+	err := r.collection.FindOne(ctx, bson.M{"_id": id, "client_id": clientID}).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("prompt template not found")
+		}
+		return nil, fmt.Errorf("failed to find prompt template: %w", err)
+	}
+	return &template, nil
+}
+
+// FindPublished returns the client's template called name for channelType, with a published
+// version, or nil when there is none. An empty channelType finds the client-wide template.
+func (r *PromptTemplateRepository) FindPublished(ctx context.Context, clientID, name, channelType string) (*models.PromptTemplate, error) {
+	filter := bson.M{"client_id": clientID, "name": name, "active_version": bson.M{"$gt": 0}}
+	if channelType == "" {
+		filter["channel_type"] = bson.M{"$exists": false}
+	} else {
+		filter["channel_type"] = channelType
+	}
+	var template models.PromptTemplate
+	if err := r.collection.FindOne(ctx, filter).Decode(&template); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find prompt template: %w", err)
+	}
+	return &template, nil
+}
+
+// List retrieves a client's prompt templates by name, client-wide ones before channel variants.
+func (r *PromptTemplateRepository) List(ctx context.Context, filter bson.M) ([]models.PromptTemplate, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "channel_type", Value: 1}})
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find prompt templates: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	templates := make([]models.PromptTemplate, 0)
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, fmt.Errorf("failed to decode prompt templates: %w", err)
+	}
+	return templates, nil
+}
+
+// AddVersion appends version to a template that still has count versions, so two writers
+// can't both add the same version number. It reports whether the version was added.
+func (r *PromptTemplateRepository) AddVersion(ctx context.Context, clientID string, id primitive.ObjectID, count int, version models.PromptTemplateVersion) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "client_id": clientID, "versions": bson.M{"$size": count}},
+		bson.M{
+			"$push": bson.M{"versions": version},
+			"$set":  bson.M{"updated_at": time.Now().UTC()},
+		},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to add prompt template version: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Publish makes a version live if the active version is still from, and records the publication.
+// It reports whether the template was changed.
+func (r *PromptTemplateRepository) Publish(ctx context.Context, clientID string, id primitive.ObjectID, from int, publication models.PromptPublication) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "client_id": clientID, "active_version": from},
+		bson.M{
+			"$push": bson.M{"publications": publication},
+			"$set":  bson.M{"active_version": publication.Version, "updated_at": time.Now().UTC()},
+		},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to publish prompt template: %w", err)
+	}
+	return result.MatchedCount > 0, nil
+}
+
+// Delete removes a client's prompt template.
+func (r *PromptTemplateRepository) Delete(ctx context.Context, clientID string, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "client_id": clientID})
+	if err != nil {
+		return fmt.Errorf("failed to delete prompt template: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("prompt template not found")
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+// Package repository provides data access layer for rate limit buckets.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RateLimitRepository stores token buckets so that limits hold across API instances.
+type RateLimitRepository struct {
+	collection *Collection
+}
+
+// NewRateLimitRepository creates a new RateLimitRepository.
+func NewRateLimitRepository(db *mongo.Database) *RateLimitRepository {
+	return &RateLimitRepository{
+		collection: newCollection(db, models.RateLimitBucket{}.TableName()),
+	}
+}
+
+// Take refills the bucket key for the time since its last use, at refillPerSecond up to capacity,
+// then takes one token from it if there is one. Refill and take happen in a single update, so
+// concurrent requests on any instance can't overdraw the bucket. A missing bucket starts full.
+func (r *RateLimitRepository) Take(ctx context.Context, key string, capacity, refillPerSecond float64, now time.Time) (*models.RateLimitBucket, error) {
+	elapsed := bson.M{"$divide": bson.A{
+		bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{now, bson.M{"$ifNull": bson.A{"$updated_at", now}}}}}},
+		1000,
+	}}
+	refilled := bson.M{"$min": bson.A{
+		capacity,
+		bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$tokens", capacity}}, bson.M{"$multiply": bson.A{elapsed, refillPerSecond}}}},
+	}}
+	hasToken := bson.M{"$gte": bson.A{"$tokens", 1}}
+	untilFull := bson.M{"$multiply": bson.A{bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{capacity, "$tokens"}}, refillPerSecond}}, 1000}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tokens": refilled}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed":    hasToken,
+			"tokens":     bson.M{"$cond": bson.A{hasToken, bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"updated_at": now,
+		}}},
+		{{Key: "$set", Value: bson.M{"expires_at": bson.M{"$add": bson.A{now, untilFull}}}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var bucket models.RateLimitBucket
+	err := r.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&bucket)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another request created the bucket at the same time; it exists now
+		err = r.collection.FindOneAndUpdate(ctx, bson.M{"_id": key}, pipeline, opts).Decode(&bucket)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update rate limit bucket: %w", err)
+	}
+	return &bucket, nil
+}
@@ -0,0 +1,87 @@
+// Package repository provides data access layer for operator repair actions.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RepairActionRepository handles database operations for repair actions.
+type RepairActionRepository struct {
+	collection *Collection
+}
+
+// NewRepairActionRepository creates a new RepairActionRepository.
+func NewRepairActionRepository(db *mongo.Database) *RepairActionRepository {
+	return &RepairActionRepository{
+		collection: newCollection(db, models.RepairAction{}.TableName()),
+	}
+}
+
+// Create inserts a new repair action.
+func (r *RepairActionRepository) Create(ctx context.Context, action *models.RepairAction) error {
+	action.ID = primitive.NewObjectID()
+	action.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, action); err != nil {
+		return fmt.Errorf("failed to insert repair action: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a repair action by its ID.
+func (r *RepairActionRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.RepairAction, error) {
+	var action models.RepairAction
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&action)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("repair action not found")
+		}
+		return nil, fmt.Errorf("failed to find repair action: %w", err)
+	}
+	return &action, nil
+}
+
+// List retrieves repair actions, newest first.
+func (r *RepairActionRepository) List(ctx context.Context, filter map[string]interface{}, limit, offset int) ([]models.RepairAction, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find repair actions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	actions := make([]models.RepairAction, 0)
+	if err := cursor.All(ctx, &actions); err != nil {
+		return nil, fmt.Errorf("failed to decode repair actions: %w", err)
+	}
+	return actions, nil
+}
+
+// Update modifies an existing repair action.
+func (r *RepairActionRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	update["updated_at"] = time.Now().UTC()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return fmt.Errorf("failed to update repair action: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("repair action not found")
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package repository provides data access layer for report schedules.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReportScheduleRepository handles database operations for report schedules.
+type ReportScheduleRepository struct {
+	collection *Collection
+}
+
+// NewReportScheduleRepository creates a new ReportScheduleRepository.
+func NewReportScheduleRepository(db *mongo.Database) *ReportScheduleRepository {
+	return &ReportScheduleRepository{
+		collection: newCollection(db, models.ReportSchedule{}.TableName()),
+	}
+}
+
+// Create inserts a new report schedule.
+func (r *ReportScheduleRepository) Create(ctx context.Context, schedule *models.ReportSchedule) error {
+	schedule.ID = primitive.NewObjectID()
+	schedule.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, schedule); err != nil {
+		return fmt.Errorf("failed to insert report schedule: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a report schedule by its ID. An empty clientID matches any client.
+func (r *ReportScheduleRepository) GetByID(ctx context.Context, clientID string, id primitive.ObjectID) (*models.ReportSchedule, error) {
+	filter := bson.M{"_id": id}
+	if clientID != "" {
+		filter["client_id"] = clientID
+	}
+	var schedule models.ReportSchedule
+	err := r.collection.FindOne(ctx, filter).Decode(&schedule)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("report schedule not found")
+		}
+		return nil, fmt.Errorf("failed to find report schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+// List retrieves a client's report schedules by name.
+func (r *ReportScheduleRepository) List(ctx context.Context, clientID string) ([]models.ReportSchedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "name", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{"client_id": clientID}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find report schedules: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	schedules := make([]models.ReportSchedule, 0)
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to decode report schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// ListDue retrieves up to limit enabled schedules due at now, longest overdue first.
+func (r *ReportScheduleRepository) ListDue(ctx context.Context, now time.Time, limit int64) ([]models.ReportSchedule, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "next_run_at", Value: 1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{"enabled": true, "next_run_at": bson.M{"$lte": now}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find due report schedules: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	schedules := make([]models.ReportSchedule, 0)
+	if err := cursor.All(ctx, &schedules); err != nil {
+		return nil, fmt.Errorf("failed to decode report schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// Replace stores every field of a client's report schedule.
+func (r *ReportScheduleRepository) Replace(ctx context.Context, schedule *models.ReportSchedule) error {
+	schedule.UpdatedAt = time.Now().UTC()
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": schedule.ID, "client_id": schedule.ClientID}, schedule)
+	if err != nil {
+		return fmt.Errorf("failed to update report schedule: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("report schedule not found")
+	}
+	return nil
+}
+
+// Delete removes a client's report schedule.
+func (r *ReportScheduleRepository) Delete(ctx context.Context, clientID string, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "client_id": clientID})
+	if err != nil {
+		return fmt.Errorf("failed to delete report schedule: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("report schedule not found")
+	}
+	return nil
+}
+
+// Advance moves a schedule's next run from due to next. It returns false when the run was
+// advanced already, so of concurrent schedulers only one sends each report.
+func (r *ReportScheduleRepository) Advance(ctx context.Context, id primitive.ObjectID, due, next time.Time) (bool, error) {
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "next_run_at": due},
+		bson.M{"$set": bson.M{"next_run_at": next}},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to advance report schedule: %w", err)
+	}
+	return result.ModifiedCount == 1, nil
+}
+
+// RecordRun stores when a report was last sent and why it failed, if it did.
+func (r *ReportScheduleRepository) RecordRun(ctx context.Context, id primitive.ObjectID, at time.Time, runErr error) error {
+	update := bson.M{"$set": bson.M{"last_run_at": at}, "$unset": bson.M{"last_error": ""}}
+	if runErr != nil {
+		update = bson.M{"$set": bson.M{"last_run_at": at, "last_error": runErr.Error()}}
+	}
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update); err != nil {
+		return fmt.Errorf("failed to record report run: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Package repository provides data access layer for user role assignments.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RoleAssignmentRepository handles database operations for user role assignments.
+type RoleAssignmentRepository struct {
+	collection *Collection
+}
+
+// NewRoleAssignmentRepository creates a new RoleAssignmentRepository.
+func NewRoleAssignmentRepository(db *mongo.Database) *RoleAssignmentRepository {
+	return &RoleAssignmentRepository{
+		collection: newCollection(db, models.RoleAssignment{}.TableName()),
+	}
+}
+
+// GetBySubject retrieves the role assignment of a user.
+func (r *RoleAssignmentRepository) GetBySubject(ctx context.Context, subject string) (*models.RoleAssignment, error) {
+	var assignment models.RoleAssignment
+	err := r.collection.FindOne(ctx, bson.M{"subject": subject}).Decode(&assignment)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("role assignment not found")
+		}
+		return nil, fmt.Errorf("failed to find role assignment: %w", err)
+	}
+	return &assignment, nil
+}
+
+// List retrieves role assignments ordered by subject.
+func (r *RoleAssignmentRepository) List(ctx context.Context, limit, offset int) ([]models.RoleAssignment, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "subject", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find role assignments: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	assignments := make([]models.RoleAssignment, 0)
+	if err := cursor.All(ctx, &assignments); err != nil {
+		return nil, fmt.Errorf("failed to decode role assignments: %w", err)
+	}
+	return assignments, nil
+}
+
+// Upsert replaces the roles and client of a user's assignment, creating it if needed, and
+// returns the stored assignment.
+func (r *RoleAssignmentRepository) Upsert(ctx context.Context, subject string, roles []models.Role, clientID string) (*models.RoleAssignment, error) {
+	now := time.Now().UTC()
+	update := bson.M{
+		"$set":         bson.M{"roles": roles, "client_id": clientID, "updated_at": now},
+		"$setOnInsert": bson.M{"subject": subject, "created_at": now},
+	}
+	if clientID == "" {
+		update["$set"] = bson.M{"roles": roles, "updated_at": now}
+		update["$unset"] = bson.M{"client_id": ""}
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var assignment models.RoleAssignment
+	if err := r.collection.FindOneAndUpdate(ctx, bson.M{"subject": subject}, update, opts).Decode(&assignment); err != nil {
+		return nil, fmt.Errorf("failed to save role assignment: %w", err)
+	}
+	return &assignment, nil
+}
+
+// Delete removes a user's role assignment.
+func (r *RoleAssignmentRepository) Delete(ctx context.Context, subject string) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"subject": subject})
+	if err != nil {
+		return fmt.Errorf("failed to delete role assignment: %w", err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("role assignment not found")
+	}
+	return nil
+}
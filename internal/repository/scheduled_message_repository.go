@@ -0,0 +1,123 @@
+// Package repository provides data access layer for scheduled messages.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ScheduledMessageRepository handles database operations for scheduled messages.
+type ScheduledMessageRepository struct {
+	collection *Collection
+}
+
+// NewScheduledMessageRepository creates a new ScheduledMessageRepository.
+func NewScheduledMessageRepository(db *mongo.Database) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{
+		collection: newCollection(db, models.ScheduledMessage{}.TableName()),
+	}
+}
+
+// Create inserts a new scheduled message.
+func (r *ScheduledMessageRepository) Create(ctx context.Context, msg *models.ScheduledMessage) error {
+	msg.ID = primitive.NewObjectID()
+	msg.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, msg); err != nil {
+		return fmt.Errorf("failed to insert scheduled message: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a scheduled message by its ID.
+func (r *ScheduledMessageRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.ScheduledMessage, error) {
+	var msg models.ScheduledMessage
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&msg)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("scheduled message not found")
+		}
+		return nil, fmt.Errorf("failed to find scheduled message: %w", err)
+	}
+	return &msg, nil
+}
+
+// List retrieves scheduled messages ordered by send time.
+func (r *ScheduledMessageRepository) List(ctx context.Context, filter map[string]interface{}, limit, offset int) ([]models.ScheduledMessage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "send_at", Value: 1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find scheduled messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	messages := make([]models.ScheduledMessage, 0)
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode scheduled messages: %w", err)
+	}
+	return messages, nil
+}
+
+// ListOverdue retrieves up to limit pending messages whose send time is older than before,
+// oldest first.
+func (r *ScheduledMessageRepository) ListOverdue(ctx context.Context, before time.Time, limit int64) ([]models.ScheduledMessage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "send_at", Value: 1}}).SetLimit(limit)
+	cursor, err := r.collection.Find(ctx, bson.M{
+		"status":  models.ScheduledMessageStatusPending,
+		"send_at": bson.M{"$lt": before},
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find overdue scheduled messages: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	messages := make([]models.ScheduledMessage, 0)
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode scheduled messages: %w", err)
+	}
+	return messages, nil
+}
+
+// UpdateIfPending applies update only while the message is still pending.
+// It returns false when the message was already sent, cancelled or failed, so
+// cancellation and delivery cannot both win.
+func (r *ScheduledMessageRepository) UpdateIfPending(ctx context.Context, id primitive.ObjectID, update bson.M) (bool, error) {
+	update["updated_at"] = time.Now().UTC()
+
+	result, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.ScheduledMessageStatusPending},
+		bson.M{"$set": update},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to update scheduled message: %w", err)
+	}
+	return result.ModifiedCount > 0, nil
+}
+
+// Update modifies an existing scheduled message.
+func (r *ScheduledMessageRepository) Update(ctx context.Context, id primitive.ObjectID, update bson.M) error {
+	update["updated_at"] = time.Now().UTC()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": update})
+	if err != nil {
+		return fmt.Errorf("failed to update scheduled message: %w", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("scheduled message not found")
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+// Package repository provides data access layer for conversation simulations.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SimulationRepository handles database operations for simulations.
+type SimulationRepository struct {
+	collection *Collection
+}
+
+// NewSimulationRepository creates a new SimulationRepository.
+func NewSimulationRepository(db *mongo.Database) *SimulationRepository {
+	return &SimulationRepository{
+		collection: newCollection(db, models.Simulation{}.TableName()),
+	}
+}
+
+// Create inserts a new simulation with the ID it was given, so its session can refer to it.
+func (r *SimulationRepository) Create(ctx context.Context, sim *models.Simulation) error {
+	if sim.ID.IsZero() {
+		sim.ID = primitive.NewObjectID()
+	}
+	sim.BeforeCreate()
+
+	if _, err := r.collection.InsertOne(ctx, sim); err != nil {
+		return fmt.Errorf("failed to insert simulation: %w", err)
+	}
+	return nil
+}
+
+// GetByID retrieves a simulation by its ID.
+func (r *SimulationRepository) GetByID(ctx context.Context, id primitive.ObjectID) (*models.Simulation, error) {
+	var sim models.Simulation
+	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&sim); err != nil {
+		return nil, err
+	}
+	return &sim, nil
+}
+
+// List retrieves simulations matching filter, newest first, without their turns.
+func (r *SimulationRepository) List(ctx context.Context, filter bson.M, limit, offset int) ([]models.Simulation, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetProjection(bson.M{"turns": 0, "script": 0})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	if offset > 0 {
+		opts.SetSkip(int64(offset))
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find simulations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	sims := make([]models.Simulation, 0)
+	if err := cursor.All(ctx, &sims); err != nil {
+		return nil, fmt.Errorf("failed to decode simulations: %w", err)
+	}
+	return sims, nil
+}
+
+// Start marks a queued simulation as running. It returns false when the simulation isn't queued,
+// so a redelivered task doesn't run it twice.
+func (r *SimulationRepository) Start(ctx context.Context, id primitive.ObjectID, at time.Time) (bool, error) {
+	res, err := r.collection.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.SimulationStatusQueued},
+		bson.M{"$set": bson.M{"status": models.SimulationStatusRunning, "started_at": at, "updated_at": at}})
+	if err != nil {
+		return false, fmt.Errorf("failed to start simulation: %w", err)
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// AddTurn appends the turn of a step to a running simulation.
+func (r *SimulationRepository) AddTurn(ctx context.Context, id primitive.ObjectID, turn models.SimulationTurn) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$push": bson.M{"turns": turn},
+		"$set":  bson.M{"updated_at": time.Now().UTC()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record simulation turn: %w", err)
+	}
+	return nil
+}
+
+// Finish records how a simulation ended.
+func (r *SimulationRepository) Finish(ctx context.Context, id primitive.ObjectID, status models.SimulationStatus, report *models.SimulationReport, reason string) error {
+	now := time.Now().UTC()
+	set := bson.M{"status": status, "completed_at": now, "updated_at": now}
+	if report != nil {
+		set["report"] = report
+	}
+	if reason != "" {
+		set["error"] = reason
+	}
+	if _, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": set}); err != nil {
+		return fmt.Errorf("failed to finish simulation: %w", err)
+	}
+	return nil
+}
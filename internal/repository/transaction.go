@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Transactor runs groups of writes in a multi-document transaction, so either all of them are
+// stored or none is. Transactions need a replica set or a sharded cluster; on a standalone server,
+// or when disabled, the writes run one after another as they always did.
+type Transactor struct {
+	client  *mongo.Client
+	enabled bool
+}
+
+type transactionKey struct{}
+
+// transaction collects what has to wait for the commit of the transaction it belongs to.
+type transaction struct {
+	afterCommit []func(context.Context)
+}
+
+// NewTransactor creates a Transactor for client. With enabled set it asks the server whether it
+// supports transactions; the error says why it can't, and the Transactor is then still usable.
+func NewTransactor(ctx context.Context, client *mongo.Client, enabled bool) (*Transactor, error) {
+	t := &Transactor{client: client}
+	if !enabled || client == nil {
+		return t, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return t, fmt.Errorf("failed to check transaction support: %w", err)
+	}
+	// mongos answers with msg "isdbgrid"
+	if hello.SetName == "" && hello.Msg != "isdbgrid" {
+		return t, fmt.Errorf("transactions need a replica set or sharded cluster")
+	}
+	t.enabled = true
+	return t, nil
+}
+
+// Enabled reports whether Do runs its writes in a transaction.
+func (t *Transactor) Enabled() bool {
+	return t != nil && t.enabled
+}
+
+// Do runs fn in a transaction and commits it when fn returns nil. fn is run again when the
+// transaction fails with a transient error, so it must only write through the context it is given.
+// Inside a transaction fn joins it; without transactions fn runs on its own.
+func (t *Transactor) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if !t.Enabled() || InTransaction(ctx) {
+		return fn(ctx)
+	}
+
+	session, err := t.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer session.EndSession(ctx)
+
+	var tx *transaction
+	// Reads in a transaction must go to the primary, whatever the client's read preference is
+	opts := options.Transaction().SetReadPreference(readpref.Primary())
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		tx = &transaction{}
+		return nil, fn(context.WithValue(sc, transactionKey{}, tx))
+	}, opts)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range tx.afterCommit {
+		f(ctx)
+	}
+	return nil
+}
+
+// InTransaction reports whether ctx belongs to a transaction started by Do.
+func InTransaction(ctx context.Context) bool {
+	_, ok := ctx.Value(transactionKey{}).(*transaction)
+	return ok
+}
+
+// AfterCommit runs fn once the transaction of ctx is committed, and not at all when it is aborted.
+// Outside a transaction fn runs right away. Side effects that can't be rolled back, such as
+// publishing a task, belong here.
+func AfterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if tx, ok := ctx.Value(transactionKey{}).(*transaction); ok {
+		tx.afterCommit = append(tx.afterCommit, fn)
+		return
+	}
+	fn(ctx)
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VersionConflictError is returned by an update made at a version the document has moved past.
+type VersionConflictError struct {
+	Current int64
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict: the current version is %d", e.Current)
+}
+
+// versionFilter narrows filter to documents at version, when one is given. Documents written
+// before versions existed are at version 0.
+func versionFilter(filter bson.M, version *int64) bson.M {
+	if version == nil {
+		return filter
+	}
+	if *version == 0 {
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	} else {
+		filter["version"] = *version
+	}
+	return filter
+}
+
+// bumpVersion adds the version increment to update.
+func bumpVersion(update bson.M) bson.M {
+	inc, _ := update["$inc"].(bson.M)
+	if inc == nil {
+		inc = bson.M{}
+	}
+	inc["version"] = 1
+	update["$inc"] = inc
+	return update
+}
+
+// bumpVersionStage is the version increment as a stage of a pipeline update, which takes no $inc.
+func bumpVersionStage() bson.D {
+	return bson.D{{Key: "$set", Value: bson.M{
+		"version": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$version", 0}}, 1}},
+	}}}
+}
+
+// versionConflict tells why a versioned update of id matched nothing: a VersionConflictError when
+// the document exists at another version, mongo.ErrNoDocuments when it doesn't exist.
+func versionConflict(ctx context.Context, collection *Collection, id primitive.ObjectID) error {
+	var current struct {
+		Version int64 `bson:"version"`
+	}
+	opts := options.FindOne().SetProjection(bson.M{"version": 1})
+	if err := collection.FindOne(ctx, bson.M{"_id": id}, opts).Decode(&current); err != nil {
+		return err
+	}
+	return &VersionConflictError{Current: current.Version}
+}
@@ -0,0 +1,179 @@
+// Package scheduler enqueues periodic jobs on cron schedules. Any number of instances can run;
+// a lease in MongoDB elects the one that enqueues.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/cron"
+)
+
+const (
+	// leaseCollection holds the lease of the leading scheduler
+	leaseCollection = "scheduler_lease"
+	leaseID         = "scheduler"
+	// jobCollection records each job's next run, so a new leader picks up where the last one stopped
+	jobCollection = "scheduler_jobs"
+)
+
+// Job is a job enqueued whenever its schedule falls due.
+type Job struct {
+	Name     string
+	Schedule cron.Schedule
+}
+
+// Enqueuer enqueues a run of the named job.
+type Enqueuer interface {
+	EnqueueMaintenance(ctx context.Context, job string) error
+}
+
+// jobState is the stored schedule of a job.
+type jobState struct {
+	Name      string    `bson:"_id"`
+	Schedule  string    `bson:"schedule"`
+	NextRunAt time.Time `bson:"next_run_at"`
+	LastRunAt time.Time `bson:"last_run_at,omitempty"`
+}
+
+// Scheduler enqueues its jobs while it holds the lease. The leader renews the lease several
+// times per lease period; when it stops renewing, another instance takes over once the lease
+// has run out. A job due more than once while no instance led is enqueued once.
+type Scheduler struct {
+	db       *mongo.Database
+	enqueuer Enqueuer
+	jobs     []Job
+	lease    time.Duration
+	owner    string
+	logger   *zap.Logger
+	leading  bool
+}
+
+// New creates a Scheduler for jobs that holds the lease for lease at a time.
+func New(db *mongo.Database, enqueuer Enqueuer, jobs []Job, lease time.Duration, logger *zap.Logger) *Scheduler {
+	return &Scheduler{
+		db:       db,
+		enqueuer: enqueuer,
+		jobs:     jobs,
+		lease:    lease,
+		owner:    primitive.NewObjectID().Hex(),
+		logger:   logger,
+	}
+}
+
+// Run enqueues due jobs until ctx is cancelled, then gives up the lease.
+func (s *Scheduler) Run(ctx context.Context) error {
+	defer s.release()
+
+	// Due times are checked well within each minute and the lease renewed well within its period
+	ticker := time.NewTicker(min(s.lease/3, 15*time.Second))
+	defer ticker.Stop()
+	for {
+		s.tick(ctx, time.Now().UTC())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context, now time.Time) {
+	leading, err := s.acquire(ctx, now)
+	if err != nil {
+		s.logger.Error("Failed to renew scheduler lease", zap.Error(err))
+		leading = false
+	}
+	if leading != s.leading {
+		if leading {
+			s.logger.Info("Became the leading scheduler", zap.String("owner", s.owner))
+		} else {
+			s.logger.Info("No longer the leading scheduler", zap.String("owner", s.owner))
+		}
+		s.leading = leading
+	}
+	if !leading {
+		return
+	}
+
+	for _, job := range s.jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := s.runIfDue(ctx, job, now); err != nil {
+			s.logger.Error("Failed to schedule job", zap.String("job", job.Name), zap.Error(err))
+		}
+	}
+}
+
+// acquire takes or renews the lease and reports whether this scheduler holds it.
+func (s *Scheduler) acquire(ctx context.Context, now time.Time) (bool, error) {
+	_, err := s.db.Collection(leaseCollection).UpdateOne(ctx,
+		bson.M{"_id": leaseID, "$or": bson.A{
+			bson.M{"owner": s.owner},
+			bson.M{"locked_until": bson.M{"$lt": now}},
+		}},
+		bson.M{"$set": bson.M{"owner": s.owner, "locked_until": now.Add(s.lease)}},
+		options.Update().SetUpsert(true),
+	)
+	if err == nil {
+		return true, nil
+	}
+	// The upsert collides with the lease document while another scheduler holds it
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+func (s *Scheduler) release() {
+	if !s.leading {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := s.db.Collection(leaseCollection).DeleteOne(ctx, bson.M{"_id": leaseID, "owner": s.owner}); err != nil {
+		s.logger.Warn("Failed to release scheduler lease", zap.Error(err))
+	}
+}
+
+// runIfDue enqueues job when its stored next run has passed and moves the next run on. A job
+// seen for the first time, or whose schedule changed, is only scheduled.
+func (s *Scheduler) runIfDue(ctx context.Context, job Job, now time.Time) error {
+	jobs := s.db.Collection(jobCollection)
+	var state jobState
+	err := jobs.FindOne(ctx, bson.M{"_id": job.Name}).Decode(&state)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return fmt.Errorf("failed to read job schedule: %w", err)
+	}
+
+	update := bson.M{"schedule": job.Schedule.String(), "next_run_at": job.Schedule.Next(now)}
+	switch {
+	case err == mongo.ErrNoDocuments, state.Schedule != job.Schedule.String():
+		s.logger.Info("Scheduled job",
+			zap.String("job", job.Name),
+			zap.String("schedule", job.Schedule.String()),
+			zap.Time("next_run_at", update["next_run_at"].(time.Time)))
+	case now.Before(state.NextRunAt):
+		return nil
+	default:
+		if err := s.enqueuer.EnqueueMaintenance(ctx, job.Name); err != nil {
+			return fmt.Errorf("failed to enqueue job: %w", err)
+		}
+		update["last_run_at"] = now
+		s.logger.Info("Enqueued job", zap.String("job", job.Name), zap.Time("due_at", state.NextRunAt))
+	}
+
+	_, err = jobs.UpdateOne(ctx, bson.M{"_id": job.Name}, bson.M{"$set": update}, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to store job schedule: %w", err)
+	}
+	return nil
+}
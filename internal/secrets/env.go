@@ -0,0 +1,37 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"strings"
+)
+
+// EnvProvider reads secrets from environment variables named Prefix, the client's scope and the
+// reference's path, upper-cased with other characters turned into underscores. With the default
+// prefix, secret://env/webhook_token of client 65f0... reads SECRET_65F0..._WEBHOOK_TOKEN, so
+// references can't reach the deployment's own variables.
+type EnvProvider struct {
+	Prefix string
+}
+
+// Get returns the environment variable for path in scope.
+func (p EnvProvider) Get(_ context.Context, scope, path string) (string, error) {
+	value, ok := os.LookupEnv(p.Prefix + envName(scope) + "_" + envName(path))
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func envName(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,136 @@
+package secrets
+
+import (
+	"net/url"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Masked stands in for credentials in configuration read back through the API
+const Masked = "********"
+
+// credentialKeyParts mark configuration keys holding credentials, such as auth_token, bot_token,
+// app_secret, smtp_password, sendgrid_api_key or an Authorization header
+var credentialKeyParts = []string{"token", "secret", "password", "passwd", "credential", "authorization", "cookie", "api_key", "apikey", "api-key", "private_key", "client_key"}
+
+// IsCredentialKey reports whether a configuration key holds a credential.
+func IsCredentialKey(key string) bool {
+	key = strings.ToLower(key)
+	if key == "auth" {
+		return true
+	}
+	for _, part := range credentialKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskConfig returns a copy of config for read responses, with the string value of every credential
+// key replaced by Masked, at any depth and whether it is inline or a reference, and the password of
+// every URL replaced too. Objects under credential keys, like auth, keep their other fields. Writing
+// a masked config back keeps the credentials; see UnmaskConfig.
+func MaskConfig(config map[string]interface{}) map[string]interface{} {
+	if config == nil {
+		return nil
+	}
+	return maskValue(config).(map[string]interface{})
+}
+
+func maskValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return maskURLPassword(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			if _, ok := item.(string); ok && IsCredentialKey(k) {
+				out[k] = Masked
+				continue
+			}
+			out[k] = maskValue(item)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = maskValue(item)
+		}
+		return out
+	case primitive.A:
+		// Arrays of configs read from Mongo
+		return maskValue([]interface{}(v))
+	default:
+		return v
+	}
+}
+
+// maskURLPassword masks the password of value if it is a URL carrying one, like an AMQP or proxy URL.
+func maskURLPassword(value string) string {
+	if !strings.Contains(value, "@") || !strings.Contains(value, "://") {
+		return value
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return value
+	}
+	if _, ok := u.User.Password(); !ok {
+		return value
+	}
+	u.User = url.UserPassword(u.User.Username(), Masked)
+	return strings.Replace(u.String(), url.QueryEscape(Masked), Masked, 1)
+}
+
+// UnmaskConfig returns config, as written by a caller that may have read it masked, with every
+// value still masked taken from stored, the config it replaces. Masked values stored has no
+// counterpart for are left as written.
+func UnmaskConfig(config, stored map[string]interface{}) map[string]interface{} {
+	if config == nil || stored == nil {
+		return config
+	}
+	return unmaskValue(config, stored).(map[string]interface{})
+}
+
+func unmaskValue(v, stored interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		storedString, ok := stored.(string)
+		if !ok {
+			return v
+		}
+		if v == Masked {
+			return storedString
+		}
+		if strings.Contains(v, Masked) && maskURLPassword(storedString) == v {
+			return storedString
+		}
+		return v
+	case map[string]interface{}:
+		storedMap, _ := stored.(map[string]interface{})
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = unmaskValue(item, storedMap[k])
+		}
+		return out
+	case []interface{}:
+		storedSlice, ok := stored.([]interface{})
+		if !ok {
+			storedSlice, _ = stored.(primitive.A)
+		}
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			var storedItem interface{}
+			if i < len(storedSlice) {
+				storedItem = storedSlice[i]
+			}
+			out[i] = unmaskValue(item, storedItem)
+		}
+		return out
+	case primitive.A:
+		return unmaskValue([]interface{}(v), stored)
+	default:
+		return v
+	}
+}
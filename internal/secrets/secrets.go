@@ -0,0 +1,222 @@
+// Package secrets resolves references to credentials kept in a secrets store, so processor and
+// channel configuration can hold "secret://<provider>/<path>[#<key>]" instead of the credential.
+// References are resolved only when a delivery or connector needs the value; the configuration
+// keeps the reference, and what is read back from it has credentials masked (see MaskConfig).
+//
+// Each client's references are scoped to the client: a path is looked up under the client's own
+// prefix in the store, so one client can't name another's secrets.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheme prefixes configuration values that are secret references
+const Scheme = "secret://"
+
+// Provider names usable in references
+const (
+	ProviderEnv   = "env"
+	ProviderVault = "vault"
+	ProviderAWS   = "aws"
+)
+
+var knownProviders = map[string]bool{ProviderEnv: true, ProviderVault: true, ProviderAWS: true}
+
+var (
+	// ErrInvalidRef is returned for values that start with Scheme but aren't valid references.
+	ErrInvalidRef = errors.New("invalid secret reference")
+	// ErrProviderNotConfigured is returned for references to a provider the deployment doesn't set up.
+	ErrProviderNotConfigured = errors.New("secrets provider not configured")
+	// ErrNotFound is returned when the store has no secret at a reference.
+	ErrNotFound = errors.New("secret not found")
+)
+
+// Provider looks up a client's secret in a secrets store. The value may be JSON, from which a
+// reference's key selects a field.
+type Provider interface {
+	Get(ctx context.Context, scope, path string) (string, error)
+}
+
+// Ref is a parsed secret reference.
+type Ref struct {
+	Provider string
+	Path     string
+	Key      string // Field of a JSON secret; empty for the whole value
+}
+
+// IsRef reports whether value is meant as a secret reference.
+func IsRef(value string) bool {
+	return strings.HasPrefix(value, Scheme)
+}
+
+// ParseRef parses a "secret://<provider>/<path>[#<key>]" reference.
+func ParseRef(value string) (*Ref, error) {
+	if !IsRef(value) {
+		return nil, fmt.Errorf("%w: must start with %s", ErrInvalidRef, Scheme)
+	}
+	rest := strings.TrimPrefix(value, Scheme)
+	provider, path, ok := strings.Cut(rest, "/")
+	if !ok || path == "" {
+		return nil, fmt.Errorf("%w: %q has no path", ErrInvalidRef, value)
+	}
+	if !knownProviders[provider] {
+		return nil, fmt.Errorf("%w: unknown provider %q", ErrInvalidRef, provider)
+	}
+	path, key, _ := strings.Cut(path, "#")
+	if path == "" || strings.Contains(path, "..") || strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("%w: %q has an invalid path", ErrInvalidRef, value)
+	}
+	return &Ref{Provider: provider, Path: path, Key: key}, nil
+}
+
+// ValidateRefs checks that every reference in config, at any depth, parses. It doesn't look the
+// secrets up.
+func ValidateRefs(config map[string]interface{}) error {
+	var err error
+	walk(config, func(value string) string {
+		if err == nil && IsRef(value) {
+			_, err = ParseRef(value)
+		}
+		return value
+	})
+	return err
+}
+
+// Resolver resolves secret references with the providers the deployment configures, caching
+// resolved values for a while so each delivery doesn't call the store.
+type Resolver struct {
+	providers map[string]Provider
+	ttl       time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cachedSecret
+}
+
+type cachedSecret struct {
+	value     string
+	expiresAt time.Time
+}
+
+// NewResolver creates a Resolver that caches values for ttl; 0 disables caching.
+func NewResolver(ttl time.Duration) *Resolver {
+	return &Resolver{
+		providers: make(map[string]Provider),
+		ttl:       ttl,
+		cache:     make(map[string]cachedSecret),
+	}
+}
+
+// Register makes a provider available to references under name.
+func (r *Resolver) Register(name string, provider Provider) {
+	r.providers[name] = provider
+}
+
+// Resolve returns the secret value is a reference to, looked up in the scope of a client, or
+// value itself when it isn't a reference.
+func (r *Resolver) Resolve(ctx context.Context, scope, value string) (string, error) {
+	if !IsRef(value) {
+		return value, nil
+	}
+	ref, err := ParseRef(value)
+	if err != nil {
+		return "", err
+	}
+	if r == nil {
+		return "", fmt.Errorf("%w: %s", ErrProviderNotConfigured, ref.Provider)
+	}
+	provider, ok := r.providers[ref.Provider]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrProviderNotConfigured, ref.Provider)
+	}
+
+	cacheKey := scope + "\x00" + value
+	if secret, ok := r.cached(cacheKey); ok {
+		return secret, nil
+	}
+	secret, err := provider.Get(ctx, scope, ref.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", value, err)
+	}
+	if ref.Key != "" {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(secret), &fields); err != nil {
+			return "", fmt.Errorf("failed to resolve %s: secret isn't a JSON object", value)
+		}
+		field, ok := fields[ref.Key].(string)
+		if !ok {
+			return "", fmt.Errorf("failed to resolve %s: %w", value, ErrNotFound)
+		}
+		secret = field
+	}
+	r.store(cacheKey, secret)
+	return secret, nil
+}
+
+// ResolveConfig returns a copy of config with every reference in it, at any depth, resolved in
+// the scope of a client. config itself is left untouched.
+func (r *Resolver) ResolveConfig(ctx context.Context, scope string, config map[string]interface{}) (map[string]interface{}, error) {
+	var err error
+	resolved := walk(config, func(value string) string {
+		if err != nil || !IsRef(value) {
+			return value
+		}
+		var secret string
+		secret, err = r.Resolve(ctx, scope, value)
+		return secret
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]interface{}), nil
+}
+
+func (r *Resolver) cached(key string) (string, bool) {
+	if r.ttl <= 0 {
+		return "", false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	secret, ok := r.cache[key]
+	if !ok || time.Now().After(secret.expiresAt) {
+		return "", false
+	}
+	return secret.value, true
+}
+
+func (r *Resolver) store(key, value string) {
+	if r.ttl <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cache[key] = cachedSecret{value: value, expiresAt: time.Now().Add(r.ttl)}
+}
+
+// walk returns a copy of v with fn applied to every string in it.
+func walk(v interface{}, fn func(string) string) interface{} {
+	switch v := v.(type) {
+	case string:
+		return fn(v)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			out[k] = walk(item, fn)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = walk(item, fn)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,78 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// VaultProvider reads secrets from a HashiCorp Vault KV version 2 engine. A client's secrets live
+// under <Mount>/<Prefix>/<scope>/, and each secret is returned as a JSON object of its fields, so
+// references pick one with #key.
+type VaultProvider struct {
+	Addr       string
+	Token      string
+	Mount      string
+	Prefix     string
+	HTTPClient *http.Client
+}
+
+// NewVaultProvider creates a VaultProvider for the Vault server at addr.
+func NewVaultProvider(addr, token, mount, prefix string) *VaultProvider {
+	return &VaultProvider{
+		Addr:       strings.TrimRight(addr, "/"),
+		Token:      token,
+		Mount:      mount,
+		Prefix:     strings.Trim(prefix, "/"),
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Get returns the fields of the secret at path in scope, as JSON.
+func (p *VaultProvider) Get(ctx context.Context, scope, path string) (string, error) {
+	url := fmt.Sprintf("%s/v1/%s/data/%s", p.Addr, p.Mount, joinPath(p.Prefix, scope, path))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", p.Token)
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return "", ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d", resp.StatusCode)
+	}
+
+	var body struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	if len(body.Data.Data) == 0 || string(body.Data.Data) == "null" {
+		return "", ErrNotFound
+	}
+	return string(body.Data.Data), nil
+}
+
+// joinPath joins the non-empty parts of a store path with slashes.
+func joinPath(parts ...string) string {
+	var nonEmpty []string
+	for _, part := range parts {
+		if part = strings.Trim(part, "/"); part != "" {
+			nonEmpty = append(nonEmpty, part)
+		}
+	}
+	return strings.Join(nonEmpty, "/")
+}
@@ -0,0 +1,204 @@
+// Package service provides business logic for batching AI suggestion requests.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+)
+
+const (
+	// DefaultAIBatchWindow is how long the first suggestion request of a batch waits for others
+	DefaultAIBatchWindow = 200 * time.Millisecond
+	// DefaultAIBatchMaxSize is how many suggestion requests a batch holds before it is sent at once
+	DefaultAIBatchMaxSize = 20
+)
+
+// AIBatchRequest is the body sent to the AI service's batch endpoint: suggestion requests of one
+// client.
+type AIBatchRequest struct {
+	ClientID string      `json:"client_id"`
+	Requests []AIRequest `json:"requests"`
+}
+
+// AIBatchResponse is the batch endpoint's reply: one response per request, matched by message_id,
+// or by position when a response has none.
+type AIBatchResponse struct {
+	Responses []AIResponse `json:"responses"`
+}
+
+// SuggestionBatcher groups suggestion requests of the same client that arrive within Window and
+// sends them to the AI service's batch endpoint in one call. Each caller blocks until its batch
+// is answered. A batch of one goes to the regular endpoint.
+type SuggestionBatcher struct {
+	AI       *AIService
+	BatchURL string
+	Window   time.Duration
+	MaxSize  int
+
+	mu      sync.Mutex
+	pending map[string]*suggestionBatch
+}
+
+type suggestionBatch struct {
+	ctx      context.Context
+	requests []AIRequest
+	results  []chan suggestionResult
+	timer    *time.Timer
+}
+
+type suggestionResult struct {
+	response *AIResponse
+	err      error
+}
+
+// NewSuggestionBatcher creates a SuggestionBatcher sending batches to batchURL through ai.
+func NewSuggestionBatcher(ai *AIService, batchURL string, window time.Duration, maxSize int) *SuggestionBatcher {
+	return &SuggestionBatcher{
+		AI:       ai,
+		BatchURL: batchURL,
+		Window:   window,
+		MaxSize:  maxSize,
+		pending:  make(map[string]*suggestionBatch),
+	}
+}
+
+// GenerateSuggestions adds a suggestion request for a message of clientID to that client's
+// pending batch and waits for its response. modelConfig is the client's, so it is the same for
+// the whole batch.
+func (b *SuggestionBatcher) GenerateSuggestions(ctx context.Context, clientID, messageID, sessionID, message string, sessionContext map[string]interface{}, modelConfig *models.ClientAIConfig) (*AIResponse, error) {
+	result := make(chan suggestionResult, 1)
+	request := AIRequest{
+		MessageID:        messageID,
+		SessionID:        sessionID,
+		CurrentMessage:   message,
+		CurrentMessageID: messageID,
+		Context:          sessionContext,
+		Suggestion:       true,
+		ModelConfig:      modelConfig,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	b.mu.Lock()
+	batch, ok := b.pending[clientID]
+	if !ok {
+		// The batch outlives the task that opened it, so it keeps that task's trace but not its deadline
+		batch = &suggestionBatch{ctx: context.WithoutCancel(ctx)}
+		b.pending[clientID] = batch
+		batch.timer = time.AfterFunc(b.Window, func() { b.flush(clientID, batch) })
+	}
+	batch.requests = append(batch.requests, request)
+	batch.results = append(batch.results, result)
+	full := len(batch.requests) >= b.MaxSize
+	if full {
+		delete(b.pending, clientID)
+	}
+	b.mu.Unlock()
+
+	if full {
+		batch.timer.Stop()
+		go b.send(clientID, batch)
+	}
+
+	select {
+	case r := <-result:
+		return r.response, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flush sends batch when its window closes, unless it filled up and was sent already.
+func (b *SuggestionBatcher) flush(clientID string, batch *suggestionBatch) {
+	b.mu.Lock()
+	if b.pending[clientID] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, clientID)
+	b.mu.Unlock()
+	b.send(clientID, batch)
+}
+
+// send calls the AI service for batch and hands each caller its response.
+func (b *SuggestionBatcher) send(clientID string, batch *suggestionBatch) {
+	if len(batch.requests) == 1 {
+		response, err := b.AI.ProcessAIRequest(batch.ctx, batch.requests[0])
+		batch.results[0] <- suggestionResult{response, err}
+		return
+	}
+
+	responses, err := b.AI.ProcessAIBatch(batch.ctx, b.BatchURL, AIBatchRequest{ClientID: clientID, Requests: batch.requests})
+	if err != nil {
+		for _, result := range batch.results {
+			result <- suggestionResult{err: err}
+		}
+		return
+	}
+	byMessage := make(map[string]*AIResponse, len(responses))
+	for i := range responses {
+		if responses[i].MessageID != "" {
+			byMessage[responses[i].MessageID] = &responses[i]
+		}
+	}
+	for i, request := range batch.requests {
+		response := byMessage[request.MessageID]
+		if response == nil && i < len(responses) && responses[i].MessageID == "" {
+			response = &responses[i]
+		}
+		switch {
+		case response == nil:
+			batch.results[i] <- suggestionResult{err: fmt.Errorf("AI batch response has no result for message %s", request.MessageID)}
+		case response.Error != "":
+			batch.results[i] <- suggestionResult{err: fmt.Errorf("AI service failed message %s: %s", request.MessageID, response.Error)}
+		default:
+			batch.results[i] <- suggestionResult{response: response}
+		}
+	}
+}
+
+// ProcessAIBatch sends batch to the AI service's batch endpoint at url.
+func (ai *AIService) ProcessAIBatch(ctx context.Context, url string, batch AIBatchRequest) (responses []AIResponse, err error) {
+	ai.logger.Info("Processing AI batch request",
+		zap.String("client_id", batch.ClientID),
+		zap.Int("size", len(batch.Requests)))
+
+	requestBytes, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create batch request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ai.aiToken))
+	req.Header.Set("User-Agent", "Fraiday-AI-Client/1.0")
+
+	start := time.Now()
+	defer func() { telemetry.ObserveAIRequest("suggestions_batch", time.Since(start), err) }()
+	resp, err := ai.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send AI batch request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("AI service returned status %d for batch", resp.StatusCode)
+	}
+	var batchResponse AIBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&batchResponse); err != nil {
+		return nil, fmt.Errorf("failed to decode batch response: %w", err)
+	}
+	return batchResponse.Responses, nil
+}
@@ -0,0 +1,366 @@
+// Package service provides business logic for forwarding answer feedback to the AI service.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+)
+
+const (
+	defaultAIFeedbackContextMessages = 6
+	maxAIFeedbackContextMessages     = 50
+	// maxAIFeedbackAttempts is how many times a feedback is sent before it is given up on
+	maxAIFeedbackAttempts = 8
+	// Failed sends are retried after aiFeedbackRetryBase, doubling with each attempt up to aiFeedbackRetryMax
+	aiFeedbackRetryBase = time.Minute
+	aiFeedbackRetryMax  = 6 * time.Hour
+)
+
+// AIFeedbackRequest is the body sent to the AI service's feedback endpoint: feedback on the AI
+// answers of one client.
+type AIFeedbackRequest struct {
+	ClientID string           `json:"client_id"`
+	Feedback []AIFeedbackItem `json:"feedback"`
+}
+
+// AIFeedbackItem is the feedback on one AI answer, with the conversation that led up to it.
+type AIFeedbackItem struct {
+	FeedbackID   string           `json:"feedback_id"`
+	MessageID    string           `json:"message_id"`
+	SessionID    string           `json:"session_id"`
+	Thumb        string           `json:"thumb"` // "up" for positive ratings, "down" otherwise
+	Rating       int              `json:"rating"`
+	Comment      string           `json:"comment,omitempty"`
+	Answer       string           `json:"answer"`
+	Confidence   float64          `json:"confidence,omitempty"`
+	Conversation []AIFeedbackTurn `json:"conversation"` // Oldest first, ending with the answer
+	RatedAt      time.Time        `json:"rated_at"`
+}
+
+// AIFeedbackTurn is one message of the conversation sent with a feedback.
+type AIFeedbackTurn struct {
+	MessageID  string    `json:"message_id"`
+	SenderType string    `json:"sender_type"`
+	Text       string    `json:"text"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// AIFeedbackService manages per-client AI feedback policies and forwards the feedback of clients
+// that opt in to the AI service. Feedback is queued when it is given and sent in batches by the
+// ai_feedback maintenance job, which retries failed sends with backoff.
+type AIFeedbackService struct {
+	Repo            *repository.ChatMessageFeedbackRepository
+	ClientRepo      *repository.ClientRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	ChatMessageRepo *repository.ChatMessageRepository
+	AI              *AIService
+	// URL is the AI service's feedback endpoint; without one no feedback is queued or sent
+	URL         string
+	BatchSize   int
+	Invalidator CacheInvalidator
+	Clients     ClientCache
+	logger      *zap.Logger
+}
+
+// NewAIFeedbackService creates a new AIFeedbackService.
+func NewAIFeedbackService(
+	repo *repository.ChatMessageFeedbackRepository,
+	clientRepo *repository.ClientRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	ai *AIService,
+	url string,
+	batchSize int,
+	logger *zap.Logger,
+) *AIFeedbackService {
+	return &AIFeedbackService{
+		Repo:            repo,
+		ClientRepo:      clientRepo,
+		ChatSessionRepo: chatSessionRepo,
+		ChatMessageRepo: chatMessageRepo,
+		AI:              ai,
+		URL:             url,
+		BatchSize:       batchSize,
+		logger:          logger,
+	}
+}
+
+// GetPolicy returns a client's AI feedback policy, or nil if none is configured.
+func (s *AIFeedbackService) GetPolicy(ctx context.Context, clientID string) (*models.AIFeedbackPolicy, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return client.AIFeedback, nil
+}
+
+// SetPolicy validates and stores a client's AI feedback policy, replacing any existing one.
+func (s *AIFeedbackService) SetPolicy(ctx context.Context, clientID string, policy *models.AIFeedbackPolicy) error {
+	if policy.ContextMessages == 0 {
+		policy.ContextMessages = defaultAIFeedbackContextMessages
+	}
+	if policy.ContextMessages < 0 || policy.ContextMessages > maxAIFeedbackContextMessages {
+		return fmt.Errorf("context_messages must be between 1 and %d", maxAIFeedbackContextMessages)
+	}
+
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"ai_feedback": policy}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// DeletePolicy removes a client's AI feedback policy. Feedback already queued is skipped rather
+// than sent.
+func (s *AIFeedbackService) DeletePolicy(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"ai_feedback": nil}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+func (s *AIFeedbackService) invalidate(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
+
+// Queue marks a feedback for forwarding when it rates an AI answer of a client that opted in.
+// Sandbox sessions are left out. Failures are logged: the feedback itself is already saved.
+func (s *AIFeedbackService) Queue(ctx context.Context, feedback *models.ChatMessageFeedback) {
+	if s.URL == "" {
+		return
+	}
+	message, err := s.ChatMessageRepo.GetByID(ctx, feedback.ChatMessageID)
+	if err != nil || message.SenderType != string(models.SenderTypeAssistant) {
+		return
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, message.SessionID)
+	if err != nil || session.Client == nil || session.Test {
+		return
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client)
+	if err != nil || client.AIFeedback == nil || !client.AIFeedback.Enabled {
+		return
+	}
+	if err := s.Repo.QueueForward(ctx, feedback.ID, client.ClientID, time.Now().UTC()); err != nil {
+		s.logger.Warn("Failed to queue feedback for the AI service",
+			zap.String("feedback_id", feedback.ID.Hex()),
+			zap.Error(err))
+	}
+}
+
+// Forward sends the queued feedback that is due, a batch per client, and returns how many were
+// sent. Feedback of clients that opted out since is skipped.
+func (s *AIFeedbackService) Forward(ctx context.Context, now time.Time) (int, error) {
+	if s.URL == "" {
+		return 0, nil
+	}
+	due, err := s.Repo.ListDueForwards(ctx, now, maintenanceBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var clientIDs []string
+	byClient := make(map[string][]models.ChatMessageFeedback)
+	for _, feedback := range due {
+		if _, ok := byClient[feedback.ClientID]; !ok {
+			clientIDs = append(clientIDs, feedback.ClientID)
+		}
+		byClient[feedback.ClientID] = append(byClient[feedback.ClientID], feedback)
+	}
+
+	sent := 0
+	for _, clientID := range clientIDs {
+		n, err := s.forwardClient(ctx, clientID, byClient[clientID], now)
+		sent += n
+		if err != nil {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+// forwardClient sends the due feedback of one client in batches of BatchSize.
+func (s *AIFeedbackService) forwardClient(ctx context.Context, clientID string, feedbacks []models.ChatMessageFeedback, now time.Time) (int, error) {
+	policy, err := s.policy(ctx, clientID)
+	if err != nil {
+		return 0, err
+	}
+	if policy == nil || !policy.Enabled {
+		for _, feedback := range feedbacks {
+			if _, err := s.Repo.UpdateForward(ctx, feedback.ID, feedback.UpdatedAt, bson.M{"forward_status": models.FeedbackForwardSkipped}); err != nil {
+				return 0, err
+			}
+		}
+		return 0, nil
+	}
+
+	var items []AIFeedbackItem
+	var included []models.ChatMessageFeedback
+	for _, feedback := range feedbacks {
+		item, err := s.feedbackItem(ctx, &feedback, policy)
+		if err != nil {
+			// The rated message or its session is gone, so there is nothing to send
+			if _, err := s.Repo.UpdateForward(ctx, feedback.ID, feedback.UpdatedAt, bson.M{
+				"forward_status": models.FeedbackForwardSkipped,
+				"forward_error":  err.Error(),
+			}); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		items = append(items, *item)
+		included = append(included, feedback)
+	}
+
+	batchSize := s.BatchSize
+	if batchSize < 1 {
+		batchSize = len(items)
+	}
+	sent := 0
+	for start := 0; start < len(items); start += batchSize {
+		end := min(start+batchSize, len(items))
+		sendErr := s.AI.SendFeedback(ctx, s.URL, AIFeedbackRequest{ClientID: clientID, Feedback: items[start:end]})
+		for _, feedback := range included[start:end] {
+			if err := s.recordSend(ctx, &feedback, sendErr, now); err != nil {
+				return sent, err
+			}
+		}
+		if sendErr != nil {
+			s.logger.Warn("Failed to forward feedback to the AI service",
+				zap.String("client_id", clientID),
+				zap.Int("feedback", end-start),
+				zap.Error(sendErr))
+			continue
+		}
+		sent += end - start
+	}
+	return sent, nil
+}
+
+// recordSend records the outcome of sending a feedback: sent, or due again after a backoff
+// until it has failed maxAIFeedbackAttempts times.
+func (s *AIFeedbackService) recordSend(ctx context.Context, feedback *models.ChatMessageFeedback, sendErr error, now time.Time) error {
+	set := bson.M{"forwarded_at": now, "forward_status": models.FeedbackForwardSent}
+	if sendErr != nil {
+		attempts := feedback.ForwardAttempts + 1
+		set = bson.M{"forward_attempts": attempts, "forward_error": sendErr.Error()}
+		if attempts >= maxAIFeedbackAttempts {
+			set["forward_status"] = models.FeedbackForwardFailed
+		} else {
+			set["next_forward_at"] = now.Add(min(aiFeedbackRetryBase<<(attempts-1), aiFeedbackRetryMax))
+		}
+	}
+	_, err := s.Repo.UpdateForward(ctx, feedback.ID, feedback.UpdatedAt, set)
+	return err
+}
+
+// feedbackItem builds what the AI service is sent for a feedback: the rated answer and the
+// conversation up to it.
+func (s *AIFeedbackService) feedbackItem(ctx context.Context, feedback *models.ChatMessageFeedback, policy *models.AIFeedbackPolicy) (*AIFeedbackItem, error) {
+	message, err := s.ChatMessageRepo.GetByID(ctx, feedback.ChatMessageID)
+	if err != nil {
+		return nil, errors.New("rated message not found")
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, message.SessionID)
+	if err != nil {
+		return nil, errors.New("session of the rated message not found")
+	}
+	history, err := s.ChatMessageRepo.List(ctx, bson.M{
+		"session":    message.SessionID,
+		"created_at": bson.M{"$lte": message.CreatedAt},
+	}, int64(policy.ContextMessages))
+	if err != nil {
+		return nil, err
+	}
+
+	item := &AIFeedbackItem{
+		FeedbackID:   feedback.ID.Hex(),
+		MessageID:    message.ID.Hex(),
+		SessionID:    session.SessionID,
+		Thumb:        "down",
+		Rating:       feedback.Rating,
+		Answer:       message.Text,
+		Confidence:   message.Confidence,
+		Conversation: make([]AIFeedbackTurn, 0, len(history)),
+		RatedAt:      feedback.UpdatedAt,
+	}
+	if feedback.Rating > 0 {
+		item.Thumb = "up"
+	}
+	if policy.IncludeComments && feedback.Comment != nil {
+		item.Comment = *feedback.Comment
+	}
+	// history is newest first
+	for i := len(history) - 1; i >= 0; i-- {
+		item.Conversation = append(item.Conversation, AIFeedbackTurn{
+			MessageID:  history[i].ID.Hex(),
+			SenderType: history[i].SenderType,
+			Text:       history[i].Text,
+			CreatedAt:  history[i].CreatedAt,
+		})
+	}
+	return item, nil
+}
+
+func (s *AIFeedbackService) policy(ctx context.Context, clientID string) (*models.AIFeedbackPolicy, error) {
+	var client *models.Client
+	var err error
+	if s.Clients != nil {
+		client, err = s.Clients.GetClient(ctx, clientID)
+	} else {
+		client, err = s.ClientRepo.GetByClientID(ctx, clientID)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// A deleted client has opted out
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return client.AIFeedback, nil
+}
+
+// SendFeedback sends a batch of answer feedback to the AI service's feedback endpoint at url.
+func (ai *AIService) SendFeedback(ctx context.Context, url string, request AIFeedbackRequest) (err error) {
+	requestBytes, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to marshal feedback request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(requestBytes))
+	if err != nil {
+		return fmt.Errorf("failed to create feedback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ai.aiToken))
+	req.Header.Set("User-Agent", "Fraiday-AI-Client/1.0")
+
+	start := time.Now()
+	defer func() { telemetry.ObserveAIRequest("feedback", time.Since(start), err) }()
+	resp, err := ai.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send feedback request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("AI service returned status %d for feedback", resp.StatusCode)
+	}
+	return nil
+}
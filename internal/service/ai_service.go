@@ -5,21 +5,25 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"time"
 
 	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 )
 
 // AIService handles AI processing requests
 type AIService struct {
-	logger        *zap.Logger
-	httpClient    *http.Client
+	logger     *zap.Logger
+	httpClient *http.Client
+	// slackAIClient calls Slack AI workflows, whose URLs clients choose
+	slackAIClient *http.Client
 	aiURL         string
 	aiToken       string
-	slackAIURL    string
-	slackAIToken  string
-	slackWorkflowID string
 }
 
 // NewAIService creates a new AI service
@@ -27,26 +31,18 @@ func NewAIService(logger *zap.Logger, aiURL, aiToken string) *AIService {
 	return &AIService{
 		logger: logger,
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
-		aiURL:   aiURL,
-		aiToken: aiToken,
+		slackAIClient: newEgressClient(nil, 60*time.Second),
+		aiURL:         aiURL,
+		aiToken:       aiToken,
 	}
 }
 
-// NewAIServiceWithSlack creates a new AI service with Slack configuration
-func NewAIServiceWithSlack(logger *zap.Logger, aiURL, aiToken, slackAIURL, slackAIToken, slackWorkflowID string) *AIService {
-	return &AIService{
-		logger: logger,
-		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
-		},
-		aiURL:           aiURL,
-		aiToken:         aiToken,
-		slackAIURL:      slackAIURL,
-		slackAIToken:    slackAIToken,
-		slackWorkflowID: slackWorkflowID,
-	}
+// SetEgressPolicy limits the hosts Slack AI workflows can reach to those policy allows.
+func (ai *AIService) SetEgressPolicy(policy *egress.Policy) {
+	ai.slackAIClient = newEgressClient(policy, 60*time.Second)
 }
 
 // AIRequest represents the request structure for AI processing
@@ -63,31 +59,8 @@ type AIRequest struct {
 	Context           map[string]interface{} `json:"context,omitempty"`
 	Suggestion        bool                   `json:"suggestion,omitempty"`
 	Attachments       []map[string]interface{} `json:"attachments,omitempty"`
-}
-
-// AICarouselItem represents an item in a carousel
-type AICarouselItem struct {
-	Title              string                   `json:"title"`
-	Description        string                   `json:"description"`
-	MediaURL           string                   `json:"media_url,omitempty"`
-	MediaType          string                   `json:"media_type,omitempty"`
-	DefaultActionURL   string                   `json:"default_action_url,omitempty"`
-	Buttons            []map[string]interface{} `json:"buttons,omitempty"`
-}
-
-// AICarousel represents carousel data structure
-type AICarousel struct {
-	Items []AICarouselItem `json:"items,omitempty"`
-}
-
-// AIAttachment represents an attachment in AI response
-type AIAttachment struct {
-	Type     string                 `json:"type"`
-	FileName string                 `json:"file_name,omitempty"`
-	FileURL  string                 `json:"file_url,omitempty"`
-	FileType string                 `json:"file_type,omitempty"`
-	Carousel AICarousel             `json:"carousel,omitempty"`
-	Buttons  []map[string]interface{} `json:"buttons,omitempty"`
+	// ModelConfig, when set, is the client's choice of model and prompt
+	ModelConfig       *models.ClientAIConfig `json:"model_config,omitempty"`
 }
 
 // AIAnswer represents the answer data in AI response
@@ -95,7 +68,9 @@ type AIAnswer struct {
 	AnswerText string                 `json:"answer_text"`
 	AnswerData interface{}            `json:"answer_data"`
 	AnswerURL  string                 `json:"answer_url"`
-	Attachments []AIAttachment        `json:"attachments,omitempty"`
+	Attachments []models.Attachment `json:"attachments,omitempty"`
+	// Sources are the knowledge base documents the answer was drawn from
+	Sources []models.Citation `json:"sources,omitempty"`
 }
 
 // AIData represents the data section in AI response
@@ -115,7 +90,8 @@ type AIResponseResult struct {
 	ConfidenceScore float64                  `json:"confidence_score"`
 	Data            map[string]interface{}   `json:"data,omitempty"`
 	Metadata        map[string]interface{}   `json:"metadata,omitempty"`
-	Attachments     []AIAttachment           `json:"attachments,omitempty"`
+	Attachments     []models.Attachment      `json:"attachments,omitempty"`
+	Sources         []models.Citation        `json:"sources,omitempty"`
 }
 
 // AIResponse represents the response structure from AI processing
@@ -132,10 +108,30 @@ type AIResponse struct {
 	SessionID   string                 `json:"session_id,omitempty"`
 	Response    string                 `json:"response,omitempty"`
 	Suggestions []string               `json:"suggestions,omitempty"`
+	// Token counts, when the AI service reports them
+	Usage       *AIUsage               `json:"usage,omitempty"`
+}
+
+// AIUsage reports the model tokens an AI response consumed
+type AIUsage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+}
+
+// aiOperation names the kind of request for metrics: the task named in its context, or
+// whether it asks for suggestions or a chat reply.
+func aiOperation(request AIRequest) string {
+	if task, ok := request.Context["task"].(string); ok && task != "" {
+		return task
+	}
+	if request.Suggestion {
+		return "suggestions"
+	}
+	return "chat"
 }
 
 // ProcessAIRequest sends a request to the AI service and returns the response
-func (ai *AIService) ProcessAIRequest(ctx context.Context, request AIRequest) (*AIResponse, error) {
+func (ai *AIService) ProcessAIRequest(ctx context.Context, request AIRequest) (response *AIResponse, err error) {
 	ai.logger.Info("Processing AI request",
 		zap.String("message_id", request.MessageID),
 		zap.String("session_id", request.SessionID),
@@ -161,6 +157,8 @@ func (ai *AIService) ProcessAIRequest(ctx context.Context, request AIRequest) (*
 	req.Header.Set("User-Agent", "Fraiday-AI-Client/1.0")
 
 	// Send request
+	start := time.Now()
+	defer func() { telemetry.ObserveAIRequest(aiOperation(request), time.Since(start), err) }()
 	resp, err := ai.httpClient.Do(req)
 	if err != nil {
 		ai.logger.Error("Failed to send AI request", zap.Error(err))
@@ -189,8 +187,9 @@ func (ai *AIService) ProcessAIRequest(ctx context.Context, request AIRequest) (*
 	return &aiResponse, nil
 }
 
-// GenerateChatResponse generates a chat response using AI
-func (ai *AIService) GenerateChatResponse(ctx context.Context, messageID, sessionID, message string, context map[string]interface{}) (*AIResponse, error) {
+// GenerateChatResponse generates a chat response using AI, with the client's model config when
+// it has one
+func (ai *AIService) GenerateChatResponse(ctx context.Context, messageID, sessionID, message string, context map[string]interface{}, modelConfig *models.ClientAIConfig) (*AIResponse, error) {
 	request := AIRequest{
 		MessageID:        messageID,
 		SessionID:        sessionID,
@@ -198,6 +197,7 @@ func (ai *AIService) GenerateChatResponse(ctx context.Context, messageID, sessio
 		CurrentMessageID: messageID,
 		Context:          context,
 		Suggestion:       false,
+		ModelConfig:      modelConfig,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
@@ -205,8 +205,9 @@ func (ai *AIService) GenerateChatResponse(ctx context.Context, messageID, sessio
 	return ai.ProcessAIRequest(ctx, request)
 }
 
-// GenerateSuggestions generates suggestions using AI
-func (ai *AIService) GenerateSuggestions(ctx context.Context, messageID, sessionID, message string, context map[string]interface{}) (*AIResponse, error) {
+// GenerateSuggestions generates suggestions using AI, with the client's model config when it has
+// one
+func (ai *AIService) GenerateSuggestions(ctx context.Context, messageID, sessionID, message string, context map[string]interface{}, modelConfig *models.ClientAIConfig) (*AIResponse, error) {
 	request := AIRequest{
 		MessageID:        messageID,
 		SessionID:        sessionID,
@@ -214,6 +215,7 @@ func (ai *AIService) GenerateSuggestions(ctx context.Context, messageID, session
 		CurrentMessageID: messageID,
 		Context:          context,
 		Suggestion:       true,
+		ModelConfig:      modelConfig,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
@@ -221,26 +223,115 @@ func (ai *AIService) GenerateSuggestions(ctx context.Context, messageID, session
 	return ai.ProcessAIRequest(ctx, request)
 }
 
-// HealthCheck checks if the AI service is available
-func (ai *AIService) HealthCheck(ctx context.Context) error {
+// SandboxResponse returns a canned reply for sandbox sessions without calling the AI service.
+func (ai *AIService) SandboxResponse(messageID, sessionID, message string) *AIResponse {
+	text := fmt.Sprintf("[sandbox] Received: %s", message)
+	return &AIResponse{
+		Status: "success",
+		Data: AIData{
+			Answer:          AIAnswer{AnswerText: text, AnswerData: map[string]interface{}{"sandbox": true}},
+			ConfidenceScore: 1,
+		},
+		MessageID:   messageID,
+		SessionID:   sessionID,
+		Response:    text,
+		Suggestions: []string{text},
+	}
+}
+
+// AISessionSummary is the structured recap the AI service returns for a session transcript
+type AISessionSummary struct {
+	Summary    string   `json:"summary"`
+	Topics     []string `json:"topics"`
+	Resolution string   `json:"resolution"`
+	Sentiment  string   `json:"sentiment"`
+}
+
+// SummarizeSession asks the AI service for a structured summary of a session transcript.
+// The summary is read from answer_data; answer_text is used as the summary when the service sends plain text.
+func (ai *AIService) SummarizeSession(ctx context.Context, sessionID string, transcript []interface{}) (*AISessionSummary, error) {
+	request := AIRequest{
+		SessionID:   sessionID,
+		ChatHistory: transcript,
+		Context:     map[string]interface{}{"task": "summarize_session"},
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	resp, err := ai.ProcessAIRequest(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &AISessionSummary{}
+	if data, err := json.Marshal(resp.Data.Answer.AnswerData); err == nil {
+		_ = json.Unmarshal(data, summary)
+	}
+	if summary.Summary == "" {
+		summary.Summary = resp.Data.Answer.AnswerText
+	}
+	if summary.Summary == "" && len(summary.Topics) == 0 {
+		return nil, fmt.Errorf("AI service returned an empty summary")
+	}
+	return summary, nil
+}
+
+// ClassifyIntent asks the AI service for the intent of a message, read from answer_data.intent and
+// answer_data.confidence.
+func (ai *AIService) ClassifyIntent(ctx context.Context, messageID, sessionID, message string) (string, float64, error) {
+	request := AIRequest{
+		MessageID:        messageID,
+		SessionID:        sessionID,
+		CurrentMessage:   message,
+		CurrentMessageID: messageID,
+		Context:          map[string]interface{}{"task": "classify_intent"},
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+
+	resp, err := ai.ProcessAIRequest(ctx, request)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var result struct {
+		Intent     string  `json:"intent"`
+		Confidence float64 `json:"confidence"`
+	}
+	if data, err := json.Marshal(resp.Data.Answer.AnswerData); err == nil {
+		_ = json.Unmarshal(data, &result)
+	}
+	if result.Intent == "" {
+		return "", 0, fmt.Errorf("AI service returned no intent")
+	}
+	return result.Intent, result.Confidence, nil
+}
+
+// HealthCheck checks if the AI service is available and returns the version it reports, if any
+func (ai *AIService) HealthCheck(ctx context.Context) (string, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", ai.aiURL+"/health", nil)
 	if err != nil {
-		return fmt.Errorf("failed to create health check request: %w", err)
+		return "", fmt.Errorf("failed to create health check request: %w", err)
 	}
 
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", ai.aiToken))
 
 	resp, err := ai.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send health check request: %w", err)
+		return "", fmt.Errorf("failed to send health check request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("AI service health check failed with status %d", resp.StatusCode)
+		return "", fmt.Errorf("AI service health check failed with status %d", resp.StatusCode)
 	}
 
-	return nil
+	// The body is optional; a healthy service that doesn't report a version is still healthy
+	var health struct {
+		Version string `json:"version"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&health)
+	return health.Version, nil
 }
 
 // ProcessAIRequestWithHistory processes AI request with chat history
@@ -262,20 +353,29 @@ func (ai *AIService) ProcessAIRequestWithHistory(ctx context.Context, messageID,
 	return ai.ProcessAIRequest(ctx, request)
 }
 
-// ProcessSlackAIRequest processes AI request for Slack channels
-func (ai *AIService) ProcessSlackAIRequest(ctx context.Context, clientID, userID, message, sessionID string, metadata map[string]interface{}) (*AIResponse, error) {
-	if ai.slackAIURL == "" || ai.slackAIToken == "" || ai.slackWorkflowID == "" {
+// SlackAIWorkflow is the Slack AI workflow a Slack channel's replies are generated by, with its
+// token decrypted.
+type SlackAIWorkflow struct {
+	URL        string
+	Token      string
+	WorkflowID string
+}
+
+// ProcessSlackAIRequest processes AI request for Slack channels through the channel's workflow
+func (ai *AIService) ProcessSlackAIRequest(ctx context.Context, workflow *SlackAIWorkflow, clientID, userID, message, sessionID string, metadata map[string]interface{}) (response *AIResponse, err error) {
+	if workflow == nil || workflow.URL == "" || workflow.Token == "" || workflow.WorkflowID == "" {
 		return nil, fmt.Errorf("Slack AI configuration not provided")
 	}
 
 	ai.logger.Info("Processing Slack AI request",
 		zap.String("client_id", clientID),
+		zap.String("workflow_id", workflow.WorkflowID),
 		zap.String("user_id", userID),
 		zap.String("session_id", sessionID))
 
 	// Prepare Slack AI request payload
 	payload := map[string]interface{}{
-		"id": ai.slackWorkflowID,
+		"id": workflow.WorkflowID,
 		"input_args": map[string]interface{}{
 			"client_id":  clientID,
 			"user_id":    userID,
@@ -293,7 +393,7 @@ func (ai *AIService) ProcessSlackAIRequest(ctx context.Context, clientID, userID
 	}
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, "POST", ai.slackAIURL, bytes.NewBuffer(requestBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", workflow.URL, bytes.NewBuffer(requestBytes))
 	if err != nil {
 		ai.logger.Error("Failed to create Slack AI request", zap.Error(err))
 		return nil, fmt.Errorf("failed to create request: %w", err)
@@ -301,10 +401,12 @@ func (ai *AIService) ProcessSlackAIRequest(ctx context.Context, clientID, userID
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", ai.slackAIToken))
+	req.Header.Set("Authorization", fmt.Sprintf("Basic %s", workflow.Token))
 
 	// Send request
-	resp, err := ai.httpClient.Do(req)
+	start := time.Now()
+	defer func() { telemetry.ObserveAIRequest("slack", time.Since(start), err) }()
+	resp, err := ai.slackAIClient.Do(req)
 	if err != nil {
 		ai.logger.Error("Failed to send Slack AI request", zap.Error(err))
 		return nil, fmt.Errorf("failed to send Slack AI request: %w", err)
@@ -345,7 +447,7 @@ func (ai *AIService) convertSlackResponse(slackResponse map[string]interface{})
 	}
 
 	// Parse attachments
-	var attachments []AIAttachment
+	var attachments []models.Attachment
 	if attachmentList, exists := result["attachments"].([]interface{}); exists {
 		for _, att := range attachmentList {
 			if attMap, ok := att.(map[string]interface{}); ok {
@@ -381,78 +483,15 @@ func (ai *AIService) convertSlackResponse(slackResponse map[string]interface{})
 }
 
 // parseAttachment parses attachment data from AI response
-func (ai *AIService) parseAttachment(attachment map[string]interface{}) AIAttachment {
-	attachmentType := "file" // default
-	if attType, exists := attachment["type"].(string); exists {
-		attachmentType = attType
-	}
-
-	result := AIAttachment{
-		Type: attachmentType,
-	}
-
-	if fileName, exists := attachment["file_name"].(string); exists {
-		result.FileName = fileName
-	}
-	if fileURL, exists := attachment["file_url"].(string); exists {
-		result.FileURL = fileURL
-	}
-	if fileType, exists := attachment["file_type"].(string); exists {
-		result.FileType = fileType
-	}
-
-	// Add carousel data if present
-	if attachmentType == "carousel" {
-		if carouselMap, exists := attachment["carousel"].(map[string]interface{}); exists {
-			if items, itemsExist := carouselMap["items"].([]interface{}); itemsExist {
-				carouselItems := make([]AICarouselItem, len(items))
-				for i, item := range items {
-					if itemMap, ok := item.(map[string]interface{}); ok {
-						carouselItem := AICarouselItem{}
-						if title, titleOk := itemMap["title"].(string); titleOk {
-							carouselItem.Title = title
-						}
-						if desc, descOk := itemMap["description"].(string); descOk {
-							carouselItem.Description = desc
-						}
-						if mediaURL, mediaOk := itemMap["media_url"].(string); mediaOk {
-							carouselItem.MediaURL = mediaURL
-						}
-						if mediaType, mediaTypeOk := itemMap["media_type"].(string); mediaTypeOk {
-							carouselItem.MediaType = mediaType
-						}
-						if defaultURL, defaultOk := itemMap["default_action_url"].(string); defaultOk {
-							carouselItem.DefaultActionURL = defaultURL
-						}
-						if buttons, buttonsOk := itemMap["buttons"].([]interface{}); buttonsOk {
-							buttonsData := make([]map[string]interface{}, len(buttons))
-							for j, button := range buttons {
-								if buttonMap, buttonOk := button.(map[string]interface{}); buttonOk {
-									buttonsData[j] = buttonMap
-								}
-							}
-							carouselItem.Buttons = buttonsData
-						}
-						carouselItems[i] = carouselItem
-					}
-				}
-				result.Carousel = AICarousel{Items: carouselItems}
-			}
+func (ai *AIService) parseAttachment(attachment map[string]interface{}) models.Attachment {
+	var result models.Attachment
+	if data, err := json.Marshal(attachment); err == nil {
+		if err := json.Unmarshal(data, &result); err != nil {
+			ai.logger.Warn("Failed to parse AI attachment", zap.Error(err))
 		}
 	}
-
-	// Add buttons data if present
-	if attachmentType == "buttons" {
-		if buttons, exists := attachment["buttons"].([]interface{}); exists {
-			buttonsData := make([]map[string]interface{}, len(buttons))
-			for i, button := range buttons {
-				if buttonMap, ok := button.(map[string]interface{}); ok {
-					buttonsData[i] = buttonMap
-				}
-			}
-			result.Buttons = buttonsData
-		}
+	if result.Type == "" {
+		result.Type = models.AttachmentTypeFile
 	}
-
 	return result
 }
\ No newline at end of file
@@ -0,0 +1,187 @@
+// Package service provides business logic for batching deliveries to AMQP processors.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+)
+
+// amqpConfirmTimeout is how long a batch waits for the broker to confirm its messages
+const amqpConfirmTimeout = 30 * time.Second
+
+// amqpMessage is an event to publish to an AMQP processor
+type amqpMessage struct {
+	exchange   string
+	routingKey string
+	queue      string
+	publishing amqp.Publishing
+}
+
+// AMQPBatcher groups the events of each batched AMQP processor and publishes every batch on one
+// channel in confirm mode. Each caller blocks until the broker has confirmed its message, so the
+// delivery task is only acked once its event is safely with the broker; a batch that isn't
+// confirmed fails all of its callers and their tasks are retried, which may deliver an event
+// twice.
+type AMQPBatcher struct {
+	conn   *amqp.Connection
+	logger *zap.Logger
+
+	mu      sync.Mutex
+	pending map[primitive.ObjectID]*amqpBatch
+
+	// channelMu serializes publishing on channel; queues records the queues declared on it
+	channelMu sync.Mutex
+	channel   *amqp.Channel
+	queues    map[string]bool
+}
+
+type amqpBatch struct {
+	ctx      context.Context
+	messages []amqpMessage
+	results  []chan error
+	timer    *time.Timer
+}
+
+// NewAMQPBatcher creates an AMQPBatcher publishing on conn.
+func NewAMQPBatcher(conn *amqp.Connection, logger *zap.Logger) *AMQPBatcher {
+	return &AMQPBatcher{
+		conn:    conn,
+		logger:  logger,
+		pending: make(map[primitive.ObjectID]*amqpBatch),
+	}
+}
+
+// Publish adds message to the pending batch of processorID and waits until the broker confirms
+// it. The batch is published once it holds batch.MaxSize messages or batch.FlushInterval after
+// its first message.
+func (b *AMQPBatcher) Publish(ctx context.Context, processorID primitive.ObjectID, batch *models.AmqpBatchConfig, message amqpMessage) error {
+	result := make(chan error, 1)
+
+	b.mu.Lock()
+	pending, ok := b.pending[processorID]
+	if !ok {
+		// The batch outlives the task that opened it, so it keeps that task's trace but not its deadline
+		pending = &amqpBatch{ctx: context.WithoutCancel(ctx)}
+		b.pending[processorID] = pending
+		pending.timer = time.AfterFunc(batch.FlushInterval, func() { b.flush(processorID, pending) })
+	}
+	pending.messages = append(pending.messages, message)
+	pending.results = append(pending.results, result)
+	full := len(pending.messages) >= batch.MaxSize
+	if full {
+		delete(b.pending, processorID)
+	}
+	b.mu.Unlock()
+
+	if full {
+		pending.timer.Stop()
+		go b.send(processorID, pending)
+	}
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// flush publishes batch when its flush interval ends, unless it filled up and was sent already.
+func (b *AMQPBatcher) flush(processorID primitive.ObjectID, batch *amqpBatch) {
+	b.mu.Lock()
+	if b.pending[processorID] != batch {
+		b.mu.Unlock()
+		return
+	}
+	delete(b.pending, processorID)
+	b.mu.Unlock()
+	b.send(processorID, batch)
+}
+
+// send publishes batch and hands each caller the broker's answer for its message.
+func (b *AMQPBatcher) send(processorID primitive.ObjectID, batch *amqpBatch) {
+	confirms, err := b.publish(batch)
+	if err != nil {
+		b.logger.Warn("Failed to publish AMQP batch",
+			zap.String("processor_id", processorID.Hex()),
+			zap.Int("size", len(batch.messages)),
+			zap.Error(err))
+		telemetry.ObserveAMQPBatch(len(batch.messages), false)
+		for _, result := range batch.results {
+			result <- err
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(batch.ctx, amqpConfirmTimeout)
+	defer cancel()
+	confirmed := true
+	for i, confirm := range confirms {
+		acked, err := confirm.WaitContext(ctx)
+		switch {
+		case err != nil:
+			err = fmt.Errorf("waiting for publisher confirm: %w", err)
+		case !acked:
+			err = errors.New("message was not confirmed by the broker")
+		}
+		confirmed = confirmed && err == nil
+		batch.results[i] <- err
+	}
+	telemetry.ObserveAMQPBatch(len(batch.messages), confirmed)
+}
+
+// publish publishes the messages of batch and returns their pending confirmations.
+func (b *AMQPBatcher) publish(batch *amqpBatch) ([]*amqp.DeferredConfirmation, error) {
+	b.channelMu.Lock()
+	defer b.channelMu.Unlock()
+
+	channel, err := b.confirmChannel()
+	if err != nil {
+		return nil, err
+	}
+	confirms := make([]*amqp.DeferredConfirmation, 0, len(batch.messages))
+	for _, message := range batch.messages {
+		if message.queue != "" && !b.queues[message.queue] {
+			if _, err := channel.QueueDeclare(message.queue, true, false, false, false, nil); err != nil {
+				// A failed declare closes the channel; the next batch opens another
+				return nil, fmt.Errorf("failed to declare queue: %w", err)
+			}
+			b.queues[message.queue] = true
+		}
+		confirm, err := channel.PublishWithDeferredConfirmWithContext(batch.ctx, message.exchange, message.routingKey, false, false, message.publishing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to publish message: %w", err)
+		}
+		confirms = append(confirms, confirm)
+	}
+	return confirms, nil
+}
+
+// confirmChannel returns the channel batches are published on, opening it in confirm mode if it
+// isn't open. The caller holds channelMu.
+func (b *AMQPBatcher) confirmChannel() (*amqp.Channel, error) {
+	if b.channel != nil && !b.channel.IsClosed() {
+		return b.channel, nil
+	}
+	channel, err := b.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AMQP channel: %w", err)
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	b.channel = channel
+	b.queues = make(map[string]bool)
+	return channel, nil
+}
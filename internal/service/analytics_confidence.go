@@ -0,0 +1,185 @@
+// Package service provides business logic for AI confidence analytics.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+const (
+	// DefaultConfidenceThreshold is the confidence at or below which an AI answer is low confidence
+	DefaultConfidenceThreshold = 0.5
+	// minIntentAnswers is how many answers an intent needs to be ranked among the weakest, so a
+	// couple of unlucky answers don't top the list
+	minIntentAnswers = 5
+	// weakIntentsLimit is how many of the weakest intents are reported
+	weakIntentsLimit = 10
+	// confidenceBuckets is how many equal-width buckets the 0 to 1 confidence range is split into
+	confidenceBuckets = 10
+)
+
+// confidenceRow holds the counts aggregated for a group of AI answers
+type confidenceRow struct {
+	Answers       int64   `bson:"answers"`
+	LowConfidence int64   `bson:"low_confidence"`
+	Average       float64 `bson:"average"`
+}
+
+func (row confidenceRow) stats() dto.ConfidenceStats {
+	st := dto.ConfidenceStats{
+		Answers:           row.Answers,
+		LowConfidence:     row.LowConfidence,
+		AverageConfidence: row.Average,
+	}
+	if row.Answers > 0 {
+		st.LowConfidenceRate = float64(row.LowConfidence) / float64(row.Answers)
+	}
+	return st
+}
+
+// GetConfidenceMetrics aggregates the confidence of the AI answers sent in range: their
+// distribution, the share at or below threshold overall and per day or ISO week, and the intents
+// of the messages answered with the highest share of low-confidence answers. An AI answer stored
+// without a score was answered with confidence 0.
+func (s *AnalyticsService) GetConfidenceMetrics(ctx context.Context, f AnalyticsFilter, threshold float64, aggregation string) (*dto.ConfidenceMetrics, error) {
+	if threshold < 0 || threshold > 1 {
+		return nil, errors.New("threshold must be between 0 and 1")
+	}
+	aggregation, bucket, err := trendBucket(f, aggregation, "$created_at")
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.sessionMatch(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := func(id interface{}) bson.M {
+		return bson.M{"$group": bson.M{
+			"_id":            id,
+			"answers":        bson.M{"$sum": 1},
+			"low_confidence": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$lte": bson.A{"$confidence", threshold}}, 1, 0}}},
+			"average":        bson.M{"$avg": "$confidence"},
+		}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"sender_type":        string(models.SenderTypeAssistant),
+			"config.ai_response": true,
+			"created_at":         bson.M{"$gte": f.Start, "$lt": f.End},
+		}}},
+		{{Key: "$lookup", Value: bson.M{"from": "chat_sessions", "localField": "session", "foreignField": "_id", "as": "chat_session"}}},
+		{{Key: "$unwind", Value: "$chat_session"}},
+		{{Key: "$match", Value: prefixFields(sessions, "chat_session.")}},
+		{{Key: "$project", Value: bson.M{
+			"confidence": bson.M{"$ifNull": bson.A{"$confidence_score", 0}},
+			"bucket":     bucket,
+			"original":   "$config.original_message_id",
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"total": bson.A{stats(nil)},
+			"trend": bson.A{stats("$bucket")},
+			// Scores of 1 fall in the last bucket
+			"distribution": bson.A{
+				bson.M{"$group": bson.M{
+					"_id": bson.M{"$max": bson.A{0, bson.M{"$min": bson.A{
+						confidenceBuckets - 1, bson.M{"$floor": bson.M{"$multiply": bson.A{"$confidence", confidenceBuckets}}},
+					}}}},
+					"answers": bson.M{"$sum": 1},
+				}},
+			},
+			"intents": bson.A{
+				bson.M{"$lookup": bson.M{
+					"from": models.ChatMessage{}.TableName(),
+					"let": bson.M{"original": bson.M{"$convert": bson.M{
+						"input": "$original", "to": "objectId", "onError": nil, "onNull": nil,
+					}}},
+					"pipeline": bson.A{
+						bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$original"}}}},
+						bson.M{"$project": bson.M{"intent": 1}},
+					},
+					"as": "original",
+				}},
+				bson.M{"$unwind": "$original"},
+				bson.M{"$match": bson.M{"original.intent": bson.M{"$nin": bson.A{nil, ""}}}},
+				stats("$original.intent"),
+				bson.M{"$match": bson.M{"answers": bson.M{"$gte": minIntentAnswers}, "low_confidence": bson.M{"$gt": 0}}},
+				bson.M{"$addFields": bson.M{"rate": bson.M{"$divide": bson.A{"$low_confidence", "$answers"}}}},
+				bson.M{"$sort": bson.D{{Key: "rate", Value: -1}, {Key: "answers", Value: -1}, {Key: "_id", Value: 1}}},
+				bson.M{"$limit": weakIntentsLimit},
+			},
+		}}},
+	}
+
+	cursor, err := s.DB.Collection(models.ChatMessage{}.TableName()).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate confidence: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var facets []struct {
+		Total []confidenceRow `bson:"total"`
+		Trend []struct {
+			Bucket        string `bson:"_id"`
+			confidenceRow `bson:",inline"`
+		} `bson:"trend"`
+		Distribution []struct {
+			Bucket  int   `bson:"_id"`
+			Answers int64 `bson:"answers"`
+		} `bson:"distribution"`
+		Intents []struct {
+			Intent        string `bson:"_id"`
+			confidenceRow `bson:",inline"`
+		} `bson:"intents"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, fmt.Errorf("failed to decode confidence: %w", err)
+	}
+
+	metrics := &dto.ConfidenceMetrics{
+		StartTime:    f.Start,
+		EndTime:      f.End,
+		Threshold:    threshold,
+		Aggregation:  aggregation,
+		Distribution: make([]dto.ConfidenceBucket, confidenceBuckets),
+		Trend:        []dto.ConfidencePoint{},
+		WeakIntents:  []dto.IntentConfidence{},
+	}
+	for i := range metrics.Distribution {
+		metrics.Distribution[i].Min = float64(i) / confidenceBuckets
+		metrics.Distribution[i].Max = float64(i+1) / confidenceBuckets
+	}
+	var byBucket map[string]dto.ConfidenceStats
+	if len(facets) > 0 {
+		if len(facets[0].Total) > 0 {
+			metrics.ConfidenceStats = facets[0].Total[0].stats()
+		}
+		for _, b := range facets[0].Distribution {
+			if b.Bucket >= 0 && b.Bucket < confidenceBuckets {
+				metrics.Distribution[b.Bucket].Answers = b.Answers
+			}
+		}
+		byBucket = make(map[string]dto.ConfidenceStats, len(facets[0].Trend))
+		for _, point := range facets[0].Trend {
+			byBucket[point.Bucket] = point.stats()
+		}
+		for _, intent := range facets[0].Intents {
+			metrics.WeakIntents = append(metrics.WeakIntents, dto.IntentConfidence{Intent: intent.Intent, ConfidenceStats: intent.stats()})
+		}
+	}
+	for _, t := range trendTimes(f, aggregation) {
+		metrics.Trend = append(metrics.Trend, dto.ConfidencePoint{
+			Time:            t,
+			TimeLabel:       trendLabel(t, aggregation),
+			ConfidenceStats: byBucket[t.Format("2006-01-02")],
+		})
+	}
+	return metrics, nil
+}
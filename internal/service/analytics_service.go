@@ -2,15 +2,49 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
 	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
 )
 
-type AnalyticsService struct{}
+type AnalyticsService struct {
+	// DB is where metrics are aggregated; the analytics database, so reads can go to secondaries
+	DB         *mongo.Database
+	ClientRepo *repository.ClientRepository
+	Clients    ClientCache
+	// CacheTTL is how long volume heatmaps are cached; zero disables caching
+	CacheTTL time.Duration
+
+	mu          sync.Mutex
+	volumeCache map[string]cachedVolume
+}
 
-func NewAnalyticsService() *AnalyticsService {
-	return &AnalyticsService{}
+func NewAnalyticsService(db *mongo.Database, clientRepo *repository.ClientRepository) *AnalyticsService {
+	return &AnalyticsService{
+		DB:          db,
+		ClientRepo:  clientRepo,
+		CacheTTL:    DefaultAnalyticsCacheTTL,
+		volumeCache: make(map[string]cachedVolume),
+	}
+}
+
+// AnalyticsFilter selects what analytics are computed over: a time range, and optionally one
+// client (by client_id) and one of its channels. Sandbox sessions are always excluded.
+type AnalyticsFilter struct {
+	ClientID  string
+	ChannelID *primitive.ObjectID
+	Start     time.Time
+	End       time.Time
 }
 
 func (s *AnalyticsService) GetDashboardMetrics(startTime, endTime time.Time) *dto.DashboardMetricsResponse {
@@ -63,30 +97,495 @@ func (s *AnalyticsService) GetBotEngagementMetrics(startTime, endTime time.Time)
 	}
 }
 
-func (s *AnalyticsService) GetContainmentRateMetrics(startTime, endTime time.Time, aggregation string) *dto.ContainmentRateResponse {
-	// Stubbed data
-	data := []interface{}{
-		map[string]interface{}{
-			"time":       "2025-07-25T00:00:00Z",
-			"time_label": "00:00",
-			"value":      85.0,
-			"unit":       "percent",
-		},
-		map[string]interface{}{
-			"time":       "2025-07-25T01:00:00Z",
-			"time_label": "01:00",
-			"value":      90.0,
-			"unit":       "percent",
-		},
+// Trend aggregations
+const (
+	AggregationAuto   = "auto"
+	AggregationDaily  = "daily"
+	AggregationWeekly = "weekly"
+)
+
+// maxDailyTrendDays is the longest range the auto aggregation reports in daily buckets
+const maxDailyTrendDays = 31
+
+// GetContainmentRateMetrics computes how many conversations the bot kept from agents, from the
+// chat_workflow_completed, chat_workflow_handover and handover_requested events in range. A
+// session counts once, in the bucket of its first such event: bot-handled when the bot answered in
+// it, handed over when either handover event was published for it, and contained when the bot
+// answered and it was not handed over. aggregation buckets the trend by day or ISO week.
+func (s *AnalyticsService) GetContainmentRateMetrics(ctx context.Context, f AnalyticsFilter, aggregation string) (*dto.ContainmentRateResponse, error) {
+	aggregation, bucket, err := trendBucket(f, aggregation, "$first_at")
+	if err != nil {
+		return nil, err
 	}
-	metadata := map[string]interface{}{
-		"aggregation": aggregation,
-		"start_time":  startTime,
-		"end_time":    endTime,
+	sessions, err := s.sessionMatch(ctx, f)
+	if err != nil {
+		return nil, err
 	}
-	return &dto.ContainmentRateResponse{
+
+	completed := string(models.EventTypeChatWorkflowCompleted)
+	countIf := func(cond interface{}) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+	}
+	counts := func(id interface{}) bson.D {
+		return bson.D{{Key: "$group", Value: bson.M{
+			"_id":         id,
+			"sessions":    bson.M{"$sum": 1},
+			"bot_handled": countIf("$bot"),
+			"contained":   countIf(bson.M{"$and": bson.A{"$bot", bson.M{"$not": bson.A{"$handed_over"}}}}),
+			"handed_over": countIf("$handed_over"),
+		}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"event_type": bson.M{"$in": bson.A{
+				completed, string(models.EventTypeChatWorkflowHandover), string(models.EventTypeHandoverRequested),
+			}},
+			"created_at":      bson.M{"$gte": f.Start, "$lt": f.End},
+			"data.session_id": bson.M{"$type": "string"},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         "$data.session_id",
+			"first_at":    bson.M{"$min": "$created_at"},
+			"bot":         bson.M{"$max": bson.M{"$eq": bson.A{"$event_type", completed}}},
+			"handed_over": bson.M{"$max": bson.M{"$ne": bson.A{"$event_type", completed}}},
+		}}},
+		{{Key: "$lookup", Value: bson.M{"from": "chat_sessions", "localField": "_id", "foreignField": "session_id", "as": "session"}}},
+		{{Key: "$unwind", Value: "$session"}},
+		{{Key: "$match", Value: prefixFields(sessions, "session.")}},
+		{{Key: "$project", Value: bson.M{
+			"bucket":      bucket,
+			"channel":     "$session.client_channel",
+			"bot":         1,
+			"handed_over": 1,
+		}}},
+		{{Key: "$facet", Value: bson.M{
+			"trend": bson.A{counts("$bucket"), bson.M{"$sort": bson.M{"_id": 1}}},
+			"channels": bson.A{
+				counts("$channel"),
+				bson.M{"$lookup": bson.M{"from": models.ClientChannel{}.TableName(), "localField": "_id", "foreignField": "_id", "as": "channel"}},
+				bson.M{"$addFields": bson.M{"channel_type": bson.M{"$first": "$channel.channel_type"}}},
+				bson.M{"$sort": bson.M{"sessions": -1}},
+			},
+			"total": bson.A{counts(nil)},
+		}}},
+	}
+
+	cursor, err := s.DB.Collection(models.Event{}.TableName()).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate containment: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	type containmentRow struct {
+		Sessions   int64 `bson:"sessions"`
+		BotHandled int64 `bson:"bot_handled"`
+		Contained  int64 `bson:"contained"`
+		HandedOver int64 `bson:"handed_over"`
+	}
+	var facets []struct {
+		Trend []struct {
+			Bucket         string `bson:"_id"`
+			containmentRow `bson:",inline"`
+		} `bson:"trend"`
+		Channels []struct {
+			Channel        *primitive.ObjectID `bson:"_id"`
+			ChannelType    string              `bson:"channel_type"`
+			containmentRow `bson:",inline"`
+		} `bson:"channels"`
+		Total []containmentRow `bson:"total"`
+	}
+	if err := cursor.All(ctx, &facets); err != nil {
+		return nil, fmt.Errorf("failed to decode containment: %w", err)
+	}
+	stats := func(row containmentRow) dto.ContainmentStats {
+		st := dto.ContainmentStats{
+			Sessions:   row.Sessions,
+			BotHandled: row.BotHandled,
+			Contained:  row.Contained,
+			HandedOver: row.HandedOver,
+		}
+		if row.BotHandled > 0 {
+			st.ContainmentRate = float64(row.Contained) / float64(row.BotHandled)
+		}
+		if row.Sessions > 0 {
+			st.DeflectionRate = float64(row.Contained) / float64(row.Sessions)
+		}
+		return st
+	}
+
+	resp := &dto.ContainmentRateResponse{
 		Success:  true,
-		Data:     data,
-		Metadata: metadata,
+		Data:     []dto.ContainmentPoint{},
+		Summary:  &dto.ContainmentStats{},
+		Channels: []dto.ChannelContainment{},
+		Metadata: map[string]interface{}{
+			"aggregation": aggregation,
+			"start_time":  f.Start,
+			"end_time":    f.End,
+		},
+	}
+	if len(facets) == 0 {
+		return resp, nil
+	}
+	if len(facets[0].Total) > 0 {
+		*resp.Summary = stats(facets[0].Total[0])
+	}
+	for _, channel := range facets[0].Channels {
+		entry := dto.ChannelContainment{ChannelType: channel.ChannelType, ContainmentStats: stats(channel.containmentRow)}
+		if channel.Channel != nil {
+			entry.ChannelID = channel.Channel.Hex()
+		}
+		resp.Channels = append(resp.Channels, entry)
+	}
+
+	// Every bucket of the range is listed, with zeros where no session started
+	byBucket := make(map[string]dto.ContainmentStats, len(facets[0].Trend))
+	for _, point := range facets[0].Trend {
+		byBucket[point.Bucket] = stats(point.containmentRow)
+	}
+	for _, t := range trendTimes(f, aggregation) {
+		st := byBucket[t.Format("2006-01-02")]
+		resp.Data = append(resp.Data, dto.ContainmentPoint{
+			Time:             t,
+			TimeLabel:        trendLabel(t, aggregation),
+			Value:            st.ContainmentRate * 100,
+			Unit:             "percent",
+			ContainmentStats: st,
+		})
+	}
+	return resp, nil
+}
+
+// GetPerformanceMetrics aggregates first response time, bot response latency, handle time and
+// resolution over the sessions matching f.
+func (s *AnalyticsService) GetPerformanceMetrics(ctx context.Context, f AnalyticsFilter) (*dto.PerformanceMetrics, error) {
+	sessions, err := s.sessionMatch(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	inRange := bson.M{"$gte": f.Start, "$lt": f.End}
+
+	metrics := &dto.PerformanceMetrics{StartTime: f.Start, EndTime: f.End}
+	if metrics.FirstResponseTime, err = s.firstResponseTime(ctx, sessions, inRange); err != nil {
+		return nil, err
+	}
+	if metrics.BotResponseLatency, err = s.botResponseLatency(ctx, sessions, inRange); err != nil {
+		return nil, err
+	}
+	if metrics.AverageHandleTime, err = s.handleTime(ctx, sessions, inRange); err != nil {
+		return nil, err
+	}
+	if metrics.Resolution, err = s.resolution(ctx, sessions, inRange); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+// firstResponseTime measures, per session created in range, the time from its first user message
+// to the first message after it from anyone but the user or the system.
+func (s *AnalyticsService) firstResponseTime(ctx context.Context, sessions, inRange bson.M) (dto.DurationStats, error) {
+	match := withField(sessions, "created_at", inRange)
+	messages := models.ChatMessage{}.TableName()
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": messages,
+			"let":  bson.M{"session": "$_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$session", "$$session"}}, "sender_type": string(models.SenderTypeUser)}},
+				bson.M{"$sort": bson.M{"created_at": 1}},
+				bson.M{"$limit": 1},
+				bson.M{"$project": bson.M{"created_at": 1}},
+			},
+			"as": "asked",
+		}}},
+		{{Key: "$unwind", Value: "$asked"}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": messages,
+			"let":  bson.M{"session": "$_id", "asked_at": "$asked.created_at"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{
+					"$expr": bson.M{"$and": bson.A{
+						bson.M{"$eq": bson.A{"$session", "$$session"}},
+						bson.M{"$gte": bson.A{"$created_at", "$$asked_at"}},
+					}},
+					"sender_type": bson.M{"$nin": bson.A{string(models.SenderTypeUser), string(models.SenderTypeSystem)}},
+				}},
+				bson.M{"$sort": bson.M{"created_at": 1}},
+				bson.M{"$limit": 1},
+				bson.M{"$project": bson.M{"created_at": 1}},
+			},
+			"as": "answered",
+		}}},
+		{{Key: "$unwind", Value: "$answered"}},
+		{{Key: "$project", Value: bson.M{"duration": bson.M{"$subtract": bson.A{"$answered.created_at", "$asked.created_at"}}}}},
+	}
+	return s.durationStats(ctx, "chat_sessions", pipeline)
+}
+
+// botResponseLatency measures the time from a message to the AI response to it, for AI responses
+// sent in range.
+func (s *AnalyticsService) botResponseLatency(ctx context.Context, sessions, inRange bson.M) (dto.DurationStats, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"sender_type":                string(models.SenderTypeAssistant),
+			"created_at":                 inRange,
+			"config.original_message_id": bson.M{"$type": "string"},
+		}}},
+		{{Key: "$lookup", Value: bson.M{"from": "chat_sessions", "localField": "session", "foreignField": "_id", "as": "chat_session"}}},
+		{{Key: "$unwind", Value: "$chat_session"}},
+		{{Key: "$match", Value: prefixFields(sessions, "chat_session.")}},
+		{{Key: "$lookup", Value: bson.M{
+			"from": models.ChatMessage{}.TableName(),
+			"let": bson.M{"original": bson.M{"$convert": bson.M{
+				"input": "$config.original_message_id", "to": "objectId", "onError": nil, "onNull": nil,
+			}}},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$original"}}}},
+				bson.M{"$project": bson.M{"created_at": 1}},
+			},
+			"as": "original",
+		}}},
+		{{Key: "$unwind", Value: "$original"}},
+		{{Key: "$project", Value: bson.M{"duration": bson.M{"$subtract": bson.A{"$created_at", "$original.created_at"}}}}},
+	}
+	return s.durationStats(ctx, models.ChatMessage{}.TableName(), pipeline)
+}
+
+// handleTime measures the time agents spent on handovers completed in range, from accepting one to
+// completing it.
+func (s *AnalyticsService) handleTime(ctx context.Context, sessions, inRange bson.M) (dto.DurationStats, error) {
+	match := withField(sessions, "handover.completed_at", inRange)
+	match["handover.accepted_at"] = bson.M{"$type": "date"}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$project", Value: bson.M{"duration": bson.M{"$subtract": bson.A{"$handover.completed_at", "$handover.accepted_at"}}}}},
+	}
+	return s.durationStats(ctx, "chat_sessions", pipeline)
+}
+
+// resolution counts the sessions created in range that are closed, closed without a handover, and
+// handed over.
+func (s *AnalyticsService) resolution(ctx context.Context, sessions, inRange bson.M) (dto.ResolutionStats, error) {
+	countIf := func(cond bson.M) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+	}
+	closed := bson.M{"$eq": bson.A{"$state", models.SessionStateClosed}}
+	handedOver := bson.M{"$gt": bson.A{"$handover", nil}}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: withField(sessions, "created_at", inRange)}},
+		{{Key: "$group", Value: bson.M{
+			"_id":             nil,
+			"sessions":        bson.M{"$sum": 1},
+			"resolved":        countIf(closed),
+			"resolved_by_bot": countIf(bson.M{"$and": bson.A{closed, bson.M{"$not": bson.A{handedOver}}}}),
+			"handed_over":     countIf(handedOver),
+		}}},
+	}
+
+	cursor, err := s.DB.Collection("chat_sessions").Aggregate(ctx, pipeline)
+	if err != nil {
+		return dto.ResolutionStats{}, fmt.Errorf("failed to aggregate resolution: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Sessions      int64 `bson:"sessions"`
+		Resolved      int64 `bson:"resolved"`
+		ResolvedByBot int64 `bson:"resolved_by_bot"`
+		HandedOver    int64 `bson:"handed_over"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return dto.ResolutionStats{}, fmt.Errorf("failed to decode resolution: %w", err)
+	}
+	var stats dto.ResolutionStats
+	if len(rows) == 0 {
+		return stats, nil
+	}
+	row := rows[0]
+	stats = dto.ResolutionStats{
+		Sessions:      row.Sessions,
+		Resolved:      row.Resolved,
+		ResolvedByBot: row.ResolvedByBot,
+		HandedOver:    row.HandedOver,
+	}
+	if row.Sessions > 0 {
+		stats.ResolutionRate = float64(row.Resolved) / float64(row.Sessions)
+		stats.BotResolutionRate = float64(row.ResolvedByBot) / float64(row.Sessions)
+	}
+	return stats, nil
+}
+
+// durationStats runs pipeline, which yields one duration in milliseconds per document, on
+// collection and summarizes the durations. Negative durations, from clock skew between writers,
+// are dropped.
+func (s *AnalyticsService) durationStats(ctx context.Context, collection string, pipeline mongo.Pipeline) (dto.DurationStats, error) {
+	pipeline = append(pipeline,
+		bson.D{{Key: "$match", Value: bson.M{"duration": bson.M{"$gte": 0}}}},
+		bson.D{{Key: "$group", Value: bson.M{
+			"_id":   nil,
+			"count": bson.M{"$sum": 1},
+			"avg":   bson.M{"$avg": "$duration"},
+			"min":   bson.M{"$min": "$duration"},
+			"max":   bson.M{"$max": "$duration"},
+		}}},
+	)
+	cursor, err := s.DB.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return dto.DurationStats{}, fmt.Errorf("failed to aggregate %s: %w", collection, err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Count int64   `bson:"count"`
+		Avg   float64 `bson:"avg"`
+		Min   float64 `bson:"min"`
+		Max   float64 `bson:"max"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return dto.DurationStats{}, fmt.Errorf("failed to decode %s durations: %w", collection, err)
+	}
+	if len(rows) == 0 {
+		return dto.DurationStats{}, nil
+	}
+	return dto.DurationStats{
+		Count:          rows[0].Count,
+		AverageSeconds: rows[0].Avg / 1000,
+		MinSeconds:     rows[0].Min / 1000,
+		MaxSeconds:     rows[0].Max / 1000,
+	}, nil
+}
+
+// sessionMatch returns the chat_sessions filter for the client and channel of f.
+func (s *AnalyticsService) sessionMatch(ctx context.Context, f AnalyticsFilter) (bson.M, error) {
+	match := bson.M{"test": bson.M{"$ne": true}}
+	if f.ClientID != "" {
+		client, err := s.ClientRepo.GetByClientID(ctx, f.ClientID)
+		if err != nil {
+			return nil, errors.New("client not found")
+		}
+		match["client"] = client.ID
+	}
+	if f.ChannelID != nil {
+		match["client_channel"] = *f.ChannelID
+	}
+	return match, nil
+}
+
+// withField returns a copy of match that also requires field to match value.
+func withField(match bson.M, field string, value interface{}) bson.M {
+	out := make(bson.M, len(match)+1)
+	for k, v := range match {
+		out[k] = v
+	}
+	out[field] = value
+	return out
+}
+
+// GetCSATSummary counts the CSAT surveys of the client and channel in f triggered in range, and
+// averages the scores of their rating and nps answers.
+func (s *AnalyticsService) GetCSATSummary(ctx context.Context, f AnalyticsFilter) (*dto.CSATSummary, error) {
+	match, err := s.sessionMatch(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	// CSAT sessions have no sandbox flag of their own
+	delete(match, "test")
+	match["triggered_at"] = bson.M{"$gte": f.Start, "$lt": f.End}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$lookup", Value: bson.M{"from": models.CSATResponse{}.TableName(), "localField": "_id", "foreignField": "csat_session", "as": "responses"}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":         nil,
+			"surveys":     bson.M{"$sum": 1},
+			"completed":   bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$status", "completed"}}, 1, 0}}},
+			"score_sum":   bson.M{"$sum": bson.M{"$sum": "$responses.score"}},
+			"score_count": bson.M{"$sum": bson.M{"$size": "$responses.score"}},
+		}}},
+	}
+
+	cursor, err := s.DB.Collection(models.CSATSession{}.TableName()).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate csat: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Surveys    int64   `bson:"surveys"`
+		Completed  int64   `bson:"completed"`
+		ScoreSum   float64 `bson:"score_sum"`
+		ScoreCount int64   `bson:"score_count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode csat: %w", err)
+	}
+	summary := &dto.CSATSummary{}
+	if len(rows) > 0 {
+		row := rows[0]
+		summary.Surveys, summary.Completed, summary.ScoredAnswers = row.Surveys, row.Completed, row.ScoreCount
+		if row.Surveys > 0 {
+			summary.CompletionRate = float64(row.Completed) / float64(row.Surveys)
+		}
+		if row.ScoreCount > 0 {
+			average := row.ScoreSum / float64(row.ScoreCount)
+			summary.AverageScore = &average
+		}
+	}
+	return summary, nil
+}
+
+// trendBucket resolves aggregation for the range of f, auto being daily up to maxDailyTrendDays and
+// weekly beyond, and returns it with an expression labelling the date in field with its bucket as
+// YYYY-MM-DD: the day itself, or the Monday of its ISO week.
+func trendBucket(f AnalyticsFilter, aggregation, field string) (string, bson.M, error) {
+	if aggregation == AggregationAuto {
+		aggregation = AggregationDaily
+		if f.End.Sub(f.Start) > maxDailyTrendDays*24*time.Hour {
+			aggregation = AggregationWeekly
+		}
+	}
+	var date interface{} = field
+	switch aggregation {
+	case AggregationDaily:
+	case AggregationWeekly:
+		date = bson.M{"$subtract": bson.A{field, bson.M{"$multiply": bson.A{
+			bson.M{"$subtract": bson.A{bson.M{"$isoDayOfWeek": field}, 1}}, 24 * 60 * 60 * 1000,
+		}}}}
+	default:
+		return "", nil, fmt.Errorf("aggregation must be %s, %s or %s", AggregationAuto, AggregationDaily, AggregationWeekly)
+	}
+	return aggregation, bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": date}}, nil
+}
+
+// trendTimes returns the start of every bucket overlapping the range of f, so trends list empty
+// buckets too.
+func trendTimes(f AnalyticsFilter, aggregation string) []time.Time {
+	step, from := 1, f.Start.UTC()
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	if aggregation == AggregationWeekly {
+		step = 7
+		start = start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7))
+	}
+	var times []time.Time
+	for t := start; t.Before(f.End); t = t.AddDate(0, 0, step) {
+		times = append(times, t)
+	}
+	return times
+}
+
+// trendLabel formats the start of a trend bucket for display.
+func trendLabel(t time.Time, aggregation string) string {
+	if aggregation == AggregationWeekly {
+		return t.Format("Week of Jan 2")
+	}
+	return t.Format("Jan 2")
+}
+
+// prefixFields returns match with each field prefixed, to apply it to an embedded document.
+func prefixFields(match bson.M, prefix string) bson.M {
+	out := make(bson.M, len(match))
+	for k, v := range match {
+		out[prefix+k] = v
 	}
+	return out
 }
@@ -0,0 +1,186 @@
+// Package service provides business logic for the conversation volume heatmap.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+const (
+	// DefaultAnalyticsCacheTTL is how long a computed volume heatmap is served from memory
+	DefaultAnalyticsCacheTTL = 5 * time.Minute
+	// MaxVolumeDays bounds the range of a volume heatmap
+	MaxVolumeDays = 92
+)
+
+type cachedVolume struct {
+	heatmap   *dto.VolumeHeatmap
+	expiresAt time.Time
+}
+
+type volumeCell struct {
+	Client    primitive.ObjectID `bson:"client"`
+	DayOfWeek int                `bson:"day"`
+	Hour      int                `bson:"hour"`
+	Count     int64              `bson:"count"`
+}
+
+// GetVolumeHeatmap counts the messages and sessions created in range per client, by day of the week
+// and hour of the day in loc. The range is rounded down to the minute, and results are cached for
+// CacheTTL, so dashboards refreshing the same view don't aggregate again.
+func (s *AnalyticsService) GetVolumeHeatmap(ctx context.Context, f AnalyticsFilter, loc *time.Location) (*dto.VolumeHeatmap, error) {
+	f.Start, f.End = f.Start.UTC().Truncate(time.Minute), f.End.UTC().Truncate(time.Minute)
+	if !f.End.After(f.Start) {
+		return nil, errors.New("end_time must be after start_time")
+	}
+	if f.End.Sub(f.Start) > MaxVolumeDays*24*time.Hour {
+		return nil, fmt.Errorf("the volume heatmap covers at most %d days", MaxVolumeDays)
+	}
+
+	channel := ""
+	if f.ChannelID != nil {
+		channel = f.ChannelID.Hex()
+	}
+	key := fmt.Sprintf("%s|%s|%s|%d|%d", f.ClientID, channel, loc, f.Start.Unix(), f.End.Unix())
+	if heatmap := s.cachedVolume(key); heatmap != nil {
+		return heatmap, nil
+	}
+
+	sessions, err := s.sessionMatch(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	inRange := bson.M{"$gte": f.Start, "$lt": f.End}
+	cell := func(date string) bson.M {
+		return bson.M{
+			"day":  bson.M{"$isoDayOfWeek": bson.M{"date": date, "timezone": loc.String()}},
+			"hour": bson.M{"$hour": bson.M{"date": date, "timezone": loc.String()}},
+		}
+	}
+
+	// Messages are counted per session first, so each session is looked up once per cell
+	messageCells, err := s.volumeCells(ctx, models.ChatMessage{}.TableName(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"created_at": inRange}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"session": "$session", "cell": cell("$created_at")},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$lookup", Value: bson.M{"from": "chat_sessions", "localField": "_id.session", "foreignField": "_id", "as": "chat_session"}}},
+		{{Key: "$unwind", Value: "$chat_session"}},
+		{{Key: "$match", Value: prefixFields(sessions, "chat_session.")}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"client": "$chat_session.client", "day": "$_id.cell.day", "hour": "$_id.cell.hour"},
+			"count": bson.M{"$sum": "$count"},
+		}}},
+		{{Key: "$replaceWith", Value: bson.M{"$mergeObjects": bson.A{"$_id", bson.M{"count": "$count"}}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	sessionCells, err := s.volumeCells(ctx, "chat_sessions", mongo.Pipeline{
+		{{Key: "$match", Value: withField(sessions, "created_at", inRange)}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   bson.M{"client": "$client", "cell": cell("$created_at")},
+			"count": bson.M{"$sum": 1},
+		}}},
+		{{Key: "$replaceWith", Value: bson.M{"$mergeObjects": bson.A{"$_id.cell", bson.M{"client": "$_id.client", "count": "$count"}}}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byClient := map[primitive.ObjectID]*dto.ClientVolume{}
+	volume := func(client primitive.ObjectID) *dto.ClientVolume {
+		v, ok := byClient[client]
+		if !ok {
+			v = &dto.ClientVolume{ClientID: client.Hex()}
+			if c, err := clientByID(ctx, s.Clients, s.ClientRepo, client); err == nil {
+				v.ClientID, v.ClientName = c.ClientID, c.Name
+			}
+			byClient[client] = v
+		}
+		return v
+	}
+	// $isoDayOfWeek runs from 1 (Monday) to 7 (Sunday); rows run from Monday
+	for _, c := range messageCells {
+		v := volume(c.Client)
+		v.Messages[c.DayOfWeek-1][c.Hour] += c.Count
+		v.TotalMessages += c.Count
+	}
+	for _, c := range sessionCells {
+		v := volume(c.Client)
+		v.Sessions[c.DayOfWeek-1][c.Hour] += c.Count
+		v.TotalSessions += c.Count
+	}
+
+	heatmap := &dto.VolumeHeatmap{
+		StartTime: f.Start,
+		EndTime:   f.End,
+		Timezone:  loc.String(),
+		Clients:   make([]dto.ClientVolume, 0, len(byClient)),
+	}
+	for _, v := range byClient {
+		heatmap.Clients = append(heatmap.Clients, *v)
+	}
+	sort.Slice(heatmap.Clients, func(i, j int) bool { return heatmap.Clients[i].ClientID < heatmap.Clients[j].ClientID })
+
+	s.cacheVolume(key, heatmap)
+	return heatmap, nil
+}
+
+// volumeCells runs a heatmap pipeline whose documents are volumeCells.
+func (s *AnalyticsService) volumeCells(ctx context.Context, collection string, pipeline mongo.Pipeline) ([]volumeCell, error) {
+	cursor, err := s.DB.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate %s volume: %w", collection, err)
+	}
+	defer cursor.Close(ctx)
+
+	var cells []volumeCell
+	if err := cursor.All(ctx, &cells); err != nil {
+		return nil, fmt.Errorf("failed to decode %s volume: %w", collection, err)
+	}
+	// Sessions without a client have nothing to be attributed to
+	valid := cells[:0]
+	for _, c := range cells {
+		if !c.Client.IsZero() && c.DayOfWeek >= 1 && c.DayOfWeek <= 7 && c.Hour >= 0 && c.Hour < 24 {
+			valid = append(valid, c)
+		}
+	}
+	return valid, nil
+}
+
+func (s *AnalyticsService) cachedVolume(key string) *dto.VolumeHeatmap {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cached, ok := s.volumeCache[key]; ok && time.Now().Before(cached.expiresAt) {
+		return cached.heatmap
+	}
+	return nil
+}
+
+// cacheVolume stores a heatmap, dropping the expired ones so the cache only holds recent views.
+func (s *AnalyticsService) cacheVolume(key string, heatmap *dto.VolumeHeatmap) {
+	if s.CacheTTL <= 0 {
+		return
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, cached := range s.volumeCache {
+		if !now.Before(cached.expiresAt) {
+			delete(s.volumeCache, k)
+		}
+	}
+	s.volumeCache[key] = cachedVolume{heatmap: heatmap, expiresAt: now.Add(s.CacheTTL)}
+}
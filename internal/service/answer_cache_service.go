@@ -0,0 +1,184 @@
+// Package service provides business logic for caching AI answers to repeated questions.
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+const (
+	defaultAnswerCacheTTLSeconds = 24 * 60 * 60
+	maxAnswerCacheTTLSeconds     = 30 * 24 * 60 * 60
+	// maxCachedQuestionLength keeps long, conversation-specific messages out of the cache
+	maxCachedQuestionLength = 500
+)
+
+// AnswerCacheKey identifies the cached answer to one question of a client.
+type AnswerCacheKey struct {
+	ClientID       string
+	Key            string
+	Question       string
+	SuggestionMode bool
+	policy         *models.AnswerCachePolicy
+}
+
+// AnswerCacheService manages per-client answer cache policies and the answers cached under them.
+type AnswerCacheService struct {
+	Repo            *repository.AnswerCacheRepository
+	ClientRepo      *repository.ClientRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	Invalidator     CacheInvalidator
+	Clients         ClientCache
+}
+
+// NewAnswerCacheService creates a new AnswerCacheService.
+func NewAnswerCacheService(repo *repository.AnswerCacheRepository, clientRepo *repository.ClientRepository, chatSessionRepo *repository.ChatSessionRepository) *AnswerCacheService {
+	return &AnswerCacheService{Repo: repo, ClientRepo: clientRepo, ChatSessionRepo: chatSessionRepo}
+}
+
+// GetPolicy returns a client's answer cache policy, or nil if none is configured.
+func (s *AnswerCacheService) GetPolicy(ctx context.Context, clientID string) (*models.AnswerCachePolicy, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return client.AnswerCache, nil
+}
+
+// SetPolicy validates and stores a client's answer cache policy, replacing any existing one.
+func (s *AnswerCacheService) SetPolicy(ctx context.Context, clientID string, policy *models.AnswerCachePolicy) error {
+	if policy.TTLSeconds == 0 {
+		policy.TTLSeconds = defaultAnswerCacheTTLSeconds
+	}
+	if policy.TTLSeconds < 0 || policy.TTLSeconds > maxAnswerCacheTTLSeconds {
+		return fmt.Errorf("ttl_seconds must be between 1 and %d", maxAnswerCacheTTLSeconds)
+	}
+	if policy.MinConfidence < 0 || policy.MinConfidence > 1 {
+		return errors.New("min_confidence must be between 0 and 1")
+	}
+	policy.KnowledgeVersion = strings.TrimSpace(policy.KnowledgeVersion)
+
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"answer_cache": policy}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// DeletePolicy removes a client's answer cache policy along with its cached answers.
+func (s *AnswerCacheService) DeletePolicy(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"answer_cache": nil}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	_, err := s.Invalidate(ctx, clientID, "")
+	return err
+}
+
+func (s *AnswerCacheService) invalidate(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
+
+// Invalidate removes a client's cached answers to question, or all of them when question is
+// empty, and reports how many were removed.
+func (s *AnswerCacheService) Invalidate(ctx context.Context, clientID, question string) (int64, error) {
+	filter := bson.M{"client_id": clientID}
+	if question != "" {
+		filter["question"] = normalizeQuestion(question)
+	}
+	return s.Repo.DeleteMany(ctx, filter)
+}
+
+// Key returns the cache key of message's question, or nil when the client of its session doesn't
+// cache answers or the message isn't a plain question. The prompt template version the AI is
+// asked with is part of the key, so publishing another version starts afresh.
+func (s *AnswerCacheService) Key(ctx context.Context, message *models.ChatMessage, suggestionMode bool, prompt *models.PromptVersionRef) *AnswerCacheKey {
+	if len(message.Attachments) > 0 || len(message.Text) > maxCachedQuestionLength {
+		return nil
+	}
+	question := normalizeQuestion(message.Text)
+	if question == "" {
+		return nil
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, message.SessionID)
+	if err != nil || session.Client == nil {
+		return nil
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client)
+	if err != nil || client.AnswerCache == nil || !client.AnswerCache.Enabled {
+		return nil
+	}
+
+	mode, promptVersion := "reply", ""
+	if suggestionMode {
+		mode = "suggestions"
+	}
+	if prompt != nil {
+		promptVersion = fmt.Sprintf("%s:%d", prompt.TemplateID, prompt.Version)
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{client.AnswerCache.KnowledgeVersion, mode, promptVersion, question}, "\x00")))
+	return &AnswerCacheKey{
+		ClientID:       client.ClientID,
+		Key:            hex.EncodeToString(sum[:]),
+		Question:       question,
+		SuggestionMode: suggestionMode,
+		policy:         client.AnswerCache,
+	}
+}
+
+// Get returns the cached answer for key, or nil on a miss.
+func (s *AnswerCacheService) Get(ctx context.Context, key *AnswerCacheKey) (*AIResponse, error) {
+	entry, err := s.Repo.Hit(ctx, key.ClientID, key.Key)
+	if err != nil || entry == nil {
+		return nil, err
+	}
+	var response AIResponse
+	if err := json.Unmarshal([]byte(entry.Response), &response); err != nil {
+		return nil, fmt.Errorf("failed to decode cached answer: %w", err)
+	}
+	return &response, nil
+}
+
+// Put caches response as the answer for key, unless its confidence is below the policy's minimum.
+func (s *AnswerCacheService) Put(ctx context.Context, key *AnswerCacheKey, response *AIResponse, confidence float64) error {
+	if confidence < key.policy.MinConfidence {
+		return nil
+	}
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode AI response: %w", err)
+	}
+	return s.Repo.Put(ctx, &models.AnswerCacheEntry{
+		ClientID:         key.ClientID,
+		Key:              key.Key,
+		Question:         key.Question,
+		KnowledgeVersion: key.policy.KnowledgeVersion,
+		SuggestionMode:   key.SuggestionMode,
+		Response:         string(raw),
+		Confidence:       confidence,
+		ExpiresAt:        time.Now().UTC().Add(time.Duration(key.policy.TTLSeconds) * time.Second),
+	})
+}
+
+// normalizeQuestion reduces a question to lower-case words, so questions that differ only in
+// case, punctuation or spacing share an answer.
+func normalizeQuestion(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}), " ")
+}
@@ -0,0 +1,219 @@
+// Package service provides business logic for client API keys.
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrAPIKeyNotFound       = errors.New("API key not found")
+	ErrAPIKeyClientNotFound = errors.New("client not found")
+	ErrInvalidAPIKey        = errors.New("invalid API key")
+	ErrInvalidAPIKeyRequest = errors.New("invalid API key request")
+)
+
+const (
+	// apiKeyPrefix marks client API keys so they can be told apart from other bearer tokens
+	apiKeyPrefix = "fk_"
+	// apiKeySecretLength is the number of random characters after the prefix
+	apiKeySecretLength = 40
+	// apiKeyDisplayLength is how much of a key is kept in clear to identify it in listings
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+	// apiKeyLastUsedInterval bounds how often last_used_at is written for a busy key
+	apiKeyLastUsedInterval = time.Minute
+	// MaxAPIKeyRotationGrace is the longest a rotated key may keep working alongside its replacement
+	MaxAPIKeyRotationGrace = 7 * 24 * time.Hour
+)
+
+// APIKeyService issues, rotates, revokes and authenticates client API keys.
+type APIKeyService struct {
+	Repo       *repository.APIKeyRepository
+	ClientRepo *repository.ClientRepository
+	// RateLimits, when set, is used to check the tier of new keys
+	RateLimits *RateLimitService
+}
+
+// NewAPIKeyService creates a new APIKeyService.
+func NewAPIKeyService(repo *repository.APIKeyRepository, clientRepo *repository.ClientRepository) *APIKeyService {
+	return &APIKeyService{Repo: repo, ClientRepo: clientRepo}
+}
+
+// hashAPIKey returns the stored form of a key. Keys are long and random, so a plain SHA-256
+// is enough; no salt or slow hash is needed.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// resolveClient finds the client addressed by ref, either its ObjectID or its client_id.
+func (s *APIKeyService) resolveClient(ctx context.Context, ref string) (*models.Client, error) {
+	if id := ParseObjectID(ref); id != nil {
+		if client, err := s.ClientRepo.GetByID(ctx, *id); err == nil {
+			return client, nil
+		}
+	}
+	client, err := s.ClientRepo.GetByClientID(ctx, ref)
+	if err != nil {
+		return nil, ErrAPIKeyClientNotFound
+	}
+	return client, nil
+}
+
+// CreateAPIKey issues a new key for the client addressed by clientRef, held to the rate limit
+// tier (the default tier when empty). The returned secret is the only copy of the key; it cannot
+// be recovered later.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, clientRef, name string, scopes []models.APIKeyScope, tier string, expiresAt *time.Time) (*models.APIKey, string, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, "", fmt.Errorf("%w: name is required", ErrInvalidAPIKeyRequest)
+	}
+	if len(scopes) == 0 {
+		return nil, "", fmt.Errorf("%w: at least one scope is required", ErrInvalidAPIKeyRequest)
+	}
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, "", fmt.Errorf("%w: unknown scope %q", ErrInvalidAPIKeyRequest, scope)
+		}
+	}
+	if tier != "" && s.RateLimits != nil && !s.RateLimits.HasTier(tier) {
+		return nil, "", fmt.Errorf("%w: unknown rate limit tier %q", ErrInvalidAPIKeyRequest, tier)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expires_at must be in the future", ErrInvalidAPIKeyRequest)
+	}
+	client, err := s.resolveClient(ctx, clientRef)
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := apiKeyPrefix + generateClientSecret(apiKeySecretLength)
+	key := &models.APIKey{
+		Client:    client.ID,
+		ClientID:  client.ClientID,
+		Name:      strings.TrimSpace(name),
+		Prefix:    secret[:apiKeyDisplayLength],
+		KeyHash:   hashAPIKey(secret),
+		Scopes:    scopes,
+		Tier:      tier,
+		ExpiresAt: expiresAt,
+	}
+	if err := s.Repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// ListAPIKeys returns every key issued to a client, including revoked and expired ones.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, clientRef string) ([]models.APIKey, error) {
+	client, err := s.resolveClient(ctx, clientRef)
+	if err != nil {
+		return nil, err
+	}
+	return s.Repo.ListByClient(ctx, client.ID)
+}
+
+// getAPIKey returns one of a client's keys.
+func (s *APIKeyService) getAPIKey(ctx context.Context, clientRef, keyID string) (*models.APIKey, error) {
+	client, err := s.resolveClient(ctx, clientRef)
+	if err != nil {
+		return nil, err
+	}
+	id := ParseObjectID(keyID)
+	if id == nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	key, err := s.Repo.GetByID(ctx, client.ID, *id)
+	if err != nil {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, nil
+}
+
+// RotateAPIKey issues a replacement for a key with the same name, scopes, roles, tier and expiry.
+// The old key stops working after grace, or immediately when grace is zero, so callers can roll
+// the new key out without downtime.
+func (s *APIKeyService) RotateAPIKey(ctx context.Context, clientRef, keyID string, grace time.Duration) (*models.APIKey, string, error) {
+	if grace < 0 || grace > MaxAPIKeyRotationGrace {
+		return nil, "", fmt.Errorf("%w: grace period must be between 0 and %s", ErrInvalidAPIKeyRequest, MaxAPIKeyRotationGrace)
+	}
+	old, err := s.getAPIKey(ctx, clientRef, keyID)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now().UTC()
+	if !old.IsActive(now) || old.ReplacedBy != nil {
+		return nil, "", fmt.Errorf("%w: only active keys that have not been rotated can be rotated", ErrInvalidAPIKeyRequest)
+	}
+
+	key, secret, err := s.CreateAPIKey(ctx, old.Client.Hex(), old.Name, old.Scopes, old.Tier, old.ExpiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(old.Roles) > 0 {
+		if key, err = s.SetAPIKeyRoles(ctx, old.Client.Hex(), key.ID.Hex(), old.Roles); err != nil {
+			return nil, "", err
+		}
+	}
+	revokeAt := now.Add(grace)
+	if err := s.Repo.Update(ctx, old.ID, bson.M{"revoked_at": revokeAt, "replaced_by": key.ID}); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// SetAPIKeyRoles replaces the roles granted to a key on top of its scopes. Client keys can't
+// hold the admin role.
+func (s *APIKeyService) SetAPIKeyRoles(ctx context.Context, clientRef, keyID string, roles []models.Role) (*models.APIKey, error) {
+	if err := validateRoles(roles, false); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAPIKeyRequest, err)
+	}
+	key, err := s.getAPIKey(ctx, clientRef, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Update(ctx, key.ID, bson.M{"roles": roles}); err != nil {
+		return nil, err
+	}
+	key.Roles = roles
+	return key, nil
+}
+
+// RevokeAPIKey disables a key immediately. Revoking a key twice is not an error.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, clientRef, keyID string) error {
+	key, err := s.getAPIKey(ctx, clientRef, keyID)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	if key.RevokedAt != nil && !now.Before(*key.RevokedAt) {
+		return nil
+	}
+	return s.Repo.Update(ctx, key.ID, bson.M{"revoked_at": now})
+}
+
+// AuthenticateAPIKey returns the active key matching secret and records its use.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, secret string) (*models.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := s.Repo.GetByHash(ctx, hashAPIKey(secret))
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	now := time.Now().UTC()
+	if !key.IsActive(now) {
+		return nil, ErrInvalidAPIKey
+	}
+	// Usage tracking is best effort; a failed write must not reject the request
+	_ = s.Repo.TouchLastUsed(ctx, key.ID, now, apiKeyLastUsedInterval)
+	return key, nil
+}
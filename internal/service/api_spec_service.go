@@ -0,0 +1,301 @@
+package service
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// APIRoute is a registered route as the API specs describe it.
+type APIRoute struct {
+	Method     string
+	Path       string // In gin's syntax, /clients/:client_id
+	Handler    string // Name of the handler function, as gin reports it
+	Permission models.Permission
+	Public     bool
+}
+
+// APISpecService serves the OpenAPI document of the REST routes and the AsyncAPI document of the
+// events. Both are built from what is registered, so they can't fall behind the code.
+type APISpecService struct {
+	version  string
+	openAPI  map[string]interface{}
+	asyncAPI map[string]interface{}
+}
+
+// NewAPISpecService creates an APISpecService for the given service version. Routes are set once
+// they are all registered.
+func NewAPISpecService(version string) *APISpecService {
+	if version == "" {
+		version = "dev"
+	}
+	return &APISpecService{version: version, asyncAPI: buildAsyncAPI(version)}
+}
+
+// SetRoutes builds the OpenAPI document from routes.
+func (s *APISpecService) SetRoutes(routes []APIRoute) {
+	s.openAPI = buildOpenAPI(s.version, routes)
+}
+
+// OpenAPI returns the OpenAPI 3 document of the REST routes.
+func (s *APISpecService) OpenAPI() map[string]interface{} {
+	return s.openAPI
+}
+
+// AsyncAPI returns the AsyncAPI document of the events published to processors and webhooks.
+func (s *APISpecService) AsyncAPI() map[string]interface{} {
+	return s.asyncAPI
+}
+
+var routeParam = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+
+func buildOpenAPI(version string, routes []APIRoute) map[string]interface{} {
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].Path != routes[j].Path {
+			return routes[i].Path < routes[j].Path
+		}
+		return routes[i].Method < routes[j].Method
+	})
+
+	paths := map[string]interface{}{}
+	tags := map[string]bool{}
+	operationIDs := map[string]int{}
+	for _, route := range routes {
+		path := routeParam.ReplaceAllString(route.Path, "{$1}")
+		item, _ := paths[path].(map[string]interface{})
+		if item == nil {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+
+		tag, name := handlerName(route.Handler)
+		operationID := name
+		if operationID == "" {
+			operationID = strings.ToLower(route.Method) + path
+		}
+		// Handlers of several types share names, and a handler can serve several routes, while
+		// operation ids must stay unique
+		if operationIDs[operationID] > 0 && tag != "" {
+			operationID = tag + operationID
+		}
+		if n := operationIDs[operationID]; n > 0 {
+			operationIDs[operationID] = n + 1
+			operationID = operationID + "_" + strings.ToLower(route.Method) + "_" + strconv.Itoa(n+1)
+		} else {
+			operationIDs[operationID] = 1
+		}
+
+		operation := map[string]interface{}{
+			"operationId": operationID,
+			"summary":     splitWords(name),
+			"responses": map[string]interface{}{
+				"default": map[string]interface{}{"$ref": "#/components/responses/Default"},
+			},
+		}
+		if tag != "" {
+			operation["tags"] = []string{tag}
+			tags[tag] = true
+		}
+		if route.Public {
+			operation["security"] = []interface{}{}
+		} else {
+			permission := route.Permission
+			if permission == "" {
+				permission = models.PermissionSystem
+			}
+			operation["x-permission"] = permission
+		}
+
+		var parameters []interface{}
+		for _, match := range routeParam.FindAllStringSubmatch(route.Path, -1) {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     match[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]interface{}{"type": "string"},
+			})
+		}
+		if parameters != nil {
+			operation["parameters"] = parameters
+		}
+		if route.Method == "POST" || route.Method == "PUT" || route.Method == "PATCH" {
+			operation["requestBody"] = map[string]interface{}{
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{"schema": map[string]interface{}{"type": "object"}},
+				},
+			}
+		}
+		item[strings.ToLower(route.Method)] = operation
+	}
+
+	var tagList []interface{}
+	for _, tag := range sortedKeys(tags) {
+		tagList = append(tagList, map[string]interface{}{"name": tag})
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       "Fraiday API",
+			"version":     version,
+			"description": "Every operation needs the permission in x-permission, except those with empty security.",
+		},
+		"servers": []interface{}{map[string]interface{}{"url": "/"}},
+		"tags":    tagList,
+		"paths":   paths,
+		"security": []interface{}{
+			map[string]interface{}{"bearerAuth": []string{}},
+			map[string]interface{}{"basicAuth": []string{}},
+		},
+		"components": map[string]interface{}{
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "API key or OIDC token"},
+				"basicAuth":  map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+			"responses": map[string]interface{}{
+				"Default": map[string]interface{}{
+					"description": "The result, or an error",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{
+								"type":       "object",
+								"properties": map[string]interface{}{"error": map[string]interface{}{"type": "string"}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+func buildAsyncAPI(version string) map[string]interface{} {
+	catalog := EventCatalog()
+	channels := map[string]interface{}{}
+	messages := map[string]interface{}{}
+	for _, event := range catalog.Events {
+		payload := copySchema(catalog.Envelope)
+		delete(payload, "$schema")
+		delete(payload, "title")
+		properties := copySchema(payload["properties"].(map[string]interface{}))
+		properties["event_type"] = map[string]interface{}{"const": event.EventType}
+		properties["entity_type"] = map[string]interface{}{"const": event.EntityType}
+		properties["schema_version"] = map[string]interface{}{"type": "integer", "const": event.SchemaVersion}
+		data := copySchema(event.Schema)
+		delete(data, "$schema")
+		properties["data"] = data
+		payload["properties"] = properties
+
+		messages[event.EventType] = map[string]interface{}{
+			"name":          event.EventType,
+			"title":         splitWords(event.EventType),
+			"contentType":   "application/json",
+			"schemaFormat":  "application/schema+json;version=draft-2020-12",
+			"payload":       payload,
+			"headers":       map[string]interface{}{"$ref": "#/components/schemas/DeliveryHeaders"},
+			"x-entity-type": event.EntityType,
+		}
+		channels[event.EventType] = map[string]interface{}{
+			"description": "Events of type " + event.EventType + " about a " + event.EntityType,
+			"subscribe": map[string]interface{}{
+				"operationId": "on_" + event.EventType,
+				"message":     map[string]interface{}{"$ref": "#/components/messages/" + event.EventType},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"asyncapi": "2.6.0",
+		"info": map[string]interface{}{
+			"title":       "Fraiday Events",
+			"version":     version,
+			"description": "Events delivered to event processors over HTTP or AMQP, one channel per event type.",
+		},
+		"defaultContentType": "application/json",
+		"channels":           channels,
+		"components": map[string]interface{}{
+			"messages": messages,
+			"schemas": map[string]interface{}{
+				"DeliveryHeaders": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"X-Event-Schema-Version": map[string]interface{}{"type": "string", "description": "schema_version of the envelope; AMQP deliveries carry it in the schema_version header"},
+					},
+				},
+			},
+		},
+	}
+}
+
+// handlerName returns the tag and the function name of a handler gin reports as
+// ".../handlers.(*EventsHandler).GetEventCatalog-fm".
+func handlerName(handler string) (tag, name string) {
+	handler = strings.TrimSuffix(handler, "-fm")
+	if i := strings.LastIndex(handler, "."); i >= 0 {
+		name = handler[i+1:]
+		handler = handler[:i]
+	}
+	if i := strings.LastIndex(handler, "(*"); i >= 0 {
+		tag = strings.TrimSuffix(strings.TrimSuffix(handler[i+2:], ")"), "Handler")
+	}
+	if strings.HasPrefix(name, "func") {
+		name = ""
+	}
+	return tag, name
+}
+
+// splitWords turns GetEventCatalog or chat_message_created into "Get event catalog" and
+// "Chat message created".
+func splitWords(name string) string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' {
+			if word != nil {
+				words = append(words, string(word))
+				word = nil
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && word != nil && (unicode.IsLower(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, r)
+	}
+	if word != nil {
+		words = append(words, string(word))
+	}
+	for i, w := range words {
+		if strings.ToUpper(w) != w {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	sentence := strings.Join(words, " ")
+	if sentence == "" {
+		return ""
+	}
+	return strings.ToUpper(sentence[:1]) + sentence[1:]
+}
+
+func copySchema(schema map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{}, len(schema))
+	for k, v := range schema {
+		copied[k] = v
+	}
+	return copied
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
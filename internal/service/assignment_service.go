@@ -0,0 +1,312 @@
+// Package service provides business logic for routing handed-over sessions to agents.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const defaultAgentMaxConcurrent = 5
+
+var ErrNoAgentAvailable = errors.New("no agent available for assignment")
+
+// AssignmentService manages agents and assignment rules, and picks an agent for each handover.
+type AssignmentService struct {
+	AgentRepo       *repository.AgentRepository
+	RuleRepo        *repository.AssignmentRuleRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	ClientRepo      *repository.ClientRepository
+}
+
+// NewAssignmentService creates a new AssignmentService.
+func NewAssignmentService(
+	agentRepo *repository.AgentRepository,
+	ruleRepo *repository.AssignmentRuleRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	clientRepo *repository.ClientRepository,
+) *AssignmentService {
+	return &AssignmentService{
+		AgentRepo:       agentRepo,
+		RuleRepo:        ruleRepo,
+		ChatSessionRepo: chatSessionRepo,
+		ClientRepo:      clientRepo,
+	}
+}
+
+// RegisterAgent creates an agent for a client. New agents start offline unless a status is given.
+func (s *AssignmentService) RegisterAgent(ctx context.Context, clientID string, agent *models.Agent) error {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return errors.New("client not found")
+	}
+	if _, err := s.AgentRepo.GetByAgentID(ctx, client.ID, agent.AgentID); err == nil {
+		return fmt.Errorf("agent %s already exists", agent.AgentID)
+	}
+	if err := validateAgentStatus(agent.Status); err != nil {
+		return err
+	}
+
+	agent.ClientID = client.ID
+	agent.ActiveSessions = 0
+	if agent.Status == "" {
+		agent.Status = models.AgentStatusOffline
+	}
+	if agent.MaxConcurrent <= 0 {
+		agent.MaxConcurrent = defaultAgentMaxConcurrent
+	}
+	return s.AgentRepo.Create(ctx, agent)
+}
+
+// ListAgents lists a client's agents, optionally filtered by status.
+func (s *AssignmentService) ListAgents(ctx context.Context, clientID, status string) ([]models.Agent, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	filter := map[string]interface{}{"client": client.ID}
+	if status != "" {
+		filter["status"] = status
+	}
+	return s.AgentRepo.List(ctx, filter)
+}
+
+// SetAvailability updates an agent's status and, optionally, its concurrent session limit.
+func (s *AssignmentService) SetAvailability(ctx context.Context, clientID, agentID string, status models.AgentStatus, maxConcurrent *int) (*models.Agent, error) {
+	if status == "" {
+		return nil, fmt.Errorf("status is required")
+	}
+	if err := validateAgentStatus(status); err != nil {
+		return nil, err
+	}
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	agent, err := s.AgentRepo.GetByAgentID(ctx, client.ID, agentID)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{"status": status}
+	if maxConcurrent != nil {
+		if *maxConcurrent <= 0 {
+			return nil, fmt.Errorf("max_concurrent must be positive")
+		}
+		update["max_concurrent"] = *maxConcurrent
+	}
+	if err := s.AgentRepo.Update(ctx, agent.ID, update); err != nil {
+		return nil, err
+	}
+	return s.AgentRepo.GetByID(ctx, agent.ID)
+}
+
+// CreateRule adds an assignment rule for a client.
+func (s *AssignmentService) CreateRule(ctx context.Context, clientID string, rule *models.AssignmentRule) error {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return errors.New("client not found")
+	}
+	switch rule.Strategy {
+	case models.AssignmentStrategyRoundRobin, models.AssignmentStrategyLeastBusy, models.AssignmentStrategySkillsMatch:
+	default:
+		return fmt.Errorf("invalid strategy: %s", rule.Strategy)
+	}
+
+	rule.ClientID = client.ID
+	return s.RuleRepo.Create(ctx, rule)
+}
+
+// ListRules lists a client's assignment rules in evaluation order.
+func (s *AssignmentService) ListRules(ctx context.Context, clientID string) ([]models.AssignmentRule, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return s.RuleRepo.ListByClient(ctx, client.ID)
+}
+
+// DeleteRule removes one of a client's assignment rules.
+func (s *AssignmentService) DeleteRule(ctx context.Context, clientID, ruleID string) error {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return errors.New("client not found")
+	}
+	id, err := primitive.ObjectIDFromHex(ruleID)
+	if err != nil {
+		return fmt.Errorf("invalid rule ID: %w", err)
+	}
+	return s.RuleRepo.Delete(ctx, client.ID, id)
+}
+
+// Assign picks and reserves an agent for the session's handover using the client's rules.
+// The assignment is not recorded on the session; call Record once the handover is persisted.
+func (s *AssignmentService) Assign(ctx context.Context, session *models.ChatSession, handover *models.SessionHandover) (*models.SessionAssignment, error) {
+	if session.Client == nil {
+		return nil, ErrNoAgentAvailable
+	}
+
+	rules, err := s.RuleRepo.ListByClient(ctx, *session.Client)
+	if err != nil {
+		return nil, err
+	}
+	rule := matchAssignmentRule(rules, handover.TargetQueue)
+
+	agents, err := s.AgentRepo.List(ctx, map[string]interface{}{
+		"client": *session.Client,
+		"status": models.AgentStatusAvailable,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, agent := range rankAgents(agents, rule, handover) {
+		reserved, err := s.AgentRepo.Reserve(ctx, agent.ID)
+		if err != nil {
+			return nil, err
+		}
+		if !reserved {
+			continue // Picked up by a concurrent assignment or went offline
+		}
+
+		assignment := &models.SessionAssignment{
+			AgentID:    agent.AgentID,
+			Strategy:   rule.Strategy,
+			Queue:      handover.TargetQueue,
+			AssignedAt: time.Now().UTC(),
+		}
+		if !rule.ID.IsZero() {
+			ruleID := rule.ID
+			assignment.RuleID = &ruleID
+		}
+		return assignment, nil
+	}
+	return nil, ErrNoAgentAvailable
+}
+
+// Claim records an agent taking a handover that was not routed to anyone in particular.
+func (s *AssignmentService) Claim(ctx context.Context, session *models.ChatSession, agentID, queue string) error {
+	// Agents that are not registered can still accept; they just aren't counted
+	if session.Client != nil {
+		if agent, err := s.AgentRepo.GetByAgentID(ctx, *session.Client, agentID); err == nil {
+			if err := s.AgentRepo.Acquire(ctx, agent.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return s.Record(ctx, session, &models.SessionAssignment{
+		AgentID:    agentID,
+		Queue:      queue,
+		AssignedAt: time.Now().UTC(),
+	})
+}
+
+// Record appends an assignment to the session's history.
+func (s *AssignmentService) Record(ctx context.Context, session *models.ChatSession, assignment *models.SessionAssignment) error {
+	if err := s.ChatSessionRepo.AddAssignment(ctx, session.ID, *assignment); err != nil {
+		return fmt.Errorf("failed to record assignment: %w", err)
+	}
+	return nil
+}
+
+// Release frees the agent's slot and closes its open assignment on the session.
+func (s *AssignmentService) Release(ctx context.Context, session *models.ChatSession, agentID string) error {
+	if session.Client != nil {
+		if err := s.AgentRepo.Release(ctx, *session.Client, agentID); err != nil {
+			return err
+		}
+	}
+	return s.ChatSessionRepo.ReleaseAssignment(ctx, session.ID, agentID, time.Now().UTC())
+}
+
+// ListAssignments returns the assignment history of a session.
+func (s *AssignmentService) ListAssignments(ctx context.Context, sessionID string) ([]models.SessionAssignment, error) {
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.New("invalid session id")
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("chat session not found")
+	}
+	if session.Assignments == nil {
+		return []models.SessionAssignment{}, nil
+	}
+	return session.Assignments, nil
+}
+
+// matchAssignmentRule returns the first enabled rule for queue, or a least-busy default when none match.
+func matchAssignmentRule(rules []models.AssignmentRule, queue string) models.AssignmentRule {
+	for _, rule := range rules {
+		if rule.Enabled && (rule.Queue == "" || rule.Queue == queue) {
+			return rule
+		}
+	}
+	return models.AssignmentRule{Strategy: models.AssignmentStrategyLeastBusy}
+}
+
+// rankAgents filters agents eligible under rule and orders them by the rule's strategy, best first.
+func rankAgents(agents []models.Agent, rule models.AssignmentRule, handover *models.SessionHandover) []models.Agent {
+	eligible := make([]models.Agent, 0, len(agents))
+	for _, agent := range agents {
+		if agent.Status != models.AgentStatusAvailable || agent.ActiveSessions >= agent.MaxConcurrent {
+			continue
+		}
+		if !agent.HasSkills(rule.RequiredSkills) {
+			continue
+		}
+		if handover.TargetQueue != "" && len(agent.Queues) > 0 && !slices.Contains(agent.Queues, handover.TargetQueue) {
+			continue
+		}
+		eligible = append(eligible, agent)
+	}
+
+	lastAssigned := func(a models.Agent) time.Time {
+		if a.LastAssignedAt == nil {
+			return time.Time{}
+		}
+		return *a.LastAssignedAt
+	}
+	skillScore := func(a models.Agent) int {
+		score := 0
+		for _, skill := range handover.Skills {
+			if slices.Contains(a.Skills, skill) {
+				score++
+			}
+		}
+		return score
+	}
+
+	sort.SliceStable(eligible, func(i, j int) bool {
+		a, b := eligible[i], eligible[j]
+		switch rule.Strategy {
+		case models.AssignmentStrategyRoundRobin:
+			return lastAssigned(a).Before(lastAssigned(b))
+		case models.AssignmentStrategySkillsMatch:
+			if sa, sb := skillScore(a), skillScore(b); sa != sb {
+				return sa > sb
+			}
+		}
+		if a.ActiveSessions != b.ActiveSessions {
+			return a.ActiveSessions < b.ActiveSessions
+		}
+		return lastAssigned(a).Before(lastAssigned(b))
+	})
+	return eligible
+}
+
+func validateAgentStatus(status models.AgentStatus) error {
+	switch status {
+	case "", models.AgentStatusAvailable, models.AgentStatusBusy, models.AgentStatusOffline:
+		return nil
+	}
+	return fmt.Errorf("invalid agent status: %s", status)
+}
@@ -0,0 +1,63 @@
+// Package service provides business logic for storing and serving message attachment files.
+package service
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+)
+
+var ErrAttachmentNotFound = errors.New("attachment not found")
+
+// AttachmentService keeps files received from channels so messages can link to them after the
+// channel's own media URLs expire.
+type AttachmentService struct {
+	Repo *repository.AttachmentRepository
+	// BaseURL prefixes the file_url of stored attachments; empty gives URLs relative to the API
+	BaseURL string
+}
+
+// NewAttachmentService creates a new AttachmentService.
+func NewAttachmentService(repo *repository.AttachmentRepository, baseURL string) *AttachmentService {
+	return &AttachmentService{
+		Repo:    repo,
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+// Store saves content and returns a message attachment pointing at it.
+func (s *AttachmentService) Store(fileName, contentType, attachmentType string, metadata bson.M, content io.Reader) (*models.Attachment, error) {
+	stored, err := s.Repo.Upload(fileName, contentType, metadata, content)
+	if err != nil {
+		return nil, err
+	}
+	return &models.Attachment{
+		FileName: fileName,
+		FileType: contentType,
+		FileSize: stored.Size,
+		FileURL:  s.BaseURL + "/api/v1/attachments/" + stored.ID.Hex(),
+		Type:     attachmentType,
+	}, nil
+}
+
+// Open returns a stored attachment and a reader for its content.
+func (s *AttachmentService) Open(id string) (*repository.StoredAttachment, io.ReadCloser, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	file, content, err := s.Repo.Open(objID)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, content, nil
+}
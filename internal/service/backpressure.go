@@ -0,0 +1,126 @@
+// Package service provides backpressure for workflow tasks when their queue is saturated.
+package service
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+)
+
+// Degradation modes of a workflow triggered while its queue was saturated
+const (
+	// DegradationDelayed means the workflow was enqueued behind a long queue, so its reply is late
+	DegradationDelayed = "delayed"
+	// DegradationSuggestionsShed means a suggestion workflow was dropped to keep the queue free for
+	// chat workflows
+	DegradationSuggestionsShed = "suggestions_shed"
+)
+
+// Degradation is how backpressure changed the workflow of a message. The zero value means the
+// workflow was enqueued normally.
+type Degradation struct {
+	Mode       string
+	QueueDepth int
+}
+
+// Degraded reports whether backpressure changed the workflow.
+func (d Degradation) Degraded() bool {
+	return d.Mode != ""
+}
+
+// QueueDepthSource reports how many tasks are ready in a queue. tasks.TaskClient implements it.
+type QueueDepthSource interface {
+	QueueDepth(queue string) (int, error)
+}
+
+// Backpressure watches the depth of the workflow queue. Above ShedDepth suggestion workflows are
+// shed, and above DegradedDepth chat workflows are reported as delayed; 0 disables either. The
+// depth is refreshed in the background once it is older than Interval, so triggers never wait on
+// the broker.
+type Backpressure struct {
+	Source        QueueDepthSource
+	Queue         string
+	ShedDepth     int
+	DegradedDepth int
+	Interval      time.Duration
+
+	logger     *zap.Logger
+	mu         sync.Mutex
+	depth      int
+	checkedAt  time.Time
+	refreshing bool
+}
+
+// NewBackpressure creates a Backpressure for cfg's workflow queue, whose depth source reports, or
+// nil when both thresholds are disabled.
+func NewBackpressure(source QueueDepthSource, cfg *config.Config, logger *zap.Logger) *Backpressure {
+	if cfg.BackpressureShedDepth == 0 && cfg.BackpressureDegradedDepth == 0 {
+		return nil
+	}
+	return &Backpressure{
+		Source:        source,
+		Queue:         cfg.CeleryDefaultQueue,
+		ShedDepth:     cfg.BackpressureShedDepth,
+		DegradedDepth: cfg.BackpressureDegradedDepth,
+		Interval:      cfg.BackpressureCheckInterval,
+		logger:        logger,
+	}
+}
+
+// Depth returns the last known depth of the queue, and starts refreshing it if it is stale.
+func (b *Backpressure) Depth() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.refreshing && time.Since(b.checkedAt) >= b.Interval {
+		b.refreshing = true
+		go b.refresh()
+	}
+	return b.depth
+}
+
+// refresh reads the depth of the queue. While it can't be read the queue is taken to be empty, so
+// a broker outage doesn't shed suggestions indefinitely.
+func (b *Backpressure) refresh() {
+	depth, err := b.Source.QueueDepth(b.Queue)
+	if err != nil {
+		b.logger.Warn("Failed to check workflow queue depth", zap.String("queue", b.Queue), zap.Error(err))
+		depth = 0
+	} else {
+		telemetry.SetTaskQueueSize(b.Queue, depth)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.depth = depth
+	b.checkedAt = time.Now()
+	b.refreshing = false
+}
+
+// chatWorkflow returns the degradation of a chat workflow triggered now.
+func (b *Backpressure) chatWorkflow() Degradation {
+	if b == nil || b.DegradedDepth == 0 {
+		return Degradation{}
+	}
+	if depth := b.Depth(); depth > b.DegradedDepth {
+		return Degradation{Mode: DegradationDelayed, QueueDepth: depth}
+	}
+	return Degradation{}
+}
+
+// suggestionWorkflow returns the degradation of a suggestion workflow triggered now: shed above
+// ShedDepth, otherwise delayed like chat workflows.
+func (b *Backpressure) suggestionWorkflow() Degradation {
+	if b == nil {
+		return Degradation{}
+	}
+	if b.ShedDepth > 0 {
+		if depth := b.Depth(); depth > b.ShedDepth {
+			return Degradation{Mode: DegradationSuggestionsShed, QueueDepth: depth}
+		}
+	}
+	return b.chatWorkflow()
+}
@@ -0,0 +1,145 @@
+// Package service provides business logic for client canned responses.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrCannedResponseNotFound = errors.New("canned response not found")
+	ErrMissingPlaceholders    = errors.New("missing values for placeholders")
+)
+
+// placeholderPattern matches {{name}} placeholders, allowing whitespace inside the braces.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// CannedResponseService manages canned responses and expands them into message text.
+type CannedResponseService struct {
+	Repo       *repository.CannedResponseRepository
+	ClientRepo *repository.ClientRepository
+}
+
+// NewCannedResponseService creates a new CannedResponseService.
+func NewCannedResponseService(repo *repository.CannedResponseRepository, clientRepo *repository.ClientRepository) *CannedResponseService {
+	return &CannedResponseService{Repo: repo, ClientRepo: clientRepo}
+}
+
+// CreateCannedResponse stores a new canned response for an existing client.
+func (s *CannedResponseService) CreateCannedResponse(ctx context.Context, resp *models.CannedResponse) error {
+	if _, err := s.ClientRepo.GetByClientID(ctx, resp.ClientID); err != nil {
+		return errors.New("client not found")
+	}
+	resp.Placeholders = Placeholders(resp.Text)
+	resp.UsageCount = 0
+	resp.LastUsedAt = nil
+	return s.Repo.Create(ctx, resp)
+}
+
+// GetCannedResponse returns one of a client's canned responses.
+func (s *CannedResponseService) GetCannedResponse(ctx context.Context, clientID, id string) (*models.CannedResponse, error) {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrCannedResponseNotFound
+	}
+	resp, err := s.Repo.GetByID(ctx, clientID, *objID)
+	if err != nil {
+		return nil, ErrCannedResponseNotFound
+	}
+	return resp, nil
+}
+
+// ListCannedResponses returns a client's canned responses, optionally limited to a category.
+func (s *CannedResponseService) ListCannedResponses(ctx context.Context, clientID, category string, limit, offset int) ([]models.CannedResponse, error) {
+	filter := bson.M{"client_id": clientID}
+	if category != "" {
+		filter["category"] = category
+	}
+	return s.Repo.List(ctx, filter, limit, offset)
+}
+
+// UpdateCannedResponse applies update to a client's canned response. Placeholders are
+// recomputed whenever the text changes.
+func (s *CannedResponseService) UpdateCannedResponse(ctx context.Context, clientID, id string, update bson.M) error {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return ErrCannedResponseNotFound
+	}
+	if text, ok := update["text"].(string); ok {
+		update["placeholders"] = Placeholders(text)
+	}
+	if err := s.Repo.Update(ctx, clientID, *objID, update); err != nil {
+		return ErrCannedResponseNotFound
+	}
+	return nil
+}
+
+// DeleteCannedResponse removes a client's canned response.
+func (s *CannedResponseService) DeleteCannedResponse(ctx context.Context, clientID, id string) error {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return ErrCannedResponseNotFound
+	}
+	if err := s.Repo.Delete(ctx, clientID, *objID); err != nil {
+		return ErrCannedResponseNotFound
+	}
+	return nil
+}
+
+// Expand loads a client's canned response and fills its placeholders from variables.
+func (s *CannedResponseService) Expand(ctx context.Context, clientID, id string, variables map[string]string) (*models.CannedResponse, string, error) {
+	resp, err := s.GetCannedResponse(ctx, clientID, id)
+	if err != nil {
+		return nil, "", err
+	}
+	text, err := RenderPlaceholders(resp.Text, variables)
+	if err != nil {
+		return nil, "", err
+	}
+	return resp, text, nil
+}
+
+// RecordUsage counts one more use of a canned response.
+func (s *CannedResponseService) RecordUsage(ctx context.Context, id primitive.ObjectID) error {
+	return s.Repo.IncrementUsage(ctx, id)
+}
+
+// Placeholders returns the distinct placeholder names in text, sorted.
+func Placeholders(text string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range placeholderPattern.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RenderPlaceholders replaces every {{name}} in text with variables[name]. Any placeholder
+// without a value fails the whole render rather than leaking braces to the end user.
+func RenderPlaceholders(text string, variables map[string]string) (string, error) {
+	var missing []string
+	for _, name := range Placeholders(text) {
+		if _, ok := variables[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("%w: %s", ErrMissingPlaceholders, strings.Join(missing, ", "))
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		return variables[placeholderPattern.FindStringSubmatch(m)[1]]
+	}), nil
+}
@@ -0,0 +1,191 @@
+// Package service provides business logic for adapting replies to what a channel can render.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChannelCapabilityService degrades chat_message_created events for processors without a native
+// connector, such as client webhooks, to the capabilities of the message's channel. Native
+// connectors call DegradeAttachments themselves with the channel they already loaded.
+type ChannelCapabilityService struct {
+	ChatMessageRepo   *repository.ChatMessageRepository
+	ChatSessionRepo   *repository.ChatSessionRepository
+	ClientChannelRepo *repository.ClientChannelRepository
+	// Clients, when set, serves channel lookups from memory
+	Clients ClientCache
+}
+
+// NewChannelCapabilityService creates a new ChannelCapabilityService.
+func NewChannelCapabilityService(
+	chatMessageRepo *repository.ChatMessageRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+) *ChannelCapabilityService {
+	return &ChannelCapabilityService{
+		ChatMessageRepo:   chatMessageRepo,
+		ChatSessionRepo:   chatSessionRepo,
+		ClientChannelRepo: clientChannelRepo,
+	}
+}
+
+// DegradeEventData returns eventData with the text and attachments of its message payload
+// degraded to the capabilities of the session's channel. eventData itself is never modified, and
+// is returned as is for other events or when the channel renders everything the message holds.
+func (s *ChannelCapabilityService) DegradeEventData(ctx context.Context, eventData map[string]interface{}) map[string]interface{} {
+	if eventType, _ := eventData["event_type"].(string); eventType != string(models.EventTypeChatMessageCreated) {
+		return eventData
+	}
+	data := asMap(eventData["data"])
+	if len(asSlice(data["attachments"])) == 0 {
+		return eventData
+	}
+	entityID, _ := eventData["entity_id"].(string)
+	messageID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return eventData
+	}
+	msg, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return eventData
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, msg.SessionID)
+	if err != nil || session.ClientChannel == nil {
+		return eventData
+	}
+	channel, err := channelByID(ctx, s.Clients, s.ClientChannelRepo, *session.ClientChannel)
+	if err != nil {
+		return eventData
+	}
+
+	text, attachments := DegradeAttachments(msg.Text, msg.Attachments, channel.EffectiveCapabilities())
+	if text == msg.Text && len(attachments) == len(msg.Attachments) {
+		return eventData
+	}
+	degradedData := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		degradedData[k] = v
+	}
+	degradedData["text"] = text
+	if len(attachments) > 0 {
+		// Round-trip through JSON so the payload matches what the publisher built
+		encoded, _ := json.Marshal(attachments)
+		var decoded []interface{}
+		_ = json.Unmarshal(encoded, &decoded)
+		degradedData["attachments"] = decoded
+	} else {
+		delete(degradedData, "attachments")
+	}
+	degraded := make(map[string]interface{}, len(eventData))
+	for k, v := range eventData {
+		degraded[k] = v
+	}
+	degraded["data"] = degradedData
+	return degraded
+}
+
+// DegradeAttachments rewrites the attachments caps can't render as text appended to the reply:
+// carousel items become a list of titles and links, buttons beyond what the channel supports
+// become "Reply with" options or "title: url" links, and files become their URLs. The returned
+// attachments are copies; attachments left empty are dropped.
+func DegradeAttachments(text string, attachments []models.Attachment, caps models.ChannelCapabilities) (string, []models.Attachment) {
+	var items, options, links []string
+	buttonsKept := 0
+	keepButton := func(b models.AttachmentButton) bool {
+		if !caps.SupportsButtons || (caps.MaxButtons > 0 && buttonsKept >= caps.MaxButtons) {
+			if b.Title == "" {
+				return false
+			}
+			if isAbsoluteURL(b.URL) {
+				links = append(links, b.Title+": "+b.URL)
+			} else {
+				options = append(options, b.Title)
+			}
+			return false
+		}
+		buttonsKept++
+		return true
+	}
+	keepButtons := func(buttons []models.AttachmentButton) []models.AttachmentButton {
+		var kept []models.AttachmentButton
+		for _, b := range buttons {
+			if keepButton(b) {
+				kept = append(kept, b)
+			}
+		}
+		return kept
+	}
+
+	var kept []models.Attachment
+	for _, attachment := range attachments {
+		if (attachment.Type == models.AttachmentTypeImage || attachment.Type == models.AttachmentTypeFile) && attachment.FileURL != "" && !caps.SupportsFiles {
+			if isAbsoluteURL(attachment.FileURL) {
+				links = append(links, attachment.FileURL)
+			}
+			continue
+		}
+
+		attachment.Buttons = keepButtons(attachment.Buttons)
+
+		if attachment.Carousel != nil {
+			carousel := models.Carousel{Items: attachment.Carousel.Items}
+			if len(carousel.Items) > 0 && !caps.SupportsCarousel {
+				for _, item := range carousel.Items {
+					items = append(items, carouselItemText(item)...)
+				}
+				carousel.Items = nil
+			}
+			carousel.Buttons = keepButtons(attachment.Carousel.Buttons)
+			attachment.Carousel = nil
+			if len(carousel.Items) > 0 || len(carousel.Buttons) > 0 {
+				attachment.Carousel = &carousel
+			}
+		}
+
+		if attachment.FileURL == "" && len(attachment.AllButtons()) == 0 && len(attachment.CarouselItems()) == 0 {
+			continue
+		}
+		kept = append(kept, attachment)
+	}
+
+	parts := []string{}
+	if text = strings.TrimSpace(text); text != "" {
+		parts = append(parts, text)
+	}
+	if len(items) > 0 {
+		parts = append(parts, strings.Join(items, "\n"))
+	}
+	if len(options) > 0 {
+		parts = append(parts, "Reply with: "+strings.Join(options, ", "))
+	}
+	parts = append(parts, links...)
+	return strings.Join(parts, "\n\n"), kept
+}
+
+// carouselItemText renders a carousel item as a "- title: description" line followed by the
+// links of its default action and URL buttons.
+func carouselItemText(item models.CarouselItem) []string {
+	if item.Title == "" {
+		return nil
+	}
+	line := "- " + item.Title
+	if item.Description != "" {
+		line += ": " + item.Description
+	}
+	lines := []string{line}
+	if isAbsoluteURL(item.DefaultActionURL) {
+		lines = append(lines, "  "+item.DefaultActionURL)
+	}
+	for _, b := range item.Buttons {
+		if isAbsoluteURL(b.URL) {
+			lines = append(lines, "  "+b.Title+": "+b.URL)
+		}
+	}
+	return lines
+}
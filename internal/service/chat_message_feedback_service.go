@@ -14,6 +14,8 @@ import (
 
 type ChatMessageFeedbackService struct {
 	Repo *repository.ChatMessageFeedbackRepository
+	// Forwarding, when set, queues feedback on AI answers for the AI service
+	Forwarding *AIFeedbackService
 }
 
 func NewChatMessageFeedbackService(repo *repository.ChatMessageFeedbackRepository) *ChatMessageFeedbackService {
@@ -34,6 +36,9 @@ func (s *ChatMessageFeedbackService) CreateFeedback(ctx context.Context, message
 	if err := s.Repo.CreateFeedback(ctx, feedback); err != nil {
 		return nil, err
 	}
+	if s.Forwarding != nil {
+		s.Forwarding.Queue(ctx, feedback)
+	}
 	return feedback, nil
 }
 
@@ -45,7 +50,11 @@ func (s *ChatMessageFeedbackService) ListFeedbacksByMessageID(ctx context.Contex
 	return s.Repo.ListFeedbacksByMessageID(ctx, msgID)
 }
 
-func (s *ChatMessageFeedbackService) UpdateFeedback(ctx context.Context, feedbackID string, rating *int, comment *string, metadata map[string]interface{}) (*models.ChatMessageFeedback, error) {
+func (s *ChatMessageFeedbackService) UpdateFeedback(ctx context.Context, messageID, feedbackID string, rating *int, comment *string, metadata map[string]interface{}) (*models.ChatMessageFeedback, error) {
+	msgID, err := primitive.ObjectIDFromHex(messageID)
+	if err != nil {
+		return nil, errors.New("invalid message_id")
+	}
 	fbID, err := primitive.ObjectIDFromHex(feedbackID)
 	if err != nil {
 		return nil, errors.New("invalid feedback_id")
@@ -60,5 +69,13 @@ func (s *ChatMessageFeedbackService) UpdateFeedback(ctx context.Context, feedbac
 	if metadata != nil {
 		update["metadata"] = metadata
 	}
-	return s.Repo.UpdateFeedback(ctx, fbID, update)
+	updated, err := s.Repo.UpdateFeedback(ctx, msgID, fbID, update)
+	if err != nil {
+		return nil, err
+	}
+	// A changed feedback is forwarded again
+	if s.Forwarding != nil {
+		s.Forwarding.Queue(ctx, updated)
+	}
+	return updated, nil
 }
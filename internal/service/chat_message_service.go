@@ -4,12 +4,15 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"time"
 
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // ChatMessageService encapsulates business logic for chat messages.
@@ -17,6 +20,14 @@ type ChatMessageService struct {
 	Repo                 *repository.ChatMessageRepository
 	EventPublisherService *EventPublisherService
 	PayloadService       *PayloadService
+	// ChatSessionRepo, when set, records each message as session activity for inactivity auto-close
+	ChatSessionRepo *repository.ChatSessionRepository
+	// Usage, when set, meters messages per client and enforces message quotas
+	Usage *UsageService
+	// Contacts, when set, links user messages and their sessions to the sender's contact
+	Contacts *ContactService
+	// Transactions, when set, stores a message and what it touches in one transaction
+	Transactions *repository.Transactor
 }
 
 // NewChatMessageService creates a new ChatMessageService.
@@ -28,13 +39,104 @@ func NewChatMessageService(repo *repository.ChatMessageRepository, eventPublishe
 	}
 }
 
+// Errors returned when a reply references an invalid parent message.
+var (
+	ErrParentMessageNotFound = errors.New("parent message not found")
+	ErrParentMessageSession  = errors.New("parent message belongs to a different session")
+)
+
+// ErrMessageDuplicate is returned when a message with the same dedup key was stored first; the
+// message passed in is replaced by the stored one.
+var ErrMessageDuplicate = errors.New("message already exists")
+
+// FindDuplicate returns the message stored under dedupKey, or nil when there is none.
+func (s *ChatMessageService) FindDuplicate(ctx context.Context, dedupKey string) (*models.ChatMessage, error) {
+	messages, err := s.Repo.List(ctx, bson.M{"dedup_key": dedupKey}, 1)
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return &messages[0], nil
+}
+
 // CreateChatMessage creates a new chat message.
 func (s *ChatMessageService) CreateChatMessage(ctx context.Context, msg *models.ChatMessage) error {
-	// Create the message in database
-	if err := s.Repo.Create(ctx, msg); err != nil {
+	// Replies must stay within the parent's session
+	if msg.ParentMessageID != nil {
+		if err := s.checkParent(ctx, msg, nil); err != nil {
+			return err
+		}
+	}
+
+	// AI replies answer messages that were already let through, so only other messages are held to the quota
+	var clientID primitive.ObjectID
+	if s.Usage != nil {
+		if id, err := s.Usage.SessionClient(ctx, msg.SessionID); err == nil && !id.IsZero() {
+			clientID = id
+			if msg.SenderType != "assistant" {
+				if err := s.Usage.Allow(ctx, clientID, models.UsageMetricMessages); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if s.Contacts != nil && msg.SenderType == string(models.SenderTypeUser) && msg.Contact == nil {
+		// A message that can't be linked is still stored
+		if err := s.Contacts.LinkMessage(ctx, msg); err != nil {
+			log.Printf("Failed to link message to contact in session %s: %v", msg.SessionID.Hex(), err)
+		}
+	}
+
+	// The message, its session's activity, the parent's reply count and the event are stored
+	// together, so a failed step doesn't leave a message no processor heard of
+	err := s.Transactions.Do(ctx, func(ctx context.Context) error {
+		if err := s.Repo.Create(ctx, msg); err != nil {
+			return err
+		}
+		return s.recordCreated(ctx, msg)
+	})
+	if err != nil {
+		// A redelivery racing the first delivery loses to the unique dedup_key index
+		if msg.DedupKey != "" && mongo.IsDuplicateKeyError(err) {
+			if existing, findErr := s.FindDuplicate(ctx, msg.DedupKey); findErr == nil && existing != nil {
+				*msg = *existing
+				return ErrMessageDuplicate
+			}
+		}
 		return err
 	}
 
+	if !clientID.IsZero() {
+		s.Usage.Record(ctx, clientID, map[string]int64{models.UsageMetricMessages: 1})
+	}
+
+	return nil
+}
+
+// recordCreated records a stored message as session activity and as a reply, and publishes its
+// CHAT_MESSAGE_CREATED event. In a transaction a failed step fails the message; otherwise the
+// message is already stored and failures are only logged.
+func (s *ChatMessageService) recordCreated(ctx context.Context, msg *models.ChatMessage) error {
+	atomic := repository.InTransaction(ctx)
+
+	if s.ChatSessionRepo != nil {
+		if err := s.ChatSessionRepo.Touch(ctx, msg.SessionID, time.Now().UTC()); err != nil {
+			if atomic {
+				return err
+			}
+			log.Printf("Failed to record activity for session %s: %v", msg.SessionID.Hex(), err)
+		}
+	}
+
+	if msg.ParentMessageID != nil {
+		if err := s.Repo.IncrementReplyCount(ctx, *msg.ParentMessageID); err != nil {
+			if atomic {
+				return err
+			}
+			log.Printf("Failed to increment reply count for message %s: %v", msg.ParentMessageID.Hex(), err)
+		}
+	}
+
 	// Publish CHAT_MESSAGE_CREATED event (matching Python implementation)
 	if s.EventPublisherService != nil && s.PayloadService != nil {
 		// Create payload data for the event
@@ -63,6 +165,9 @@ func (s *ChatMessageService) CreateChatMessage(ctx context.Context, msg *models.
 			payload,
 		)
 		if err != nil {
+			if atomic {
+				return err
+			}
 			// Log error but don't fail the message creation
 			log.Printf("Failed to publish CHAT_MESSAGE_CREATED event: %v", err)
 		}
@@ -93,9 +198,74 @@ func (s *ChatMessageService) GetChatMessage(ctx context.Context, id primitive.Ob
 	return s.Repo.GetByID(ctx, id)
 }
 
-// BulkCreateChatMessages creates multiple chat messages at once.
+// ListReplies retrieves the direct replies to a message.
+func (s *ChatMessageService) ListReplies(ctx context.Context, parentID primitive.ObjectID, limit int64) ([]models.ChatMessage, error) {
+	if _, err := s.Repo.GetByID(ctx, parentID); err != nil {
+		return nil, ErrParentMessageNotFound
+	}
+	return s.Repo.ListReplies(ctx, parentID, limit)
+}
+
+// checkParent checks that the parent msg replies to is stored and in msg's session. parents, when
+// set, caches the parents already read.
+func (s *ChatMessageService) checkParent(ctx context.Context, msg *models.ChatMessage, parents map[primitive.ObjectID]*models.ChatMessage) error {
+	parent, ok := parents[*msg.ParentMessageID]
+	if !ok {
+		var err error
+		if parent, err = s.Repo.GetByID(ctx, *msg.ParentMessageID); err != nil {
+			return ErrParentMessageNotFound
+		}
+		if parents != nil {
+			parents[parent.ID] = parent
+		}
+	}
+	if parent.SessionID != msg.SessionID {
+		return ErrParentMessageSession
+	}
+	return nil
+}
+
+// BulkCreateChatMessages creates multiple chat messages at once. Replies are checked like single
+// messages before any is stored; their parents must already be stored.
 func (s *ChatMessageService) BulkCreateChatMessages(ctx context.Context, msgs []models.ChatMessage) error {
-	return s.Repo.BulkCreate(ctx, msgs)
+	parents := map[primitive.ObjectID]*models.ChatMessage{}
+	for i := range msgs {
+		if msgs[i].ParentMessageID == nil {
+			continue
+		}
+		if err := s.checkParent(ctx, &msgs[i], parents); err != nil {
+			return fmt.Errorf("messages[%d]: %w", i, err)
+		}
+	}
+
+	// Bulk imports carry one session; they are held to the quota as a whole
+	var clientID primitive.ObjectID
+	if s.Usage != nil && len(msgs) > 0 {
+		if id, err := s.Usage.SessionClient(ctx, msgs[0].SessionID); err == nil && !id.IsZero() {
+			clientID = id
+			if err := s.Usage.Allow(ctx, clientID, models.UsageMetricMessages); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := s.Repo.BulkCreate(ctx, msgs); err != nil {
+		return err
+	}
+
+	if !clientID.IsZero() {
+		s.Usage.Record(ctx, clientID, map[string]int64{models.UsageMetricMessages: int64(len(msgs))})
+	}
+
+	for _, msg := range msgs {
+		if msg.ParentMessageID == nil {
+			continue
+		}
+		if err := s.Repo.IncrementReplyCount(ctx, *msg.ParentMessageID); err != nil {
+			log.Printf("Failed to increment reply count for message %s: %v", msg.ParentMessageID.Hex(), err)
+		}
+	}
+	return nil
 }
 
 // GetChatMessageByID retrieves a chat message by its ObjectID.
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -10,18 +12,37 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+// maxSuggestionDiffWords bounds the word diff of an edited suggestion; longer texts are
+// recorded as a whole replacement.
+const maxSuggestionDiffWords = 1000
+
+var (
+	ErrSuggestionNotFound = errors.New("suggestion not found")
+	ErrSuggestionReviewed = errors.New("suggestion has already been accepted or rejected")
 )
 
 // ChatMessageSuggestionService handles chat message suggestion operations
 type ChatMessageSuggestionService struct {
-	collection *mongo.Collection
+	collection      *mongo.Collection
+	ChatSessionRepo *repository.ChatSessionRepository
+	ClientRepo      *repository.ClientRepository
+	// EventPublisherService, when set, publishes accept and reject events
+	EventPublisherService *EventPublisherService
+	// AnalyticsDB, when set, is where acceptance stats are aggregated, e.g. on secondaries
+	AnalyticsDB *mongo.Database
 }
 
 // NewChatMessageSuggestionService creates a new ChatMessageSuggestionService
 func NewChatMessageSuggestionService(db *mongo.Database) *ChatMessageSuggestionService {
 	return &ChatMessageSuggestionService{
-		collection: db.Collection("chat_message_suggestions"),
+		collection:      db.Collection("chat_message_suggestions"),
+		ChatSessionRepo: repository.NewChatSessionRepository(db),
+		ClientRepo:      repository.NewClientRepository(db),
 	}
 }
 
@@ -144,4 +165,264 @@ func (s *ChatMessageSuggestionService) DeleteSuggestion(ctx context.Context, sug
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// RecordSuggestion stores the review record for an AI suggestion saved as suggestionMsg, using the
+// message's ID so the ID in chat_suggestion_created events can be accepted or rejected.
+func (s *ChatMessageSuggestionService) RecordSuggestion(ctx context.Context, suggestionMsg *models.ChatMessage, messageID string) error {
+	session, err := s.ChatSessionRepo.GetByID(ctx, suggestionMsg.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	suggestion := &models.ChatMessageSuggestion{
+		ID:              suggestionMsg.ID,
+		ChatSessionID:   session.ID,
+		SuggestionText:  suggestionMsg.Text,
+		ConfidenceScore: suggestionMsg.Confidence,
+		SuggestionType:  "ai_generated",
+		MessageID:       ParseObjectID(messageID),
+		Test:            session.Test,
+		Status:          models.SuggestionStatusPending,
+	}
+	if session.Client != nil {
+		suggestion.ClientID = *session.Client
+	}
+	return s.CreateSuggestion(ctx, suggestion)
+}
+
+// AcceptSuggestion records that an agent used a suggestion. When finalText differs from the
+// suggestion, the suggestion is marked edited and a word diff of the agent's changes is stored.
+func (s *ChatMessageSuggestionService) AcceptSuggestion(ctx context.Context, suggestionID, finalText, agentID string) (*models.ChatMessageSuggestion, error) {
+	suggestion, err := s.getPending(ctx, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	set := bson.M{
+		"status":      models.SuggestionStatusAccepted,
+		"is_used":     true,
+		"used_at":     now,
+		"reviewed_by": agentID,
+		"reviewed_at": now,
+		"updated_at":  now,
+	}
+	if finalText != "" && finalText != suggestion.SuggestionText {
+		set["final_text"] = finalText
+		set["edited"] = true
+		set["edits"] = diffWords(suggestion.SuggestionText, finalText)
+	}
+
+	updated, err := s.review(ctx, suggestion.ID, set)
+	if err != nil {
+		return nil, err
+	}
+	s.publishReview(ctx, models.EventTypeChatSuggestionAccepted, updated, map[string]interface{}{
+		"edited":     updated.Edited,
+		"final_text": updated.FinalText,
+		"edits":      updated.Edits,
+	})
+	return updated, nil
+}
+
+// RejectSuggestion records that an agent discarded a suggestion.
+func (s *ChatMessageSuggestionService) RejectSuggestion(ctx context.Context, suggestionID, reason, agentID string) (*models.ChatMessageSuggestion, error) {
+	suggestion, err := s.getPending(ctx, suggestionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	updated, err := s.review(ctx, suggestion.ID, bson.M{
+		"status":           models.SuggestionStatusRejected,
+		"rejection_reason": reason,
+		"reviewed_by":      agentID,
+		"reviewed_at":      now,
+		"updated_at":       now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.publishReview(ctx, models.EventTypeChatSuggestionRejected, updated, map[string]interface{}{
+		"reason": reason,
+	})
+	return updated, nil
+}
+
+func (s *ChatMessageSuggestionService) getPending(ctx context.Context, suggestionID string) (*models.ChatMessageSuggestion, error) {
+	suggestion, err := s.GetSuggestion(ctx, suggestionID)
+	if err != nil || suggestion == nil {
+		return nil, ErrSuggestionNotFound
+	}
+	if suggestion.CurrentStatus() != models.SuggestionStatusPending {
+		return nil, ErrSuggestionReviewed
+	}
+	return suggestion, nil
+}
+
+// review applies set to a suggestion that is still pending, so concurrent reviews can't both win.
+func (s *ChatMessageSuggestionService) review(ctx context.Context, id primitive.ObjectID, set bson.M) (*models.ChatMessageSuggestion, error) {
+	filter := bson.M{
+		"_id": id,
+		"$or": bson.A{
+			bson.M{"status": models.SuggestionStatusPending},
+			bson.M{"status": bson.M{"$exists": false}},
+		},
+	}
+	var updated models.ChatMessageSuggestion
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := s.collection.FindOneAndUpdate(ctx, filter, bson.M{"$set": set}, opts).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSuggestionReviewed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to review suggestion: %w", err)
+	}
+	return &updated, nil
+}
+
+func (s *ChatMessageSuggestionService) publishReview(ctx context.Context, eventType models.EventType, suggestion *models.ChatMessageSuggestion, data map[string]interface{}) {
+	if s.EventPublisherService == nil {
+		return
+	}
+	data["session_id"] = suggestion.ChatSessionID.Hex()
+	data["suggestion_text"] = suggestion.SuggestionText
+	data["reviewed_by"] = suggestion.ReviewedBy
+	var messageID *string
+	if suggestion.MessageID != nil {
+		id := suggestion.MessageID.Hex()
+		messageID = &id
+	}
+	_, _ = s.EventPublisherService.PublishChatSuggestionEvent(ctx, eventType, suggestion.ID.Hex(), messageID, data)
+}
+
+// AcceptanceStats aggregates suggestion reviews per client for suggestions created in [start, end).
+// clientID limits the result to one client. Suggestions from sandbox sessions are excluded.
+func (s *ChatMessageSuggestionService) AcceptanceStats(ctx context.Context, clientID string, start, end time.Time) ([]dto.SuggestionStats, error) {
+	match := bson.M{
+		"test":       bson.M{"$ne": true},
+		"created_at": bson.M{"$gte": start, "$lt": end},
+	}
+	if clientID != "" {
+		client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+		if err != nil {
+			return nil, errors.New("client not found")
+		}
+		match["client"] = client.ID
+	}
+
+	countIf := func(cond bson.M) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{cond, 1, 0}}}
+	}
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: match}},
+		{{Key: "$group", Value: bson.M{
+			"_id":      "$client",
+			"total":    bson.M{"$sum": 1},
+			"accepted": countIf(bson.M{"$eq": bson.A{"$status", models.SuggestionStatusAccepted}}),
+			"accepted_edited": countIf(bson.M{"$and": bson.A{
+				bson.M{"$eq": bson.A{"$status", models.SuggestionStatusAccepted}},
+				bson.M{"$eq": bson.A{"$edited", true}},
+			}}),
+			"rejected": countIf(bson.M{"$eq": bson.A{"$status", models.SuggestionStatusRejected}}),
+		}}},
+	}
+
+	collection := s.collection
+	if s.AnalyticsDB != nil {
+		collection = s.AnalyticsDB.Collection(s.collection.Name())
+	}
+	cursor, err := collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate suggestions: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Client         primitive.ObjectID `bson:"_id"`
+		Total          int64              `bson:"total"`
+		Accepted       int64              `bson:"accepted"`
+		AcceptedEdited int64              `bson:"accepted_edited"`
+		Rejected       int64              `bson:"rejected"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode suggestion stats: %w", err)
+	}
+
+	stats := make([]dto.SuggestionStats, 0, len(rows))
+	for _, row := range rows {
+		st := dto.SuggestionStats{
+			ClientID:       row.Client.Hex(),
+			Total:          row.Total,
+			Accepted:       row.Accepted,
+			AcceptedEdited: row.AcceptedEdited,
+			Rejected:       row.Rejected,
+			Pending:        row.Total - row.Accepted - row.Rejected,
+		}
+		if client, err := s.ClientRepo.GetByID(ctx, row.Client); err == nil {
+			st.ClientID = client.ClientID
+		}
+		if reviewed := row.Accepted + row.Rejected; reviewed > 0 {
+			st.AcceptanceRate = float64(row.Accepted) / float64(reviewed)
+		}
+		if row.Accepted > 0 {
+			st.EditRate = float64(row.AcceptedEdited) / float64(row.Accepted)
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
+
+// diffWords returns the word-level edits that turn from into to, merging runs of the same operation.
+func diffWords(from, to string) []models.SuggestionEdit {
+	a, b := strings.Fields(from), strings.Fields(to)
+	if len(a) > maxSuggestionDiffWords || len(b) > maxSuggestionDiffWords {
+		return []models.SuggestionEdit{{Op: "delete", Text: from}, {Op: "insert", Text: to}}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var edits []models.SuggestionEdit
+	add := func(op, word string) {
+		if n := len(edits); n > 0 && edits[n-1].Op == op {
+			edits[n-1].Text += " " + word
+			return
+		}
+		edits = append(edits, models.SuggestionEdit{Op: op, Text: word})
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add("equal", a[i])
+			i, j = i+1, j+1
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add("delete", a[i])
+			i++
+		default:
+			add("insert", b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add("delete", a[i])
+	}
+	for ; j < len(b); j++ {
+		add("insert", b[j])
+	}
+	return edits
+}
@@ -4,15 +4,26 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 
 	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// recapTranscriptLimit bounds how many of a session's most recent messages are sent for summarization
+const recapTranscriptLimit = 200
+
 type ChatSessionRecapService struct {
 	Repo *repository.ChatSessionRecapRepository
+	// Set on workers that generate recaps when sessions close
+	ChatSessionRepo       *repository.ChatSessionRepository
+	ChatMessageRepo       *repository.ChatMessageRepository
+	AIService             *AIService
+	EventPublisherService *EventPublisherService
 }
 
 func NewChatSessionRecapService(repo *repository.ChatSessionRecapRepository) *ChatSessionRecapService {
@@ -59,3 +70,79 @@ func (s *ChatSessionRecapService) GetLatestRecap(ctx context.Context, sessionID
 		UpdatedAt: recap.UpdatedAt,
 	}, nil
 }
+
+// SummarizeSession generates a recap of a session's transcript with the AI service, stores it and
+// publishes chat_session_recap_created. Sandbox sessions get a canned summary without calling the AI service.
+func (s *ChatSessionRecapService) SummarizeSession(ctx context.Context, sessionID string) (*models.ChatSessionRecap, error) {
+	if s.ChatSessionRepo == nil || s.ChatMessageRepo == nil || s.AIService == nil {
+		return nil, errors.New("recap summarization is not configured")
+	}
+	sid, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.New("invalid session_id")
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, sid)
+	if err != nil {
+		return nil, fmt.Errorf("chat session not found")
+	}
+
+	messages, err := s.ChatMessageRepo.List(ctx, bson.M{"session": sid}, recapTranscriptLimit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transcript: %w", err)
+	}
+	if len(messages) == 0 {
+		return nil, nil
+	}
+	// List returns newest first
+	slices.Reverse(messages)
+	transcript := make([]interface{}, 0, len(messages))
+	for _, m := range messages {
+		transcript = append(transcript, map[string]interface{}{
+			"sender_type": m.SenderType,
+			"text":        m.Text,
+			"created_at":  m.CreatedAt,
+		})
+	}
+
+	var summary *AISessionSummary
+	if session.Test {
+		summary = &AISessionSummary{
+			Summary:    fmt.Sprintf("[sandbox] Session with %d messages", len(messages)),
+			Topics:     []string{},
+			Resolution: "unknown",
+			Sentiment:  "neutral",
+		}
+	} else {
+		summary, err = s.AIService.SummarizeSession(ctx, session.SessionID, transcript)
+		if err != nil {
+			return nil, fmt.Errorf("failed to summarize session: %w", err)
+		}
+	}
+
+	recap := &models.ChatSessionRecap{
+		SessionID: sid,
+		RecapData: map[string]interface{}{
+			"summary":       summary.Summary,
+			"topics":        summary.Topics,
+			"resolution":    summary.Resolution,
+			"sentiment":     summary.Sentiment,
+			"message_count": len(messages),
+			"source":        "ai",
+		},
+	}
+	if err := s.Repo.Create(ctx, recap); err != nil {
+		return nil, fmt.Errorf("failed to create recap: %w", err)
+	}
+
+	if s.EventPublisherService != nil {
+		_, _ = s.EventPublisherService.PublishChatSessionEvent(ctx, models.EventTypeChatSessionRecapCreated, sid.Hex(), map[string]interface{}{
+			"session_id": session.SessionID,
+			"recap_id":   recap.ID.Hex(),
+			"summary":    summary.Summary,
+			"topics":     summary.Topics,
+			"resolution": summary.Resolution,
+			"sentiment":  summary.Sentiment,
+		})
+	}
+	return recap, nil
+}
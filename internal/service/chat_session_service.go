@@ -4,7 +4,10 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/fraiday-org/api-service/internal/api/dto"
@@ -14,6 +17,15 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+const maxSessionTagLength = 64
+
+// sessionAttributeKeyPattern keeps attribute keys safe to use as MongoDB field path segments.
+var sessionAttributeKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// VersionConflictError is returned by sessions and processor configs updated at a version they
+// have moved past; Current is the version to read again from.
+type VersionConflictError = repository.VersionConflictError
+
 type ChatSessionService struct {
 	Repo           *repository.ChatSessionRepository
 	ThreadManager  *ThreadManagerService
@@ -42,14 +54,14 @@ func (s *ChatSessionService) GetOrCreateSessionBySessionID(ctx context.Context,
 	baseSessionID := s.ThreadManager.GetBaseSessionIDForEvent(sessionID)
 	
 	// Check if threading is enabled for this client
-	threadingEnabled := s.ThreadManager.IsThreadingEnabledForClient(ctx, client)
+	threadingEnabled := s.ThreadManager.IsThreadingEnabled(ctx, client, clientChannel)
 	
 	if threadingEnabled {
 		// Only use thread management when threading is explicitly enabled
 		log.Printf("[ChatSessionService] Using thread management for message in session %s", baseSessionID)
 		
 		// Use thread management - this will handle creating threaded sessions
-		// Pass -1 to indicate use client's configured inactivity_minutes
+		// Pass -1 to indicate use the channel's or client's configured inactivity_minutes
 		threadedSession, err := s.ThreadManager.GetOrCreateActiveThread(ctx, sessionID, client, clientChannel, false, -1)
 		if err != nil {
 			return nil, "", err
@@ -72,6 +84,7 @@ func (s *ChatSessionService) GetOrCreateSessionBySessionID(ctx context.Context,
 			Active:        true,
 			Client:        &client.ID,
 			ClientChannel: &clientChannel.ID,
+			Test:          client.IsSandbox(clientChannel),
 		}
 		if err := s.Repo.Create(ctx, session); err != nil {
 			return nil, "", err
@@ -96,6 +109,7 @@ func (s *ChatSessionService) GetOrCreateSessionBySessionID(ctx context.Context,
 		Active:        true,
 		Client:        &client.ID,
 		ClientChannel: &clientChannel.ID,
+		Test:          client.IsSandbox(clientChannel),
 	}
 	if err := s.Repo.Create(ctx, session); err != nil {
 		return nil, "", err
@@ -112,12 +126,100 @@ func (s *ChatSessionService) GetSession(ctx context.Context, id string) (*dto.Ch
 	if err != nil {
 		return nil, err
 	}
+	return toChatSessionResponse(session), nil
+}
+
+// UpdateTags adds and removes tags on a session. Tags are trimmed and lowercased. With version set
+// the tags are only changed while the session is at that version.
+func (s *ChatSessionService) UpdateTags(ctx context.Context, id string, version *int64, add, remove []string) (*dto.ChatSessionResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid session id")
+	}
+	add, err = normalizeSessionTags(add)
+	if err != nil {
+		return nil, err
+	}
+	remove, err = normalizeSessionTags(remove)
+	if err != nil {
+		return nil, err
+	}
+	if len(add) == 0 && len(remove) == 0 {
+		return nil, errors.New("add or remove is required")
+	}
+
+	session, err := s.Repo.UpdateTags(ctx, objID, version, add, remove)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session tags: %w", err)
+	}
+	return toChatSessionResponse(session), nil
+}
+
+// UpdateAttributes merges attributes into a session's custom attributes. Nil values remove the attribute.
+// With version set the attributes are only changed while the session is at that version.
+func (s *ChatSessionService) UpdateAttributes(ctx context.Context, id string, version *int64, attributes map[string]*string) (*dto.ChatSessionResponse, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, errors.New("invalid session id")
+	}
+	if len(attributes) == 0 {
+		return nil, errors.New("attributes must not be empty")
+	}
+
+	set := map[string]string{}
+	var unset []string
+	for key, value := range attributes {
+		if !sessionAttributeKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid attribute key: %q", key)
+		}
+		if value == nil {
+			unset = append(unset, key)
+		} else {
+			set[key] = *value
+		}
+	}
+
+	session, err := s.Repo.UpdateAttributesAtVersion(ctx, objID, version, set, unset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session attributes: %w", err)
+	}
+	return toChatSessionResponse(session), nil
+}
+
+func toChatSessionResponse(session *models.ChatSession) *dto.ChatSessionResponse {
+	tags := session.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	attributes := session.Attributes
+	if attributes == nil {
+		attributes = map[string]string{}
+	}
 	return &dto.ChatSessionResponse{
-		ID:        session.ID.Hex(),
-		CreatedAt: session.CreatedAt,
-		UpdatedAt: session.UpdatedAt,
-		Active:    session.Active,
-	}, nil
+		ID:         session.ID.Hex(),
+		CreatedAt:  session.CreatedAt,
+		UpdatedAt:  session.UpdatedAt,
+		Active:     session.Active,
+		State:      string(session.CurrentState()),
+		Tags:       tags,
+		Attributes: attributes,
+		Version:    session.Version,
+	}
+}
+
+func normalizeSessionTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" {
+			continue
+		}
+		if len(tag) > maxSessionTagLength {
+			return nil, fmt.Errorf("tag %q exceeds %d characters", tag, maxSessionTagLength)
+		}
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
 }
 
 type ListSessionsParams struct {
@@ -126,8 +228,11 @@ type ListSessionsParams struct {
 	UserID        *string
 	SessionID     *string
 	Active        *bool
+	State         *string
 	StartDate     *time.Time
 	EndDate       *time.Time
+	Tags          []string          // Sessions must carry all of these tags
+	Attributes    map[string]string // Sessions must have these exact attribute values
 	Skip          int64
 	Limit         int64
 }
@@ -147,6 +252,14 @@ func (s *ChatSessionService) ListSessions(ctx context.Context, params ListSessio
 	if params.Active != nil {
 		filter["active"] = *params.Active
 	}
+	if params.State != nil {
+		if models.SessionState(*params.State) == models.SessionStateOpen {
+			// Sessions created before lifecycle states have no state field
+			filter["state"] = bson.M{"$in": bson.A{models.SessionStateOpen, nil}}
+		} else {
+			filter["state"] = *params.State
+		}
+	}
 	if params.StartDate != nil && params.EndDate != nil {
 		filter["updated_at"] = bson.M{"$gte": *params.StartDate, "$lte": *params.EndDate}
 	} else if params.StartDate != nil {
@@ -157,6 +270,14 @@ func (s *ChatSessionService) ListSessions(ctx context.Context, params ListSessio
 	if params.SessionID != nil {
 		filter["session_id"] = bson.M{"$regex": *params.SessionID, "$options": "i"}
 	}
+	if tags, err := normalizeSessionTags(params.Tags); err == nil && len(tags) > 0 {
+		filter["tags"] = bson.M{"$all": tags}
+	}
+	for key, value := range params.Attributes {
+		if sessionAttributeKeyPattern.MatchString(key) {
+			filter["attributes."+key] = value
+		}
+	}
 	// UserID filtering would require a lookup in the messages collection, which is not implemented here.
 
 	sessions, total, err := s.Repo.ListWithFilters(ctx, filter, params.Skip, params.Limit, bson.D{{"updated_at", -1}})
@@ -186,7 +307,11 @@ func (s *ChatSessionService) ListSessions(ctx context.Context, params ListSessio
 			Client:        client,
 			ClientChannel: channel,
 			Participants:  s.Participants,
-			Handover:      false, // Handover detection not implemented in this version
+			Handover:      s.Handover.IsOpen(),
+			State:         string(s.CurrentState()),
+			Tags:          s.Tags,
+			Attributes:    s.Attributes,
+			Test:          s.Test,
 		}
 	}
 	return resp, nil
@@ -196,3 +321,13 @@ func (s *ChatSessionService) ListSessions(ctx context.Context, params ListSessio
 func (s *ChatSessionService) GetSessionByID(ctx context.Context, id primitive.ObjectID) (*models.ChatSession, error) {
 	return s.Repo.GetByID(ctx, id)
 }
+
+// LookupSession retrieves a session by its MongoDB ObjectID or, failing that, by its session_id
+func (s *ChatSessionService) LookupSession(ctx context.Context, ref string) (*models.ChatSession, error) {
+	if id, err := primitive.ObjectIDFromHex(ref); err == nil {
+		if session, err := s.Repo.GetByID(ctx, id); err == nil {
+			return session, nil
+		}
+	}
+	return s.Repo.GetBySessionID(ctx, ref)
+}
@@ -6,14 +6,27 @@ import (
 	"errors"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
 	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ErrThreadNotFound is returned when a conversation has no thread, or no session, with the given ID.
+var ErrThreadNotFound = errors.New("thread not found")
+
 type ChatSessionThreadService struct {
 	Repo *repository.ChatSessionThreadRepository
+	// ChatSessionRepo, ChatMessageRepo and ThreadManager are needed to merge and split threads and
+	// to list their messages
+	ChatSessionRepo *repository.ChatSessionRepository
+	ChatMessageRepo *repository.ChatMessageRepository
+	ThreadManager   *ThreadManagerService
+	// EventPublisherService is optional; when set, merges and splits publish audit events
+	EventPublisherService *EventPublisherService
 }
 
 func NewChatSessionThreadService(repo *repository.ChatSessionThreadRepository) *ChatSessionThreadService {
@@ -59,15 +72,8 @@ func (s *ChatSessionThreadService) ListThreads(ctx context.Context, sessionID st
 		Threads: make([]dto.ThreadResponse, len(threads)),
 		Total:   len(threads),
 	}
-	for i, t := range threads {
-		resp.Threads[i] = dto.ThreadResponse{
-			ThreadID:        t.ThreadID,
-			ThreadSessionID: t.ThreadSessionID,
-			ParentSessionID: t.ParentSessionID,
-			ChatSessionID:   t.ChatSessionID.Hex(),
-			Active:          t.Active,
-			LastActivity:    t.LastActivity,
-		}
+	for i := range threads {
+		resp.Threads[i] = threadResponse(&threads[i])
 	}
 	return resp, nil
 }
@@ -98,3 +104,255 @@ func (s *ChatSessionThreadService) CloseThread(ctx context.Context, sessionID st
 	}
 	return s.Repo.CloseThread(ctx, sid, threadID)
 }
+
+// MergeThread moves every message of a thread into another thread of the same conversation and
+// retires the thread. sessionID is the ID of any chat session of the conversation. The target
+// thread takes over the merged thread's activity, so a merged active thread stays the one new
+// messages go to.
+func (s *ChatSessionThreadService) MergeThread(ctx context.Context, sessionID, threadID, targetThreadID, performedBy string) (*dto.ThreadMergeResponse, error) {
+	if threadID == targetThreadID {
+		return nil, errors.New("a thread can't be merged into itself")
+	}
+	parentSessionID, err := s.conversationID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.getThread(ctx, parentSessionID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.getThread(ctx, parentSessionID, targetThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if source.MergedInto != "" || target.MergedInto != "" {
+		return nil, errors.New("merged threads can't be merged again")
+	}
+
+	// Messages move before the thread is retired, so a failed merge can be retried
+	moved, err := s.ChatMessageRepo.MoveAfter(ctx, source.ChatSessionID, target.ChatSessionID, nil)
+	if err != nil {
+		return nil, err
+	}
+	retired, err := s.Repo.MarkMerged(ctx, source.ID, target.ThreadID)
+	if err != nil {
+		return nil, err
+	}
+	if !retired {
+		return nil, errors.New("merged threads can't be merged again")
+	}
+	if source.LastActivity.After(target.LastActivity) {
+		target.LastActivity = source.LastActivity
+	}
+	target.Active = target.Active || source.Active
+	if err := s.Repo.SetActivity(ctx, target.ID, target.Active, target.LastActivity); err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, models.EventTypeThreadMerged, target, map[string]interface{}{
+		"source_thread_id":  source.ThreadID,
+		"source_session_id": source.ThreadSessionID,
+		"messages_moved":    moved,
+		"performed_by":      performedBy,
+	})
+	return &dto.ThreadMergeResponse{
+		Thread:         threadResponse(target),
+		SourceThreadID: source.ThreadID,
+		MessagesMoved:  moved,
+	}, nil
+}
+
+// SplitThread moves the messages of a thread that come after afterMessageID into a new thread of
+// the same conversation. The new thread carries on the thread's activity, so when the thread was
+// active, new messages go to the new thread.
+func (s *ChatSessionThreadService) SplitThread(ctx context.Context, sessionID, threadID, afterMessageID, performedBy string) (*dto.ThreadSplitResponse, error) {
+	parentSessionID, err := s.conversationID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.getThread(ctx, parentSessionID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if source.MergedInto != "" {
+		return nil, errors.New("merged threads can't be split")
+	}
+	messageID, err := primitive.ObjectIDFromHex(afterMessageID)
+	if err != nil {
+		return nil, errors.New("invalid after_message_id")
+	}
+	message, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil || message.SessionID != source.ChatSessionID {
+		return nil, errors.New("after_message_id is not a message of the thread")
+	}
+	remaining, err := s.ChatMessageRepo.CountAfter(ctx, source.ChatSessionID, message)
+	if err != nil {
+		return nil, err
+	}
+	if remaining == 0 {
+		return nil, errors.New("no messages come after after_message_id")
+	}
+
+	sourceSession, err := s.ChatSessionRepo.GetByID(ctx, source.ChatSessionID)
+	if err != nil {
+		return nil, ErrThreadNotFound
+	}
+	newThreadID := primitive.NewObjectID().Hex()[:8]
+	session := &models.ChatSession{
+		SessionID:     s.ThreadManager.FormatThreadSessionID(parentSessionID, newThreadID),
+		Client:        sourceSession.Client,
+		ClientChannel: sourceSession.ClientChannel,
+		Test:          sourceSession.Test,
+	}
+	if err := s.ChatSessionRepo.Create(ctx, session); err != nil {
+		return nil, err
+	}
+	thread := &models.ChatSessionThread{
+		ThreadID:        newThreadID,
+		ThreadSessionID: session.SessionID,
+		ParentSessionID: parentSessionID,
+		ChatSessionID:   session.ID,
+	}
+	if err := s.Repo.Create(ctx, thread); err != nil {
+		return nil, err
+	}
+
+	moved, err := s.ChatMessageRepo.MoveAfter(ctx, source.ChatSessionID, session.ID, message)
+	if err != nil {
+		return nil, err
+	}
+	// The new thread holds the latest messages, the thread ends at the split
+	thread.Active, thread.LastActivity = source.Active, source.LastActivity
+	if err := s.Repo.SetActivity(ctx, thread.ID, thread.Active, thread.LastActivity); err != nil {
+		return nil, err
+	}
+	source.Active, source.LastActivity = false, message.CreatedAt
+	if err := s.Repo.SetActivity(ctx, source.ID, source.Active, source.LastActivity); err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, models.EventTypeThreadSplit, thread, map[string]interface{}{
+		"source_thread_id":  source.ThreadID,
+		"source_session_id": source.ThreadSessionID,
+		"after_message_id":  afterMessageID,
+		"messages_moved":    moved,
+		"performed_by":      performedBy,
+	})
+	return &dto.ThreadSplitResponse{
+		Thread:        threadResponse(source),
+		NewThread:     threadResponse(thread),
+		MessagesMoved: moved,
+	}, nil
+}
+
+// conversationID returns the parent session_id shared by the threads of the conversation the chat
+// session with ID sessionID belongs to.
+func (s *ChatSessionThreadService) conversationID(ctx context.Context, sessionID string) (string, error) {
+	sid, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return "", errors.New("invalid session_id")
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, sid)
+	if err != nil {
+		return "", ErrThreadNotFound
+	}
+	parentSessionID, _ := s.ThreadManager.ParseSessionID(session.SessionID)
+	return parentSessionID, nil
+}
+
+// ListThreadMessages returns up to lastN of the newest messages of a thread, or all of them when
+// lastN is 0. sessionID is the ID of any chat session of the conversation.
+func (s *ChatSessionThreadService) ListThreadMessages(ctx context.Context, sessionID, threadID string, lastN int64) ([]models.ChatMessage, error) {
+	parentSessionID, err := s.conversationID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	thread, err := s.getThread(ctx, parentSessionID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.ChatMessageRepo.List(ctx, bson.M{"session": thread.ChatSessionID}, lastN)
+}
+
+// ListMessagesByThread returns the messages of the conversation of a chat session grouped by
+// thread, most recently active thread first, reading every thread's messages in one query. lastN
+// bounds the newest messages returned across threads; threads without any of them are listed with
+// no messages. Merged threads, whose messages moved, are left out.
+func (s *ChatSessionThreadService) ListMessagesByThread(ctx context.Context, sessionID string, lastN int64) (*dto.ThreadedMessagesResponse, error) {
+	sid, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.New("invalid session_id")
+	}
+	parentSessionID, err := s.conversationID(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	threads, err := s.Repo.ListByParentSessionID(ctx, parentSessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ThreadedMessagesResponse{ParentSessionID: parentSessionID, Threads: []dto.ThreadMessages{}}
+	sessions := bson.A{}
+	group := map[primitive.ObjectID]int{}
+	for i := range threads {
+		if threads[i].MergedInto != "" {
+			continue
+		}
+		group[threads[i].ChatSessionID] = len(resp.Threads)
+		sessions = append(sessions, threads[i].ChatSessionID)
+		resp.Threads = append(resp.Threads, dto.ThreadMessages{
+			Thread:   threadResponse(&threads[i]),
+			Messages: []models.ChatMessage{},
+		})
+	}
+	// A session of a conversation without threads holds the messages itself
+	if _, ok := group[sid]; !ok {
+		sessions = append(sessions, sid)
+	}
+
+	messages, err := s.ChatMessageRepo.List(ctx, bson.M{"session": bson.M{"$in": sessions}}, lastN)
+	if err != nil {
+		return nil, err
+	}
+	for _, msg := range messages {
+		if i, ok := group[msg.SessionID]; ok {
+			resp.Threads[i].Messages = append(resp.Threads[i].Messages, msg)
+		} else {
+			resp.Messages = append(resp.Messages, msg)
+		}
+	}
+	resp.Total = len(messages)
+	return resp, nil
+}
+
+func (s *ChatSessionThreadService) getThread(ctx context.Context, parentSessionID, threadID string) (*models.ChatSessionThread, error) {
+	thread, err := s.Repo.GetByThreadID(ctx, parentSessionID, threadID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrThreadNotFound
+	}
+	return thread, err
+}
+
+// publish publishes an audit event about thread on the thread's chat session.
+func (s *ChatSessionThreadService) publish(ctx context.Context, eventType models.EventType, thread *models.ChatSessionThread, data map[string]interface{}) {
+	if s.EventPublisherService == nil {
+		return
+	}
+	data["session_id"] = thread.ThreadSessionID
+	data["parent_session_id"] = thread.ParentSessionID
+	data["thread_id"] = thread.ThreadID
+	_, _ = s.EventPublisherService.PublishChatSessionEvent(ctx, eventType, thread.ChatSessionID.Hex(), data)
+}
+
+func threadResponse(t *models.ChatSessionThread) dto.ThreadResponse {
+	return dto.ThreadResponse{
+		ThreadID:        t.ThreadID,
+		ThreadSessionID: t.ThreadSessionID,
+		ParentSessionID: t.ParentSessionID,
+		ChatSessionID:   t.ChatSessionID.Hex(),
+		Active:          t.Active,
+		LastActivity:    t.LastActivity,
+		MergedInto:      t.MergedInto,
+	}
+}
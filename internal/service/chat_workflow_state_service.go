@@ -0,0 +1,80 @@
+// Package service provides business logic for checkpointing chat workflows.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ChatWorkflowStateService records how far the chat workflow of each message got, so a retry
+// picks up after the last completed step.
+type ChatWorkflowStateService struct {
+	Repo *repository.ChatWorkflowStateRepository
+}
+
+// NewChatWorkflowStateService creates a new ChatWorkflowStateService.
+func NewChatWorkflowStateService(repo *repository.ChatWorkflowStateRepository) *ChatWorkflowStateService {
+	return &ChatWorkflowStateService{Repo: repo}
+}
+
+// Begin records an attempt at the workflow of a message and returns its state, with the
+// checkpoints of earlier attempts.
+func (s *ChatWorkflowStateService) Begin(ctx context.Context, messageID, sessionID string, suggestionMode bool) (*models.ChatWorkflowState, error) {
+	return s.Repo.Begin(ctx, messageID, sessionID, suggestionMode)
+}
+
+// CheckpointAI records the AI's answer and the reply drafted from it once the AI call completed.
+func (s *ChatWorkflowStateService) CheckpointAI(ctx context.Context, state *models.ChatWorkflowState, response *AIResponse, draft models.ChatWorkflowDraft) error {
+	raw, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("failed to encode AI response: %w", err)
+	}
+	state.Step, state.AIResponse, state.Draft = models.ChatWorkflowStepAICall, string(raw), &draft
+	return s.Repo.Update(ctx, state.ID, bson.M{"step": state.Step, "ai_response": state.AIResponse, "draft": state.Draft})
+}
+
+// CheckpointDraft records the reply as the after_ai hooks left it.
+func (s *ChatWorkflowStateService) CheckpointDraft(ctx context.Context, state *models.ChatWorkflowState, draft models.ChatWorkflowDraft) error {
+	state.Step, state.Draft = models.ChatWorkflowStepAfterAI, &draft
+	return s.Repo.Update(ctx, state.ID, bson.M{"step": state.Step, "draft": state.Draft})
+}
+
+// CheckpointPersisted records the stored reply.
+func (s *ChatWorkflowStateService) CheckpointPersisted(ctx context.Context, state *models.ChatWorkflowState, responseMessageID primitive.ObjectID) error {
+	state.Step, state.ResponseMessageID = models.ChatWorkflowStepPersist, &responseMessageID
+	return s.Repo.Update(ctx, state.ID, bson.M{"step": state.Step, "response_message": responseMessageID})
+}
+
+// Complete marks the workflow done. stoppedBy names what ended it early, if anything.
+func (s *ChatWorkflowStateService) Complete(ctx context.Context, state *models.ChatWorkflowState, stoppedBy string) error {
+	state.Status, state.StoppedBy = models.ExecutionStatusCompleted, stoppedBy
+	update := bson.M{"status": state.Status, "error": ""}
+	if stoppedBy != "" {
+		update["stopped_by"] = stoppedBy
+	} else {
+		state.Step = models.ChatWorkflowStepPublish
+		update["step"] = state.Step
+	}
+	return s.Repo.Update(ctx, state.ID, update)
+}
+
+// Fail records why an attempt failed. The checkpoints stay for the next attempt.
+func (s *ChatWorkflowStateService) Fail(ctx context.Context, state *models.ChatWorkflowState, cause error) error {
+	state.Status, state.Error = models.ExecutionStatusFailed, cause.Error()
+	return s.Repo.Update(ctx, state.ID, bson.M{"status": state.Status, "error": state.Error})
+}
+
+// RestoreAIResponse decodes the AI answer checkpointed in state.
+func (s *ChatWorkflowStateService) RestoreAIResponse(state *models.ChatWorkflowState) (*AIResponse, error) {
+	var response AIResponse
+	if err := json.Unmarshal([]byte(state.AIResponse), &response); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpointed AI response: %w", err)
+	}
+	return &response, nil
+}
@@ -0,0 +1,104 @@
+// Package service provides business logic for per-client AI model and prompt configuration.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	maxAIConfigTemperature  = 2.0
+	maxAIConfigMaxTokens    = 100000
+	maxAIConfigSystemPrompt = 20000
+)
+
+// ClientAIConfigService manages the AI model and prompt each client is answered with.
+type ClientAIConfigService struct {
+	ClientRepo      *repository.ClientRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	Invalidator     CacheInvalidator
+	Clients         ClientCache
+}
+
+// NewClientAIConfigService creates a new ClientAIConfigService.
+func NewClientAIConfigService(clientRepo *repository.ClientRepository, chatSessionRepo *repository.ChatSessionRepository) *ClientAIConfigService {
+	return &ClientAIConfigService{ClientRepo: clientRepo, ChatSessionRepo: chatSessionRepo}
+}
+
+// GetConfig returns a client's AI config, or nil if none is configured.
+func (s *ClientAIConfigService) GetConfig(ctx context.Context, clientID string) (*models.ClientAIConfig, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return client.AIConfig, nil
+}
+
+// SetConfig validates and stores a client's AI config, replacing any existing one.
+func (s *ClientAIConfigService) SetConfig(ctx context.Context, clientID string, config *models.ClientAIConfig) error {
+	if err := validateAIConfig(config); err != nil {
+		return err
+	}
+
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"ai_config": config}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// DeleteConfig removes a client's AI config, so it is answered with the AI service's defaults.
+func (s *ClientAIConfigService) DeleteConfig(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"ai_config": nil}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// validateAIConfig checks an AI config and trims its names.
+func validateAIConfig(config *models.ClientAIConfig) error {
+	config.Provider = strings.TrimSpace(config.Provider)
+	config.Model = strings.TrimSpace(config.Model)
+	if config.Model == "" {
+		return errors.New("model is required")
+	}
+	if t := config.Temperature; t != nil && (*t < 0 || *t > maxAIConfigTemperature) {
+		return fmt.Errorf("temperature must be between 0 and %g", maxAIConfigTemperature)
+	}
+	if config.MaxTokens < 0 || config.MaxTokens > maxAIConfigMaxTokens {
+		return fmt.Errorf("max_tokens must be between 0 and %d", maxAIConfigMaxTokens)
+	}
+	if len(config.SystemPrompt) > maxAIConfigSystemPrompt {
+		return fmt.Errorf("system_prompt must not exceed %d characters", maxAIConfigSystemPrompt)
+	}
+	return nil
+}
+
+func (s *ClientAIConfigService) invalidate(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
+
+// ForSession returns the enabled AI config of the session's client, or nil when the client has
+// none or can't be resolved.
+func (s *ClientAIConfigService) ForSession(ctx context.Context, sessionID primitive.ObjectID) *models.ClientAIConfig {
+	session, err := s.ChatSessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.Client == nil {
+		return nil
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client)
+	if err != nil || client.AIConfig == nil || !client.AIConfig.Enabled {
+		return nil
+	}
+	return client.AIConfig
+}
@@ -0,0 +1,225 @@
+// Package service provides business logic for caching client and channel lookups.
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+// DefaultClientCacheTTL is how long a client or channel is served from memory before being reread
+const DefaultClientCacheTTL = 30 * time.Second
+
+// ClientCache looks up clients and their channels, which every message needs, without reading
+// MongoDB each time. Returned documents are copies, but their maps and slices are shared, so
+// callers must not modify those.
+type ClientCache interface {
+	GetClient(ctx context.Context, clientID string) (*models.Client, error)
+	GetClientByID(ctx context.Context, id primitive.ObjectID) (*models.Client, error)
+	GetChannel(ctx context.Context, id primitive.ObjectID) (*models.ClientChannel, error)
+	GetChannelByType(ctx context.Context, clientID primitive.ObjectID, channelType string) (*models.ClientChannel, error)
+}
+
+// MemoryClientCache is a ClientCache held in process memory. Entries expire after TTL, or earlier
+// when the cache bus reports that the client or its channels changed. Lookups that find nothing
+// aren't cached, so new clients and channels are seen at once.
+type MemoryClientCache struct {
+	ClientRepo  *repository.ClientRepository
+	ChannelRepo *repository.ClientChannelRepository
+	TTL         time.Duration
+
+	mu           sync.Mutex
+	clients      map[primitive.ObjectID]cachedClient
+	clientIDs    map[string]primitive.ObjectID
+	channels     map[primitive.ObjectID]cachedChannel
+	channelTypes map[channelTypeKey]primitive.ObjectID
+	// generation counts invalidations, so a read that raced one isn't cached
+	generation uint64
+}
+
+type cachedClient struct {
+	client    models.Client
+	expiresAt time.Time
+}
+
+type cachedChannel struct {
+	channel   models.ClientChannel
+	expiresAt time.Time
+}
+
+type channelTypeKey struct {
+	client      primitive.ObjectID
+	channelType string
+}
+
+// NewMemoryClientCache creates a MemoryClientCache reading through the given repositories.
+func NewMemoryClientCache(clientRepo *repository.ClientRepository, channelRepo *repository.ClientChannelRepository, ttl time.Duration) *MemoryClientCache {
+	return &MemoryClientCache{
+		ClientRepo:   clientRepo,
+		ChannelRepo:  channelRepo,
+		TTL:          ttl,
+		clients:      make(map[primitive.ObjectID]cachedClient),
+		clientIDs:    make(map[string]primitive.ObjectID),
+		channels:     make(map[primitive.ObjectID]cachedChannel),
+		channelTypes: make(map[channelTypeKey]primitive.ObjectID),
+	}
+}
+
+// GetClient returns the client with client_id clientID.
+func (c *MemoryClientCache) GetClient(ctx context.Context, clientID string) (*models.Client, error) {
+	c.mu.Lock()
+	id, ok := c.clientIDs[clientID]
+	c.mu.Unlock()
+	if ok {
+		if client, ok := c.cachedClient(id); ok {
+			return client, nil
+		}
+	}
+	return c.loadClient(func() (*models.Client, error) { return c.ClientRepo.GetByClientID(ctx, clientID) })
+}
+
+// GetClientByID returns the client with ObjectID id.
+func (c *MemoryClientCache) GetClientByID(ctx context.Context, id primitive.ObjectID) (*models.Client, error) {
+	if client, ok := c.cachedClient(id); ok {
+		return client, nil
+	}
+	return c.loadClient(func() (*models.Client, error) { return c.ClientRepo.GetByID(ctx, id) })
+}
+
+// GetChannel returns the channel with ObjectID id.
+func (c *MemoryClientCache) GetChannel(ctx context.Context, id primitive.ObjectID) (*models.ClientChannel, error) {
+	if channel, ok := c.cachedChannel(id); ok {
+		return channel, nil
+	}
+	return c.loadChannel(func() (*models.ClientChannel, error) { return c.ChannelRepo.GetByID(ctx, id) })
+}
+
+// GetChannelByType returns the channel of type channelType belonging to the client with ObjectID
+// clientID.
+func (c *MemoryClientCache) GetChannelByType(ctx context.Context, clientID primitive.ObjectID, channelType string) (*models.ClientChannel, error) {
+	c.mu.Lock()
+	id, ok := c.channelTypes[channelTypeKey{clientID, channelType}]
+	c.mu.Unlock()
+	if ok {
+		if channel, ok := c.cachedChannel(id); ok {
+			return channel, nil
+		}
+	}
+	return c.loadChannel(func() (*models.ClientChannel, error) {
+		return c.ChannelRepo.GetByFilter(ctx, bson.M{"client": clientID, "channel_type": channelType})
+	})
+}
+
+// Forget drops the entries an invalidation describes: a client by client_id, or every channel of
+// a client by the client's hex ObjectID. Its signature lets it subscribe to the cache bus directly.
+func (c *MemoryClientCache) Forget(inv cache.Invalidation) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	switch inv.Kind {
+	case cache.KindClient:
+		if id, ok := c.clientIDs[inv.Key]; ok {
+			delete(c.clients, id)
+			delete(c.clientIDs, inv.Key)
+		}
+	case cache.KindClientChannel:
+		for id, entry := range c.channels {
+			if entry.channel.ClientID.Hex() == inv.Key {
+				delete(c.channels, id)
+				delete(c.channelTypes, channelTypeKey{entry.channel.ClientID, string(entry.channel.ChannelType)})
+			}
+		}
+	}
+}
+
+// Invalidate drops entries from this cache only. It stands in for the cache bus when there is
+// none, so changes still apply at once on the node that made them.
+func (c *MemoryClientCache) Invalidate(_ context.Context, kind, key string) error {
+	c.Forget(cache.Invalidation{Kind: kind, Key: key})
+	return nil
+}
+
+func (c *MemoryClientCache) cachedClient(id primitive.ObjectID) (*models.Client, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.clients[id]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	client := entry.client
+	return &client, true
+}
+
+func (c *MemoryClientCache) cachedChannel(id primitive.ObjectID) (*models.ClientChannel, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.channels[id]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+	channel := entry.channel
+	return &channel, true
+}
+
+// loadClient reads a client with load and caches it, unless it was invalidated meanwhile.
+func (c *MemoryClientCache) loadClient(load func() (*models.Client, error)) (*models.Client, error) {
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	client, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.clients[client.ID] = cachedClient{client: *client, expiresAt: time.Now().Add(c.TTL)}
+		c.clientIDs[client.ClientID] = client.ID
+	}
+	return client, nil
+}
+
+// loadChannel reads a channel with load and caches it, unless it was invalidated meanwhile.
+func (c *MemoryClientCache) loadChannel(load func() (*models.ClientChannel, error)) (*models.ClientChannel, error) {
+	c.mu.Lock()
+	generation := c.generation
+	c.mu.Unlock()
+
+	channel, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.channels[channel.ID] = cachedChannel{channel: *channel, expiresAt: time.Now().Add(c.TTL)}
+		c.channelTypes[channelTypeKey{channel.ClientID, string(channel.ChannelType)}] = channel.ID
+	}
+	return channel, nil
+}
+
+// clientByID reads a client through clients when it is set, and from repo otherwise.
+func clientByID(ctx context.Context, clients ClientCache, repo *repository.ClientRepository, id primitive.ObjectID) (*models.Client, error) {
+	if clients != nil {
+		return clients.GetClientByID(ctx, id)
+	}
+	return repo.GetByID(ctx, id)
+}
+
+// channelByID reads a channel through clients when it is set, and from repo otherwise.
+func channelByID(ctx context.Context, clients ClientCache, repo *repository.ClientChannelRepository, id primitive.ObjectID) (*models.ClientChannel, error) {
+	if clients != nil {
+		return clients.GetChannel(ctx, id)
+	}
+	return repo.GetByID(ctx, id)
+}
@@ -4,18 +4,25 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/cache"
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/utils"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // ClientChannelService encapsulates business logic for client channels.
 type ClientChannelService struct {
-	Repo       *repository.ClientChannelRepository
-	ClientRepo *repository.ClientRepository
+	Repo        *repository.ClientChannelRepository
+	ClientRepo  *repository.ClientRepository
+	Invalidator CacheInvalidator
+	// Secrets encrypts the credentials stored with channels; without it none can be stored
+	Secrets *utils.SecretBox
 }
 
 // NewClientChannelService creates a new ClientChannelService.
@@ -45,17 +52,27 @@ func (s *ClientChannelService) CreateChannel(ctx context.Context, clientID strin
 	if req.IsActive != nil {
 		channel.IsActive = *req.IsActive
 	}
+	if req.Sandbox != nil {
+		channel.Sandbox = *req.Sandbox
+	}
+	channel.Capabilities = req.Capabilities
+	channel.ThreadConfig = req.ThreadConfig
+	if req.SlackAI != nil {
+		channel.SlackAI = &models.SlackAIConfig{URL: req.SlackAI.URL, WorkflowID: req.SlackAI.WorkflowID, Token: req.SlackAI.Token}
+	}
+	if err := channel.ValidateConfig(); err != nil {
+		return nil, err
+	}
+	if err := s.sealSlackAI(channel.SlackAI); err != nil {
+		return nil, err
+	}
 
 	if err := s.Repo.Create(ctx, channel); err != nil {
 		return nil, err
 	}
 
-	return &dto.ClientChannelResponse{
-		ID:            channel.ID.Hex(),
-		ChannelType:   channel.ChannelType,
-		ChannelConfig: channel.ChannelConfig,
-		IsActive:      channel.IsActive,
-	}, nil
+	response := channelResponse(channel)
+	return &response, nil
 }
 
 // ListChannels retrieves all active channels for a client.
@@ -78,13 +95,8 @@ func (s *ClientChannelService) ListChannels(ctx context.Context, clientID string
 	}
 
 	resp := make([]dto.ClientChannelResponse, len(channels))
-	for i, c := range channels {
-		resp[i] = dto.ClientChannelResponse{
-			ID:            c.ID.Hex(),
-			ChannelType:   c.ChannelType,
-			ChannelConfig: c.ChannelConfig,
-			IsActive:      c.IsActive,
-		}
+	for i := range channels {
+		resp[i] = channelResponse(&channels[i])
 	}
 
 	return resp, nil
@@ -114,28 +126,64 @@ func (s *ClientChannelService) UpdateChannel(ctx context.Context, channelID stri
 		return nil, errors.New("invalid channel ID")
 	}
 
+	// An empty slack_ai removes the channel's workflow
+	var slackAI *models.SlackAIConfig
+	if req.SlackAI != nil && *req.SlackAI != (dto.SlackAIConfigRequest{}) {
+		slackAI = &models.SlackAIConfig{URL: req.SlackAI.URL, WorkflowID: req.SlackAI.WorkflowID, Token: req.SlackAI.Token}
+	}
+
+	channelConfig := req.ChannelConfig
+	if channelConfig != nil {
+		existing, err := s.Repo.GetByID(ctx, channelObjID)
+		if err != nil {
+			return nil, err
+		}
+		// Credentials read back masked and written back unchanged keep their stored values
+		channelConfig = secrets.UnmaskConfig(channelConfig, existing.ChannelConfig)
+		channelType := req.ChannelType
+		if channelType == "" {
+			channelType = existing.ChannelType
+		}
+		candidate := models.ClientChannel{ChannelType: channelType, ChannelConfig: channelConfig, Capabilities: req.Capabilities, ThreadConfig: req.ThreadConfig, SlackAI: slackAI}
+		if err := candidate.ValidateConfig(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.sealSlackAI(slackAI); err != nil {
+		return nil, err
+	}
+
 	update := bson.M{}
 	if req.ChannelType != "" {
 		update["channel_type"] = req.ChannelType
 	}
-	if req.ChannelConfig != nil {
-		update["channel_config"] = req.ChannelConfig
+	if channelConfig != nil {
+		update["channel_config"] = channelConfig
 	}
 	if req.IsActive != nil {
 		update["is_active"] = *req.IsActive
 	}
+	if req.Sandbox != nil {
+		update["sandbox"] = *req.Sandbox
+	}
+	if req.Capabilities != nil {
+		update["capabilities"] = req.Capabilities
+	}
+	if req.ThreadConfig != nil {
+		update["thread_config"] = req.ThreadConfig
+	}
+	if req.SlackAI != nil {
+		update["slack_ai"] = slackAI
+	}
 
 	updated, err := s.Repo.Update(ctx, channelObjID, update)
 	if err != nil {
 		return nil, err
 	}
+	s.invalidate(ctx, updated)
 
-	return &dto.ClientChannelResponse{
-		ID:            updated.ID.Hex(),
-		ChannelType:   updated.ChannelType,
-		ChannelConfig: updated.ChannelConfig,
-		IsActive:      updated.IsActive,
-	}, nil
+	response := channelResponse(updated)
+	return &response, nil
 }
 
 // DeleteChannel soft deletes a client channel by setting is_active to false.
@@ -146,6 +194,49 @@ func (s *ClientChannelService) DeleteChannel(ctx context.Context, channelID stri
 	}
 
 	update := bson.M{"is_active": false}
-	_, err = s.Repo.Update(ctx, channelObjID, update)
-	return err
+	updated, err := s.Repo.Update(ctx, channelObjID, update)
+	if err != nil {
+		return err
+	}
+	s.invalidate(ctx, updated)
+	return nil
+}
+
+// channelResponse converts a client channel to its API representation, with the credentials in
+// its channel_config masked.
+func channelResponse(channel *models.ClientChannel) dto.ClientChannelResponse {
+	return dto.ClientChannelResponse{
+		ID:            channel.ID.Hex(),
+		ChannelType:   channel.ChannelType,
+		ChannelConfig: secrets.MaskConfig(channel.ChannelConfig),
+		IsActive:      channel.IsActive,
+		Sandbox:       channel.Sandbox,
+		Capabilities:  channel.EffectiveCapabilities(),
+		ThreadConfig:  channel.ThreadConfig,
+		SlackAI:       channel.SlackAI,
+	}
+}
+
+// sealSlackAI encrypts the token of a Slack AI workflow before it is stored.
+func (s *ClientChannelService) sealSlackAI(slackAI *models.SlackAIConfig) error {
+	if slackAI == nil {
+		return nil
+	}
+	if s.Secrets == nil {
+		return fmt.Errorf("slack_ai can't be stored: %w", utils.ErrNoEncryptionKey)
+	}
+	token, err := s.Secrets.Seal(slackAI.Token)
+	if err != nil {
+		return err
+	}
+	slackAI.Token = token
+	return nil
+}
+
+// invalidate evicts the channels of the updated channel's client from caches cluster-wide.
+func (s *ClientChannelService) invalidate(ctx context.Context, channel *models.ClientChannel) {
+	if s.Invalidator == nil || channel == nil {
+		return
+	}
+	_ = s.Invalidator.Invalidate(ctx, cache.KindClientChannel, channel.ClientID.Hex())
 }
\ No newline at end of file
@@ -0,0 +1,121 @@
+// Package service provides business logic for finding the client that records belong to.
+package service
+
+import (
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+// ErrOwnerNotFound is returned for references to clients or records that don't exist
+var ErrOwnerNotFound = errors.New("not found")
+
+// ownerReference is where the record a route parameter names keeps its owner.
+type ownerReference struct {
+	collection string
+	field      string
+	// parent, when set, is the parameter naming the record that field refers to, in place of a client
+	parent string
+}
+
+// ownerReferences covers the route parameters naming records of a single client. Scheduled
+// messages refer to their client by client_id, the other records by id.
+var ownerReferences = map[string]ownerReference{
+	"session_id":      {collection: "chat_sessions", field: "client"},
+	"message_id":      {collection: "chat_messages", field: "session", parent: "session_id"},
+	"channel_id":      {collection: "client_channels", field: "client"},
+	"attachment_id":   {collection: "attachments.files", field: "metadata.channel_id", parent: "channel_id"},
+	"suggestion_id":   {collection: models.ChatMessageSuggestion{}.TableName(), field: "client"},
+	"scheduled_id":    {collection: models.ScheduledMessage{}.TableName(), field: "client_id"},
+	"simulation_id":   {collection: models.Simulation{}.TableName(), field: "client"},
+	"import_id":       {collection: models.MessageImport{}.TableName(), field: "client"},
+	"csat_session_id": {collection: models.CSATSession{}.TableName(), field: "client"},
+	"config_id":       {collection: models.EventProcessorConfig{}.TableName(), field: "client"},
+}
+
+// ClientOwnership finds the clients that REST requests address, so that callers limited to one
+// client can be kept to its records.
+type ClientOwnership struct {
+	Repo       *repository.OwnershipRepository
+	ClientRepo *repository.ClientRepository
+	// Clients, when set, serves client lookups from memory
+	Clients ClientCache
+}
+
+// NewClientOwnership creates a new ClientOwnership.
+func NewClientOwnership(db *mongo.Database) *ClientOwnership {
+	return &ClientOwnership{
+		Repo:       repository.NewOwnershipRepository(db),
+		ClientRepo: repository.NewClientRepository(db),
+	}
+}
+
+// Client returns the client ref names, by id or by client_id.
+func (o *ClientOwnership) Client(ctx context.Context, ref string) (*models.Client, error) {
+	var (
+		client *models.Client
+		err    error
+	)
+	if id, hexErr := primitive.ObjectIDFromHex(ref); hexErr == nil {
+		if o.Clients != nil {
+			client, err = o.Clients.GetClientByID(ctx, id)
+		} else {
+			client, err = o.ClientRepo.GetByID(ctx, id)
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return client, err
+		}
+	}
+	if o.Clients != nil {
+		client, err = o.Clients.GetClient(ctx, ref)
+	} else {
+		client, err = o.ClientRepo.GetByClientID(ctx, ref)
+	}
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrOwnerNotFound
+	}
+	return client, err
+}
+
+// Owner returns the client owning the record that route parameter param names by value, as its id
+// or its client_id. It returns false for parameters that don't name records of a single client,
+// and ErrOwnerNotFound when there is no such record or it belongs to no client.
+func (o *ClientOwnership) Owner(ctx context.Context, param, value string) (string, bool, error) {
+	ref, ok := ownerReferences[param]
+	if !ok {
+		return "", false, nil
+	}
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return "", true, ErrOwnerNotFound
+	}
+	raw, err := o.Repo.Reference(ctx, ref.collection, ref.field, id)
+	if errors.Is(err, mongo.ErrNoDocuments) || errors.Is(err, bsoncore.ErrElementNotFound) {
+		return "", true, ErrOwnerNotFound
+	}
+	if err != nil {
+		return "", true, err
+	}
+
+	var owner string
+	switch raw.Type {
+	case bson.TypeObjectID:
+		owner = raw.ObjectID().Hex()
+	case bson.TypeString:
+		owner = raw.StringValue()
+	}
+	if owner == "" {
+		return "", true, ErrOwnerNotFound
+	}
+	if ref.parent != "" {
+		return o.Owner(ctx, ref.parent, owner)
+	}
+	return owner, true, nil
+}
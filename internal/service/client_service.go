@@ -7,14 +7,22 @@ import (
 	"encoding/base64"
 
 	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/cache"
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// CacheInvalidator broadcasts that a cached document changed. Implemented by cache.Bus.
+type CacheInvalidator interface {
+	Invalidate(ctx context.Context, kind, key string) error
+}
+
 type ClientService struct {
 	Repo *repository.ClientRepository
+	// Invalidator is optional; when set, client updates evict cached copies on every node
+	Invalidator CacheInvalidator
 }
 
 func NewClientService(repo *repository.ClientRepository) *ClientService {
@@ -39,21 +47,29 @@ func (s *ClientService) CreateClient(ctx context.Context, req *dto.ClientCreateO
 		isActive = *req.IsActive
 	}
 	client := &models.Client{
-		Name:      req.Name,
-		Email:     req.Email,
-		ClientID:  clientID,
-		ClientKey: generateClientSecret(32),
-		IsActive:  isActive,
+		Name:           req.Name,
+		Email:          req.Email,
+		ClientID:       clientID,
+		ClientKey:      generateClientSecret(32),
+		IsActive:       isActive,
+		Sandbox:        req.Sandbox != nil && *req.Sandbox,
+		StrictPayloads: req.StrictPayloads != nil && *req.StrictPayloads,
+	}
+	if req.DeliveryFailureCallbackURL != nil {
+		client.DeliveryFailureCallbackURL = *req.DeliveryFailureCallbackURL
 	}
 	if err := s.Repo.Create(ctx, client); err != nil {
 		return nil, err
 	}
 	return &dto.ClientResponse{
-		ID:       client.ID.Hex(),
-		Name:     client.Name,
-		Email:    client.Email,
-		ClientID: client.ClientID,
-		IsActive: client.IsActive,
+		ID:                         client.ID.Hex(),
+		Name:                       client.Name,
+		Email:                      client.Email,
+		ClientID:                   client.ClientID,
+		IsActive:                   client.IsActive,
+		Sandbox:                    client.Sandbox,
+		StrictPayloads:             client.StrictPayloads,
+		DeliveryFailureCallbackURL: client.DeliveryFailureCallbackURL,
 	}, nil
 }
 
@@ -65,11 +81,14 @@ func (s *ClientService) ListClients(ctx context.Context) ([]dto.ClientResponse,
 	resp := make([]dto.ClientResponse, len(clients))
 	for i, c := range clients {
 		resp[i] = dto.ClientResponse{
-			ID:       c.ID.Hex(),
-			Name:     c.Name,
-			Email:    c.Email,
-			ClientID: c.ClientID,
-			IsActive: c.IsActive,
+			ID:                         c.ID.Hex(),
+			Name:                       c.Name,
+			Email:                      c.Email,
+			ClientID:                   c.ClientID,
+			IsActive:                   c.IsActive,
+			Sandbox:                    c.Sandbox,
+			StrictPayloads:             c.StrictPayloads,
+			DeliveryFailureCallbackURL: c.DeliveryFailureCallbackURL,
 		}
 	}
 	return resp, nil
@@ -91,15 +110,30 @@ func (s *ClientService) UpdateClient(ctx context.Context, clientID string, req *
 	if req.IsActive != nil {
 		update["is_active"] = *req.IsActive
 	}
+	if req.Sandbox != nil {
+		update["sandbox"] = *req.Sandbox
+	}
+	if req.StrictPayloads != nil {
+		update["strict_payloads"] = *req.StrictPayloads
+	}
+	if req.DeliveryFailureCallbackURL != nil {
+		update["delivery_failure_callback_url"] = *req.DeliveryFailureCallbackURL
+	}
 	updated, err := s.Repo.Update(ctx, clientID, update)
 	if err != nil {
 		return nil, err
 	}
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, updated.ClientID)
+	}
 	return &dto.ClientResponse{
-		ID:       updated.ID.Hex(),
-		Name:     updated.Name,
-		Email:    updated.Email,
-		ClientID: updated.ClientID,
-		IsActive: updated.IsActive,
+		ID:                         updated.ID.Hex(),
+		Name:                       updated.Name,
+		Email:                      updated.Email,
+		ClientID:                   updated.ClientID,
+		IsActive:                   updated.IsActive,
+		Sandbox:                    updated.Sandbox,
+		StrictPayloads:             updated.StrictPayloads,
+		DeliveryFailureCallbackURL: updated.DeliveryFailureCallbackURL,
 	}, nil
 }
@@ -0,0 +1,446 @@
+// Package service provides business logic for contacts.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+var (
+	ErrContactNotFound = errors.New("contact not found")
+	// ErrContactIdentityTaken is returned when an identity already belongs to another contact of the client
+	ErrContactIdentityTaken = errors.New("an identity belongs to another contact")
+	ErrContactMerged        = errors.New("contact has been merged into another contact")
+)
+
+// ContactService manages a client's contacts and links the messages of end users, and the
+// sessions they start, to them.
+type ContactService struct {
+	Repo            *repository.ContactRepository
+	ClientRepo      *repository.ClientRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	ChatMessageRepo *repository.ChatMessageRepository
+	Invalidator     CacheInvalidator
+}
+
+// NewContactService creates a new ContactService.
+func NewContactService(
+	repo *repository.ContactRepository,
+	clientRepo *repository.ClientRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+) *ContactService {
+	return &ContactService{
+		Repo:            repo,
+		ClientRepo:      clientRepo,
+		ChatSessionRepo: chatSessionRepo,
+		ChatMessageRepo: chatMessageRepo,
+	}
+}
+
+// CreateContact stores a new contact for an existing client.
+func (s *ContactService) CreateContact(ctx context.Context, clientID string, req *dto.ContactCreate) (*models.Contact, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	if err := models.ValidateIdentities(req.Identities); err != nil {
+		return nil, err
+	}
+	contact := &models.Contact{
+		ClientID:   client.ID,
+		Identities: req.Identities,
+		Name:       req.Name,
+		Locale:     req.Locale,
+		Attributes: req.Attributes,
+		Consent:    req.Consent,
+	}
+	if err := s.Repo.Create(ctx, contact); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrContactIdentityTaken
+		}
+		return nil, err
+	}
+	return contact, nil
+}
+
+// GetContact returns one of a client's contacts.
+func (s *ContactService) GetContact(ctx context.Context, clientID, id string) (*models.Contact, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, ErrContactNotFound
+	}
+	return s.getContact(ctx, client.ID, id)
+}
+
+func (s *ContactService) getContact(ctx context.Context, clientID primitive.ObjectID, id string) (*models.Contact, error) {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrContactNotFound
+	}
+	contact, err := s.Repo.GetByID(ctx, clientID, *objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+	return contact, nil
+}
+
+// ListContacts returns a client's contacts, most recently seen first. Given a channel and an
+// external ID, it returns the contact with that identity. Merged contacts are left out.
+func (s *ContactService) ListContacts(ctx context.Context, clientID string, identity *models.ContactIdentity, limit, offset int) ([]models.Contact, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	filter := bson.M{"client": client.ID, "merged_into": bson.M{"$exists": false}}
+	if identity != nil {
+		filter["identity_keys"] = identity.Key()
+	}
+	return s.Repo.List(ctx, filter, limit, offset)
+}
+
+// UpdateContact applies req to a client's contact and returns the updated contact.
+func (s *ContactService) UpdateContact(ctx context.Context, clientID, id string, req *dto.ContactUpdate) (*models.Contact, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, ErrContactNotFound
+	}
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrContactNotFound
+	}
+
+	update := bson.M{}
+	if req.Identities != nil {
+		if err := models.ValidateIdentities(req.Identities); err != nil {
+			return nil, err
+		}
+		update["identities"] = req.Identities
+		update["identity_keys"] = models.IdentityKeys(req.Identities)
+	}
+	if req.Name != nil {
+		update["name"] = *req.Name
+	}
+	if req.Locale != nil {
+		update["locale"] = *req.Locale
+	}
+	if req.Attributes != nil {
+		update["attributes"] = req.Attributes
+	}
+	if req.Consent != nil {
+		update["consent"] = req.Consent
+		update["consent_updated_at"] = time.Now().UTC()
+	}
+	if len(update) == 0 {
+		return nil, errors.New("no fields to update")
+	}
+
+	contact, err := s.Repo.Update(ctx, client.ID, *objID, update)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrContactIdentityTaken
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+	return contact, nil
+}
+
+// DeleteContact removes a client's contact and unlinks its sessions and messages.
+func (s *ContactService) DeleteContact(ctx context.Context, clientID, id string) error {
+	contact, err := s.GetContact(ctx, clientID, id)
+	if err != nil {
+		return err
+	}
+	// Links go first, so a failed delete leaves a contact that can be deleted again
+	if _, err := s.ChatSessionRepo.ReassignContact(ctx, contact.ID, nil); err != nil {
+		return err
+	}
+	if _, err := s.ChatMessageRepo.ReassignContact(ctx, contact.ID, nil); err != nil {
+		return err
+	}
+	if _, err := s.Repo.Delete(ctx, contact.ClientID, contact.ID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// MergeContacts merges the source contact into the target, for a user who turned out to be known
+// under two contacts. The target takes the source's identities, sessions and messages, and the
+// name, locale, attributes and consent flags it doesn't have itself. The source is kept, with
+// merged_into pointing at the target.
+func (s *ContactService) MergeContacts(ctx context.Context, clientID, targetID, sourceID string) (*dto.ContactMergeResponse, error) {
+	if targetID == sourceID {
+		return nil, errors.New("a contact can't be merged into itself")
+	}
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, ErrContactNotFound
+	}
+	target, err := s.getContact(ctx, client.ID, targetID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.getContact(ctx, client.ID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if target.MergedInto != nil || source.MergedInto != nil {
+		return nil, ErrContactMerged
+	}
+
+	update := bson.M{"identities": append(target.Identities, source.Identities...)}
+	update["identity_keys"] = models.IdentityKeys(update["identities"].([]models.ContactIdentity))
+	if target.Name == "" && source.Name != "" {
+		update["name"] = source.Name
+	}
+	if target.Locale == "" && source.Locale != "" {
+		update["locale"] = source.Locale
+	}
+	if len(source.Attributes) > 0 {
+		update["attributes"] = fillMissing(target.Attributes, source.Attributes)
+	}
+	if len(source.Consent) > 0 {
+		update["consent"] = fillMissing(target.Consent, source.Consent)
+		update["consent_updated_at"] = time.Now().UTC()
+	}
+	if source.LastSeenAt != nil && (target.LastSeenAt == nil || source.LastSeenAt.After(*target.LastSeenAt)) {
+		update["last_seen_at"] = *source.LastSeenAt
+	}
+
+	// The source gives up its identities before the target takes them, as an identity can only
+	// belong to one contact
+	merged, err := s.Repo.MarkMerged(ctx, source.ID, target.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !merged {
+		return nil, ErrContactMerged
+	}
+	updated, err := s.Repo.Update(ctx, client.ID, target.ID, update)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge contact %s into %s: %w", source.ID.Hex(), target.ID.Hex(), err)
+	}
+
+	resp := &dto.ContactMergeResponse{Contact: updated}
+	if resp.SessionsMoved, err = s.ChatSessionRepo.ReassignContact(ctx, source.ID, &target.ID); err != nil {
+		return nil, err
+	}
+	if resp.MessagesMoved, err = s.ChatMessageRepo.ReassignContact(ctx, source.ID, &target.ID); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// SetConsent records a client's contact opting in to or out of the purposes in consent, leaving
+// its other consent flags as they are, and returns the updated contact.
+func (s *ContactService) SetConsent(ctx context.Context, clientID, id string, consent map[string]bool) (*models.Contact, error) {
+	if len(consent) == 0 {
+		return nil, errors.New("consent must name at least one purpose")
+	}
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, ErrContactNotFound
+	}
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrContactNotFound
+	}
+
+	update := bson.M{"consent_updated_at": time.Now().UTC()}
+	for purpose, granted := range consent {
+		if purpose == "" || strings.ContainsAny(purpose, ".$") {
+			return nil, fmt.Errorf("invalid consent purpose %q", purpose)
+		}
+		update["consent."+purpose] = granted
+	}
+	contact, err := s.Repo.Update(ctx, client.ID, *objID, update)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrContactNotFound
+		}
+		return nil, err
+	}
+	return contact, nil
+}
+
+// CSATConsent is a CSATConsentChecker that keeps surveys from sessions whose contact opted out
+// of marketing.
+func (s *ContactService) CSATConsent(ctx context.Context, session *models.ChatSession) (bool, string) {
+	if session.Contact == nil || session.Client == nil {
+		return true, ""
+	}
+	contact, err := s.Repo.GetByID(ctx, *session.Client, *session.Contact)
+	if err != nil {
+		// A contact deleted since is no reason to hold the survey back
+		return true, ""
+	}
+	if contact.OptedOut(models.ConsentMarketing) {
+		return false, CSATSkipConsentDenied
+	}
+	return true, ""
+}
+
+// ConsentPayload returns the consent state of a client's contact as sent in webhook payloads.
+func (s *ContactService) ConsentPayload(ctx context.Context, clientID, id primitive.ObjectID) (map[string]interface{}, error) {
+	contact, err := s.Repo.GetByID(ctx, clientID, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contact: %w", err)
+	}
+	consent := contact.Consent
+	if consent == nil {
+		consent = map[string]bool{}
+	}
+	payload := map[string]interface{}{
+		"id":      contact.ID.Hex(),
+		"consent": consent,
+	}
+	if contact.ConsentUpdatedAt != nil {
+		payload["consent_updated_at"] = contact.ConsentUpdatedAt.Format(time.RFC3339)
+	}
+	return payload, nil
+}
+
+// fillMissing returns the entries of into with those of from it lacks added.
+func fillMissing[V any](into, from map[string]V) map[string]V {
+	merged := make(map[string]V, len(into)+len(from))
+	for k, v := range from {
+		merged[k] = v
+	}
+	for k, v := range into {
+		merged[k] = v
+	}
+	return merged
+}
+
+// ListSessions returns the sessions linked to a client's contact, newest first, and how many there are.
+func (s *ContactService) ListSessions(ctx context.Context, clientID, id string, limit, offset int64) ([]models.ChatSession, int64, error) {
+	contact, err := s.GetContact(ctx, clientID, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return s.ChatSessionRepo.ListWithFilters(ctx, bson.M{"contact": contact.ID}, offset, limit, bson.D{{Key: "created_at", Value: -1}})
+}
+
+// ListMessages returns up to lastN of the newest messages of every session linked to a client's
+// contact, or all of them when lastN is 0, including the replies the contact received.
+func (s *ContactService) ListMessages(ctx context.Context, clientID, id string, lastN int64) ([]models.ChatMessage, error) {
+	sessions, _, err := s.ListSessions(ctx, clientID, id, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]primitive.ObjectID, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	return s.ChatMessageRepo.List(ctx, bson.M{"session": bson.M{"$in": ids}}, lastN)
+}
+
+// LinkMessage links a user message to the contact of its sender on the session's channel,
+// creating the contact the first time the sender writes, and links the session to the contact
+// when it has none. Messages of sandbox sessions and of sessions without a channel aren't linked.
+func (s *ContactService) LinkMessage(ctx context.Context, msg *models.ChatMessage) error {
+	if msg.Sender == "" {
+		return nil
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to find session: %w", err)
+	}
+	if session.Client == nil || session.ClientChannel == nil || session.Test {
+		return nil
+	}
+
+	contact, err := s.seeSender(ctx, *session.Client, models.ContactIdentity{ChannelID: *session.ClientChannel, ExternalID: msg.Sender}, msg.SenderName)
+	if err != nil {
+		return err
+	}
+	msg.Contact = &contact.ID
+	if session.Contact == nil {
+		if err := s.ChatSessionRepo.SetContact(ctx, session.ID, contact.ID); err != nil {
+			return fmt.Errorf("failed to link session to contact: %w", err)
+		}
+	}
+	return nil
+}
+
+// seeSender returns the client's contact with identity, creating it with name when there is none.
+func (s *ContactService) seeSender(ctx context.Context, clientID primitive.ObjectID, identity models.ContactIdentity, name string) (*models.Contact, error) {
+	now := time.Now().UTC()
+	contact, err := s.Repo.SeeByIdentity(ctx, clientID, identity, now)
+	if err == nil {
+		return contact, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("failed to find contact: %w", err)
+	}
+
+	contact = &models.Contact{
+		ClientID:   clientID,
+		Identities: []models.ContactIdentity{identity},
+		Name:       name,
+		LastSeenAt: &now,
+	}
+	err = s.Repo.Create(ctx, contact)
+	if mongo.IsDuplicateKeyError(err) {
+		// Created by a concurrent message from the same sender
+		return s.Repo.SeeByIdentity(ctx, clientID, identity, now)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return contact, nil
+}
+
+// GetHistoryPolicy returns a client's contact history policy, or nil if none is configured.
+func (s *ContactService) GetHistoryPolicy(ctx context.Context, clientID string) (*models.ContactHistoryPolicy, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return client.ContactHistory, nil
+}
+
+// SetHistoryPolicy validates and stores a client's contact history policy, replacing any existing one.
+func (s *ContactService) SetHistoryPolicy(ctx context.Context, clientID string, policy *models.ContactHistoryPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"contact_history": policy}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// DeleteHistoryPolicy removes a client's contact history policy, which turns the history off.
+func (s *ContactService) DeleteHistoryPolicy(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"contact_history": nil}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+func (s *ContactService) invalidate(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
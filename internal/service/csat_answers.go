@@ -0,0 +1,122 @@
+// Package service provides business logic for validating CSAT answers.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+var (
+	ErrInvalidCSATAnswer   = errors.New("invalid CSAT answer")
+	ErrInvalidCSATQuestion = errors.New("invalid CSAT question")
+)
+
+// ValidateCSATQuestions checks a question set before it is saved: answer types, translations
+// and branch rules.
+func ValidateCSATQuestions(questions []models.CSATQuestionTemplate) error {
+	for i := range questions {
+		if err := validateCSATAnswerType(&questions[i]); err != nil {
+			return err
+		}
+		if err := validateCSATTranslations(&questions[i]); err != nil {
+			return err
+		}
+	}
+	return ValidateCSATBranches(questions)
+}
+
+// validateCSATAnswerType checks a question's answer settings: multiple_choice questions need
+// options, and the options of rating and nps questions must be valid scores so that every button
+// produces an accepted answer.
+func validateCSATAnswerType(q *models.CSATQuestionTemplate) error {
+	switch q.AnswerType {
+	case "", models.CSATAnswerTypeFreeText:
+		if q.MaxLength < 0 {
+			return fmt.Errorf("%w: question %d: max_length must not be negative", ErrInvalidCSATQuestion, q.Order)
+		}
+	case models.CSATAnswerTypeMultipleChoice:
+		if len(q.Options) == 0 {
+			return fmt.Errorf("%w: question %d: multiple_choice needs options", ErrInvalidCSATQuestion, q.Order)
+		}
+	case models.CSATAnswerTypeRating, models.CSATAnswerTypeNPS:
+		for _, option := range q.Options {
+			if _, _, err := NormalizeCSATAnswer(q, option); err != nil {
+				return fmt.Errorf("%w: question %d: option %q is not a valid %s score", ErrInvalidCSATQuestion, q.Order, option, q.AnswerType)
+			}
+		}
+	default:
+		return fmt.Errorf("%w: question %d: unknown answer_type %q", ErrInvalidCSATQuestion, q.Order, q.AnswerType)
+	}
+	return nil
+}
+
+// NormalizeCSATAnswer validates value against the question's answer type and returns it in
+// canonical form, with the numeric score for rating and nps questions. Options picked from a
+// translation count as the question's own. Questions without an answer type accept any non-empty
+// value.
+func NormalizeCSATAnswer(question *models.CSATQuestionTemplate, value string) (normalized string, score *float64, err error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil, fmt.Errorf("%w: answer is empty", ErrInvalidCSATAnswer)
+	}
+	value = canonicalCSATOption(question, value)
+
+	switch question.AnswerType {
+	case models.CSATAnswerTypeRating:
+		return normalizeCSATScore(value, models.CSATRatingMin, models.CSATRatingMax)
+	case models.CSATAnswerTypeNPS:
+		return normalizeCSATScore(value, models.CSATNPSMin, models.CSATNPSMax)
+	case models.CSATAnswerTypeMultipleChoice:
+		for _, option := range question.Options {
+			if strings.EqualFold(value, strings.TrimSpace(option)) {
+				return option, nil, nil
+			}
+		}
+		return "", nil, fmt.Errorf("%w: %q is not one of the question's options", ErrInvalidCSATAnswer, value)
+	case models.CSATAnswerTypeFreeText:
+		maxLength := question.MaxLength
+		if maxLength <= 0 {
+			maxLength = models.CSATFreeTextDefaultMaxLength
+		}
+		if utf8.RuneCountInString(value) > maxLength {
+			return "", nil, fmt.Errorf("%w: answer is longer than %d characters", ErrInvalidCSATAnswer, maxLength)
+		}
+	}
+	return value, nil, nil
+}
+
+// normalizeCSATScore accepts a whole number within [min, max]; "4.0" is read as 4.
+func normalizeCSATScore(value string, min, max int) (string, *float64, error) {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f != float64(int(f)) || int(f) < min || int(f) > max {
+		return "", nil, fmt.Errorf("%w: %q is not a whole number from %d to %d", ErrInvalidCSATAnswer, value, min, max)
+	}
+	return strconv.Itoa(int(f)), &f, nil
+}
+
+// csatQuestionOptions returns the buttons offered for a question: its configured options, or the
+// full score range for rating and nps questions configured without any.
+func csatQuestionOptions(question *models.CSATQuestionTemplate) []string {
+	if len(question.Options) > 0 {
+		return question.Options
+	}
+	var min, max int
+	switch question.AnswerType {
+	case models.CSATAnswerTypeRating:
+		min, max = models.CSATRatingMin, models.CSATRatingMax
+	case models.CSATAnswerTypeNPS:
+		min, max = models.CSATNPSMin, models.CSATNPSMax
+	default:
+		return question.Options
+	}
+	options := make([]string, 0, max-min+1)
+	for score := min; score <= max; score++ {
+		options = append(options, strconv.Itoa(score))
+	}
+	return options
+}
@@ -0,0 +1,229 @@
+// Package service provides business logic for CSAT question branching.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+var ErrInvalidCSATBranch = errors.New("invalid CSAT branch")
+
+// ValidateCSATBranches checks the branch rules of a question set before it is saved: operators
+// and values must be well formed, every target must be another active question, orders must be
+// unique once any question branches, and no answer path may lead back to a question already asked.
+func ValidateCSATBranches(questions []models.CSATQuestionTemplate) error {
+	var active []models.CSATQuestionTemplate
+	branching := false
+	for _, q := range questions {
+		for i, rule := range q.Branches {
+			if err := validateCSATBranchRule(rule); err != nil {
+				return fmt.Errorf("%w: question %d rule %d: %v", ErrInvalidCSATBranch, q.Order, i+1, err)
+			}
+		}
+		if q.Active {
+			active = append(active, q)
+			branching = branching || len(q.Branches) > 0
+		}
+	}
+	if !branching {
+		return nil
+	}
+
+	// Mirror the order the survey asks them in (see CSATQuestionTemplateRepository.GetByConfigurationID)
+	sort.SliceStable(active, func(i, j int) bool { return active[i].Order < active[j].Order })
+	indexByOrder := make(map[int]int, len(active))
+	for i, q := range active {
+		if _, dup := indexByOrder[q.Order]; dup {
+			return fmt.Errorf("%w: order %d is used by more than one active question", ErrInvalidCSATBranch, q.Order)
+		}
+		indexByOrder[q.Order] = i
+	}
+
+	edges := make([][]int, len(active))
+	for i, q := range active {
+		fallsThrough := true
+		for _, rule := range q.Branches {
+			if rule.Operator == models.CSATBranchOperatorAny {
+				fallsThrough = false
+			}
+			if rule.End {
+				continue
+			}
+			target, ok := indexByOrder[rule.NextOrder]
+			if !ok {
+				return fmt.Errorf("%w: question %d branches to order %d, which is not an active question", ErrInvalidCSATBranch, q.Order, rule.NextOrder)
+			}
+			edges[i] = append(edges[i], target)
+		}
+		if fallsThrough && i+1 < len(active) {
+			edges[i] = append(edges[i], i+1)
+		}
+	}
+
+	// Depth-first search; reaching a question still on the stack means an answer path loops
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	state := make([]int, len(active))
+	var stack []int
+	var visit func(i int) error
+	visit = func(i int) error {
+		state[i] = onStack
+		stack = append(stack, i)
+		for _, next := range edges[i] {
+			switch state[next] {
+			case onStack:
+				var path []string
+				for j := len(stack) - 1; j >= 0; j-- {
+					path = append([]string{strconv.Itoa(active[stack[j]].Order)}, path...)
+					if stack[j] == next {
+						break
+					}
+				}
+				path = append(path, strconv.Itoa(active[next].Order))
+				return fmt.Errorf("%w: branches loop through questions %s", ErrInvalidCSATBranch, strings.Join(path, " -> "))
+			case unvisited:
+				if err := visit(next); err != nil {
+					return err
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = done
+		return nil
+	}
+	for i := range active {
+		if state[i] == unvisited {
+			if err := visit(i); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func validateCSATBranchRule(rule models.CSATBranchRule) error {
+	switch rule.Operator {
+	case models.CSATBranchOperatorEq, models.CSATBranchOperatorNeq:
+		if strings.TrimSpace(rule.Value) == "" {
+			return fmt.Errorf("operator %s needs a value", rule.Operator)
+		}
+	case models.CSATBranchOperatorLt, models.CSATBranchOperatorLte, models.CSATBranchOperatorGt, models.CSATBranchOperatorGte:
+		if _, err := strconv.ParseFloat(strings.TrimSpace(rule.Value), 64); err != nil {
+			return fmt.Errorf("operator %s needs a numeric value", rule.Operator)
+		}
+	case models.CSATBranchOperatorIn:
+		if len(rule.Values) == 0 {
+			return fmt.Errorf("operator in needs values")
+		}
+	case models.CSATBranchOperatorAny:
+	default:
+		return fmt.Errorf("unknown operator %q", rule.Operator)
+	}
+	if !rule.End && rule.NextOrder == 0 {
+		return fmt.Errorf("rule needs next_order or end")
+	}
+	return nil
+}
+
+// matchesCSATBranch reports whether answer satisfies rule. Numeric operators never match answers
+// that aren't numbers.
+func matchesCSATBranch(rule models.CSATBranchRule, answer string) bool {
+	answer = strings.TrimSpace(answer)
+	switch rule.Operator {
+	case models.CSATBranchOperatorAny:
+		return true
+	case models.CSATBranchOperatorEq:
+		return strings.EqualFold(answer, strings.TrimSpace(rule.Value))
+	case models.CSATBranchOperatorNeq:
+		return !strings.EqualFold(answer, strings.TrimSpace(rule.Value))
+	case models.CSATBranchOperatorIn:
+		for _, v := range rule.Values {
+			if strings.EqualFold(answer, strings.TrimSpace(v)) {
+				return true
+			}
+		}
+		return false
+	}
+
+	got, err := strconv.ParseFloat(answer, 64)
+	if err != nil {
+		return false
+	}
+	want, err := strconv.ParseFloat(strings.TrimSpace(rule.Value), 64)
+	if err != nil {
+		return false
+	}
+	switch rule.Operator {
+	case models.CSATBranchOperatorLt:
+		return got < want
+	case models.CSATBranchOperatorLte:
+		return got <= want
+	case models.CSATBranchOperatorGt:
+		return got > want
+	case models.CSATBranchOperatorGte:
+		return got >= want
+	}
+	return false
+}
+
+// nextCSATQuestionIndex applies the branch rules of the last question sent in session to its
+// answer. ok is false when the question has no rule for the answer, or hasn't been answered, and
+// the survey should continue in order. An index of len(questions) ends the survey, as does a rule
+// leading back to a question already asked, which can happen when the set changes mid-survey.
+func (s *CSATService) nextCSATQuestionIndex(ctx context.Context, session *models.CSATSession, questions []models.CSATQuestionTemplate) (index int, ok bool) {
+	if len(session.QuestionsSent) == 0 {
+		return 0, false
+	}
+	lastID := session.QuestionsSent[len(session.QuestionsSent)-1]
+	var last *models.CSATQuestionTemplate
+	for i := range questions {
+		if questions[i].ID == lastID {
+			last = &questions[i]
+			break
+		}
+	}
+	if last == nil || len(last.Branches) == 0 {
+		return 0, false
+	}
+	response, err := s.CSATResponseRepo.GetBySessionAndQuestion(ctx, session.ID, lastID)
+	if err != nil || response == nil {
+		return 0, false
+	}
+
+	// Responses saved before answer types existed only hold the raw value
+	answer := response.NormalizedValue
+	if answer == "" {
+		answer = response.ResponseValue
+	}
+	for _, rule := range last.Branches {
+		if !matchesCSATBranch(rule, answer) {
+			continue
+		}
+		if rule.End {
+			return len(questions), true
+		}
+		for i, q := range questions {
+			if q.Order != rule.NextOrder {
+				continue
+			}
+			for _, sent := range session.QuestionsSent {
+				if sent == q.ID {
+					return len(questions), true
+				}
+			}
+			return i, true
+		}
+		// The target was deactivated after the rule was saved
+		return 0, false
+	}
+	return 0, false
+}
@@ -0,0 +1,245 @@
+// Package service provides business logic for bulk CSAT campaigns.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	defaultBulkCSATLimit         = 500
+	maxBulkCSATLimit             = 5000
+	defaultBulkCSATRatePerMinute = 60
+)
+
+// Skip reasons reported in BulkCSATTriggerResult.
+const (
+	CSATSkipSessionNotFound  = "session_not_found"
+	CSATSkipMissingChannel   = "missing_client_channel"
+	CSATSkipNotConfigured    = "csat_not_configured"
+	CSATSkipDisabled         = "csat_disabled"
+	CSATSkipAlreadySurveyed  = "already_surveyed"
+	CSATSkipConsentDenied    = "consent_denied"
+	CSATSkipDuplicate        = "duplicate_in_request"
+	CSATSkipEnqueueFailed    = "enqueue_failed"
+	CSATSkipTaskQueueMissing = "task_queue_unavailable"
+)
+
+// CSATTaskClient enqueues CSAT trigger, reminder and expiry tasks for the worker.
+type CSATTaskClient interface {
+	EnqueueCSATTrigger(ctx context.Context, sessionID, csatType string, delay time.Duration) error
+	EnqueueCSATReminder(ctx context.Context, csatSessionID string, questionIndex int, delay time.Duration) error
+	EnqueueCSATExpiry(ctx context.Context, csatSessionID string, delay time.Duration) error
+}
+
+// ErrCSATConsentDenied is returned when ConsentChecker keeps a survey from a session.
+var ErrCSATConsentDenied = errors.New("the session's contact has not consented to surveys")
+
+// CSATConsentChecker decides whether a chat session may receive a survey.
+// When allowed is false, reason is reported in the bulk summary (defaults to consent_denied).
+type CSATConsentChecker func(ctx context.Context, session *models.ChatSession) (allowed bool, reason string)
+
+// BulkCSATTriggerParams selects the sessions to survey in a bulk sweep.
+// SessionIDs takes precedence over the filter fields when provided, but sessions of clients other
+// than ClientID are still left out.
+type BulkCSATTriggerParams struct {
+	Type          string
+	SessionIDs    []string
+	ClientID      *primitive.ObjectID
+	ChannelID     *primitive.ObjectID
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Active        *bool
+	Limit         int
+	RatePerMinute int
+	DryRun        bool
+}
+
+// BulkCSATTriggerSkip records why a single session was not triggered.
+type BulkCSATTriggerSkip struct {
+	SessionID string
+	Reason    string
+}
+
+// BulkCSATTriggerResult summarises a bulk sweep.
+type BulkCSATTriggerResult struct {
+	Matched             int
+	Triggered           int
+	TriggeredSessionIDs []string
+	Skipped             []BulkCSATTriggerSkip
+	Reasons             map[string]int
+	Duration            time.Duration // time until the last enqueued trigger fires
+	DryRun              bool
+}
+
+func (r *BulkCSATTriggerResult) skip(sessionID, reason string) {
+	r.Skipped = append(r.Skipped, BulkCSATTriggerSkip{SessionID: sessionID, Reason: reason})
+	r.Reasons[reason]++
+}
+
+// BulkTriggerCSAT enqueues csat_trigger tasks for every eligible session matched by params.
+// Triggers are spaced by RatePerMinute so large sweeps don't flood the channel.
+func (s *CSATService) BulkTriggerCSAT(ctx context.Context, params BulkCSATTriggerParams) (*BulkCSATTriggerResult, error) {
+	if err := utils.ValidateCSATType(params.Type); err != nil {
+		return nil, fmt.Errorf("invalid CSAT type format: %w", err)
+	}
+	if len(params.SessionIDs) == 0 && params.ClientID == nil {
+		return nil, fmt.Errorf("either session_ids or a client_id filter is required")
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultBulkCSATLimit
+	}
+	if limit > maxBulkCSATLimit {
+		limit = maxBulkCSATLimit
+	}
+	rate := params.RatePerMinute
+	if rate <= 0 {
+		rate = defaultBulkCSATRatePerMinute
+	}
+	interval := time.Minute / time.Duration(rate)
+
+	result := &BulkCSATTriggerResult{
+		TriggeredSessionIDs: make([]string, 0),
+		Skipped:             make([]BulkCSATTriggerSkip, 0),
+		Reasons:             make(map[string]int),
+		DryRun:              params.DryRun,
+	}
+
+	sessions, err := s.resolveBulkCSATSessions(ctx, params, limit, result)
+	if err != nil {
+		return nil, err
+	}
+	result.Matched = len(sessions) + len(result.Skipped)
+
+	if !params.DryRun && s.TaskClient == nil {
+		for _, session := range sessions {
+			result.skip(session.SessionID, CSATSkipTaskQueueMissing)
+		}
+		return result, nil
+	}
+
+	// Cache configurations per client/channel pair; sweeps usually hit a handful of channels
+	configs := make(map[string]*models.CSATConfiguration)
+	seen := make(map[string]bool)
+
+	for i := range sessions {
+		session := &sessions[i]
+		if seen[session.SessionID] {
+			result.skip(session.SessionID, CSATSkipDuplicate)
+			continue
+		}
+		seen[session.SessionID] = true
+
+		if session.Client == nil || session.ClientChannel == nil {
+			result.skip(session.SessionID, CSATSkipMissingChannel)
+			continue
+		}
+
+		key := session.Client.Hex() + ":" + session.ClientChannel.Hex()
+		config, ok := configs[key]
+		if !ok {
+			config, err = s.CSATConfigRepo.GetByClientChannelAndType(ctx, *session.Client, *session.ClientChannel, params.Type)
+			if err != nil {
+				config = nil
+			}
+			configs[key] = config
+		}
+		if config == nil {
+			result.skip(session.SessionID, CSATSkipNotConfigured)
+			continue
+		}
+		if !config.Enabled {
+			result.skip(session.SessionID, CSATSkipDisabled)
+			continue
+		}
+
+		surveyed, err := s.CSATSessionRepo.ExistsForBaseSession(ctx, session.SessionID, config.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check existing CSAT sessions: %w", err)
+		}
+		if surveyed {
+			result.skip(session.SessionID, CSATSkipAlreadySurveyed)
+			continue
+		}
+
+		if s.ConsentChecker != nil {
+			if allowed, reason := s.ConsentChecker(ctx, session); !allowed {
+				if reason == "" {
+					reason = CSATSkipConsentDenied
+				}
+				result.skip(session.SessionID, reason)
+				continue
+			}
+		}
+
+		delay := time.Duration(result.Triggered) * interval
+		if !params.DryRun {
+			if err := s.TaskClient.EnqueueCSATTrigger(ctx, session.SessionID, params.Type, delay); err != nil {
+				result.skip(session.SessionID, CSATSkipEnqueueFailed)
+				continue
+			}
+		}
+
+		result.Triggered++
+		result.TriggeredSessionIDs = append(result.TriggeredSessionIDs, session.SessionID)
+		result.Duration = delay
+	}
+
+	return result, nil
+}
+
+// resolveBulkCSATSessions loads the chat sessions targeted by params.
+// Explicit session IDs that cannot be found, or belong to another client than params.ClientID,
+// are recorded as skips on result.
+func (s *CSATService) resolveBulkCSATSessions(ctx context.Context, params BulkCSATTriggerParams, limit int, result *BulkCSATTriggerResult) ([]models.ChatSession, error) {
+	if len(params.SessionIDs) > 0 {
+		if len(params.SessionIDs) > limit {
+			return nil, fmt.Errorf("too many session_ids: %d exceeds limit of %d", len(params.SessionIDs), limit)
+		}
+
+		sessions := make([]models.ChatSession, 0, len(params.SessionIDs))
+		for _, sessionID := range params.SessionIDs {
+			baseSessionID, _ := parseSessionID(sessionID)
+			session, err := s.ChatSessionRepo.GetBySessionID(ctx, baseSessionID)
+			if err != nil || session == nil || (params.ClientID != nil && (session.Client == nil || *session.Client != *params.ClientID)) {
+				result.skip(sessionID, CSATSkipSessionNotFound)
+				continue
+			}
+			sessions = append(sessions, *session)
+		}
+		return sessions, nil
+	}
+
+	filter := bson.M{"client": *params.ClientID}
+	if params.ChannelID != nil {
+		filter["client_channel"] = *params.ChannelID
+	}
+	if params.Active != nil {
+		filter["active"] = *params.Active
+	}
+	createdAt := bson.M{}
+	if params.CreatedAfter != nil {
+		createdAt["$gte"] = *params.CreatedAfter
+	}
+	if params.CreatedBefore != nil {
+		createdAt["$lte"] = *params.CreatedBefore
+	}
+	if len(createdAt) > 0 {
+		filter["created_at"] = createdAt
+	}
+
+	sessions, _, err := s.ChatSessionRepo.ListWithFilters(ctx, filter, 0, int64(limit), bson.D{{Key: "created_at", Value: -1}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chat sessions: %w", err)
+	}
+	return sessions, nil
+}
@@ -0,0 +1,324 @@
+// Package service provides business logic for listing and exporting CSAT survey results.
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// csatExportBatchSize is how many CSAT sessions are loaded at a time while exporting
+const csatExportBatchSize = 500
+
+// CSATSessionFilter selects CSAT sessions for listing and export. Dates bound triggered_at; the
+// score bounds match sessions with at least one rating or nps answer in range.
+type CSATSessionFilter struct {
+	Status    string
+	ClientID  *primitive.ObjectID
+	ChannelID *primitive.ObjectID
+	StartDate *time.Time
+	EndDate   *time.Time
+	MinScore  *float64
+	MaxScore  *float64
+}
+
+// CSATSessionSummary is a CSAT session with the average of its scored answers.
+type CSATSessionSummary struct {
+	Session      models.CSATSession
+	AverageScore *float64
+}
+
+// csatExportHeader names the columns written by ExportCSATResponses
+var csatExportHeader = []string{
+	"csat_session_id", "chat_session_id", "client_id", "channel_id", "csat_type", "status", "locale",
+	"triggered_at", "completed_at", "question_id", "question_order", "question_text", "answer_type",
+	"response_value", "normalized_value", "score", "responded_at",
+}
+
+// buildCSATSessionFilter translates f into a MongoDB filter. ok is false when the score bounds
+// match no session at all.
+func (s *CSATService) buildCSATSessionFilter(ctx context.Context, f CSATSessionFilter) (filter bson.M, ok bool, err error) {
+	filter = bson.M{}
+	if f.Status != "" {
+		filter["status"] = f.Status
+	}
+	if f.ClientID != nil {
+		filter["client"] = *f.ClientID
+	}
+	if f.ChannelID != nil {
+		filter["client_channel"] = *f.ChannelID
+	}
+	if f.StartDate != nil || f.EndDate != nil {
+		triggered := bson.M{}
+		if f.StartDate != nil {
+			triggered["$gte"] = *f.StartDate
+		}
+		if f.EndDate != nil {
+			triggered["$lte"] = *f.EndDate
+		}
+		filter["triggered_at"] = triggered
+	}
+	if f.MinScore != nil || f.MaxScore != nil {
+		ids, err := s.CSATResponseRepo.SessionIDsWithScore(ctx, f.MinScore, f.MaxScore)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(ids) == 0 {
+			return nil, false, nil
+		}
+		filter["_id"] = bson.M{"$in": ids}
+	}
+	return filter, true, nil
+}
+
+// ListCSATSessions returns a page of CSAT sessions matching f, newest first, with the total count.
+func (s *CSATService) ListCSATSessions(ctx context.Context, f CSATSessionFilter, skip, limit int64) ([]CSATSessionSummary, int64, error) {
+	filter, ok, err := s.buildCSATSessionFilter(ctx, f)
+	if err != nil || !ok {
+		return []CSATSessionSummary{}, 0, err
+	}
+	sessions, total, err := s.CSATSessionRepo.ListWithFilters(ctx, filter, skip, limit, bson.D{{Key: "triggered_at", Value: -1}})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]primitive.ObjectID, len(sessions))
+	for i, session := range sessions {
+		ids[i] = session.ID
+	}
+	responses, err := s.CSATResponseRepo.ListBySessions(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	sums := map[primitive.ObjectID]float64{}
+	counts := map[primitive.ObjectID]int{}
+	for _, response := range responses {
+		if response.Score != nil {
+			sums[response.CSATSession] += *response.Score
+			counts[response.CSATSession]++
+		}
+	}
+
+	summaries := make([]CSATSessionSummary, len(sessions))
+	for i, session := range sessions {
+		summaries[i] = CSATSessionSummary{Session: session}
+		if n := counts[session.ID]; n > 0 {
+			avg := sums[session.ID] / float64(n)
+			summaries[i].AverageScore = &avg
+		}
+	}
+	return summaries, total, nil
+}
+
+// ExportCSATResponses writes every response of the CSAT sessions matching f to w as CSV, one row
+// per response joined with its session and question. Sessions are read in batches so large
+// exports stream instead of being held in memory.
+func (s *CSATService) ExportCSATResponses(ctx context.Context, f CSATSessionFilter, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csatExportHeader); err != nil {
+		return err
+	}
+	filter, ok, err := s.buildCSATSessionFilter(ctx, f)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		writer.Flush()
+		return writer.Error()
+	}
+
+	// Exports scan every matching session, so they read where analytics queries do
+	sessionRepo, responseRepo := s.CSATSessionRepo, s.CSATResponseRepo
+	if s.AnalyticsDB != nil {
+		sessionRepo = repository.NewCSATSessionRepository(s.AnalyticsDB)
+		responseRepo = repository.NewCSATResponseRepository(s.AnalyticsDB)
+	}
+
+	questions := map[primitive.ObjectID]*models.CSATQuestionTemplate{}
+	csatTypes := map[primitive.ObjectID]string{}
+	var lastID primitive.ObjectID
+	for {
+		page := filter
+		if !lastID.IsZero() {
+			page = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$gt": lastID}}}}
+		}
+		sessions, _, err := sessionRepo.ListWithFilters(ctx, page, 0, csatExportBatchSize, bson.D{{Key: "_id", Value: 1}})
+		if err != nil {
+			return err
+		}
+		if len(sessions) == 0 {
+			break
+		}
+		lastID = sessions[len(sessions)-1].ID
+
+		sessionsByID := make(map[primitive.ObjectID]*models.CSATSession, len(sessions))
+		ids := make([]primitive.ObjectID, len(sessions))
+		for i := range sessions {
+			sessionsByID[sessions[i].ID] = &sessions[i]
+			ids[i] = sessions[i].ID
+			if _, seen := csatTypes[sessions[i].CSATConfigurationID]; !seen {
+				csatTypes[sessions[i].CSATConfigurationID] = ""
+				if config, err := s.CSATConfigRepo.GetByID(ctx, sessions[i].CSATConfigurationID); err == nil {
+					csatTypes[sessions[i].CSATConfigurationID] = config.Type
+				}
+			}
+		}
+		responses, err := responseRepo.ListBySessions(ctx, ids)
+		if err != nil {
+			return err
+		}
+		if err := s.loadExportQuestions(ctx, responses, questions); err != nil {
+			return err
+		}
+
+		for _, response := range responses {
+			session := sessionsByID[response.CSATSession]
+			if err := writer.Write(csatExportRow(session, questions[response.QuestionTemplate], csatTypes[session.CSATConfigurationID], &response)); err != nil {
+				return err
+			}
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return err
+		}
+		if len(sessions) < csatExportBatchSize {
+			break
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// loadExportQuestions adds the questions of responses missing from questions.
+func (s *CSATService) loadExportQuestions(ctx context.Context, responses []models.CSATResponse, questions map[primitive.ObjectID]*models.CSATQuestionTemplate) error {
+	var missing []primitive.ObjectID
+	for _, response := range responses {
+		if _, ok := questions[response.QuestionTemplate]; !ok {
+			questions[response.QuestionTemplate] = nil
+			missing = append(missing, response.QuestionTemplate)
+		}
+	}
+	loaded, err := s.CSATQuestionRepo.GetByIDs(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for i := range loaded {
+		questions[loaded[i].ID] = &loaded[i]
+	}
+	return nil
+}
+
+// csatExportRow renders one response as a CSV row. question is nil when its template was deleted
+// since, typically by replacing the configuration's questions.
+func csatExportRow(session *models.CSATSession, question *models.CSATQuestionTemplate, csatType string, response *models.CSATResponse) []string {
+	formatTime := func(t *time.Time) string {
+		if t == nil || t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	var order, text, score string
+	answerType := string(response.AnswerType)
+	if question != nil {
+		order = strconv.Itoa(question.Order)
+		text = question.QuestionText
+		if answerType == "" {
+			answerType = string(question.AnswerType)
+		}
+	}
+	if response.Score != nil {
+		score = strconv.FormatFloat(*response.Score, 'f', -1, 64)
+	}
+	return []string{
+		session.ID.Hex(),
+		csvSafe(session.ChatSessionID),
+		session.Client.Hex(),
+		session.ClientChannel.Hex(),
+		csatType,
+		session.Status,
+		session.Locale,
+		formatTime(&session.TriggeredAt),
+		formatTime(session.CompletedAt),
+		response.QuestionTemplate.Hex(),
+		order,
+		csvSafe(text),
+		answerType,
+		csvSafe(response.ResponseValue),
+		csvSafe(response.NormalizedValue),
+		score,
+		formatTime(&response.RespondedAt),
+	}
+}
+
+// csvSafe keeps spreadsheet applications from evaluating user-written cells as formulas.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// csatCompletionResults returns the question/answer pairs of a CSAT session in the order they were
+// answered, with the average of its scored answers overall and per answer type. The average is nil
+// when no answer was scored.
+func (s *CSATService) csatCompletionResults(ctx context.Context, session *models.CSATSession) ([]map[string]interface{}, *float64, map[string]float64, error) {
+	responses, err := s.CSATResponseRepo.ListBySessions(ctx, []primitive.ObjectID{session.ID})
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	questions := map[primitive.ObjectID]*models.CSATQuestionTemplate{}
+	if err := s.loadExportQuestions(ctx, responses, questions); err != nil {
+		return nil, nil, nil, err
+	}
+
+	results := make([]map[string]interface{}, 0, len(responses))
+	var sum float64
+	typeSums := map[string]float64{}
+	typeCounts := map[string]int{}
+	for _, response := range responses {
+		answerType := string(response.AnswerType)
+		result := map[string]interface{}{
+			"question_id":      response.QuestionTemplate.Hex(),
+			"response_value":   response.ResponseValue,
+			"normalized_value": response.NormalizedValue,
+			"score":            response.Score,
+			"responded_at":     response.RespondedAt,
+		}
+		if question := questions[response.QuestionTemplate]; question != nil {
+			result["question_text"] = question.QuestionText
+			result["order"] = question.Order
+			if answerType == "" {
+				answerType = string(question.AnswerType)
+			}
+		}
+		result["answer_type"] = answerType
+		results = append(results, result)
+
+		if response.Score != nil {
+			sum += *response.Score
+			typeSums[answerType] += *response.Score
+			typeCounts[answerType]++
+		}
+	}
+
+	var average *float64
+	typeAverages := map[string]float64{}
+	count := 0
+	for answerType, n := range typeCounts {
+		typeAverages[answerType] = typeSums[answerType] / float64(n)
+		count += n
+	}
+	if count > 0 {
+		avg := sum / float64(count)
+		average = &avg
+	}
+	return results, average, typeAverages, nil
+}
@@ -0,0 +1,135 @@
+// Package service provides business logic for localizing CSAT questions.
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// csatLanguageAttributes are the chat session attributes read, in order, for the end user's language
+var csatLanguageAttributes = []string{"language", "locale"}
+
+var localePattern = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})*$`)
+
+// normalizeLocale lowercases a locale and uses "-" as its separator, so "pt_BR" matches "pt-br".
+func normalizeLocale(locale string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(locale)), "_", "-")
+}
+
+// chatSessionLocale returns the end user's language recorded on the chat session, if any.
+func chatSessionLocale(session *models.ChatSession) string {
+	for _, key := range csatLanguageAttributes {
+		if locale := normalizeLocale(session.Attributes[key]); locale != "" {
+			return locale
+		}
+	}
+	return ""
+}
+
+// csatLocaleChain lists the locales tried for a question: the session's locale, then each fallback,
+// each followed by its primary language ("pt-br" then "pt"). The untranslated question comes last.
+func csatLocaleChain(locale string, fallbacks []string) []string {
+	var chain []string
+	seen := map[string]bool{}
+	add := func(l string) {
+		if l != "" && !seen[l] {
+			seen[l] = true
+			chain = append(chain, l)
+		}
+	}
+	for _, l := range append([]string{locale}, fallbacks...) {
+		l = normalizeLocale(l)
+		add(l)
+		if primary, _, ok := strings.Cut(l, "-"); ok {
+			add(primary)
+		}
+	}
+	return chain
+}
+
+// localizeCSATQuestion returns a copy of question with the text and options of the first
+// translation found along chain, and the locale used; that locale is empty when none matched.
+func localizeCSATQuestion(question *models.CSATQuestionTemplate, chain []string) (models.CSATQuestionTemplate, string) {
+	localized := *question
+	for _, locale := range chain {
+		for key, translation := range question.Translations {
+			if normalizeLocale(key) != locale {
+				continue
+			}
+			localized.QuestionText = translation.QuestionText
+			if len(translation.Options) > 0 {
+				localized.Options = translation.Options
+			}
+			return localized, locale
+		}
+	}
+	return localized, ""
+}
+
+// localizeQuestion localizes question for the language of the CSAT session, falling back through
+// its configuration's fallback locales.
+func (s *CSATService) localizeQuestion(ctx context.Context, session *models.CSATSession, question *models.CSATQuestionTemplate) (models.CSATQuestionTemplate, string) {
+	if len(question.Translations) == 0 {
+		return *question, ""
+	}
+	var fallbacks []string
+	if config, err := s.CSATConfigRepo.GetByID(ctx, session.CSATConfigurationID); err == nil {
+		fallbacks = config.FallbackLocales
+	}
+	return localizeCSATQuestion(question, csatLocaleChain(session.Locale, fallbacks))
+}
+
+// canonicalCSATOption maps an option picked from a translated question back to the question's own
+// option at the same position. Other values are returned unchanged.
+func canonicalCSATOption(question *models.CSATQuestionTemplate, value string) string {
+	for _, option := range question.Options {
+		if strings.EqualFold(value, strings.TrimSpace(option)) {
+			return value
+		}
+	}
+	for _, translation := range question.Translations {
+		for i, option := range translation.Options {
+			if i < len(question.Options) && strings.EqualFold(value, strings.TrimSpace(option)) {
+				return question.Options[i]
+			}
+		}
+	}
+	return value
+}
+
+// validateCSATTranslations checks that translation locales are well formed and unique, and that
+// translated options line up with the question's own.
+func validateCSATTranslations(q *models.CSATQuestionTemplate) error {
+	seen := map[string]string{}
+	for key, translation := range q.Translations {
+		locale := normalizeLocale(key)
+		if !localePattern.MatchString(locale) {
+			return fmt.Errorf("%w: question %d: invalid locale %q", ErrInvalidCSATQuestion, q.Order, key)
+		}
+		if other, dup := seen[locale]; dup {
+			return fmt.Errorf("%w: question %d: locales %q and %q are the same", ErrInvalidCSATQuestion, q.Order, other, key)
+		}
+		seen[locale] = key
+		if strings.TrimSpace(translation.QuestionText) == "" {
+			return fmt.Errorf("%w: question %d: %s translation needs question_text", ErrInvalidCSATQuestion, q.Order, key)
+		}
+		if len(translation.Options) > 0 && len(translation.Options) != len(q.Options) {
+			return fmt.Errorf("%w: question %d: %s translation has %d options, the question has %d", ErrInvalidCSATQuestion, q.Order, key, len(translation.Options), len(q.Options))
+		}
+	}
+	return nil
+}
+
+// ValidateCSATFallbackLocales checks the fallback locales of a CSAT configuration.
+func ValidateCSATFallbackLocales(locales []string) error {
+	for _, locale := range locales {
+		if !localePattern.MatchString(normalizeLocale(locale)) {
+			return fmt.Errorf("invalid fallback locale %q", locale)
+		}
+	}
+	return nil
+}
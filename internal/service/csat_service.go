@@ -11,8 +11,12 @@ import (
 	"github.com/fraiday-org/api-service/internal/repository"
 	"github.com/fraiday-org/api-service/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// CSATExperimentTagger returns the experiment variant a CSAT survey's chat session was in, or nil.
+type CSATExperimentTagger func(ctx context.Context, session *models.CSATSession) *models.ExperimentAssignment
+
 // CSATService encapsulates business logic for CSAT surveys.
 type CSATService struct {
 	CSATConfigRepo        *repository.CSATConfigurationRepository
@@ -24,6 +28,10 @@ type CSATService struct {
 	ThreadService         *ChatSessionThreadService
 	EventPublisherService *EventPublisherService
 	PayloadService        *PayloadService
+	TaskClient            CSATTaskClient       // Optional; required for bulk triggers, reminders and expiry
+	ConsentChecker        CSATConsentChecker   // Optional; nil allows every session
+	ExperimentTagger      CSATExperimentTagger // Optional; nil leaves responses untagged
+	AnalyticsDB           *mongo.Database      // Optional; exports read sessions and responses from it
 }
 
 // NewCSATService creates a new CSATService.
@@ -66,6 +74,13 @@ func parseSessionID(sessionID string) (baseSessionID string, threadFromSessionID
 	return sessionID, ""
 }
 
+// ChatSessionBySessionID returns the chat session that the external sessionID, possibly naming a
+// thread, belongs to.
+func (s *CSATService) ChatSessionBySessionID(ctx context.Context, sessionID string) (*models.ChatSession, error) {
+	baseSessionID, _ := parseSessionID(sessionID)
+	return s.ChatSessionRepo.GetBySessionID(ctx, baseSessionID)
+}
+
 // TriggerCSATSurveyBySessionID triggers a CSAT survey using external session_id and CSAT type.
 func (s *CSATService) TriggerCSATSurveyBySessionID(ctx context.Context, sessionID string, csatType string) (*models.CSATSession, error) {
 	// Validate CSAT type format
@@ -91,6 +106,12 @@ func (s *CSATService) TriggerCSATSurveyBySessionID(ctx context.Context, sessionI
 	
 	clientID := *chatSession.Client
 	channelID := *chatSession.ClientChannel
+
+	if s.ConsentChecker != nil {
+		if allowed, _ := s.ConsentChecker(ctx, chatSession); !allowed {
+			return nil, ErrCSATConsentDenied
+		}
+	}
 	
 	// 3. Determine target session context and thread information
 	var targetSessionContext string
@@ -122,11 +143,11 @@ func (s *CSATService) TriggerCSATSurveyBySessionID(ctx context.Context, sessionI
 	}
 	
 	// 4. Trigger CSAT with resolved context
-	return s.triggerCSATSurvey(ctx, targetSessionContext, clientID, channelID, csatType, threadSessionID, threadContext)
+	return s.triggerCSATSurvey(ctx, targetSessionContext, clientID, channelID, csatType, threadSessionID, threadContext, chatSessionLocale(chatSession))
 }
 
 // triggerCSATSurvey is the internal method that creates the CSAT session.
-func (s *CSATService) triggerCSATSurvey(ctx context.Context, chatSessionID string, clientID, channelID primitive.ObjectID, csatType string, threadSessionID *string, threadContext bool) (*models.CSATSession, error) {
+func (s *CSATService) triggerCSATSurvey(ctx context.Context, chatSessionID string, clientID, channelID primitive.ObjectID, csatType string, threadSessionID *string, threadContext bool, locale string) (*models.CSATSession, error) {
 	// Get type-specific configuration
 	config, err := s.CSATConfigRepo.GetByClientChannelAndType(ctx, clientID, channelID, csatType)
 	if err != nil {
@@ -151,6 +172,7 @@ func (s *CSATService) triggerCSATSurvey(ctx context.Context, chatSessionID strin
 		ClientChannel:        channelID,
 		ThreadSessionID:      threadSessionID,
 		ThreadContext:        threadContext,
+		Locale:               locale,
 		Status:               "pending",
 		CurrentQuestionIndex: 0,
 		QuestionsSent:        make([]primitive.ObjectID, 0),
@@ -188,6 +210,11 @@ func (s *CSATService) triggerCSATSurvey(ctx context.Context, chatSessionID strin
 	if err := s.SendNextQuestion(ctx, csatSession.ID); err != nil {
 		return nil, fmt.Errorf("failed to send first question: %w", err)
 	}
+
+	// The survey is already out, so a failed expiry schedule doesn't fail the trigger
+	if s.TaskClient != nil && config.ExpiryHours > 0 {
+		_ = s.TaskClient.EnqueueCSATExpiry(ctx, csatSession.ID.Hex(), time.Duration(config.ExpiryHours)*time.Hour)
+	}
 	
 	return csatSession, nil
 }
@@ -210,6 +237,11 @@ func (s *CSATService) SendNextQuestion(ctx context.Context, sessionID primitive.
 		return fmt.Errorf("no CSAT questions configured for this client and channel")
 	}
 	
+	// Branch rules on the question just answered may skip ahead or end the survey
+	if next, ok := s.nextCSATQuestionIndex(ctx, session, questions); ok {
+		session.CurrentQuestionIndex = next
+	}
+
 	// Check if we've sent all questions
 	if session.CurrentQuestionIndex >= len(questions) {
 		return s.CompleteCSATSurvey(ctx, sessionID)
@@ -219,7 +251,8 @@ func (s *CSATService) SendNextQuestion(ctx context.Context, sessionID primitive.
 	currentQuestion := questions[session.CurrentQuestionIndex]
 	
 	// Create chat message structure (but don't save to database)
-	chatMessageStructure, err := s.createQuestionMessageStructure(session, &currentQuestion)
+	localized, locale := s.localizeQuestion(ctx, session, &currentQuestion)
+	chatMessageStructure, err := s.createQuestionMessageStructure(session, &localized)
 	if err != nil {
 		return fmt.Errorf("failed to create question message structure: %w", err)
 	}
@@ -239,6 +272,9 @@ func (s *CSATService) SendNextQuestion(ctx context.Context, sessionID primitive.
 		"message_type":    "question",
 		"chat_message":    chatMessageStructure,
 	}
+	if locale != "" {
+		eventData["locale"] = locale
+	}
 	
 	// Get session ID for parent_id (use chat session context)
 	chatSessionIDStr := session.ChatSessionID
@@ -255,10 +291,143 @@ func (s *CSATService) SendNextQuestion(ctx context.Context, sessionID primitive.
 	if err != nil {
 		return fmt.Errorf("failed to publish CSAT message sent event: %w", err)
 	}
+
+	if s.TaskClient != nil {
+		config, err := s.CSATConfigRepo.GetByID(ctx, session.CSATConfigurationID)
+		if err == nil && config.ReminderMinutes > 0 {
+			_ = s.TaskClient.EnqueueCSATReminder(ctx, session.ID.Hex(), session.CurrentQuestionIndex, time.Duration(config.ReminderMinutes)*time.Minute)
+		}
+	}
 	
 	return nil
 }
 
+// SendReminder re-sends the question at questionIndex if the survey is still waiting on it, and
+// publishes it as a csat_reminder_sent event. Reminders for answered questions or finished
+// surveys are ignored.
+func (s *CSATService) SendReminder(ctx context.Context, csatSessionID string, questionIndex int) error {
+	id, err := primitive.ObjectIDFromHex(csatSessionID)
+	if err != nil {
+		return fmt.Errorf("invalid CSAT session ID: %w", err)
+	}
+	session, err := s.CSATSessionRepo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get CSAT session: %w", err)
+	}
+	if session.Status != "in_progress" || session.CurrentQuestionIndex != questionIndex {
+		return nil
+	}
+
+	questions, err := s.CSATQuestionRepo.GetByConfigurationID(ctx, session.CSATConfigurationID)
+	if err != nil {
+		return fmt.Errorf("failed to get CSAT questions: %w", err)
+	}
+	if questionIndex >= len(questions) {
+		return nil
+	}
+	question := questions[questionIndex]
+
+	localized, locale := s.localizeQuestion(ctx, session, &question)
+	chatMessageStructure, err := s.createQuestionMessageStructure(session, &localized)
+	if err != nil {
+		return fmt.Errorf("failed to create question message structure: %w", err)
+	}
+	if data, ok := chatMessageStructure["data"].(map[string]interface{}); ok {
+		data["reminder"] = true
+	}
+
+	now := time.Now().UTC()
+	session.ReminderSentAt = &now
+	if err := s.CSATSessionRepo.Update(ctx, session); err != nil {
+		return fmt.Errorf("failed to update CSAT session: %w", err)
+	}
+
+	chatSessionIDStr := session.ChatSessionID
+	eventData := map[string]interface{}{
+		"csat_session_id": session.ID.Hex(),
+		"question_id":     question.ID.Hex(),
+		"chat_session_id": session.ChatSessionID,
+		"message_type":    "reminder",
+		"chat_message":    chatMessageStructure,
+	}
+	if locale != "" {
+		eventData["locale"] = locale
+	}
+	_, err = s.EventPublisherService.PublishEvent(
+		ctx,
+		models.EventTypeCSATReminderSent,
+		models.EntityTypeCSATQuestion,
+		question.ID.Hex(),
+		&chatSessionIDStr,
+		eventData,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish CSAT reminder sent event: %w", err)
+	}
+	return nil
+}
+
+// ExpireSurvey closes a CSAT survey that is still pending or in progress once the expiry window of
+// its configuration has passed, and publishes a csat_expired event. A task that fires early is
+// pushed back to the deadline.
+func (s *CSATService) ExpireSurvey(ctx context.Context, csatSessionID string) error {
+	id, err := primitive.ObjectIDFromHex(csatSessionID)
+	if err != nil {
+		return fmt.Errorf("invalid CSAT session ID: %w", err)
+	}
+	session, err := s.CSATSessionRepo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get CSAT session: %w", err)
+	}
+	if session.Status != "pending" && session.Status != "in_progress" {
+		return nil
+	}
+
+	config, err := s.CSATConfigRepo.GetByID(ctx, session.CSATConfigurationID)
+	if err != nil {
+		return fmt.Errorf("failed to get CSAT configuration: %w", err)
+	}
+	// Expiry was switched off after the survey was triggered
+	if config.ExpiryHours <= 0 {
+		return nil
+	}
+	deadline := session.TriggeredAt.Add(time.Duration(config.ExpiryHours) * time.Hour)
+	if remaining := time.Until(deadline); remaining > time.Second {
+		if s.TaskClient == nil {
+			return nil
+		}
+		return s.TaskClient.EnqueueCSATExpiry(ctx, csatSessionID, remaining)
+	}
+
+	now := time.Now().UTC()
+	session.Status = "expired"
+	session.ExpiredAt = &now
+	if err := s.CSATSessionRepo.Update(ctx, session); err != nil {
+		return fmt.Errorf("failed to update CSAT session: %w", err)
+	}
+
+	chatSessionIDStr := session.ChatSessionID
+	eventData := map[string]interface{}{
+		"csat_session_id":    session.ID.Hex(),
+		"chat_session_id":    session.ChatSessionID,
+		"expired_at":         session.ExpiredAt,
+		"questions_answered": session.CurrentQuestionIndex,
+		"message_type":       "expiry",
+	}
+	_, err = s.EventPublisherService.PublishEvent(
+		ctx,
+		models.EventTypeCSATExpired,
+		models.EntityTypeCSATSession,
+		session.ID.Hex(),
+		&chatSessionIDStr,
+		eventData,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish CSAT expired event: %w", err)
+	}
+	return nil
+}
+
 // ProcessResponse processes a user response to a CSAT question.
 func (s *CSATService) ProcessResponse(ctx context.Context, sessionID primitive.ObjectID, questionID primitive.ObjectID, responseValue string) error {
 	// Get the CSAT session
@@ -270,12 +439,27 @@ func (s *CSATService) ProcessResponse(ctx context.Context, sessionID primitive.O
 	if session.Status != "in_progress" {
 		return fmt.Errorf("CSAT session is not in progress")
 	}
+
+	question, err := s.CSATQuestionRepo.GetByID(ctx, questionID)
+	if err != nil {
+		return fmt.Errorf("failed to get CSAT question: %w", err)
+	}
+	normalized, score, err := NormalizeCSATAnswer(question, responseValue)
+	if err != nil {
+		return err
+	}
 	
 	// Save the response
 	response := &models.CSATResponse{
 		CSATSession:      sessionID,
 		QuestionTemplate: questionID,
 		ResponseValue:    responseValue,
+		NormalizedValue:  normalized,
+		Score:            score,
+		AnswerType:       question.AnswerType,
+	}
+	if s.ExperimentTagger != nil {
+		response.Experiment = s.ExperimentTagger(ctx, session)
 	}
 	
 	if err := s.CSATResponseRepo.Create(ctx, response); err != nil {
@@ -339,6 +523,11 @@ func (s *CSATService) ProcessResponseBySessionID(ctx context.Context, sessionID,
 	if currentQuestionIndex == -1 {
 		return "", fmt.Errorf("question not found in current survey")
 	}
+
+	normalized, score, err := NormalizeCSATAnswer(&questions[currentQuestionIndex], responseValue)
+	if err != nil {
+		return "", err
+	}
 	
 	// 6. Check if response already exists for this question
 	var responseID string
@@ -346,6 +535,9 @@ func (s *CSATService) ProcessResponseBySessionID(ctx context.Context, sessionID,
 	if err == nil && existingResponse != nil {
 		// EXISTING RESPONSE: Update only, do NOT send next question
 		existingResponse.ResponseValue = responseValue
+		existingResponse.NormalizedValue = normalized
+		existingResponse.Score = score
+		existingResponse.AnswerType = questions[currentQuestionIndex].AnswerType
 		if err := s.CSATResponseRepo.Update(ctx, existingResponse); err != nil {
 			return "", fmt.Errorf("failed to update CSAT response: %w", err)
 		}
@@ -359,6 +551,12 @@ func (s *CSATService) ProcessResponseBySessionID(ctx context.Context, sessionID,
 			CSATSession:      csatSession.ID,
 			QuestionTemplate: questionObjID,
 			ResponseValue:    responseValue,
+			NormalizedValue:  normalized,
+			Score:            score,
+			AnswerType:       questions[currentQuestionIndex].AnswerType,
+		}
+		if s.ExperimentTagger != nil {
+			response.Experiment = s.ExperimentTagger(ctx, csatSession)
 		}
 		
 		if err := s.CSATResponseRepo.Create(ctx, response); err != nil {
@@ -420,12 +618,30 @@ func (s *CSATService) CompleteCSATSurvey(ctx context.Context, sessionID primitiv
 		"updated_at": now,
 	}
 	
+	// Include every answer and the aggregate score so processors can sync results downstream
+	responses, aggregateScore, scoresByType, err := s.csatCompletionResults(ctx, session)
+	if err != nil {
+		return fmt.Errorf("failed to load CSAT responses: %w", err)
+	}
+	var csatType string
+	if config, err := s.CSATConfigRepo.GetByID(ctx, session.CSATConfigurationID); err == nil {
+		csatType = config.Type
+	}
+	
 	// Publish CSAT completed event with thank you message structure
 	chatSessionIDStr := session.ChatSessionID
 	eventData := map[string]interface{}{
 		"csat_session_id": session.ID.Hex(),
 		"chat_session_id": session.ChatSessionID,
+		"client_id":       session.Client.Hex(),
+		"channel_id":      session.ClientChannel.Hex(),
+		"csat_type":       csatType,
+		"locale":          session.Locale,
+		"triggered_at":    session.TriggeredAt,
 		"completed_at":    session.CompletedAt,
+		"responses":       responses,
+		"aggregate_score": aggregateScore,
+		"scores_by_type":  scoresByType,
 		"message_type":    "completion",
 		"chat_message":    thankYouMessageStructure,
 	}
@@ -448,20 +664,20 @@ func (s *CSATService) CompleteCSATSurvey(ctx context.Context, sessionID primitiv
 // createQuestionMessageStructure creates a chat message structure for CSAT questions without database persistence.
 func (s *CSATService) createQuestionMessageStructure(session *models.CSATSession, question *models.CSATQuestionTemplate) (map[string]interface{}, error) {
 	// Create postback buttons with CSAT payload format
-	buttons := make([]map[string]interface{}, 0)
-	for _, option := range question.Options {
-		button := map[string]interface{}{
-			"type":    "postback",
-			"text":    option,
-			"payload": fmt.Sprintf("csat:%s:%s", question.ID.Hex(), option),
+	buttons := make([]models.AttachmentButton, 0)
+	for _, option := range csatQuestionOptions(question) {
+		button := models.AttachmentButton{
+			Type:    "postback",
+			Title:   option,
+			Payload: fmt.Sprintf("csat:%s:%s", question.ID.Hex(), option),
 		}
 		buttons = append(buttons, button)
 	}
 	
 	// Create buttons attachment (not carousel)
-	attachment := map[string]interface{}{
-		"type":    "buttons",
-		"buttons": buttons,
+	attachment := models.Attachment{
+		Type:    models.AttachmentTypeButtons,
+		Buttons: buttons,
 	}
 	
 	// Generate a temporary ID for the message structure
@@ -475,17 +691,24 @@ func (s *CSATService) createQuestionMessageStructure(session *models.CSATSession
 		"sender_type": string(models.SenderTypeSystem),
 		"session_id":  session.ChatSessionID, // Use actual chat session ID
 		"text":        question.QuestionText,
-		"attachments": []map[string]interface{}{attachment},
+		"attachments": []models.Attachment{attachment},
 		"category":    string(models.MessageCategoryInfo),
 		"data": map[string]interface{}{
 			"csat_message":    true,
 			"csat_session_id": session.ID.Hex(),
 			"question_id":     question.ID.Hex(),
-			"options":         question.Options,
+			"options":         csatQuestionOptions(question),
 		},
 		"created_at": time.Now().UTC(),
 		"updated_at": time.Now().UTC(),
 	}
+	if question.AnswerType != "" {
+		chatMessageStructure["data"].(map[string]interface{})["answer_type"] = string(question.AnswerType)
+	}
+	// Free-text questions are answered by typing, so there is nothing to click
+	if len(buttons) == 0 {
+		delete(chatMessageStructure, "attachments")
+	}
 	
 	return chatMessageStructure, nil
 }
@@ -496,19 +719,19 @@ func (s *CSATService) createQuestionMessage(session *models.CSATSession, questio
 	var attachments []models.Attachment
 	
 	// Create postback buttons for options
-	buttons := make([]map[string]interface{}, 0)
-	for _, option := range question.Options {
-		button := map[string]interface{}{
-			"type":    "postback",
-			"text":    option,
-			"payload": fmt.Sprintf("csat:%s:%s", question.ID.Hex(), option),
+	buttons := make([]models.AttachmentButton, 0)
+	for _, option := range csatQuestionOptions(question) {
+		button := models.AttachmentButton{
+			Type:    "postback",
+			Title:   option,
+			Payload: fmt.Sprintf("csat:%s:%s", question.ID.Hex(), option),
 		}
 		buttons = append(buttons, button)
 	}
 	
 	// Create buttons attachment (not carousel)
 	attachment := models.Attachment{
-		Type:    "buttons",
+		Type:    models.AttachmentTypeButtons,
 		Buttons: buttons,
 	}
 	attachments = append(attachments, attachment)
@@ -525,7 +748,7 @@ func (s *CSATService) createQuestionMessage(session *models.CSATSession, questio
 			"csat_message":    true,
 			"csat_session_id": session.ID.Hex(),
 			"question_id":     question.ID.Hex(),
-			"options":         question.Options,
+			"options":         csatQuestionOptions(question),
 		},
 	}, nil
 }
@@ -9,6 +9,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
@@ -17,14 +18,21 @@ type DatabaseService struct {
 	logger     *zap.Logger
 	mongoClient *mongo.Client
 	database   *mongo.Database
+	// Clients, when set, serves client lookups from memory
+	Clients ClientCache
+	// ContextMessages bounds how many recent messages GetSessionContext returns
+	ContextMessages int
+	// ContextCache, when set, keeps session contexts in Redis between tasks
+	ContextCache *SessionContextCache
 }
 
 // NewDatabaseService creates a new database service
 func NewDatabaseService(logger *zap.Logger, mongoClient *mongo.Client, dbName string) *DatabaseService {
 	return &DatabaseService{
-		logger:      logger,
-		mongoClient: mongoClient,
-		database:    mongoClient.Database(dbName),
+		logger:          logger,
+		mongoClient:     mongoClient,
+		database:        mongoClient.Database(dbName),
+		ContextMessages: DefaultSessionContextMessages,
 	}
 }
 
@@ -120,6 +128,36 @@ func (db *DatabaseService) GetChatSessionByID(ctx context.Context, sessionID str
 	return &session, nil
 }
 
+// IsSandboxSession reports whether the session with the given session_id was created in sandbox
+// mode and gets canned replies. Sessions of simulations are sandbox sessions answered by the real
+// AI, so they are not reported.
+func (db *DatabaseService) IsSandboxSession(ctx context.Context, sessionID string) bool {
+	collection := db.database.Collection("chat_sessions")
+
+	var session models.ChatSession
+	opts := options.FindOne().SetProjection(bson.M{"test": 1, "simulation": 1})
+	if err := collection.FindOne(ctx, bson.M{"session_id": sessionID}, opts).Decode(&session); err != nil {
+		return false
+	}
+	return session.Test && session.Simulation == nil
+}
+
+// IsSandboxClient reports whether the client is in sandbox mode
+func (db *DatabaseService) IsSandboxClient(ctx context.Context, clientID primitive.ObjectID) bool {
+	if db.Clients != nil {
+		client, err := db.Clients.GetClientByID(ctx, clientID)
+		return err == nil && client.Sandbox
+	}
+	collection := db.database.Collection("clients")
+
+	var client models.Client
+	opts := options.FindOne().SetProjection(bson.M{"sandbox": 1})
+	if err := collection.FindOne(ctx, bson.M{"_id": clientID}, opts).Decode(&client); err != nil {
+		return false
+	}
+	return client.Sandbox
+}
+
 // SaveChatMessage saves or updates a chat message
 func (db *DatabaseService) SaveChatMessage(ctx context.Context, message *ChatMessage) error {
 	collection := db.database.Collection("chat_messages")
@@ -186,34 +224,265 @@ func (db *DatabaseService) UpdateEventDeliveryRecord(ctx context.Context, record
 	return nil
 }
 
-// GetSessionContext retrieves context information for a chat session
+// GetSessionContext returns the context of the chat session with the given session_id: its last
+// ContextMessages messages, oldest first. Messages reference their session by ObjectID, so the
+// session is looked up first; an unknown session has no messages.
 func (db *DatabaseService) GetSessionContext(ctx context.Context, sessionID string) (map[string]interface{}, error) {
-	// Get recent messages from the session for context
-	collection := db.database.Collection("chat_messages")
-	
-	cursor, err := collection.Find(ctx, bson.M{
-		"session_id": sessionID,
-	}, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get session messages: %w", err)
+	var session ChatSession
+	opts := options.FindOne().SetProjection(bson.M{"_id": 1})
+	err := db.database.Collection("chat_sessions").FindOne(ctx, bson.M{"session_id": sessionID}, opts).Decode(&session)
+	if err != nil && err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to get session: %w", err)
 	}
-	defer cursor.Close(ctx)
-	
+
 	var messages []ChatMessage
-	if err := cursor.All(ctx, &messages); err != nil {
-		return nil, fmt.Errorf("failed to decode messages: %w", err)
+	if err == nil {
+		if messages, err = db.recentMessages(ctx, session.ID); err != nil {
+			return nil, fmt.Errorf("failed to get session messages: %w", err)
+		}
 	}
-	
+
 	// Build context from messages
 	context := map[string]interface{}{
-		"session_id":     sessionID,
-		"message_count":  len(messages),
+		"session_id":      sessionID,
+		"message_count":   len(messages),
 		"recent_messages": messages,
 	}
-	
+
 	return context, nil
 }
 
+// recentMessages returns the last ContextMessages messages of a session through ContextCache when
+// it is set. When Redis fails, they are read from MongoDB instead.
+func (db *DatabaseService) recentMessages(ctx context.Context, sessionID primitive.ObjectID) ([]ChatMessage, error) {
+	load := func(ctx context.Context, since time.Time, limit int) ([]ChatMessage, error) {
+		return db.loadRecentMessages(ctx, sessionID, since, limit)
+	}
+	if db.ContextCache != nil {
+		messages, err := db.ContextCache.Messages(ctx, sessionID, db.ContextMessages, load)
+		if err == nil {
+			return messages, nil
+		}
+		db.logger.Warn("Session context cache failed, reading messages from MongoDB",
+			zap.String("session", sessionID.Hex()), zap.Error(err))
+	}
+	return load(ctx, time.Time{}, db.ContextMessages)
+}
+
+// loadRecentMessages reads the last limit messages of a session created at or after since, oldest
+// first. A zero since means from the start of the session.
+func (db *DatabaseService) loadRecentMessages(ctx context.Context, sessionID primitive.ObjectID, since time.Time, limit int) ([]ChatMessage, error) {
+	filter := bson.M{"session": sessionID}
+	if !since.IsZero() {
+		filter["created_at"] = bson.M{"$gte": since}
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := db.database.Collection("chat_messages").Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var messages []ChatMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+		messages[i], messages[j] = messages[j], messages[i]
+	}
+	return messages, nil
+}
+
+// maxReplyChainDepth bounds how far GetReplyChain walks up parent references
+const maxReplyChainDepth = 20
+
+// GetReplyChain returns the messages a reply belongs with, oldest first: its ancestors up to
+// the root message, followed by earlier replies to the same parent. Returns nil for non-replies.
+func (db *DatabaseService) GetReplyChain(ctx context.Context, message *ChatMessage) ([]map[string]interface{}, error) {
+	if message.ParentMessageID == nil {
+		return nil, nil
+	}
+	collection := db.database.Collection("chat_messages")
+
+	// Walk up to the root
+	var ancestors []ChatMessage
+	parentID := message.ParentMessageID
+	for depth := 0; parentID != nil && depth < maxReplyChainDepth; depth++ {
+		var parent ChatMessage
+		if err := collection.FindOne(ctx, bson.M{"_id": *parentID}).Decode(&parent); err != nil {
+			if err == mongo.ErrNoDocuments {
+				break
+			}
+			return nil, fmt.Errorf("failed to get parent message: %w", err)
+		}
+		ancestors = append([]ChatMessage{parent}, ancestors...)
+		parentID = parent.ParentMessageID
+	}
+
+	// Earlier siblings under the same parent
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(maxReplyChainDepth)
+	cursor, err := collection.Find(ctx, bson.M{
+		"parent_message": *message.ParentMessageID,
+		"_id":            bson.M{"$ne": message.ID},
+		"created_at":     bson.M{"$lte": message.CreatedAt},
+	}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sibling replies: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var siblings []ChatMessage
+	if err := cursor.All(ctx, &siblings); err != nil {
+		return nil, fmt.Errorf("failed to decode sibling replies: %w", err)
+	}
+
+	chain := make([]map[string]interface{}, 0, len(ancestors)+len(siblings))
+	for _, m := range append(ancestors, siblings...) {
+		entry := map[string]interface{}{
+			"id":          m.ID.Hex(),
+			"sender":      m.Sender,
+			"sender_type": m.SenderType,
+			"text":        m.Text,
+			"created_at":  m.CreatedAt,
+		}
+		if m.ParentMessageID != nil {
+			entry["parent_message_id"] = m.ParentMessageID.Hex()
+		}
+		chain = append(chain, entry)
+	}
+
+	return chain, nil
+}
+
+// GetContactHistory returns what the AI is told about the earlier sessions of the contact who
+// sent message, newest first: the latest recap of each session started within the bounds of the
+// client's contact history policy. Sessions that were never recapped are left out. Returns nil
+// when the message has no contact or the client hasn't enabled the history.
+func (db *DatabaseService) GetContactHistory(ctx context.Context, message *ChatMessage) ([]map[string]interface{}, error) {
+	if message.Contact == nil {
+		return nil, nil
+	}
+
+	var session models.ChatSession
+	opts := options.FindOne().SetProjection(bson.M{"client": 1})
+	if err := db.database.Collection("chat_sessions").FindOne(ctx, bson.M{"_id": message.SessionID}, opts).Decode(&session); err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session.Client == nil {
+		return nil, nil
+	}
+	policy, err := db.contactHistoryPolicy(ctx, *session.Client)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil || !policy.Enabled {
+		return nil, nil
+	}
+
+	filter := bson.M{"contact": *message.Contact, "_id": bson.M{"$ne": message.SessionID}}
+	if policy.MaxAgeDays > 0 {
+		filter["created_at"] = bson.M{"$gte": time.Now().UTC().AddDate(0, 0, -policy.MaxAgeDays)}
+	}
+	maxSessions := policy.MaxSessions
+	if maxSessions <= 0 || maxSessions > models.MaxContactHistorySessions {
+		maxSessions = models.DefaultContactHistorySessions
+	}
+	findOpts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(maxSessions)).
+		SetProjection(bson.M{"session_id": 1, "created_at": 1, "closed_at": 1})
+	cursor, err := db.database.Collection("chat_sessions").Find(ctx, filter, findOpts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get contact sessions: %w", err)
+	}
+	var sessions []models.ChatSession
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to decode contact sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]primitive.ObjectID, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.ID
+	}
+	cursor, err = db.database.Collection("chat_session_recaps").Find(ctx,
+		bson.M{"session_id": bson.M{"$in": ids}},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session recaps: %w", err)
+	}
+	var recaps []models.ChatSessionRecap
+	if err := cursor.All(ctx, &recaps); err != nil {
+		return nil, fmt.Errorf("failed to decode session recaps: %w", err)
+	}
+	latest := make(map[primitive.ObjectID]map[string]interface{}, len(recaps))
+	for _, recap := range recaps {
+		if _, seen := latest[recap.SessionID]; !seen {
+			latest[recap.SessionID] = recap.RecapData
+		}
+	}
+
+	history := make([]map[string]interface{}, 0, len(sessions))
+	for _, s := range sessions {
+		recap, ok := latest[s.ID]
+		if !ok {
+			continue
+		}
+		entry := map[string]interface{}{
+			"session_id": s.SessionID,
+			"started_at": s.CreatedAt,
+		}
+		if s.ClosedAt != nil {
+			entry["closed_at"] = *s.ClosedAt
+		}
+		for _, key := range []string{"summary", "topics", "resolution"} {
+			if v, ok := recap[key]; ok {
+				entry[key] = v
+			}
+		}
+		history = append(history, entry)
+	}
+	return history, nil
+}
+
+// ContactOptedOut reports whether the contact who sent message has opted out of purpose. Messages
+// without a contact, and those of contacts deleted since, have no opt-outs.
+func (db *DatabaseService) ContactOptedOut(ctx context.Context, message *ChatMessage, purpose string) (bool, error) {
+	if message.Contact == nil {
+		return false, nil
+	}
+	var contact models.Contact
+	opts := options.FindOne().SetProjection(bson.M{"consent": 1})
+	err := db.database.Collection(models.Contact{}.TableName()).FindOne(ctx, bson.M{"_id": *message.Contact}, opts).Decode(&contact)
+	if err == mongo.ErrNoDocuments {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get contact: %w", err)
+	}
+	return contact.OptedOut(purpose), nil
+}
+
+// contactHistoryPolicy returns the contact history policy of a client, through Clients when it is set.
+func (db *DatabaseService) contactHistoryPolicy(ctx context.Context, clientID primitive.ObjectID) (*models.ContactHistoryPolicy, error) {
+	if db.Clients != nil {
+		client, err := db.Clients.GetClientByID(ctx, clientID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get client: %w", err)
+		}
+		return client.ContactHistory, nil
+	}
+
+	var client models.Client
+	opts := options.FindOne().SetProjection(bson.M{"contact_history": 1})
+	if err := db.database.Collection("clients").FindOne(ctx, bson.M{"_id": clientID}, opts).Decode(&client); err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	return client.ContactHistory, nil
+}
+
 // GetCSATSession retrieves a CSAT session by ID
 func (db *DatabaseService) GetCSATSession(ctx context.Context, sessionID string) (*models.CSATSession, error) {
 	collection := db.database.Collection("csat_sessions")
@@ -0,0 +1,142 @@
+// Package service provides business logic for reporting AI messages that could not be delivered.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// deliveryFailureEvent is the event name sent in failure callbacks
+const deliveryFailureEvent = "message_delivery_failed"
+
+// deliveryFailureCallbackTimeout bounds a call to a client's failure callback
+const deliveryFailureCallbackTimeout = 10 * time.Second
+
+// deliveryFailureCallback is the body POSTed to a client's delivery failure callback.
+type deliveryFailureCallback struct {
+	Event       string    `json:"event"`
+	ClientID    string    `json:"client_id"`
+	SessionID   string    `json:"session_id"`
+	MessageID   string    `json:"message_id"`
+	DeliveryID  string    `json:"delivery_id"`
+	ProcessorID string    `json:"processor_id"`
+	Error       string    `json:"error"`
+	FailedAt    time.Time `json:"failed_at"`
+}
+
+// DeliveryFailureService marks AI messages whose delivery to the channel processor was abandoned
+// and notifies the owning client.
+type DeliveryFailureService struct {
+	ChatMessageRepo *repository.ChatMessageRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	ClientRepo      *repository.ClientRepository
+	logger          *zap.Logger
+	httpClient      *http.Client
+}
+
+// NewDeliveryFailureService creates a new DeliveryFailureService.
+func NewDeliveryFailureService(
+	chatMessageRepo *repository.ChatMessageRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	clientRepo *repository.ClientRepository,
+	logger *zap.Logger,
+) *DeliveryFailureService {
+	return &DeliveryFailureService{
+		ChatMessageRepo: chatMessageRepo,
+		ChatSessionRepo: chatSessionRepo,
+		ClientRepo:      clientRepo,
+		logger:          logger,
+		httpClient:      newEgressClient(nil, deliveryFailureCallbackTimeout),
+	}
+}
+
+// SetEgressPolicy limits the hosts failure callbacks can reach to those policy allows.
+func (s *DeliveryFailureService) SetEgressPolicy(policy *egress.Policy) {
+	s.httpClient = newEgressClient(policy, deliveryFailureCallbackTimeout)
+}
+
+// HandleExhausted is called once every attempt to deliver an event to a processor has failed.
+// Only chat_message events for assistant messages are reported; anything else is ignored.
+func (s *DeliveryFailureService) HandleExhausted(ctx context.Context, processorID, deliveryID string, eventData map[string]interface{}, cause string) error {
+	if entityType, _ := eventData["entity_type"].(string); entityType != string(models.EntityTypeChatMessage) {
+		return nil
+	}
+	entityID, _ := eventData["entity_id"].(string)
+	messageID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q", entityID)
+	}
+
+	msg, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat message: %w", err)
+	}
+	if msg.SenderType != string(models.SenderTypeAssistant) {
+		return nil
+	}
+
+	if err := s.ChatMessageRepo.Update(ctx, msg.ID, bson.M{
+		"delivery_status": models.MessageDeliveryStatusFailed,
+		"delivery_error":  cause,
+	}); err != nil {
+		return fmt.Errorf("failed to mark message delivery failed: %w", err)
+	}
+
+	session, err := s.ChatSessionRepo.GetByID(ctx, msg.SessionID)
+	if err != nil || session.Client == nil {
+		return nil
+	}
+	client, err := s.ClientRepo.GetByID(ctx, *session.Client)
+	if err != nil || client.DeliveryFailureCallbackURL == "" {
+		return nil
+	}
+
+	return s.notify(ctx, client.DeliveryFailureCallbackURL, deliveryFailureCallback{
+		Event:       deliveryFailureEvent,
+		ClientID:    client.ClientID,
+		SessionID:   session.SessionID,
+		MessageID:   msg.ID.Hex(),
+		DeliveryID:  deliveryID,
+		ProcessorID: processorID,
+		Error:       cause,
+		FailedAt:    time.Now().UTC(),
+	})
+}
+
+func (s *DeliveryFailureService) notify(ctx context.Context, url string, body deliveryFailureCallback) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal failure callback: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create failure callback request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Fraiday-Events/1.0")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failure callback request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failure callback returned status %d", resp.StatusCode)
+	}
+
+	s.logger.Info("Sent delivery failure callback",
+		zap.String("client_id", body.ClientID),
+		zap.String("message_id", body.MessageID))
+	return nil
+}
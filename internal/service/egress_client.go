@@ -0,0 +1,26 @@
+// Package service provides the HTTP client for URLs that clients configure.
+package service
+
+import (
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+)
+
+// newEgressClient returns an HTTP client for URLs that clients configure, such as hooks and
+// callbacks. Every request and connection is checked against policy, the same one webhook
+// dispatch uses, so those URLs can't reach the deployment's own network. A nil policy blocks
+// private addresses. A zero timeout leaves deadlines to each request's context.
+func newEgressClient(policy *egress.Policy, timeout time.Duration) *http.Client {
+	if policy == nil {
+		policy, _ = egress.NewPolicy("", "", false)
+	}
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = policy.DialContext(dialer.DialContext)
+	return &http.Client{Timeout: timeout, Transport: telemetry.Transport(policy.Transport(transport))}
+}
@@ -0,0 +1,444 @@
+// Package service provides business logic for sending replies of the email channel connector.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	sendGridAPIURL = "https://api.sendgrid.com/v3/mail/send"
+	// Outbound attachments are fetched and attached up to these sizes; larger files are only linked
+	maxOutboundAttachmentBytes = 10 << 20
+	maxOutboundEmailBytes      = 20 << 20
+	smtpTimeout                = 30 * time.Second
+)
+
+// outboundEmail is a reply ready to be handed to the channel's provider.
+type outboundEmail struct {
+	From        string
+	FromName    string
+	To          string
+	Subject     string
+	MessageID   string
+	InReplyTo   string
+	References  []string
+	Text        string
+	HTML        string
+	Attachments []emailAttachment
+}
+
+// send hands an email to the channel's provider.
+func (s *EmailService) send(ctx context.Context, channel *models.ClientChannel, email *outboundEmail) error {
+	provider, _ := channel.ChannelConfig["provider"].(string)
+	switch provider {
+	case "smtp":
+		return sendSMTP(ctx, channel.ChannelConfig, email)
+	case "sendgrid":
+		apiKey, _ := channel.ChannelConfig["sendgrid_api_key"].(string)
+		return s.sendSendGrid(ctx, apiKey, email)
+	default:
+		return fmt.Errorf("unsupported email provider %q", provider)
+	}
+}
+
+// SendReport emails a report from an email channel's address to each recipient, through the
+// channel's provider. Recipients are sent separate emails, so they don't see each other.
+func (s *EmailService) SendReport(ctx context.Context, channel *models.ClientChannel, recipients []string, subject, text, htmlBody string) error {
+	resolved := *channel
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, &resolved); err != nil {
+		return err
+	}
+	channel = &resolved
+	address, _ := channel.ChannelConfig["address"].(string)
+	fromName, _ := channel.ChannelConfig["from_name"].(string)
+	var errs []error
+	for _, to := range recipients {
+		err := s.send(ctx, channel, &outboundEmail{
+			From:      address,
+			FromName:  fromName,
+			To:        to,
+			Subject:   subject,
+			MessageID: "<report-" + primitive.NewObjectID().Hex() + "@" + emailDomain(address) + ">",
+			Text:      text,
+			HTML:      htmlBody,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", to, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendSMTP delivers email through the channel's SMTP server. Port 465 uses implicit TLS; other
+// ports upgrade with STARTTLS when the server offers it.
+func sendSMTP(ctx context.Context, config map[string]interface{}, email *outboundEmail) error {
+	host, _ := config["smtp_host"].(string)
+	port := 587
+	switch v := config["smtp_port"].(type) {
+	case float64:
+		port = int(v)
+	case int32:
+		port = int(v)
+	case int64:
+		port = int(v)
+	case string:
+		if p, err := strconv.Atoi(v); err == nil {
+			port = p
+		}
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	raw, err := email.mime()
+	if err != nil {
+		return err
+	}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	var conn net.Conn
+	if port == 465 {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}).DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to smtp server: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("smtp handshake failed: %w", err)
+	}
+	defer client.Close()
+
+	if port != 465 {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return fmt.Errorf("smtp starttls failed: %w", err)
+			}
+		}
+	}
+	if username, _ := config["smtp_username"].(string); username != "" {
+		password, _ := config["smtp_password"].(string)
+		if err := client.Auth(smtp.PlainAuth("", username, password, host)); err != nil {
+			return fmt.Errorf("smtp auth failed: %w", err)
+		}
+	}
+	if err := client.Mail(email.From); err != nil {
+		return fmt.Errorf("smtp MAIL FROM rejected: %w", err)
+	}
+	if err := client.Rcpt(email.To); err != nil {
+		return fmt.Errorf("smtp RCPT TO rejected: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA rejected: %w", err)
+	}
+	if _, err := w.Write(raw); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("smtp server rejected email: %w", err)
+	}
+	return client.Quit()
+}
+
+// sendSendGrid delivers email through the SendGrid v3 mail send API.
+func (s *EmailService) sendSendGrid(ctx context.Context, apiKey string, email *outboundEmail) error {
+	headers := map[string]string{}
+	if email.InReplyTo != "" {
+		headers["In-Reply-To"] = email.InReplyTo
+	}
+	if len(email.References) > 0 {
+		headers["References"] = strings.Join(email.References, " ")
+	}
+	body := map[string]interface{}{
+		"personalizations": []map[string]interface{}{{"to": []map[string]string{{"email": email.To}}}},
+		"from":             map[string]string{"email": email.From, "name": email.FromName},
+		"subject":          email.Subject,
+		"content": []map[string]string{
+			{"type": "text/plain", "value": email.Text},
+			{"type": "text/html", "value": email.HTML},
+		},
+		"headers": headers,
+	}
+	if len(email.Attachments) > 0 {
+		attachments := make([]map[string]string, len(email.Attachments))
+		for i, a := range email.Attachments {
+			attachments[i] = map[string]string{
+				"content":     base64.StdEncoding.EncodeToString(a.Content),
+				"filename":    a.FileName,
+				"type":        a.ContentType,
+				"disposition": "attachment",
+			}
+		}
+		body["attachments"] = attachments
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sendgrid request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.SendGridAPIURL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create sendgrid request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sendgrid request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("sendgrid returned status %d: %s", resp.StatusCode, string(detail))
+	}
+	return nil
+}
+
+// mime renders the email as a multipart/mixed message with text and HTML alternatives.
+func (e *outboundEmail) mime() ([]byte, error) {
+	var buf bytes.Buffer
+	mixed := multipart.NewWriter(&buf)
+
+	from := (&mail.Address{Name: e.FromName, Address: e.From}).String()
+	header := []string{
+		"From: " + from,
+		"To: " + (&mail.Address{Address: e.To}).String(),
+		"Subject: " + mime.QEncoding.Encode("utf-8", e.Subject),
+		"Date: " + time.Now().UTC().Format(time.RFC1123Z),
+		"Message-ID: " + e.MessageID,
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/mixed; boundary=" + mixed.Boundary(),
+	}
+	if e.InReplyTo != "" {
+		header = append(header, "In-Reply-To: "+e.InReplyTo)
+	}
+	if len(e.References) > 0 {
+		header = append(header, "References: "+strings.Join(e.References, " "))
+	}
+	var out bytes.Buffer
+	out.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	var alternative bytes.Buffer
+	alt := multipart.NewWriter(&alternative)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", e.Text},
+		{"text/html; charset=utf-8", e.HTML},
+	} {
+		w, err := alt.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64Lines(w, []byte(part.body))
+	}
+	if err := alt.Close(); err != nil {
+		return nil, err
+	}
+	w, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {"multipart/alternative; boundary=" + alt.Boundary()}})
+	if err != nil {
+		return nil, err
+	}
+	w.Write(alternative.Bytes())
+
+	for _, a := range e.Attachments {
+		contentType := a.ContentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+		w, err := mixed.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.FileName})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64Lines(w, a.Content)
+	}
+	if err := mixed.Close(); err != nil {
+		return nil, err
+	}
+
+	out.Write(buf.Bytes())
+	return out.Bytes(), nil
+}
+
+// writeBase64Lines writes content as base64 wrapped at 76 characters, as RFC 2045 requires.
+func writeBase64Lines(w io.Writer, content []byte) {
+	encoded := base64.StdEncoding.EncodeToString(content)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// renderEmailHTML renders message text and attachments as a simple HTML email. Buttons become
+// links when they have a URL and a list of reply options otherwise.
+func renderEmailHTML(text string, attachments []models.Attachment) string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><body style="font-family:Arial,Helvetica,sans-serif;font-size:14px;line-height:1.5;color:#222">`)
+	for _, paragraph := range strings.Split(strings.TrimSpace(text), "\n\n") {
+		if paragraph = strings.TrimSpace(paragraph); paragraph != "" {
+			b.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(paragraph), "\n", "<br>") + "</p>")
+		}
+	}
+
+	for _, attachment := range attachments {
+		for _, item := range attachment.CarouselItems() {
+			b.WriteString(`<div style="border:1px solid #ddd;border-radius:6px;padding:12px;margin:12px 0">`)
+			if isAbsoluteURL(item.MediaURL) {
+				b.WriteString(`<img src="` + html.EscapeString(item.MediaURL) + `" alt="" style="max-width:100%;border-radius:4px">`)
+			}
+			if item.Title != "" {
+				b.WriteString("<p><strong>" + html.EscapeString(item.Title) + "</strong></p>")
+			}
+			if item.Description != "" {
+				b.WriteString("<p>" + html.EscapeString(item.Description) + "</p>")
+			}
+			writeEmailButtons(&b, item.Buttons)
+			b.WriteString("</div>")
+		}
+
+		writeEmailButtons(&b, attachment.AllButtons())
+
+		if !isAbsoluteURL(attachment.FileURL) {
+			continue
+		}
+		fileURL := html.EscapeString(attachment.FileURL)
+		if attachment.Type == "image" {
+			b.WriteString(`<p><img src="` + fileURL + `" alt="` + html.EscapeString(attachment.FileName) + `" style="max-width:100%"></p>`)
+		} else if attachment.Type == "file" {
+			name := attachment.FileName
+			if name == "" {
+				name = path.Base(attachment.FileURL)
+			}
+			b.WriteString(`<p><a href="` + fileURL + `">` + html.EscapeString(name) + `</a></p>`)
+		}
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+func writeEmailButtons(b *strings.Builder, buttons []models.AttachmentButton) {
+	var options []string
+	for _, button := range buttons {
+		title := button.Title
+		if title == "" {
+			continue
+		}
+		if isAbsoluteURL(button.URL) {
+			b.WriteString(`<p><a href="` + html.EscapeString(button.URL) + `" style="display:inline-block;padding:8px 16px;background:#2d6cdf;color:#fff;border-radius:4px;text-decoration:none">` + html.EscapeString(title) + `</a></p>`)
+			continue
+		}
+		options = append(options, "<li>"+html.EscapeString(title)+"</li>")
+	}
+	if len(options) > 0 {
+		b.WriteString("<p>You can reply with one of:</p><ul>" + strings.Join(options, "") + "</ul>")
+	}
+}
+
+func isAbsoluteURL(u string) bool {
+	return strings.HasPrefix(u, "https://") || strings.HasPrefix(u, "http://")
+}
+
+// fetchAttachments downloads file and image attachments so they can be attached to the email.
+// Files in attachment storage are read directly; files that can't be fetched stay linked only.
+func (s *EmailService) fetchAttachments(ctx context.Context, attachments []models.Attachment) []emailAttachment {
+	var fetched []emailAttachment
+	total := 0
+	for _, a := range attachments {
+		if (a.Type != "file" && a.Type != "image") || a.FileURL == "" {
+			continue
+		}
+		content, contentType, err := s.fetchAttachment(ctx, a.FileURL)
+		if err != nil {
+			s.logger.Warn("Failed to fetch attachment for email",
+				zap.String("file_url", a.FileURL),
+				zap.Error(err))
+			continue
+		}
+		if total+len(content) > maxOutboundEmailBytes {
+			break
+		}
+		total += len(content)
+		if a.FileType != "" {
+			contentType = a.FileType
+		}
+		name := a.FileName
+		if name == "" {
+			name = path.Base(a.FileURL)
+		}
+		fetched = append(fetched, emailAttachment{FileName: name, ContentType: contentType, Content: content})
+	}
+	return fetched
+}
+
+func (s *EmailService) fetchAttachment(ctx context.Context, fileURL string) ([]byte, string, error) {
+	if i := strings.Index(fileURL, "/api/v1/attachments/"); i >= 0 && s.AttachmentService != nil {
+		file, content, err := s.AttachmentService.Open(fileURL[i+len("/api/v1/attachments/"):])
+		if err != nil {
+			return nil, "", err
+		}
+		defer content.Close()
+		if file.Size > maxOutboundAttachmentBytes {
+			return nil, "", errors.New("attachment is too large to attach")
+		}
+		data, err := io.ReadAll(content)
+		return data, file.ContentType, err
+	}
+	if !isAbsoluteURL(fileURL) {
+		return nil, "", errors.New("attachment url is not absolute")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := s.attachmentClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("attachment download returned status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxOutboundAttachmentBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxOutboundAttachmentBytes {
+		return nil, "", errors.New("attachment is too large to attach")
+	}
+	return data, resp.Header.Get("Content-Type"), nil
+}
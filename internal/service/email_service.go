@@ -0,0 +1,665 @@
+// Package service provides business logic for the native email channel connector.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/http"
+	"net/mail"
+	"net/textproto"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxEmailPartBytes bounds a single decoded MIME part
+	maxEmailPartBytes = 25 << 20
+	// maxEmailReferences keeps the References header from growing without bound in long threads
+	maxEmailReferences = 20
+	// Session attributes holding what the outbound sender needs to reply in the thread
+	emailToAttribute         = "email_to"
+	emailSubjectAttribute    = "email_subject"
+	emailInReplyToAttribute  = "email_in_reply_to"
+	emailReferencesAttribute = "email_references"
+)
+
+var (
+	ErrEmailUnauthorized    = errors.New("invalid email inbound token")
+	ErrEmailChannelNotFound = errors.New("email channel not found")
+)
+
+var (
+	// quotedReplyPattern finds where a reply client starts quoting the previous message
+	quotedReplyPattern = regexp.MustCompile(`(?m)^(On .+ wrote:|-{2,}\s*Original Message\s*-{2,}|_{10,})\s*$`)
+	subjectPrefixes    = regexp.MustCompile(`(?i)^\s*((re|fwd?|aw|sv)\s*:\s*)+`)
+	htmlTagPattern     = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+)
+
+// inboundEmail is an email received from either provider, reduced to what the chat needs.
+type inboundEmail struct {
+	MessageID     string
+	InReplyTo     string
+	References    []string
+	From          string
+	FromName      string
+	Subject       string
+	Text          string
+	HTML          string
+	AutoSubmitted bool
+	Attachments   []emailAttachment
+}
+
+// emailAttachment is a file received with, or sent with, an email.
+type emailAttachment struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// EmailService connects ClientChannels of type email to mailboxes. Each channel's channel_config
+// holds its address, the inbound_token providers must pass when posting mail, the outbound
+// provider ("smtp" with smtp_host, smtp_port, smtp_username, smtp_password, or "sendgrid" with
+// sendgrid_api_key), and optionally from_name, default_subject and ai_enabled (default true).
+type EmailService struct {
+	ClientRepo         *repository.ClientRepository
+	ClientChannelRepo  *repository.ClientChannelRepository
+	ChatSessionRepo    *repository.ChatSessionRepository
+	ChatMessageRepo    *repository.ChatMessageRepository
+	SessionService     *ChatSessionService
+	ChatMessageService *ChatMessageService
+	// LifecycleService, when set, applies the closed-session policy to inbound messages
+	LifecycleService *SessionLifecycleService
+	// SecretResolver resolves secret:// references in channel configs when they're loaded
+	SecretResolver *secrets.Resolver
+	// AttachmentService, when set, keeps inbound attachments and lets stored files be attached to replies
+	AttachmentService *AttachmentService
+	SendGridAPIURL    string
+	logger            *zap.Logger
+	httpClient        *http.Client
+	// attachmentClient downloads the files replies attach by URL, which clients choose
+	attachmentClient *http.Client
+}
+
+// NewEmailService creates a new EmailService.
+func NewEmailService(
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	sessionService *ChatSessionService,
+	chatMessageService *ChatMessageService,
+	logger *zap.Logger,
+) *EmailService {
+	return &EmailService{
+		ClientRepo:         clientRepo,
+		ClientChannelRepo:  clientChannelRepo,
+		ChatSessionRepo:    chatSessionRepo,
+		ChatMessageRepo:    chatMessageRepo,
+		SessionService:     sessionService,
+		ChatMessageService: chatMessageService,
+		SendGridAPIURL:     sendGridAPIURL,
+		logger:             logger,
+		httpClient:         &http.Client{Timeout: 30 * time.Second},
+		attachmentClient:   newEgressClient(nil, 30*time.Second),
+	}
+}
+
+// SetEgressPolicy limits the hosts attachments of replies can be downloaded from to those policy allows.
+func (s *EmailService) SetEgressPolicy(policy *egress.Policy) {
+	s.attachmentClient = newEgressClient(policy, 30*time.Second)
+}
+
+// channel returns the active email channel channelID after checking the inbound token.
+func (s *EmailService) channel(ctx context.Context, channelID, token string) (*models.ClientChannel, error) {
+	objID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return nil, ErrEmailChannelNotFound
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, objID)
+	if err != nil || channel.ChannelType != models.ChannelTypeEmail || !channel.IsActive {
+		return nil, ErrEmailChannelNotFound
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return nil, err
+	}
+	expected, _ := channel.ChannelConfig["inbound_token"].(string)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(token)) != 1 {
+		return nil, ErrEmailUnauthorized
+	}
+	return channel, nil
+}
+
+// HandleSendGrid processes a POST from SendGrid Inbound Parse, in either the parsed form or the
+// raw form that carries the full MIME message in the "email" field.
+func (s *EmailService) HandleSendGrid(ctx context.Context, channelID, token string, form *multipart.Form) error {
+	channel, err := s.channel(ctx, channelID, token)
+	if err != nil {
+		return err
+	}
+
+	value := func(key string) string {
+		if values := form.Value[key]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+	if raw := value("email"); raw != "" {
+		email, err := parseMIMEEmail([]byte(raw))
+		if err != nil {
+			return err
+		}
+		return s.handleInbound(ctx, channel, email)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(strings.TrimRight(value("headers"), "\r\n") + "\r\n\r\n"))
+	if err != nil {
+		return fmt.Errorf("invalid email headers: %w", err)
+	}
+	email := emailFromHeader(msg.Header)
+	if email.From == "" {
+		if from, err := mail.ParseAddress(value("from")); err == nil {
+			email.From, email.FromName = strings.ToLower(from.Address), from.Name
+		}
+	}
+	if email.Subject == "" {
+		email.Subject = value("subject")
+	}
+	email.Text, email.HTML = value("text"), value("html")
+
+	var info map[string]struct {
+		Filename string `json:"filename"`
+		Type     string `json:"type"`
+	}
+	_ = json.Unmarshal([]byte(value("attachment-info")), &info)
+	for field, files := range form.File {
+		for _, fh := range files {
+			f, err := fh.Open()
+			if err != nil {
+				return fmt.Errorf("failed to open attachment %s: %w", field, err)
+			}
+			content, err := io.ReadAll(io.LimitReader(f, maxEmailPartBytes))
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read attachment %s: %w", field, err)
+			}
+			attachment := emailAttachment{FileName: fh.Filename, ContentType: fh.Header.Get("Content-Type"), Content: content}
+			if meta, ok := info[field]; ok {
+				attachment.FileName, attachment.ContentType = meta.Filename, meta.Type
+			}
+			email.Attachments = append(email.Attachments, attachment)
+		}
+	}
+	return s.handleInbound(ctx, channel, email)
+}
+
+// HandleSES processes an SNS notification from an SES receipt rule with an SNS action. Subscription
+// confirmations are confirmed so the topic can be subscribed without a manual step.
+func (s *EmailService) HandleSES(ctx context.Context, channelID, token string, body []byte) error {
+	channel, err := s.channel(ctx, channelID, token)
+	if err != nil {
+		return err
+	}
+
+	var notification struct {
+		Type         string `json:"Type"`
+		Message      string `json:"Message"`
+		SubscribeURL string `json:"SubscribeURL"`
+	}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return fmt.Errorf("invalid sns payload: %w", err)
+	}
+
+	switch notification.Type {
+	case "SubscriptionConfirmation":
+		return s.confirmSubscription(ctx, notification.SubscribeURL)
+	case "Notification":
+	default:
+		return nil
+	}
+
+	var received struct {
+		NotificationType string `json:"notificationType"`
+		Content          string `json:"content"`
+		Receipt          struct {
+			Action struct {
+				Encoding string `json:"encoding"`
+			} `json:"action"`
+		} `json:"receipt"`
+	}
+	if err := json.Unmarshal([]byte(notification.Message), &received); err != nil {
+		return fmt.Errorf("invalid ses notification: %w", err)
+	}
+	if received.NotificationType != "Received" {
+		return nil
+	}
+	if received.Content == "" {
+		return errors.New("ses notification has no content; the receipt rule must publish the message to SNS")
+	}
+	raw := []byte(received.Content)
+	if strings.EqualFold(received.Receipt.Action.Encoding, "BASE64") {
+		if raw, err = base64.StdEncoding.DecodeString(received.Content); err != nil {
+			return fmt.Errorf("invalid ses content: %w", err)
+		}
+	}
+	email, err := parseMIMEEmail(raw)
+	if err != nil {
+		return err
+	}
+	return s.handleInbound(ctx, channel, email)
+}
+
+func (s *EmailService) confirmSubscription(ctx context.Context, subscribeURL string) error {
+	u, err := url.Parse(subscribeURL)
+	if err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return fmt.Errorf("refusing to confirm sns subscription at %q", subscribeURL)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, subscribeURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm sns subscription: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sns subscription confirmation returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// handleInbound stores an email as a user message in the session of its thread and triggers the
+// AI workflow. Replies are matched to a thread through In-Reply-To and References; a new thread
+// becomes a new thread of the sender's conversation with the mailbox.
+func (s *EmailService) handleInbound(ctx context.Context, channel *models.ClientChannel, email *inboundEmail) error {
+	address, _ := channel.ChannelConfig["address"].(string)
+	// Auto-replies and mail from the mailbox itself would make the bot answer itself
+	if email.From == "" || email.AutoSubmitted || strings.EqualFold(email.From, address) {
+		return nil
+	}
+	if email.MessageID == "" {
+		email.MessageID = "<" + primitive.NewObjectID().Hex() + "@" + emailDomain(address) + ">"
+	}
+
+	client, err := s.ClientRepo.GetByID(ctx, channel.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	if !client.IsActive {
+		return nil
+	}
+
+	session, err := s.threadSession(ctx, channel, client, address, email)
+	if err != nil {
+		return err
+	}
+	if s.LifecycleService != nil {
+		session, err = s.LifecycleService.ResolveForMessage(ctx, session, client, channel)
+		if err != nil {
+			if errors.Is(err, ErrSessionClosed) {
+				return nil
+			}
+			return err
+		}
+	}
+
+	// Providers retry deliveries they didn't see acknowledged
+	dedupKey := models.MessageDedupKey(client.ID, channel.ID, email.MessageID)
+	if existing, err := s.ChatMessageService.FindDuplicate(ctx, dedupKey); err == nil && existing != nil {
+		telemetry.ObserveMessageDedup(string(channel.ChannelType))
+		return nil
+	}
+
+	references := appendReference(strings.Fields(session.Attributes[emailReferencesAttribute]), email.References...)
+	references = appendReference(references, email.MessageID)
+	if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, map[string]string{
+		emailToAttribute:         email.From,
+		emailSubjectAttribute:    subjectPrefixes.ReplaceAllString(email.Subject, ""),
+		emailInReplyToAttribute:  email.MessageID,
+		emailReferencesAttribute: strings.Join(references, " "),
+	}, nil); err != nil {
+		return fmt.Errorf("failed to store email thread on session: %w", err)
+	}
+
+	var attachments []models.Attachment
+	if s.AttachmentService != nil {
+		for _, a := range email.Attachments {
+			attachmentType := "file"
+			if strings.HasPrefix(a.ContentType, "image/") {
+				attachmentType = "image"
+			}
+			stored, err := s.AttachmentService.Store(a.FileName, a.ContentType, attachmentType, bson.M{
+				"source":     "email",
+				"message_id": email.MessageID,
+				"channel_id": channel.ID,
+			}, bytes.NewReader(a.Content))
+			if err != nil {
+				s.logger.Warn("Failed to store email attachment",
+					zap.String("file_name", a.FileName),
+					zap.Error(err))
+				continue
+			}
+			attachments = append(attachments, *stored)
+		}
+	}
+
+	text := email.Text
+	if strings.TrimSpace(text) == "" && email.HTML != "" {
+		text = html.UnescapeString(htmlTagPattern.ReplaceAllString(email.HTML, ""))
+	}
+	text = stripQuotedReply(text)
+	if text == "" && len(attachments) == 0 {
+		return nil
+	}
+
+	aiEnabled := true
+	if v, ok := channel.ChannelConfig["ai_enabled"].(bool); ok {
+		aiEnabled = v
+	}
+	msg := &models.ChatMessage{
+		ExternalID:  email.MessageID,
+		DedupKey:    dedupKey,
+		Sender:      email.From,
+		SenderName:  email.FromName,
+		SenderType:  string(models.SenderTypeUser),
+		SessionID:   session.ID,
+		Text:        text,
+		Attachments: attachments,
+		Category:    models.MessageCategoryMessage,
+		Config:      map[string]interface{}{"ai_enabled": aiEnabled},
+		Data: map[string]interface{}{"email": map[string]interface{}{
+			"message_id":  email.MessageID,
+			"in_reply_to": email.InReplyTo,
+			"subject":     email.Subject,
+		}},
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		if errors.Is(err, ErrMessageDuplicate) {
+			telemetry.ObserveMessageDedup(string(channel.ChannelType))
+			return nil
+		}
+		return fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	if aiEnabled {
+		TriggerChatWorkflow(ctx, msg.ID.Hex(), session.SessionID)
+	}
+	return nil
+}
+
+// threadSession finds the session of the thread an email belongs to, or creates a thread for it.
+// A thread is identified by its root message, so replies that lost part of their References
+// still land in the same session.
+func (s *EmailService) threadSession(ctx context.Context, channel *models.ClientChannel, client *models.Client, address string, email *inboundEmail) (*models.ChatSession, error) {
+	var known []string
+	if email.InReplyTo != "" {
+		known = append(known, email.InReplyTo)
+	}
+	known = append(known, email.References...)
+	if len(known) > 0 {
+		messages, err := s.ChatMessageRepo.List(ctx, bson.M{"external_id": bson.M{"$in": known}}, 20)
+		if err == nil {
+			for _, m := range messages {
+				session, err := s.ChatSessionRepo.GetByID(ctx, m.SessionID)
+				if err == nil && session.ClientChannel != nil && *session.ClientChannel == channel.ID {
+					return session, nil
+				}
+			}
+		}
+	}
+
+	root := email.MessageID
+	if len(email.References) > 0 {
+		root = email.References[0]
+	} else if email.InReplyTo != "" {
+		root = email.InReplyTo
+	}
+	sum := sha256.Sum256([]byte(root))
+	baseSessionID := "email:" + strings.ToLower(address) + ":" + email.From
+	session, err := s.SessionService.ThreadManager.GetOrCreateThread(ctx, baseSessionID, hex.EncodeToString(sum[:4]), client, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get or create email thread: %w", err)
+	}
+	return session, nil
+}
+
+// Deliver emails the message behind a chat_message_created event when it is a bot or agent reply
+// in a session of an email channel. The reply continues the thread of the last inbound email.
+func (s *EmailService) Deliver(ctx context.Context, eventData map[string]interface{}) error {
+	if eventType, _ := eventData["event_type"].(string); eventType != string(models.EventTypeChatMessageCreated) {
+		return nil
+	}
+	entityID, _ := eventData["entity_id"].(string)
+	messageID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q", entityID)
+	}
+
+	msg, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat message: %w", err)
+	}
+	if msg.SenderType != string(models.SenderTypeAssistant) && !strings.HasPrefix(msg.SenderType, "client:") {
+		return nil
+	}
+	if suggestion, _ := msg.Config["suggestion_mode"].(bool); suggestion {
+		return nil
+	}
+
+	session, err := s.ChatSessionRepo.GetByID(ctx, msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	to := session.Attributes[emailToAttribute]
+	if session.ClientChannel == nil || to == "" {
+		return nil
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *session.ClientChannel)
+	if err != nil {
+		return fmt.Errorf("failed to get client channel: %w", err)
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return err
+	}
+	if channel.ChannelType != models.ChannelTypeEmail {
+		return nil
+	}
+
+	address, _ := channel.ChannelConfig["address"].(string)
+	fromName, _ := channel.ChannelConfig["from_name"].(string)
+	subject := session.Attributes[emailSubjectAttribute]
+	if subject == "" {
+		subject, _ = channel.ChannelConfig["default_subject"].(string)
+	}
+	references := strings.Fields(session.Attributes[emailReferencesAttribute])
+	text, attachments := DegradeAttachments(msg.Text, msg.Attachments, channel.EffectiveCapabilities())
+	out := &outboundEmail{
+		From:        address,
+		FromName:    fromName,
+		To:          to,
+		Subject:     "Re: " + subject,
+		MessageID:   "<" + msg.ID.Hex() + "@" + emailDomain(address) + ">",
+		InReplyTo:   session.Attributes[emailInReplyToAttribute],
+		References:  references,
+		Text:        text,
+		HTML:        renderEmailHTML(text, attachments),
+		Attachments: s.fetchAttachments(ctx, attachments),
+	}
+	if err := s.send(ctx, channel, out); err != nil {
+		return err
+	}
+
+	// The customer's reply will reference this message; remember it so the reply finds the thread
+	if err := s.ChatMessageRepo.Update(ctx, msg.ID, bson.M{"external_id": out.MessageID}); err != nil {
+		s.logger.Warn("Failed to store sent email message id",
+			zap.String("message_id", msg.ID.Hex()),
+			zap.Error(err))
+	}
+	if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, map[string]string{
+		emailReferencesAttribute: strings.Join(appendReference(references, out.MessageID), " "),
+	}, nil); err != nil {
+		s.logger.Warn("Failed to store email references on session",
+			zap.String("session_id", session.ID.Hex()),
+			zap.Error(err))
+	}
+	return nil
+}
+
+// parseMIMEEmail parses a raw RFC 5322 message, keeping the first text and HTML bodies and every
+// part with a filename as an attachment.
+func parseMIMEEmail(raw []byte) (*inboundEmail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid email message: %w", err)
+	}
+	email := emailFromHeader(msg.Header)
+	if err := walkMIMEPart(textproto.MIMEHeader(msg.Header), msg.Body, email, 0); err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
+func emailFromHeader(header mail.Header) *inboundEmail {
+	email := &inboundEmail{
+		MessageID:  strings.TrimSpace(header.Get("Message-Id")),
+		InReplyTo:  strings.TrimSpace(header.Get("In-Reply-To")),
+		References: strings.Fields(header.Get("References")),
+	}
+	if auto := strings.ToLower(header.Get("Auto-Submitted")); auto != "" && auto != "no" {
+		email.AutoSubmitted = true
+	}
+	decoder := new(mime.WordDecoder)
+	if subject, err := decoder.DecodeHeader(header.Get("Subject")); err == nil {
+		email.Subject = subject
+	}
+	if from, err := mail.ParseAddress(header.Get("From")); err == nil {
+		email.From, email.FromName = strings.ToLower(from.Address), from.Name
+	}
+	return email
+}
+
+func walkMIMEPart(header textproto.MIMEHeader, body io.Reader, email *inboundEmail, depth int) error {
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		if depth > 10 {
+			return nil
+		}
+		reader := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid multipart email: %w", err)
+			}
+			if err := walkMIMEPart(part.Header, part, email, depth+1); err != nil {
+				return err
+			}
+		}
+	}
+
+	// multipart.Reader already decodes quoted-printable parts and drops the header
+	switch strings.ToLower(header.Get("Content-Transfer-Encoding")) {
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	}
+	content, err := io.ReadAll(io.LimitReader(body, maxEmailPartBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read email part: %w", err)
+	}
+
+	disposition, dispositionParams, _ := mime.ParseMediaType(header.Get("Content-Disposition"))
+	fileName := dispositionParams["filename"]
+	if fileName == "" {
+		fileName = params["name"]
+	}
+	switch {
+	case disposition == "attachment" || fileName != "":
+		if fileName == "" {
+			fileName = "attachment"
+		}
+		email.Attachments = append(email.Attachments, emailAttachment{FileName: fileName, ContentType: mediaType, Content: content})
+	case mediaType == "text/plain" && email.Text == "":
+		email.Text = string(content)
+	case mediaType == "text/html" && email.HTML == "":
+		email.HTML = string(content)
+	}
+	return nil
+}
+
+// stripQuotedReply drops the quoted previous message that mail clients append to replies.
+// Text that is nothing but a quote is returned whole.
+func stripQuotedReply(text string) string {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	stripped := text
+	if loc := quotedReplyPattern.FindStringIndex(stripped); loc != nil {
+		stripped = stripped[:loc[0]]
+	}
+	var kept []string
+	for _, line := range strings.Split(stripped, "\n") {
+		if !strings.HasPrefix(strings.TrimSpace(line), ">") {
+			kept = append(kept, line)
+		}
+	}
+	if result := strings.TrimSpace(strings.Join(kept, "\n")); result != "" {
+		return result
+	}
+	return strings.TrimSpace(text)
+}
+
+// appendReference adds ids not already present, keeping the first (thread root) and the most recent.
+func appendReference(references []string, ids ...string) []string {
+	for _, id := range ids {
+		found := false
+		for _, r := range references {
+			if r == id {
+				found = true
+				break
+			}
+		}
+		if !found && id != "" {
+			references = append(references, id)
+		}
+	}
+	if len(references) > maxEmailReferences {
+		references = append(references[:1], references[len(references)-maxEmailReferences+1:]...)
+	}
+	return references
+}
+
+func emailDomain(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
@@ -0,0 +1,265 @@
+// Package service provides business logic for escalating chat sessions to human agents.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// defaultEscalationPolicy applies to sessions whose client and channel have no policy:
+// a single zero-confidence answer escalates, and only events are published.
+var defaultEscalationPolicy = models.EscalationPolicy{
+	Enabled:                  true,
+	ConfidenceThreshold:      0,
+	ConsecutiveLowConfidence: 1,
+}
+
+// Escalation triggers
+const (
+	EscalationTriggerKeyword       = "keyword"
+	EscalationTriggerLowConfidence = "low_confidence"
+)
+
+// EscalationDecision explains why a session was escalated.
+type EscalationDecision struct {
+	Trigger     string  `json:"trigger"`
+	Keyword     string  `json:"keyword,omitempty"`
+	Confidence  float64 `json:"confidence,omitempty"`
+	Streak      int     `json:"streak,omitempty"`
+	TargetQueue string  `json:"target_queue,omitempty"`
+	Handover    bool    `json:"handover"` // Whether a handover was requested
+}
+
+// EscalationService manages client and channel escalation policies and evaluates them in the chat workflow.
+type EscalationService struct {
+	ClientRepo        *repository.ClientRepository
+	ClientChannelRepo *repository.ClientChannelRepository
+	ChatSessionRepo   *repository.ChatSessionRepository
+	Invalidator       CacheInvalidator
+	// Set on workers, which request handovers for escalated sessions
+	HandoverService *HandoverService
+	// Set on workers, so an experiment variant's confidence threshold replaces the policy's
+	Experiments *ExperimentService
+	Clients     ClientCache
+	logger      *zap.Logger
+}
+
+// NewEscalationService creates a new EscalationService.
+func NewEscalationService(clientRepo *repository.ClientRepository, clientChannelRepo *repository.ClientChannelRepository, chatSessionRepo *repository.ChatSessionRepository, logger *zap.Logger) *EscalationService {
+	return &EscalationService{
+		ClientRepo:        clientRepo,
+		ClientChannelRepo: clientChannelRepo,
+		ChatSessionRepo:   chatSessionRepo,
+		logger:            logger,
+	}
+}
+
+// GetClientPolicy returns a client's escalation policy, or nil if none is configured.
+func (s *EscalationService) GetClientPolicy(ctx context.Context, clientID string) (*models.EscalationPolicy, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return client.Escalation, nil
+}
+
+// SetClientPolicy validates and stores a client's escalation policy.
+func (s *EscalationService) SetClientPolicy(ctx context.Context, clientID string, policy *models.EscalationPolicy) error {
+	if err := validateEscalationPolicy(policy); err != nil {
+		return err
+	}
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"escalation": policy}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidateClient(ctx, clientID)
+	return nil
+}
+
+// DeleteClientPolicy removes a client's escalation policy.
+func (s *EscalationService) DeleteClientPolicy(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"escalation": nil}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidateClient(ctx, clientID)
+	return nil
+}
+
+// GetChannelPolicy returns a channel's escalation policy, or nil if it uses the client's.
+func (s *EscalationService) GetChannelPolicy(ctx context.Context, clientID, channelID string) (*models.EscalationPolicy, error) {
+	channel, err := s.getChannel(ctx, clientID, channelID)
+	if err != nil {
+		return nil, err
+	}
+	return channel.Escalation, nil
+}
+
+// SetChannelPolicy validates and stores a channel's escalation policy.
+func (s *EscalationService) SetChannelPolicy(ctx context.Context, clientID, channelID string, policy *models.EscalationPolicy) error {
+	if err := validateEscalationPolicy(policy); err != nil {
+		return err
+	}
+	return s.updateChannel(ctx, clientID, channelID, policy)
+}
+
+// DeleteChannelPolicy removes a channel's escalation policy so the client's applies again.
+func (s *EscalationService) DeleteChannelPolicy(ctx context.Context, clientID, channelID string) error {
+	return s.updateChannel(ctx, clientID, channelID, nil)
+}
+
+func (s *EscalationService) updateChannel(ctx context.Context, clientID, channelID string, policy *models.EscalationPolicy) error {
+	channel, err := s.getChannel(ctx, clientID, channelID)
+	if err != nil {
+		return err
+	}
+	updated, err := s.ClientChannelRepo.Update(ctx, channel.ID, bson.M{"escalation": policy})
+	if err != nil {
+		return fmt.Errorf("failed to update channel: %w", err)
+	}
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClientChannel, updated.ClientID.Hex())
+	}
+	return nil
+}
+
+// getChannel loads a channel and checks that it belongs to the client.
+func (s *EscalationService) getChannel(ctx context.Context, clientID, channelID string) (*models.ClientChannel, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	id := ParseObjectID(channelID)
+	if id == nil {
+		return nil, errors.New("channel not found")
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *id)
+	if err != nil || channel.ClientID != client.ID {
+		return nil, errors.New("channel not found")
+	}
+	return channel, nil
+}
+
+func (s *EscalationService) invalidateClient(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
+
+func validateEscalationPolicy(policy *models.EscalationPolicy) error {
+	if policy.ConfidenceThreshold < 0 || policy.ConfidenceThreshold > 1 {
+		return errors.New("confidence_threshold must be between 0 and 1")
+	}
+	if policy.ConsecutiveLowConfidence < 0 {
+		return errors.New("consecutive_low_confidence must not be negative")
+	}
+	if policy.ConsecutiveLowConfidence == 0 {
+		policy.ConsecutiveLowConfidence = 1
+	}
+	return nil
+}
+
+// CheckKeywords escalates the session when the user's message contains one of the policy's keywords.
+// It returns nil when the message should be answered by the AI as usual.
+func (s *EscalationService) CheckKeywords(ctx context.Context, message *models.ChatMessage) (*EscalationDecision, error) {
+	session, policy := s.policyFor(ctx, message.SessionID)
+	if session == nil || len(policy.Keywords) == 0 {
+		return nil, nil
+	}
+	text := strings.ToLower(message.Text)
+	for _, keyword := range policy.Keywords {
+		if keyword != "" && strings.Contains(text, strings.ToLower(keyword)) {
+			return s.escalate(ctx, session, policy, &EscalationDecision{
+				Trigger: EscalationTriggerKeyword,
+				Keyword: keyword,
+			})
+		}
+	}
+	return nil, nil
+}
+
+// RecordAnswer tracks the confidence of an AI answer in the session and escalates once the policy's
+// run of low-confidence answers is reached. It returns nil when no escalation is due.
+func (s *EscalationService) RecordAnswer(ctx context.Context, sessionID primitive.ObjectID, confidence float64) (*EscalationDecision, error) {
+	session, policy := s.policyFor(ctx, sessionID)
+	if session == nil {
+		return nil, nil
+	}
+
+	threshold := policy.ConfidenceThreshold
+	if s.Experiments != nil {
+		if _, variant := s.Experiments.AssignSession(ctx, session); variant != nil && variant.ConfidenceThreshold != nil {
+			threshold = *variant.ConfidenceThreshold
+		}
+	}
+	low := confidence <= threshold
+	streak, err := s.ChatSessionRepo.UpdateLowConfidenceStreak(ctx, session.ID, low)
+	if err != nil {
+		return nil, fmt.Errorf("failed to track low-confidence answers: %w", err)
+	}
+	if !low || streak < max(policy.ConsecutiveLowConfidence, 1) {
+		return nil, nil
+	}
+
+	if _, err := s.ChatSessionRepo.UpdateLowConfidenceStreak(ctx, session.ID, false); err != nil {
+		s.logger.Warn("Failed to reset low-confidence streak",
+			zap.String("session_id", session.ID.Hex()),
+			zap.Error(err))
+	}
+	return s.escalate(ctx, session, policy, &EscalationDecision{
+		Trigger:    EscalationTriggerLowConfidence,
+		Confidence: confidence,
+		Streak:     streak,
+	})
+}
+
+// policyFor returns the session and the escalation policy in force for it: the channel's, else the
+// client's, else the default. The session is nil when escalation doesn't apply.
+func (s *EscalationService) policyFor(ctx context.Context, sessionID primitive.ObjectID) (*models.ChatSession, *models.EscalationPolicy) {
+	session, err := s.ChatSessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, nil
+	}
+
+	policy := &defaultEscalationPolicy
+	if session.Client != nil {
+		if client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client); err == nil && client.Escalation != nil {
+			policy = client.Escalation
+		}
+	}
+	if session.ClientChannel != nil {
+		if channel, err := channelByID(ctx, s.Clients, s.ClientChannelRepo, *session.ClientChannel); err == nil && channel.Escalation != nil {
+			policy = channel.Escalation
+		}
+	}
+	if !policy.Enabled {
+		return nil, nil
+	}
+	return session, policy
+}
+
+// escalate requests a handover to the policy's target queue, if it has one, and completes decision.
+func (s *EscalationService) escalate(ctx context.Context, session *models.ChatSession, policy *models.EscalationPolicy, decision *EscalationDecision) (*EscalationDecision, error) {
+	decision.TargetQueue = policy.TargetQueue
+	if policy.TargetQueue == "" || s.HandoverService == nil {
+		return decision, nil
+	}
+
+	_, err := s.HandoverService.RequestHandover(ctx, session.ID.Hex(), HandoverRequest{
+		Reason:      "escalation:" + decision.Trigger,
+		TargetQueue: policy.TargetQueue,
+		RequestedBy: "escalation_policy",
+	})
+	if err != nil && !errors.Is(err, ErrHandoverInvalidTransition) {
+		return decision, fmt.Errorf("failed to request handover: %w", err)
+	}
+	decision.Handover = err == nil
+	return decision, nil
+}
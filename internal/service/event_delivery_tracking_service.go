@@ -8,6 +8,7 @@ import (
 
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -87,6 +88,7 @@ func (s *EventDeliveryTrackingService) RecordAttempt(
 			deliveryStatus = models.DeliveryStatusPending
 		}
 	}
+	telemetry.ObserveDeliveryAttempt(delivery.EventProcessorConfigID.Hex(), status == models.AttemptStatusSuccess)
 
 	// Use the existing RecordDeliveryAttempt method
 	return s.RecordDeliveryAttempt(
@@ -178,7 +180,35 @@ func (s *EventDeliveryTrackingService) updateDeliveryStatusFromAttempt(
 		return nil
 	}
 
-	return s.DeliveryRepo.UpdateStatus(ctx, deliveryID, newStatus)
+	if err := s.DeliveryRepo.UpdateStatus(ctx, deliveryID, newStatus); err != nil {
+		return err
+	}
+
+	// Terminal deliveries have nothing left to schedule
+	if newStatus != models.DeliveryStatusPending && delivery.NextRetryAt != nil {
+		return s.DeliveryRepo.ClearNextRetry(ctx, deliveryID)
+	}
+
+	return nil
+}
+
+// ScheduleRetry persists the time of the next delivery attempt along with the backoff policy that produced it.
+func (s *EventDeliveryTrackingService) ScheduleRetry(
+	ctx context.Context,
+	deliveryID string,
+	nextRetryAt time.Time,
+	policy models.DeliveryRetryPolicy,
+) error {
+	id, err := primitive.ObjectIDFromHex(deliveryID)
+	if err != nil {
+		return fmt.Errorf("invalid delivery ID: %w", err)
+	}
+
+	if err := s.DeliveryRepo.SetNextRetry(ctx, id, nextRetryAt.UTC(), policy); err != nil {
+		return fmt.Errorf("failed to schedule retry: %w", err)
+	}
+
+	return nil
 }
 
 // GetDeliveryByID retrieves a delivery record by its ID.
@@ -3,16 +3,41 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/fraiday-org/api-service/internal/cache"
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+var (
+	ErrInvalidProcessorConfig = errors.New("invalid processor configuration")
+	// ErrProcessorTestUnsupported is returned when testing a processor that doesn't deliver to a webhook
+	ErrProcessorTestUnsupported = errors.New("only http_webhook processors can be tested")
+	// ErrProcessorTestUnavailable is returned when there is nothing to send test events with
+	ErrProcessorTestUnavailable = errors.New("processor tests are not available")
+)
+
+// maxProcessorTestBody caps how much of a processor's response to a test event is returned
+const maxProcessorTestBody = 4096
+
+// ProcessorTestResult is how a processor's endpoint answered a test event.
+type ProcessorTestResult struct {
+	EventID string
+	Result  ProcessorDispatchResult
+	Latency time.Duration
+}
+
 // EventProcessorConfigService encapsulates business logic for event processor configurations.
 type EventProcessorConfigService struct {
-	Repo *repository.EventProcessorConfigRepository
+	Repo        *repository.EventProcessorConfigRepository
+	Invalidator CacheInvalidator
+	// Dispatcher, when set, sends test events to processors
+	Dispatcher *ProcessorDispatchService
 }
 
 // NewEventProcessorConfigService creates a new EventProcessorConfigService.
@@ -48,7 +73,10 @@ func (s *EventProcessorConfigService) CreateConfig(
 
 	// Validate the configuration
 	if err := processorConfig.ValidateConfig(); err != nil {
-		return nil, fmt.Errorf("invalid processor configuration: %w", err)
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProcessorConfig, err)
+	}
+	if err := validateEntityTypes(entityTypes); err != nil {
+		return nil, err
 	}
 
 	if err := s.Repo.Create(ctx, processorConfig); err != nil {
@@ -180,15 +208,18 @@ func (s *EventProcessorConfigService) ListConfigs(
 	return configs, nil
 }
 
-// UpdateConfig updates an existing event processor configuration.
+// UpdateConfig updates an existing event processor configuration and returns its new version.
+// With version set the update only applies if the config is still at that version; otherwise it
+// fails with a repository.VersionConflictError.
 func (s *EventProcessorConfigService) UpdateConfig(
 	ctx context.Context,
 	configID string,
+	version *int64,
 	updates map[string]interface{},
-) error {
+) (int64, error) {
 	id, err := primitive.ObjectIDFromHex(configID)
 	if err != nil {
-		return fmt.Errorf("invalid config ID: %w", err)
+		return 0, fmt.Errorf("invalid config ID: %w", err)
 	}
 
 	// If config is being updated, validate it
@@ -196,23 +227,34 @@ func (s *EventProcessorConfigService) UpdateConfig(
 		// Get the current config to check processor type
 		currentConfig, err := s.Repo.GetByID(ctx, id)
 		if err != nil {
-			return fmt.Errorf("failed to get current config: %w", err)
+			return 0, fmt.Errorf("failed to get current config: %w", err)
 		}
 
+		// Credentials read back masked and written back unchanged keep their stored values
+		restored := secrets.UnmaskConfig(newConfig.(map[string]interface{}), currentConfig.Config)
+		updates["config"] = restored
+
 		// Create a temporary config for validation
 		tempConfig := *currentConfig
-		tempConfig.Config = newConfig.(map[string]interface{})
+		tempConfig.Config = restored
 
 		if err := tempConfig.ValidateConfig(); err != nil {
-			return fmt.Errorf("invalid processor configuration: %w", err)
+			return 0, fmt.Errorf("%w: %v", ErrInvalidProcessorConfig, err)
+		}
+	}
+	if entityTypes, ok := updates["entity_types"].([]models.EntityType); ok {
+		if err := validateEntityTypes(entityTypes); err != nil {
+			return 0, err
 		}
 	}
 
-	if err := s.Repo.Update(ctx, id, updates); err != nil {
-		return fmt.Errorf("failed to update processor config: %w", err)
+	newVersion, err := s.Repo.Update(ctx, id, version, updates)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update processor config: %w", err)
 	}
 
-	return nil
+	s.invalidate(ctx, id)
+	return newVersion, nil
 }
 
 // DeleteConfig removes an event processor configuration.
@@ -226,6 +268,7 @@ func (s *EventProcessorConfigService) DeleteConfig(ctx context.Context, configID
 		return fmt.Errorf("failed to delete processor config: %w", err)
 	}
 
+	s.invalidate(ctx, id)
 	return nil
 }
 
@@ -275,9 +318,69 @@ func (s *EventProcessorConfigService) ToggleConfigStatus(ctx context.Context, co
 		"is_active": !currentConfig.IsActive,
 	}
 
-	if err := s.Repo.Update(ctx, id, updates); err != nil {
+	// Only flip the status that was read, not one changed in the meantime
+	if _, err := s.Repo.Update(ctx, id, &currentConfig.Version, updates); err != nil {
 		return fmt.Errorf("failed to toggle config status: %w", err)
 	}
 
+	s.invalidate(ctx, id)
+	return nil
+}
+
+// validateEntityTypes rejects unknown entity types, which would otherwise silently never match.
+// An empty list subscribes to every entity type.
+func validateEntityTypes(entityTypes []models.EntityType) error {
+	for _, entityType := range entityTypes {
+		if !entityType.IsValid() {
+			return fmt.Errorf("%w: unknown entity type %q", ErrInvalidProcessorConfig, entityType)
+		}
+	}
 	return nil
+}
+
+// TestConfig sends a synthetic webhook_test event to a processor's endpoint, whether the processor
+// is active or not, and returns how the endpoint answered. The event is neither stored nor tracked
+// as a delivery, and goes to the real endpoint even for sandbox clients.
+func (s *EventProcessorConfigService) TestConfig(ctx context.Context, config *models.EventProcessorConfig) (*ProcessorTestResult, error) {
+	if config.ProcessorType != models.ProcessorTypeHTTPWebhook {
+		return nil, ErrProcessorTestUnsupported
+	}
+	if s.Dispatcher == nil {
+		return nil, ErrProcessorTestUnavailable
+	}
+
+	event := &models.Event{
+		ID:         primitive.NewObjectID(),
+		EventType:  models.EventTypeWebhookTest,
+		EntityType: models.EntityTypeClient,
+		EntityID:   config.ClientID.Hex(),
+		Data: map[string]interface{}{
+			"processor_id": config.ID.Hex(),
+			"message":      "Test event sent to verify this endpoint",
+		},
+		CreatedAt: time.Now().UTC(),
+	}
+	if schema, err := models.EventSchemaFor(event.EventType); err == nil {
+		event.SchemaVersion = schema.Version
+	}
+	eventID := event.ID.Hex()
+	eventData := event.Envelope().Map()
+	eventData["created_at"] = event.CreatedAt
+	eventData["test"] = true
+
+	began := time.Now()
+	result := s.Dispatcher.DispatchToProcessor(ctx, config, eventData)
+	latency := time.Since(began)
+	if len(result.ResponseBody) > maxProcessorTestBody {
+		result.ResponseBody = result.ResponseBody[:maxProcessorTestBody]
+	}
+	return &ProcessorTestResult{EventID: eventID, Result: result, Latency: latency}, nil
+}
+
+// invalidate evicts a changed config from caches cluster-wide. Failures are not fatal:
+// caches expire on their own.
+func (s *EventProcessorConfigService) invalidate(ctx context.Context, id primitive.ObjectID) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindEventProcessorConfig, id.Hex())
+	}
 }
\ No newline at end of file
@@ -4,14 +4,29 @@ package service
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/egress"
 	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
 
@@ -28,17 +43,142 @@ type ProcessorDispatchService struct {
 	logger     *zap.Logger
 	httpClient *http.Client
 	amqpConn   *amqp.Connection
+	// amqpBatcher publishes events of amqp processors configured with a batch
+	amqpBatcher *AMQPBatcher
+	// SandboxSinkURL receives deliveries for sandbox clients instead of their own endpoints
+	SandboxSinkURL string
+	// Slack, when set, handles slack processors
+	Slack *SlackService
+	// WhatsApp, when set, handles whatsapp processors
+	WhatsApp *WhatsAppService
+	// Teams, when set, handles teams processors
+	Teams *TeamsService
+	// Email, when set, handles email processors
+	Email *EmailService
+	// SMS, when set, handles sms processors
+	SMS *SMSService
+	// Sunshine, when set, handles sunshine processors
+	Sunshine *SunshineService
+	// Capabilities, when set, degrades messages for webhook and amqp processors to what their channel renders
+	Capabilities *ChannelCapabilityService
+	// SecretResolver resolves secret:// references in webhook and amqp processor configs
+	SecretResolver *secrets.Resolver
+
+	// egress limits the hosts webhooks can reach, and proxyURL, when set, relays their requests
+	egress   *egress.Policy
+	proxyURL *url.URL
+	pool     dispatchPool
+	// sinkClient posts to SandboxSinkURL, which the deployment chose, so egress doesn't apply to it
+	sinkClient *http.Client
+
+	// webhookClients holds the HTTP clients of webhook processors with their own TLS or proxy config
+	webhookMu      sync.Mutex
+	webhookClients map[primitive.ObjectID]*processorHTTPClient
 }
 
-// NewProcessorDispatchService creates a new ProcessorDispatchService
+// dispatchPool is how webhook transports keep connections for reuse
+type dispatchPool struct {
+	maxIdleConns        int
+	maxIdleConnsPerHost int
+	maxConnsPerHost     int
+	idleConnTimeout     time.Duration
+	http2               bool
+}
+
+// processorHTTPClient is the HTTP client built for a processor's TLS and proxy config, with a hash
+// of the config it was built from so a changed config or rotated secret builds a new one
+type processorHTTPClient struct {
+	hash   string
+	client *http.Client
+}
+
+// NewProcessorDispatchService creates a new ProcessorDispatchService. Until Configure says
+// otherwise, webhooks can't reach private addresses.
 func NewProcessorDispatchService(logger *zap.Logger, amqpConn *amqp.Connection) *ProcessorDispatchService {
-	return &ProcessorDispatchService{
-		logger: logger,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+	policy, _ := egress.NewPolicy("", "", false)
+	s := &ProcessorDispatchService{
+		logger:   logger,
 		amqpConn: amqpConn,
+		egress:   policy,
+		pool: dispatchPool{
+			maxIdleConns:        200,
+			maxIdleConnsPerHost: 32,
+			idleConnTimeout:     90 * time.Second,
+			http2:               true,
+		},
+		sinkClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
+		},
+		webhookClients: make(map[primitive.ObjectID]*processorHTTPClient),
 	}
+	if amqpConn != nil {
+		s.amqpBatcher = NewAMQPBatcher(amqpConn, logger)
+	}
+	s.httpClient = s.newWebhookClient(nil, nil, true)
+	return s
+}
+
+// Configure applies the deployment's webhook dispatch settings: proxy, destination host lists and
+// connection pooling.
+func (s *ProcessorDispatchService) Configure(cfg *config.Config) error {
+	policy, err := egress.NewPolicy(cfg.DispatchAllowedHosts, cfg.DispatchDeniedHosts, cfg.DispatchAllowPrivateNetworks)
+	if err != nil {
+		return err
+	}
+	var proxyURL *url.URL
+	if cfg.DispatchProxyURL != "" {
+		if proxyURL, err = url.Parse(cfg.DispatchProxyURL); err != nil {
+			return fmt.Errorf("invalid dispatch proxy: %w", err)
+		}
+	}
+
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+	s.egress = policy
+	s.proxyURL = proxyURL
+	s.pool = dispatchPool{
+		maxIdleConns:        cfg.DispatchMaxIdleConns,
+		maxIdleConnsPerHost: cfg.DispatchMaxIdleConnsPerHost,
+		maxConnsPerHost:     cfg.DispatchMaxConnsPerHost,
+		idleConnTimeout:     cfg.DispatchIdleConnTimeout,
+		http2:               cfg.DispatchHTTP2,
+	}
+	s.httpClient.CloseIdleConnections()
+	s.httpClient = s.newWebhookClient(nil, proxyURL, true)
+	for _, previous := range s.webhookClients {
+		previous.client.CloseIdleConnections()
+	}
+	s.webhookClients = make(map[primitive.ObjectID]*processorHTTPClient)
+	return nil
+}
+
+// newWebhookClient builds an HTTP client for webhooks that checks every request against the
+// egress policy. Connections are checked too, except those to a trusted proxy: the deployment's
+// own proxy may well be on a private network, while one from a processor config is not trusted.
+// Requests are bounded by their processor's timeout rather than by the client.
+func (s *ProcessorDispatchService) newWebhookClient(tlsConfig *tls.Config, proxyURL *url.URL, trustedProxy bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	transport.MaxIdleConns = s.pool.maxIdleConns
+	transport.MaxIdleConnsPerHost = s.pool.maxIdleConnsPerHost
+	transport.MaxConnsPerHost = s.pool.maxConnsPerHost
+	transport.IdleConnTimeout = s.pool.idleConnTimeout
+	transport.ForceAttemptHTTP2 = s.pool.http2
+	if !s.pool.http2 {
+		// A non-nil empty map turns off the transport's HTTP/2 upgrade
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	transport.Proxy = nil
+	transport.DialContext = s.egress.DialContext(dialer.DialContext)
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+		if trustedProxy {
+			transport.DialContext = dialer.DialContext
+		}
+	}
+	return &http.Client{Transport: telemetry.Transport(s.egress.Transport(transport))}
 }
 
 // DispatchToProcessor dispatches event data to a specific processor
@@ -49,10 +189,37 @@ func (s *ProcessorDispatchService) DispatchToProcessor(
 	eventData map[string]interface{},
 ) ProcessorDispatchResult {
 	switch processor.ProcessorType {
-	case models.ProcessorTypeHTTPWebhook:
-		return s.dispatchToHTTPWebhook(ctx, processor, eventData)
-	case models.ProcessorTypeAMQP:
-		return s.dispatchToAMQP(ctx, processor, eventData)
+	case models.ProcessorTypeHTTPWebhook, models.ProcessorTypeAMQP:
+		resolved, err := s.resolveSecrets(ctx, processor)
+		if err != nil {
+			return ProcessorDispatchResult{
+				Success:      false,
+				ErrorMessage: err.Error(),
+			}
+		}
+		if resolved.ProcessorType == models.ProcessorTypeAMQP {
+			return s.dispatchToAMQP(ctx, resolved, s.degrade(ctx, eventData))
+		}
+		client, err := s.webhookClient(resolved)
+		if err != nil {
+			return ProcessorDispatchResult{
+				Success:      false,
+				ErrorMessage: err.Error(),
+			}
+		}
+		return s.dispatchToHTTPWebhook(ctx, client, resolved, s.degrade(ctx, eventData))
+	case models.ProcessorTypeSlack:
+		return s.dispatchToSlack(ctx, eventData)
+	case models.ProcessorTypeWhatsApp:
+		return s.dispatchToWhatsApp(ctx, eventData)
+	case models.ProcessorTypeTeams:
+		return s.dispatchToTeams(ctx, eventData)
+	case models.ProcessorTypeEmail:
+		return s.dispatchToEmail(ctx, eventData)
+	case models.ProcessorTypeSMS:
+		return s.dispatchToSMS(ctx, eventData)
+	case models.ProcessorTypeSunshine:
+		return s.dispatchToSunshine(ctx, eventData)
 	default:
 		return ProcessorDispatchResult{
 			Success:      false,
@@ -61,9 +228,218 @@ func (s *ProcessorDispatchService) DispatchToProcessor(
 	}
 }
 
-// dispatchToHTTPWebhook dispatches event to HTTP webhook endpoint
+// resolveSecrets returns a copy of processor whose config has its secret references replaced by
+// their values, so credentials are only ever held in memory for the delivery
+func (s *ProcessorDispatchService) resolveSecrets(ctx context.Context, processor *models.EventProcessorConfig) (*models.EventProcessorConfig, error) {
+	config, err := s.SecretResolver.ResolveConfig(ctx, processor.ClientID.Hex(), processor.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve processor secrets: %w", err)
+	}
+	resolved := *processor
+	resolved.Config = config
+	return &resolved, nil
+}
+
+// webhookClient returns the HTTP client for a webhook processor: the shared one, or one with the
+// client certificate, CAs and proxy of the processor's config, whose secrets are already resolved.
+func (s *ProcessorDispatchService) webhookClient(processor *models.EventProcessorConfig) (*http.Client, error) {
+	config, err := processor.GetHttpWebhookConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	s.webhookMu.Lock()
+	defer s.webhookMu.Unlock()
+	if config.TLS == nil && config.ProxyURL == "" {
+		return s.httpClient, nil
+	}
+
+	parts := []string{config.ProxyURL}
+	if config.TLS != nil {
+		parts = append(parts, config.TLS.ClientCert, config.TLS.ClientKey, config.TLS.CABundle, strconv.FormatBool(config.TLS.InsecureSkipVerify))
+	}
+	sum := sha256.New()
+	for _, part := range parts {
+		sum.Write([]byte(part))
+		sum.Write([]byte{0})
+	}
+	hash := hex.EncodeToString(sum.Sum(nil))
+	if cached, ok := s.webhookClients[processor.ID]; ok && cached.hash == hash {
+		return cached.client, nil
+	}
+
+	var tlsConfig *tls.Config
+	if config.TLS != nil {
+		tlsConfig = &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: config.TLS.InsecureSkipVerify}
+		if config.TLS.ClientCert != "" {
+			cert, err := tls.X509KeyPair([]byte(config.TLS.ClientCert), []byte(config.TLS.ClientKey))
+			if err != nil {
+				return nil, fmt.Errorf("invalid webhook client certificate: %w", err)
+			}
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		if config.TLS.CABundle != "" {
+			roots, err := x509.SystemCertPool()
+			if err != nil {
+				roots = x509.NewCertPool()
+			}
+			if !roots.AppendCertsFromPEM([]byte(config.TLS.CABundle)) {
+				return nil, errors.New("invalid webhook CA bundle: no certificates found")
+			}
+			tlsConfig.RootCAs = roots
+		}
+	}
+	proxyURL, trustedProxy := s.proxyURL, true
+	if config.ProxyURL != "" {
+		if proxyURL, err = url.Parse(config.ProxyURL); err != nil {
+			return nil, fmt.Errorf("invalid webhook proxy_url: %w", err)
+		}
+		trustedProxy = false
+	}
+	client := s.newWebhookClient(tlsConfig, proxyURL, trustedProxy)
+
+	if previous, ok := s.webhookClients[processor.ID]; ok {
+		previous.client.CloseIdleConnections()
+	}
+	s.webhookClients[processor.ID] = &processorHTTPClient{hash: hash, client: client}
+	return client, nil
+}
+
+// DispatchToSandboxSink posts sandbox event data to SandboxSinkURL instead of the processor's endpoint.
+// Without a sink configured the delivery is recorded as successful but not sent anywhere.
+func (s *ProcessorDispatchService) DispatchToSandboxSink(
+	ctx context.Context,
+	processor *models.EventProcessorConfig,
+	eventData map[string]interface{},
+) ProcessorDispatchResult {
+	if s.SandboxSinkURL == "" {
+		return ProcessorDispatchResult{
+			Success:      true,
+			ResponseBody: "sandbox: delivery suppressed",
+		}
+	}
+
+	sink := *processor
+	sink.ProcessorType = models.ProcessorTypeHTTPWebhook
+	sink.Config = map[string]interface{}{"webhook_url": s.SandboxSinkURL}
+	return s.dispatchToHTTPWebhook(ctx, s.sinkClient, &sink, eventData)
+}
+
+// dispatchToSlack posts bot and agent replies to the Slack conversation of their session
+func (s *ProcessorDispatchService) dispatchToSlack(ctx context.Context, eventData map[string]interface{}) ProcessorDispatchResult {
+	if s.Slack == nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: "slack connector not configured",
+		}
+	}
+	if err := s.Slack.Deliver(ctx, eventData); err != nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	return ProcessorDispatchResult{Success: true, ResponseStatus: http.StatusOK}
+}
+
+// dispatchToWhatsApp sends bot and agent replies to the WhatsApp user of their session
+func (s *ProcessorDispatchService) dispatchToWhatsApp(ctx context.Context, eventData map[string]interface{}) ProcessorDispatchResult {
+	if s.WhatsApp == nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: "whatsapp connector not configured",
+		}
+	}
+	if err := s.WhatsApp.Deliver(ctx, eventData); err != nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	return ProcessorDispatchResult{Success: true, ResponseStatus: http.StatusOK}
+}
+
+// dispatchToTeams sends bot and agent replies to the Teams conversation of their session
+func (s *ProcessorDispatchService) dispatchToTeams(ctx context.Context, eventData map[string]interface{}) ProcessorDispatchResult {
+	if s.Teams == nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: "teams connector not configured",
+		}
+	}
+	if err := s.Teams.Deliver(ctx, eventData); err != nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	return ProcessorDispatchResult{Success: true, ResponseStatus: http.StatusOK}
+}
+
+// dispatchToEmail emails bot and agent replies in the thread of their session
+func (s *ProcessorDispatchService) dispatchToEmail(ctx context.Context, eventData map[string]interface{}) ProcessorDispatchResult {
+	if s.Email == nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: "email connector not configured",
+		}
+	}
+	if err := s.Email.Deliver(ctx, eventData); err != nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	return ProcessorDispatchResult{Success: true, ResponseStatus: http.StatusOK}
+}
+
+// dispatchToSMS texts bot and agent replies to the phone number of their session
+func (s *ProcessorDispatchService) dispatchToSMS(ctx context.Context, eventData map[string]interface{}) ProcessorDispatchResult {
+	if s.SMS == nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: "sms connector not configured",
+		}
+	}
+	if err := s.SMS.Deliver(ctx, eventData); err != nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	return ProcessorDispatchResult{Success: true, ResponseStatus: http.StatusOK}
+}
+
+// degrade adapts message events to the capabilities of the message's channel before they leave
+// for a processor that renders them itself
+func (s *ProcessorDispatchService) degrade(ctx context.Context, eventData map[string]interface{}) map[string]interface{} {
+	if s.Capabilities == nil {
+		return eventData
+	}
+	return s.Capabilities.DegradeEventData(ctx, eventData)
+}
+
+// dispatchToSunshine sends replies to Sunshine conversations and passes control on handover
+func (s *ProcessorDispatchService) dispatchToSunshine(ctx context.Context, eventData map[string]interface{}) ProcessorDispatchResult {
+	if s.Sunshine == nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: "sunshine connector not configured",
+		}
+	}
+	if err := s.Sunshine.Deliver(ctx, eventData); err != nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	return ProcessorDispatchResult{Success: true, ResponseStatus: http.StatusOK}
+}
+
+// dispatchToHTTPWebhook dispatches event to HTTP webhook endpoint with client
 func (s *ProcessorDispatchService) dispatchToHTTPWebhook(
 	ctx context.Context,
+	client *http.Client,
 	processor *models.EventProcessorConfig,
 	eventData map[string]interface{},
 ) ProcessorDispatchResult {
@@ -77,6 +453,16 @@ func (s *ProcessorDispatchService) dispatchToHTTPWebhook(
 		}
 	}
 
+	webhookConfig, err := processor.GetHttpWebhookConfig()
+	if err != nil {
+		return ProcessorDispatchResult{
+			Success:      false,
+			ErrorMessage: err.Error(),
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(webhookConfig.Timeout)*time.Second)
+	defer cancel()
+
 	// Prepare request payload
 	payload, err := json.Marshal(eventData)
 	if err != nil {
@@ -86,6 +472,12 @@ func (s *ProcessorDispatchService) dispatchToHTTPWebhook(
 		}
 	}
 
+	// Note whether the pool had a connection to reuse
+	var reused bool
+	ctx = httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused },
+	})
+
 	// Create HTTP request
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(payload))
 	if err != nil {
@@ -98,6 +490,9 @@ func (s *ProcessorDispatchService) dispatchToHTTPWebhook(
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "Fraiday-Events/1.0")
+	if version, ok := eventData["schema_version"]; ok {
+		req.Header.Set("X-Event-Schema-Version", fmt.Sprint(version))
+	}
 
 	// Add authentication if configured
 	if auth, exists := config["auth"]; exists {
@@ -135,7 +530,7 @@ func (s *ProcessorDispatchService) dispatchToHTTPWebhook(
 		zap.String("processor_id", processor.ID.Hex()))
 
 	// Send request
-	resp, err := s.httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return ProcessorDispatchResult{
 			Success:      false,
@@ -143,8 +538,9 @@ func (s *ProcessorDispatchService) dispatchToHTTPWebhook(
 		}
 	}
 	defer resp.Body.Close()
+	telemetry.ObserveWebhookConnection(reused, resp.Proto)
 
-	// Read response body
+	// Read response body; reading it to the end lets the connection go back to the pool
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return ProcessorDispatchResult{
@@ -188,40 +584,12 @@ func (s *ProcessorDispatchService) dispatchToAMQP(
 		}
 	}
 
-	// Create channel
-	channel, err := s.amqpConn.Channel()
-	if err != nil {
-		return ProcessorDispatchResult{
-			Success:      false,
-			ErrorMessage: fmt.Sprintf("failed to create AMQP channel: %v", err),
-		}
-	}
-	defer channel.Close()
-
 	// Get AMQP configuration
 	config := processor.Config
 	exchange, _ := config["exchange"].(string)
 	routingKey, _ := config["routing_key"].(string)
 	queue, _ := config["queue"].(string)
 
-	// If queue is specified, ensure it exists
-	if queue != "" {
-		_, err = channel.QueueDeclare(
-			queue,
-			true,  // durable
-			false, // delete when unused
-			false, // exclusive
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			return ProcessorDispatchResult{
-				Success:      false,
-				ErrorMessage: fmt.Sprintf("failed to declare queue: %v", err),
-			}
-		}
-	}
-
 	// Prepare message payload
 	payload, err := json.Marshal(eventData)
 	if err != nil {
@@ -236,6 +604,9 @@ func (s *ProcessorDispatchService) dispatchToAMQP(
 		"content_type": "application/json",
 		"timestamp":    time.Now().Unix(),
 	}
+	if version, ok := eventData["schema_version"]; ok {
+		headers["schema_version"] = fmt.Sprint(version)
+	}
 
 	// Add custom headers if configured
 	if configHeaders, exists := config["headers"]; exists {
@@ -252,25 +623,31 @@ func (s *ProcessorDispatchService) dispatchToAMQP(
 		zap.String("queue", queue),
 		zap.String("processor_id", processor.ID.Hex()))
 
-	// Publish message
-	err = channel.PublishWithContext(
-		ctx,
-		exchange,   // exchange
-		routingKey, // routing key
-		false,      // mandatory
-		false,      // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        payload,
-			Headers:     headers,
-			Timestamp:   time.Now(),
-		},
-	)
+	// Publish message, carrying the trace on to the processor
+	ctx, span := telemetry.StartPublish(ctx, routingKey, "event", headers)
+	defer span.End()
+	publishing := amqp.Publishing{
+		ContentType: "application/json",
+		Body:        payload,
+		Headers:     headers,
+		Timestamp:   time.Now(),
+	}
+	if amqpConfig, _ := processor.GetAmqpConfig(); amqpConfig != nil && amqpConfig.Batch != nil && s.amqpBatcher != nil {
+		err = s.amqpBatcher.Publish(ctx, processor.ID, amqpConfig.Batch, amqpMessage{
+			exchange:   exchange,
+			routingKey: routingKey,
+			queue:      queue,
+			publishing: publishing,
+		})
+	} else {
+		err = s.publishAMQP(ctx, exchange, routingKey, queue, publishing)
+	}
 
 	if err != nil {
+		telemetry.RecordError(span, err)
 		return ProcessorDispatchResult{
 			Success:      false,
-			ErrorMessage: fmt.Sprintf("failed to publish message: %v", err),
+			ErrorMessage: err.Error(),
 		}
 	}
 
@@ -281,4 +658,40 @@ func (s *ProcessorDispatchService) dispatchToAMQP(
 		Success:      true,
 		ResponseBody: "Message published successfully",
 	}
+}
+
+// publishAMQP publishes a single event on a channel of its own.
+func (s *ProcessorDispatchService) publishAMQP(ctx context.Context, exchange, routingKey, queue string, publishing amqp.Publishing) error {
+	channel, err := s.amqpConn.Channel()
+	if err != nil {
+		return fmt.Errorf("failed to create AMQP channel: %w", err)
+	}
+	defer channel.Close()
+
+	// If queue is specified, ensure it exists
+	if queue != "" {
+		_, err = channel.QueueDeclare(
+			queue,
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare queue: %w", err)
+		}
+	}
+
+	if err := channel.PublishWithContext(
+		ctx,
+		exchange,   // exchange
+		routingKey, // routing key
+		false,      // mandatory
+		false,      // immediate
+		publishing,
+	); err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+	return nil
 }
\ No newline at end of file
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 	"github.com/rabbitmq/amqp091-go"
 )
 
@@ -34,7 +35,8 @@ func NewEventProcessorService(
 		EventProcessorConfigService:  processorConfigService,
 		WebhookPayloadService:        webhookPayloadService,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 	}
 }
@@ -208,7 +210,10 @@ func (s *EventProcessorService) processAMQP(
 		return s.recordFailedAttempt(ctx, delivery, 0, fmt.Sprintf("Failed to marshal payload: %v", err), startTime)
 	}
 
-	// Publish message
+	// Publish message, carrying the trace on to the processor
+	headers := amqp091.Table{}
+	_, span := telemetry.StartPublish(ctx, amqpConfig.RoutingKey, "event", headers)
+	defer span.End()
 	err = ch.Publish(
 		amqpConfig.Exchange,
 		amqpConfig.RoutingKey,
@@ -217,9 +222,11 @@ func (s *EventProcessorService) processAMQP(
 		amqp091.Publishing{
 			ContentType: "application/json",
 			Body:        payloadBytes,
+			Headers:     headers,
 		},
 	)
 	if err != nil {
+		telemetry.RecordError(span, err)
 		return s.recordFailedAttempt(ctx, delivery, 0, fmt.Sprintf("Failed to publish message: %v", err), startTime)
 	}
 
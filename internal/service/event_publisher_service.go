@@ -5,12 +5,17 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// replayBatchSize is how many stored events are read at a time while replaying
+const replayBatchSize = 500
+
 // EventPublisherService encapsulates business logic for event publishing.
 type EventPublisherService struct {
 	EventService                  *EventService
@@ -58,7 +63,8 @@ func NewEventPublisherService(
 	}
 }
 
-// PublishEvent creates an event and triggers asynchronous processing.
+// PublishEvent creates an event and triggers asynchronous processing. data must match the schema
+// of eventType.
 func (s *EventPublisherService) PublishEvent(
 	ctx context.Context,
 	eventType models.EventType,
@@ -73,6 +79,15 @@ func (s *EventPublisherService) PublishEvent(
 		normalizedData = s.PayloadService.PrepareEventData(data)
 	}
 
+	// Hold the data to the schema of its event type, so consumers can rely on its version
+	schema, err := models.EventSchemaFor(eventType)
+	if err != nil {
+		return nil, err
+	}
+	if err := schema.Validate(normalizedData); err != nil {
+		return nil, fmt.Errorf("invalid %s event data: %w", eventType, err)
+	}
+
 	// Create and save the event
 	event, err := s.EventService.CreateEvent(
 		ctx,
@@ -80,15 +95,27 @@ func (s *EventPublisherService) PublishEvent(
 		entityType,
 		entityID,
 		parentID,
+		s.eventClientID(ctx, entityType, entityID, parentID),
 		normalizedData,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create event: %w", err)
 	}
 
-	// Publish event to RabbitMQ for asynchronous processing
+	// Publish event to RabbitMQ for asynchronous processing, once the event is committed so no
+	// processor sees an event that was rolled back
+	repository.AfterCommit(ctx, func(ctx context.Context) {
+		s.dispatchEvent(ctx, event)
+	})
+
+	return event, nil
+}
+
+// dispatchEvent hands a stored event to the event processor task, or processes it in the
+// background when the task can't be published.
+func (s *EventPublisherService) dispatchEvent(ctx context.Context, event *models.Event) {
 	if s.TaskClient != nil {
-		err = s.TaskClient.PublishEventProcessorTask(
+		err := s.TaskClient.PublishEventProcessorTask(
 			ctx,
 			event.ID.Hex(),
 			event.EventType,
@@ -119,8 +146,6 @@ func (s *EventPublisherService) PublishEvent(
 			}
 		}()
 	}
-
-	return event, nil
 }
 
 // ProcessEventAsync handles the asynchronous processing of events.
@@ -194,6 +219,44 @@ func (s *EventPublisherService) processEventDirect(ctx context.Context, event *m
 	return nil
 }
 
+// ReplayEvents queues the events created from from to to for processing again, oldest first, so
+// the processors subscribed to them now get a new delivery each. eventType narrows the events down
+// when set. At most limit events are replayed; with dryRun they are only counted.
+func (s *EventPublisherService) ReplayEvents(ctx context.Context, from, to time.Time, eventType models.EventType, limit int, dryRun bool) (int, error) {
+	if !from.Before(to) {
+		return 0, fmt.Errorf("from must be before to")
+	}
+	if limit <= 0 {
+		return 0, fmt.Errorf("limit must be positive")
+	}
+
+	replayed := 0
+	var after primitive.ObjectID
+	for replayed < limit {
+		filter := bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}
+		if eventType != "" {
+			filter["event_type"] = eventType
+		}
+		events, err := s.EventService.Repo.ListSince(ctx, filter, after, int64(min(replayBatchSize, limit-replayed)))
+		if err != nil {
+			return replayed, err
+		}
+		if len(events) == 0 {
+			break
+		}
+		for i := range events {
+			if !dryRun {
+				if err := s.ProcessEventAsync(ctx, &events[i]); err != nil {
+					return replayed, fmt.Errorf("failed to replay event %s: %w", events[i].ID.Hex(), err)
+				}
+			}
+			replayed++
+		}
+		after = events[len(events)-1].ID
+	}
+	return replayed, nil
+}
+
 // createDeliveryRecord creates a delivery record for an event and processor config.
 func (s *EventPublisherService) createDeliveryRecord(
 	ctx context.Context,
@@ -201,18 +264,8 @@ func (s *EventPublisherService) createDeliveryRecord(
 	config *models.EventProcessorConfig,
 ) error {
 	// Prepare the request payload
-	requestPayload := map[string]interface{}{
-		"event_id":     event.ID.Hex(),
-		"event_type":   event.EventType,
-		"entity_type":  event.EntityType,
-		"entity_id":    event.EntityID,
-		"data":         event.Data,
-		"created_at":   event.CreatedAt,
-	}
-
-	if event.ParentID != "" {
-		requestPayload["parent_id"] = event.ParentID
-	}
+	requestPayload := event.Envelope().Map()
+	requestPayload["created_at"] = event.CreatedAt
 
 	// Set default max attempts (can be made configurable)
 	maxAttempts := 3
@@ -332,6 +385,9 @@ func (s *EventPublisherService) GetEventStatus(ctx context.Context, eventID stri
 	if event.ParentID != "" {
 		status["parent_id"] = event.ParentID
 	}
+	if event.RequestID != "" {
+		status["request_id"] = event.RequestID
+	}
 
 	// Add delivery information
 	for _, delivery := range deliveries {
@@ -343,14 +399,58 @@ func (s *EventPublisherService) GetEventStatus(ctx context.Context, eventID stri
 			"max_attempts":             delivery.MaxAttempts,
 			"created_at":               delivery.CreatedAt,
 			"updated_at":               delivery.UpdatedAt,
+			"next_retry_at":            delivery.NextRetryAt,
 		}
 
+		// Older deliveries predate persisted policies; report the worker default for them
+		retryPolicy := models.DefaultDeliveryRetryPolicy()
+		if delivery.RetryPolicy != nil {
+			retryPolicy = *delivery.RetryPolicy
+		}
+		deliveryInfo["retry_policy"] = retryPolicy
+
 		status["deliveries"] = append(status["deliveries"].([]map[string]interface{}), deliveryInfo)
 	}
 
 	return status, nil
 }
 
+// eventClientID tells the client an event concerns, so client feeds can select its events. The
+// parent, a session for most events, is tried first; nil when neither it nor the entity tells.
+func (s *EventPublisherService) eventClientID(ctx context.Context, entityType models.EntityType, entityID string, parentID *string) *primitive.ObjectID {
+	if parentID != nil && *parentID != "" && s.ChatSessionRepo != nil {
+		var session *models.ChatSession
+		if id, err := primitive.ObjectIDFromHex(*parentID); err == nil {
+			session, _ = s.ChatSessionRepo.GetByID(ctx, id)
+		}
+		if session == nil {
+			session, _ = s.ChatSessionRepo.GetBySessionID(ctx, *parentID)
+		}
+		if session != nil && session.Client != nil {
+			return session.Client
+		}
+	}
+
+	switch entityType {
+	case models.EntityTypeChatSuggestion, models.EntityTypeAIService:
+		// Only known through their parent
+		return nil
+	case models.EntityTypeCSATSession:
+		if s.CSATSessionRepo == nil {
+			return nil
+		}
+	case models.EntityTypeCSATQuestion:
+		if s.CSATQuestionRepo == nil || s.CSATConfigRepo == nil {
+			return nil
+		}
+	}
+	clientID, err := s.getClientIDForEntity(ctx, entityType, entityID)
+	if err != nil {
+		return nil
+	}
+	return clientID
+}
+
 // getClientIDForEntity determines the client ID for different entity types.
 func (s *EventPublisherService) getClientIDForEntity(ctx context.Context, entityType models.EntityType, entityID string) (*primitive.ObjectID, error) {
 	objectID, err := primitive.ObjectIDFromHex(entityID)
@@ -437,6 +537,9 @@ func (s *EventPublisherService) getClientIDForEntity(ctx context.Context, entity
 		
 		return &csatConfig.Client, nil
 
+	case models.EntityTypeClient:
+		return &objectID, nil
+
 	default:
 		return nil, fmt.Errorf("unsupported entity type: %s", entityType)
 	}
@@ -4,15 +4,29 @@ package service
 import (
 	"context"
 	"fmt"
+	"reflect"
+	"sync"
 
+	"github.com/fraiday-org/api-service/internal/api/dto"
 	"github.com/fraiday-org/api-service/internal/models"
 	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"github.com/fraiday-org/api-service/internal/utils"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// SessionNotifier wakes requests waiting for activity on a session.
+type SessionNotifier interface {
+	Notify(ctx context.Context, key string) error
+}
+
 // EventService encapsulates business logic for events.
 type EventService struct {
 	Repo *repository.EventRepository
+	// Notifier, when set, is told about every event that belongs to a session
+	Notifier SessionNotifier
+	// Producer, when set, names this process as the producer of the events it creates
+	Producer string
 }
 
 // NewEventService creates a new EventService.
@@ -29,14 +43,24 @@ func (s *EventService) CreateEvent(
 	entityType models.EntityType,
 	entityID string,
 	parentID *string,
+	clientID *primitive.ObjectID,
 	data map[string]interface{},
 ) (*models.Event, error) {
 	event := &models.Event{
 		EventType:  eventType,
 		EntityType: entityType,
 		EntityID:   entityID,
+		RequestID:  telemetry.RequestIDFromContext(ctx),
+		ClientID:   clientID,
+		Producer:   s.Producer,
 		Data:       data,
 	}
+	if event.Producer == "" {
+		event.Producer = models.DefaultEventProducer
+	}
+	if schema, err := models.EventSchemaFor(eventType); err == nil {
+		event.SchemaVersion = schema.Version
+	}
 
 	if parentID != nil {
 		event.ParentID = *parentID
@@ -50,9 +74,24 @@ func (s *EventService) CreateEvent(
 		return nil, fmt.Errorf("failed to create event: %w", err)
 	}
 
+	if s.Notifier != nil {
+		if key := sessionKeyForEvent(event); key != "" {
+			_ = s.Notifier.Notify(ctx, key)
+		}
+	}
+
 	return event, nil
 }
 
+// sessionKeyForEvent returns the session an event belongs to: the entity itself for session events,
+// otherwise the parent, which is the session's hex ObjectID or external session_id depending on the publisher.
+func sessionKeyForEvent(event *models.Event) string {
+	if event.EntityType == models.EntityTypeChatSession {
+		return event.EntityID
+	}
+	return event.ParentID
+}
+
 // GetEventByID retrieves an event by its ID.
 func (s *EventService) GetEventByID(ctx context.Context, eventID string) (*models.Event, error) {
 	id, err := primitive.ObjectIDFromHex(eventID)
@@ -148,4 +187,35 @@ func (s *EventService) GetChildEvents(ctx context.Context, parentID string) ([]m
 	}
 
 	return events, nil
-}
\ No newline at end of file
+}
+
+// EventCatalog returns the catalog of event types and their schemas. It only depends on the
+// registered schemas, so it is built once.
+var EventCatalog = sync.OnceValue(func() *dto.EventCatalogResponse {
+	catalog := &dto.EventCatalogResponse{
+		Envelope: catalogSchema(models.EventEnvelope{}, "event_envelope"),
+	}
+	// parent_id is always present, null for events without a parent
+	if properties, ok := catalog.Envelope["properties"].(map[string]interface{}); ok {
+		properties["parent_id"] = map[string]interface{}{"type": []string{"string", "null"}}
+	}
+	for _, entityType := range models.EntityTypes() {
+		catalog.EntityTypes = append(catalog.EntityTypes, string(entityType))
+	}
+	for _, schema := range models.EventSchemas() {
+		catalog.Events = append(catalog.Events, dto.EventCatalogEntry{
+			EventType:     string(schema.EventType),
+			EntityType:    string(schema.EntityType),
+			SchemaVersion: schema.Version,
+			Schema:        catalogSchema(reflect.New(schema.DataType()).Interface(), string(schema.EventType)),
+		})
+	}
+	return catalog
+})
+
+func catalogSchema(v interface{}, title string) map[string]interface{} {
+	schema := utils.JSONSchemaOf(v)
+	schema["$schema"] = utils.JSONSchemaDialect
+	schema["title"] = title
+	return schema
+}
@@ -0,0 +1,238 @@
+// Package service provides business logic for the server-sent event feed of client events.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+const (
+	// eventStreamBatch is how many events a stream reads at a time
+	eventStreamBatch = 100
+	// defaultEventStreamInterval is how often a stream checks for new events
+	defaultEventStreamInterval = time.Second
+	// eventStreamWindow is how far back of its newest event a stream reads again. Event ids are
+	// stamped by the publishing process, so an event can be stored after newer ids from others.
+	eventStreamWindow = 5 * time.Second
+)
+
+var (
+	ErrEventStreamClientNotFound = errors.New("client not found")
+	ErrInvalidLastEventID        = errors.New("invalid Last-Event-ID")
+)
+
+// EventStreamService tails the events of a client, for feeds that replace inbound webhooks.
+type EventStreamService struct {
+	EventRepo  *repository.EventRepository
+	ClientRepo *repository.ClientRepository
+	// Interval, when set, is how often streams check for new events instead of every second
+	Interval time.Duration
+}
+
+// NewEventStreamService creates a new EventStreamService.
+func NewEventStreamService(eventRepo *repository.EventRepository, clientRepo *repository.ClientRepository) *EventStreamService {
+	return &EventStreamService{EventRepo: eventRepo, ClientRepo: clientRepo}
+}
+
+// EventStream is an open feed of one client's events. It reads from eventStreamWindow before the
+// newest event it has seen and skips the ids it has already returned, so events stored late are
+// returned too.
+type EventStream struct {
+	repo   *repository.EventRepository
+	filter bson.M
+	newest primitive.ObjectID
+	// seen holds the ids within the window that were returned or skipped
+	seen     map[primitive.ObjectID]bool
+	interval time.Duration
+}
+
+// Open starts a stream of the events of the client addressed by clientRef, either its ObjectID or
+// its client_id. eventTypes narrows the events down when set. The stream resumes after
+// lastEventID when given, and otherwise starts with the events published from now on. A resumed
+// stream can't tell which of the events stored shortly before lastEventID were returned, so it
+// returns them again; clients drop the ids they have.
+func (s *EventStreamService) Open(ctx context.Context, clientRef string, eventTypes []string, lastEventID string) (*EventStream, error) {
+	var client *models.Client
+	if id := ParseObjectID(clientRef); id != nil {
+		client, _ = s.ClientRepo.GetByID(ctx, *id)
+	}
+	if client == nil {
+		var err error
+		if client, err = s.ClientRepo.GetByClientID(ctx, clientRef); err != nil {
+			return nil, ErrEventStreamClientNotFound
+		}
+	}
+
+	filter := bson.M{"client": client.ID}
+	if len(eventTypes) > 0 {
+		types := make(bson.A, 0, len(eventTypes))
+		for _, eventType := range eventTypes {
+			if _, err := models.EventSchemaFor(models.EventType(eventType)); err != nil {
+				return nil, fmt.Errorf("%w: %s", err, eventType)
+			}
+			types = append(types, eventType)
+		}
+		filter["event_type"] = bson.M{"$in": types}
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultEventStreamInterval
+	}
+	stream := &EventStream{repo: s.EventRepo, filter: filter, seen: map[primitive.ObjectID]bool{}, interval: interval}
+
+	if lastEventID != "" {
+		id, err := primitive.ObjectIDFromHex(lastEventID)
+		if err != nil {
+			return nil, ErrInvalidLastEventID
+		}
+		stream.newest = id
+		stream.seen[id] = true
+		return stream, nil
+	}
+
+	// Events already in the window are skipped, except those of the current second, so events
+	// published while the stream opens are kept
+	start := primitive.NewObjectIDFromTimestamp(time.Now())
+	stream.newest = start
+	for {
+		events, err := stream.read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		skipped := 0
+		for _, event := range events {
+			if event.ID.Timestamp().Before(start.Timestamp()) {
+				stream.seen[event.ID] = true
+				skipped++
+			}
+		}
+		if skipped == 0 || len(events) < eventStreamBatch {
+			return stream, nil
+		}
+	}
+}
+
+// Next returns the events after the stream's position, oldest first, waiting up to wait for some
+// to be published. It returns no events when wait passes or ctx is done first.
+func (st *EventStream) Next(ctx context.Context, wait time.Duration) ([]models.Event, error) {
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	ticker := time.NewTicker(st.interval)
+	defer ticker.Stop()
+
+	for {
+		events, err := st.read(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if len(events) > 0 {
+			st.advance(events)
+			for i := range events {
+				events[i].Data = plainDocument(events[i].Data)
+			}
+			return events, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-deadline.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, nil
+		}
+	}
+}
+
+// read returns up to about eventStreamBatch events of the window the stream hasn't seen, oldest
+// first, paging past those it has.
+func (st *EventStream) read(ctx context.Context) ([]models.Event, error) {
+	after := primitive.NewObjectIDFromTimestamp(st.newest.Timestamp().Add(-eventStreamWindow))
+	var unseen []models.Event
+	for len(unseen) < eventStreamBatch {
+		filter := bson.M{}
+		for k, v := range st.filter {
+			filter[k] = v
+		}
+		events, err := st.repo.ListSince(ctx, filter, after, eventStreamBatch)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			if !st.seen[event.ID] {
+				unseen = append(unseen, event)
+			}
+		}
+		if len(events) < eventStreamBatch {
+			break
+		}
+		after = events[len(events)-1].ID
+	}
+	return unseen, nil
+}
+
+// advance records events as returned, moves the window up to the newest of them and forgets the
+// ids that fell out of it.
+func (st *EventStream) advance(events []models.Event) {
+	for _, event := range events {
+		st.seen[event.ID] = true
+		if event.ID.Timestamp().After(st.newest.Timestamp()) {
+			st.newest = event.ID
+		}
+	}
+	start := st.newest.Timestamp().Add(-eventStreamWindow)
+	for id := range st.seen {
+		if id.Timestamp().Before(start) {
+			delete(st.seen, id)
+		}
+	}
+}
+
+// plainDocument turns the nested documents and arrays of data, as decoded from MongoDB, into maps
+// and slices, so they encode to JSON as objects and arrays.
+func plainDocument(data map[string]interface{}) map[string]interface{} {
+	plain := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		plain[k] = plainValue(v)
+	}
+	return plain
+}
+
+func plainValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			m[e.Key] = plainValue(e.Value)
+		}
+		return m
+	case primitive.M:
+		return plainDocument(t)
+	case map[string]interface{}:
+		return plainDocument(t)
+	case primitive.A:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = plainValue(e)
+		}
+		return a
+	case []interface{}:
+		a := make([]interface{}, len(t))
+		for i, e := range t {
+			a[i] = plainValue(e)
+		}
+		return a
+	}
+	return v
+}
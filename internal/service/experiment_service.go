@@ -0,0 +1,351 @@
+// Package service provides business logic for A/B experiments on AI configurations.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+const (
+	maxExperimentVariants = 10
+	maxExperimentWeight   = 10000
+)
+
+var (
+	ErrExperimentNotFound = errors.New("experiment not found")
+	ErrExperimentRunning  = errors.New("client already has a running experiment")
+	ErrExperimentStatus   = errors.New("experiment is not in a status that allows this")
+)
+
+// ExperimentService manages a client's experiments and assigns its sessions to their variants.
+type ExperimentService struct {
+	Repo               *repository.ExperimentRepository
+	ClientRepo         *repository.ClientRepository
+	ChatSessionRepo    *repository.ChatSessionRepository
+	PromptTemplateRepo *repository.PromptTemplateRepository
+	Clients            ClientCache
+	// MetricsDB is where variant metrics are aggregated, e.g. the analytics database
+	MetricsDB *mongo.Database
+}
+
+// NewExperimentService creates a new ExperimentService.
+func NewExperimentService(repo *repository.ExperimentRepository, clientRepo *repository.ClientRepository, chatSessionRepo *repository.ChatSessionRepository, promptTemplateRepo *repository.PromptTemplateRepository, metricsDB *mongo.Database) *ExperimentService {
+	return &ExperimentService{
+		Repo:               repo,
+		ClientRepo:         clientRepo,
+		ChatSessionRepo:    chatSessionRepo,
+		PromptTemplateRepo: promptTemplateRepo,
+		MetricsDB:          metricsDB,
+	}
+}
+
+// CreateExperiment stores a draft experiment for an existing client.
+func (s *ExperimentService) CreateExperiment(ctx context.Context, experiment *models.Experiment) error {
+	if _, err := s.ClientRepo.GetByClientID(ctx, experiment.ClientID); err != nil {
+		return errors.New("client not found")
+	}
+	experiment.Name = strings.TrimSpace(experiment.Name)
+	if experiment.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := s.validateVariants(ctx, experiment.ClientID, experiment.Variants); err != nil {
+		return err
+	}
+	experiment.Status = models.ExperimentStatusDraft
+	experiment.StartedAt, experiment.StoppedAt = nil, nil
+	return s.Repo.Create(ctx, experiment)
+}
+
+// GetExperiment returns one of a client's experiments.
+func (s *ExperimentService) GetExperiment(ctx context.Context, clientID, id string) (*models.Experiment, error) {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrExperimentNotFound
+	}
+	experiment, err := s.Repo.GetByID(ctx, clientID, *objID)
+	if err != nil {
+		return nil, ErrExperimentNotFound
+	}
+	return experiment, nil
+}
+
+// ListExperiments returns a client's experiments, optionally only those in status.
+func (s *ExperimentService) ListExperiments(ctx context.Context, clientID, status string) ([]models.Experiment, error) {
+	filter := bson.M{"client_id": clientID}
+	if status != "" {
+		filter["status"] = status
+	}
+	return s.Repo.List(ctx, filter)
+}
+
+// UpdateExperiment applies update to an experiment that hasn't started yet.
+func (s *ExperimentService) UpdateExperiment(ctx context.Context, clientID, id string, update bson.M) (*models.Experiment, error) {
+	experiment, err := s.GetExperiment(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if name, ok := update["name"].(string); ok {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, errors.New("name is required")
+		}
+		update["name"] = name
+	}
+	if variants, ok := update["variants"].([]models.ExperimentVariant); ok {
+		if err := s.validateVariants(ctx, clientID, variants); err != nil {
+			return nil, err
+		}
+	}
+	return s.transition(ctx, experiment, models.ExperimentStatusDraft, update)
+}
+
+// StartExperiment starts splitting the client's sessions between a draft experiment's variants.
+func (s *ExperimentService) StartExperiment(ctx context.Context, clientID, id string) (*models.Experiment, error) {
+	experiment, err := s.GetExperiment(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := s.transition(ctx, experiment, models.ExperimentStatusDraft, bson.M{
+		"status":     models.ExperimentStatusRunning,
+		"started_at": time.Now().UTC(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, ErrExperimentRunning
+	}
+	return updated, err
+}
+
+// StopExperiment ends a running experiment; its sessions go back to the client's configuration.
+func (s *ExperimentService) StopExperiment(ctx context.Context, clientID, id string) (*models.Experiment, error) {
+	experiment, err := s.GetExperiment(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.transition(ctx, experiment, models.ExperimentStatusRunning, bson.M{
+		"status":     models.ExperimentStatusStopped,
+		"stopped_at": time.Now().UTC(),
+	})
+}
+
+// DeleteExperiment removes an experiment that isn't running.
+func (s *ExperimentService) DeleteExperiment(ctx context.Context, clientID, id string) error {
+	experiment, err := s.GetExperiment(ctx, clientID, id)
+	if err != nil {
+		return err
+	}
+	deleted, err := s.Repo.Delete(ctx, clientID, experiment.ID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrExperimentStatus
+	}
+	return nil
+}
+
+func (s *ExperimentService) transition(ctx context.Context, experiment *models.Experiment, from models.ExperimentStatus, set bson.M) (*models.Experiment, error) {
+	if experiment.Status != from {
+		return nil, fmt.Errorf("%w: experiment is %s", ErrExperimentStatus, experiment.Status)
+	}
+	updated, err := s.Repo.UpdateInStatus(ctx, experiment.ClientID, experiment.ID, from, set)
+	if err != nil {
+		return nil, err
+	}
+	if updated == nil {
+		return nil, ErrExperimentStatus
+	}
+	return updated, nil
+}
+
+// validateVariants checks that variants name distinct, valid configurations with positive weights.
+func (s *ExperimentService) validateVariants(ctx context.Context, clientID string, variants []models.ExperimentVariant) error {
+	if len(variants) < 2 || len(variants) > maxExperimentVariants {
+		return fmt.Errorf("an experiment needs between 2 and %d variants", maxExperimentVariants)
+	}
+	names := make(map[string]bool, len(variants))
+	total := 0
+	for i := range variants {
+		v := &variants[i]
+		v.Name = strings.TrimSpace(v.Name)
+		if v.Name == "" {
+			return fmt.Errorf("variant %d: name is required", i+1)
+		}
+		if names[v.Name] {
+			return fmt.Errorf("variant %s: name is used twice", v.Name)
+		}
+		names[v.Name] = true
+		if v.Weight <= 0 {
+			return fmt.Errorf("variant %s: weight must be positive", v.Name)
+		}
+		total += v.Weight
+		if v.AIConfig != nil {
+			v.AIConfig.Enabled = true
+			if err := validateAIConfig(v.AIConfig); err != nil {
+				return fmt.Errorf("variant %s: %w", v.Name, err)
+			}
+		}
+		if t := v.ConfidenceThreshold; t != nil && (*t < 0 || *t > 1) {
+			return fmt.Errorf("variant %s: confidence_threshold must be between 0 and 1", v.Name)
+		}
+		if v.Prompt != nil {
+			objID := ParseObjectID(v.Prompt.TemplateID)
+			if objID == nil {
+				return fmt.Errorf("variant %s: %w", v.Name, ErrPromptTemplateNotFound)
+			}
+			template, err := s.PromptTemplateRepo.GetByID(ctx, clientID, *objID)
+			if err != nil {
+				return fmt.Errorf("variant %s: %w", v.Name, ErrPromptTemplateNotFound)
+			}
+			if template.Version(v.Prompt.Version) == nil {
+				return fmt.Errorf("variant %s: %w", v.Name, ErrPromptVersionNotFound)
+			}
+		}
+	}
+	if total > maxExperimentWeight {
+		return fmt.Errorf("variant weights must not add up to more than %d", maxExperimentWeight)
+	}
+	return nil
+}
+
+// Assign returns the variant of the running experiment of the session's client, with the tag for
+// what it produces. Both are nil when the client runs no experiment.
+func (s *ExperimentService) Assign(ctx context.Context, sessionID primitive.ObjectID) (*models.ExperimentAssignment, *models.ExperimentVariant) {
+	session, err := s.ChatSessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, nil
+	}
+	return s.AssignSession(ctx, session)
+}
+
+// AssignSession is Assign for a session already loaded.
+func (s *ExperimentService) AssignSession(ctx context.Context, session *models.ChatSession) (*models.ExperimentAssignment, *models.ExperimentVariant) {
+	if session.Client == nil {
+		return nil, nil
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client)
+	if err != nil {
+		return nil, nil
+	}
+	experiment, err := s.Repo.FindRunning(ctx, client.ClientID)
+	if err != nil || experiment == nil {
+		return nil, nil
+	}
+	variant := experiment.Assign(session.ID.Hex())
+	if variant == nil {
+		return nil, nil
+	}
+	return experiment.Assignment(variant), variant
+}
+
+// CSATTag returns the variant tag for responses to a CSAT survey, taken from the chat session it
+// was sent in, so satisfaction can be compared between variants.
+func (s *ExperimentService) CSATTag(ctx context.Context, csatSession *models.CSATSession) *models.ExperimentAssignment {
+	baseSessionID, _ := parseSessionID(csatSession.ChatSessionID)
+	session, err := s.ChatSessionRepo.GetBySessionID(ctx, baseSessionID)
+	if err != nil {
+		return nil
+	}
+	assignment, _ := s.AssignSession(ctx, session)
+	return assignment
+}
+
+// GetMetrics compares the AI replies and CSAT responses each variant of an experiment produced.
+func (s *ExperimentService) GetMetrics(ctx context.Context, clientID, id string) (*dto.ExperimentMetrics, error) {
+	experiment, err := s.GetExperiment(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	experimentID := experiment.ID.Hex()
+
+	var replies []struct {
+		Variant       string  `bson:"_id"`
+		Sessions      int64   `bson:"sessions"`
+		Replies       int64   `bson:"replies"`
+		Average       float64 `bson:"average"`
+		LowConfidence int64   `bson:"low_confidence"`
+	}
+	err = s.aggregate(ctx, models.ChatMessage{}.TableName(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"config.experiment.experiment_id": experimentID,
+			"sender_type":                     string(models.SenderTypeAssistant),
+			"config.ai_response":              true,
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":            "$config.experiment.variant",
+			"session_ids":    bson.M{"$addToSet": "$session"},
+			"replies":        bson.M{"$sum": 1},
+			"average":        bson.M{"$avg": "$confidence"},
+			"low_confidence": bson.M{"$sum": bson.M{"$cond": bson.A{bson.M{"$lte": bson.A{"$confidence", DefaultConfidenceThreshold}}, 1, 0}}},
+		}}},
+		{{Key: "$addFields", Value: bson.M{"sessions": bson.M{"$size": "$session_ids"}}}},
+	}, &replies)
+	if err != nil {
+		return nil, err
+	}
+
+	var csat []struct {
+		Variant   string  `bson:"_id"`
+		Responses int64   `bson:"responses"`
+		Average   float64 `bson:"average"`
+	}
+	err = s.aggregate(ctx, models.CSATResponse{}.TableName(), mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"experiment.experiment_id": experimentID}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$experiment.variant",
+			"responses": bson.M{"$sum": 1},
+			"average":   bson.M{"$avg": "$score"},
+		}}},
+	}, &csat)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &dto.ExperimentMetrics{
+		ExperimentID: experimentID,
+		Status:       experiment.Status,
+		Variants:     make([]dto.ExperimentVariantMetrics, len(experiment.Variants)),
+	}
+	index := make(map[string]int, len(experiment.Variants))
+	for i, v := range experiment.Variants {
+		metrics.Variants[i] = dto.ExperimentVariantMetrics{Variant: v.Name, Weight: v.Weight}
+		index[v.Name] = i
+	}
+	for _, row := range replies {
+		if i, ok := index[row.Variant]; ok {
+			m := &metrics.Variants[i]
+			m.Sessions, m.AIReplies, m.AverageConfidence, m.LowConfidence = row.Sessions, row.Replies, row.Average, row.LowConfidence
+			if row.Replies > 0 {
+				m.LowConfidenceRate = float64(row.LowConfidence) / float64(row.Replies)
+			}
+		}
+	}
+	for _, row := range csat {
+		if i, ok := index[row.Variant]; ok {
+			metrics.Variants[i].CSATResponses, metrics.Variants[i].CSATAverageScore = row.Responses, row.Average
+		}
+	}
+	return metrics, nil
+}
+
+func (s *ExperimentService) aggregate(ctx context.Context, collection string, pipeline mongo.Pipeline, rows interface{}) error {
+	cursor, err := s.MetricsDB.Collection(collection).Aggregate(ctx, pipeline)
+	if err != nil {
+		return fmt.Errorf("failed to aggregate experiment metrics: %w", err)
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, rows); err != nil {
+		return fmt.Errorf("failed to decode experiment metrics: %w", err)
+	}
+	return nil
+}
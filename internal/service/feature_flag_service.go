@@ -0,0 +1,207 @@
+// Package service provides business logic for feature flags.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+var (
+	ErrFeatureFlagNotFound = errors.New("feature flag not found")
+	ErrFeatureFlagExists   = errors.New("feature flag already exists")
+	ErrInvalidFeatureFlag  = errors.New("invalid feature flag")
+)
+
+const (
+	// DefaultFeatureFlagTTL is how long flags are served from memory before being reloaded
+	DefaultFeatureFlagTTL   = 30 * time.Second
+	maxFeatureFlagKeyLength = 64
+)
+
+// FeatureFlagService manages feature flags and evaluates them for clients. Every flag is held in
+// memory and reloaded on expiry or when the cache bus reports a change.
+type FeatureFlagService struct {
+	Repo *repository.FeatureFlagRepository
+	// Invalidator is optional; when set, flag changes reach the other nodes before the TTL expires
+	Invalidator CacheInvalidator
+	TTL         time.Duration
+	logger      *zap.Logger
+
+	mu       sync.RWMutex
+	flags    map[string]models.FeatureFlag
+	loadedAt time.Time
+}
+
+// NewFeatureFlagService creates a new FeatureFlagService.
+func NewFeatureFlagService(repo *repository.FeatureFlagRepository, logger *zap.Logger) *FeatureFlagService {
+	return &FeatureFlagService{Repo: repo, TTL: DefaultFeatureFlagTTL, logger: logger}
+}
+
+// validateFeatureFlag normalizes the client lists of flag and checks its settings.
+func validateFeatureFlag(flag *models.FeatureFlag) error {
+	if flag.Key == "" || len(flag.Key) > maxFeatureFlagKeyLength {
+		return fmt.Errorf("%w: key must be 1 to %d characters", ErrInvalidFeatureFlag, maxFeatureFlagKeyLength)
+	}
+	for _, r := range flag.Key {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.') {
+			return fmt.Errorf("%w: key may only contain lowercase letters, digits and \"_-.\"", ErrInvalidFeatureFlag)
+		}
+	}
+	if flag.Percentage < 0 || flag.Percentage > 100 {
+		return fmt.Errorf("%w: percentage must be between 0 and 100", ErrInvalidFeatureFlag)
+	}
+
+	flag.Clients = normalizeClientIDs(flag.Clients)
+	flag.ExcludedClients = normalizeClientIDs(flag.ExcludedClients)
+	for _, id := range flag.Clients {
+		for _, excluded := range flag.ExcludedClients {
+			if id == excluded {
+				return fmt.Errorf("%w: client %s is both included and excluded", ErrInvalidFeatureFlag, id)
+			}
+		}
+	}
+	return nil
+}
+
+// normalizeClientIDs trims ids and drops blanks and duplicates.
+func normalizeClientIDs(ids []string) []string {
+	seen := make(map[string]bool, len(ids))
+	normalized := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		normalized = append(normalized, id)
+	}
+	return normalized
+}
+
+// ListFlags returns every feature flag.
+func (s *FeatureFlagService) ListFlags(ctx context.Context) ([]models.FeatureFlag, error) {
+	return s.Repo.List(ctx)
+}
+
+// GetFlag returns the feature flag with key.
+func (s *FeatureFlagService) GetFlag(ctx context.Context, key string) (*models.FeatureFlag, error) {
+	flag, err := s.Repo.GetByKey(ctx, key)
+	if err != nil {
+		return nil, ErrFeatureFlagNotFound
+	}
+	return flag, nil
+}
+
+// CreateFlag stores a new feature flag.
+func (s *FeatureFlagService) CreateFlag(ctx context.Context, flag *models.FeatureFlag) (*models.FeatureFlag, error) {
+	if err := validateFeatureFlag(flag); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Create(ctx, flag); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("%w: %s", ErrFeatureFlagExists, flag.Key)
+		}
+		return nil, err
+	}
+	s.invalidate(ctx, flag.Key)
+	return flag, nil
+}
+
+// UpdateFlag replaces the settings of the feature flag with flag.Key.
+func (s *FeatureFlagService) UpdateFlag(ctx context.Context, flag *models.FeatureFlag) (*models.FeatureFlag, error) {
+	if err := validateFeatureFlag(flag); err != nil {
+		return nil, err
+	}
+	updated, err := s.Repo.Update(ctx, flag)
+	if err != nil {
+		return nil, ErrFeatureFlagNotFound
+	}
+	s.invalidate(ctx, flag.Key)
+	return updated, nil
+}
+
+// DeleteFlag removes a feature flag; it is then off for every client.
+func (s *FeatureFlagService) DeleteFlag(ctx context.Context, key string) error {
+	if err := s.Repo.Delete(ctx, key); err != nil {
+		return ErrFeatureFlagNotFound
+	}
+	s.invalidate(ctx, key)
+	return nil
+}
+
+// IsEnabled reports whether the feature flag key is on for the client with clientID. Unknown
+// flags are off.
+func (s *FeatureFlagService) IsEnabled(ctx context.Context, key, clientID string) bool {
+	enabled, _ := s.Evaluate(ctx, key, clientID)
+	return enabled
+}
+
+// Evaluate reports whether the feature flag key is on for the client with clientID, and whether
+// the flag exists at all, so callers can fall back to their own default for unknown flags.
+func (s *FeatureFlagService) Evaluate(ctx context.Context, key, clientID string) (enabled, defined bool) {
+	flags := s.cachedFlags(ctx)
+	flag, ok := flags[key]
+	if !ok {
+		return false, false
+	}
+	return flag.EnabledFor(clientID), true
+}
+
+// Forget marks the cached flags stale so the next evaluation reloads them. Its signature lets it
+// subscribe to the cache bus directly.
+func (s *FeatureFlagService) Forget(cache.Invalidation) {
+	s.mu.Lock()
+	s.loadedAt = time.Time{}
+	s.mu.Unlock()
+}
+
+// cachedFlags returns the flags held in memory, reloading them once they expire. When MongoDB
+// can't be read the stale flags are kept, so a database hiccup doesn't flip features off.
+func (s *FeatureFlagService) cachedFlags(ctx context.Context) map[string]models.FeatureFlag {
+	s.mu.RLock()
+	flags, loadedAt := s.flags, s.loadedAt
+	s.mu.RUnlock()
+	if !loadedAt.IsZero() && time.Since(loadedAt) < s.TTL {
+		return flags
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Another caller may have reloaded them while this one waited
+	if !s.loadedAt.IsZero() && time.Since(s.loadedAt) < s.TTL {
+		return s.flags
+	}
+
+	list, err := s.Repo.List(ctx)
+	// Retry after a full TTL either way, rather than on every evaluation while MongoDB is down
+	s.loadedAt = time.Now()
+	if err != nil {
+		s.logger.Warn("Failed to load feature flags, using cached flags", zap.Error(err))
+		return s.flags
+	}
+	s.flags = make(map[string]models.FeatureFlag, len(list))
+	for _, flag := range list {
+		s.flags[flag.Key] = flag
+	}
+	return s.flags
+}
+
+// invalidate drops the changed flag from caches cluster-wide. The local cache is dropped even
+// without a bus, so changes apply at once on the node that made them.
+func (s *FeatureFlagService) invalidate(ctx context.Context, key string) {
+	s.Forget(cache.Invalidation{Kind: cache.KindFeatureFlag, Key: key})
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindFeatureFlag, key)
+	}
+}
@@ -0,0 +1,278 @@
+// Package service provides business logic for generic webhook channels, which map arbitrary JSON
+// payloads to chat messages.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"github.com/fraiday-org/api-service/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrGenericWebhookUnauthorized    = errors.New("invalid webhook token")
+	ErrGenericWebhookChannelNotFound = errors.New("generic webhook channel not found")
+	ErrGenericWebhookUnmapped        = errors.New("payload is missing a mapped field")
+)
+
+// imageExtensions decide whether a mapped attachment URL is sent on as an image or a file
+var imageExtensions = map[string]bool{".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".webp": true}
+
+// GenericWebhookService turns payloads posted to generic_webhook channels into user messages. The
+// channel's channel_config.mapping holds a JSONPath per message field (see
+// models.WebhookMappingFields) and optional metadata paths; inbound_token authenticates posts.
+// Replies reach the client's widget through its usual event processors.
+type GenericWebhookService struct {
+	ClientRepo         *repository.ClientRepository
+	ClientChannelRepo  *repository.ClientChannelRepository
+	ChatMessageRepo    *repository.ChatMessageRepository
+	SessionService     *ChatSessionService
+	ChatMessageService *ChatMessageService
+	// LifecycleService, when set, applies the closed-session policy to inbound messages
+	LifecycleService *SessionLifecycleService
+	// SecretResolver resolves secret:// references in channel configs when they're loaded
+	SecretResolver *secrets.Resolver
+	logger         *zap.Logger
+}
+
+// NewGenericWebhookService creates a new GenericWebhookService.
+func NewGenericWebhookService(
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	sessionService *ChatSessionService,
+	chatMessageService *ChatMessageService,
+	logger *zap.Logger,
+) *GenericWebhookService {
+	return &GenericWebhookService{
+		ClientRepo:         clientRepo,
+		ClientChannelRepo:  clientChannelRepo,
+		ChatMessageRepo:    chatMessageRepo,
+		SessionService:     sessionService,
+		ChatMessageService: chatMessageService,
+		logger:             logger,
+	}
+}
+
+// HandleWebhook maps a payload posted to a generic_webhook channel to a user message, stores it and
+// triggers the AI workflow. A payload repeating a mapped external_id returns the stored message.
+// The message is nil when the session is closed to new messages.
+func (s *GenericWebhookService) HandleWebhook(ctx context.Context, channelID, token string, body []byte) (*models.ChatMessage, error) {
+	channel, err := s.channel(ctx, channelID, token)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Keep large numeric ids exact instead of rounding them through float64
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("%w: payload is not valid JSON", ErrGenericWebhookUnmapped)
+	}
+
+	mapping := asMap(channel.ChannelConfig["mapping"])
+	text, _ := mappedString(mapping, "text", payload)
+	sessionKey, _ := mappedString(mapping, "session_key", payload)
+	if sessionKey == "" {
+		return nil, fmt.Errorf("%w: session_key", ErrGenericWebhookUnmapped)
+	}
+	attachments := mappedAttachments(mapping, payload)
+	if text == "" && len(attachments) == 0 {
+		return nil, fmt.Errorf("%w: text", ErrGenericWebhookUnmapped)
+	}
+	sender, _ := mappedString(mapping, "sender", payload)
+	senderName, _ := mappedString(mapping, "sender_name", payload)
+	externalID, _ := mappedString(mapping, "external_id", payload)
+
+	client, err := s.ClientRepo.GetByID(ctx, channel.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	if !client.IsActive {
+		return nil, ErrGenericWebhookChannelNotFound
+	}
+
+	session, effectiveSessionID, err := s.SessionService.GetOrCreateSessionBySessionID(ctx, "webhook:"+channel.ID.Hex()+":"+sessionKey, client, channel)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get or create session: %w", err)
+	}
+	if s.LifecycleService != nil {
+		session, err = s.LifecycleService.ResolveForMessage(ctx, session, client, channel)
+		if err != nil {
+			if errors.Is(err, ErrSessionClosed) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		effectiveSessionID = session.SessionID
+	}
+
+	var dedupKey string
+	if externalID != "" {
+		dedupKey = models.MessageDedupKey(client.ID, channel.ID, externalID)
+		if existing, err := s.ChatMessageService.FindDuplicate(ctx, dedupKey); err == nil && existing != nil {
+			telemetry.ObserveMessageDedup(string(channel.ChannelType))
+			return existing, nil
+		}
+	}
+
+	data := map[string]interface{}{}
+	for name, raw := range asMap(mapping["metadata"]) {
+		p, _ := raw.(string)
+		compiled, err := utils.CompileJSONPath(p)
+		if err != nil {
+			continue
+		}
+		if v, ok := compiled.First(payload); ok {
+			data[name] = jsonValue(v)
+		}
+	}
+
+	aiEnabled := true
+	if v, ok := channel.ChannelConfig["ai_enabled"].(bool); ok {
+		aiEnabled = v
+	}
+	if sender == "" {
+		sender = sessionKey
+	}
+	msg := &models.ChatMessage{
+		ExternalID:  externalID,
+		DedupKey:    dedupKey,
+		Sender:      sender,
+		SenderName:  senderName,
+		SenderType:  string(models.SenderTypeUser),
+		SessionID:   session.ID,
+		Text:        text,
+		Attachments: attachments,
+		Category:    models.MessageCategoryMessage,
+		Config:      map[string]interface{}{"ai_enabled": aiEnabled},
+	}
+	if len(data) > 0 {
+		msg.Data = map[string]interface{}{"webhook": data}
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		// A repeat racing the first delivery gets the stored message
+		if errors.Is(err, ErrMessageDuplicate) {
+			telemetry.ObserveMessageDedup(string(channel.ChannelType))
+			return msg, nil
+		}
+		return nil, fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	if aiEnabled {
+		TriggerChatWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	}
+	return msg, nil
+}
+
+// channel returns the active generic_webhook channel channelID if token is its inbound_token.
+func (s *GenericWebhookService) channel(ctx context.Context, channelID, token string) (*models.ClientChannel, error) {
+	id, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return nil, ErrGenericWebhookChannelNotFound
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, id)
+	if err != nil || channel.ChannelType != models.ChannelTypeGenericWebhook || !channel.IsActive {
+		return nil, ErrGenericWebhookChannelNotFound
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return nil, err
+	}
+	expected, _ := channel.ChannelConfig["inbound_token"].(string)
+	if expected == "" || subtle.ConstantTimeCompare([]byte(expected), []byte(token)) != 1 {
+		return nil, ErrGenericWebhookUnauthorized
+	}
+	return channel, nil
+}
+
+// mappedString evaluates the mapping's path for field against payload and renders the first match
+// as a string. The second result is false when the field isn't mapped or matches nothing.
+func mappedString(mapping map[string]interface{}, field string, payload interface{}) (string, bool) {
+	p, _ := mapping[field].(string)
+	if p == "" {
+		return "", false
+	}
+	compiled, err := utils.CompileJSONPath(p)
+	if err != nil {
+		return "", false
+	}
+	v, ok := compiled.First(payload)
+	if !ok || v == nil {
+		return "", false
+	}
+	return stringifyJSON(v), true
+}
+
+// mappedAttachments turns every URL matched by the attachments path into an attachment. The path
+// usually ends in a wildcard, e.g. $.files[*].url.
+func mappedAttachments(mapping map[string]interface{}, payload interface{}) []models.Attachment {
+	p, _ := mapping["attachments"].(string)
+	compiled, err := utils.CompileJSONPath(p)
+	if p == "" || err != nil {
+		return nil
+	}
+	var attachments []models.Attachment
+	for _, v := range compiled.Find(payload) {
+		fileURL, _ := v.(string)
+		if !isAbsoluteURL(fileURL) {
+			continue
+		}
+		name := path.Base(strings.SplitN(fileURL, "?", 2)[0])
+		attachmentType := "file"
+		if imageExtensions[strings.ToLower(path.Ext(name))] {
+			attachmentType = "image"
+		}
+		attachments = append(attachments, models.Attachment{FileName: name, FileURL: fileURL, Type: attachmentType})
+	}
+	return attachments
+}
+
+// stringifyJSON renders a decoded JSON value as message text: strings as they are, numbers and
+// booleans in their JSON form and objects or arrays as compact JSON.
+func stringifyJSON(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	}
+	encoded, _ := json.Marshal(v)
+	return string(encoded)
+}
+
+// jsonValue converts json.Number, which MongoDB can't store, back to a plain number.
+func jsonValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, item := range t {
+			t[k] = jsonValue(item)
+		}
+	case []interface{}:
+		for i, item := range t {
+			t[i] = jsonValue(item)
+		}
+	}
+	return v
+}
@@ -0,0 +1,275 @@
+// Package service provides business logic for handing chat sessions over to human agents.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrHandoverInvalidTransition = errors.New("handover is not in a state that allows this action")
+	ErrHandoverAgentMismatch     = errors.New("handover is assigned to a different agent")
+	ErrHandoverTargetRequired    = errors.New("target_queue or target_agent is required")
+)
+
+// HandoverRequest holds the inputs for requesting a handover.
+type HandoverRequest struct {
+	Reason      string
+	TargetQueue string
+	TargetAgent string
+	Skills      []string
+	RequestedBy string
+}
+
+// HandoverService drives the handover state machine stored on ChatSession:
+//
+//	(none|declined|completed) -> requested -> accepted -> completed
+//	                             requested -> declined
+//
+// When AssignmentService is set, handovers without a target agent are routed to one on request.
+type HandoverService struct {
+	ChatSessionRepo       *repository.ChatSessionRepository
+	EventPublisherService *EventPublisherService
+	AssignmentService     *AssignmentService
+}
+
+// NewHandoverService creates a new HandoverService.
+func NewHandoverService(chatSessionRepo *repository.ChatSessionRepository, eventPublisherService *EventPublisherService) *HandoverService {
+	return &HandoverService{
+		ChatSessionRepo:       chatSessionRepo,
+		EventPublisherService: eventPublisherService,
+	}
+}
+
+// GetHandover returns the current handover of a session, or nil if it was never handed over.
+func (s *HandoverService) GetHandover(ctx context.Context, sessionID string) (*models.SessionHandover, error) {
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.Handover, nil
+}
+
+// RequestHandover opens a new handover for a session without one in progress.
+func (s *HandoverService) RequestHandover(ctx context.Context, sessionID string, req HandoverRequest) (*models.SessionHandover, error) {
+	if req.TargetQueue == "" && req.TargetAgent == "" {
+		return nil, ErrHandoverTargetRequired
+	}
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	handover := &models.SessionHandover{
+		Status:      models.HandoverStatusRequested,
+		Reason:      req.Reason,
+		TargetQueue: req.TargetQueue,
+		TargetAgent: req.TargetAgent,
+		Skills:      req.Skills,
+		RequestedBy: req.RequestedBy,
+		RequestedAt: time.Now().UTC(),
+	}
+
+	var assignment *models.SessionAssignment
+	if handover.TargetAgent == "" && s.AssignmentService != nil {
+		assignment, err = s.AssignmentService.Assign(ctx, session, handover)
+		if err != nil && !errors.Is(err, ErrNoAgentAvailable) {
+			return nil, err
+		}
+		// With no agent available the handover stays open to anyone on the target queue
+		if assignment != nil {
+			handover.TargetAgent = assignment.AgentID
+		}
+	}
+
+	from := []models.HandoverStatus{models.HandoverStatusDeclined, models.HandoverStatusCompleted}
+	if err := s.transition(ctx, session, from, true, handover, models.EventTypeHandoverRequested); err != nil {
+		if assignment != nil {
+			_ = s.AssignmentService.Release(ctx, session, assignment.AgentID)
+		}
+		return nil, err
+	}
+	if assignment != nil {
+		if err := s.AssignmentService.Record(ctx, session, assignment); err != nil {
+			return nil, err
+		}
+	}
+	return handover, nil
+}
+
+// RouteHandover runs agent routing for a requested handover that has no target agent yet,
+// e.g. after agents have come online.
+func (s *HandoverService) RouteHandover(ctx context.Context, sessionID string) (*models.SessionHandover, error) {
+	if s.AssignmentService == nil {
+		return nil, ErrNoAgentAvailable
+	}
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Handover == nil || session.Handover.Status != models.HandoverStatusRequested || session.Handover.TargetAgent != "" {
+		return nil, ErrHandoverInvalidTransition
+	}
+
+	handover := *session.Handover
+	assignment, err := s.AssignmentService.Assign(ctx, session, &handover)
+	if err != nil {
+		return nil, err
+	}
+	handover.TargetAgent = assignment.AgentID
+
+	from := []models.HandoverStatus{models.HandoverStatusRequested}
+	if err := s.transition(ctx, session, from, false, &handover, models.EventTypeHandoverAssigned); err != nil {
+		_ = s.AssignmentService.Release(ctx, session, assignment.AgentID)
+		return nil, err
+	}
+	if err := s.AssignmentService.Record(ctx, session, assignment); err != nil {
+		return nil, err
+	}
+	return &handover, nil
+}
+
+// AcceptHandover assigns a requested handover to agentID.
+func (s *HandoverService) AcceptHandover(ctx context.Context, sessionID, agentID string) (*models.SessionHandover, error) {
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Handover == nil || session.Handover.Status != models.HandoverStatusRequested {
+		return nil, ErrHandoverInvalidTransition
+	}
+	if session.Handover.TargetAgent != "" && session.Handover.TargetAgent != agentID {
+		return nil, ErrHandoverAgentMismatch
+	}
+
+	now := time.Now().UTC()
+	handover := *session.Handover
+	handover.Status = models.HandoverStatusAccepted
+	handover.Agent = agentID
+	handover.AcceptedAt = &now
+
+	from := []models.HandoverStatus{models.HandoverStatusRequested}
+	if err := s.transition(ctx, session, from, false, &handover, models.EventTypeHandoverAccepted); err != nil {
+		return nil, err
+	}
+	if s.AssignmentService != nil && !hasOpenAssignment(session, agentID) {
+		if err := s.AssignmentService.Claim(ctx, session, agentID, handover.TargetQueue); err != nil {
+			return nil, err
+		}
+	}
+	return &handover, nil
+}
+
+// DeclineHandover rejects a requested handover. The session can be handed over again afterwards.
+func (s *HandoverService) DeclineHandover(ctx context.Context, sessionID, agentID, reason string) (*models.SessionHandover, error) {
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Handover == nil || session.Handover.Status != models.HandoverStatusRequested {
+		return nil, ErrHandoverInvalidTransition
+	}
+	if session.Handover.TargetAgent != "" && session.Handover.TargetAgent != agentID {
+		return nil, ErrHandoverAgentMismatch
+	}
+
+	now := time.Now().UTC()
+	handover := *session.Handover
+	handover.Status = models.HandoverStatusDeclined
+	handover.Agent = agentID
+	handover.DeclineReason = reason
+	handover.DeclinedAt = &now
+
+	from := []models.HandoverStatus{models.HandoverStatusRequested}
+	if err := s.transition(ctx, session, from, false, &handover, models.EventTypeHandoverDeclined); err != nil {
+		return nil, err
+	}
+	s.releaseAgent(ctx, session, agentID)
+	return &handover, nil
+}
+
+// CompleteHandover closes an accepted handover. Only the accepting agent may complete it.
+func (s *HandoverService) CompleteHandover(ctx context.Context, sessionID, agentID string) (*models.SessionHandover, error) {
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Handover == nil || session.Handover.Status != models.HandoverStatusAccepted {
+		return nil, ErrHandoverInvalidTransition
+	}
+	if session.Handover.Agent != agentID {
+		return nil, ErrHandoverAgentMismatch
+	}
+
+	now := time.Now().UTC()
+	handover := *session.Handover
+	handover.Status = models.HandoverStatusCompleted
+	handover.CompletedAt = &now
+
+	from := []models.HandoverStatus{models.HandoverStatusAccepted}
+	if err := s.transition(ctx, session, from, false, &handover, models.EventTypeHandoverCompleted); err != nil {
+		return nil, err
+	}
+	s.releaseAgent(ctx, session, agentID)
+	return &handover, nil
+}
+
+// transition persists handover if the session is still in one of the from states, then publishes eventType.
+func (s *HandoverService) transition(
+	ctx context.Context,
+	session *models.ChatSession,
+	from []models.HandoverStatus,
+	allowNone bool,
+	handover *models.SessionHandover,
+	eventType models.EventType,
+) error {
+	ok, err := s.ChatSessionRepo.UpdateHandover(ctx, session.ID, from, allowNone, handover)
+	if err != nil {
+		return fmt.Errorf("failed to update handover: %w", err)
+	}
+	if !ok {
+		return ErrHandoverInvalidTransition
+	}
+
+	if s.EventPublisherService != nil {
+		_, _ = s.EventPublisherService.PublishChatSessionEvent(ctx, eventType, session.ID.Hex(), map[string]interface{}{
+			"session_id": session.SessionID,
+			"handover":   handover,
+		})
+	}
+	return nil
+}
+
+// releaseAgent frees the agent's routing slot if it holds an open assignment on the session.
+func (s *HandoverService) releaseAgent(ctx context.Context, session *models.ChatSession, agentID string) {
+	if s.AssignmentService != nil && hasOpenAssignment(session, agentID) {
+		_ = s.AssignmentService.Release(ctx, session, agentID)
+	}
+}
+
+func hasOpenAssignment(session *models.ChatSession, agentID string) bool {
+	for _, assignment := range session.Assignments {
+		if assignment.AgentID == agentID && assignment.ReleasedAt == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *HandoverService) getSession(ctx context.Context, sessionID string) (*models.ChatSession, error) {
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.New("invalid session id")
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("chat session not found")
+	}
+	return session, nil
+}
@@ -0,0 +1,170 @@
+// Package service provides business logic for dependency health checks.
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+
+	"github.com/fraiday-org/api-service/internal/redis"
+)
+
+const (
+	// HealthStatusReady means every dependency is up
+	HealthStatusReady = "ready"
+	// HealthStatusDegraded means only non-critical dependencies are down; the service still
+	// handles traffic, without the features those dependencies back
+	HealthStatusDegraded = "degraded"
+	// HealthStatusNotReady means a critical dependency is down
+	HealthStatusNotReady = "not_ready"
+
+	DependencyStatusUp   = "up"
+	DependencyStatusDown = "down"
+)
+
+// DependencyProbe checks one dependency and returns the version it reports, if any.
+type DependencyProbe struct {
+	Name string
+	// Critical dependencies make the service not ready when they are down; others degrade it
+	Critical bool
+	Check    func(ctx context.Context) (string, error)
+}
+
+// DependencyHealth is the result of probing one dependency.
+type DependencyHealth struct {
+	Status    string `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMS int64  `json:"latency_ms"`
+	Version   string `json:"version,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// HealthReport is the aggregated health of the service's dependencies.
+type HealthReport struct {
+	Status    string                      `json:"status"`
+	Timestamp int64                       `json:"timestamp"`
+	Version   string                      `json:"version"`
+	Checks    map[string]DependencyHealth `json:"checks"`
+}
+
+// HealthService probes the service's dependencies.
+type HealthService struct {
+	Version string
+	// Timeout bounds each probe
+	Timeout time.Duration
+	Probes  []DependencyProbe
+}
+
+// NewHealthService creates a new HealthService reporting version.
+func NewHealthService(version string, timeout time.Duration, probes ...DependencyProbe) *HealthService {
+	return &HealthService{Version: version, Timeout: timeout, Probes: probes}
+}
+
+// Check runs every probe concurrently and aggregates the results.
+func (s *HealthService) Check(ctx context.Context) *HealthReport {
+	report := &HealthReport{
+		Status:    HealthStatusReady,
+		Timestamp: time.Now().UTC().Unix(),
+		Version:   s.Version,
+		Checks:    make(map[string]DependencyHealth, len(s.Probes)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, probe := range s.Probes {
+		wg.Add(1)
+		go func(probe DependencyProbe) {
+			defer wg.Done()
+			probeCtx, cancel := context.WithTimeout(ctx, s.Timeout)
+			defer cancel()
+
+			start := time.Now()
+			version, err := probe.Check(probeCtx)
+			health := DependencyHealth{
+				Status:    DependencyStatusUp,
+				Critical:  probe.Critical,
+				LatencyMS: time.Since(start).Milliseconds(),
+				Version:   version,
+			}
+			if err != nil {
+				health.Status = DependencyStatusDown
+				health.Error = err.Error()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[probe.Name] = health
+			switch {
+			case err == nil:
+			case probe.Critical:
+				report.Status = HealthStatusNotReady
+			case report.Status == HealthStatusReady:
+				report.Status = HealthStatusDegraded
+			}
+		}(probe)
+	}
+	wg.Wait()
+	return report
+}
+
+// MongoProbe pings the MongoDB primary and reads the server version.
+func MongoProbe(client *mongo.Client) DependencyProbe {
+	return DependencyProbe{Name: "mongodb", Critical: true, Check: func(ctx context.Context) (string, error) {
+		if err := client.Ping(ctx, readpref.Primary()); err != nil {
+			return "", err
+		}
+		var info struct {
+			Version string `bson:"version"`
+		}
+		// The version is informational; a server that hides it is still up
+		_ = client.Database("admin").RunCommand(ctx, bson.D{{Key: "buildInfo", Value: 1}}).Decode(&info)
+		return info.Version, nil
+	}}
+}
+
+// RabbitMQProbe opens a connection to the broker and reads its version from the handshake.
+func RabbitMQProbe(url string) DependencyProbe {
+	return DependencyProbe{Name: "rabbitmq", Critical: true, Check: func(ctx context.Context) (string, error) {
+		timeout := 5 * time.Second
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = time.Until(deadline)
+		}
+		conn, err := amqp.DialConfig(url, amqp.Config{Dial: amqp.DefaultDial(timeout)})
+		if err != nil {
+			return "", err
+		}
+		defer conn.Close()
+		version, _ := conn.Properties["version"].(string)
+		return version, nil
+	}}
+}
+
+// RedisProbe authenticates to Redis at addr when password is set, and reads the server version.
+func RedisProbe(addr, password string) DependencyProbe {
+	return DependencyProbe{Name: "redis", Check: func(ctx context.Context) (string, error) {
+		client := redis.New(addr, password, 0, 1)
+		defer client.Close()
+		reply, err := client.Do(ctx, "INFO", "server")
+		if err != nil {
+			return "", err
+		}
+		info, _ := reply.(string)
+		for _, line := range strings.Split(info, "\r\n") {
+			if version, ok := strings.CutPrefix(line, "redis_version:"); ok {
+				return version, nil
+			}
+		}
+		return "", nil
+	}}
+}
+
+// AIServiceProbe calls the AI service's health endpoint.
+func AIServiceProbe(ai *AIService) DependencyProbe {
+	return DependencyProbe{Name: "ai_service", Check: ai.HealthCheck}
+}
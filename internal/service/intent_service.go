@@ -0,0 +1,136 @@
+// Package service provides business logic for intent classification and routing of chat messages.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+)
+
+// IntentService manages per-client intent routing rules and applies them in the chat workflow.
+type IntentService struct {
+	ClientRepo      *repository.ClientRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	ChatMessageRepo *repository.ChatMessageRepository
+	Invalidator     CacheInvalidator
+	// Set on workers, which classify and route messages
+	Clients         ClientCache
+	AIService       *AIService
+	HandoverService *HandoverService
+	logger          *zap.Logger
+}
+
+// NewIntentService creates a new IntentService.
+func NewIntentService(clientRepo *repository.ClientRepository, chatSessionRepo *repository.ChatSessionRepository, chatMessageRepo *repository.ChatMessageRepository, logger *zap.Logger) *IntentService {
+	return &IntentService{
+		ClientRepo:      clientRepo,
+		ChatSessionRepo: chatSessionRepo,
+		ChatMessageRepo: chatMessageRepo,
+		logger:          logger,
+	}
+}
+
+// GetConfig returns a client's intent routing, or nil if none is configured.
+func (s *IntentService) GetConfig(ctx context.Context, clientID string) (*models.IntentRouting, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return client.IntentRouting, nil
+}
+
+// SetConfig validates and stores a client's intent routing, replacing any existing rules.
+func (s *IntentService) SetConfig(ctx context.Context, clientID string, routing *models.IntentRouting) error {
+	for i, rule := range routing.Rules {
+		if rule.Intent == "" {
+			return fmt.Errorf("rules[%d]: intent is required", i)
+		}
+		if rule.MinConfidence < 0 || rule.MinConfidence > 1 {
+			return fmt.Errorf("rules[%d]: min_confidence must be between 0 and 1", i)
+		}
+		switch rule.Action {
+		case models.IntentActionAIAnswer, models.IntentActionSkipBot:
+		case models.IntentActionHandover:
+			if rule.TargetQueue == "" {
+				return fmt.Errorf("rules[%d]: target_queue is required for handover", i)
+			}
+		default:
+			return fmt.Errorf("rules[%d]: invalid action: %s", i, rule.Action)
+		}
+	}
+
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"intent_routing": routing}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// DeleteConfig removes a client's intent routing.
+func (s *IntentService) DeleteConfig(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"intent_routing": nil}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+func (s *IntentService) invalidate(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
+
+// Route classifies message for clients with intent routing enabled, stores the intent on the message
+// and returns the action of the first matching rule. A handover action also requests the handover.
+// Messages of clients without routing, and messages that can't be classified, get IntentActionAIAnswer.
+func (s *IntentService) Route(ctx context.Context, message *models.ChatMessage) (models.IntentAction, error) {
+	if s.AIService == nil {
+		return models.IntentActionAIAnswer, nil
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, message.SessionID)
+	if err != nil || session.Client == nil || session.Test {
+		return models.IntentActionAIAnswer, nil
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client)
+	if err != nil || client.IntentRouting == nil || !client.IntentRouting.Enabled {
+		return models.IntentActionAIAnswer, nil
+	}
+
+	intent, confidence, err := s.AIService.ClassifyIntent(ctx, message.ID.Hex(), session.SessionID, message.Text)
+	if err != nil {
+		return models.IntentActionAIAnswer, fmt.Errorf("failed to classify intent: %w", err)
+	}
+	message.Intent, message.IntentConfidence = intent, confidence
+	if err := s.ChatMessageRepo.Update(ctx, message.ID, bson.M{"intent": intent, "intent_confidence": confidence}); err != nil {
+		s.logger.Warn("Failed to store message intent",
+			zap.String("message_id", message.ID.Hex()),
+			zap.Error(err))
+	}
+
+	rule := client.IntentRouting.Match(intent, confidence)
+	if rule == nil {
+		return models.IntentActionAIAnswer, nil
+	}
+	if rule.Action == models.IntentActionHandover {
+		if s.HandoverService == nil {
+			return models.IntentActionAIAnswer, errors.New("handover service not configured")
+		}
+		_, err := s.HandoverService.RequestHandover(ctx, session.ID.Hex(), HandoverRequest{
+			Reason:      "intent:" + intent,
+			TargetQueue: rule.TargetQueue,
+			RequestedBy: "intent_routing",
+		})
+		// A handover already in progress means a human has the session; the bot stays out either way
+		if err != nil && !errors.Is(err, ErrHandoverInvalidTransition) {
+			return models.IntentActionAIAnswer, fmt.Errorf("failed to request handover: %w", err)
+		}
+	}
+	return rule.Action, nil
+}
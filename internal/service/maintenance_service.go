@@ -0,0 +1,171 @@
+// Package service provides business logic for periodic maintenance jobs.
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+// MaintenanceJob names a periodic maintenance job that workers run on behalf of the scheduler.
+type MaintenanceJob string
+
+const (
+	// MaintenanceRetryDeliveries re-enqueues delivery retries whose delayed task was lost
+	MaintenanceRetryDeliveries MaintenanceJob = "retry_deliveries"
+	// MaintenanceCSATExpiry expires surveys left open past their configuration's expiry window
+	MaintenanceCSATExpiry MaintenanceJob = "csat_expiry"
+	// MaintenanceThreadInactivity closes threads idle past their channel's or client's inactivity window, along
+	// with their sessions
+	MaintenanceThreadInactivity MaintenanceJob = "thread_inactivity"
+	// MaintenanceUsageRollup publishes the usage reports of finished days
+	MaintenanceUsageRollup MaintenanceJob = "usage_rollup"
+	// MaintenanceAIFeedback forwards queued answer feedback to the AI service
+	MaintenanceAIFeedback MaintenanceJob = "ai_feedback"
+	// MaintenanceScheduledMessages re-enqueues pending scheduled messages whose delayed task was lost
+	MaintenanceScheduledMessages MaintenanceJob = "scheduled_messages"
+)
+
+const (
+	// maintenanceBatchSize bounds the records a single job run looks at; the rest wait for the next run
+	maintenanceBatchSize = 500
+	// deliveryRetryGrace is how late a scheduled retry must be before its delayed task counts as lost
+	deliveryRetryGrace = 5 * time.Minute
+	// scheduledMessageGrace is how late a scheduled message must be before its delayed task counts as lost
+	scheduledMessageGrace = 5 * time.Minute
+)
+
+// MaintenanceTaskClient enqueues the deliveries retried by maintenance.
+type MaintenanceTaskClient interface {
+	EnqueueDeliverToProcessor(ctx context.Context, processorID string, eventData map[string]interface{}, deliveryID string) error
+}
+
+// MaintenanceService runs the periodic maintenance jobs. Each job guards its own updates, so a
+// job queued twice, or running alongside a worker's own loops, doesn't repeat work.
+type MaintenanceService struct {
+	DeliveryRepo *repository.EventDeliveryRepository
+	CSAT         *CSATService
+	Sessions     *SessionLifecycleService
+	Usage        *UsageService
+	TaskClient   MaintenanceTaskClient
+	// Feedback forwards answer feedback; the ai_feedback job fails without it
+	Feedback *AIFeedbackService
+	// ScheduledMessages re-enqueues overdue scheduled messages; the scheduled_messages job fails without it
+	ScheduledMessages *ScheduledMessageService
+	logger            *zap.Logger
+}
+
+// NewMaintenanceService creates a new MaintenanceService.
+func NewMaintenanceService(
+	deliveryRepo *repository.EventDeliveryRepository,
+	csat *CSATService,
+	sessions *SessionLifecycleService,
+	usage *UsageService,
+	taskClient MaintenanceTaskClient,
+	logger *zap.Logger,
+) *MaintenanceService {
+	return &MaintenanceService{
+		DeliveryRepo: deliveryRepo,
+		CSAT:         csat,
+		Sessions:     sessions,
+		Usage:        usage,
+		TaskClient:   taskClient,
+		logger:       logger,
+	}
+}
+
+// Run runs a maintenance job and returns how many records it changed.
+func (s *MaintenanceService) Run(ctx context.Context, job MaintenanceJob) (int, error) {
+	switch job {
+	case MaintenanceRetryDeliveries:
+		return s.retryDeliveries(ctx, time.Now().UTC())
+	case MaintenanceCSATExpiry:
+		return s.expireSurveys(ctx, time.Now().UTC())
+	case MaintenanceThreadInactivity:
+		return s.Sessions.CloseInactiveThreads(ctx)
+	case MaintenanceUsageRollup:
+		return s.Usage.ReportUsage(ctx, time.Now())
+	case MaintenanceAIFeedback:
+		if s.Feedback == nil {
+			return 0, fmt.Errorf("AI feedback forwarding not configured")
+		}
+		return s.Feedback.Forward(ctx, time.Now().UTC())
+	case MaintenanceScheduledMessages:
+		if s.ScheduledMessages == nil {
+			return 0, fmt.Errorf("scheduled messages not configured")
+		}
+		return s.ScheduledMessages.RequeueOverdue(ctx, time.Now().UTC().Add(-scheduledMessageGrace), maintenanceBatchSize)
+	default:
+		return 0, fmt.Errorf("unknown maintenance job %q", job)
+	}
+}
+
+// retryDeliveries re-enqueues pending deliveries whose retry is overdue, typically because the
+// temporary queue holding the delayed task went away with a broker restart. Each retry is claimed
+// by moving it to now before it is enqueued, so it is enqueued once per lapse.
+func (s *MaintenanceService) retryDeliveries(ctx context.Context, now time.Time) (int, error) {
+	if s.TaskClient == nil {
+		return 0, fmt.Errorf("task queue unavailable, cannot retry deliveries")
+	}
+	deliveries, err := s.DeliveryRepo.ListOverdueRetries(ctx, now.Add(-deliveryRetryGrace), maintenanceBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	retried := 0
+	for _, delivery := range deliveries {
+		claimed, err := s.DeliveryRepo.ClaimRetry(ctx, delivery.ID, *delivery.NextRetryAt, now)
+		if err != nil {
+			return retried, err
+		}
+		if !claimed {
+			continue
+		}
+		if err := s.TaskClient.EnqueueDeliverToProcessor(
+			ctx,
+			delivery.EventProcessorConfigID.Hex(),
+			delivery.RequestPayload,
+			delivery.ID.Hex(),
+		); err != nil {
+			return retried, fmt.Errorf("failed to enqueue delivery %s: %w", delivery.ID.Hex(), err)
+		}
+		retried++
+	}
+	return retried, nil
+}
+
+// expireSurveys expires the open surveys of every configuration with an expiry window that were
+// triggered longer ago than it, covering surveys whose expiry task never ran.
+func (s *MaintenanceService) expireSurveys(ctx context.Context, now time.Time) (int, error) {
+	configs, err := s.CSAT.CSATConfigRepo.List(ctx, bson.M{"expiry_hours": bson.M{"$gt": 0}}, 0, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, config := range configs {
+		sessions, _, err := s.CSAT.CSATSessionRepo.ListWithFilters(ctx, bson.M{
+			"csat_configuration_id": config.ID,
+			"status":                bson.M{"$in": []string{"pending", "in_progress"}},
+			"triggered_at":          bson.M{"$lt": now.Add(-time.Duration(config.ExpiryHours) * time.Hour)},
+		}, 0, maintenanceBatchSize, bson.D{{Key: "triggered_at", Value: 1}})
+		if err != nil {
+			return expired, err
+		}
+		for _, session := range sessions {
+			if err := s.CSAT.ExpireSurvey(ctx, session.ID.Hex()); err != nil {
+				s.logger.Warn("Failed to expire CSAT survey",
+					zap.String("csat_session_id", session.ID.Hex()),
+					zap.Error(err))
+				continue
+			}
+			expired++
+		}
+	}
+	return expired, nil
+}
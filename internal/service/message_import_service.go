@@ -0,0 +1,398 @@
+// Package service provides business logic for importing message history.
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+const (
+	messageImportBatchSize = 500
+	// maxMessageImportLine bounds a row of an import file
+	maxMessageImportLine = 1 << 20
+)
+
+var (
+	ErrMessageImportNotFound = errors.New("message import not found")
+	// ErrMessageImportQueueUnavailable is returned when there is no task queue to run imports on
+	ErrMessageImportQueueUnavailable = errors.New("task queue unavailable, cannot run message imports")
+)
+
+// MessageImportTaskClient enqueues message imports for the worker.
+type MessageImportTaskClient interface {
+	EnqueueMessageImport(ctx context.Context, importID string) error
+}
+
+// MessageImportService takes import files on API servers and stores their rows on workers.
+type MessageImportService struct {
+	Repo              *repository.MessageImportRepository
+	ClientRepo        *repository.ClientRepository
+	ClientChannelRepo *repository.ClientChannelRepository
+	ChatSessionRepo   *repository.ChatSessionRepository
+	ChatMessageRepo   *repository.ChatMessageRepository
+	// Set on API servers, which queue the imports
+	TaskClient MessageImportTaskClient
+	// EventPublisherService, when set, publishes the summary events of imports that ask for them
+	EventPublisherService *EventPublisherService
+}
+
+// NewMessageImportService creates a new MessageImportService.
+func NewMessageImportService(
+	repo *repository.MessageImportRepository,
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+) *MessageImportService {
+	return &MessageImportService{
+		Repo:              repo,
+		ClientRepo:        clientRepo,
+		ClientChannelRepo: clientChannelRepo,
+		ChatSessionRepo:   chatSessionRepo,
+		ChatMessageRepo:   chatMessageRepo,
+	}
+}
+
+// CreateImport stores the import file in content for the client's channel and queues the import.
+func (s *MessageImportService) CreateImport(ctx context.Context, clientID, channelID, events, fileName string, content io.Reader) (*models.MessageImport, error) {
+	if events == "" {
+		events = models.MessageImportEventsNone
+	}
+	if events != models.MessageImportEventsNone && events != models.MessageImportEventsSummary {
+		return nil, fmt.Errorf("events must be %q or %q", models.MessageImportEventsNone, models.MessageImportEventsSummary)
+	}
+	if s.TaskClient == nil {
+		return nil, ErrMessageImportQueueUnavailable
+	}
+
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	channelOID := ParseObjectID(channelID)
+	if channelOID == nil {
+		return nil, errors.New("channel_id is required")
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *channelOID)
+	if err != nil || channel.ClientID != client.ID {
+		return nil, errors.New("channel not found")
+	}
+
+	fileID, size, err := s.Repo.UploadFile(fileName, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store import file: %w", err)
+	}
+	if size == 0 {
+		_ = s.Repo.DeleteFile(fileID)
+		return nil, errors.New("import file is empty")
+	}
+
+	imp := &models.MessageImport{
+		ClientID:  client.ID,
+		ChannelID: channel.ID,
+		Status:    models.MessageImportStatusQueued,
+		Events:    events,
+		FileID:    fileID,
+		FileName:  fileName,
+		FileSize:  size,
+	}
+	if err := s.Repo.Create(ctx, imp); err != nil {
+		_ = s.Repo.DeleteFile(fileID)
+		return nil, err
+	}
+	if err := s.TaskClient.EnqueueMessageImport(ctx, imp.ID.Hex()); err != nil {
+		_ = s.Repo.Finish(ctx, imp.ID, models.MessageImportStatusFailed, "failed to queue the import")
+		_ = s.Repo.DeleteFile(fileID)
+		return nil, fmt.Errorf("failed to queue message import: %w", err)
+	}
+	return imp, nil
+}
+
+// GetImport returns a message import with its progress.
+func (s *MessageImportService) GetImport(ctx context.Context, id string) (*models.MessageImport, error) {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrMessageImportNotFound
+	}
+	imp, err := s.Repo.GetByID(ctx, *objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrMessageImportNotFound
+		}
+		return nil, err
+	}
+	return imp, nil
+}
+
+// ListImports returns a client's message imports, newest first.
+func (s *MessageImportService) ListImports(ctx context.Context, clientID string, limit, offset int) ([]models.MessageImport, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return s.Repo.List(ctx, bson.M{"client": client.ID}, limit, offset)
+}
+
+// Run stores the rows of a queued import's file in batches, recording progress after each, and
+// removes the file once done. An import that isn't queued anymore is left alone.
+func (s *MessageImportService) Run(ctx context.Context, id string) error {
+	imp, err := s.GetImport(ctx, id)
+	if err != nil {
+		return err
+	}
+	ok, err := s.Repo.Start(ctx, imp.ID, time.Now().UTC())
+	if err != nil || !ok {
+		return err
+	}
+	defer func() { _ = s.Repo.DeleteFile(imp.FileID) }()
+
+	run := &messageImportRun{service: s, imp: imp, sessions: map[string]*importedSession{}}
+	if err := run.readFile(ctx); err != nil {
+		_ = s.Repo.Finish(ctx, imp.ID, models.MessageImportStatusFailed, err.Error())
+		return err
+	}
+	if imp.Events == models.MessageImportEventsSummary {
+		run.publishSummary(ctx)
+	}
+	return s.Repo.Finish(ctx, imp.ID, models.MessageImportStatusCompleted, "")
+}
+
+// messageImportRun is the state of an import while a worker runs it.
+type messageImportRun struct {
+	service  *MessageImportService
+	imp      *models.MessageImport
+	sessions map[string]*importedSession // By session_id
+}
+
+// importedSession is a session an import stores messages in.
+type importedSession struct {
+	id       primitive.ObjectID
+	created  bool // By this import
+	imported int
+}
+
+type importLine struct {
+	number int
+	data   []byte
+}
+
+func (r *messageImportRun) readFile(ctx context.Context) error {
+	file, err := r.service.Repo.OpenFile(r.imp.FileID)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageImportLine)
+	batch := make([]importLine, 0, messageImportBatchSize)
+	number := 0
+	for scanner.Scan() {
+		number++
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+		batch = append(batch, importLine{number: number, data: append([]byte(nil), data...)})
+		if len(batch) == messageImportBatchSize {
+			if err := r.importBatch(ctx, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read line %d: %w", number+1, err)
+	}
+	if len(batch) > 0 {
+		return r.importBatch(ctx, batch)
+	}
+	return nil
+}
+
+// importBatch stores the messages of a batch of rows and records what became of each row.
+func (r *messageImportRun) importBatch(ctx context.Context, batch []importLine) error {
+	progress := models.MessageImportProgress{Processed: len(batch)}
+	fail := func(line int, reason string) {
+		progress.Failed++
+		progress.Errors = append(progress.Errors, models.MessageImportError{Line: line, Error: reason})
+	}
+
+	msgs := make([]models.ChatMessage, 0, len(batch))
+	lines := make([]int, 0, len(batch))
+	sessions := make([]*importedSession, 0, len(batch))
+	for _, line := range batch {
+		var row models.MessageImportRow
+		if err := json.Unmarshal(line.data, &row); err != nil {
+			fail(line.number, "invalid JSON: "+err.Error())
+			continue
+		}
+		if err := validateImportRow(&row); err != nil {
+			fail(line.number, err.Error())
+			continue
+		}
+		session, err := r.session(ctx, row.SessionID, *row.CreatedAt, &progress)
+		if err != nil {
+			if errors.Is(err, errImportSessionTaken) {
+				fail(line.number, err.Error())
+				continue
+			}
+			return err
+		}
+		msgs = append(msgs, r.message(&row, session.id))
+		lines = append(lines, line.number)
+		sessions = append(sessions, session)
+	}
+
+	if len(msgs) > 0 {
+		failed, err := r.service.ChatMessageRepo.Import(ctx, msgs)
+		if err != nil {
+			return fmt.Errorf("failed to store messages: %w", err)
+		}
+		spans := map[*importedSession][2]time.Time{}
+		for i, msg := range msgs {
+			if we, ok := failed[i]; ok {
+				if we.Code == 11000 {
+					progress.Skipped++
+				} else {
+					fail(lines[i], we.Message)
+				}
+				continue
+			}
+			progress.Imported++
+			session := sessions[i]
+			session.imported++
+			if !session.created {
+				continue
+			}
+			span, seen := spans[session]
+			if !seen || msg.CreatedAt.Before(span[0]) {
+				span[0] = msg.CreatedAt
+			}
+			if !seen || msg.CreatedAt.After(span[1]) {
+				span[1] = msg.CreatedAt
+			}
+			spans[session] = span
+		}
+		for session, span := range spans {
+			if err := r.service.ChatSessionRepo.ExtendImported(ctx, session.id, r.imp.ID, span[0], span[1]); err != nil {
+				return fmt.Errorf("failed to update imported session: %w", err)
+			}
+		}
+	}
+
+	return r.service.Repo.AddProgress(ctx, r.imp.ID, progress)
+}
+
+var errImportSessionTaken = errors.New("session_id belongs to another client")
+
+// session returns the session of the client with sessionID, creating it closed as of at when
+// there is none.
+func (r *messageImportRun) session(ctx context.Context, sessionID string, at time.Time, progress *models.MessageImportProgress) (*importedSession, error) {
+	if session, ok := r.sessions[sessionID]; ok {
+		return session, nil
+	}
+
+	existing, _, err := r.service.ChatSessionRepo.ListWithFilters(ctx, bson.M{"session_id": sessionID}, 0, 1, bson.D{{Key: "created_at", Value: 1}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up session: %w", err)
+	}
+	if len(existing) > 0 {
+		if existing[0].Client == nil || *existing[0].Client != r.imp.ClientID {
+			return nil, errImportSessionTaken
+		}
+		session := &importedSession{id: existing[0].ID}
+		r.sessions[sessionID] = session
+		return session, nil
+	}
+
+	created := &models.ChatSession{
+		SessionID:      sessionID,
+		CreatedAt:      at,
+		Client:         &r.imp.ClientID,
+		ClientChannel:  &r.imp.ChannelID,
+		Import:         &r.imp.ID,
+		State:          models.SessionStateClosed,
+		StateChangedAt: &at,
+		StateReason:    "imported",
+		ClosedAt:       &at,
+		LastActivityAt: &at,
+	}
+	if err := r.service.ChatSessionRepo.CreateImported(ctx, created); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	progress.SessionsCreated++
+	session := &importedSession{id: created.ID, created: true}
+	r.sessions[sessionID] = session
+	return session, nil
+}
+
+func (r *messageImportRun) message(row *models.MessageImportRow, sessionID primitive.ObjectID) models.ChatMessage {
+	category := row.Category
+	if category == "" {
+		category = models.MessageCategoryMessage
+	}
+	msg := models.ChatMessage{
+		ExternalID:  row.ExternalID,
+		Sender:      row.Sender,
+		SenderName:  row.SenderName,
+		SenderType:  row.SenderType,
+		SessionID:   sessionID,
+		Text:        row.Text,
+		Attachments: row.Attachments,
+		Data:        row.Data,
+		Category:    category,
+		CreatedAt:   row.CreatedAt.UTC(),
+	}
+	// Importing a file again skips the rows it already stored
+	if row.ExternalID != "" {
+		msg.DedupKey = models.MessageDedupKey(r.imp.ClientID, r.imp.ChannelID, row.ExternalID)
+	}
+	return msg
+}
+
+func validateImportRow(row *models.MessageImportRow) error {
+	switch {
+	case strings.TrimSpace(row.SessionID) == "":
+		return errors.New("session_id is required")
+	case row.Sender == "":
+		return errors.New("sender is required")
+	case row.Text == "" && len(row.Attachments) == 0:
+		return errors.New("text or attachments are required")
+	case row.CreatedAt == nil:
+		return errors.New("created_at is required")
+	}
+	return ValidateSenderType(row.SenderType)
+}
+
+// publishSummary publishes one messages_imported event for each session messages were imported into.
+func (r *messageImportRun) publishSummary(ctx context.Context) {
+	if r.service.EventPublisherService == nil {
+		return
+	}
+	for sessionID, session := range r.sessions {
+		if session.imported == 0 {
+			continue
+		}
+		_, _ = r.service.EventPublisherService.PublishChatSessionEvent(ctx, models.EventTypeMessagesImported, session.id.Hex(), map[string]interface{}{
+			"import_id":       r.imp.ID.Hex(),
+			"session_id":      sessionID,
+			"session_created": session.created,
+			"messages":        session.imported,
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// Package service provides business logic for long-polling a session for new messages and events.
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	messagePollBatch = 100
+	// messagePollRecheck bounds how long a write can go unnoticed when its notification is lost
+	messagePollRecheck = 2 * time.Second
+)
+
+var ErrInvalidPollCursor = errors.New("invalid cursor")
+
+// SessionSubscriber delivers a signal whenever key is notified.
+type SessionSubscriber interface {
+	Subscribe(key string) (<-chan struct{}, func())
+}
+
+// MessagePollService answers long-poll requests for clients that can't hold a realtime connection.
+type MessagePollService struct {
+	ChatSessionRepo *repository.ChatSessionRepository
+	ChatMessageRepo *repository.ChatMessageRepository
+	EventRepo       *repository.EventRepository
+	// Subscriber, when set, wakes polls as soon as a session is written to instead of on the next recheck
+	Subscriber SessionSubscriber
+}
+
+// NewMessagePollService creates a new MessagePollService.
+func NewMessagePollService(chatSessionRepo *repository.ChatSessionRepository, chatMessageRepo *repository.ChatMessageRepository, eventRepo *repository.EventRepository) *MessagePollService {
+	return &MessagePollService{
+		ChatSessionRepo: chatSessionRepo,
+		ChatMessageRepo: chatMessageRepo,
+		EventRepo:       eventRepo,
+	}
+}
+
+// Poll returns the session's messages and events after the cursor, waiting up to wait for
+// something to arrive. An empty response carries the cursor it was asked for.
+func (s *MessagePollService) Poll(ctx context.Context, sessionID, after string, wait time.Duration) (*dto.MessagePollResponse, error) {
+	sid, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.New("invalid session id")
+	}
+	var cursor primitive.ObjectID
+	if after != "" {
+		if cursor, err = primitive.ObjectIDFromHex(after); err != nil {
+			return nil, ErrInvalidPollCursor
+		}
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, sid)
+	if err != nil {
+		return nil, errors.New("chat session not found")
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Subscribe before the first read so a write between the read and the wait isn't missed
+	var wake <-chan struct{}
+	if s.Subscriber != nil {
+		byID, unsubscribeID := s.Subscriber.Subscribe(sid.Hex())
+		defer unsubscribeID()
+		wake = byID
+		if session.SessionID != "" && session.SessionID != sid.Hex() {
+			byExternalID, unsubscribeExternal := s.Subscriber.Subscribe(session.SessionID)
+			defer unsubscribeExternal()
+			wake = merge(ctx, byID, byExternalID)
+		}
+	}
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	recheck := time.NewTicker(messagePollRecheck)
+	defer recheck.Stop()
+
+	for {
+		resp, err := s.fetch(ctx, session, cursor)
+		if err != nil {
+			return nil, err
+		}
+		if len(resp.Messages) > 0 || len(resp.Events) > 0 {
+			return resp, nil
+		}
+		select {
+		case <-wake:
+		case <-recheck.C:
+		case <-deadline.C:
+			return resp, nil
+		case <-ctx.Done():
+			return resp, nil
+		}
+	}
+}
+
+func (s *MessagePollService) fetch(ctx context.Context, session *models.ChatSession, cursor primitive.ObjectID) (*dto.MessagePollResponse, error) {
+	messages, err := s.ChatMessageRepo.ListSince(ctx, session.ID, cursor, messagePollBatch)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.EventRepo.ListSince(ctx, bson.M{"$or": bson.A{
+		bson.M{"entity_type": models.EntityTypeChatSession, "entity_id": session.ID.Hex()},
+		bson.M{"parent_id": bson.M{"$in": bson.A{session.ID.Hex(), session.SessionID}}},
+	}}, cursor, messagePollBatch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Both lists share one cursor, so when either batch is full the other is cut at its last
+	// item; anything later is returned by the next poll
+	next := cursor
+	if n := len(messages); n > 0 {
+		next = maxObjectID(next, messages[n-1].ID)
+	}
+	if n := len(events); n > 0 {
+		next = maxObjectID(next, events[n-1].ID)
+	}
+	if n := len(messages); n == messagePollBatch && messages[n-1].ID.Hex() < next.Hex() {
+		next = messages[n-1].ID
+	}
+	if n := len(events); n == messagePollBatch && events[n-1].ID.Hex() < next.Hex() {
+		next = events[n-1].ID
+	}
+	for len(messages) > 0 && messages[len(messages)-1].ID.Hex() > next.Hex() {
+		messages = messages[:len(messages)-1]
+	}
+	for len(events) > 0 && events[len(events)-1].ID.Hex() > next.Hex() {
+		events = events[:len(events)-1]
+	}
+
+	resp := &dto.MessagePollResponse{
+		Messages: messages,
+		Events:   events,
+	}
+	if resp.Messages == nil {
+		resp.Messages = []models.ChatMessage{}
+	}
+	if resp.Events == nil {
+		resp.Events = []models.Event{}
+	}
+	if !next.IsZero() {
+		resp.Cursor = next.Hex()
+	}
+	return resp, nil
+}
+
+func maxObjectID(a, b primitive.ObjectID) primitive.ObjectID {
+	if b.Hex() > a.Hex() {
+		return b
+	}
+	return a
+}
+
+// merge forwards signals from a and b onto one channel until ctx is done.
+func merge(ctx context.Context, a, b <-chan struct{}) <-chan struct{} {
+	out := make(chan struct{}, 1)
+	forward := func(in <-chan struct{}) {
+		for {
+			select {
+			case <-in:
+				select {
+				case out <- struct{}{}:
+				default:
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+	go forward(a)
+	go forward(b)
+	return out
+}
@@ -22,9 +22,6 @@ type MetricsService struct {
 	appInfo              *prometheus.GaugeVec
 	chatMessagesTotal    prometheus.Counter
 	chatSessionsTotal    prometheus.Counter
-	aiResponseTime       prometheus.Histogram
-	taskQueueSize        *prometheus.GaugeVec
-	taskProcessingTime   *prometheus.HistogramVec
 }
 
 // NewMetricsService creates a new metrics service
@@ -71,28 +68,6 @@ func NewMetricsService(logger *zap.Logger) *MetricsService {
 				Help: "Total number of chat sessions created",
 			},
 		),
-		aiResponseTime: promauto.NewHistogram(
-			prometheus.HistogramOpts{
-				Name:    "ai_response_time_seconds",
-				Help:    "AI response time in seconds",
-				Buckets: []float64{0.1, 0.5, 1.0, 2.0, 5.0, 10.0},
-			},
-		),
-		taskQueueSize: promauto.NewGaugeVec(
-			prometheus.GaugeOpts{
-				Name: "task_queue_size",
-				Help: "Number of tasks in queue",
-			},
-			[]string{"queue"},
-		),
-		taskProcessingTime: promauto.NewHistogramVec(
-			prometheus.HistogramOpts{
-				Name:    "task_processing_time_seconds",
-				Help:    "Task processing time in seconds",
-				Buckets: prometheus.DefBuckets,
-			},
-			[]string{"task_type"},
-		),
 	}
 }
 
@@ -125,19 +100,4 @@ func (ms *MetricsService) IncrementChatMessages() {
 // IncrementChatSessions increments the chat sessions counter
 func (ms *MetricsService) IncrementChatSessions() {
 	ms.chatSessionsTotal.Inc()
-}
-
-// ObserveAIResponseTime observes AI response time
-func (ms *MetricsService) ObserveAIResponseTime(duration time.Duration) {
-	ms.aiResponseTime.Observe(duration.Seconds())
-}
-
-// SetTaskQueueSize sets the task queue size
-func (ms *MetricsService) SetTaskQueueSize(queue string, size float64) {
-	ms.taskQueueSize.WithLabelValues(queue).Set(size)
-}
-
-// ObserveTaskProcessingTime observes task processing time
-func (ms *MetricsService) ObserveTaskProcessingTime(taskType string, duration time.Duration) {
-	ms.taskProcessingTime.WithLabelValues(taskType).Observe(duration.Seconds())
 }
\ No newline at end of file
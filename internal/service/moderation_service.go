@@ -0,0 +1,238 @@
+// Package service provides business logic for moderating AI responses before they are sent.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultModerationTimeoutMs = 3000
+	maxModerationTimeoutMs     = 30000
+)
+
+// ModerationVerdict describes why a response was blocked.
+type ModerationVerdict struct {
+	Rule   string `json:"rule"`            // "phrase", "pattern" or "api"
+	Match  string `json:"match,omitempty"` // The phrase or pattern that matched
+	Reason string `json:"reason,omitempty"`
+}
+
+// moderationRequest is the body POSTed to a client's moderation API.
+type moderationRequest struct {
+	ClientID  string `json:"client_id"`
+	SessionID string `json:"session_id"`
+	MessageID string `json:"message_id"`
+	Text      string `json:"text"`
+}
+
+// moderationReply is the body a moderation API answers with.
+type moderationReply struct {
+	Flagged bool   `json:"flagged"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// ModerationService manages per-client moderation policies and applies them to AI responses.
+type ModerationService struct {
+	ClientRepo      *repository.ClientRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	Invalidator     CacheInvalidator
+	// Set on workers, which hand blocked sessions over to a human
+	HandoverService *HandoverService
+	Clients         ClientCache
+	logger          *zap.Logger
+	httpClient      *http.Client
+}
+
+// NewModerationService creates a new ModerationService.
+func NewModerationService(clientRepo *repository.ClientRepository, chatSessionRepo *repository.ChatSessionRepository, logger *zap.Logger) *ModerationService {
+	return &ModerationService{
+		ClientRepo:      clientRepo,
+		ChatSessionRepo: chatSessionRepo,
+		logger:          logger,
+		// Per-call deadlines come from each policy's timeout
+		httpClient: newEgressClient(nil, 0),
+	}
+}
+
+// SetEgressPolicy limits the hosts moderation APIs can be reached on to those policy allows.
+func (s *ModerationService) SetEgressPolicy(policy *egress.Policy) {
+	s.httpClient = newEgressClient(policy, 0)
+}
+
+// GetPolicy returns a client's moderation policy, or nil if none is configured.
+func (s *ModerationService) GetPolicy(ctx context.Context, clientID string) (*models.ModerationPolicy, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return client.Moderation, nil
+}
+
+// SetPolicy validates and stores a client's moderation policy, replacing any existing one.
+func (s *ModerationService) SetPolicy(ctx context.Context, clientID string, policy *models.ModerationPolicy) error {
+	if policy.HandoverQueue == "" {
+		return errors.New("handover_queue is required")
+	}
+	for i, pattern := range policy.BlockedPatterns {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("blocked_patterns[%d]: %v", i, err)
+		}
+	}
+	if policy.APIURL != "" {
+		if u, err := url.Parse(policy.APIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid api_url: %s", policy.APIURL)
+		}
+		if policy.APITimeoutMs <= 0 {
+			policy.APITimeoutMs = defaultModerationTimeoutMs
+		}
+		if policy.APITimeoutMs > maxModerationTimeoutMs {
+			return fmt.Errorf("api_timeout_ms must not exceed %d", maxModerationTimeoutMs)
+		}
+	}
+
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"moderation": policy}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// DeletePolicy removes a client's moderation policy.
+func (s *ModerationService) DeletePolicy(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"moderation": nil}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+func (s *ModerationService) invalidate(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
+
+// Moderate screens an AI response against the moderation policy of the session's client.
+// It returns nil when the response may be sent. A non-nil verdict means the response must be
+// suppressed; the session has then been handed over, and the error reports a failed handover.
+// A failing moderation API is logged and treated as allowing the response.
+func (s *ModerationService) Moderate(ctx context.Context, sessionID primitive.ObjectID, messageID, text string) (*ModerationVerdict, error) {
+	session, err := s.ChatSessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.Client == nil {
+		return nil, nil
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client)
+	if err != nil || client.Moderation == nil || !client.Moderation.Enabled {
+		return nil, nil
+	}
+	policy := client.Moderation
+
+	verdict := matchBlocked(policy, text)
+	if verdict == nil && policy.APIURL != "" {
+		verdict, err = s.callAPI(ctx, policy, moderationRequest{
+			ClientID:  client.ClientID,
+			SessionID: session.SessionID,
+			MessageID: messageID,
+			Text:      text,
+		})
+		if err != nil {
+			s.logger.Warn("Moderation API failed, allowing response",
+				zap.String("client_id", client.ClientID),
+				zap.String("message_id", messageID),
+				zap.Error(err))
+			return nil, nil
+		}
+	}
+	if verdict == nil {
+		return nil, nil
+	}
+
+	if s.HandoverService == nil {
+		return verdict, errors.New("handover service not configured")
+	}
+	_, err = s.HandoverService.RequestHandover(ctx, session.ID.Hex(), HandoverRequest{
+		Reason:      "moderation:" + verdict.Rule,
+		TargetQueue: policy.HandoverQueue,
+		RequestedBy: "moderation",
+	})
+	if err != nil && !errors.Is(err, ErrHandoverInvalidTransition) {
+		return verdict, fmt.Errorf("failed to request handover: %w", err)
+	}
+	return verdict, nil
+}
+
+// matchBlocked checks text against the policy's blocked phrases and patterns.
+func matchBlocked(policy *models.ModerationPolicy, text string) *ModerationVerdict {
+	lower := strings.ToLower(text)
+	for _, phrase := range policy.BlockedPhrases {
+		if phrase != "" && strings.Contains(lower, strings.ToLower(phrase)) {
+			return &ModerationVerdict{Rule: "phrase", Match: phrase}
+		}
+	}
+	for _, pattern := range policy.BlockedPatterns {
+		// Patterns are validated on write, so a compile error here means stale data; skip it
+		re, err := regexp.Compile(pattern)
+		if err == nil && re.MatchString(text) {
+			return &ModerationVerdict{Rule: "pattern", Match: pattern}
+		}
+	}
+	return nil
+}
+
+// callAPI asks the policy's moderation API whether req should be blocked.
+func (s *ModerationService) callAPI(ctx context.Context, policy *models.ModerationPolicy, req moderationRequest) (*ModerationVerdict, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal moderation request: %w", err)
+	}
+
+	timeout := time.Duration(policy.APITimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultModerationTimeoutMs * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, policy.APIURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create moderation request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "Fraiday-Events/1.0")
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("moderation request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("moderation API returned status %d", resp.StatusCode)
+	}
+	var reply moderationReply
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to decode moderation reply: %w", err)
+	}
+	if !reply.Flagged {
+		return nil, nil
+	}
+	return &ModerationVerdict{Rule: "api", Reason: reply.Reason}, nil
+}
@@ -2,11 +2,13 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/fraiday-org/api-service/internal/models"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // PayloadService handles creation of structured event payloads
@@ -14,6 +16,8 @@ type PayloadService struct {
 	ChatMessageService *ChatMessageService
 	ChatSessionService *ChatSessionService
 	ThreadManagerService *ThreadManagerService
+	// Contacts, when set, adds the consent flags of a message's contact to its payload
+	Contacts *ContactService
 }
 
 // NewPayloadService creates a new PayloadService instance
@@ -35,6 +39,7 @@ type ChatMessagePayload struct {
 	SessionID    string                 `json:"session_id"`
 	Text         string                 `json:"text"`
 	Attachments  []models.Attachment    `json:"attachments,omitempty"`
+	Citations    []models.Citation      `json:"citations,omitempty"`
 	Data         map[string]interface{} `json:"data,omitempty"`
 	Category     string                 `json:"category"`
 	Config       map[string]interface{} `json:"config,omitempty"`
@@ -104,6 +109,7 @@ func (ps *PayloadService) CreateChatMessagePayload(ctx context.Context, messageI
 		SessionID:   ps.normalizeSessionID(session.SessionID),
 		Text:        message.Text,
 		Attachments: message.Attachments,
+		Citations:   message.Citations,
 		Data:        message.Data,
 		Category:    string(message.Category),
 		Config:      message.Config,
@@ -122,6 +128,7 @@ func (ps *PayloadService) CreateChatMessagePayload(ctx context.Context, messageI
 		"category":     payload.Category,
 		"created_at":   payload.CreatedAt.Format(time.RFC3339),
 		"updated_at":   payload.UpdatedAt.Format(time.RFC3339),
+		"reply_count":  message.ReplyCount,
 	}
 
 	// Add optional fields if they exist
@@ -134,6 +141,9 @@ func (ps *PayloadService) CreateChatMessagePayload(ctx context.Context, messageI
 	if len(payload.Attachments) > 0 {
 		result["attachments"] = payload.Attachments
 	}
+	if len(payload.Citations) > 0 {
+		result["citations"] = payload.Citations
+	}
 	if payload.Data != nil {
 		result["data"] = payload.Data
 	}
@@ -143,6 +153,25 @@ func (ps *PayloadService) CreateChatMessagePayload(ctx context.Context, messageI
 	if payload.Confidence != nil {
 		result["confidence"] = *payload.Confidence
 	}
+	if message.ParentMessageID != nil {
+		result["parent_message_id"] = message.ParentMessageID.Hex()
+	}
+
+	// Replies carry the consent of the session's contact, so downstream systems can honor opt-outs
+	// for everything they receive about the conversation
+	contactID := message.Contact
+	if contactID == nil {
+		contactID = session.Contact
+	}
+	if ps.Contacts != nil && contactID != nil && session.Client != nil {
+		contact, err := ps.Contacts.ConsentPayload(ctx, *session.Client, *contactID)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+		if contact != nil {
+			result["contact"] = contact
+		}
+	}
 
 	return result, nil
 }
@@ -0,0 +1,230 @@
+// Package service provides business logic for client post-processing hooks on AI responses.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPostProcessingTimeoutMs = 3000
+	maxPostProcessingTimeoutMs     = 30000
+	// postProcessingSignatureHeader carries the hex HMAC-SHA256 of the request body when the hook has a secret
+	postProcessingSignatureHeader = "X-Fraiday-Signature"
+)
+
+var ErrResponseVetoed = errors.New("response vetoed by post-processing hook")
+
+// PostProcessingDraft is the part of an AI response a post-processing hook can see and replace.
+type PostProcessingDraft struct {
+	Text        string              `json:"text"`
+	Confidence  float64             `json:"confidence"`
+	Attachments []models.Attachment `json:"attachments,omitempty"`
+}
+
+// postProcessingRequest is the body POSTed to a client's hook.
+type postProcessingRequest struct {
+	ClientID   string              `json:"client_id"`
+	SessionID  string              `json:"session_id"`
+	MessageID  string              `json:"message_id"`
+	Message    string              `json:"message"`
+	Suggestion bool                `json:"suggestion"`
+	Response   PostProcessingDraft `json:"response"`
+}
+
+// postProcessingReply is the body a hook answers with.
+type postProcessingReply struct {
+	Action   models.PostProcessingAction `json:"action"`
+	Response *PostProcessingDraft        `json:"response,omitempty"`
+	Reason   string                      `json:"reason,omitempty"`
+}
+
+// PostProcessingService manages client post-processing hooks and runs them on AI responses.
+type PostProcessingService struct {
+	ClientRepo      *repository.ClientRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	Invalidator     CacheInvalidator
+	Clients         ClientCache // Optional; set on workers, which look the client up for every response
+	logger          *zap.Logger
+	httpClient      *http.Client
+}
+
+// NewPostProcessingService creates a new PostProcessingService.
+func NewPostProcessingService(clientRepo *repository.ClientRepository, chatSessionRepo *repository.ChatSessionRepository, logger *zap.Logger) *PostProcessingService {
+	return &PostProcessingService{
+		ClientRepo:      clientRepo,
+		ChatSessionRepo: chatSessionRepo,
+		logger:          logger,
+		// Per-call deadlines come from each hook's timeout
+		httpClient: newEgressClient(nil, 0),
+	}
+}
+
+// SetEgressPolicy limits the hosts hooks can reach to those policy allows.
+func (s *PostProcessingService) SetEgressPolicy(policy *egress.Policy) {
+	s.httpClient = newEgressClient(policy, 0)
+}
+
+// GetHook returns a client's post-processing hook, or nil if none is registered.
+func (s *PostProcessingService) GetHook(ctx context.Context, clientID string) (*models.PostProcessingHook, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return client.PostProcessingHook, nil
+}
+
+// SetHook validates and stores a client's post-processing hook, replacing any existing one.
+func (s *PostProcessingService) SetHook(ctx context.Context, clientID string, hook *models.PostProcessingHook) error {
+	if u, err := url.Parse(hook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid hook url: %s", hook.URL)
+	}
+	if hook.TimeoutMs <= 0 {
+		hook.TimeoutMs = defaultPostProcessingTimeoutMs
+	}
+	if hook.TimeoutMs > maxPostProcessingTimeoutMs {
+		return fmt.Errorf("timeout_ms must not exceed %d", maxPostProcessingTimeoutMs)
+	}
+	switch hook.FailurePolicy {
+	case "":
+		hook.FailurePolicy = models.PostProcessingFailOpen
+	case models.PostProcessingFailOpen, models.PostProcessingFailClosed:
+	default:
+		return fmt.Errorf("invalid failure_policy: %s", hook.FailurePolicy)
+	}
+
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"post_processing_hook": hook}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// DeleteHook removes a client's post-processing hook.
+func (s *PostProcessingService) DeleteHook(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"post_processing_hook": nil}); err != nil {
+		return errors.New("client not found")
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+func (s *PostProcessingService) invalidate(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
+
+// Process runs the post-processing hook of the session's client on draft and returns the response to save.
+// It returns ErrResponseVetoed when the hook vetoes the response, or when the hook fails under a
+// fail-closed policy. Sessions whose client has no enabled hook get draft back unchanged.
+func (s *PostProcessingService) Process(
+	ctx context.Context,
+	sessionID primitive.ObjectID,
+	messageID, messageText string,
+	suggestion bool,
+	draft *PostProcessingDraft,
+) (*PostProcessingDraft, error) {
+	session, err := s.ChatSessionRepo.GetByID(ctx, sessionID)
+	if err != nil || session.Client == nil {
+		return draft, nil
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client)
+	if err != nil || client.PostProcessingHook == nil || !client.PostProcessingHook.Enabled {
+		return draft, nil
+	}
+	hook := client.PostProcessingHook
+
+	reply, err := s.call(ctx, hook, postProcessingRequest{
+		ClientID:   client.ClientID,
+		SessionID:  session.SessionID,
+		MessageID:  messageID,
+		Message:    messageText,
+		Suggestion: suggestion,
+		Response:   *draft,
+	})
+	if err == nil {
+		switch reply.Action {
+		case "", models.PostProcessingActionAllow:
+			return draft, nil
+		case models.PostProcessingActionModify:
+			if reply.Response != nil {
+				return reply.Response, nil
+			}
+			err = errors.New("modify reply without response")
+		case models.PostProcessingActionVeto:
+			return nil, fmt.Errorf("%w: %s", ErrResponseVetoed, reply.Reason)
+		default:
+			err = fmt.Errorf("unknown action %q", reply.Action)
+		}
+	}
+
+	if hook.FailurePolicy == models.PostProcessingFailClosed {
+		return nil, fmt.Errorf("%w: hook failed: %v", ErrResponseVetoed, err)
+	}
+	s.logger.Warn("Post-processing hook failed, keeping original response",
+		zap.String("client_id", client.ClientID),
+		zap.String("message_id", messageID),
+		zap.Error(err))
+	return draft, nil
+}
+
+// call POSTs req to the hook and decodes its reply within the hook's timeout.
+func (s *PostProcessingService) call(ctx context.Context, hook *models.PostProcessingHook, req postProcessingRequest) (*postProcessingReply, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal hook request: %w", err)
+	}
+
+	timeout := time.Duration(hook.TimeoutMs) * time.Millisecond
+	if timeout <= 0 {
+		timeout = defaultPostProcessingTimeoutMs * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create hook request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("User-Agent", "Fraiday-Events/1.0")
+	if hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(hook.Secret))
+		mac.Write(body)
+		httpReq.Header.Set(postProcessingSignatureHeader, hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("hook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("hook returned status %d", resp.StatusCode)
+	}
+	var reply postProcessingReply
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&reply); err != nil {
+		return nil, fmt.Errorf("failed to decode hook reply: %w", err)
+	}
+	return &reply, nil
+}
@@ -0,0 +1,279 @@
+// Package service provides business logic for versioned prompt templates.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxPromptTemplateText bounds the text of a prompt template version
+const maxPromptTemplateText = 20000
+
+var (
+	ErrPromptTemplateNotFound = errors.New("prompt template not found")
+	ErrPromptTemplateExists   = errors.New("prompt template already exists")
+	ErrPromptVersionNotFound  = errors.New("prompt template version not found")
+	ErrPromptTemplateConflict = errors.New("prompt template was changed concurrently, try again")
+	ErrNothingToRollback      = errors.New("no earlier published version to roll back to")
+)
+
+// RenderedPrompt is a published prompt template version filled in for one message.
+type RenderedPrompt struct {
+	Ref  *models.PromptVersionRef
+	Text string
+}
+
+// PromptTemplateService manages prompt templates and renders the published version of one for
+// the chat workflow.
+type PromptTemplateService struct {
+	Repo              *repository.PromptTemplateRepository
+	ClientRepo        *repository.ClientRepository
+	ClientChannelRepo *repository.ClientChannelRepository
+	ChatSessionRepo   *repository.ChatSessionRepository
+	Clients           ClientCache
+}
+
+// NewPromptTemplateService creates a new PromptTemplateService.
+func NewPromptTemplateService(repo *repository.PromptTemplateRepository, clientRepo *repository.ClientRepository, clientChannelRepo *repository.ClientChannelRepository, chatSessionRepo *repository.ChatSessionRepository) *PromptTemplateService {
+	return &PromptTemplateService{Repo: repo, ClientRepo: clientRepo, ClientChannelRepo: clientChannelRepo, ChatSessionRepo: chatSessionRepo}
+}
+
+// CreateTemplate stores a new template for an existing client with first as its version 1. The
+// template is not used until a version is published.
+func (s *PromptTemplateService) CreateTemplate(ctx context.Context, template *models.PromptTemplate, first models.PromptTemplateVersion) error {
+	if _, err := s.ClientRepo.GetByClientID(ctx, template.ClientID); err != nil {
+		return errors.New("client not found")
+	}
+	template.Name = strings.TrimSpace(template.Name)
+	if template.Name == "" {
+		return errors.New("name is required")
+	}
+	if err := validateChannelType(template.ChannelType); err != nil {
+		return err
+	}
+	if err := preparePromptVersion(&first, 1); err != nil {
+		return err
+	}
+
+	template.Versions = []models.PromptTemplateVersion{first}
+	template.ActiveVersion = 0
+	template.Publications = nil
+	if err := s.Repo.Create(ctx, template); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return ErrPromptTemplateExists
+		}
+		return err
+	}
+	return nil
+}
+
+// GetTemplate returns one of a client's prompt templates.
+func (s *PromptTemplateService) GetTemplate(ctx context.Context, clientID, id string) (*models.PromptTemplate, error) {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrPromptTemplateNotFound
+	}
+	template, err := s.Repo.GetByID(ctx, clientID, *objID)
+	if err != nil {
+		return nil, ErrPromptTemplateNotFound
+	}
+	return template, nil
+}
+
+// ListTemplates returns a client's prompt templates, optionally only those called name.
+func (s *PromptTemplateService) ListTemplates(ctx context.Context, clientID, name string) ([]models.PromptTemplate, error) {
+	filter := bson.M{"client_id": clientID}
+	if name != "" {
+		filter["name"] = name
+	}
+	return s.Repo.List(ctx, filter)
+}
+
+// DeleteTemplate removes a client's prompt template with all its versions.
+func (s *PromptTemplateService) DeleteTemplate(ctx context.Context, clientID, id string) error {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return ErrPromptTemplateNotFound
+	}
+	if err := s.Repo.Delete(ctx, clientID, *objID); err != nil {
+		return ErrPromptTemplateNotFound
+	}
+	return nil
+}
+
+// AddVersion adds version as the next version of a template, unpublished.
+func (s *PromptTemplateService) AddVersion(ctx context.Context, clientID, id string, version models.PromptTemplateVersion) (*models.PromptTemplate, error) {
+	template, err := s.GetTemplate(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := preparePromptVersion(&version, len(template.Versions)+1); err != nil {
+		return nil, err
+	}
+	added, err := s.Repo.AddVersion(ctx, clientID, template.ID, len(template.Versions), version)
+	if err != nil {
+		return nil, err
+	}
+	if !added {
+		return nil, ErrPromptTemplateConflict
+	}
+	template.Versions = append(template.Versions, version)
+	return template, nil
+}
+
+// Publish makes a version of a template the one the chat workflow renders.
+func (s *PromptTemplateService) Publish(ctx context.Context, clientID, id string, version int, publishedBy string) (*models.PromptTemplate, error) {
+	template, err := s.GetTemplate(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if template.Version(version) == nil {
+		return nil, ErrPromptVersionNotFound
+	}
+	if template.ActiveVersion == version {
+		return nil, fmt.Errorf("version %d is already published", version)
+	}
+	return s.publish(ctx, template, models.PromptPublication{Version: version, PublishedBy: publishedBy})
+}
+
+// Rollback publishes again the version that was live before the current one. Rolling back
+// repeatedly walks further back through the versions published before.
+func (s *PromptTemplateService) Rollback(ctx context.Context, clientID, id, publishedBy string) (*models.PromptTemplate, error) {
+	template, err := s.GetTemplate(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	live := liveVersions(template.Publications)
+	if len(live) < 2 {
+		return nil, ErrNothingToRollback
+	}
+	return s.publish(ctx, template, models.PromptPublication{Version: live[len(live)-2], Rollback: true, PublishedBy: publishedBy})
+}
+
+func (s *PromptTemplateService) publish(ctx context.Context, template *models.PromptTemplate, publication models.PromptPublication) (*models.PromptTemplate, error) {
+	publication.PublishedAt = time.Now().UTC()
+	changed, err := s.Repo.Publish(ctx, template.ClientID, template.ID, template.ActiveVersion, publication)
+	if err != nil {
+		return nil, err
+	}
+	if !changed {
+		return nil, ErrPromptTemplateConflict
+	}
+	template.ActiveVersion = publication.Version
+	template.Publications = append(template.Publications, publication)
+	return template, nil
+}
+
+// liveVersions replays publications into the stack of versions that went live, each rollback
+// taking back the version on top.
+func liveVersions(publications []models.PromptPublication) []int {
+	var live []int
+	for _, p := range publications {
+		if p.Rollback && len(live) > 0 {
+			live = live[:len(live)-1]
+			continue
+		}
+		live = append(live, p.Version)
+	}
+	return live
+}
+
+// Render fills in the published version of the template called name for the client and channel
+// of message's session: the channel type's variant when there is one, otherwise the client-wide
+// template. pin, when it names one of the client's templates called name, replaces the published
+// version for an experiment variant. It returns nil when the client has no published template by
+// that name.
+func (s *PromptTemplateService) Render(ctx context.Context, name string, message *models.ChatMessage, pin *models.ExperimentPrompt) (*RenderedPrompt, error) {
+	session, err := s.ChatSessionRepo.GetByID(ctx, message.SessionID)
+	if err != nil || session.Client == nil {
+		return nil, nil
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, *session.Client)
+	if err != nil {
+		return nil, nil
+	}
+	var channel *models.ClientChannel
+	if session.ClientChannel != nil {
+		channel, _ = channelByID(ctx, s.Clients, s.ClientChannelRepo, *session.ClientChannel)
+	}
+
+	var template *models.PromptTemplate
+	active := 0
+	if pin != nil {
+		if objID := ParseObjectID(pin.TemplateID); objID != nil {
+			if pinned, err := s.Repo.GetByID(ctx, client.ClientID, *objID); err == nil && pinned.Name == name {
+				template, active = pinned, pin.Version
+			}
+		}
+	}
+	if template == nil && channel != nil {
+		if template, err = s.Repo.FindPublished(ctx, client.ClientID, name, string(channel.ChannelType)); err != nil {
+			return nil, err
+		}
+	}
+	if template == nil {
+		if template, err = s.Repo.FindPublished(ctx, client.ClientID, name, ""); err != nil || template == nil {
+			return nil, err
+		}
+	}
+	if active == 0 {
+		active = template.ActiveVersion
+	}
+	version := template.Version(active)
+	if version == nil {
+		return nil, ErrPromptVersionNotFound
+	}
+
+	// Session attributes can't shadow the values every conversation has
+	values := make(map[string]string, len(version.Defaults)+len(session.Attributes)+4)
+	for k, v := range version.Defaults {
+		values[k] = v
+	}
+	for k, v := range session.Attributes {
+		values[k] = v
+	}
+	values["client_name"] = client.Name
+	values["session_id"] = session.SessionID
+	values["sender_name"] = message.SenderName
+	if channel != nil {
+		values["channel_type"] = string(channel.ChannelType)
+	}
+
+	text, err := RenderPlaceholders(version.Text, values)
+	if err != nil {
+		return nil, fmt.Errorf("prompt template %s version %d: %w", template.Name, version.Version, err)
+	}
+	return &RenderedPrompt{Ref: template.Ref(version.Version), Text: text}, nil
+}
+
+// preparePromptVersion validates a new version and fills in its number and variables.
+func preparePromptVersion(version *models.PromptTemplateVersion, number int) error {
+	if strings.TrimSpace(version.Text) == "" {
+		return errors.New("text is required")
+	}
+	if len(version.Text) > maxPromptTemplateText {
+		return fmt.Errorf("text must not exceed %d characters", maxPromptTemplateText)
+	}
+	version.Version = number
+	version.Variables = Placeholders(version.Text)
+	version.CreatedAt = time.Now().UTC()
+	return nil
+}
+
+// validateChannelType accepts an empty channel type, for client-wide templates, or a known one.
+func validateChannelType(channelType string) error {
+	switch models.ChannelType(channelType) {
+	case "", models.ChannelTypeWebhook, models.ChannelTypeSlack, models.ChannelTypeSunshine, models.ChannelTypeWhatsApp,
+		models.ChannelTypeTeams, models.ChannelTypeEmail, models.ChannelTypeSMS, models.ChannelTypeGenericWebhook:
+		return nil
+	}
+	return fmt.Errorf("unknown channel_type: %s", channelType)
+}
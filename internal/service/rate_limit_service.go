@@ -0,0 +1,100 @@
+// Package service provides business logic for request rate limiting.
+package service
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+// DefaultRateLimitTier applies to API keys without a tier, or with one that isn't configured,
+// and to dashboard users.
+const DefaultRateLimitTier = "default"
+
+// RateLimit allows Requests per Window. Tokens refill continuously, so a caller who has been idle
+// can burst up to Requests at once. A limit with no Requests is disabled.
+type RateLimit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitResult describes a caller's bucket after a request.
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long a rejected caller has to wait for the next token
+	RetryAfter time.Duration
+	// ResetAt is when the bucket is full again
+	ResetAt time.Time
+}
+
+// RateLimitService applies token bucket limits to API keys, users and IPs.
+type RateLimitService struct {
+	Repo    *repository.RateLimitRepository
+	Tiers   map[string]RateLimit
+	IPLimit RateLimit
+}
+
+// NewRateLimitService creates a new RateLimitService.
+func NewRateLimitService(repo *repository.RateLimitRepository, tiers map[string]RateLimit, ipLimit RateLimit) *RateLimitService {
+	return &RateLimitService{Repo: repo, Tiers: tiers, IPLimit: ipLimit}
+}
+
+// ParseRateLimitTiers reads tiers written as "name=requests_per_minute,...", as in
+// "default=120,premium=1200". Malformed entries are ignored.
+func ParseRateLimitTiers(s string) map[string]RateLimit {
+	tiers := map[string]RateLimit{}
+	for _, entry := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(entry, "=")
+		requests, err := strconv.Atoi(strings.TrimSpace(value))
+		if name = strings.TrimSpace(name); ok && name != "" && err == nil && requests >= 0 {
+			tiers[name] = RateLimit{Requests: requests, Window: time.Minute}
+		}
+	}
+	return tiers
+}
+
+// HasTier reports whether tier is configured.
+func (s *RateLimitService) HasTier(tier string) bool {
+	_, ok := s.Tiers[tier]
+	return ok
+}
+
+// Tier returns the limit of tier, or of the default tier when tier isn't configured.
+func (s *RateLimitService) Tier(tier string) RateLimit {
+	if limit, ok := s.Tiers[tier]; ok {
+		return limit
+	}
+	return s.Tiers[DefaultRateLimitTier]
+}
+
+// Take spends one request of the bucket named key under limit.
+func (s *RateLimitService) Take(ctx context.Context, key string, limit RateLimit) (*RateLimitResult, error) {
+	now := time.Now().UTC()
+	capacity := float64(limit.Requests)
+	refillPerSecond := capacity / limit.Window.Seconds()
+
+	bucket, err := s.Repo.Take(ctx, key, capacity, refillPerSecond, now)
+	if err != nil {
+		return nil, err
+	}
+	result := &RateLimitResult{
+		Allowed:   bucket.Allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(bucket.Tokens)),
+		ResetAt:   now.Add(secondsToDuration((capacity - bucket.Tokens) / refillPerSecond)),
+	}
+	if !bucket.Allowed {
+		result.RetryAfter = secondsToDuration((1 - bucket.Tokens) / refillPerSecond)
+	}
+	return result, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
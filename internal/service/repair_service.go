@@ -0,0 +1,297 @@
+// Package service provides business logic for operator repair actions.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	defaultRepairLimit = 200
+	maxRepairLimit     = 1000
+	// repairThreadPage is how many active threads close_orphan_threads checks at a time
+	repairThreadPage = 500
+)
+
+// RepairTaskClient enqueues the tasks used by repair actions.
+type RepairTaskClient interface {
+	EnqueueRepairAction(ctx context.Context, actionID string) error
+	EnqueueDeliverToProcessor(ctx context.Context, processorID string, eventData map[string]interface{}, deliveryID string) error
+}
+
+// RepairService runs guarded operator repairs as tracked, audited tasks.
+type RepairService struct {
+	RepairActionRepo *repository.RepairActionRepository
+	DeliveryRepo     *repository.EventDeliveryRepository
+	ThreadRepo       *repository.ChatSessionThreadRepository
+	ChatSessionRepo  *repository.ChatSessionRepository
+	TaskClient       RepairTaskClient
+}
+
+// NewRepairService creates a new RepairService.
+func NewRepairService(
+	repairActionRepo *repository.RepairActionRepository,
+	deliveryRepo *repository.EventDeliveryRepository,
+	threadRepo *repository.ChatSessionThreadRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	taskClient RepairTaskClient,
+) *RepairService {
+	return &RepairService{
+		RepairActionRepo: repairActionRepo,
+		DeliveryRepo:     deliveryRepo,
+		ThreadRepo:       threadRepo,
+		ChatSessionRepo:  chatSessionRepo,
+		TaskClient:       taskClient,
+	}
+}
+
+// RequestAction validates and records a repair action, then enqueues it for the worker.
+func (s *RepairService) RequestAction(
+	ctx context.Context,
+	actionType models.RepairActionType,
+	params models.RepairActionParams,
+	dryRun bool,
+	requestedBy string,
+) (*models.RepairAction, error) {
+	if err := validateRepairParams(actionType, &params); err != nil {
+		return nil, err
+	}
+	if s.TaskClient == nil {
+		return nil, fmt.Errorf("task queue unavailable, cannot schedule repair action")
+	}
+
+	action := &models.RepairAction{
+		Action:      actionType,
+		Params:      params,
+		DryRun:      dryRun,
+		Status:      models.ExecutionStatusPending,
+		RequestedBy: requestedBy,
+	}
+	if err := s.RepairActionRepo.Create(ctx, action); err != nil {
+		return nil, fmt.Errorf("failed to record repair action: %w", err)
+	}
+
+	if err := s.TaskClient.EnqueueRepairAction(ctx, action.ID.Hex()); err != nil {
+		s.finish(ctx, action, fmt.Errorf("failed to enqueue repair action: %w", err))
+		return nil, fmt.Errorf("failed to enqueue repair action: %w", err)
+	}
+
+	return action, nil
+}
+
+// GetAction retrieves a repair action by ID.
+func (s *RepairService) GetAction(ctx context.Context, actionID string) (*models.RepairAction, error) {
+	id, err := primitive.ObjectIDFromHex(actionID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid action ID: %w", err)
+	}
+	return s.RepairActionRepo.GetByID(ctx, id)
+}
+
+// ListActions lists repair actions, optionally filtered by action type.
+func (s *RepairService) ListActions(ctx context.Context, actionType string, limit, offset int) ([]models.RepairAction, error) {
+	filter := map[string]interface{}{}
+	if actionType != "" {
+		filter["action"] = actionType
+	}
+	return s.RepairActionRepo.List(ctx, filter, limit, offset)
+}
+
+// RunAction executes a previously requested repair action. Called by the worker.
+func (s *RepairService) RunAction(ctx context.Context, actionID string) error {
+	action, err := s.GetAction(ctx, actionID)
+	if err != nil {
+		return err
+	}
+	if action.Status != models.ExecutionStatusPending {
+		return fmt.Errorf("repair action %s already %s", actionID, action.Status)
+	}
+
+	startedAt := time.Now().UTC()
+	action.StartedAt = &startedAt
+	if err := s.RepairActionRepo.Update(ctx, action.ID, bson.M{
+		"status":     models.ExecutionStatusRunning,
+		"started_at": startedAt,
+	}); err != nil {
+		return err
+	}
+
+	switch action.Action {
+	case models.RepairActionRequeueFailedDeliveries:
+		err = s.requeueFailedDeliveries(ctx, action)
+	case models.RepairActionResetStuckDeliveries:
+		err = s.resetStuckDeliveries(ctx, action)
+	case models.RepairActionCloseOrphanThreads:
+		err = s.closeOrphanThreads(ctx, action)
+	default:
+		err = fmt.Errorf("unknown repair action: %s", action.Action)
+	}
+
+	s.finish(ctx, action, err)
+	return err
+}
+
+// finish persists the outcome of a repair action.
+func (s *RepairService) finish(ctx context.Context, action *models.RepairAction, runErr error) {
+	completedAt := time.Now().UTC()
+	update := bson.M{
+		"status":       models.ExecutionStatusCompleted,
+		"matched":      action.Matched,
+		"affected":     action.Affected,
+		"affected_ids": action.AffectedIDs,
+		"completed_at": completedAt,
+	}
+	if runErr != nil {
+		update["status"] = models.ExecutionStatusFailed
+		update["error"] = runErr.Error()
+	}
+	_ = s.RepairActionRepo.Update(ctx, action.ID, update)
+}
+
+// requeueFailedDeliveries resets failed deliveries of one processor in a time window and re-dispatches them.
+func (s *RepairService) requeueFailedDeliveries(ctx context.Context, action *models.RepairAction) error {
+	params := action.Params
+	filter := map[string]interface{}{
+		"status":                 models.DeliveryStatusFailed,
+		"event_processor_config": *params.ProcessorID,
+		"updated_at":             bson.M{"$gte": *params.From, "$lte": *params.To},
+	}
+
+	deliveries, err := s.DeliveryRepo.List(ctx, filter, params.Limit, 0)
+	if err != nil {
+		return err
+	}
+	return s.redispatch(ctx, action, deliveries)
+}
+
+// resetStuckDeliveries moves deliveries stuck in in_progress back to pending and re-dispatches them.
+func (s *RepairService) resetStuckDeliveries(ctx context.Context, action *models.RepairAction) error {
+	params := action.Params
+	cutoff := time.Now().Add(-time.Duration(params.OlderThanMinutes) * time.Minute)
+	filter := map[string]interface{}{
+		"status":     models.DeliveryStatusInProgress,
+		"updated_at": bson.M{"$lt": cutoff},
+	}
+	if params.ProcessorID != nil {
+		filter["event_processor_config"] = *params.ProcessorID
+	}
+
+	deliveries, err := s.DeliveryRepo.List(ctx, filter, params.Limit, 0)
+	if err != nil {
+		return err
+	}
+	return s.redispatch(ctx, action, deliveries)
+}
+
+// redispatch resets each delivery to pending with a fresh attempt budget and enqueues deliver_to_processor.
+func (s *RepairService) redispatch(ctx context.Context, action *models.RepairAction, deliveries []models.EventDelivery) error {
+	action.Matched = len(deliveries)
+	for _, delivery := range deliveries {
+		if action.DryRun {
+			action.AffectedIDs = append(action.AffectedIDs, delivery.ID.Hex())
+			continue
+		}
+
+		if err := s.DeliveryRepo.Update(ctx, delivery.ID, bson.M{
+			"status":           models.DeliveryStatusPending,
+			"current_attempts": 0,
+		}); err != nil {
+			return fmt.Errorf("failed to reset delivery %s: %w", delivery.ID.Hex(), err)
+		}
+		if err := s.TaskClient.EnqueueDeliverToProcessor(
+			ctx,
+			delivery.EventProcessorConfigID.Hex(),
+			delivery.RequestPayload,
+			delivery.ID.Hex(),
+		); err != nil {
+			return fmt.Errorf("failed to enqueue delivery %s: %w", delivery.ID.Hex(), err)
+		}
+
+		action.Affected++
+		action.AffectedIDs = append(action.AffectedIDs, delivery.ID.Hex())
+	}
+	return nil
+}
+
+// closeOrphanThreads closes up to limit active threads whose parent chat session is missing or no
+// longer active, paging through active threads until that many are found. A session that can't be
+// read fails the action rather than closing its threads.
+func (s *RepairService) closeOrphanThreads(ctx context.Context, action *models.RepairAction) error {
+	var after primitive.ObjectID
+	for {
+		threads, err := s.ThreadRepo.ListActive(ctx, after, repairThreadPage)
+		if err != nil {
+			return fmt.Errorf("failed to list active threads: %w", err)
+		}
+
+		for _, thread := range threads {
+			after = thread.ID
+			session, err := s.ChatSessionRepo.GetByID(ctx, thread.ChatSessionID)
+			if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+				return fmt.Errorf("failed to get session of thread %s: %w", thread.ID.Hex(), err)
+			}
+			if err == nil && session.Active {
+				continue
+			}
+
+			action.Matched++
+			if action.DryRun {
+				action.AffectedIDs = append(action.AffectedIDs, thread.ID.Hex())
+			} else {
+				closed, err := s.ThreadRepo.CloseByID(ctx, thread.ID)
+				if err != nil {
+					return fmt.Errorf("failed to close thread %s: %w", thread.ID.Hex(), err)
+				}
+				if closed {
+					action.Affected++
+					action.AffectedIDs = append(action.AffectedIDs, thread.ID.Hex())
+				}
+			}
+			if action.Matched >= action.Params.Limit {
+				return nil
+			}
+		}
+		if len(threads) < repairThreadPage {
+			return nil
+		}
+	}
+}
+
+// validateRepairParams checks the inputs required by each action and applies the default limit.
+func validateRepairParams(actionType models.RepairActionType, params *models.RepairActionParams) error {
+	if params.Limit <= 0 {
+		params.Limit = defaultRepairLimit
+	}
+	if params.Limit > maxRepairLimit {
+		return fmt.Errorf("limit must not exceed %d", maxRepairLimit)
+	}
+
+	switch actionType {
+	case models.RepairActionRequeueFailedDeliveries:
+		if params.ProcessorID == nil {
+			return fmt.Errorf("processor_id is required")
+		}
+		if params.From == nil || params.To == nil {
+			return fmt.Errorf("from and to are required")
+		}
+		if !params.From.Before(*params.To) {
+			return fmt.Errorf("from must be before to")
+		}
+	case models.RepairActionResetStuckDeliveries:
+		if params.OlderThanMinutes <= 0 {
+			return fmt.Errorf("older_than_minutes must be positive")
+		}
+	case models.RepairActionCloseOrphanThreads:
+	default:
+		return fmt.Errorf("unknown repair action: %s", actionType)
+	}
+	return nil
+}
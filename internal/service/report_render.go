@@ -0,0 +1,153 @@
+// Package service provides business logic for building and rendering analytics reports.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// reportSection is a titled list of label and value rows, rendered as text or HTML.
+type reportSection struct {
+	title string
+	rows  [][2]string
+}
+
+// BuildReport gathers a client's analytics and CSAT results from start to end for a schedule.
+func (s *ReportService) BuildReport(ctx context.Context, schedule *models.ReportSchedule, start, end time.Time) (*dto.AnalyticsReport, error) {
+	f := AnalyticsFilter{ClientID: schedule.ClientID, Start: start, End: end}
+	report := &dto.AnalyticsReport{
+		ReportID:  schedule.ID.Hex(),
+		Name:      schedule.Name,
+		ClientID:  schedule.ClientID,
+		Frequency: schedule.Frequency,
+		// In the schedule's time zone, so the report shows its local period
+		StartTime: start.In(schedule.Location()),
+		EndTime:   end.In(schedule.Location()),
+	}
+	if client, err := s.ClientRepo.GetByClientID(ctx, schedule.ClientID); err == nil {
+		report.ClientName = client.Name
+	}
+
+	var err error
+	if report.Performance, err = s.Analytics.GetPerformanceMetrics(ctx, f); err != nil {
+		return nil, err
+	}
+	containment, err := s.Analytics.GetContainmentRateMetrics(ctx, f, AggregationDaily)
+	if err != nil {
+		return nil, err
+	}
+	report.Containment = containment.Summary
+	if report.CSAT, err = s.Analytics.GetCSATSummary(ctx, f); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func reportSubject(report *dto.AnalyticsReport) string {
+	name := report.ClientName
+	if name == "" {
+		name = report.ClientID
+	}
+	return fmt.Sprintf("%s: %s report for %s", report.Name, report.Frequency, name)
+}
+
+func reportPeriod(report *dto.AnalyticsReport) string {
+	return report.StartTime.Format("Jan 2, 2006 15:04") + " to " + report.EndTime.Format("Jan 2, 2006 15:04 MST")
+}
+
+func reportSections(report *dto.AnalyticsReport) []reportSection {
+	count := func(n int64) string { return fmt.Sprintf("%d", n) }
+	share := func(n int64, rate float64) string { return fmt.Sprintf("%d (%.1f%%)", n, rate*100) }
+	duration := func(d dto.DurationStats) string {
+		if d.Count == 0 {
+			return "none"
+		}
+		return fmt.Sprintf("%s average over %d", time.Duration(d.AverageSeconds*float64(time.Second)).Round(time.Second), d.Count)
+	}
+
+	sections := []reportSection{}
+	if p := report.Performance; p != nil {
+		sections = append(sections,
+			reportSection{"Conversations", [][2]string{
+				{"Sessions", count(p.Resolution.Sessions)},
+				{"Resolved", share(p.Resolution.Resolved, p.Resolution.ResolutionRate)},
+				{"Resolved by the bot", share(p.Resolution.ResolvedByBot, p.Resolution.BotResolutionRate)},
+				{"Handed over", count(p.Resolution.HandedOver)},
+			}},
+			reportSection{"Response times", [][2]string{
+				{"First response", duration(p.FirstResponseTime)},
+				{"Bot response", duration(p.BotResponseLatency)},
+				{"Agent handle time", duration(p.AverageHandleTime)},
+			}},
+		)
+	}
+	if c := report.Containment; c != nil {
+		sections = append(sections, reportSection{"Containment", [][2]string{
+			{"Answered by the bot", count(c.BotHandled)},
+			{"Contained", share(c.Contained, c.ContainmentRate)},
+			{"Deflection rate", fmt.Sprintf("%.1f%%", c.DeflectionRate*100)},
+		}})
+	}
+	if c := report.CSAT; c != nil {
+		average := "no scores"
+		if c.AverageScore != nil {
+			average = fmt.Sprintf("%.2f over %d answers", *c.AverageScore, c.ScoredAnswers)
+		}
+		sections = append(sections, reportSection{"Customer satisfaction", [][2]string{
+			{"Surveys sent", count(c.Surveys)},
+			{"Completed", share(c.Completed, c.CompletionRate)},
+			{"Average score", average},
+		}})
+	}
+	return sections
+}
+
+// renderReportText renders a report as plain text.
+func renderReportText(report *dto.AnalyticsReport) string {
+	var b strings.Builder
+	b.WriteString(reportSubject(report) + "\n" + reportPeriod(report) + "\n")
+	for _, section := range reportSections(report) {
+		b.WriteString("\n" + section.title + "\n")
+		for _, row := range section.rows {
+			b.WriteString("  " + row[0] + ": " + row[1] + "\n")
+		}
+	}
+	return b.String()
+}
+
+// renderReportHTML renders a report as an HTML email with a table per section.
+func renderReportHTML(report *dto.AnalyticsReport) string {
+	var b strings.Builder
+	b.WriteString(`<!DOCTYPE html><html><body style="font-family:Arial,Helvetica,sans-serif;font-size:14px;line-height:1.5;color:#222">`)
+	b.WriteString("<h2>" + html.EscapeString(reportSubject(report)) + "</h2>")
+	b.WriteString(`<p style="color:#666">` + html.EscapeString(reportPeriod(report)) + "</p>")
+	for _, section := range reportSections(report) {
+		b.WriteString("<h3>" + html.EscapeString(section.title) + `</h3><table style="border-collapse:collapse">`)
+		for _, row := range section.rows {
+			b.WriteString(`<tr><td style="padding:4px 16px 4px 0">` + html.EscapeString(row[0]) + `</td><td style="padding:4px 0"><strong>` + html.EscapeString(row[1]) + "</strong></td></tr>")
+		}
+		b.WriteString("</table>")
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+// jsonMap converts v to the generic map its JSON encoding decodes to.
+func jsonMap(v interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
@@ -0,0 +1,319 @@
+// Package service provides business logic for scheduled analytics reports.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/egress"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+// reportDueBatchSize is how many due schedules a scheduler run claims at most
+const reportDueBatchSize = 100
+
+// ErrReportScheduleNotFound is returned when a client has no report schedule with the given ID.
+var ErrReportScheduleNotFound = errors.New("report schedule not found")
+
+// ReportTaskClient enqueues the generation of a report over a period.
+type ReportTaskClient interface {
+	EnqueueReportGeneration(ctx context.Context, scheduleID string, start, end time.Time) error
+}
+
+// ReportService manages report schedules, queues reports as they fall due, and renders and
+// delivers them.
+type ReportService struct {
+	Repo              *repository.ReportScheduleRepository
+	ClientRepo        *repository.ClientRepository
+	ClientChannelRepo *repository.ClientChannelRepository
+	Analytics         *AnalyticsService
+	TaskClient        ReportTaskClient
+	// Email and Webhooks deliver reports; they are set on workers
+	Email    *EmailService
+	Webhooks *WebhookService
+	// Egress, when set, refuses webhook URLs that webhooks can't reach when schedules are saved
+	Egress *egress.Policy
+	logger *zap.Logger
+}
+
+// NewReportService creates a new ReportService.
+func NewReportService(repo *repository.ReportScheduleRepository, clientRepo *repository.ClientRepository, clientChannelRepo *repository.ClientChannelRepository, analytics *AnalyticsService, logger *zap.Logger) *ReportService {
+	return &ReportService{
+		Repo:              repo,
+		ClientRepo:        clientRepo,
+		ClientChannelRepo: clientChannelRepo,
+		Analytics:         analytics,
+		logger:            logger,
+	}
+}
+
+// CreateSchedule validates and stores a new report schedule for an existing client.
+func (s *ReportService) CreateSchedule(ctx context.Context, schedule *models.ReportSchedule) error {
+	if err := s.validate(ctx, schedule); err != nil {
+		return err
+	}
+	schedule.NextRunAt = schedule.NextRun(time.Now())
+	schedule.LastRunAt, schedule.LastError = nil, ""
+	return s.Repo.Create(ctx, schedule)
+}
+
+// GetSchedule returns one of a client's report schedules.
+func (s *ReportService) GetSchedule(ctx context.Context, clientID, id string) (*models.ReportSchedule, error) {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrReportScheduleNotFound
+	}
+	schedule, err := s.Repo.GetByID(ctx, clientID, *objID)
+	if err != nil {
+		return nil, ErrReportScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// ListSchedules returns a client's report schedules.
+func (s *ReportService) ListSchedules(ctx context.Context, clientID string) ([]models.ReportSchedule, error) {
+	return s.Repo.List(ctx, clientID)
+}
+
+// UpdateSchedule applies req to a client's report schedule, validates the result, and moves its
+// next run to match the new timing.
+func (s *ReportService) UpdateSchedule(ctx context.Context, clientID, id string, req *dto.ReportScheduleUpdate) (*models.ReportSchedule, error) {
+	schedule, err := s.GetSchedule(ctx, clientID, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name != nil {
+		schedule.Name = *req.Name
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+	if req.Frequency != nil {
+		schedule.Frequency = *req.Frequency
+	}
+	if req.Weekday != nil {
+		schedule.Weekday = *req.Weekday
+	}
+	if req.Hour != nil {
+		schedule.Hour = *req.Hour
+	}
+	if req.Timezone != nil {
+		schedule.Timezone = *req.Timezone
+	}
+	if req.EmailChannelID != nil {
+		schedule.EmailChannel = nil
+		if *req.EmailChannelID != "" {
+			if schedule.EmailChannel = ParseObjectID(*req.EmailChannelID); schedule.EmailChannel == nil {
+				return nil, errors.New("invalid email_channel_id")
+			}
+		}
+	}
+	if req.Recipients != nil {
+		schedule.Recipients = req.Recipients
+	}
+	if req.WebhookURL != nil {
+		schedule.WebhookURL = *req.WebhookURL
+	}
+
+	if err := s.validate(ctx, schedule); err != nil {
+		return nil, err
+	}
+	schedule.NextRunAt = schedule.NextRun(time.Now())
+	if err := s.Repo.Replace(ctx, schedule); err != nil {
+		return nil, ErrReportScheduleNotFound
+	}
+	return schedule, nil
+}
+
+// DeleteSchedule removes a client's report schedule.
+func (s *ReportService) DeleteSchedule(ctx context.Context, clientID, id string) error {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return ErrReportScheduleNotFound
+	}
+	if err := s.Repo.Delete(ctx, clientID, *objID); err != nil {
+		return ErrReportScheduleNotFound
+	}
+	return nil
+}
+
+// RunNow queues a report over the day or week up to now, without moving the schedule's next run.
+func (s *ReportService) RunNow(ctx context.Context, clientID, id string) (time.Time, time.Time, error) {
+	schedule, err := s.GetSchedule(ctx, clientID, id)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if s.TaskClient == nil {
+		return time.Time{}, time.Time{}, errors.New("reports can't be queued without a task queue")
+	}
+	start, end := schedule.Period(time.Now())
+	if err := s.TaskClient.EnqueueReportGeneration(ctx, schedule.ID.Hex(), start, end); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	return start, end, nil
+}
+
+// QueueDue queues a report for every schedule due at now and advances each to its next run. A
+// schedule is advanced before its report is queued, so concurrent schedulers queue it once; runs
+// missed while no scheduler was running are skipped rather than sent late one after another.
+func (s *ReportService) QueueDue(ctx context.Context, now time.Time) (int, error) {
+	if s.TaskClient == nil {
+		return 0, errors.New("reports can't be queued without a task queue")
+	}
+	due, err := s.Repo.ListDue(ctx, now, reportDueBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	queued := 0
+	for i := range due {
+		schedule := &due[i]
+		claimed, err := s.Repo.Advance(ctx, schedule.ID, schedule.NextRunAt, schedule.NextRun(now))
+		if err != nil {
+			return queued, err
+		}
+		if !claimed {
+			continue
+		}
+		start, end := schedule.Period(schedule.NextRunAt)
+		if err := s.TaskClient.EnqueueReportGeneration(ctx, schedule.ID.Hex(), start, end); err != nil {
+			s.logger.Error("Failed to queue scheduled report",
+				zap.String("report_id", schedule.ID.Hex()),
+				zap.Error(err))
+			_ = s.Repo.RecordRun(ctx, schedule.ID, now, fmt.Errorf("failed to queue report: %w", err))
+			continue
+		}
+		queued++
+	}
+	return queued, nil
+}
+
+// Deliver builds the report of a schedule over start to end and sends it to the schedule's email
+// recipients and webhook. The outcome is recorded on the schedule.
+func (s *ReportService) Deliver(ctx context.Context, scheduleID string, start, end time.Time) error {
+	objID := ParseObjectID(scheduleID)
+	if objID == nil {
+		return ErrReportScheduleNotFound
+	}
+	schedule, err := s.Repo.GetByID(ctx, "", *objID)
+	if err != nil {
+		return ErrReportScheduleNotFound
+	}
+
+	err = s.deliver(ctx, schedule, start, end)
+	if recordErr := s.Repo.RecordRun(ctx, schedule.ID, time.Now().UTC(), err); recordErr != nil {
+		s.logger.Warn("Failed to record report run", zap.String("report_id", scheduleID), zap.Error(recordErr))
+	}
+	return err
+}
+
+func (s *ReportService) deliver(ctx context.Context, schedule *models.ReportSchedule, start, end time.Time) error {
+	report, err := s.BuildReport(ctx, schedule, start, end)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	if schedule.EmailChannel != nil && len(schedule.Recipients) > 0 {
+		if err := s.sendEmail(ctx, schedule, report); err != nil {
+			errs = append(errs, fmt.Errorf("email: %w", err))
+		}
+	}
+	if schedule.WebhookURL != "" {
+		if err := s.sendWebhook(ctx, schedule, report); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *ReportService) sendEmail(ctx context.Context, schedule *models.ReportSchedule, report *dto.AnalyticsReport) error {
+	if s.Email == nil {
+		return errors.New("email delivery is not configured")
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *schedule.EmailChannel)
+	if err != nil {
+		return fmt.Errorf("failed to get email channel: %w", err)
+	}
+	if channel.ChannelType != models.ChannelTypeEmail || !channel.IsActive {
+		return errors.New("email channel is not an active email channel")
+	}
+	return s.Email.SendReport(ctx, channel, schedule.Recipients, reportSubject(report), renderReportText(report), renderReportHTML(report))
+}
+
+func (s *ReportService) sendWebhook(ctx context.Context, schedule *models.ReportSchedule, report *dto.AnalyticsReport) error {
+	if s.Webhooks == nil {
+		return errors.New("webhook delivery is not configured")
+	}
+	data, err := jsonMap(report)
+	if err != nil {
+		return err
+	}
+	return s.Webhooks.SendEventWebhook(ctx, schedule.WebhookURL, string(models.EventTypeAnalyticsReport), string(models.EntityTypeClient), schedule.ClientID, data)
+}
+
+// validate checks a schedule's client, timing and deliveries.
+func (s *ReportService) validate(ctx context.Context, schedule *models.ReportSchedule) error {
+	client, err := s.ClientRepo.GetByClientID(ctx, schedule.ClientID)
+	if err != nil {
+		return errors.New("client not found")
+	}
+	if schedule.Name == "" {
+		return errors.New("name is required")
+	}
+	switch schedule.Frequency {
+	case models.ReportFrequencyDaily, models.ReportFrequencyWeekly:
+	default:
+		return fmt.Errorf("frequency must be %s or %s", models.ReportFrequencyDaily, models.ReportFrequencyWeekly)
+	}
+	if schedule.Weekday < time.Sunday || schedule.Weekday > time.Saturday {
+		return errors.New("weekday must be between 0 (Sunday) and 6 (Saturday)")
+	}
+	if schedule.Hour < 0 || schedule.Hour > 23 {
+		return errors.New("hour must be between 0 and 23")
+	}
+	if _, err := time.LoadLocation(schedule.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", schedule.Timezone)
+	}
+
+	if schedule.EmailChannel == nil && schedule.WebhookURL == "" {
+		return errors.New("an email channel with recipients or a webhook_url is required")
+	}
+	if schedule.EmailChannel != nil {
+		channel, err := s.ClientChannelRepo.GetByID(ctx, *schedule.EmailChannel)
+		if err != nil || channel.ClientID != client.ID {
+			return errors.New("email channel not found")
+		}
+		if channel.ChannelType != models.ChannelTypeEmail {
+			return errors.New("email_channel_id must be an email channel")
+		}
+		if len(schedule.Recipients) == 0 {
+			return errors.New("recipients are required with an email channel")
+		}
+		for _, recipient := range schedule.Recipients {
+			if _, err := mail.ParseAddress(recipient); err != nil {
+				return fmt.Errorf("invalid recipient %q", recipient)
+			}
+		}
+	}
+	if schedule.WebhookURL != "" {
+		if !isAbsoluteURL(schedule.WebhookURL) {
+			return errors.New("webhook_url must be an http or https URL")
+		}
+		// Hosts that don't resolve yet are left to fail at delivery
+		if u, err := url.Parse(schedule.WebhookURL); err == nil && s.Egress != nil {
+			if _, err := s.Egress.Check(ctx, u.Hostname()); errors.Is(err, egress.ErrBlocked) {
+				return fmt.Errorf("webhook_url: %w", err)
+			}
+		}
+	}
+	return nil
+}
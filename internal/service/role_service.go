@@ -0,0 +1,90 @@
+// Package service provides business logic for role-based access control.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+var (
+	ErrRoleAssignmentNotFound = errors.New("role assignment not found")
+	ErrInvalidRoles           = errors.New("invalid roles")
+)
+
+// RoleService manages the roles granted to dashboard users.
+type RoleService struct {
+	Repo *repository.RoleAssignmentRepository
+}
+
+// NewRoleService creates a new RoleService.
+func NewRoleService(repo *repository.RoleAssignmentRepository) *RoleService {
+	return &RoleService{Repo: repo}
+}
+
+// validateRoles rejects unknown roles and, when allowAdmin is false, the admin role.
+func validateRoles(roles []models.Role, allowAdmin bool) error {
+	for _, role := range roles {
+		if !role.IsValid() {
+			return fmt.Errorf("%w: unknown role %q", ErrInvalidRoles, role)
+		}
+		if role == models.RoleAdmin && !allowAdmin {
+			return fmt.Errorf("%w: the admin role can't be granted here", ErrInvalidRoles)
+		}
+	}
+	return nil
+}
+
+// ListAssignments returns user role assignments.
+func (s *RoleService) ListAssignments(ctx context.Context, limit, offset int) ([]models.RoleAssignment, error) {
+	return s.Repo.List(ctx, limit, offset)
+}
+
+// SetAssignment replaces the roles of the user with the given OIDC subject. clientID, when set,
+// limits the user to that client.
+func (s *RoleService) SetAssignment(ctx context.Context, subject string, roles []models.Role, clientID string) (*models.RoleAssignment, error) {
+	if strings.TrimSpace(subject) == "" {
+		return nil, fmt.Errorf("%w: subject is required", ErrInvalidRoles)
+	}
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("%w: at least one role is required", ErrInvalidRoles)
+	}
+	if err := validateRoles(roles, true); err != nil {
+		return nil, err
+	}
+	if clientID != "" && containsRole(roles, models.RoleAdmin) {
+		return nil, fmt.Errorf("%w: admin can't be limited to a client", ErrInvalidRoles)
+	}
+	return s.Repo.Upsert(ctx, subject, roles, clientID)
+}
+
+// DeleteAssignment removes the roles granted to a user.
+func (s *RoleService) DeleteAssignment(ctx context.Context, subject string) error {
+	if err := s.Repo.Delete(ctx, subject); err != nil {
+		return ErrRoleAssignmentNotFound
+	}
+	return nil
+}
+
+// AssignmentFor returns the roles granted to a user and the client they are limited to. A user
+// without an assignment gets no roles.
+func (s *RoleService) AssignmentFor(ctx context.Context, subject string) ([]models.Role, string) {
+	assignment, err := s.Repo.GetBySubject(ctx, subject)
+	if err != nil {
+		return nil, ""
+	}
+	return assignment.Roles, assignment.ClientID
+}
+
+func containsRole(roles []models.Role, role models.Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
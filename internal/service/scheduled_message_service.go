@@ -0,0 +1,269 @@
+// Package service provides business logic for scheduled chat messages.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxScheduleHorizon keeps send_at well inside RabbitMQ's per-queue TTL limit (~49 days).
+const maxScheduleHorizon = 30 * 24 * time.Hour
+
+var (
+	ErrScheduledMessageNotPending = errors.New("scheduled message is no longer pending")
+	ErrInvalidSendAt              = errors.New("send_at must be in the future and within 30 days")
+)
+
+// ScheduledMessageTaskClient enqueues the tasks used to deliver scheduled messages.
+type ScheduledMessageTaskClient interface {
+	EnqueueScheduledMessage(ctx context.Context, scheduledMessageID string, delay time.Duration) error
+	EnqueueChatWorkflow(ctx context.Context, messageID, sessionID string) error
+	EnqueueSuggestionWorkflow(ctx context.Context, messageID, sessionID string) error
+}
+
+// ScheduledMessageService persists scheduled messages and delivers them when due.
+type ScheduledMessageService struct {
+	Repo                 *repository.ScheduledMessageRepository
+	ChatMessageService   *ChatMessageService
+	SessionService       *ChatSessionService
+	ClientService        *ClientService
+	ClientChannelService *ClientChannelService
+	TaskClient           ScheduledMessageTaskClient
+}
+
+// NewScheduledMessageService creates a new ScheduledMessageService.
+func NewScheduledMessageService(
+	repo *repository.ScheduledMessageRepository,
+	chatMessageService *ChatMessageService,
+	sessionService *ChatSessionService,
+	clientService *ClientService,
+	clientChannelService *ClientChannelService,
+	taskClient ScheduledMessageTaskClient,
+) *ScheduledMessageService {
+	return &ScheduledMessageService{
+		Repo:                 repo,
+		ChatMessageService:   chatMessageService,
+		SessionService:       sessionService,
+		ClientService:        clientService,
+		ClientChannelService: clientChannelService,
+		TaskClient:           taskClient,
+	}
+}
+
+// Schedule validates and stores a pending message, then enqueues a delayed task for its send time.
+func (s *ScheduledMessageService) Schedule(ctx context.Context, msg *models.ScheduledMessage) error {
+	now := time.Now().UTC()
+	if !msg.SendAt.After(now) || msg.SendAt.Sub(now) > maxScheduleHorizon {
+		return ErrInvalidSendAt
+	}
+	if err := ValidateSenderType(msg.SenderType); err != nil {
+		return err
+	}
+	if _, _, err := s.resolveClientChannel(ctx, msg.ClientID, msg.ClientChannelType); err != nil {
+		return err
+	}
+	if s.TaskClient == nil {
+		return fmt.Errorf("task queue unavailable, cannot schedule message")
+	}
+
+	msg.SendAt = msg.SendAt.UTC()
+	msg.Status = models.ScheduledMessageStatusPending
+	if err := s.Repo.Create(ctx, msg); err != nil {
+		return fmt.Errorf("failed to store scheduled message: %w", err)
+	}
+
+	if err := s.TaskClient.EnqueueScheduledMessage(ctx, msg.ID.Hex(), msg.SendAt.Sub(now)); err != nil {
+		_ = s.Repo.Update(ctx, msg.ID, bson.M{
+			"status": models.ScheduledMessageStatusFailed,
+			"error":  err.Error(),
+		})
+		return fmt.Errorf("failed to enqueue scheduled message: %w", err)
+	}
+	return nil
+}
+
+// RequeueOverdue enqueues the delivery of up to limit pending messages due before before, whose
+// delayed task was typically lost with a broker restart. Delivery claims the message while it is
+// still pending, so a message whose task turns up after all is still sent once.
+func (s *ScheduledMessageService) RequeueOverdue(ctx context.Context, before time.Time, limit int64) (int, error) {
+	if s.TaskClient == nil {
+		return 0, fmt.Errorf("task queue unavailable, cannot requeue scheduled messages")
+	}
+	messages, err := s.Repo.ListOverdue(ctx, before, limit)
+	if err != nil {
+		return 0, err
+	}
+
+	requeued := 0
+	for _, msg := range messages {
+		if err := s.TaskClient.EnqueueScheduledMessage(ctx, msg.ID.Hex(), 0); err != nil {
+			return requeued, fmt.Errorf("failed to enqueue scheduled message %s: %w", msg.ID.Hex(), err)
+		}
+		requeued++
+	}
+	return requeued, nil
+}
+
+// GetScheduledMessage retrieves a scheduled message by ID.
+func (s *ScheduledMessageService) GetScheduledMessage(ctx context.Context, id string) (*models.ScheduledMessage, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid scheduled message ID: %w", err)
+	}
+	return s.Repo.GetByID(ctx, oid)
+}
+
+// ListScheduledMessages lists scheduled messages filtered by client, session and status.
+func (s *ScheduledMessageService) ListScheduledMessages(ctx context.Context, clientID, sessionID, status string, limit, offset int) ([]models.ScheduledMessage, error) {
+	filter := map[string]interface{}{}
+	if clientID != "" {
+		filter["client_id"] = clientID
+	}
+	if sessionID != "" {
+		filter["session_id"] = sessionID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+	return s.Repo.List(ctx, filter, limit, offset)
+}
+
+// Cancel stops a pending scheduled message from being sent.
+// The queued task is left in place; it finds the message cancelled and does nothing.
+func (s *ScheduledMessageService) Cancel(ctx context.Context, id string) (*models.ScheduledMessage, error) {
+	msg, err := s.GetScheduledMessage(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	cancelledAt := time.Now().UTC()
+	ok, err := s.Repo.UpdateIfPending(ctx, msg.ID, bson.M{
+		"status":       models.ScheduledMessageStatusCancelled,
+		"cancelled_at": cancelledAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrScheduledMessageNotPending
+	}
+
+	msg.Status = models.ScheduledMessageStatusCancelled
+	msg.CancelledAt = &cancelledAt
+	return msg, nil
+}
+
+// Deliver creates the chat message for a due scheduled message. Called by the worker.
+// Messages that were cancelled or already handled are skipped without error.
+func (s *ScheduledMessageService) Deliver(ctx context.Context, id string) error {
+	msg, err := s.GetScheduledMessage(ctx, id)
+	if err != nil {
+		return err
+	}
+	if msg.Status != models.ScheduledMessageStatusPending {
+		return nil
+	}
+
+	// The delayed queue can fire slightly early; push the task back rather than sending ahead of time
+	if remaining := time.Until(msg.SendAt); remaining > time.Second {
+		return s.TaskClient.EnqueueScheduledMessage(ctx, id, remaining)
+	}
+
+	claimed, err := s.Repo.UpdateIfPending(ctx, msg.ID, bson.M{"status": models.ScheduledMessageStatusSending})
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+
+	chatMessage, effectiveSessionID, err := s.createChatMessage(ctx, msg)
+	if err != nil {
+		_ = s.Repo.Update(ctx, msg.ID, bson.M{
+			"status": models.ScheduledMessageStatusFailed,
+			"error":  err.Error(),
+		})
+		return err
+	}
+
+	sentAt := time.Now().UTC()
+	if err := s.Repo.Update(ctx, msg.ID, bson.M{
+		"status":  models.ScheduledMessageStatusSent,
+		"message": chatMessage.ID,
+		"sent_at": sentAt,
+	}); err != nil {
+		return fmt.Errorf("failed to mark scheduled message sent: %w", err)
+	}
+
+	s.triggerWorkflow(ctx, chatMessage, effectiveSessionID)
+	return nil
+}
+
+// createChatMessage runs the same client, channel and session resolution as POST /messages.
+func (s *ScheduledMessageService) createChatMessage(ctx context.Context, msg *models.ScheduledMessage) (*models.ChatMessage, string, error) {
+	client, clientChannel, err := s.resolveClientChannel(ctx, msg.ClientID, msg.ClientChannelType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	session, effectiveSessionID, err := s.SessionService.GetOrCreateSessionBySessionID(ctx, msg.SessionID, client, clientChannel)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get or create session: %w", err)
+	}
+
+	chatMessage := &models.ChatMessage{
+		ExternalID:      msg.ExternalID,
+		Sender:          msg.Sender,
+		SenderName:      msg.SenderName,
+		SenderType:      msg.SenderType,
+		SessionID:       session.ID,
+		ParentMessageID: msg.ParentMessageID,
+		Text:            msg.Text,
+		Attachments:     msg.Attachments,
+		Data:            msg.Data,
+		Category:        msg.Category,
+		Config:          msg.Config,
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, chatMessage); err != nil {
+		return nil, "", err
+	}
+	return chatMessage, effectiveSessionID, nil
+}
+
+// resolveClientChannel loads the client and channel and checks both are active.
+func (s *ScheduledMessageService) resolveClientChannel(ctx context.Context, clientID, channelType string) (*models.Client, *models.ClientChannel, error) {
+	client, err := s.ClientService.GetClient(ctx, clientID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("client not found")
+	}
+	if !client.IsActive {
+		return nil, nil, fmt.Errorf("client is not active")
+	}
+
+	clientChannel, err := s.ClientChannelService.GetChannelByType(ctx, clientID, channelType)
+	if err != nil {
+		return nil, nil, fmt.Errorf("client channel not found")
+	}
+	if !clientChannel.IsActive {
+		return nil, nil, fmt.Errorf("client channel is not active")
+	}
+	return client, clientChannel, nil
+}
+
+// triggerWorkflow starts the AI chat or suggestion workflow based on the message config, as POST /messages does.
+func (s *ScheduledMessageService) triggerWorkflow(ctx context.Context, msg *models.ChatMessage, effectiveSessionID string) {
+	aiEnabled, aiOk := msg.Config["ai_enabled"].(bool)
+	suggestionMode, suggestionOk := msg.Config["suggestion_mode"].(bool)
+	if aiOk && aiEnabled && (!suggestionOk || !suggestionMode) {
+		_ = s.TaskClient.EnqueueChatWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	} else if suggestionOk && suggestionMode && (!aiOk || !aiEnabled) {
+		_ = s.TaskClient.EnqueueSuggestionWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	}
+}
@@ -0,0 +1,38 @@
+// Package service provides the secrets resolver for processor and channel credentials.
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/secrets"
+)
+
+// NewSecretResolver creates the resolver for the secrets providers cfg configures.
+func NewSecretResolver(cfg *config.Config) *secrets.Resolver {
+	resolver := secrets.NewResolver(cfg.SecretsCacheTTL)
+	resolver.Register(secrets.ProviderEnv, secrets.EnvProvider{Prefix: cfg.SecretsEnvPrefix})
+	if cfg.VaultAddr != "" {
+		resolver.Register(secrets.ProviderVault, secrets.NewVaultProvider(cfg.VaultAddr, cfg.VaultToken, cfg.VaultMount, cfg.SecretsPathPrefix))
+	}
+	if cfg.AWSRegion != "" {
+		resolver.Register(secrets.ProviderAWS, secrets.NewAWSProvider(cfg.AWSRegion, cfg.AWSAccessKeyID, cfg.AWSSecretAccessKey, cfg.AWSSessionToken, cfg.SecretsPathPrefix))
+	}
+	return resolver
+}
+
+// resolveChannelSecrets replaces the secret references in the channel_config of freshly loaded
+// channels with their values, in the scope of each channel's client. Channels from a ClientCache
+// are shared and must be copied first.
+func resolveChannelSecrets(ctx context.Context, resolver *secrets.Resolver, channels ...*models.ClientChannel) error {
+	for _, channel := range channels {
+		resolved, err := resolver.ResolveConfig(ctx, channel.ClientID.Hex(), channel.ChannelConfig)
+		if err != nil {
+			return fmt.Errorf("channel %s: %w", channel.ID.Hex(), err)
+		}
+		channel.ChannelConfig = resolved
+	}
+	return nil
+}
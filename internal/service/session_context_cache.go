@@ -0,0 +1,165 @@
+// Package service provides business logic for caching the message context of chat sessions.
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/fraiday-org/api-service/internal/redis"
+)
+
+const (
+	// DefaultSessionContextMessages is how many recent messages the AI context of a session holds
+	DefaultSessionContextMessages = 50
+	// DefaultSessionContextCacheTTL is how long a session's context is kept in Redis before it is
+	// rebuilt from MongoDB
+	DefaultSessionContextCacheTTL = 10 * time.Minute
+)
+
+// SessionContextCache keeps the recent messages of chat sessions in Redis, so each AI request
+// reads from MongoDB only the messages that arrived since the previous one. A session is a sorted
+// set of JSON-encoded messages scored by creation time. Its expiry is set when it is built and
+// isn't extended by appends, so edits to cached messages show up at the latest TTL later.
+type SessionContextCache struct {
+	Redis *redis.Client
+	TTL   time.Duration
+}
+
+// NewSessionContextCache creates a SessionContextCache on the given Redis client.
+func NewSessionContextCache(client *redis.Client, ttl time.Duration) *SessionContextCache {
+	return &SessionContextCache{Redis: client, TTL: ttl}
+}
+
+// Messages returns the last limit messages of a session, oldest first. load reads the last limit
+// messages created at or after since, oldest first; a zero since means from the start. A cached
+// session is topped up with what load finds after its newest message; otherwise it is built from
+// the last limit messages.
+func (c *SessionContextCache) Messages(ctx context.Context, sessionID primitive.ObjectID, limit int, load func(ctx context.Context, since time.Time, limit int) ([]ChatMessage, error)) ([]ChatMessage, error) {
+	key := "session_context:" + sessionID.Hex()
+	reply, err := c.Redis.Do(ctx, "ZRANGE", key, "0", "-1")
+	if err != nil {
+		return nil, err
+	}
+	members, _ := reply.([]interface{})
+
+	if len(members) == 0 {
+		messages, err := load(ctx, time.Time{}, limit)
+		if err != nil {
+			return nil, err
+		}
+		if len(messages) > 0 {
+			if err := c.store(ctx, key, messages); err != nil {
+				return nil, err
+			}
+		}
+		return messages, nil
+	}
+
+	cached := make([]ChatMessage, 0, len(members))
+	seen := make(map[primitive.ObjectID]bool, len(members))
+	for _, member := range members {
+		data, _ := member.(string)
+		var message ChatMessage
+		if err := json.Unmarshal([]byte(data), &message); err != nil {
+			return nil, fmt.Errorf("invalid cached message: %w", err)
+		}
+		cached = append(cached, message)
+		seen[message.ID] = true
+	}
+
+	// Messages created in the same instant as the newest cached one are read again, so skip those
+	loaded, err := load(ctx, cached[len(cached)-1].CreatedAt, limit)
+	if err != nil {
+		return nil, err
+	}
+	var fresh []ChatMessage
+	for _, message := range loaded {
+		if !seen[message.ID] {
+			fresh = append(fresh, message)
+		}
+	}
+	if len(fresh) > 0 {
+		if err := c.add(ctx, key, fresh, limit); err != nil {
+			return nil, err
+		}
+	}
+
+	messages := append(cached, fresh...)
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return messages, nil
+}
+
+// store replaces the cached session at key with messages in one transaction, and sets its expiry.
+func (c *SessionContextCache) store(ctx context.Context, key string, messages []ChatMessage) error {
+	zadd, err := zaddCommand(key, messages)
+	if err != nil {
+		return err
+	}
+	replies, err := c.Redis.Pipeline(ctx,
+		[]string{"MULTI"},
+		[]string{"DEL", key},
+		zadd,
+		[]string{"PEXPIRE", key, strconv.FormatInt(c.TTL.Milliseconds(), 10)},
+		[]string{"EXEC"},
+	)
+	if err != nil {
+		return err
+	}
+	if results, ok := replies[len(replies)-1].([]interface{}); ok {
+		replies = append(replies, results...)
+	}
+	return firstReplyError(replies)
+}
+
+// add appends messages to the cached session at key and trims it to its newest limit messages.
+// When the session expired since it was read, the add recreates it, so it gets an expiry again.
+func (c *SessionContextCache) add(ctx context.Context, key string, messages []ChatMessage, limit int) error {
+	zadd, err := zaddCommand(key, messages)
+	if err != nil {
+		return err
+	}
+	replies, err := c.Redis.Pipeline(ctx,
+		zadd,
+		[]string{"ZREMRANGEBYRANK", key, "0", strconv.Itoa(-limit - 1)},
+		[]string{"PTTL", key},
+	)
+	if err != nil {
+		return err
+	}
+	if err := firstReplyError(replies); err != nil {
+		return err
+	}
+	// PTTL is -1 for a key without expiry
+	if ttl, _ := replies[2].(int64); ttl == -1 {
+		_, err = c.Redis.Do(ctx, "PEXPIRE", key, strconv.FormatInt(c.TTL.Milliseconds(), 10))
+	}
+	return err
+}
+
+func zaddCommand(key string, messages []ChatMessage) ([]string, error) {
+	command := []string{"ZADD", key}
+	for i := range messages {
+		data, err := json.Marshal(&messages[i])
+		if err != nil {
+			return nil, err
+		}
+		command = append(command, strconv.FormatInt(messages[i].CreatedAt.UnixMilli(), 10), string(data))
+	}
+	return command, nil
+}
+
+func firstReplyError(replies []interface{}) error {
+	for _, reply := range replies {
+		if err, ok := reply.(redis.Error); ok {
+			return err
+		}
+	}
+	return nil
+}
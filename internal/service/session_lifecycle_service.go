@@ -0,0 +1,314 @@
+// Package service provides business logic for chat session lifecycle states.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// sessionSweepBatch bounds how many sessions one sweep pass handles per client
+const sessionSweepBatch = 500
+
+var (
+	ErrSessionInvalidTransition = errors.New("session is not in a state that allows this transition")
+	ErrSessionSnoozeUntil       = errors.New("snoozed_until must be in the future")
+	ErrSessionClosed            = errors.New("session is closed")
+)
+
+// sessionTransitions lists the states each state may move to.
+var sessionTransitions = map[models.SessionState][]models.SessionState{
+	models.SessionStateOpen:     {models.SessionStatePending, models.SessionStateSnoozed, models.SessionStateClosed},
+	models.SessionStatePending:  {models.SessionStateOpen, models.SessionStateSnoozed, models.SessionStateClosed},
+	models.SessionStateSnoozed:  {models.SessionStateOpen, models.SessionStatePending, models.SessionStateClosed},
+	models.SessionStateReopened: {models.SessionStatePending, models.SessionStateSnoozed, models.SessionStateClosed},
+	models.SessionStateClosed:   {models.SessionStateReopened},
+}
+
+// SessionRecapTaskClient enqueues recap generation for sessions that have closed.
+type SessionRecapTaskClient interface {
+	EnqueueSessionRecap(ctx context.Context, sessionID string) error
+}
+
+// SessionLifecycleService drives the session state machine and closes sessions left inactive.
+type SessionLifecycleService struct {
+	ChatSessionRepo       *repository.ChatSessionRepository
+	ClientRepo            *repository.ClientRepository
+	EventPublisherService *EventPublisherService
+	// DefaultAutoCloseMinutes applies to clients without chat_config.auto_close_minutes; 0 disables auto-close
+	DefaultAutoCloseMinutes int
+	// RecapTaskClient, when set, summarizes every session as it closes
+	RecapTaskClient SessionRecapTaskClient
+	// ThreadManager is needed for the new_thread closed-session policy
+	ThreadManager *ThreadManagerService
+	// DefaultClosedSessionPolicy applies to clients without chat_config.closed_session_policy
+	DefaultClosedSessionPolicy models.ClosedSessionPolicy
+}
+
+// NewSessionLifecycleService creates a new SessionLifecycleService.
+func NewSessionLifecycleService(
+	chatSessionRepo *repository.ChatSessionRepository,
+	clientRepo *repository.ClientRepository,
+	eventPublisherService *EventPublisherService,
+	defaultAutoCloseMinutes int,
+) *SessionLifecycleService {
+	return &SessionLifecycleService{
+		ChatSessionRepo:         chatSessionRepo,
+		ClientRepo:              clientRepo,
+		EventPublisherService:   eventPublisherService,
+		DefaultAutoCloseMinutes: defaultAutoCloseMinutes,
+	}
+}
+
+// Close closes a session.
+func (s *SessionLifecycleService) Close(ctx context.Context, sessionID, reason string) (*models.ChatSession, error) {
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Transition(ctx, session, models.SessionStateClosed, reason, nil)
+}
+
+// Reopen reopens a closed session.
+func (s *SessionLifecycleService) Reopen(ctx context.Context, sessionID, reason string) (*models.ChatSession, error) {
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Transition(ctx, session, models.SessionStateReopened, reason, nil)
+}
+
+// SetState moves a session to state. snoozedUntil is required when snoozing.
+func (s *SessionLifecycleService) SetState(ctx context.Context, sessionID string, state models.SessionState, snoozedUntil *time.Time, reason string) (*models.ChatSession, error) {
+	if _, ok := sessionTransitions[state]; !ok {
+		return nil, fmt.Errorf("invalid state: %s", state)
+	}
+	session, err := s.getSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Transition(ctx, session, state, reason, snoozedUntil)
+}
+
+// Transition moves session to state if allowed and publishes session_state_changed.
+// It fails with ErrSessionInvalidTransition if the session changed state concurrently.
+func (s *SessionLifecycleService) Transition(
+	ctx context.Context,
+	session *models.ChatSession,
+	to models.SessionState,
+	reason string,
+	snoozedUntil *time.Time,
+) (*models.ChatSession, error) {
+	from := session.CurrentState()
+	if !slices.Contains(sessionTransitions[from], to) {
+		return nil, ErrSessionInvalidTransition
+	}
+
+	now := time.Now().UTC()
+	set := bson.M{
+		"state":            to,
+		"state_changed_at": now,
+		"state_reason":     reason,
+		"active":           to != models.SessionStateClosed,
+		"snoozed_until":    nil,
+	}
+	switch to {
+	case models.SessionStateSnoozed:
+		if snoozedUntil == nil || !snoozedUntil.After(now) {
+			return nil, ErrSessionSnoozeUntil
+		}
+		set["snoozed_until"] = snoozedUntil.UTC()
+	case models.SessionStateClosed:
+		set["closed_at"] = now
+	case models.SessionStateReopened:
+		// Restart the inactivity clock so the sweep doesn't close it straight away
+		set["last_activity_at"] = now
+	}
+
+	updated, err := s.ChatSessionRepo.UpdateState(ctx, session.ID, session.State, set)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session state: %w", err)
+	}
+	if updated == nil {
+		return nil, ErrSessionInvalidTransition
+	}
+
+	if s.EventPublisherService != nil {
+		_, _ = s.EventPublisherService.PublishChatSessionEvent(ctx, models.EventTypeSessionStateChanged, session.ID.Hex(), map[string]interface{}{
+			"session_id": session.SessionID,
+			"from":       from,
+			"to":         to,
+			"reason":     reason,
+		})
+	}
+	if to == models.SessionStateClosed && s.RecapTaskClient != nil {
+		// A missing recap shouldn't undo the close; it can still be generated through the recap endpoint
+		_ = s.RecapTaskClient.EnqueueSessionRecap(ctx, session.ID.Hex())
+	}
+	return updated, nil
+}
+
+// Sweep wakes snoozed sessions whose snooze has ended and closes sessions that have been inactive
+// longer than their client's auto-close timeout. It returns how many sessions it changed.
+func (s *SessionLifecycleService) Sweep(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	changed := 0
+
+	snoozed, err := s.ChatSessionRepo.ListSnoozeExpired(ctx, now, sessionSweepBatch)
+	if err != nil {
+		return changed, fmt.Errorf("failed to list snoozed sessions: %w", err)
+	}
+	for i := range snoozed {
+		if _, err := s.Transition(ctx, &snoozed[i], models.SessionStateOpen, "snooze_expired", nil); err == nil {
+			changed++
+		}
+	}
+
+	clients, err := s.ClientRepo.List(ctx)
+	if err != nil {
+		return changed, fmt.Errorf("failed to list clients: %w", err)
+	}
+	for _, client := range clients {
+		minutes := s.autoCloseMinutes(&client)
+		if minutes <= 0 {
+			continue
+		}
+		cutoff := now.Add(-time.Duration(minutes) * time.Minute)
+		sessions, err := s.ChatSessionRepo.ListInactive(ctx, client.ID, cutoff, sessionSweepBatch)
+		if err != nil {
+			return changed, fmt.Errorf("failed to list inactive sessions: %w", err)
+		}
+		for i := range sessions {
+			// Lost races with concurrent transitions are skipped; the next sweep sees the new state
+			if _, err := s.Transition(ctx, &sessions[i], models.SessionStateClosed, "inactivity", nil); err == nil {
+				changed++
+			}
+		}
+	}
+	return changed, nil
+}
+
+// CloseInactiveThreads closes threads idle past their inactivity window, closes each
+// thread's chat session, and publishes thread_closed for it. A thread's session_id in the event
+// can be passed to the CSAT trigger to survey the thread. It returns how many threads it closed.
+func (s *SessionLifecycleService) CloseInactiveThreads(ctx context.Context) (int, error) {
+	if s.ThreadManager == nil {
+		return 0, errors.New("closing threads needs the thread manager")
+	}
+	threads, err := s.ThreadManager.CloseInactiveThreads(ctx, sessionSweepBatch)
+	for i := range threads {
+		thread := &threads[i]
+		if session, getErr := s.ChatSessionRepo.GetByID(ctx, thread.ChatSessionID); getErr == nil && session.CurrentState() != models.SessionStateClosed {
+			// A session that changed state concurrently still has its thread closed
+			_, _ = s.Transition(ctx, session, models.SessionStateClosed, "thread_inactivity", nil)
+		}
+		if s.EventPublisherService != nil {
+			_, _ = s.EventPublisherService.PublishChatSessionEvent(ctx, models.EventTypeThreadClosed, thread.ChatSessionID.Hex(), map[string]interface{}{
+				"session_id":        thread.ThreadSessionID,
+				"parent_session_id": thread.ParentSessionID,
+				"thread_id":         thread.ThreadID,
+				"last_activity":     thread.LastActivity,
+				"reason":            "inactivity",
+			})
+		}
+	}
+	return len(threads), err
+}
+
+// ResolveForMessage applies the closed-session policy to a session a new message is addressed to and
+// returns the session the message should be stored in. Sessions that aren't closed are returned as is.
+// Reopening publishes session_reopened; the reject policy fails with ErrSessionClosed. new_thread falls
+// back to reopening for clients without threading, whose later messages would land in the closed session again.
+func (s *SessionLifecycleService) ResolveForMessage(
+	ctx context.Context,
+	session *models.ChatSession,
+	client *models.Client,
+	clientChannel *models.ClientChannel,
+) (*models.ChatSession, error) {
+	if session.CurrentState() != models.SessionStateClosed {
+		return session, nil
+	}
+
+	policy := s.closedSessionPolicy(client)
+	if policy == models.ClosedSessionPolicyNewThread && (s.ThreadManager == nil || !s.ThreadManager.IsThreadingEnabled(ctx, client, clientChannel)) {
+		policy = models.ClosedSessionPolicyReopen
+	}
+
+	switch policy {
+	case models.ClosedSessionPolicyReject:
+		return nil, ErrSessionClosed
+	case models.ClosedSessionPolicyNewThread:
+		baseSessionID, _ := s.ThreadManager.ParseSessionID(session.SessionID)
+		thread, err := s.ThreadManager.CreateNewThread(ctx, baseSessionID, client, clientChannel)
+		if err != nil {
+			return nil, fmt.Errorf("failed to start new thread: %w", err)
+		}
+		return thread, nil
+	default:
+		updated, err := s.Transition(ctx, session, models.SessionStateReopened, "new_message", nil)
+		if errors.Is(err, ErrSessionInvalidTransition) {
+			// Reopened concurrently by another message
+			if current, getErr := s.ChatSessionRepo.GetByID(ctx, session.ID); getErr == nil && current.CurrentState() != models.SessionStateClosed {
+				return current, nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if s.EventPublisherService != nil {
+			_, _ = s.EventPublisherService.PublishChatSessionEvent(ctx, models.EventTypeSessionReopened, session.ID.Hex(), map[string]interface{}{
+				"session_id": session.SessionID,
+				"reason":     "new_message",
+			})
+		}
+		return updated, nil
+	}
+}
+
+// closedSessionPolicy returns the client's chat_config.closed_session_policy, or the service default.
+func (s *SessionLifecycleService) closedSessionPolicy(client *models.Client) models.ClosedSessionPolicy {
+	if v, ok := client.ChatConfig["closed_session_policy"].(string); ok {
+		switch p := models.ClosedSessionPolicy(v); p {
+		case models.ClosedSessionPolicyReject, models.ClosedSessionPolicyReopen, models.ClosedSessionPolicyNewThread:
+			return p
+		}
+	}
+	if s.DefaultClosedSessionPolicy == "" {
+		return models.ClosedSessionPolicyReopen
+	}
+	return s.DefaultClosedSessionPolicy
+}
+
+// autoCloseMinutes returns the client's chat_config.auto_close_minutes, or the service default.
+func (s *SessionLifecycleService) autoCloseMinutes(client *models.Client) int {
+	switch v := client.ChatConfig["auto_close_minutes"].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	case float64:
+		return int(v)
+	}
+	return s.DefaultAutoCloseMinutes
+}
+
+func (s *SessionLifecycleService) getSession(ctx context.Context, sessionID string) (*models.ChatSession, error) {
+	id, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.New("invalid session id")
+	}
+	session, err := s.ChatSessionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("chat session not found")
+	}
+	return session, nil
+}
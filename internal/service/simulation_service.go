@@ -0,0 +1,324 @@
+// Package service provides business logic for conversation simulations.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+)
+
+const (
+	maxSimulationSteps              = 50
+	defaultSimulationTimeoutSeconds = 60
+	maxSimulationTimeoutSeconds     = 300
+	simulationSender                = "simulator"
+)
+
+var (
+	ErrSimulationNotFound = errors.New("simulation not found")
+	// ErrSimulationQueueUnavailable is returned when there is no task queue to run simulations on
+	ErrSimulationQueueUnavailable = errors.New("task queue unavailable, cannot run simulations")
+)
+
+// simulationOutcomeEvents are the chat workflow events that end a turn without an answer.
+var simulationOutcomeEvents = []models.EventType{
+	models.EventTypeChatWorkflowRouted,
+	models.EventTypeChatWorkflowEscalated,
+	models.EventTypeChatWorkflowHandover,
+	models.EventTypeChatWorkflowVetoed,
+	models.EventTypeChatWorkflowModerated,
+	models.EventTypeChatWorkflowQuotaExceeded,
+	models.EventTypeChatWorkflowOptedOut,
+	models.EventTypeChatWorkflowError,
+}
+
+// SimulationTaskClient enqueues simulation runs for the worker.
+type SimulationTaskClient interface {
+	EnqueueSimulation(ctx context.Context, simulationID string) error
+}
+
+// SimulationWorkflow runs the chat workflow for a user message, as the worker does for a
+// chat_workflow task.
+type SimulationWorkflow func(ctx context.Context, messageID, sessionID string) error
+
+// SimulationService creates simulations on API servers and runs them on workers.
+type SimulationService struct {
+	Repo              *repository.SimulationRepository
+	ClientRepo        *repository.ClientRepository
+	ClientChannelRepo *repository.ClientChannelRepository
+	ChatSessionRepo   *repository.ChatSessionRepository
+	ChatMessageRepo   *repository.ChatMessageRepository
+	EventRepo         *repository.EventRepository
+	// Set on API servers, which queue the runs
+	TaskClient SimulationTaskClient
+	// Set on workers, which create the script's messages
+	ChatMessageService *ChatMessageService
+}
+
+// NewSimulationService creates a new SimulationService.
+func NewSimulationService(
+	repo *repository.SimulationRepository,
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	eventRepo *repository.EventRepository,
+) *SimulationService {
+	return &SimulationService{
+		Repo:              repo,
+		ClientRepo:        clientRepo,
+		ClientChannelRepo: clientChannelRepo,
+		ChatSessionRepo:   chatSessionRepo,
+		ChatMessageRepo:   chatMessageRepo,
+		EventRepo:         eventRepo,
+	}
+}
+
+// CreateSimulation validates req, creates the simulation and its sandbox session, and queues the run.
+func (s *SimulationService) CreateSimulation(ctx context.Context, req *dto.SimulationCreate) (*models.Simulation, error) {
+	if len(req.Script) == 0 || len(req.Script) > maxSimulationSteps {
+		return nil, fmt.Errorf("script must have between 1 and %d steps", maxSimulationSteps)
+	}
+	for i, step := range req.Script {
+		if strings.TrimSpace(step.Text) == "" {
+			return nil, fmt.Errorf("script[%d]: text is required", i)
+		}
+		if step.Expect != nil && (step.Expect.MinConfidence < 0 || step.Expect.MinConfidence > 1) {
+			return nil, fmt.Errorf("script[%d]: expect.min_confidence must be between 0 and 1", i)
+		}
+	}
+	timeout := req.StepTimeoutSeconds
+	if timeout == 0 {
+		timeout = defaultSimulationTimeoutSeconds
+	}
+	if timeout < 0 || timeout > maxSimulationTimeoutSeconds {
+		return nil, fmt.Errorf("step_timeout_seconds must be between 1 and %d", maxSimulationTimeoutSeconds)
+	}
+	if s.TaskClient == nil {
+		return nil, ErrSimulationQueueUnavailable
+	}
+
+	client, err := s.ClientRepo.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	var channelID *primitive.ObjectID
+	if req.ChannelID != "" {
+		channelID = ParseObjectID(req.ChannelID)
+		if channelID == nil {
+			return nil, errors.New("channel not found")
+		}
+		channel, err := s.ClientChannelRepo.GetByID(ctx, *channelID)
+		if err != nil || channel.ClientID != client.ID {
+			return nil, errors.New("channel not found")
+		}
+	}
+
+	sim := &models.Simulation{
+		ID:                 primitive.NewObjectID(),
+		ClientID:           client.ID,
+		ChannelID:          channelID,
+		Name:               req.Name,
+		Status:             models.SimulationStatusQueued,
+		Script:             req.Script,
+		StepTimeoutSeconds: timeout,
+		Turns:              []models.SimulationTurn{},
+	}
+	session := &models.ChatSession{
+		SessionID:     "simulation-" + sim.ID.Hex(),
+		Client:        &client.ID,
+		ClientChannel: channelID,
+		Test:          true,
+		Simulation:    &sim.ID,
+	}
+	if err := s.ChatSessionRepo.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create simulation session: %w", err)
+	}
+	sim.ChatSessionID = session.ID
+	sim.SessionID = session.SessionID
+	if err := s.Repo.Create(ctx, sim); err != nil {
+		return nil, err
+	}
+
+	if err := s.TaskClient.EnqueueSimulation(ctx, sim.ID.Hex()); err != nil {
+		_ = s.Repo.Finish(ctx, sim.ID, models.SimulationStatusFailed, nil, "failed to queue the run")
+		return nil, fmt.Errorf("failed to queue simulation: %w", err)
+	}
+	return sim, nil
+}
+
+// GetSimulation returns a simulation with the turns run so far.
+func (s *SimulationService) GetSimulation(ctx context.Context, id string) (*models.Simulation, error) {
+	objID := ParseObjectID(id)
+	if objID == nil {
+		return nil, ErrSimulationNotFound
+	}
+	sim, err := s.Repo.GetByID(ctx, *objID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrSimulationNotFound
+		}
+		return nil, err
+	}
+	return sim, nil
+}
+
+// ListSimulations returns a client's simulations, newest first, without their scripts and turns.
+func (s *SimulationService) ListSimulations(ctx context.Context, clientID string, limit, offset int) ([]models.Simulation, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, errors.New("client not found")
+	}
+	return s.Repo.List(ctx, bson.M{"client": client.ID}, limit, offset)
+}
+
+// Run replays a queued simulation's script through workflow, one step after the other, and
+// records each turn as it ends and the report once all have. A simulation that isn't queued
+// anymore is left alone.
+func (s *SimulationService) Run(ctx context.Context, id string, workflow SimulationWorkflow) error {
+	sim, err := s.GetSimulation(ctx, id)
+	if err != nil {
+		return err
+	}
+	started := time.Now().UTC()
+	ok, err := s.Repo.Start(ctx, sim.ID, started)
+	if err != nil || !ok {
+		return err
+	}
+
+	turns := make([]models.SimulationTurn, 0, len(sim.Script))
+	for i, step := range sim.Script {
+		turn, err := s.runStep(ctx, sim, i, step, workflow)
+		if err != nil {
+			report := simulationReport(turns, started)
+			report.Passed = false
+			_ = s.Repo.Finish(ctx, sim.ID, models.SimulationStatusFailed, report, err.Error())
+			return err
+		}
+		if err := s.Repo.AddTurn(ctx, sim.ID, *turn); err != nil {
+			return err
+		}
+		turns = append(turns, *turn)
+	}
+	return s.Repo.Finish(ctx, sim.ID, models.SimulationStatusCompleted, simulationReport(turns, started), "")
+}
+
+// runStep sends a step's message and runs the chat workflow on it. Errors of the workflow are
+// part of the turn; the error returned means the step couldn't be run at all.
+func (s *SimulationService) runStep(ctx context.Context, sim *models.Simulation, index int, step models.SimulationStep, workflow SimulationWorkflow) (*models.SimulationTurn, error) {
+	msg := &models.ChatMessage{
+		Sender:     simulationSender,
+		SenderName: simulationSender,
+		SenderType: string(models.SenderTypeUser),
+		SessionID:  sim.ChatSessionID,
+		Text:       step.Text,
+		Category:   models.MessageCategoryMessage,
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		return nil, fmt.Errorf("failed to send step %d: %w", index, err)
+	}
+	turn := &models.SimulationTurn{Step: index, MessageID: msg.ID}
+
+	stepCtx, cancel := context.WithTimeout(ctx, time.Duration(sim.StepTimeoutSeconds)*time.Second)
+	began := time.Now()
+	err := workflow(stepCtx, msg.ID.Hex(), sim.SessionID)
+	turn.LatencyMs = time.Since(began).Milliseconds()
+	timedOut := stepCtx.Err() == context.DeadlineExceeded
+	cancel()
+
+	switch {
+	case timedOut:
+		turn.Outcome = models.SimulationOutcomeTimeout
+	case err != nil:
+		turn.Outcome = models.SimulationOutcomeError
+		turn.Error = err.Error()
+	default:
+		if err := s.readOutcome(ctx, sim.ChatSessionID, msg.ID, turn); err != nil {
+			return nil, err
+		}
+	}
+	turn.Passed = turn.Outcome == models.SimulationOutcomeAnswered
+	if turn.Passed && step.Expect != nil {
+		turn.Failures = step.Expect.Check(turn.Answer, turn.Confidence)
+		turn.Passed = len(turn.Failures) == 0
+	}
+	return turn, nil
+}
+
+// readOutcome fills in the answer the chat workflow saved for a message or, when there is none,
+// the event it published instead.
+func (s *SimulationService) readOutcome(ctx context.Context, sessionID, messageID primitive.ObjectID, turn *models.SimulationTurn) error {
+	answers, err := s.ChatMessageRepo.List(ctx, bson.M{"session": sessionID, "config.original_message_id": messageID.Hex()}, 1)
+	if err != nil {
+		return fmt.Errorf("failed to read answer: %w", err)
+	}
+	if len(answers) > 0 {
+		turn.Outcome = models.SimulationOutcomeAnswered
+		turn.Answer = answers[0].Text
+		turn.AnswerMessageID = &answers[0].ID
+		turn.Confidence = answers[0].Confidence
+		return nil
+	}
+
+	events, err := s.EventRepo.List(ctx, map[string]interface{}{
+		"entity_id":  messageID.Hex(),
+		"event_type": bson.M{"$in": simulationOutcomeEvents},
+	}, 1, 0)
+	if err != nil {
+		return fmt.Errorf("failed to read workflow events: %w", err)
+	}
+	turn.Outcome = models.SimulationOutcomeNoAnswer
+	if len(events) > 0 {
+		turn.Outcome = string(events[0].EventType)
+	}
+	return nil
+}
+
+// simulationReport sums up turns of a run started at started.
+func simulationReport(turns []models.SimulationTurn, started time.Time) *models.SimulationReport {
+	report := &models.SimulationReport{
+		Steps:      len(turns),
+		Passed:     true,
+		DurationMs: time.Since(started).Milliseconds(),
+	}
+	latencies := make([]int64, 0, len(turns))
+	var confidenceSum float64
+	var latencySum int64
+	for _, turn := range turns {
+		latencies = append(latencies, turn.LatencyMs)
+		latencySum += turn.LatencyMs
+		if !turn.Passed {
+			report.Failed++
+			report.Passed = false
+		}
+		if turn.Outcome != models.SimulationOutcomeAnswered {
+			continue
+		}
+		if report.Answered == 0 || turn.Confidence < report.MinConfidence {
+			report.MinConfidence = turn.Confidence
+		}
+		report.Answered++
+		confidenceSum += turn.Confidence
+	}
+	if report.Answered > 0 {
+		report.AvgConfidence = confidenceSum / float64(report.Answered)
+	}
+	if len(latencies) > 0 {
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		report.AvgLatencyMs = latencySum / int64(len(latencies))
+		report.P95LatencyMs = latencies[(len(latencies)*95+99)/100-1]
+		report.MaxLatencyMs = latencies[len(latencies)-1]
+	}
+	return report
+}
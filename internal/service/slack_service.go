@@ -0,0 +1,473 @@
+// Package service provides business logic for the native Slack channel connector.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"github.com/fraiday-org/api-service/internal/utils"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	slackAPIBaseURL = "https://slack.com/api"
+	// slackMaxClockSkew rejects signed requests older than this, as Slack recommends
+	slackMaxClockSkew = 5 * time.Minute
+	// Session attributes that tell the outbound adapter where to post replies
+	slackChannelAttribute = "slack_channel"
+	slackThreadAttribute  = "slack_thread_ts"
+)
+
+var (
+	ErrSlackSignature       = errors.New("invalid slack signature")
+	ErrSlackChannelNotFound = errors.New("no active slack channel matches the request")
+)
+
+// slackMentionPattern matches a leading bot mention such as "<@U012AB3CD> "
+var slackMentionPattern = regexp.MustCompile(`^\s*<@[A-Z0-9]+>\s*`)
+
+// SlackEnvelope is the outer body of a request from the Slack Events API.
+type SlackEnvelope struct {
+	Type      string      `json:"type"`
+	Challenge string      `json:"challenge,omitempty"`
+	TeamID    string      `json:"team_id,omitempty"`
+	EventID   string      `json:"event_id,omitempty"`
+	Event     *SlackEvent `json:"event,omitempty"`
+}
+
+// SlackEvent is the inner event of an event_callback envelope.
+type SlackEvent struct {
+	Type     string `json:"type"`
+	Subtype  string `json:"subtype,omitempty"`
+	User     string `json:"user,omitempty"`
+	BotID    string `json:"bot_id,omitempty"`
+	Text     string `json:"text"`
+	Channel  string `json:"channel"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+}
+
+// SlackService connects ClientChannels of type slack to the Slack Events and Web APIs.
+// Each channel's channel_config holds its signing_secret, bot_token and team_id, and
+// optionally ai_enabled (default true) for messages received from Slack. A channel's slack_ai
+// routes its replies through the Slack AI workflow of its workspace.
+type SlackService struct {
+	ClientRepo         *repository.ClientRepository
+	ClientChannelRepo  *repository.ClientChannelRepository
+	ChatSessionRepo    *repository.ChatSessionRepository
+	ChatMessageRepo    *repository.ChatMessageRepository
+	SessionService     *ChatSessionService
+	ChatMessageService *ChatMessageService
+	// LifecycleService, when set, applies the closed-session policy to inbound messages
+	LifecycleService *SessionLifecycleService
+	// SecretResolver resolves secret:// references in channel configs when they're loaded
+	SecretResolver *secrets.Resolver
+	// Secrets decrypts the tokens of channels' Slack AI workflows
+	Secrets    *utils.SecretBox
+	APIBaseURL string
+	logger     *zap.Logger
+	httpClient *http.Client
+}
+
+// NewSlackService creates a new SlackService.
+func NewSlackService(
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	sessionService *ChatSessionService,
+	chatMessageService *ChatMessageService,
+	logger *zap.Logger,
+) *SlackService {
+	return &SlackService{
+		ClientRepo:         clientRepo,
+		ClientChannelRepo:  clientChannelRepo,
+		ChatSessionRepo:    chatSessionRepo,
+		ChatMessageRepo:    chatMessageRepo,
+		SessionService:     sessionService,
+		ChatMessageService: chatMessageService,
+		APIBaseURL:         slackAPIBaseURL,
+		logger:             logger,
+		httpClient:         &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// HandleEvents verifies and processes a request from the Slack Events API. It returns the
+// challenge to echo for url_verification requests and "" otherwise.
+func (s *SlackService) HandleEvents(ctx context.Context, timestamp, signature string, body []byte) (string, error) {
+	var envelope SlackEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return "", fmt.Errorf("invalid slack payload: %w", err)
+	}
+
+	channel, err := s.verifiedChannel(ctx, envelope.TeamID, timestamp, signature, body)
+	if err != nil {
+		return "", err
+	}
+
+	switch envelope.Type {
+	case "url_verification":
+		return envelope.Challenge, nil
+	case "event_callback":
+		if envelope.Event != nil {
+			return "", s.handleMessage(ctx, channel, envelope.TeamID, envelope.Event)
+		}
+	}
+	return "", nil
+}
+
+// verifiedChannel finds the active Slack channel whose signing secret produced signature.
+// url_verification requests carry no team, so every Slack channel is a candidate.
+func (s *SlackService) verifiedChannel(ctx context.Context, teamID, timestamp, signature string, body []byte) (*models.ClientChannel, error) {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrSlackSignature
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > slackMaxClockSkew || skew < -slackMaxClockSkew {
+		return nil, ErrSlackSignature
+	}
+
+	filter := bson.M{"channel_type": models.ChannelTypeSlack, "is_active": true}
+	if teamID != "" {
+		filter["channel_config.team_id"] = teamID
+	}
+	channels, err := s.ClientChannelRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list slack channels: %w", err)
+	}
+	if len(channels) == 0 {
+		return nil, ErrSlackChannelNotFound
+	}
+
+	base := "v0:" + timestamp + ":" + string(body)
+	for i := range channels {
+		if err := resolveChannelSecrets(ctx, s.SecretResolver, &channels[i]); err != nil {
+			s.logger.Warn("Failed to resolve slack channel secrets", zap.Error(err))
+			continue
+		}
+		secret, _ := channels[i].ChannelConfig["signing_secret"].(string)
+		if secret == "" {
+			continue
+		}
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(base))
+		if hmac.Equal([]byte("v0="+hex.EncodeToString(mac.Sum(nil))), []byte(signature)) {
+			return &channels[i], nil
+		}
+	}
+	return nil, ErrSlackSignature
+}
+
+// handleMessage stores a user message from Slack and triggers the AI workflow. Bot messages,
+// edits and other subtypes are ignored so replies posted by the connector don't loop back.
+func (s *SlackService) handleMessage(ctx context.Context, channel *models.ClientChannel, teamID string, event *SlackEvent) error {
+	if (event.Type != "message" && event.Type != "app_mention") || event.Subtype != "" || event.BotID != "" || event.User == "" {
+		return nil
+	}
+
+	client, err := s.ClientRepo.GetByID(ctx, channel.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	if !client.IsActive {
+		return nil
+	}
+
+	sessionID := "slack:" + teamID + ":" + event.Channel
+	if event.ThreadTS != "" {
+		sessionID += ":" + event.ThreadTS
+	}
+	session, effectiveSessionID, err := s.SessionService.GetOrCreateSessionBySessionID(ctx, sessionID, client, channel)
+	if err != nil {
+		return fmt.Errorf("failed to get or create session: %w", err)
+	}
+	if s.LifecycleService != nil {
+		session, err = s.LifecycleService.ResolveForMessage(ctx, session, client, channel)
+		if err != nil {
+			if errors.Is(err, ErrSessionClosed) {
+				return nil
+			}
+			return err
+		}
+		effectiveSessionID = session.SessionID
+	}
+
+	// Slack sends both message and app_mention for a mention in a channel the app is in, and
+	// retries events it didn't see acknowledged. Timestamps are only unique within a conversation.
+	dedupKey := models.MessageDedupKey(client.ID, channel.ID, event.Channel+":"+event.TS)
+	if existing, err := s.ChatMessageService.FindDuplicate(ctx, dedupKey); err == nil && existing != nil {
+		telemetry.ObserveMessageDedup(string(channel.ChannelType))
+		return nil
+	}
+
+	if session.Attributes[slackChannelAttribute] != event.Channel || session.Attributes[slackThreadAttribute] != event.ThreadTS {
+		attrs := map[string]string{slackChannelAttribute: event.Channel}
+		var unset []string
+		if event.ThreadTS != "" {
+			attrs[slackThreadAttribute] = event.ThreadTS
+		} else {
+			unset = []string{slackThreadAttribute}
+		}
+		if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, attrs, unset); err != nil {
+			return fmt.Errorf("failed to store slack conversation on session: %w", err)
+		}
+	}
+
+	aiEnabled := true
+	if v, ok := channel.ChannelConfig["ai_enabled"].(bool); ok {
+		aiEnabled = v
+	}
+	msg := &models.ChatMessage{
+		ExternalID: event.TS,
+		DedupKey:   dedupKey,
+		Sender:     event.User,
+		SenderType: string(models.SenderTypeUser),
+		SessionID:  session.ID,
+		Text:       slackMentionPattern.ReplaceAllString(event.Text, ""),
+		Category:   models.MessageCategoryMessage,
+		Config:     map[string]interface{}{"ai_enabled": aiEnabled},
+		Data:       map[string]interface{}{"slack": map[string]interface{}{"channel": event.Channel, "ts": event.TS, "thread_ts": event.ThreadTS}},
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		if errors.Is(err, ErrMessageDuplicate) {
+			telemetry.ObserveMessageDedup(string(channel.ChannelType))
+			return nil
+		}
+		return fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	if aiEnabled {
+		TriggerChatWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	}
+	return nil
+}
+
+// Deliver posts the message behind a chat_message_created event to Slack when it is a bot or
+// agent reply in a session of a Slack channel. Other events are acknowledged without posting.
+func (s *SlackService) Deliver(ctx context.Context, eventData map[string]interface{}) error {
+	if eventType, _ := eventData["event_type"].(string); eventType != string(models.EventTypeChatMessageCreated) {
+		return nil
+	}
+	entityID, _ := eventData["entity_id"].(string)
+	messageID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q", entityID)
+	}
+
+	msg, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat message: %w", err)
+	}
+	if msg.SenderType != string(models.SenderTypeAssistant) && !strings.HasPrefix(msg.SenderType, "client:") {
+		return nil
+	}
+	// Suggestions are for agents, not for the Slack user
+	if suggestion, _ := msg.Config["suggestion_mode"].(bool); suggestion {
+		return nil
+	}
+
+	session, err := s.ChatSessionRepo.GetByID(ctx, msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	slackChannel := session.Attributes[slackChannelAttribute]
+	if session.ClientChannel == nil || slackChannel == "" {
+		return nil
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *session.ClientChannel)
+	if err != nil {
+		return fmt.Errorf("failed to get client channel: %w", err)
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return err
+	}
+	token, _ := channel.ChannelConfig["bot_token"].(string)
+	if channel.ChannelType != models.ChannelTypeSlack || token == "" {
+		return nil
+	}
+
+	text, attachments := DegradeAttachments(msg.Text, msg.Attachments, channel.EffectiveCapabilities())
+	return s.PostMessage(ctx, token, slackChannel, session.Attributes[slackThreadAttribute], text, attachments)
+}
+
+// AIWorkflow returns the Slack AI workflow of the Slack channel a session is on, with the
+// client_id it runs for, or nil when the channel has none.
+func (s *SlackService) AIWorkflow(ctx context.Context, sessionID primitive.ObjectID) (*SlackAIWorkflow, string, error) {
+	session, err := s.ChatSessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get session: %w", err)
+	}
+	if session.ClientChannel == nil {
+		return nil, "", nil
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *session.ClientChannel)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get client channel: %w", err)
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return nil, "", err
+	}
+	if channel.ChannelType != models.ChannelTypeSlack || channel.SlackAI == nil {
+		return nil, "", nil
+	}
+	if s.Secrets == nil {
+		return nil, "", fmt.Errorf("slack AI token can't be decrypted: %w", utils.ErrNoEncryptionKey)
+	}
+	token, err := s.Secrets.Open(channel.SlackAI.Token)
+	if err != nil {
+		return nil, "", fmt.Errorf("slack AI token of channel %s: %w", channel.ID.Hex(), err)
+	}
+	client, err := s.ClientRepo.GetByID(ctx, channel.ClientID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get client: %w", err)
+	}
+	return &SlackAIWorkflow{URL: channel.SlackAI.URL, Token: token, WorkflowID: channel.SlackAI.WorkflowID}, client.ClientID, nil
+}
+
+// PostMessage sends text and any button attachments to a Slack conversation with chat.postMessage.
+func (s *SlackService) PostMessage(ctx context.Context, token, channel, threadTS, text string, attachments []models.Attachment) error {
+	body := map[string]interface{}{
+		"channel": channel,
+		"text":    text,
+	}
+	if threadTS != "" {
+		body["thread_ts"] = threadTS
+	}
+	if blocks := slackBlocks(text, attachments); blocks != nil {
+		body["blocks"] = blocks
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal slack message: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.APIBaseURL+"/chat.postMessage", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create slack request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("slack request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// The Web API reports most failures as 200 with ok=false
+	var reply struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&reply); err != nil {
+		return fmt.Errorf("failed to decode slack response (status %d): %w", resp.StatusCode, err)
+	}
+	if !reply.OK {
+		return fmt.Errorf("slack chat.postMessage failed: %s", reply.Error)
+	}
+	return nil
+}
+
+// slackBlocks renders button attachments as a Block Kit actions block under the text.
+// It returns nil when there are no buttons, leaving Slack to render the plain text.
+func slackBlocks(text string, attachments []models.Attachment) []map[string]interface{} {
+	var buttons []map[string]interface{}
+	for _, attachment := range attachments {
+		for _, b := range attachment.AllButtons() {
+			label := b.Title
+			if label == "" {
+				continue
+			}
+			button := map[string]interface{}{
+				"type":      "button",
+				"text":      map[string]interface{}{"type": "plain_text", "text": label},
+				"action_id": fmt.Sprintf("button_%d", len(buttons)),
+			}
+			if b.URL != "" {
+				button["url"] = b.URL
+			}
+			if b.Payload != "" {
+				button["value"] = b.Payload
+			}
+			buttons = append(buttons, button)
+		}
+	}
+	if len(buttons) == 0 {
+		return nil
+	}
+
+	// Slack allows at most 25 elements in an actions block
+	if len(buttons) > 25 {
+		buttons = buttons[:25]
+	}
+	elements := make([]interface{}, len(buttons))
+	for i, b := range buttons {
+		elements[i] = b
+	}
+	var blocks []map[string]interface{}
+	if text != "" {
+		blocks = append(blocks, map[string]interface{}{"type": "section", "text": map[string]interface{}{"type": "mrkdwn", "text": text}})
+	}
+	return append(blocks, map[string]interface{}{"type": "actions", "elements": elements})
+}
+
+func firstString(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := m[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// asMap converts a nested document, as built in memory or as decoded from MongoDB, to a map.
+func asMap(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t
+	case primitive.M:
+		return t
+	case primitive.D:
+		m := make(map[string]interface{}, len(t))
+		for _, e := range t {
+			m[e.Key] = e.Value
+		}
+		return m
+	}
+	return nil
+}
+
+// asSlice converts a nested array, as built in memory or as decoded from MongoDB, to a slice.
+func asSlice(v interface{}) []interface{} {
+	switch t := v.(type) {
+	case []interface{}:
+		return t
+	case primitive.A:
+		return t
+	case []map[string]interface{}:
+		s := make([]interface{}, len(t))
+		for i, m := range t {
+			s[i] = m
+		}
+		return s
+	}
+	return nil
+}
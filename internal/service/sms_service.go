@@ -0,0 +1,550 @@
+// Package service provides business logic for the Twilio SMS channel connector.
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	twilioAPIBaseURL = "https://api.twilio.com/2010-04-01"
+	// SMSStatusCallbackPath is where Twilio reports the delivery status of messages the connector sent
+	SMSStatusCallbackPath = "/api/v1/channels/twilio/sms/status"
+	// smsRecipientAttribute is the session attribute holding the number replies are texted to
+	smsRecipientAttribute = "sms_to"
+	// smsDefaultMaxSegments bounds what one reply costs when the channel doesn't set max_segments
+	smsDefaultMaxSegments = 3
+	smsMaxMedia           = 10
+	// GSM-7 characters; the extended ones take an escape character and count twice
+	gsm7Basic    = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	gsm7Extended = "^{}\\[~]|€\f"
+)
+
+var (
+	ErrSMSSignature       = errors.New("invalid twilio signature")
+	ErrSMSChannelNotFound = errors.New("no active sms channel matches the request")
+)
+
+// smsStatusRank orders Twilio message statuses so late callbacks can't move a message backwards.
+var smsStatusRank = map[string]int{
+	models.MessageDeliveryStatusSent:      1,
+	models.MessageDeliveryStatusDelivered: 2,
+	models.MessageDeliveryStatusRead:      3,
+	models.MessageDeliveryStatusFailed:    4,
+}
+
+// SMSService connects ClientChannels of type sms to Twilio Programmable Messaging. Each channel's
+// channel_config holds its account_sid, auth_token and phone_number (E.164), and optionally
+// messaging_service_sid, max_segments (default 3), webhook_url and ai_enabled (default true).
+type SMSService struct {
+	ClientRepo         *repository.ClientRepository
+	ClientChannelRepo  *repository.ClientChannelRepository
+	ChatSessionRepo    *repository.ChatSessionRepository
+	ChatMessageRepo    *repository.ChatMessageRepository
+	SessionService     *ChatSessionService
+	ChatMessageService *ChatMessageService
+	// LifecycleService, when set, applies the closed-session policy to inbound messages
+	LifecycleService *SessionLifecycleService
+	// SecretResolver resolves secret:// references in channel configs when they're loaded
+	SecretResolver *secrets.Resolver
+	// EventPublisher, when set, publishes chat_message_delivery_updated for status callbacks
+	EventPublisher *EventPublisherService
+	// StatusCallbackURL is sent with outbound messages; delivery statuses aren't tracked when empty
+	StatusCallbackURL string
+	APIBaseURL        string
+	logger            *zap.Logger
+	httpClient        *http.Client
+}
+
+// NewSMSService creates a new SMSService. publicAPIURL is the externally reachable base URL of
+// this API, used for Twilio's status callbacks.
+func NewSMSService(
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	sessionService *ChatSessionService,
+	chatMessageService *ChatMessageService,
+	publicAPIURL string,
+	logger *zap.Logger,
+) *SMSService {
+	statusCallbackURL := ""
+	if publicAPIURL != "" {
+		statusCallbackURL = strings.TrimRight(publicAPIURL, "/") + SMSStatusCallbackPath
+	}
+	return &SMSService{
+		ClientRepo:         clientRepo,
+		ClientChannelRepo:  clientChannelRepo,
+		ChatSessionRepo:    chatSessionRepo,
+		ChatMessageRepo:    chatMessageRepo,
+		SessionService:     sessionService,
+		ChatMessageService: chatMessageService,
+		StatusCallbackURL:  statusCallbackURL,
+		APIBaseURL:         twilioAPIBaseURL,
+		logger:             logger,
+		httpClient:         &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// HandleInbound verifies and processes an incoming message webhook. requestURL is the URL Twilio
+// called, which the signature covers along with params.
+func (s *SMSService) HandleInbound(ctx context.Context, requestURL, signature string, params url.Values) error {
+	if params.Get("To") == "" {
+		return ErrSMSChannelNotFound
+	}
+	channel, err := s.channelForNumber(ctx, params.Get("AccountSid"), params.Get("To"))
+	if err != nil {
+		return err
+	}
+	webhookURL, _ := channel.ChannelConfig["webhook_url"].(string)
+	if !verifyTwilioSignature(channel, requestURL, webhookURL, signature, params) {
+		return ErrSMSSignature
+	}
+
+	from, sid := params.Get("From"), params.Get("MessageSid")
+	text := strings.TrimSpace(params.Get("Body"))
+	attachments := twilioMedia(params)
+	if from == "" || sid == "" || (text == "" && len(attachments) == 0) {
+		return nil
+	}
+
+	client, err := s.ClientRepo.GetByID(ctx, channel.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	if !client.IsActive {
+		return nil
+	}
+
+	session, effectiveSessionID, err := s.SessionService.GetOrCreateSessionBySessionID(ctx, smsSessionID(channel, from), client, channel)
+	if err != nil {
+		return fmt.Errorf("failed to get or create session: %w", err)
+	}
+	if s.LifecycleService != nil {
+		session, err = s.LifecycleService.ResolveForMessage(ctx, session, client, channel)
+		if err != nil {
+			if errors.Is(err, ErrSessionClosed) {
+				return nil
+			}
+			return err
+		}
+		effectiveSessionID = session.SessionID
+	}
+
+	// Twilio retries webhooks that time out
+	dedupKey := models.MessageDedupKey(client.ID, channel.ID, sid)
+	if existing, err := s.ChatMessageService.FindDuplicate(ctx, dedupKey); err == nil && existing != nil {
+		telemetry.ObserveMessageDedup(string(channel.ChannelType))
+		return nil
+	}
+
+	if session.Attributes[smsRecipientAttribute] != from {
+		if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, map[string]string{smsRecipientAttribute: from}, nil); err != nil {
+			return fmt.Errorf("failed to store sms conversation on session: %w", err)
+		}
+	}
+
+	aiEnabled := true
+	if v, ok := channel.ChannelConfig["ai_enabled"].(bool); ok {
+		aiEnabled = v
+	}
+	msg := &models.ChatMessage{
+		ExternalID:  sid,
+		DedupKey:    dedupKey,
+		Sender:      from,
+		SenderType:  string(models.SenderTypeUser),
+		SessionID:   session.ID,
+		Text:        text,
+		Attachments: attachments,
+		Category:    models.MessageCategoryMessage,
+		Config:      map[string]interface{}{"ai_enabled": aiEnabled},
+		Data: map[string]interface{}{"sms": map[string]interface{}{
+			"message_sid":  sid,
+			"to":           params.Get("To"),
+			"from_city":    params.Get("FromCity"),
+			"from_country": params.Get("FromCountry"),
+			"num_segments": params.Get("NumSegments"),
+		}},
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		if errors.Is(err, ErrMessageDuplicate) {
+			telemetry.ObserveMessageDedup(string(channel.ChannelType))
+			return nil
+		}
+		return fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	if aiEnabled {
+		TriggerChatWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	}
+	return nil
+}
+
+// HandleStatus verifies a status callback and records the reported status on the message sent
+// with that MessageSid. Statuses that arrive out of order never downgrade the recorded one.
+func (s *SMSService) HandleStatus(ctx context.Context, requestURL, signature string, params url.Values) error {
+	// Messages sent through a messaging service may come from a number of the pool, not the channel's
+	channel, err := s.channelForNumber(ctx, params.Get("AccountSid"), params.Get("From"))
+	if errors.Is(err, ErrSMSChannelNotFound) {
+		channel, err = s.channelForNumber(ctx, params.Get("AccountSid"), "")
+	}
+	if err != nil {
+		return err
+	}
+	// Twilio signs the callback URL messages were sent with, not the inbound webhook_url
+	if !verifyTwilioSignature(channel, requestURL, s.StatusCallbackURL, signature, params) {
+		return ErrSMSSignature
+	}
+
+	sid := params.Get("MessageSid")
+	status := smsDeliveryStatus(params.Get("MessageStatus"))
+	if sid == "" || status == "" {
+		return nil
+	}
+	messages, err := s.ChatMessageRepo.List(ctx, bson.M{"external_id": sid}, 1)
+	if err != nil {
+		return fmt.Errorf("failed to find message %s: %w", sid, err)
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+	msg := &messages[0]
+	if smsStatusRank[status] <= smsStatusRank[msg.DeliveryStatus] {
+		return nil
+	}
+
+	update := bson.M{"delivery_status": status}
+	if status == models.MessageDeliveryStatusFailed {
+		update["delivery_error"] = "twilio " + params.Get("MessageStatus") + " (error " + params.Get("ErrorCode") + ")"
+	}
+	if err := s.ChatMessageRepo.Update(ctx, msg.ID, update); err != nil {
+		return fmt.Errorf("failed to update message delivery status: %w", err)
+	}
+
+	if s.EventPublisher != nil {
+		sessionID := msg.SessionID.Hex()
+		if _, err := s.EventPublisher.PublishChatMessageEvent(ctx, models.EventTypeChatMessageDeliveryUpdated, msg.ID.Hex(), &sessionID, map[string]interface{}{
+			"delivery_status": status,
+			"channel":         string(models.ChannelTypeSMS),
+			"external_id":     sid,
+			"error_code":      params.Get("ErrorCode"),
+		}); err != nil {
+			s.logger.Warn("Failed to publish sms delivery update", zap.String("message_id", msg.ID.Hex()), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// channelForNumber finds the active sms channel of a Twilio account and number. An empty number
+// matches any channel of the account.
+func (s *SMSService) channelForNumber(ctx context.Context, accountSID, number string) (*models.ClientChannel, error) {
+	if accountSID == "" {
+		return nil, ErrSMSChannelNotFound
+	}
+	filter := bson.M{
+		"channel_type":               models.ChannelTypeSMS,
+		"is_active":                  true,
+		"channel_config.account_sid": accountSID,
+	}
+	if number != "" {
+		filter["channel_config.phone_number"] = number
+	}
+	channels, err := s.ClientChannelRepo.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sms channels: %w", err)
+	}
+	if len(channels) == 0 {
+		return nil, ErrSMSChannelNotFound
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, &channels[0]); err != nil {
+		return nil, err
+	}
+	return &channels[0], nil
+}
+
+// verifyTwilioSignature checks X-Twilio-Signature: the base64 HMAC-SHA1, keyed with the auth token,
+// of the request URL followed by every parameter name and value sorted by name. publicURL, when set,
+// replaces requestURL, since a proxy may rewrite the public URL Twilio called.
+func verifyTwilioSignature(channel *models.ClientChannel, requestURL, publicURL, signature string, params url.Values) bool {
+	token, _ := channel.ChannelConfig["auth_token"].(string)
+	if token == "" || signature == "" {
+		return false
+	}
+	if publicURL != "" {
+		if _, query, ok := strings.Cut(requestURL, "?"); ok {
+			publicURL += "?" + query
+		}
+		requestURL = publicURL
+	}
+
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(requestURL)
+	for _, k := range keys {
+		for _, v := range params[k] {
+			b.WriteString(k)
+			b.WriteString(v)
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(token))
+	mac.Write([]byte(b.String()))
+	return hmac.Equal([]byte(base64.StdEncoding.EncodeToString(mac.Sum(nil))), []byte(signature))
+}
+
+// smsSessionID is the external session id of the conversation between a channel number and a phone.
+func smsSessionID(channel *models.ClientChannel, from string) string {
+	number, _ := channel.ChannelConfig["phone_number"].(string)
+	return "sms:" + number + ":" + from
+}
+
+// twilioMedia references the MMS media of an inbound message. The URLs are served by Twilio and
+// require the account credentials unless the account allows public media access.
+func twilioMedia(params url.Values) []models.Attachment {
+	count, _ := strconv.Atoi(params.Get("NumMedia"))
+	if count > smsMaxMedia {
+		count = smsMaxMedia
+	}
+	var attachments []models.Attachment
+	for i := 0; i < count; i++ {
+		mediaURL := params.Get("MediaUrl" + strconv.Itoa(i))
+		if mediaURL == "" {
+			continue
+		}
+		contentType := params.Get("MediaContentType" + strconv.Itoa(i))
+		attachmentType := "file"
+		if strings.HasPrefix(contentType, "image/") {
+			attachmentType = "image"
+		}
+		attachments = append(attachments, models.Attachment{
+			FileName: mediaURL[strings.LastIndex(mediaURL, "/")+1:],
+			FileType: contentType,
+			FileURL:  mediaURL,
+			Type:     attachmentType,
+		})
+	}
+	return attachments
+}
+
+// smsDeliveryStatus maps a Twilio MessageStatus to a message delivery status, or "" for statuses
+// that don't change it.
+func smsDeliveryStatus(status string) string {
+	switch status {
+	case "sent":
+		return models.MessageDeliveryStatusSent
+	case "delivered":
+		return models.MessageDeliveryStatusDelivered
+	case "read":
+		return models.MessageDeliveryStatusRead
+	case "failed", "undelivered":
+		return models.MessageDeliveryStatusFailed
+	}
+	return ""
+}
+
+// Deliver texts the message behind a chat_message_created event when it is a bot or agent reply
+// in a session of an sms channel, and keeps the Twilio sid so status callbacks can find it.
+func (s *SMSService) Deliver(ctx context.Context, eventData map[string]interface{}) error {
+	if eventType, _ := eventData["event_type"].(string); eventType != string(models.EventTypeChatMessageCreated) {
+		return nil
+	}
+	entityID, _ := eventData["entity_id"].(string)
+	messageID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q", entityID)
+	}
+
+	msg, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat message: %w", err)
+	}
+	if msg.SenderType != string(models.SenderTypeAssistant) && !strings.HasPrefix(msg.SenderType, "client:") {
+		return nil
+	}
+	if suggestion, _ := msg.Config["suggestion_mode"].(bool); suggestion {
+		return nil
+	}
+
+	session, err := s.ChatSessionRepo.GetByID(ctx, msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	to := session.Attributes[smsRecipientAttribute]
+	if session.ClientChannel == nil || to == "" {
+		return nil
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *session.ClientChannel)
+	if err != nil {
+		return fmt.Errorf("failed to get client channel: %w", err)
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return err
+	}
+	if channel.ChannelType != models.ChannelTypeSMS {
+		return nil
+	}
+
+	// SMS renders nothing but text, whatever the channel's capabilities say
+	text, _ := DegradeAttachments(msg.Text, msg.Attachments, models.ChannelCapabilities{})
+	body := truncateSMS(text, smsMaxSegments(channel))
+	if body == "" {
+		return nil
+	}
+
+	sid, status, err := s.SendSMS(ctx, channel, to, body)
+	if err != nil {
+		return err
+	}
+	update := bson.M{"external_id": sid}
+	if deliveryStatus := smsDeliveryStatus(status); deliveryStatus != "" {
+		update["delivery_status"] = deliveryStatus
+	}
+	if err := s.ChatMessageRepo.Update(ctx, msg.ID, update); err != nil {
+		// The text went out; only status tracking is lost
+		s.logger.Warn("Failed to store twilio message sid", zap.String("message_id", msg.ID.Hex()), zap.Error(err))
+	}
+	return nil
+}
+
+// SendSMS texts body to a phone number from the channel's number, or its messaging service when
+// messaging_service_sid is set. It returns the Twilio message sid and initial status.
+func (s *SMSService) SendSMS(ctx context.Context, channel *models.ClientChannel, to, body string) (string, string, error) {
+	accountSID, _ := channel.ChannelConfig["account_sid"].(string)
+	token, _ := channel.ChannelConfig["auth_token"].(string)
+	if accountSID == "" || token == "" {
+		return "", "", errors.New("sms channel is missing account_sid or auth_token")
+	}
+
+	form := url.Values{"To": {to}, "Body": {body}}
+	if serviceSID, _ := channel.ChannelConfig["messaging_service_sid"].(string); serviceSID != "" {
+		form.Set("MessagingServiceSid", serviceSID)
+	} else {
+		from, _ := channel.ChannelConfig["phone_number"].(string)
+		form.Set("From", from)
+	}
+	if s.StatusCallbackURL != "" {
+		form.Set("StatusCallback", s.StatusCallbackURL)
+	}
+
+	endpoint := s.APIBaseURL + "/Accounts/" + url.PathEscape(accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create twilio request: %w", err)
+	}
+	req.SetBasicAuth(accountSID, token)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", "", fmt.Errorf("twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		SID     string `json:"sid"`
+		Status  string `json:"status"`
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&reply); err != nil {
+		return "", "", fmt.Errorf("failed to decode twilio response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", "", fmt.Errorf("twilio request returned status %d: %s (code %d)", resp.StatusCode, reply.Message, reply.Code)
+	}
+	return reply.SID, reply.Status, nil
+}
+
+// smsMaxSegments reads max_segments, which is a float64 when set through the API and an integer
+// type when decoded from Mongo.
+func smsMaxSegments(channel *models.ClientChannel) int {
+	var n int
+	switch v := channel.ChannelConfig["max_segments"].(type) {
+	case float64:
+		n = int(v)
+	case int32:
+		n = int(v)
+	case int64:
+		n = int(v)
+	case int:
+		n = v
+	}
+	if n < 1 {
+		return smsDefaultMaxSegments
+	}
+	return n
+}
+
+// truncateSMS shortens text to fit in maxSegments SMS segments. GSM-7 text fits 160 characters in
+// one segment and 153 per segment once split; anything else is sent as UCS-2 with 70 and 67 code
+// units. Truncated text ends in "...".
+func truncateSMS(text string, maxSegments int) string {
+	gsm := true
+	for _, r := range text {
+		if !strings.ContainsRune(gsm7Basic, r) && !strings.ContainsRune(gsm7Extended, r) {
+			gsm = false
+			break
+		}
+	}
+	cost := func(r rune) int {
+		if gsm && strings.ContainsRune(gsm7Extended, r) {
+			return 2
+		}
+		if !gsm && r > 0xFFFF {
+			// Encoded as a UTF-16 surrogate pair
+			return 2
+		}
+		return 1
+	}
+	single, perSegment := 160, 153
+	if !gsm {
+		single, perSegment = 70, 67
+	}
+
+	total := 0
+	for _, r := range text {
+		total += cost(r)
+	}
+	if total <= single || total <= perSegment*maxSegments {
+		return text
+	}
+
+	capacity := perSegment * maxSegments
+	if maxSegments == 1 {
+		capacity = single
+	}
+	limit := capacity - len("...")
+	used := 0
+	var b strings.Builder
+	for _, r := range text {
+		if used+cost(r) > limit {
+			break
+		}
+		used += cost(r)
+		b.WriteRune(r)
+	}
+	return strings.TrimRight(b.String(), " \n") + "..."
+}
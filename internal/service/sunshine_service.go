@@ -0,0 +1,647 @@
+// Package service provides business logic for the Sunshine Conversations (Zendesk) channel connector.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	sunshineAPIBaseURL = "https://api.smooch.io"
+	// Session attributes identifying the Sunshine conversation and who controls it
+	sunshineConversationAttribute = "sunshine_conversation_id"
+	sunshineUserAttribute         = "sunshine_user_id"
+	sunshineControlAttribute      = "sunshine_control"
+	// sunshineControlPassed marks a conversation whose control was passed to another switchboard
+	// integration, such as the Zendesk agent workspace; the AI stays quiet until control comes back
+	sunshineControlPassed = "passed"
+	// sunshineDefaultHandoverTarget passes control to the switchboard's configured next integration
+	sunshineDefaultHandoverTarget = "next"
+	sunshineMaxCarouselItems      = 10
+	sunshineMaxItemActions        = 3
+)
+
+var (
+	ErrSunshineUnauthorized    = errors.New("invalid sunshine webhook secret")
+	ErrSunshineChannelNotFound = errors.New("no active sunshine channel matches the request")
+)
+
+// SunshineWebhook is the body of a Sunshine Conversations v2 webhook request.
+type SunshineWebhook struct {
+	App struct {
+		ID string `json:"id"`
+	} `json:"app"`
+	Events []SunshineEvent `json:"events"`
+}
+
+// SunshineEvent is one event of a webhook request; the content of Payload depends on Type.
+type SunshineEvent struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Payload struct {
+		Conversation SunshineConversation `json:"conversation"`
+		Message      *SunshineMessage     `json:"message,omitempty"`
+		Postback     *struct {
+			Payload    string `json:"payload"`
+			ActionName string `json:"actionName,omitempty"`
+		} `json:"postback,omitempty"`
+		User *struct {
+			ID string `json:"id"`
+		} `json:"user,omitempty"`
+	} `json:"payload"`
+}
+
+// SunshineConversation identifies a conversation and, when the webhook includes it, the
+// switchboard integration currently in control of it.
+type SunshineConversation struct {
+	ID                           string `json:"id"`
+	ActiveSwitchboardIntegration *struct {
+		Name            string `json:"name"`
+		IntegrationID   string `json:"integrationId"`
+		IntegrationType string `json:"integrationType"`
+	} `json:"activeSwitchboardIntegration,omitempty"`
+}
+
+// SunshineMessage is a message of a conversation:message event.
+type SunshineMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		UserID      string `json:"userId"`
+		DisplayName string `json:"displayName"`
+		Type        string `json:"type"`
+	} `json:"author"`
+	Content struct {
+		Type      string `json:"type"`
+		Text      string `json:"text"`
+		Payload   string `json:"payload,omitempty"`
+		MediaURL  string `json:"mediaUrl,omitempty"`
+		MediaType string `json:"mediaType,omitempty"`
+		AltText   string `json:"altText,omitempty"`
+	} `json:"content"`
+	Source struct {
+		Type string `json:"type"`
+	} `json:"source"`
+}
+
+// SunshineService connects ClientChannels of type sunshine to a Sunshine Conversations app. Each
+// channel's channel_config holds the app_id, the key_id and secret of an app API key and the
+// webhook_secret of the integration's webhook. Optional keys are api_base_url (for other regions
+// or a Zendesk subdomain), integration_id (this switchboard integration), handover_target (default
+// "next"), handover_metadata and ai_enabled (default true).
+//
+// Messages authored by the business, which include this connector's replies and Zendesk agents,
+// are not recorded.
+type SunshineService struct {
+	ClientRepo         *repository.ClientRepository
+	ClientChannelRepo  *repository.ClientChannelRepository
+	ChatSessionRepo    *repository.ChatSessionRepository
+	ChatMessageRepo    *repository.ChatMessageRepository
+	SessionService     *ChatSessionService
+	ChatMessageService *ChatMessageService
+	// LifecycleService, when set, applies the closed-session policy to inbound messages
+	LifecycleService *SessionLifecycleService
+	// SecretResolver resolves secret:// references in channel configs when they're loaded
+	SecretResolver *secrets.Resolver
+	logger         *zap.Logger
+	httpClient     *http.Client
+}
+
+// NewSunshineService creates a new SunshineService.
+func NewSunshineService(
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	sessionService *ChatSessionService,
+	chatMessageService *ChatMessageService,
+	logger *zap.Logger,
+) *SunshineService {
+	return &SunshineService{
+		ClientRepo:         clientRepo,
+		ClientChannelRepo:  clientChannelRepo,
+		ChatSessionRepo:    chatSessionRepo,
+		ChatMessageRepo:    chatMessageRepo,
+		SessionService:     sessionService,
+		ChatMessageService: chatMessageService,
+		logger:             logger,
+		httpClient:         &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// HandleWebhook verifies and processes a webhook request. apiKey is the X-API-Key header, which
+// Sunshine sets to the webhook's secret.
+func (s *SunshineService) HandleWebhook(ctx context.Context, apiKey string, body []byte) error {
+	var webhook SunshineWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return fmt.Errorf("invalid sunshine payload: %w", err)
+	}
+	channel, err := s.channelForApp(ctx, webhook.App.ID)
+	if err != nil {
+		return err
+	}
+	secret, _ := channel.ChannelConfig["webhook_secret"].(string)
+	if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(apiKey)) != 1 {
+		return ErrSunshineUnauthorized
+	}
+
+	for i := range webhook.Events {
+		event := &webhook.Events[i]
+		switch event.Type {
+		case "conversation:message":
+			err = s.handleMessage(ctx, channel, event)
+		case "conversation:postback":
+			err = s.handlePostback(ctx, channel, event)
+		case "switchboard:passControl", "switchboard:releaseControl":
+			err = s.handleControlChange(ctx, channel, event)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SunshineService) channelForApp(ctx context.Context, appID string) (*models.ClientChannel, error) {
+	if appID == "" {
+		return nil, ErrSunshineChannelNotFound
+	}
+	channels, err := s.ClientChannelRepo.List(ctx, bson.M{
+		"channel_type":          models.ChannelTypeSunshine,
+		"is_active":             true,
+		"channel_config.app_id": appID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sunshine channels: %w", err)
+	}
+	if len(channels) == 0 {
+		return nil, ErrSunshineChannelNotFound
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, &channels[0]); err != nil {
+		return nil, err
+	}
+	return &channels[0], nil
+}
+
+// sunshineSessionID is the external session id of a Sunshine conversation.
+func sunshineSessionID(channel *models.ClientChannel, conversationID string) string {
+	appID, _ := channel.ChannelConfig["app_id"].(string)
+	return "sunshine:" + appID + ":" + conversationID
+}
+
+// handleMessage stores a user message and triggers the AI workflow unless control of the
+// conversation has been passed to agents.
+func (s *SunshineService) handleMessage(ctx context.Context, channel *models.ClientChannel, event *SunshineEvent) error {
+	in := event.Payload.Message
+	if in == nil || in.Author.Type != "user" {
+		return nil
+	}
+	text := in.Content.Text
+	data := map[string]interface{}{
+		"message_id":   in.ID,
+		"content_type": in.Content.Type,
+		"source":       in.Source.Type,
+	}
+	if in.Content.Payload != "" {
+		data["reply_id"] = in.Content.Payload
+	}
+	var attachments []models.Attachment
+	switch in.Content.Type {
+	case "text":
+	case "image", "file":
+		if in.Content.MediaURL != "" {
+			attachments = append(attachments, models.Attachment{
+				FileName: in.Content.MediaURL[strings.LastIndex(in.Content.MediaURL, "/")+1:],
+				FileType: in.Content.MediaType,
+				FileURL:  in.Content.MediaURL,
+				Type:     in.Content.Type,
+			})
+		}
+	default:
+		// Locations, forms and other structured content have no chat representation
+		return nil
+	}
+	if text == "" && len(attachments) == 0 {
+		return nil
+	}
+	return s.recordUserMessage(ctx, channel, event.Payload.Conversation.ID, in.Author.UserID, in.Author.DisplayName, in.ID, text, attachments, data)
+}
+
+// handlePostback records a tapped postback button as a user message carrying its payload.
+func (s *SunshineService) handlePostback(ctx context.Context, channel *models.ClientChannel, event *SunshineEvent) error {
+	postback := event.Payload.Postback
+	if postback == nil || postback.Payload == "" {
+		return nil
+	}
+	userID := ""
+	if event.Payload.User != nil {
+		userID = event.Payload.User.ID
+	}
+	text := postback.ActionName
+	if text == "" {
+		text = postback.Payload
+	}
+	data := map[string]interface{}{"event_id": event.ID, "reply_id": postback.Payload}
+	return s.recordUserMessage(ctx, channel, event.Payload.Conversation.ID, userID, "", event.ID, text, nil, data)
+}
+
+func (s *SunshineService) recordUserMessage(
+	ctx context.Context,
+	channel *models.ClientChannel,
+	conversationID, userID, senderName, externalID, text string,
+	attachments []models.Attachment,
+	data map[string]interface{},
+) error {
+	if conversationID == "" {
+		return nil
+	}
+	client, err := s.ClientRepo.GetByID(ctx, channel.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	if !client.IsActive {
+		return nil
+	}
+
+	session, effectiveSessionID, err := s.SessionService.GetOrCreateSessionBySessionID(ctx, sunshineSessionID(channel, conversationID), client, channel)
+	if err != nil {
+		return fmt.Errorf("failed to get or create session: %w", err)
+	}
+	if s.LifecycleService != nil {
+		session, err = s.LifecycleService.ResolveForMessage(ctx, session, client, channel)
+		if err != nil {
+			if errors.Is(err, ErrSessionClosed) {
+				return nil
+			}
+			return err
+		}
+		effectiveSessionID = session.SessionID
+	}
+
+	// Sunshine retries webhooks that aren't acknowledged within its timeout
+	dedupKey := models.MessageDedupKey(client.ID, channel.ID, externalID)
+	if existing, err := s.ChatMessageService.FindDuplicate(ctx, dedupKey); err == nil && existing != nil {
+		telemetry.ObserveMessageDedup(string(channel.ChannelType))
+		return nil
+	}
+
+	if session.Attributes[sunshineConversationAttribute] != conversationID || (userID != "" && session.Attributes[sunshineUserAttribute] != userID) {
+		attributes := map[string]string{sunshineConversationAttribute: conversationID}
+		if userID != "" {
+			attributes[sunshineUserAttribute] = userID
+		}
+		if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, attributes, nil); err != nil {
+			return fmt.Errorf("failed to store sunshine conversation on session: %w", err)
+		}
+	}
+
+	aiEnabled := true
+	if v, ok := channel.ChannelConfig["ai_enabled"].(bool); ok {
+		aiEnabled = v
+	}
+	if session.Attributes[sunshineControlAttribute] == sunshineControlPassed {
+		aiEnabled = false
+	}
+	data["conversation_id"] = conversationID
+	msg := &models.ChatMessage{
+		ExternalID:  externalID,
+		DedupKey:    dedupKey,
+		Sender:      userID,
+		SenderName:  senderName,
+		SenderType:  string(models.SenderTypeUser),
+		SessionID:   session.ID,
+		Text:        text,
+		Attachments: attachments,
+		Category:    models.MessageCategoryMessage,
+		Config:      map[string]interface{}{"ai_enabled": aiEnabled},
+		Data:        map[string]interface{}{"sunshine": data},
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		if errors.Is(err, ErrMessageDuplicate) {
+			telemetry.ObserveMessageDedup(string(channel.ChannelType))
+			return nil
+		}
+		return fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	if aiEnabled {
+		TriggerChatWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	}
+	return nil
+}
+
+// handleControlChange clears the passed-control mark once the switchboard hands the conversation
+// back to this integration, so the AI answers again.
+func (s *SunshineService) handleControlChange(ctx context.Context, channel *models.ClientChannel, event *SunshineEvent) error {
+	conversation := event.Payload.Conversation
+	active := conversation.ActiveSwitchboardIntegration
+	if conversation.ID == "" || active == nil {
+		return nil
+	}
+	ours := !strings.HasPrefix(active.IntegrationType, "zd:")
+	if integrationID, _ := channel.ChannelConfig["integration_id"].(string); integrationID != "" {
+		ours = active.IntegrationID == integrationID
+	}
+	if !ours {
+		return nil
+	}
+
+	session, err := s.ChatSessionRepo.GetBySessionID(ctx, sunshineSessionID(channel, conversation.ID))
+	if err != nil || session.Attributes[sunshineControlAttribute] == "" {
+		return nil
+	}
+	if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, nil, []string{sunshineControlAttribute}); err != nil {
+		return fmt.Errorf("failed to clear sunshine control on session: %w", err)
+	}
+	return nil
+}
+
+// Deliver sends bot and agent replies of chat_message_created events to the Sunshine
+// conversation of their session, and passes control of the conversation on handover_requested
+// and chat_workflow_handover events.
+func (s *SunshineService) Deliver(ctx context.Context, eventData map[string]interface{}) error {
+	eventType, _ := eventData["event_type"].(string)
+	entityID, _ := eventData["entity_id"].(string)
+	entityObjID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return fmt.Errorf("invalid entity id %q", entityID)
+	}
+
+	switch models.EventType(eventType) {
+	case models.EventTypeChatMessageCreated:
+		return s.deliverMessage(ctx, entityObjID)
+	case models.EventTypeHandoverRequested:
+		handover := asMap(asMap(eventData["data"])["handover"])
+		return s.passControlForSession(ctx, entityObjID, firstString(handover, "reason"), firstString(handover, "target_queue"))
+	case models.EventTypeChatWorkflowHandover:
+		msg, err := s.ChatMessageRepo.GetByID(ctx, entityObjID)
+		if err != nil {
+			return fmt.Errorf("failed to get chat message: %w", err)
+		}
+		return s.passControlForSession(ctx, msg.SessionID, "ai_handover", "")
+	}
+	return nil
+}
+
+func (s *SunshineService) deliverMessage(ctx context.Context, messageID primitive.ObjectID) error {
+	msg, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat message: %w", err)
+	}
+	if msg.SenderType != string(models.SenderTypeAssistant) && !strings.HasPrefix(msg.SenderType, "client:") {
+		return nil
+	}
+	if suggestion, _ := msg.Config["suggestion_mode"].(bool); suggestion {
+		return nil
+	}
+
+	session, channel, err := s.sessionChannel(ctx, msg.SessionID)
+	if err != nil || channel == nil {
+		return err
+	}
+	text, attachments := DegradeAttachments(msg.Text, msg.Attachments, channel.EffectiveCapabilities())
+	for _, content := range sunshineContents(text, attachments) {
+		if _, err := s.SendMessage(ctx, channel, session.Attributes[sunshineConversationAttribute], content); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// passControlForSession passes control of the session's conversation to the channel's
+// handover_target. Sessions whose control was already passed are left alone, since the
+// workflow and the handover API can both request a handover for the same escalation.
+func (s *SunshineService) passControlForSession(ctx context.Context, sessionID primitive.ObjectID, reason, targetQueue string) error {
+	session, channel, err := s.sessionChannel(ctx, sessionID)
+	if err != nil || channel == nil {
+		return err
+	}
+	if session.Attributes[sunshineControlAttribute] == sunshineControlPassed {
+		return nil
+	}
+
+	metadata := map[string]interface{}{}
+	for k, v := range asMap(channel.ChannelConfig["handover_metadata"]) {
+		metadata[k] = v
+	}
+	if reason != "" {
+		metadata["reason"] = reason
+	}
+	if targetQueue != "" {
+		metadata["target_queue"] = targetQueue
+	}
+	target, _ := channel.ChannelConfig["handover_target"].(string)
+	if target == "" {
+		target = sunshineDefaultHandoverTarget
+	}
+	if err := s.PassControl(ctx, channel, session.Attributes[sunshineConversationAttribute], target, metadata); err != nil {
+		return err
+	}
+	if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, map[string]string{sunshineControlAttribute: sunshineControlPassed}, nil); err != nil {
+		return fmt.Errorf("control passed but failed to record it on session: %w", err)
+	}
+	return nil
+}
+
+// sessionChannel loads a session and its sunshine channel. The channel is nil when the session
+// doesn't belong to a Sunshine conversation.
+func (s *SunshineService) sessionChannel(ctx context.Context, sessionID primitive.ObjectID) (*models.ChatSession, *models.ClientChannel, error) {
+	session, err := s.ChatSessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	if session.ClientChannel == nil || session.Attributes[sunshineConversationAttribute] == "" {
+		return session, nil, nil
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *session.ClientChannel)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get client channel: %w", err)
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return nil, nil, err
+	}
+	if channel.ChannelType != models.ChannelTypeSunshine {
+		return session, nil, nil
+	}
+	return session, channel, nil
+}
+
+// SendMessage posts message content as the business to a conversation and returns the message id.
+func (s *SunshineService) SendMessage(ctx context.Context, channel *models.ClientChannel, conversationID string, content map[string]interface{}) (string, error) {
+	var reply struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	err := s.post(ctx, channel, "/conversations/"+url.PathEscape(conversationID)+"/messages", map[string]interface{}{
+		"author":  map[string]interface{}{"type": "business"},
+		"content": content,
+	}, &reply)
+	if err != nil {
+		return "", err
+	}
+	if len(reply.Messages) == 0 {
+		return "", nil
+	}
+	return reply.Messages[0].ID, nil
+}
+
+// PassControl hands a conversation to another switchboard integration, such as
+// "zd-agentWorkspace" for Zendesk agents, or "next" for the integration configured after this one.
+func (s *SunshineService) PassControl(ctx context.Context, channel *models.ClientChannel, conversationID, target string, metadata map[string]interface{}) error {
+	body := map[string]interface{}{"switchboardIntegration": target}
+	if len(metadata) > 0 {
+		body["metadata"] = metadata
+	}
+	return s.post(ctx, channel, "/conversations/"+url.PathEscape(conversationID)+"/passControl", body, nil)
+}
+
+// post calls an app-scoped endpoint of the Sunshine API with the channel's API key.
+func (s *SunshineService) post(ctx context.Context, channel *models.ClientChannel, path string, body interface{}, out interface{}) error {
+	appID, _ := channel.ChannelConfig["app_id"].(string)
+	keyID, _ := channel.ChannelConfig["key_id"].(string)
+	secret, _ := channel.ChannelConfig["secret"].(string)
+	if appID == "" || keyID == "" || secret == "" {
+		return errors.New("sunshine channel is missing app_id, key_id or secret")
+	}
+	baseURL, _ := channel.ChannelConfig["api_base_url"].(string)
+	if baseURL == "" {
+		baseURL = sunshineAPIBaseURL
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal sunshine request: %w", err)
+	}
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/v2/apps/" + url.PathEscape(appID) + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create sunshine request: %w", err)
+	}
+	req.SetBasicAuth(keyID, secret)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("sunshine request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sunshine returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to decode sunshine response: %w", err)
+		}
+	}
+	return nil
+}
+
+// sunshineContents renders a reply as Sunshine message contents: the text with its buttons as
+// actions, a carousel for carousel items, and one message per image or file.
+func sunshineContents(text string, attachments []models.Attachment) []map[string]interface{} {
+	var buttons []models.AttachmentButton
+	var items []map[string]interface{}
+	var media []map[string]interface{}
+	for _, attachment := range attachments {
+		buttons = append(buttons, attachment.AllButtons()...)
+		for _, carouselItem := range attachment.CarouselItems() {
+			if item := sunshineCarouselItem(carouselItem); item != nil && len(items) < sunshineMaxCarouselItems {
+				items = append(items, item)
+			}
+		}
+		if (attachment.Type == "image" || attachment.Type == "file") && isAbsoluteURL(attachment.FileURL) {
+			media = append(media, map[string]interface{}{"type": attachment.Type, "mediaUrl": attachment.FileURL})
+		}
+	}
+
+	var contents []map[string]interface{}
+	if text != "" {
+		content := map[string]interface{}{"type": "text", "text": text}
+		if actions := sunshineActions(buttons); len(actions) > 0 {
+			content["actions"] = actions
+		}
+		contents = append(contents, content)
+	}
+	if len(items) > 0 {
+		contents = append(contents, map[string]interface{}{"type": "carousel", "items": items})
+	}
+	return append(contents, media...)
+}
+
+// sunshineCarouselItem renders a carousel item, or returns nil for items without a title or any
+// action, which Sunshine rejects.
+func sunshineCarouselItem(item models.CarouselItem) map[string]interface{} {
+	title := item.Title
+	if title == "" {
+		return nil
+	}
+	actions := sunshineActions(item.Buttons)
+	if len(actions) == 0 && isAbsoluteURL(item.DefaultActionURL) {
+		actions = append(actions, map[string]interface{}{"type": "link", "text": title, "uri": item.DefaultActionURL})
+	}
+	if len(actions) == 0 {
+		return nil
+	}
+	if len(actions) > sunshineMaxItemActions {
+		actions = actions[:sunshineMaxItemActions]
+	}
+	rendered := map[string]interface{}{"title": title, "actions": actions}
+	if item.Description != "" {
+		rendered["description"] = item.Description
+	}
+	if item.MediaURL != "" {
+		rendered["mediaUrl"] = item.MediaURL
+	}
+	return rendered
+}
+
+// sunshineActions turns URL buttons into link actions and the rest into quick replies. Quick
+// replies can't be combined with other actions, so they become postbacks next to links.
+func sunshineActions(buttons []models.AttachmentButton) []map[string]interface{} {
+	hasLinks := false
+	for _, b := range buttons {
+		if isAbsoluteURL(b.URL) {
+			hasLinks = true
+		}
+	}
+
+	var actions []map[string]interface{}
+	for _, b := range buttons {
+		title := b.Title
+		if title == "" {
+			continue
+		}
+		if isAbsoluteURL(b.URL) {
+			actions = append(actions, map[string]interface{}{"type": "link", "text": title, "uri": b.URL})
+			continue
+		}
+		payload := b.Payload
+		if payload == "" {
+			payload = title
+		}
+		actionType := "reply"
+		if hasLinks {
+			actionType = "postback"
+		}
+		actions = append(actions, map[string]interface{}{"type": actionType, "text": title, "payload": payload})
+	}
+	return actions
+}
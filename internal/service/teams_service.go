@@ -0,0 +1,434 @@
+// Package service provides business logic for the native Microsoft Teams channel connector.
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/botframework"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// Session attributes holding the conversation reference used for proactive replies
+	teamsServiceURLAttribute     = "teams_service_url"
+	teamsConversationIDAttribute = "teams_conversation_id"
+	teamsBotIDAttribute          = "teams_bot_id"
+	adaptiveCardContentType      = "application/vnd.microsoft.card.adaptive"
+)
+
+var (
+	ErrTeamsUnauthorized    = errors.New("invalid teams request token")
+	ErrTeamsChannelNotFound = errors.New("no active teams channel matches the request")
+)
+
+// teamsMentionPattern matches mentions such as "<at>Support Bot</at>" in activity text
+var teamsMentionPattern = regexp.MustCompile(`<at>[^<]*</at>\s*`)
+
+// TeamsActivity is the subset of a Bot Framework activity the connector uses.
+type TeamsActivity struct {
+	Type         string       `json:"type"`
+	ID           string       `json:"id"`
+	ServiceURL   string       `json:"serviceUrl"`
+	ChannelID    string       `json:"channelId"`
+	From         TeamsAccount `json:"from"`
+	Recipient    TeamsAccount `json:"recipient"`
+	Conversation struct {
+		ID               string `json:"id"`
+		ConversationType string `json:"conversationType,omitempty"`
+		TenantID         string `json:"tenantId,omitempty"`
+	} `json:"conversation"`
+	Text string `json:"text,omitempty"`
+	// Value carries the data of an adaptive card Action.Submit
+	Value map[string]interface{} `json:"value,omitempty"`
+}
+
+// TeamsAccount identifies a user or bot in an activity.
+type TeamsAccount struct {
+	ID          string `json:"id"`
+	Name        string `json:"name,omitempty"`
+	AADObjectID string `json:"aadObjectId,omitempty"`
+}
+
+// TeamsService connects ClientChannels of type teams to Microsoft Teams through the Bot Framework.
+// Each channel's channel_config holds the bot's app_id and app_password, optionally tenant_id for
+// single-tenant bots and ai_enabled (default true) for messages received from Teams.
+type TeamsService struct {
+	ClientRepo         *repository.ClientRepository
+	ClientChannelRepo  *repository.ClientChannelRepository
+	ChatSessionRepo    *repository.ChatSessionRepository
+	ChatMessageRepo    *repository.ChatMessageRepository
+	SessionService     *ChatSessionService
+	ChatMessageService *ChatMessageService
+	// LifecycleService, when set, applies the closed-session policy to inbound messages
+	LifecycleService *SessionLifecycleService
+	// SecretResolver resolves secret:// references in channel configs when they're loaded
+	SecretResolver *secrets.Resolver
+	Authenticator  *botframework.Authenticator
+	TokenSource    *botframework.TokenSource
+	logger         *zap.Logger
+	httpClient     *http.Client
+}
+
+// NewTeamsService creates a new TeamsService.
+func NewTeamsService(
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	sessionService *ChatSessionService,
+	chatMessageService *ChatMessageService,
+	logger *zap.Logger,
+) *TeamsService {
+	return &TeamsService{
+		ClientRepo:         clientRepo,
+		ClientChannelRepo:  clientChannelRepo,
+		ChatSessionRepo:    chatSessionRepo,
+		ChatMessageRepo:    chatMessageRepo,
+		SessionService:     sessionService,
+		ChatMessageService: chatMessageService,
+		Authenticator:      botframework.NewAuthenticator(),
+		TokenSource:        botframework.NewTokenSource(),
+		logger:             logger,
+		httpClient:         &http.Client{Timeout: 15 * time.Second},
+	}
+}
+
+// HandleActivity authenticates and processes an activity posted to the bot's messaging endpoint.
+// The token's audience selects the channel, and its serviceurl claim must match the activity so
+// replies can't be redirected to another host.
+func (s *TeamsService) HandleActivity(ctx context.Context, authorization string, body []byte) error {
+	var activity TeamsActivity
+	if err := json.Unmarshal(body, &activity); err != nil {
+		return fmt.Errorf("invalid teams activity: %w", err)
+	}
+
+	claims, err := s.Authenticator.Verify(ctx, authorization, activity.ChannelID)
+	if err != nil {
+		if errors.Is(err, botframework.ErrUnauthorized) {
+			return ErrTeamsUnauthorized
+		}
+		return err
+	}
+	if claims.ServiceURL == "" || claims.ServiceURL != activity.ServiceURL {
+		return ErrTeamsUnauthorized
+	}
+
+	channels, err := s.ClientChannelRepo.List(ctx, bson.M{
+		"channel_type":          models.ChannelTypeTeams,
+		"is_active":             true,
+		"channel_config.app_id": claims.Audience,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list teams channels: %w", err)
+	}
+	if len(channels) == 0 {
+		return ErrTeamsChannelNotFound
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, &channels[0]); err != nil {
+		return err
+	}
+
+	// conversationUpdate, typing and invoke activities need no reply from the workflow
+	if activity.Type != "message" {
+		return nil
+	}
+	return s.handleMessage(ctx, &channels[0], &activity)
+}
+
+// handleMessage stores a user message from Teams, with the conversation reference needed to reply
+// later, and triggers the AI workflow.
+func (s *TeamsService) handleMessage(ctx context.Context, channel *models.ClientChannel, activity *TeamsActivity) error {
+	text := strings.TrimSpace(teamsMentionPattern.ReplaceAllString(activity.Text, ""))
+	data := map[string]interface{}{
+		"id":              activity.ID,
+		"conversation_id": activity.Conversation.ID,
+		"tenant_id":       activity.Conversation.TenantID,
+		"aad_object_id":   activity.From.AADObjectID,
+	}
+	if activity.Value != nil {
+		data["value"] = activity.Value
+		if text == "" {
+			text = firstString(activity.Value, "title", "text", "payload")
+		}
+		if reply := firstString(activity.Value, "payload", "value"); reply != "" {
+			data["reply_id"] = reply
+		}
+	}
+	if text == "" {
+		return nil
+	}
+
+	client, err := s.ClientRepo.GetByID(ctx, channel.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	if !client.IsActive {
+		return nil
+	}
+
+	session, effectiveSessionID, err := s.SessionService.GetOrCreateSessionBySessionID(ctx, "teams:"+activity.Conversation.ID, client, channel)
+	if err != nil {
+		return fmt.Errorf("failed to get or create session: %w", err)
+	}
+	if s.LifecycleService != nil {
+		session, err = s.LifecycleService.ResolveForMessage(ctx, session, client, channel)
+		if err != nil {
+			if errors.Is(err, ErrSessionClosed) {
+				return nil
+			}
+			return err
+		}
+		effectiveSessionID = session.SessionID
+	}
+
+	// The connector retries activities it didn't see acknowledged. Activity IDs are only unique
+	// within a conversation.
+	dedupKey := models.MessageDedupKey(client.ID, channel.ID, activity.Conversation.ID+":"+activity.ID)
+	if existing, err := s.ChatMessageService.FindDuplicate(ctx, dedupKey); err == nil && existing != nil {
+		telemetry.ObserveMessageDedup(string(channel.ChannelType))
+		return nil
+	}
+
+	reference := map[string]string{
+		teamsServiceURLAttribute:     activity.ServiceURL,
+		teamsConversationIDAttribute: activity.Conversation.ID,
+		teamsBotIDAttribute:          activity.Recipient.ID,
+	}
+	for key, value := range reference {
+		if session.Attributes[key] != value {
+			if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, reference, nil); err != nil {
+				return fmt.Errorf("failed to store teams conversation on session: %w", err)
+			}
+			break
+		}
+	}
+
+	aiEnabled := true
+	if v, ok := channel.ChannelConfig["ai_enabled"].(bool); ok {
+		aiEnabled = v
+	}
+	msg := &models.ChatMessage{
+		ExternalID: activity.ID,
+		DedupKey:   dedupKey,
+		Sender:     activity.From.ID,
+		SenderName: activity.From.Name,
+		SenderType: string(models.SenderTypeUser),
+		SessionID:  session.ID,
+		Text:       text,
+		Category:   models.MessageCategoryMessage,
+		Config:     map[string]interface{}{"ai_enabled": aiEnabled},
+		Data:       map[string]interface{}{"teams": data},
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		if errors.Is(err, ErrMessageDuplicate) {
+			telemetry.ObserveMessageDedup(string(channel.ChannelType))
+			return nil
+		}
+		return fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	if aiEnabled {
+		TriggerChatWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	}
+	return nil
+}
+
+// Deliver sends the message behind a chat_message_created event to Teams when it is a bot or
+// agent reply in a session of a Teams channel. Replies are proactive messages to the stored
+// conversation, so agent replies long after the user's message still arrive.
+func (s *TeamsService) Deliver(ctx context.Context, eventData map[string]interface{}) error {
+	if eventType, _ := eventData["event_type"].(string); eventType != string(models.EventTypeChatMessageCreated) {
+		return nil
+	}
+	entityID, _ := eventData["entity_id"].(string)
+	messageID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q", entityID)
+	}
+
+	msg, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat message: %w", err)
+	}
+	if msg.SenderType != string(models.SenderTypeAssistant) && !strings.HasPrefix(msg.SenderType, "client:") {
+		return nil
+	}
+	if suggestion, _ := msg.Config["suggestion_mode"].(bool); suggestion {
+		return nil
+	}
+
+	session, err := s.ChatSessionRepo.GetByID(ctx, msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	serviceURL := session.Attributes[teamsServiceURLAttribute]
+	conversationID := session.Attributes[teamsConversationIDAttribute]
+	if session.ClientChannel == nil || serviceURL == "" || conversationID == "" {
+		return nil
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *session.ClientChannel)
+	if err != nil {
+		return fmt.Errorf("failed to get client channel: %w", err)
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return err
+	}
+	if channel.ChannelType != models.ChannelTypeTeams {
+		return nil
+	}
+
+	text, attachments := DegradeAttachments(msg.Text, msg.Attachments, channel.EffectiveCapabilities())
+	_, err = s.SendActivity(ctx, channel, serviceURL, conversationID, session.Attributes[teamsBotIDAttribute], text, attachments)
+	return err
+}
+
+// SendActivity posts a message activity to a Teams conversation and returns its activity id.
+// Carousel and button attachments are sent as adaptive cards.
+func (s *TeamsService) SendActivity(ctx context.Context, channel *models.ClientChannel, serviceURL, conversationID, botID, text string, attachments []models.Attachment) (string, error) {
+	appID, _ := channel.ChannelConfig["app_id"].(string)
+	appPassword, _ := channel.ChannelConfig["app_password"].(string)
+	tenantID, _ := channel.ChannelConfig["tenant_id"].(string)
+	token, err := s.TokenSource.Token(ctx, appID, appPassword, tenantID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get bot framework token: %w", err)
+	}
+
+	activity := map[string]interface{}{
+		"type":       "message",
+		"text":       text,
+		"textFormat": "markdown",
+	}
+	if botID != "" {
+		activity["from"] = map[string]interface{}{"id": botID}
+	}
+	if cards, layout := teamsAttachments(attachments); len(cards) > 0 {
+		activity["attachments"] = cards
+		activity["attachmentLayout"] = layout
+	}
+	payload, err := json.Marshal(activity)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal teams activity: %w", err)
+	}
+
+	endpoint := strings.TrimSuffix(serviceURL, "/") + "/v3/conversations/" + url.PathEscape(conversationID) + "/activities"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create teams request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("teams request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("teams returned status %d: %s", resp.StatusCode, string(respBody))
+	}
+
+	var reply struct {
+		ID string `json:"id"`
+	}
+	_ = json.Unmarshal(respBody, &reply)
+	return reply.ID, nil
+}
+
+// teamsAttachments renders carousel items as a carousel of adaptive cards, buttons as a card of
+// actions and images as inline attachments. The layout is "carousel" when there are carousel items.
+func teamsAttachments(attachments []models.Attachment) ([]map[string]interface{}, string) {
+	var cards []map[string]interface{}
+	layout := "list"
+	for _, attachment := range attachments {
+		for _, item := range attachment.CarouselItems() {
+			var body []map[string]interface{}
+			if item.MediaURL != "" {
+				body = append(body, map[string]interface{}{"type": "Image", "url": item.MediaURL, "size": "Stretch"})
+			}
+			if item.Title != "" {
+				body = append(body, map[string]interface{}{"type": "TextBlock", "text": item.Title, "weight": "Bolder", "size": "Medium", "wrap": true})
+			}
+			if item.Description != "" {
+				body = append(body, map[string]interface{}{"type": "TextBlock", "text": item.Description, "wrap": true, "isSubtle": true})
+			}
+			card := adaptiveCard(body, teamsActions(item.Buttons))
+			if item.DefaultActionURL != "" {
+				card["selectAction"] = map[string]interface{}{"type": "Action.OpenUrl", "url": item.DefaultActionURL}
+			}
+			cards = append(cards, map[string]interface{}{"contentType": adaptiveCardContentType, "content": card})
+			layout = "carousel"
+		}
+
+		if actions := teamsActions(attachment.AllButtons()); len(actions) > 0 {
+			cards = append(cards, map[string]interface{}{"contentType": adaptiveCardContentType, "content": adaptiveCard(nil, actions)})
+		}
+
+		if attachment.Type == "image" && attachment.FileURL != "" {
+			contentType := attachment.FileType
+			if contentType == "" {
+				contentType = "image/*"
+			}
+			cards = append(cards, map[string]interface{}{"contentType": contentType, "contentUrl": attachment.FileURL, "name": attachment.FileName})
+		}
+	}
+	return cards, layout
+}
+
+func adaptiveCard(body, actions []map[string]interface{}) map[string]interface{} {
+	card := map[string]interface{}{
+		"type":    "AdaptiveCard",
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"version": "1.4",
+		"body":    body,
+	}
+	if body == nil {
+		card["body"] = []map[string]interface{}{}
+	}
+	if len(actions) > 0 {
+		card["actions"] = actions
+	}
+	return card
+}
+
+// teamsActions turns URL buttons into Action.OpenUrl and the rest into Action.Submit, whose data
+// comes back as the value of the next message activity.
+func teamsActions(buttons []models.AttachmentButton) []map[string]interface{} {
+	var actions []map[string]interface{}
+	for _, b := range buttons {
+		title := b.Title
+		if title == "" {
+			continue
+		}
+		if b.URL != "" {
+			actions = append(actions, map[string]interface{}{"type": "Action.OpenUrl", "title": title, "url": b.URL})
+			continue
+		}
+		payload := b.Payload
+		if payload == "" {
+			payload = title
+		}
+		actions = append(actions, map[string]interface{}{
+			"type":  "Action.Submit",
+			"title": title,
+			"data":  map[string]interface{}{"payload": payload, "title": title},
+		})
+	}
+	return actions
+}
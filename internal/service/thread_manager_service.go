@@ -22,6 +22,10 @@ type ThreadManagerService struct {
 	chatSessionCollection       *mongo.Collection
 	chatSessionThreadCollection *mongo.Collection
 	clientCollection           *mongo.Collection
+	// Flags is optional; when set, the threading flag decides for clients whose thread_config doesn't
+	Flags *FeatureFlagService
+	// Clients is optional; when set, clients and channels of existing sessions are read through it
+	Clients ClientCache
 }
 
 // NewThreadManagerService creates a new ThreadManagerService
@@ -57,44 +61,35 @@ func (tm *ThreadManagerService) GetBaseSessionIDForEvent(sessionID string) strin
 	return baseID
 }
 
-// IsThreadingEnabledForClient checks if threading is enabled for a client
+// IsThreadingEnabledForClient checks if threading is enabled for a client. The client's
+// thread_config decides when it sets enabled, otherwise the threading feature flag does.
 func (tm *ThreadManagerService) IsThreadingEnabledForClient(ctx context.Context, client *models.Client) bool {
 	if client == nil {
 		log.Printf("[ThreadManager] Client is nil, threading disabled")
 		return false
 	}
 
-	log.Printf("[ThreadManager] Checking threading for client %s (client_id: %s)", client.ID.Hex(), client.ClientID)
-	log.Printf("[ThreadManager] Client ThreadConfig: %+v", client.ThreadConfig)
-
-	// Check if thread_config exists at root level and is enabled
-	if client.ThreadConfig != nil {
-		log.Printf("[ThreadManager] Found ThreadConfig: %+v", client.ThreadConfig)
-		if enabled, ok := client.ThreadConfig["enabled"].(bool); ok {
-			log.Printf("[ThreadManager] Threading enabled: %v", enabled)
-			return enabled
-		} else {
-			log.Printf("[ThreadManager] 'enabled' field not found or not boolean in ThreadConfig")
-		}
-	} else {
-		log.Printf("[ThreadManager] No ThreadConfig found at root level")
-		
-		// Fallback: check if thread_config exists in Config field (for backward compatibility)
-		if threadConfig, exists := client.Config["thread_config"]; exists {
-			log.Printf("[ThreadManager] Found thread_config in Config: %+v", threadConfig)
-			if config, ok := threadConfig.(map[string]interface{}); ok {
-				log.Printf("[ThreadManager] thread_config is map: %+v", config)
-				if enabled, ok := config["enabled"].(bool); ok {
-					log.Printf("[ThreadManager] Threading enabled (from Config): %v", enabled)
-					return enabled
-				}
-			}
-		}
-		log.Printf("[ThreadManager] No thread_config found in either ThreadConfig or Config")
+	if client.ThreadConfig != nil && client.ThreadConfig.Enabled != nil {
+		log.Printf("[ThreadManager] Threading enabled for client %s (from thread_config): %v", client.ClientID, *client.ThreadConfig.Enabled)
+		return *client.ThreadConfig.Enabled
+	}
+	if tm.Flags != nil {
+		enabled := tm.Flags.IsEnabled(ctx, models.FeatureFlagThreading, client.ClientID)
+		log.Printf("[ThreadManager] Threading enabled for client %s (from feature flag): %v", client.ClientID, enabled)
+		return enabled
 	}
 	return false
 }
 
+// IsThreadingEnabled checks if threading is enabled for conversations on a client's channel. The
+// channel's thread_config decides when it sets enabled, otherwise the client's settings do.
+func (tm *ThreadManagerService) IsThreadingEnabled(ctx context.Context, client *models.Client, clientChannel *models.ClientChannel) bool {
+	if clientChannel != nil && clientChannel.ThreadConfig != nil && clientChannel.ThreadConfig.Enabled != nil {
+		return *clientChannel.ThreadConfig.Enabled
+	}
+	return tm.IsThreadingEnabledForClient(ctx, client)
+}
+
 // IsThreadingEnabledForClientID checks if threading is enabled for a client by ID
 func (tm *ThreadManagerService) IsThreadingEnabledForClientID(ctx context.Context, clientID string) (bool, error) {
 	clientObjID, err := primitive.ObjectIDFromHex(clientID)
@@ -102,8 +97,7 @@ func (tm *ThreadManagerService) IsThreadingEnabledForClientID(ctx context.Contex
 		return false, fmt.Errorf("invalid client ID: %w", err)
 	}
 
-	var client models.Client
-	err = tm.clientCollection.FindOne(ctx, bson.M{"_id": clientObjID}).Decode(&client)
+	client, err := tm.getClient(ctx, clientObjID)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return false, nil
@@ -111,7 +105,31 @@ func (tm *ThreadManagerService) IsThreadingEnabledForClientID(ctx context.Contex
 		return false, fmt.Errorf("failed to find client: %w", err)
 	}
 
-	return tm.IsThreadingEnabledForClient(ctx, &client), nil
+	return tm.IsThreadingEnabledForClient(ctx, client), nil
+}
+
+// getClient reads a client through the cache when there is one
+func (tm *ThreadManagerService) getClient(ctx context.Context, id primitive.ObjectID) (*models.Client, error) {
+	if tm.Clients != nil {
+		return tm.Clients.GetClientByID(ctx, id)
+	}
+	var client models.Client
+	if err := tm.clientCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&client); err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+// getClientChannel reads a client channel through the cache when there is one
+func (tm *ThreadManagerService) getClientChannel(ctx context.Context, id primitive.ObjectID) (*models.ClientChannel, error) {
+	if tm.Clients != nil {
+		return tm.Clients.GetChannel(ctx, id)
+	}
+	var channel models.ClientChannel
+	if err := tm.clientCollection.Database().Collection("client_channels").FindOne(ctx, bson.M{"_id": id}).Decode(&channel); err != nil {
+		return nil, err
+	}
+	return &channel, nil
 }
 
 // IsThreadingEnabledForSession checks if threading is enabled for a session
@@ -135,7 +153,19 @@ func (tm *ThreadManagerService) IsThreadingEnabledForSession(ctx context.Context
 	if session.Client == nil {
 		return false, nil
 	}
-	return tm.IsThreadingEnabledForClientID(ctx, session.Client.Hex())
+	client, err := tm.getClient(ctx, *session.Client)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to find client: %w", err)
+	}
+	var clientChannel *models.ClientChannel
+	if session.ClientChannel != nil {
+		// A channel that can't be read leaves the decision to the client's settings
+		clientChannel, _ = tm.getClientChannel(ctx, *session.ClientChannel)
+	}
+	return tm.IsThreadingEnabled(ctx, client, clientChannel), nil
 }
 
 // GetLatestThread gets the latest thread for a parent session
@@ -188,6 +218,7 @@ func (tm *ThreadManagerService) createFirstThread(ctx context.Context, baseSessi
 		Active:        true,
 		Client:        &client.ID,
 		ClientChannel: &clientChannel.ID,
+		Test:          client.IsSandbox(clientChannel),
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
@@ -222,6 +253,85 @@ func (tm *ThreadManagerService) createFirstThread(ctx context.Context, baseSessi
 	return session, nil
 }
 
+// InactivityMinutes returns how long threads on a client's channel stay active without messages:
+// the channel's thread_config inactivity_minutes when it sets one, otherwise the client's.
+func (tm *ThreadManagerService) InactivityMinutes(client *models.Client, clientChannel *models.ClientChannel) int {
+	if clientChannel != nil && clientChannel.ThreadConfig != nil && clientChannel.ThreadConfig.InactivityMinutes > 0 {
+		return clientChannel.ThreadConfig.InactivityMinutes
+	}
+	return tm.ClientInactivityMinutes(client)
+}
+
+// ClientInactivityMinutes returns how long a client's threads stay active without messages, read
+// from its thread_config and defaulting to DefaultThreadInactivityMinutes
+func (tm *ThreadManagerService) ClientInactivityMinutes(client *models.Client) int {
+	if client.ThreadConfig != nil && client.ThreadConfig.InactivityMinutes > 0 {
+		return client.ThreadConfig.InactivityMinutes
+	}
+	return models.DefaultThreadInactivityMinutes
+}
+
+// CloseInactiveThreads deactivates active threads idle for longer than their client's inactivity
+// window, or their channel's, looking at up to limit of the least recently active, and returns the
+// threads it closed. Threads of sessions without a client are left to the lookup in
+// GetOrCreateActiveThread.
+func (tm *ThreadManagerService) CloseInactiveThreads(ctx context.Context, limit int64) ([]models.ChatSessionThread, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "last_activity", Value: 1}}).SetLimit(limit)
+	cur, err := tm.chatSessionThreadCollection.Find(ctx, bson.M{"active": true}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list active threads: %w", err)
+	}
+	var threads []models.ChatSessionThread
+	if err := cur.All(ctx, &threads); err != nil {
+		return nil, fmt.Errorf("failed to decode active threads: %w", err)
+	}
+
+	// Windows are looked up once per client and channel
+	type source struct{ client, channel primitive.ObjectID }
+	windows := map[source]int{}
+	var closed []models.ChatSessionThread
+	for i := range threads {
+		thread := &threads[i]
+		var session models.ChatSession
+		if err := tm.chatSessionCollection.FindOne(ctx, bson.M{"_id": thread.ChatSessionID}).Decode(&session); err != nil || session.Client == nil {
+			continue
+		}
+		key := source{client: *session.Client}
+		if session.ClientChannel != nil {
+			key.channel = *session.ClientChannel
+		}
+		minutes, ok := windows[key]
+		if !ok {
+			client, err := tm.getClient(ctx, key.client)
+			if err != nil {
+				continue
+			}
+			var clientChannel *models.ClientChannel
+			if !key.channel.IsZero() {
+				clientChannel, _ = tm.getClientChannel(ctx, key.channel)
+			}
+			minutes = tm.InactivityMinutes(client, clientChannel)
+			windows[key] = minutes
+		}
+		if tm.IsThreadActive(thread, minutes) {
+			continue
+		}
+
+		// last_activity is kept so the thread's idle time stays visible
+		res, err := tm.chatSessionThreadCollection.UpdateOne(ctx,
+			bson.M{"_id": thread.ID, "active": true},
+			bson.M{"$set": bson.M{"active": false}})
+		if err != nil {
+			return closed, fmt.Errorf("failed to close thread %s: %w", thread.ID.Hex(), err)
+		}
+		if res.ModifiedCount > 0 {
+			thread.Active = false
+			closed = append(closed, *thread)
+		}
+	}
+	return closed, nil
+}
+
 // IsThreadActive checks if a thread is active based on inactivity minutes
 func (tm *ThreadManagerService) IsThreadActive(thread *models.ChatSessionThread, inactivityMinutes int) bool {
 	if thread == nil || !thread.Active {
@@ -229,7 +339,7 @@ func (tm *ThreadManagerService) IsThreadActive(thread *models.ChatSessionThread,
 	}
 
 	if inactivityMinutes <= 0 {
-		inactivityMinutes = 1440 // Default 24 hours
+		inactivityMinutes = models.DefaultThreadInactivityMinutes
 	}
 
 	inactivityDuration := time.Duration(inactivityMinutes) * time.Minute
@@ -332,6 +442,7 @@ func (tm *ThreadManagerService) CreateNewThread(ctx context.Context, baseSession
 		Active:        true,
 		Client:        &client.ID,
 		ClientChannel: &clientChannel.ID,
+		Test:          client.IsSandbox(clientChannel),
 		CreatedAt:     now,
 		UpdatedAt:     now,
 	}
@@ -394,10 +505,8 @@ func (tm *ThreadManagerService) GetOrCreateActiveThread(ctx context.Context, ses
 	if client == nil && sessionExists {
 		if existingSessions[0].Client != nil {
 			// Get client from existing session
-			var clientObj models.Client
-			err = tm.clientCollection.FindOne(ctx, bson.M{"_id": *existingSessions[0].Client}).Decode(&clientObj)
-			if err == nil {
-				client = &clientObj
+			if clientObj, err := tm.getClient(ctx, *existingSessions[0].Client); err == nil {
+				client = clientObj
 			}
 		}
 	}
@@ -406,10 +515,8 @@ func (tm *ThreadManagerService) GetOrCreateActiveThread(ctx context.Context, ses
 	if clientChannel == nil && sessionExists {
 		if existingSessions[0].ClientChannel != nil {
 			// Get client channel from existing session
-			var channelObj models.ClientChannel
-			err = tm.clientCollection.Database().Collection("client_channels").FindOne(ctx, bson.M{"_id": *existingSessions[0].ClientChannel}).Decode(&channelObj)
-			if err == nil {
-				clientChannel = &channelObj
+			if channelObj, err := tm.getClientChannel(ctx, *existingSessions[0].ClientChannel); err == nil {
+				clientChannel = channelObj
 			}
 		}
 	}
@@ -420,40 +527,11 @@ func (tm *ThreadManagerService) GetOrCreateActiveThread(ctx context.Context, ses
 	}
 
 	// Check if threading is enabled for this client
-	threadingEnabled := tm.IsThreadingEnabledForClient(ctx, client)
+	threadingEnabled := tm.IsThreadingEnabled(ctx, client, clientChannel)
 	var clientInactivityMinutes int
 
 	if threadingEnabled {
-		// Get inactivity minutes from client ThreadConfig (root level)
-		if client.ThreadConfig != nil {
-			if minutes, ok := client.ThreadConfig["inactivity_minutes"].(float64); ok {
-				clientInactivityMinutes = int(minutes)
-				log.Printf("[ThreadManager] Got inactivity_minutes from ThreadConfig (float64): %d", clientInactivityMinutes)
-			} else if minutes, ok := client.ThreadConfig["inactivity_minutes"].(int); ok {
-				clientInactivityMinutes = minutes
-				log.Printf("[ThreadManager] Got inactivity_minutes from ThreadConfig (int): %d", clientInactivityMinutes)
-			} else if minutes, ok := client.ThreadConfig["inactivity_minutes"].(int32); ok {
-				clientInactivityMinutes = int(minutes)
-				log.Printf("[ThreadManager] Got inactivity_minutes from ThreadConfig (int32): %d", clientInactivityMinutes)
-			} else {
-				log.Printf("[ThreadManager] Could not parse inactivity_minutes from ThreadConfig: %+v (type: %T)", client.ThreadConfig["inactivity_minutes"], client.ThreadConfig["inactivity_minutes"])
-			}
-		}
-		
-		// Fallback: check Config field for backward compatibility
-		if clientInactivityMinutes <= 0 {
-			if threadConfig, exists := client.Config["thread_config"]; exists {
-				if config, ok := threadConfig.(map[string]interface{}); ok {
-					if minutes, ok := config["inactivity_minutes"].(float64); ok {
-						clientInactivityMinutes = int(minutes)
-					}
-				}
-			}
-		}
-		
-		if clientInactivityMinutes <= 0 {
-			clientInactivityMinutes = 1440 // Default 24 hours
-		}
+		clientInactivityMinutes = tm.InactivityMinutes(client, clientChannel)
 		log.Printf("[ThreadManager] Threading enabled for client %s with inactivity_minutes=%d", client.ID.Hex(), clientInactivityMinutes)
 	} else {
 		log.Printf("[ThreadManager] Threading disabled for client %s", client.ID.Hex())
@@ -478,6 +556,7 @@ func (tm *ThreadManagerService) GetOrCreateActiveThread(ctx context.Context, ses
 				Active:        true,
 				Client:        &client.ID,
 				ClientChannel: &clientChannel.ID,
+				Test:          client.IsSandbox(clientChannel),
 				CreatedAt:     now,
 				UpdatedAt:     now,
 			}
@@ -571,4 +650,57 @@ func (tm *ThreadManagerService) ListThreads(ctx context.Context, parentSessionID
 	}
 
 	return threads, nil
-}
\ No newline at end of file
+}
+// GetOrCreateThread returns the session of a specific thread, creating it when it doesn't exist yet.
+// Unlike GetOrCreateActiveThread the caller picks the thread ID and other threads of the base session
+// stay active, for channels such as email where a user keeps several conversations open at once.
+func (tm *ThreadManagerService) GetOrCreateThread(ctx context.Context, baseSessionID, threadID string, client *models.Client, clientChannel *models.ClientChannel) (*models.ChatSession, error) {
+	threadSessionID := tm.FormatThreadSessionID(baseSessionID, threadID)
+	now := time.Now().UTC()
+
+	var thread models.ChatSessionThread
+	err := tm.chatSessionThreadCollection.FindOneAndUpdate(ctx,
+		bson.M{"thread_session_id": threadSessionID},
+		bson.M{"$set": bson.M{"active": true, "last_activity": now}},
+	).Decode(&thread)
+	if err == nil {
+		var session models.ChatSession
+		if err := tm.chatSessionCollection.FindOne(ctx, bson.M{"_id": thread.ChatSessionID}).Decode(&session); err != nil {
+			return nil, fmt.Errorf("failed to find threaded session: %w", err)
+		}
+		return &session, nil
+	}
+	if err != mongo.ErrNoDocuments {
+		return nil, fmt.Errorf("failed to get thread: %w", err)
+	}
+
+	session := &models.ChatSession{
+		SessionID:     threadSessionID,
+		Active:        true,
+		Client:        &client.ID,
+		ClientChannel: &clientChannel.ID,
+		Test:          client.IsSandbox(clientChannel),
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	result, err := tm.chatSessionCollection.InsertOne(ctx, session)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create threaded session: %w", err)
+	}
+	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
+		session.ID = oid
+	}
+
+	thread = models.ChatSessionThread{
+		ThreadID:        threadID,
+		ThreadSessionID: threadSessionID,
+		ParentSessionID: baseSessionID,
+		ChatSessionID:   session.ID,
+		Active:          true,
+		LastActivity:    now,
+	}
+	if _, err := tm.chatSessionThreadCollection.InsertOne(ctx, &thread); err != nil {
+		return nil, fmt.Errorf("failed to create thread tracking record: %w", err)
+	}
+	return session, nil
+}
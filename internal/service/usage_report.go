@@ -0,0 +1,185 @@
+// Package service provides business logic for daily usage reports and billing exports.
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+)
+
+// MaxUsageExportDays bounds the date range of a usage export
+const MaxUsageExportDays = 366
+
+// usageReportLookbackDays is how many days before today each report run covers, so days that
+// ended while no worker was running still get reported
+const usageReportLookbackDays = 3
+
+// usageExportHeader names the columns written by WriteUsageCSV
+var usageExportHeader = []string{"date", "client_id", "client_name", "metric", "quantity"}
+
+// ReportUsage publishes a usage_report event for every client's usage on each of the last few days
+// before now (UTC) that was not reported yet, and returns how many it published. A day is claimed
+// before its event is published, so concurrent runs on several workers report it once.
+func (s *UsageService) ReportUsage(ctx context.Context, now time.Time) (int, error) {
+	if s.Events == nil {
+		return 0, errors.New("usage reports need an event publisher")
+	}
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	published := 0
+	for i := usageReportLookbackDays; i >= 1; i-- {
+		n, err := s.reportDay(ctx, today.AddDate(0, 0, -i))
+		published += n
+		if err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
+
+func (s *UsageService) reportDay(ctx context.Context, day time.Time) (int, error) {
+	dd := day.Format("02")
+	usage, err := s.Repo.ListUnreported(ctx, day.Format("2006-01"), dd)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for i := range usage {
+		claimed, err := s.Repo.MarkReported(ctx, usage[i].ID, dd)
+		if err != nil {
+			return published, err
+		}
+		if !claimed {
+			continue
+		}
+		_, err = s.Events.PublishEvent(ctx, models.EventTypeUsageReport, models.EntityTypeClient, usage[i].Client.Hex(), nil, s.usageReportData(ctx, &usage[i], day))
+		if err != nil {
+			// Release the day so the next run reports it
+			if unmarkErr := s.Repo.UnmarkReported(ctx, usage[i].ID, dd); unmarkErr != nil {
+				s.logger.Error("Failed to release unreported usage day, it will not be reported",
+					zap.String("client", usage[i].Client.Hex()),
+					zap.String("date", day.Format("2006-01-02")),
+					zap.Error(unmarkErr))
+			}
+			return published, err
+		}
+		published++
+	}
+	return published, nil
+}
+
+// usageReportData builds the usage_report event data: the day's counts, the month's counts up to
+// and including that day, and the client's quota.
+func (s *UsageService) usageReportData(ctx context.Context, usage *models.ClientUsage, day time.Time) map[string]interface{} {
+	dd := day.Format("02")
+	monthToDate := map[string]int64{}
+	for d, counts := range usage.Daily {
+		if d > dd {
+			continue
+		}
+		for metric, n := range counts {
+			monthToDate[metric] += n
+		}
+	}
+
+	data := map[string]interface{}{
+		"date":          day.Format("2006-01-02"),
+		"period":        usage.Period,
+		"usage":         usage.Daily[dd],
+		"month_to_date": monthToDate,
+	}
+	if client, err := clientByID(ctx, s.Clients, s.ClientRepo, usage.Client); err == nil {
+		data["client_id"] = client.ClientID
+		if client.Quota != nil {
+			data["quota"] = client.Quota
+		}
+	}
+	return data
+}
+
+// UsageRecords returns daily usage from start to end (both dates inclusive, UTC), one record per
+// client, day and metric, ordered by date, client and metric. An empty clientID covers every
+// client.
+func (s *UsageService) UsageRecords(ctx context.Context, clientID string, start, end time.Time) ([]dto.UsageRecord, error) {
+	var clientObjID *primitive.ObjectID
+	if clientID != "" {
+		client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+		if err != nil {
+			return nil, ErrUsageClientNotFound
+		}
+		clientObjID = &client.ID
+	}
+	usage, err := s.Repo.ListPeriods(ctx, clientObjID, start.Format("2006-01"), end.Format("2006-01"))
+	if err != nil {
+		return nil, err
+	}
+
+	first, last := start.Format("2006-01-02"), end.Format("2006-01-02")
+	clients := map[primitive.ObjectID]*models.Client{}
+	records := []dto.UsageRecord{}
+	for i := range usage {
+		client, ok := clients[usage[i].Client]
+		if !ok {
+			// Usage outlives deleted clients; those are exported by ObjectID
+			var err error
+			if client, err = clientByID(ctx, s.Clients, s.ClientRepo, usage[i].Client); err != nil {
+				client = nil
+			}
+			clients[usage[i].Client] = client
+		}
+		record := dto.UsageRecord{ClientID: usage[i].Client.Hex()}
+		if client != nil {
+			record.ClientID, record.ClientName = client.ClientID, client.Name
+		}
+		for d, counts := range usage[i].Daily {
+			record.Date = usage[i].Period + "-" + d
+			if record.Date < first || record.Date > last {
+				continue
+			}
+			for metric, n := range counts {
+				record.Metric, record.Quantity = metric, n
+				records = append(records, record)
+			}
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.ClientID != b.ClientID {
+			return a.ClientID < b.ClientID
+		}
+		return a.Metric < b.Metric
+	})
+	return records, nil
+}
+
+// WriteUsageCSV writes records to w as CSV with a header row.
+func WriteUsageCSV(w io.Writer, records []dto.UsageRecord) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(usageExportHeader); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := []string{record.Date, csvSafe(record.ClientID), csvSafe(record.ClientName), record.Metric, strconv.FormatInt(record.Quantity, 10)}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
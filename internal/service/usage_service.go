@@ -0,0 +1,196 @@
+// Package service provides business logic for per-client usage metering and quotas.
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/cache"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Quota states reported per metric by GetUsage
+const (
+	QuotaStatusOK          = "ok"
+	QuotaStatusSoftReached = "soft_limit_reached"
+	QuotaStatusHardReached = "hard_limit_reached"
+)
+
+// MaxUsageMonths bounds how many months GetUsage returns
+const MaxUsageMonths = 24
+
+var (
+	// ErrQuotaExceeded is returned when a client has reached a hard limit of its quota.
+	ErrQuotaExceeded = errors.New("usage quota exceeded")
+	// ErrUsageClientNotFound is returned when the client of a usage request doesn't exist.
+	ErrUsageClientNotFound = errors.New("client not found")
+)
+
+// UsageService meters what each client uses per month and enforces client quotas. Metering
+// never fails the operation it counts: errors are logged, and a quota whose usage can't be read
+// lets the operation through.
+type UsageService struct {
+	Repo            *repository.ClientUsageRepository
+	ClientRepo      *repository.ClientRepository
+	ChatSessionRepo *repository.ChatSessionRepository
+	Invalidator     CacheInvalidator
+	Clients         ClientCache
+	// Events, when set, publishes the daily usage_report events
+	Events *EventPublisherService
+	logger *zap.Logger
+}
+
+// NewUsageService creates a new UsageService.
+func NewUsageService(repo *repository.ClientUsageRepository, clientRepo *repository.ClientRepository, chatSessionRepo *repository.ChatSessionRepository, logger *zap.Logger) *UsageService {
+	return &UsageService{
+		Repo:            repo,
+		ClientRepo:      clientRepo,
+		ChatSessionRepo: chatSessionRepo,
+		logger:          logger,
+	}
+}
+
+// SessionClient returns the ObjectID of the client owning a chat session, or the zero ObjectID for
+// sandbox sessions, which are not metered.
+func (s *UsageService) SessionClient(ctx context.Context, sessionID primitive.ObjectID) (primitive.ObjectID, error) {
+	session, err := s.ChatSessionRepo.GetByID(ctx, sessionID)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	if session.Test {
+		return primitive.NilObjectID, nil
+	}
+	if session.Client == nil {
+		return primitive.NilObjectID, fmt.Errorf("session %s has no client", sessionID.Hex())
+	}
+	return *session.Client, nil
+}
+
+// Allow returns ErrQuotaExceeded when the client has reached the hard limit of metric this month.
+func (s *UsageService) Allow(ctx context.Context, clientID primitive.ObjectID, metric string) error {
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, clientID)
+	if err != nil {
+		return nil
+	}
+	limit := client.Quota.Limit(metric)
+	if limit.Hard <= 0 {
+		return nil
+	}
+	usage, err := s.Repo.Get(ctx, clientID, time.Now().UTC().Format("2006-01"))
+	if err != nil {
+		s.logger.Warn("Failed to read usage, not enforcing quota",
+			zap.String("client_id", client.ClientID), zap.String("metric", metric), zap.Error(err))
+		return nil
+	}
+	if usage != nil && usage.Totals[metric] >= limit.Hard {
+		return fmt.Errorf("%w: %s reached the monthly limit of %d", ErrQuotaExceeded, metric, limit.Hard)
+	}
+	return nil
+}
+
+// Record adds counts to the client's usage for this month, and logs each metric that they take past
+// its soft limit.
+func (s *UsageService) Record(ctx context.Context, clientID primitive.ObjectID, counts map[string]int64) {
+	totals, err := s.Repo.Increment(ctx, clientID, counts, time.Now())
+	if err != nil {
+		s.logger.Warn("Failed to record usage", zap.String("client", clientID.Hex()), zap.Error(err))
+		return
+	}
+	client, err := clientByID(ctx, s.Clients, s.ClientRepo, clientID)
+	if err != nil {
+		return
+	}
+	for metric, n := range counts {
+		total := totals[metric]
+		if soft := client.Quota.Limit(metric).Soft; soft > 0 && total >= soft && total-n < soft {
+			s.logger.Warn("Client reached soft usage limit",
+				zap.String("client_id", client.ClientID),
+				zap.String("metric", metric),
+				zap.Int64("limit", soft),
+				zap.Int64("used", total))
+		}
+	}
+}
+
+// GetUsage returns a client's usage over the last months months, including the current one,
+// newest first, with its quota and where this month stands against it.
+func (s *UsageService) GetUsage(ctx context.Context, clientID string, months int) (*dto.ClientUsageResponse, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, ErrUsageClientNotFound
+	}
+
+	now := time.Now().UTC()
+	current := now.Format("2006-01")
+	first := time.Date(now.Year(), now.Month()-time.Month(months-1), 1, 0, 0, 0, 0, time.UTC).Format("2006-01")
+	usage, err := s.Repo.ListSince(ctx, client.ID, first)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &dto.ClientUsageResponse{
+		ClientID: client.ClientID,
+		Period:   current,
+		Quota:    client.Quota,
+		Months:   usage,
+	}
+	if client.Quota != nil {
+		var totals map[string]int64
+		if len(usage) > 0 && usage[0].Period == current {
+			totals = usage[0].Totals
+		}
+		resp.QuotaStatus = map[string]string{}
+		for _, metric := range []string{models.UsageMetricMessages, models.UsageMetricAICalls} {
+			limit := client.Quota.Limit(metric)
+			switch used := totals[metric]; {
+			case limit.Hard > 0 && used >= limit.Hard:
+				resp.QuotaStatus[metric] = QuotaStatusHardReached
+			case limit.Soft > 0 && used >= limit.Soft:
+				resp.QuotaStatus[metric] = QuotaStatusSoftReached
+			case limit.Hard > 0 || limit.Soft > 0:
+				resp.QuotaStatus[metric] = QuotaStatusOK
+			}
+		}
+	}
+	return resp, nil
+}
+
+// SetQuota validates and stores a client's quota, replacing any existing one.
+func (s *UsageService) SetQuota(ctx context.Context, clientID string, quota *models.UsageQuota) error {
+	for _, metric := range []string{models.UsageMetricMessages, models.UsageMetricAICalls} {
+		limit := quota.Limit(metric)
+		if limit.Soft < 0 || limit.Hard < 0 {
+			return fmt.Errorf("%s limits must not be negative", metric)
+		}
+		if limit.Soft > 0 && limit.Hard > 0 && limit.Soft > limit.Hard {
+			return fmt.Errorf("%s soft limit must not exceed its hard limit", metric)
+		}
+	}
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"quota": quota}); err != nil {
+		return ErrUsageClientNotFound
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+// DeleteQuota removes a client's quota; its usage is still metered.
+func (s *UsageService) DeleteQuota(ctx context.Context, clientID string) error {
+	if _, err := s.ClientRepo.Update(ctx, clientID, bson.M{"quota": nil}); err != nil {
+		return ErrUsageClientNotFound
+	}
+	s.invalidate(ctx, clientID)
+	return nil
+}
+
+func (s *UsageService) invalidate(ctx context.Context, clientID string) {
+	if s.Invalidator != nil {
+		_ = s.Invalidator.Invalidate(ctx, cache.KindClient, clientID)
+	}
+}
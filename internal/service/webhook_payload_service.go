@@ -68,18 +68,12 @@ func (s *MessagePayloadStrategy) CreatePayload(ctx context.Context, entityID str
 
 	// Add attachments if present
 	if len(message.Attachments) > 0 {
-		attachments := make([]map[string]interface{}, len(message.Attachments))
-		for i, attachment := range message.Attachments {
-			attachments[i] = map[string]interface{}{
-				"type":      attachment.Type,
-				"file_url":  attachment.FileURL,
-				"file_name": attachment.FileName,
-				"file_size": attachment.FileSize,
-				"file_type": attachment.FileType,
-				"carousel":  attachment.Carousel,
-			}
-		}
-		payload["attachments"] = attachments
+		payload["attachments"] = message.Attachments
+	}
+
+	// Add the knowledge sources of AI replies
+	if len(message.Citations) > 0 {
+		payload["citations"] = message.Citations
 	}
 
 	// Add confidence score if present
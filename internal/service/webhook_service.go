@@ -8,7 +8,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/fraiday-org/api-service/internal/egress"
 	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 	"go.uber.org/zap"
 )
 
@@ -24,19 +26,27 @@ func NewWebhookService(logger *zap.Logger, webhookPayloadService *WebhookPayload
 	return &WebhookService{
 		logger: logger,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout:   30 * time.Second,
+			Transport: telemetry.Transport(nil),
 		},
 		WebhookPayloadService: webhookPayloadService,
 	}
 }
 
+// SetEgressPolicy limits the hosts webhooks can reach to those policy allows, for webhook URLs
+// that clients choose.
+func (s *WebhookService) SetEgressPolicy(policy *egress.Policy) {
+	s.httpClient = newEgressClient(policy, 30*time.Second)
+}
+
 // WebhookPayload represents the payload structure for webhooks
 type WebhookPayload struct {
-	EventType  string                 `json:"event_type"`
-	EntityType string                 `json:"entity_type"`
-	EntityID   string                 `json:"entity_id"`
-	Data       map[string]interface{} `json:"data"`
-	Timestamp  time.Time              `json:"timestamp"`
+	SchemaVersion int                    `json:"schema_version,omitempty"` // Schema version of the event type's data
+	EventType     string                 `json:"event_type"`
+	EntityType    string                 `json:"entity_type"`
+	EntityID      string                 `json:"entity_id"`
+	Data          map[string]interface{} `json:"data"`
+	Timestamp     time.Time              `json:"timestamp"`
 }
 
 // SendWebhook sends a webhook notification to the specified URL
@@ -187,6 +197,9 @@ func (ws *WebhookService) SendEventWebhook(ctx context.Context, webhookURL, even
 		EntityID:   entityID,
 		Data:       eventData,
 	}
+	if schema, err := models.EventSchemaFor(models.EventType(eventType)); err == nil {
+		payload.SchemaVersion = schema.Version
+	}
 
 	return ws.SendWebhook(ctx, webhookURL, payload)
 }
\ No newline at end of file
@@ -0,0 +1,739 @@
+// Package service provides business logic for the native WhatsApp Cloud API channel connector.
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fraiday-org/api-service/internal/api/dto"
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/secrets"
+	"github.com/fraiday-org/api-service/internal/telemetry"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	whatsAppGraphBaseURL = "https://graph.facebook.com/v19.0"
+	// whatsAppMaxMediaBytes caps media downloads; the Cloud API allows documents up to 100MB
+	whatsAppMaxMediaBytes = 25 << 20
+	// whatsAppSessionWindow is how long after the user's last message free-form replies are allowed.
+	// Outside it only approved templates can be sent.
+	whatsAppSessionWindow = 24 * time.Hour
+	// Session attributes that tell the outbound adapter who to reply to and whether the window is open
+	whatsAppRecipientAttribute   = "whatsapp_to"
+	whatsAppLastInboundAttribute = "whatsapp_last_inbound_at"
+	// Interactive message limits of the Cloud API
+	whatsAppMaxReplyButtons  = 3
+	whatsAppMaxListRows      = 10
+	whatsAppButtonTitleLimit = 20
+	whatsAppRowTitleLimit    = 24
+)
+
+var (
+	ErrWhatsAppSignature       = errors.New("invalid whatsapp signature")
+	ErrWhatsAppVerifyToken     = errors.New("invalid whatsapp verify token")
+	ErrWhatsAppChannelNotFound = errors.New("no active whatsapp channel matches the request")
+)
+
+// WhatsAppWebhook is the body of a webhook request from the WhatsApp Cloud API.
+type WhatsAppWebhook struct {
+	Object string `json:"object"`
+	Entry  []struct {
+		ID      string `json:"id"`
+		Changes []struct {
+			Field string        `json:"field"`
+			Value WhatsAppValue `json:"value"`
+		} `json:"changes"`
+	} `json:"entry"`
+}
+
+// WhatsAppValue holds the messages received on one business phone number.
+type WhatsAppValue struct {
+	Metadata struct {
+		PhoneNumberID      string `json:"phone_number_id"`
+		DisplayPhoneNumber string `json:"display_phone_number"`
+	} `json:"metadata"`
+	Contacts []struct {
+		WaID    string `json:"wa_id"`
+		Profile struct {
+			Name string `json:"name"`
+		} `json:"profile"`
+	} `json:"contacts"`
+	Messages []WhatsAppMessage `json:"messages"`
+}
+
+// WhatsAppMessage is an inbound message. Exactly one of the content fields is set, matching Type.
+type WhatsAppMessage struct {
+	From      string `json:"from"`
+	ID        string `json:"id"`
+	Timestamp string `json:"timestamp"`
+	Type      string `json:"type"`
+	Text      *struct {
+		Body string `json:"body"`
+	} `json:"text,omitempty"`
+	// Button is a quick reply to a template message
+	Button *struct {
+		Text    string `json:"text"`
+		Payload string `json:"payload"`
+	} `json:"button,omitempty"`
+	Interactive *struct {
+		Type        string               `json:"type"`
+		ButtonReply *WhatsAppInteractive `json:"button_reply,omitempty"`
+		ListReply   *WhatsAppInteractive `json:"list_reply,omitempty"`
+	} `json:"interactive,omitempty"`
+	Image    *WhatsAppMedia `json:"image,omitempty"`
+	Document *WhatsAppMedia `json:"document,omitempty"`
+	Audio    *WhatsAppMedia `json:"audio,omitempty"`
+	Video    *WhatsAppMedia `json:"video,omitempty"`
+	Sticker  *WhatsAppMedia `json:"sticker,omitempty"`
+}
+
+// WhatsAppInteractive is the reply to a button or list message sent by the connector.
+type WhatsAppInteractive struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// WhatsAppMedia references media that has to be downloaded from the Graph API.
+type WhatsAppMedia struct {
+	ID       string `json:"id"`
+	MimeType string `json:"mime_type"`
+	Caption  string `json:"caption,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// WhatsAppService connects ClientChannels of type whatsapp to the WhatsApp Cloud API. Each
+// channel's channel_config holds its phone_number_id, access_token, app_secret and verify_token,
+// and optionally ai_enabled (default true), default_template and template_language.
+type WhatsAppService struct {
+	ClientRepo         *repository.ClientRepository
+	ClientChannelRepo  *repository.ClientChannelRepository
+	ChatSessionRepo    *repository.ChatSessionRepository
+	ChatMessageRepo    *repository.ChatMessageRepository
+	SessionService     *ChatSessionService
+	ChatMessageService *ChatMessageService
+	// LifecycleService, when set, applies the closed-session policy to inbound messages
+	LifecycleService *SessionLifecycleService
+	// SecretResolver resolves secret:// references in channel configs when they're loaded
+	SecretResolver *secrets.Resolver
+	// AttachmentService, when set, keeps inbound media; without it messages only reference the media id
+	AttachmentService *AttachmentService
+	GraphBaseURL      string
+	logger            *zap.Logger
+	httpClient        *http.Client
+}
+
+// NewWhatsAppService creates a new WhatsAppService.
+func NewWhatsAppService(
+	clientRepo *repository.ClientRepository,
+	clientChannelRepo *repository.ClientChannelRepository,
+	chatSessionRepo *repository.ChatSessionRepository,
+	chatMessageRepo *repository.ChatMessageRepository,
+	sessionService *ChatSessionService,
+	chatMessageService *ChatMessageService,
+	logger *zap.Logger,
+) *WhatsAppService {
+	return &WhatsAppService{
+		ClientRepo:         clientRepo,
+		ClientChannelRepo:  clientChannelRepo,
+		ChatSessionRepo:    chatSessionRepo,
+		ChatMessageRepo:    chatMessageRepo,
+		SessionService:     sessionService,
+		ChatMessageService: chatMessageService,
+		GraphBaseURL:       whatsAppGraphBaseURL,
+		logger:             logger,
+		httpClient:         &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// VerifyWebhook answers the subscription handshake Meta sends when the webhook URL is configured.
+// It returns the challenge to echo when token matches the verify_token of an active channel.
+func (s *WhatsAppService) VerifyWebhook(ctx context.Context, mode, token, challenge string) (string, error) {
+	if mode != "subscribe" || token == "" {
+		return "", ErrWhatsAppVerifyToken
+	}
+	channels, err := s.ClientChannelRepo.List(ctx, bson.M{
+		"channel_type":                models.ChannelTypeWhatsApp,
+		"is_active":                   true,
+		"channel_config.verify_token": token,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list whatsapp channels: %w", err)
+	}
+	if len(channels) == 0 {
+		return "", ErrWhatsAppVerifyToken
+	}
+	return challenge, nil
+}
+
+// HandleWebhook verifies and processes a webhook request. Each change is checked against the
+// app_secret of the channel owning its phone number. Status updates carry no messages and are ignored.
+func (s *WhatsAppService) HandleWebhook(ctx context.Context, signature string, body []byte) error {
+	var webhook WhatsAppWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return fmt.Errorf("invalid whatsapp payload: %w", err)
+	}
+
+	for _, entry := range webhook.Entry {
+		for _, change := range entry.Changes {
+			if change.Field != "messages" {
+				continue
+			}
+			channel, err := s.verifiedChannel(ctx, change.Value.Metadata.PhoneNumberID, signature, body)
+			if err != nil {
+				return err
+			}
+			for _, msg := range change.Value.Messages {
+				name := ""
+				for _, contact := range change.Value.Contacts {
+					if contact.WaID == msg.From {
+						name = contact.Profile.Name
+					}
+				}
+				if err := s.handleMessage(ctx, channel, name, &msg); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// verifiedChannel finds the active channel of phoneNumberID and checks that its app secret produced signature.
+func (s *WhatsAppService) verifiedChannel(ctx context.Context, phoneNumberID, signature string, body []byte) (*models.ClientChannel, error) {
+	channel, err := s.channelForPhoneNumber(ctx, phoneNumberID)
+	if err != nil {
+		return nil, err
+	}
+	secret, _ := channel.ChannelConfig["app_secret"].(string)
+	if secret == "" {
+		return nil, ErrWhatsAppSignature
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	if !hmac.Equal([]byte("sha256="+hex.EncodeToString(mac.Sum(nil))), []byte(signature)) {
+		return nil, ErrWhatsAppSignature
+	}
+	return channel, nil
+}
+
+func (s *WhatsAppService) channelForPhoneNumber(ctx context.Context, phoneNumberID string) (*models.ClientChannel, error) {
+	if phoneNumberID == "" {
+		return nil, ErrWhatsAppChannelNotFound
+	}
+	channels, err := s.ClientChannelRepo.List(ctx, bson.M{
+		"channel_type":                   models.ChannelTypeWhatsApp,
+		"is_active":                      true,
+		"channel_config.phone_number_id": phoneNumberID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list whatsapp channels: %w", err)
+	}
+	if len(channels) == 0 {
+		return nil, ErrWhatsAppChannelNotFound
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, &channels[0]); err != nil {
+		return nil, err
+	}
+	return &channels[0], nil
+}
+
+// whatsAppSessionID is the external session id of the conversation between a business number and a user.
+func whatsAppSessionID(channel *models.ClientChannel, waID string) string {
+	phoneNumberID, _ := channel.ChannelConfig["phone_number_id"].(string)
+	return "whatsapp:" + phoneNumberID + ":" + waID
+}
+
+// handleMessage stores a user message from WhatsApp and triggers the AI workflow. Message types
+// the chat can't represent, such as locations and reactions, are ignored.
+func (s *WhatsAppService) handleMessage(ctx context.Context, channel *models.ClientChannel, senderName string, in *WhatsAppMessage) error {
+	text, data, media := whatsAppContent(in)
+	if text == "" && media == nil {
+		return nil
+	}
+
+	client, err := s.ClientRepo.GetByID(ctx, channel.ClientID)
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	if !client.IsActive {
+		return nil
+	}
+
+	session, effectiveSessionID, err := s.SessionService.GetOrCreateSessionBySessionID(ctx, whatsAppSessionID(channel, in.From), client, channel)
+	if err != nil {
+		return fmt.Errorf("failed to get or create session: %w", err)
+	}
+	if s.LifecycleService != nil {
+		session, err = s.LifecycleService.ResolveForMessage(ctx, session, client, channel)
+		if err != nil {
+			if errors.Is(err, ErrSessionClosed) {
+				return nil
+			}
+			return err
+		}
+		effectiveSessionID = session.SessionID
+	}
+
+	// Meta redelivers webhooks it didn't see acknowledged in time
+	dedupKey := models.MessageDedupKey(client.ID, channel.ID, in.ID)
+	if existing, err := s.ChatMessageService.FindDuplicate(ctx, dedupKey); err == nil && existing != nil {
+		telemetry.ObserveMessageDedup(string(channel.ChannelType))
+		return nil
+	}
+
+	receivedAt := time.Now().UTC()
+	if ts, err := strconv.ParseInt(in.Timestamp, 10, 64); err == nil {
+		receivedAt = time.Unix(ts, 0).UTC()
+	}
+	if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, map[string]string{
+		whatsAppRecipientAttribute:   in.From,
+		whatsAppLastInboundAttribute: receivedAt.Format(time.RFC3339),
+	}, nil); err != nil {
+		return fmt.Errorf("failed to store whatsapp conversation on session: %w", err)
+	}
+
+	var attachments []models.Attachment
+	if media != nil {
+		data["media_id"] = media.ID
+		if attachment, err := s.downloadMedia(ctx, channel, in.Type, media); err != nil {
+			s.logger.Warn("Failed to download whatsapp media",
+				zap.String("media_id", media.ID),
+				zap.Error(err))
+		} else if attachment != nil {
+			attachments = append(attachments, *attachment)
+		}
+	}
+
+	aiEnabled := true
+	if v, ok := channel.ChannelConfig["ai_enabled"].(bool); ok {
+		aiEnabled = v
+	}
+	data["id"] = in.ID
+	data["type"] = in.Type
+	msg := &models.ChatMessage{
+		ExternalID:  in.ID,
+		DedupKey:    dedupKey,
+		Sender:      in.From,
+		SenderName:  senderName,
+		SenderType:  string(models.SenderTypeUser),
+		SessionID:   session.ID,
+		Text:        text,
+		Attachments: attachments,
+		Category:    models.MessageCategoryMessage,
+		Config:      map[string]interface{}{"ai_enabled": aiEnabled},
+		Data:        map[string]interface{}{"whatsapp": data},
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		if errors.Is(err, ErrMessageDuplicate) {
+			telemetry.ObserveMessageDedup(string(channel.ChannelType))
+			return nil
+		}
+		return fmt.Errorf("failed to create chat message: %w", err)
+	}
+
+	if aiEnabled {
+		TriggerChatWorkflow(ctx, msg.ID.Hex(), effectiveSessionID)
+	}
+	return nil
+}
+
+// whatsAppContent extracts the text, extra data and media of an inbound message.
+// Button and list replies become the title the user tapped, with the reply id kept in data.
+func whatsAppContent(in *WhatsAppMessage) (string, map[string]interface{}, *WhatsAppMedia) {
+	data := map[string]interface{}{}
+	switch in.Type {
+	case "text":
+		if in.Text != nil {
+			return in.Text.Body, data, nil
+		}
+	case "button":
+		if in.Button != nil {
+			data["reply_payload"] = in.Button.Payload
+			return in.Button.Text, data, nil
+		}
+	case "interactive":
+		if in.Interactive == nil {
+			break
+		}
+		reply := in.Interactive.ButtonReply
+		if reply == nil {
+			reply = in.Interactive.ListReply
+		}
+		if reply != nil {
+			data["reply_id"] = reply.ID
+			return reply.Title, data, nil
+		}
+	case "image", "document", "audio", "video", "sticker":
+		media := map[string]*WhatsAppMedia{
+			"image":    in.Image,
+			"document": in.Document,
+			"audio":    in.Audio,
+			"video":    in.Video,
+			"sticker":  in.Sticker,
+		}[in.Type]
+		if media != nil {
+			return media.Caption, data, media
+		}
+	}
+	return "", data, nil
+}
+
+// downloadMedia resolves a media id to its short-lived URL, downloads it and keeps it in attachment storage.
+func (s *WhatsAppService) downloadMedia(ctx context.Context, channel *models.ClientChannel, messageType string, media *WhatsAppMedia) (*models.Attachment, error) {
+	if s.AttachmentService == nil {
+		return nil, nil
+	}
+	token, _ := channel.ChannelConfig["access_token"].(string)
+
+	var info struct {
+		URL      string `json:"url"`
+		MimeType string `json:"mime_type"`
+		FileSize int64  `json:"file_size"`
+	}
+	resp, err := s.graphRequest(ctx, http.MethodGet, s.GraphBaseURL+"/"+media.ID, token, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&info)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode media info: %w", err)
+	}
+	if info.FileSize > whatsAppMaxMediaBytes {
+		return nil, fmt.Errorf("media is %d bytes, over the %d byte limit", info.FileSize, whatsAppMaxMediaBytes)
+	}
+
+	resp, err = s.graphRequest(ctx, http.MethodGet, info.URL, token, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	content, err := io.ReadAll(io.LimitReader(resp.Body, whatsAppMaxMediaBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download media: %w", err)
+	}
+	if len(content) > whatsAppMaxMediaBytes {
+		return nil, fmt.Errorf("media is over the %d byte limit", whatsAppMaxMediaBytes)
+	}
+
+	contentType := media.MimeType
+	if contentType == "" {
+		contentType = info.MimeType
+	}
+	fileName := media.Filename
+	if fileName == "" {
+		fileName = media.ID
+		if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+			fileName += exts[0]
+		}
+	}
+	attachmentType := "file"
+	if messageType == "image" || messageType == "sticker" {
+		attachmentType = "image"
+	}
+	return s.AttachmentService.Store(fileName, contentType, attachmentType, bson.M{
+		"source":     "whatsapp",
+		"media_id":   media.ID,
+		"channel_id": channel.ID,
+	}, bytes.NewReader(content))
+}
+
+// Deliver sends the message behind a chat_message_created event to WhatsApp when it is a bot or
+// agent reply in a session of a WhatsApp channel. Replies outside the 24-hour window fall back to
+// the channel's default_template and fail without one.
+func (s *WhatsAppService) Deliver(ctx context.Context, eventData map[string]interface{}) error {
+	if eventType, _ := eventData["event_type"].(string); eventType != string(models.EventTypeChatMessageCreated) {
+		return nil
+	}
+	entityID, _ := eventData["entity_id"].(string)
+	messageID, err := primitive.ObjectIDFromHex(entityID)
+	if err != nil {
+		return fmt.Errorf("invalid message id %q", entityID)
+	}
+
+	msg, err := s.ChatMessageRepo.GetByID(ctx, messageID)
+	if err != nil {
+		return fmt.Errorf("failed to get chat message: %w", err)
+	}
+	if msg.SenderType != string(models.SenderTypeAssistant) && !strings.HasPrefix(msg.SenderType, "client:") {
+		return nil
+	}
+	if suggestion, _ := msg.Config["suggestion_mode"].(bool); suggestion {
+		return nil
+	}
+
+	session, err := s.ChatSessionRepo.GetByID(ctx, msg.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	to := session.Attributes[whatsAppRecipientAttribute]
+	if session.ClientChannel == nil || to == "" {
+		return nil
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, *session.ClientChannel)
+	if err != nil {
+		return fmt.Errorf("failed to get client channel: %w", err)
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return err
+	}
+	if channel.ChannelType != models.ChannelTypeWhatsApp {
+		return nil
+	}
+
+	lastInbound, _ := time.Parse(time.RFC3339, session.Attributes[whatsAppLastInboundAttribute])
+	if time.Since(lastInbound) > whatsAppSessionWindow {
+		template, _ := channel.ChannelConfig["default_template"].(string)
+		if template == "" {
+			return errors.New("whatsapp customer service window has closed and the channel has no default_template")
+		}
+		_, err := s.SendTemplate(ctx, channel, to, template, "", nil)
+		return err
+	}
+
+	text, attachments := DegradeAttachments(msg.Text, msg.Attachments, channel.EffectiveCapabilities())
+	_, err = s.SendMessage(ctx, channel, to, text, attachments)
+	return err
+}
+
+// SendMessage sends text to a WhatsApp user, rendering button attachments as reply buttons, or as a
+// list when there are more than WhatsApp allows on a button message. It returns the WhatsApp message id.
+func (s *WhatsAppService) SendMessage(ctx context.Context, channel *models.ClientChannel, to, text string, attachments []models.Attachment) (string, error) {
+	payload := map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                to,
+	}
+	if interactive := whatsAppInteractive(text, attachments); interactive != nil {
+		payload["type"] = "interactive"
+		payload["interactive"] = interactive
+	} else {
+		payload["type"] = "text"
+		payload["text"] = map[string]interface{}{"body": text}
+	}
+	return s.send(ctx, channel, payload)
+}
+
+// SendTemplate sends an approved template message, the only kind WhatsApp accepts outside the
+// 24-hour window. An empty language uses the channel's template_language, then en_US.
+func (s *WhatsAppService) SendTemplate(ctx context.Context, channel *models.ClientChannel, to, name, language string, components []map[string]interface{}) (string, error) {
+	if language == "" {
+		language, _ = channel.ChannelConfig["template_language"].(string)
+	}
+	if language == "" {
+		language = "en_US"
+	}
+	template := map[string]interface{}{
+		"name":     name,
+		"language": map[string]interface{}{"code": language},
+	}
+	if len(components) > 0 {
+		template["components"] = components
+	}
+	return s.send(ctx, channel, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"to":                to,
+		"type":              "template",
+		"template":          template,
+	})
+}
+
+// SendTemplateMessage sends a template to a user through one of a client's WhatsApp channels and
+// records it in the conversation's session as a system message, so channel processors don't resend it.
+func (s *WhatsAppService) SendTemplateMessage(ctx context.Context, clientID, channelID string, req *dto.WhatsAppTemplateSendRequest) (*models.ChatMessage, error) {
+	client, err := s.ClientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, ErrWhatsAppChannelNotFound
+	}
+	channelObjID, err := primitive.ObjectIDFromHex(channelID)
+	if err != nil {
+		return nil, ErrWhatsAppChannelNotFound
+	}
+	channel, err := s.ClientChannelRepo.GetByID(ctx, channelObjID)
+	if err != nil || channel.ClientID != client.ID || channel.ChannelType != models.ChannelTypeWhatsApp || !channel.IsActive {
+		return nil, ErrWhatsAppChannelNotFound
+	}
+	if err := resolveChannelSecrets(ctx, s.SecretResolver, channel); err != nil {
+		return nil, err
+	}
+
+	wamid, err := s.SendTemplate(ctx, channel, req.To, req.TemplateName, req.Language, req.Components)
+	if err != nil {
+		return nil, err
+	}
+
+	session, _, err := s.SessionService.GetOrCreateSessionBySessionID(ctx, whatsAppSessionID(channel, req.To), client, channel)
+	if err != nil {
+		return nil, fmt.Errorf("template sent but failed to get or create session: %w", err)
+	}
+	if session.Attributes[whatsAppRecipientAttribute] != req.To {
+		if _, err := s.ChatSessionRepo.UpdateAttributes(ctx, session.ID, map[string]string{whatsAppRecipientAttribute: req.To}, nil); err != nil {
+			return nil, fmt.Errorf("template sent but failed to store whatsapp conversation on session: %w", err)
+		}
+	}
+
+	text := req.Text
+	if text == "" {
+		text = req.TemplateName
+	}
+	msg := &models.ChatMessage{
+		ExternalID: wamid,
+		Sender:     "whatsapp_template",
+		SenderType: string(models.SenderTypeSystem),
+		SessionID:  session.ID,
+		Text:       text,
+		Category:   models.MessageCategoryMessage,
+		Config:     map[string]interface{}{"ai_enabled": false},
+		Data: map[string]interface{}{"whatsapp_template": map[string]interface{}{
+			"name":       req.TemplateName,
+			"language":   req.Language,
+			"components": req.Components,
+		}},
+	}
+	if err := s.ChatMessageService.CreateChatMessage(ctx, msg); err != nil {
+		return nil, fmt.Errorf("template sent but failed to record chat message: %w", err)
+	}
+	return msg, nil
+}
+
+// send posts a message payload to the channel's phone number and returns the WhatsApp message id.
+func (s *WhatsAppService) send(ctx context.Context, channel *models.ClientChannel, payload map[string]interface{}) (string, error) {
+	phoneNumberID, _ := channel.ChannelConfig["phone_number_id"].(string)
+	token, _ := channel.ChannelConfig["access_token"].(string)
+	if phoneNumberID == "" || token == "" {
+		return "", errors.New("whatsapp channel is missing phone_number_id or access_token")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal whatsapp message: %w", err)
+	}
+	resp, err := s.graphRequest(ctx, http.MethodPost, s.GraphBaseURL+"/"+phoneNumberID+"/messages", token, body)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&reply); err != nil {
+		return "", fmt.Errorf("failed to decode whatsapp response: %w", err)
+	}
+	if len(reply.Messages) == 0 {
+		return "", errors.New("whatsapp response has no message id")
+	}
+	return reply.Messages[0].ID, nil
+}
+
+// graphRequest calls the Graph API with the channel's token. Non-2xx responses are returned as
+// errors carrying the API's error message.
+func (s *WhatsAppService) graphRequest(ctx context.Context, method, url, token string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create whatsapp request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("whatsapp request failed: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	var apiErr struct {
+		Error struct {
+			Message string `json:"message"`
+			Code    int    `json:"code"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&apiErr)
+	return nil, fmt.Errorf("whatsapp request returned status %d: %s (code %d)", resp.StatusCode, apiErr.Error.Message, apiErr.Error.Code)
+}
+
+// whatsAppInteractive renders button attachments as an interactive message, or returns nil when
+// there are no buttons or no text to put in the required body.
+func whatsAppInteractive(text string, attachments []models.Attachment) map[string]interface{} {
+	type option struct{ id, title string }
+	var options []option
+	for _, attachment := range attachments {
+		for _, b := range attachment.AllButtons() {
+			title := b.Title
+			if title == "" {
+				continue
+			}
+			id := b.Payload
+			if id == "" {
+				id = title
+			}
+			options = append(options, option{id: id, title: title})
+		}
+	}
+	if len(options) == 0 || text == "" {
+		return nil
+	}
+
+	body := map[string]interface{}{"text": text}
+	if len(options) <= whatsAppMaxReplyButtons {
+		buttons := make([]map[string]interface{}, len(options))
+		for i, o := range options {
+			buttons[i] = map[string]interface{}{
+				"type":  "reply",
+				"reply": map[string]interface{}{"id": o.id, "title": truncateRunes(o.title, whatsAppButtonTitleLimit)},
+			}
+		}
+		return map[string]interface{}{
+			"type":   "button",
+			"body":   body,
+			"action": map[string]interface{}{"buttons": buttons},
+		}
+	}
+
+	if len(options) > whatsAppMaxListRows {
+		options = options[:whatsAppMaxListRows]
+	}
+	rows := make([]map[string]interface{}, len(options))
+	for i, o := range options {
+		rows[i] = map[string]interface{}{"id": o.id, "title": truncateRunes(o.title, whatsAppRowTitleLimit)}
+	}
+	return map[string]interface{}{
+		"type": "list",
+		"body": body,
+		"action": map[string]interface{}{
+			"button":   "Options",
+			"sections": []map[string]interface{}{{"rows": rows}},
+		},
+	}
+}
+
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit])
+}
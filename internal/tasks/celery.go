@@ -0,0 +1,344 @@
+package tasks
+
+import (
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	"github.com/fraiday-org/api-service/internal/config"
+)
+
+// Celery message protocols. Protocol 1 carries the task's metadata in a JSON object body;
+// protocol 2 carries it in the message headers and the body is [args, kwargs, embed]. Celery tells
+// them apart by the task header, which protocol 1 messages must not have.
+const (
+	CeleryProtocolV1 = 1
+	CeleryProtocolV2 = 2
+)
+
+// celeryTimeLayout is the ISO 8601 format Python's isoformat produces for aware datetimes
+const celeryTimeLayout = "2006-01-02T15:04:05.000000-07:00"
+
+// celeryTask is a task as carried by a Celery message
+type celeryTask struct {
+	ID       string
+	Task     string
+	Args     []interface{}
+	Kwargs   map[string]interface{}
+	Retries  int
+	ETA      *time.Time
+	Expires  *time.Time
+	RootID   string
+	ParentID string
+}
+
+// celeryOrigin names this process in the origin header, as Celery does
+var celeryOrigin = func() string {
+	host, _ := os.Hostname()
+	return fmt.Sprintf("gen%d@%s", os.Getpid(), host)
+}()
+
+// encodeCeleryMessage returns a publishing carrying task in protocol. headers are added to the
+// message's headers, which in protocol 2 also hold the task's metadata.
+func encodeCeleryMessage(task celeryTask, protocol int, headers amqp.Table) (amqp.Publishing, error) {
+	if task.Args == nil {
+		task.Args = []interface{}{}
+	}
+	if headers == nil {
+		headers = amqp.Table{}
+	}
+
+	var body interface{}
+	if protocol == CeleryProtocolV2 {
+		rootID := task.RootID
+		if rootID == "" {
+			rootID = task.ID
+		}
+		kwargsRepr, _ := json.Marshal(task.Kwargs)
+		headers["lang"] = "py" // the only language Celery defines
+		headers["task"] = task.Task
+		headers["id"] = task.ID
+		headers["shadow"] = nil
+		headers["eta"] = celeryTime(task.ETA)
+		headers["expires"] = celeryTime(task.Expires)
+		headers["group"] = nil
+		headers["group_index"] = nil
+		headers["retries"] = task.Retries
+		headers["timelimit"] = []interface{}{nil, nil}
+		headers["root_id"] = rootID
+		headers["parent_id"] = celeryString(task.ParentID)
+		headers["argsrepr"] = "()"
+		headers["kwargsrepr"] = string(kwargsRepr)
+		headers["origin"] = celeryOrigin
+		headers["ignore_result"] = false
+		body = []interface{}{
+			task.Args,
+			task.Kwargs,
+			map[string]interface{}{"callbacks": nil, "errbacks": nil, "chain": nil, "chord": nil},
+		}
+	} else {
+		body = map[string]interface{}{
+			"id":        task.ID,
+			"task":      task.Task,
+			"args":      task.Args,
+			"kwargs":    task.Kwargs,
+			"retries":   task.Retries,
+			"eta":       celeryTime(task.ETA),
+			"expires":   celeryTime(task.Expires),
+			"utc":       true,
+			"callbacks": nil,
+			"errbacks":  nil,
+			"timelimit": []interface{}{nil, nil},
+			"taskset":   nil,
+			"chord":     nil,
+		}
+	}
+
+	bodyBytes, err := json.Marshal(body)
+	if err != nil {
+		return amqp.Publishing{}, fmt.Errorf("failed to marshal message: %w", err)
+	}
+	return amqp.Publishing{
+		ContentType:     "application/json",
+		ContentEncoding: "utf-8",
+		DeliveryMode:    amqp.Persistent, // make message persistent
+		CorrelationId:   task.ID,
+		MessageId:       task.ID,
+		Body:            bodyBytes,
+		Headers:         headers,
+	}, nil
+}
+
+// decodeCeleryMessage reads the task of a Celery message in either protocol. Messages are JSON,
+// optionally compressed as Celery compresses them; other serializers are rejected.
+func decodeCeleryMessage(msg amqp.Delivery) (*celeryTask, error) {
+	if msg.ContentType != "" && msg.ContentType != "application/json" {
+		return nil, fmt.Errorf("unsupported content type %q", msg.ContentType)
+	}
+	if enc := strings.ToLower(msg.ContentEncoding); enc != "" && enc != "utf-8" && enc != "utf8" {
+		return nil, fmt.Errorf("unsupported content encoding %q", msg.ContentEncoding)
+	}
+	body, err := decompressCeleryBody(msg.Body, msg.Headers["compression"])
+	if err != nil {
+		return nil, err
+	}
+
+	// Messages of older publishers have a task header but a protocol 1 body, so the body decides
+	if trimmed := bytes.TrimLeft(body, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '[' {
+		return decodeCeleryV2(msg.Headers, body)
+	}
+	return decodeCeleryV1(body)
+}
+
+func decodeCeleryV2(headers amqp.Table, body []byte) (*celeryTask, error) {
+	var parts []json.RawMessage
+	if err := json.Unmarshal(body, &parts); err != nil {
+		return nil, fmt.Errorf("invalid protocol 2 body: %w", err)
+	}
+	if len(parts) < 2 {
+		return nil, errors.New("invalid protocol 2 body: want [args, kwargs, embed]")
+	}
+	task := &celeryTask{}
+	if err := json.Unmarshal(parts[0], &task.Args); err != nil {
+		return nil, fmt.Errorf("invalid args: %w", err)
+	}
+	if err := json.Unmarshal(parts[1], &task.Kwargs); err != nil {
+		return nil, fmt.Errorf("invalid kwargs: %w", err)
+	}
+
+	task.Task, _ = headers["task"].(string)
+	task.ID, _ = headers["id"].(string)
+	task.RootID, _ = headers["root_id"].(string)
+	task.ParentID, _ = headers["parent_id"].(string)
+	task.Retries = tableInt(headers["retries"])
+	var err error
+	if task.ETA, err = parseCeleryTime(headers["eta"]); err != nil {
+		return nil, fmt.Errorf("invalid eta: %w", err)
+	}
+	if task.Expires, err = parseCeleryTime(headers["expires"]); err != nil {
+		return nil, fmt.Errorf("invalid expires: %w", err)
+	}
+	return task, task.validate()
+}
+
+func decodeCeleryV1(body []byte) (*celeryTask, error) {
+	var fields struct {
+		ID      string                 `json:"id"`
+		Task    string                 `json:"task"`
+		Args    []interface{}          `json:"args"`
+		Kwargs  map[string]interface{} `json:"kwargs"`
+		Retries float64                `json:"retries"`
+		ETA     interface{}            `json:"eta"`
+		Expires interface{}            `json:"expires"`
+	}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fmt.Errorf("invalid protocol 1 body: %w", err)
+	}
+	task := &celeryTask{
+		ID:      fields.ID,
+		Task:    fields.Task,
+		Args:    fields.Args,
+		Kwargs:  fields.Kwargs,
+		Retries: int(fields.Retries),
+	}
+	var err error
+	if task.ETA, err = parseCeleryTime(fields.ETA); err != nil {
+		return nil, fmt.Errorf("invalid eta: %w", err)
+	}
+	if task.Expires, err = parseCeleryTime(fields.Expires); err != nil {
+		return nil, fmt.Errorf("invalid expires: %w", err)
+	}
+	return task, task.validate()
+}
+
+// validate checks that the task can be handled: handlers take keyword arguments only.
+func (t *celeryTask) validate() error {
+	if t.Task == "" {
+		return errors.New("missing or invalid task type")
+	}
+	if len(t.Args) > 0 {
+		return fmt.Errorf("task %s has positional arguments, which aren't supported; pass kwargs", t.Task)
+	}
+	return nil
+}
+
+// decompressCeleryBody undoes the compression named by a message's compression header. Celery's
+// "application/x-gzip" is a zlib stream; real gzip is accepted too.
+func decompressCeleryBody(body []byte, compression interface{}) ([]byte, error) {
+	name, _ := compression.(string)
+	var r io.Reader
+	switch name {
+	case "":
+		return body, nil
+	case "application/x-gzip", "application/gzip", "zlib", "gzip":
+		var err error
+		if len(body) > 1 && body[0] == 0x1f && body[1] == 0x8b {
+			r, err = gzip.NewReader(bytes.NewReader(body))
+		} else {
+			r, err = zlib.NewReader(bytes.NewReader(body))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s body: %w", name, err)
+		}
+	case "application/x-bz2", "bzip2":
+		r = bzip2.NewReader(bytes.NewReader(body))
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", name)
+	}
+	decompressed, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s body: %w", name, err)
+	}
+	return decompressed, nil
+}
+
+// celeryTime formats t for a message, or nil when it is unset.
+func celeryTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(celeryTimeLayout)
+}
+
+// parseCeleryTime reads an ISO 8601 time of a message. Times without a zone are UTC, as Celery
+// sends them with utc enabled.
+func parseCeleryTime(v interface{}) (*time.Time, error) {
+	s, _ := v.(string)
+	if s == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%q is not an ISO 8601 time", s)
+}
+
+func celeryString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// tableInt reads an integer header, which AMQP may carry in any integer or float width.
+func tableInt(v interface{}) int {
+	switch n := v.(type) {
+	case int:
+		return n
+	case int8:
+		return int(n)
+	case int16:
+		return int(n)
+	case int32:
+		return int(n)
+	case int64:
+		return int(n)
+	case uint8:
+		return int(n)
+	case uint16:
+		return int(n)
+	case uint32:
+		return int(n)
+	case float32:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
+
+// taskRoute returns the exchange and routing key a task for queue is published with: the
+// configured task exchange with the queue's name, or straight to the queue.
+func taskRoute(cfg *config.Config, queue string) (exchange, routingKey string) {
+	return cfg.CeleryTaskExchange, queue
+}
+
+// declareTaskQueues declares queues as durable and, with a task exchange configured, declares it
+// and binds each queue to it under the queue's name.
+func declareTaskQueues(channel *amqp.Channel, cfg *config.Config, queues []string) error {
+	if cfg.CeleryTaskExchange != "" {
+		if err := channel.ExchangeDeclare(
+			cfg.CeleryTaskExchange,
+			cfg.CeleryTaskExchangeType,
+			true,  // durable
+			false, // auto-deleted
+			false, // internal
+			false, // no-wait
+			nil,   // arguments
+		); err != nil {
+			return fmt.Errorf("failed to declare exchange %s: %w", cfg.CeleryTaskExchange, err)
+		}
+	}
+
+	for _, queue := range queues {
+		_, err := channel.QueueDeclare(
+			queue, // name
+			true,  // durable
+			false, // delete when unused
+			false, // exclusive
+			false, // no-wait
+			nil,   // arguments
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare queue %s: %w", queue, err)
+		}
+		if cfg.CeleryTaskExchange != "" {
+			if err := channel.QueueBind(queue, queue, cfg.CeleryTaskExchange, false, nil); err != nil {
+				return fmt.Errorf("failed to bind queue %s: %w", queue, err)
+			}
+		}
+	}
+	return nil
+}
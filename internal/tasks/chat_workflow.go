@@ -0,0 +1,799 @@
+package tasks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+
+	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/repository"
+	"github.com/fraiday-org/api-service/internal/service"
+)
+
+// maxChatWorkflowAttempts is how many times the workflow of a message is tried before it is given up
+const maxChatWorkflowAttempts = 5
+
+// ChatWorkflowHookPoint names a place in the chat workflow where hooks run.
+type ChatWorkflowHookPoint string
+
+const (
+	// ChatWorkflowBeforeAI hooks run once the message and its context are loaded, before the AI
+	// is asked. They can enrich SessionContext or stop the workflow.
+	ChatWorkflowBeforeAI ChatWorkflowHookPoint = "before_ai"
+	// ChatWorkflowAfterAI hooks run on the AI's answer before it is stored. They can rewrite
+	// Response or stop the workflow so nothing is sent.
+	ChatWorkflowAfterAI ChatWorkflowHookPoint = "after_ai"
+)
+
+// ChatWorkflowHook is a step inserted into the chat workflow at a hook point. A hook that
+// handled the message itself calls Stop on the run; a returned error fails the task so it is
+// retried.
+type ChatWorkflowHook struct {
+	Name string
+	Run  func(ctx context.Context, run *ChatWorkflowRun) error
+}
+
+// ChatWorkflowRun is the state of one chat workflow task as it passes through the pipeline.
+type ChatWorkflowRun struct {
+	Payload        ChatWorkflowPayload
+	Message        *service.ChatMessage
+	SessionContext map[string]interface{}
+	// Sandbox is set for sessions of sandbox clients, which get canned answers and aren't metered
+	Sandbox bool
+	// Experiment is the experiment variant the session is in, if its client runs one
+	Experiment *models.ExperimentAssignment
+	// Prompt is the prompt template version rendered into SessionContext, if any
+	Prompt     *models.PromptVersionRef
+	AIResponse *service.AIResponse
+	// Response is the reply drafted from AIResponse, as hooks may change it before it is stored
+	Response models.ChatWorkflowDraft
+	// ResponseMessage is the stored reply, once the persist step has run
+	ResponseMessage *models.ChatMessage
+
+	usageClient        primitive.ObjectID
+	userMessagePayload map[string]interface{}
+	aiMessagePayload   map[string]interface{}
+	stopped            bool
+	stoppedBy          string
+	variant            *models.ExperimentVariant
+	// state checkpoints the run when workflow states are recorded
+	state *models.ChatWorkflowState
+}
+
+// Stop ends the workflow after the current step without an error.
+func (run *ChatWorkflowRun) Stop() {
+	run.stopped = true
+}
+
+// AddChatWorkflowHook registers hook to run at point, after the hooks already there. Hooks must
+// be added before Start.
+func (tw *TaskWorker) AddChatWorkflowHook(point ChatWorkflowHookPoint, hook ChatWorkflowHook) {
+	if tw.chatWorkflowHooks == nil {
+		tw.chatWorkflowHooks = make(map[ChatWorkflowHookPoint][]ChatWorkflowHook)
+	}
+	tw.chatWorkflowHooks[point] = append(tw.chatWorkflowHooks[point], hook)
+}
+
+// addBuiltinChatWorkflowHooks registers the hooks of the optional services. Each does nothing
+// until its service is set.
+func (tw *TaskWorker) addBuiltinChatWorkflowHooks() {
+	tw.AddChatWorkflowHook(ChatWorkflowBeforeAI, ChatWorkflowHook{Name: "intent_routing", Run: tw.routeIntent})
+	tw.AddChatWorkflowHook(ChatWorkflowBeforeAI, ChatWorkflowHook{Name: "keyword_escalation", Run: tw.escalateOnKeywords})
+	tw.AddChatWorkflowHook(ChatWorkflowBeforeAI, ChatWorkflowHook{Name: "experiment", Run: tw.assignExperiment})
+	tw.AddChatWorkflowHook(ChatWorkflowBeforeAI, ChatWorkflowHook{Name: "prompt_template", Run: tw.renderPromptTemplate})
+	tw.AddChatWorkflowHook(ChatWorkflowAfterAI, ChatWorkflowHook{Name: "post_processing", Run: tw.postProcessResponse})
+	tw.AddChatWorkflowHook(ChatWorkflowAfterAI, ChatWorkflowHook{Name: "moderation", Run: tw.moderateResponse})
+}
+
+// HandleChatWorkflow handles chat workflow tasks: it fetches the message and its context, runs
+// the before_ai hooks, asks the AI, runs the after_ai hooks, then stores and announces the reply.
+func (tw *TaskWorker) HandleChatWorkflow(ctx context.Context, kwargs map[string]interface{}) error {
+	// Parse payload
+	payloadBytes, err := json.Marshal(kwargs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kwargs: %w", err)
+	}
+
+	var payload ChatWorkflowPayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal chat workflow payload: %w", err)
+	}
+
+	tw.logger.Info("Processing chat workflow task",
+		zap.String("message_id", payload.MessageID),
+		zap.String("session_id", payload.SessionID))
+
+	run := &ChatWorkflowRun{Payload: payload}
+	if tw.workflowStateService != nil {
+		state, err := tw.workflowStateService.Begin(ctx, payload.MessageID, payload.SessionID, payload.SuggestionMode)
+		if err != nil {
+			tw.logger.Warn("Failed to load chat workflow state, running without checkpoints", zap.Error(err))
+		} else {
+			switch {
+			case state.Status == models.ExecutionStatusCompleted:
+				tw.logger.Info("Chat workflow already completed",
+					zap.String("message_id", payload.MessageID))
+				return nil
+			case state.Attempts > maxChatWorkflowAttempts:
+				tw.abandonChatWorkflow(ctx, state)
+				return nil
+			}
+			run.state = state
+			if err := tw.restoreChatWorkflow(ctx, run); err != nil {
+				tw.failChatWorkflow(ctx, run, err)
+				return err
+			}
+		}
+	}
+
+	// This mirrors the generate_ai_response_task from Python backend
+	steps := []struct {
+		name models.ChatWorkflowStep
+		run  func(context.Context, *ChatWorkflowRun) error
+	}{
+		{models.ChatWorkflowStepFetchContext, tw.fetchChatContext},
+		{models.ChatWorkflowStepBeforeAI, tw.chatWorkflowHookStep(ChatWorkflowBeforeAI)},
+		{models.ChatWorkflowStepAICall, tw.callChatAI},
+		{models.ChatWorkflowStepAfterAI, tw.chatWorkflowHookStep(ChatWorkflowAfterAI)},
+		{models.ChatWorkflowStepPersist, tw.persistChatResponse},
+		{models.ChatWorkflowStepPublish, tw.publishChatOutcome},
+	}
+	for _, step := range steps {
+		// The message and its context are always loaded; later steps resume from their checkpoint
+		if run.state != nil && step.name != models.ChatWorkflowStepFetchContext && run.state.Completed(step.name) {
+			continue
+		}
+		if err := step.run(ctx, run); err != nil {
+			tw.failChatWorkflow(ctx, run, err)
+			return err
+		}
+		if run.stopped {
+			if run.stoppedBy == "" {
+				run.stoppedBy = string(step.name)
+			}
+			tw.completeChatWorkflow(ctx, run)
+			return nil
+		}
+		tw.checkpointChatWorkflow(ctx, run, step.name)
+	}
+	tw.completeChatWorkflow(ctx, run)
+
+	tw.logger.Info("Completed chat workflow task",
+		zap.String("message_id", payload.MessageID))
+
+	return nil
+}
+
+// restoreChatWorkflow loads what the checkpointed steps of an earlier attempt produced.
+func (tw *TaskWorker) restoreChatWorkflow(ctx context.Context, run *ChatWorkflowRun) error {
+	state := run.state
+	if state.Completed(models.ChatWorkflowStepAICall) {
+		aiResponse, err := tw.workflowStateService.RestoreAIResponse(state)
+		if err != nil {
+			return err
+		}
+		run.AIResponse = aiResponse
+		if state.Draft != nil {
+			run.Response = *state.Draft
+		}
+		tw.logger.Info("Resuming chat workflow",
+			zap.String("message_id", run.Payload.MessageID),
+			zap.String("after_step", string(state.Step)),
+			zap.Int("attempt", state.Attempts))
+	}
+	if state.Completed(models.ChatWorkflowStepPersist) && state.ResponseMessageID != nil {
+		responseMessage, err := tw.databaseService.GetChatMessage(ctx, state.ResponseMessageID.Hex())
+		if err != nil {
+			return fmt.Errorf("failed to get stored AI response: %w", err)
+		}
+		run.ResponseMessage = responseMessage
+	}
+	return nil
+}
+
+// checkpointChatWorkflow records the outcome of step, when it is one a retry can resume after.
+// A checkpoint that can't be written only costs the retry some repeated work.
+func (tw *TaskWorker) checkpointChatWorkflow(ctx context.Context, run *ChatWorkflowRun, step models.ChatWorkflowStep) {
+	if run.state == nil {
+		return
+	}
+	var err error
+	switch step {
+	case models.ChatWorkflowStepAICall:
+		err = tw.workflowStateService.CheckpointAI(ctx, run.state, run.AIResponse, run.Response)
+	case models.ChatWorkflowStepAfterAI:
+		err = tw.workflowStateService.CheckpointDraft(ctx, run.state, run.Response)
+	case models.ChatWorkflowStepPersist:
+		err = tw.workflowStateService.CheckpointPersisted(ctx, run.state, run.ResponseMessage.ID)
+	default:
+		return
+	}
+	if err != nil {
+		tw.logger.Warn("Failed to checkpoint chat workflow",
+			zap.String("message_id", run.Payload.MessageID),
+			zap.String("step", string(step)),
+			zap.Error(err))
+	}
+}
+
+// completeChatWorkflow marks the run's workflow done, so redeliveries of the task are ignored.
+func (tw *TaskWorker) completeChatWorkflow(ctx context.Context, run *ChatWorkflowRun) {
+	if run.state == nil {
+		return
+	}
+	if err := tw.workflowStateService.Complete(ctx, run.state, run.stoppedBy); err != nil {
+		tw.logger.Warn("Failed to mark chat workflow completed",
+			zap.String("message_id", run.Payload.MessageID),
+			zap.Error(err))
+	}
+}
+
+// failChatWorkflow records why the run failed; its checkpoints stay for the retry.
+func (tw *TaskWorker) failChatWorkflow(ctx context.Context, run *ChatWorkflowRun, cause error) {
+	if run.state == nil {
+		return
+	}
+	if err := tw.workflowStateService.Fail(ctx, run.state, cause); err != nil {
+		tw.logger.Warn("Failed to record chat workflow failure",
+			zap.String("message_id", run.Payload.MessageID),
+			zap.Error(err))
+	}
+}
+
+// abandonChatWorkflow gives up on a workflow that failed on every attempt, announcing the error
+// so the message isn't left waiting for a reply that won't come.
+func (tw *TaskWorker) abandonChatWorkflow(ctx context.Context, state *models.ChatWorkflowState) {
+	tw.logger.Error("Chat workflow failed on every attempt, giving up",
+		zap.String("message_id", state.MessageID),
+		zap.Int("attempts", state.Attempts-1),
+		zap.String("last_error", state.Error))
+	cause := fmt.Errorf("gave up after %d attempts: %s", state.Attempts-1, state.Error)
+	tw.publishWorkflowError(ctx, state.MessageID, state.SessionID, "retries_exhausted", cause)
+	if err := tw.workflowStateService.Fail(ctx, state, cause); err != nil {
+		tw.logger.Warn("Failed to record abandoned chat workflow",
+			zap.String("message_id", state.MessageID),
+			zap.Error(err))
+	}
+}
+
+// chatWorkflowHookStep runs the hooks of point in order, until one stops the run.
+func (tw *TaskWorker) chatWorkflowHookStep(point ChatWorkflowHookPoint) func(context.Context, *ChatWorkflowRun) error {
+	return func(ctx context.Context, run *ChatWorkflowRun) error {
+		for _, hook := range tw.chatWorkflowHooks[point] {
+			if err := hook.Run(ctx, run); err != nil {
+				return fmt.Errorf("chat workflow hook %s failed: %w", hook.Name, err)
+			}
+			if run.stopped {
+				run.stoppedBy = hook.Name
+				tw.logger.Info("Chat workflow stopped by hook",
+					zap.String("hook", hook.Name),
+					zap.String("point", string(point)),
+					zap.String("message_id", run.Payload.MessageID))
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// publishWorkflowError announces that the workflow failed at stage.
+func (tw *TaskWorker) publishWorkflowError(ctx context.Context, messageID, sessionID, stage string, cause error) {
+	// Publish error event with detailed error information (matching Python)
+	_, err := tw.eventPublisherService.PublishChatMessageEvent(
+		ctx,
+		models.EventTypeChatWorkflowError,
+		messageID,
+		&sessionID,
+		map[string]interface{}{
+			"error":      fmt.Sprintf("%+v", cause), // Include stack trace
+			"session_id": sessionID,
+			"stage":      stage,
+		},
+	)
+	if err != nil {
+		tw.logger.Error("Failed to publish error event", zap.Error(err))
+	}
+}
+
+// fetchChatContext announces the workflow started and loads the message and the context the AI
+// answers in. Contacts who opted out of AI processing stop the run here.
+func (tw *TaskWorker) fetchChatContext(ctx context.Context, run *ChatWorkflowRun) error {
+	payload := run.Payload
+
+	// A run resuming after the AI call already announced itself
+	if run.AIResponse == nil {
+		_, err := tw.eventPublisherService.PublishChatMessageEvent(
+			ctx,
+			models.EventTypeChatWorkflowProcessing,
+			payload.MessageID,
+			&payload.SessionID,
+			map[string]interface{}{
+				"status":     "ai_processing_started",
+				"session_id": payload.SessionID,
+			},
+		)
+		if err != nil {
+			tw.logger.Error("Failed to publish processing event", zap.Error(err))
+			// Don't return error, continue with processing
+		}
+	}
+
+	tw.logger.Info("Processing AI request",
+		zap.String("message_id", payload.MessageID),
+		zap.String("session_id", payload.SessionID))
+
+	message, err := tw.databaseService.GetChatMessage(ctx, payload.MessageID)
+	if err != nil {
+		tw.logger.Error("Failed to get message from database", zap.Error(err))
+		tw.publishWorkflowError(ctx, payload.MessageID, payload.SessionID, "message_retrieval", err)
+		return fmt.Errorf("failed to get message: %w", err)
+	}
+	run.Message = message
+
+	// A contact who opted out of AI processing only hears from humans
+	if optedOut, err := tw.databaseService.ContactOptedOut(ctx, message, models.ConsentAIProcessing); err != nil {
+		return fmt.Errorf("failed to check contact consent: %w", err)
+	} else if optedOut {
+		tw.publishWorkflowOptedOut(ctx, payload.MessageID, payload.SessionID, models.ConsentAIProcessing)
+		run.Stop()
+		return nil
+	}
+
+	sessionContext, err := tw.databaseService.GetSessionContext(ctx, payload.SessionID)
+	if err != nil {
+		tw.logger.Warn("Failed to get session context, using minimal context", zap.Error(err))
+		sessionContext = map[string]interface{}{"session_id": payload.SessionID}
+	}
+	tw.attachReplyChain(ctx, sessionContext, message)
+	tw.attachContactHistory(ctx, sessionContext, message)
+	run.SessionContext = sessionContext
+	return nil
+}
+
+// routeIntent keeps the bot out of conversations intent routing sends elsewhere.
+func (tw *TaskWorker) routeIntent(ctx context.Context, run *ChatWorkflowRun) error {
+	if tw.intentService == nil {
+		return nil
+	}
+	action, err := tw.intentService.Route(ctx, run.Message)
+	if err != nil {
+		tw.logger.Warn("Intent routing failed, answering with AI", zap.Error(err))
+	} else if action != models.IntentActionAIAnswer {
+		tw.publishWorkflowRouted(ctx, run.Payload.MessageID, run.Payload.SessionID, run.Message, action)
+		run.Stop()
+	}
+	return nil
+}
+
+// escalateOnKeywords skips the bot for a user asking for a human.
+func (tw *TaskWorker) escalateOnKeywords(ctx context.Context, run *ChatWorkflowRun) error {
+	if tw.escalationService == nil || run.Payload.SuggestionMode {
+		return nil
+	}
+	escalation, err := tw.escalationService.CheckKeywords(ctx, run.Message)
+	if err != nil {
+		tw.logger.Warn("Escalation handover failed", zap.Error(err))
+	}
+	if escalation != nil {
+		tw.publishWorkflowEscalated(ctx, run.Payload.MessageID, run.Payload.SessionID, escalation)
+		run.Stop()
+	}
+	return nil
+}
+
+// assignExperiment puts the session in its variant of the client's running experiment, whose
+// settings the later steps use in place of the client's own.
+func (tw *TaskWorker) assignExperiment(ctx context.Context, run *ChatWorkflowRun) error {
+	if tw.experimentService == nil {
+		return nil
+	}
+	run.Experiment, run.variant = tw.experimentService.Assign(ctx, run.Message.SessionID)
+	return nil
+}
+
+// renderPromptTemplate adds the client's published prompt for the message to the AI request's
+// context. A prompt that can't be rendered leaves the AI service's default prompt in place.
+func (tw *TaskWorker) renderPromptTemplate(ctx context.Context, run *ChatWorkflowRun) error {
+	if tw.promptTemplateService == nil {
+		return nil
+	}
+	name := models.PromptTemplateChat
+	if run.Payload.SuggestionMode {
+		name = models.PromptTemplateSuggestions
+	}
+	var pin *models.ExperimentPrompt
+	if run.variant != nil {
+		pin = run.variant.Prompt
+	}
+	prompt, err := tw.promptTemplateService.Render(ctx, name, run.Message, pin)
+	if err != nil {
+		tw.logger.Warn("Failed to render prompt template, using the default prompt",
+			zap.String("message_id", run.Payload.MessageID), zap.Error(err))
+		return nil
+	}
+	if prompt == nil {
+		return nil
+	}
+	run.SessionContext["prompt"] = map[string]interface{}{
+		"template_id": prompt.Ref.TemplateID,
+		"name":        prompt.Ref.Name,
+		"version":     prompt.Ref.Version,
+		"text":        prompt.Text,
+	}
+	run.Prompt = prompt.Ref
+	return nil
+}
+
+// callChatAI asks the AI for a reply or suggestions, within the client's quota, and drafts the
+// response from its answer.
+func (tw *TaskWorker) callChatAI(ctx context.Context, run *ChatWorkflowRun) error {
+	payload, message := run.Payload, run.Message
+
+	run.Sandbox = tw.databaseService.IsSandboxSession(ctx, payload.SessionID)
+	cacheKey := tw.answerCacheKey(ctx, run)
+	aiResponse := tw.cachedAnswer(ctx, run, cacheKey)
+	cached := aiResponse != nil
+
+	// A cached answer costs no AI call, so it is neither limited nor metered
+	if !cached {
+		var err error
+		if !run.Sandbox {
+			if run.usageClient, err = tw.checkAIQuota(ctx, message.SessionID); err != nil {
+				tw.publishWorkflowQuotaExceeded(ctx, payload.MessageID, payload.SessionID, err)
+				run.Stop()
+				return nil
+			}
+		}
+
+		if run.Sandbox {
+			aiResponse = tw.aiService.SandboxResponse(payload.MessageID, payload.SessionID, message.Text)
+		} else {
+			// Replies on Slack channels with a workflow of their own are generated by it
+			var workflow *service.SlackAIWorkflow
+			var workflowClientID string
+			if !payload.SuggestionMode && tw.processorDispatchService.Slack != nil {
+				workflow, workflowClientID, err = tw.processorDispatchService.Slack.AIWorkflow(ctx, message.SessionID)
+			}
+			if workflow != nil {
+				aiResponse, err = tw.aiService.ProcessSlackAIRequest(ctx, workflow, workflowClientID, message.Sender, message.Text, payload.SessionID, run.SessionContext)
+			} else if err == nil {
+				modelConfig := tw.clientAIConfig(ctx, message.SessionID)
+				if run.variant != nil && run.variant.AIConfig != nil {
+					modelConfig = run.variant.AIConfig
+				}
+				if payload.SuggestionMode {
+					aiResponse, err = tw.aiService.GenerateSuggestions(ctx, payload.MessageID, payload.SessionID, message.Text, run.SessionContext, modelConfig)
+				} else {
+					aiResponse, err = tw.aiService.GenerateChatResponse(ctx, payload.MessageID, payload.SessionID, message.Text, run.SessionContext, modelConfig)
+				}
+			}
+		}
+		if err != nil {
+			tw.logger.Error("Failed to process AI request", zap.Error(err))
+			tw.publishWorkflowError(ctx, payload.MessageID, payload.SessionID, "ai_processing", err)
+			return fmt.Errorf("AI processing failed: %w", err)
+		}
+		tw.recordAIUsage(ctx, run.usageClient, aiResponse)
+	}
+	run.AIResponse = aiResponse
+
+	tw.logger.Info("AI response received",
+		zap.String("message_id", aiResponse.MessageID),
+		zap.String("response_length", fmt.Sprintf("%d", len(aiResponse.Response))),
+		zap.Bool("cached", cached))
+
+	// Handle different response formats (Slack/Sunshine vs regular AI service)
+	if aiResponse.Result != nil {
+		// Slack/Sunshine format - data is in Result field
+		run.Response = models.ChatWorkflowDraft{
+			Text:        aiResponse.Result.Text,
+			Confidence:  aiResponse.Result.ConfidenceScore,
+			Attachments: tw.validAIAttachments(aiResponse.Result.Attachments),
+			Citations:   tw.validAICitations(aiResponse.Result.Sources),
+			AnswerData:  aiResponse.Result.Data,
+		}
+		if aiResponse.Result.Metadata != nil {
+			if closeSession, ok := aiResponse.Result.Metadata["close_session"].(bool); ok {
+				run.Response.CloseSession = closeSession
+			}
+		}
+	} else {
+		// Regular AI service format - data is in Data field
+		run.Response = models.ChatWorkflowDraft{
+			Text:         aiResponse.Data.Answer.AnswerText,
+			Confidence:   aiResponse.Data.ConfidenceScore,
+			Attachments:  tw.validAIAttachments(aiResponse.Data.Answer.Attachments),
+			Citations:    tw.validAICitations(aiResponse.Data.Answer.Sources),
+			CloseSession: aiResponse.Metadata.CloseSession,
+			AnswerData:   aiResponse.Data.Answer.AnswerData,
+		}
+	}
+	run.Response.Prompt, run.Response.Experiment = run.Prompt, run.Experiment
+	run.Response.Cached = cached
+	if cacheKey != nil && !cached {
+		if err := tw.answerCacheService.Put(ctx, cacheKey, aiResponse, run.Response.Confidence); err != nil {
+			tw.logger.Warn("Failed to cache AI answer", zap.String("message_id", payload.MessageID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// answerCacheKey returns the key the run's answer is cached under, or nil when it isn't cached.
+// Sandbox answers are canned already, and sessions in an experiment always ask the AI so their
+// variant is what gets measured.
+func (tw *TaskWorker) answerCacheKey(ctx context.Context, run *ChatWorkflowRun) *service.AnswerCacheKey {
+	if tw.answerCacheService == nil || run.Sandbox || run.variant != nil {
+		return nil
+	}
+	return tw.answerCacheService.Key(ctx, run.Message, run.Payload.SuggestionMode, run.Prompt)
+}
+
+// cachedAnswer returns the cached answer for key, readdressed to the run's message, or nil on a
+// miss. A cache that can't be read is a miss.
+func (tw *TaskWorker) cachedAnswer(ctx context.Context, run *ChatWorkflowRun, key *service.AnswerCacheKey) *service.AIResponse {
+	if key == nil {
+		return nil
+	}
+	aiResponse, err := tw.answerCacheService.Get(ctx, key)
+	if err != nil {
+		tw.logger.Warn("Failed to read answer cache", zap.String("message_id", run.Payload.MessageID), zap.Error(err))
+		return nil
+	}
+	if aiResponse != nil {
+		aiResponse.MessageID, aiResponse.SessionID = run.Payload.MessageID, run.Payload.SessionID
+	}
+	return aiResponse
+}
+
+// postProcessResponse lets the client's post-processing hook review the response before it is
+// saved, and drops it when the hook vetoes it.
+func (tw *TaskWorker) postProcessResponse(ctx context.Context, run *ChatWorkflowRun) error {
+	if tw.postProcessingService == nil {
+		return nil
+	}
+	draft, err := tw.postProcessingService.Process(ctx, run.Message.SessionID, run.Payload.MessageID, run.Message.Text, run.Payload.SuggestionMode, &service.PostProcessingDraft{
+		Text:        run.Response.Text,
+		Confidence:  run.Response.Confidence,
+		Attachments: run.Response.Attachments,
+	})
+	if err != nil {
+		tw.publishWorkflowVetoed(ctx, run.Payload.MessageID, run.Payload.SessionID, err)
+		run.Stop()
+		return nil
+	}
+	run.Response.Text, run.Response.Confidence, run.Response.Attachments = draft.Text, draft.Confidence, draft.Attachments
+	return nil
+}
+
+// moderateResponse hands the session to a human instead of sending a blocked response.
+// Suggestions are only shown to agents, so they are not moderated.
+func (tw *TaskWorker) moderateResponse(ctx context.Context, run *ChatWorkflowRun) error {
+	if tw.moderationService == nil || run.Payload.SuggestionMode {
+		return nil
+	}
+	verdict, err := tw.moderationService.Moderate(ctx, run.Message.SessionID, run.Payload.MessageID, run.Response.Text)
+	if verdict != nil {
+		tw.publishWorkflowModerated(ctx, run.Payload.MessageID, run.Payload.SessionID, verdict, err)
+		run.Stop()
+	}
+	return nil
+}
+
+// persistChatResponse stores the response with the suggestion record or the completed event
+// announcing it.
+func (tw *TaskWorker) persistChatResponse(ctx context.Context, run *ChatWorkflowRun) error {
+	payload := run.Payload
+	dedupKey := models.AIReplyDedupKey(payload.MessageID, payload.SuggestionMode)
+
+	// An attempt that stored the reply but died before its checkpoint doesn't store it twice
+	existing, err := tw.chatMessageService.FindDuplicate(ctx, dedupKey)
+	if err != nil {
+		return fmt.Errorf("failed to look up stored AI response: %w", err)
+	}
+	if existing != nil {
+		tw.logger.Info("AI response already stored",
+			zap.String("message_id", payload.MessageID),
+			zap.String("response_message_id", existing.ID.Hex()))
+		run.ResponseMessage = existing
+		return nil
+	}
+
+	responseMessage := &models.ChatMessage{
+		DedupKey:    dedupKey,
+		Text:        run.Response.Text,
+		Sender:      "fraiday-bot", // BOT_SENDER_NAME equivalent
+		SenderName:  "fraiday-bot",
+		SenderType:  "assistant",
+		SessionID:   run.Message.SessionID,
+		Category:    models.MessageCategoryMessage,
+		Confidence:  run.Response.Confidence,
+		Attachments: run.Response.Attachments,
+		Citations:   run.Response.Citations,
+		Config: map[string]interface{}{
+			"ai_response":         true,
+			"original_message_id": payload.MessageID,
+		},
+		Data: map[string]interface{}{
+			"close_session": run.Response.CloseSession,
+			"meta_data":     run.Response.AnswerData, // Add metadata like Python
+		},
+	}
+	if run.Response.Prompt != nil {
+		responseMessage.Config["prompt"] = run.Response.Prompt
+	}
+	if run.Response.Experiment != nil {
+		responseMessage.Config["experiment"] = run.Response.Experiment
+	}
+	if run.Response.Cached {
+		responseMessage.Config["cached_answer"] = true
+	}
+	run.ResponseMessage = responseMessage
+
+	// The AI response, its suggestion record and the event announcing them are stored together, so
+	// a failed step leaves nothing behind and the retried task starts clean
+	err = tw.chatMessageService.Transactions.Do(ctx, func(ctx context.Context) error {
+		atomic := repository.InTransaction(ctx)
+
+		// Use ChatMessageService to create the message (this will publish chat_message_created event)
+		if err := tw.chatMessageService.CreateChatMessage(ctx, responseMessage); err != nil {
+			return fmt.Errorf("failed to save AI response: %w", err)
+		}
+
+		if payload.SuggestionMode {
+			tw.logger.Info("Creating chat suggestion",
+				zap.String("message_id", payload.MessageID))
+
+			// Agents accept or reject the suggestion by the ID published below
+			if tw.suggestionService != nil {
+				if err := tw.suggestionService.RecordSuggestion(ctx, responseMessage, payload.MessageID); err != nil {
+					if atomic {
+						return fmt.Errorf("failed to record suggestion: %w", err)
+					}
+					tw.logger.Error("Failed to record suggestion", zap.Error(err))
+				}
+			}
+
+			// Publish suggestion created event with full payload (matching Python)
+			suggestionPayload, err := tw.payloadService.CreateChatSuggestionPayload(ctx, responseMessage.ID.Hex())
+			if err != nil {
+				tw.logger.Error("Failed to create suggestion payload", zap.Error(err))
+				suggestionPayload = map[string]interface{}{
+					"id":         responseMessage.ID.Hex(),
+					"message_id": payload.MessageID,
+					"session_id": payload.SessionID,
+					"content":    run.AIResponse.Response,
+				}
+			}
+			if run.Response.Prompt != nil {
+				suggestionPayload["prompt"] = run.Response.Prompt
+			}
+
+			_, err = tw.eventPublisherService.PublishChatSuggestionEvent(
+				ctx,
+				models.EventTypeChatSuggestionCreated,
+				responseMessage.ID.Hex(),
+				&payload.MessageID,
+				suggestionPayload,
+			)
+			if err != nil {
+				if atomic {
+					return fmt.Errorf("failed to publish suggestion created event: %w", err)
+				}
+				tw.logger.Error("Failed to publish suggestion created event", zap.Error(err))
+			}
+			return nil
+		}
+
+		tw.logger.Info("Creating chat message response",
+			zap.String("message_id", payload.MessageID))
+
+		// Publish workflow completed event with full message payloads (matching Python)
+		run.userMessagePayload, run.aiMessagePayload = tw.chatWorkflowPayloads(ctx, payload.MessageID, responseMessage.ID.Hex())
+
+		completedData := map[string]interface{}{
+			"user_message": run.userMessagePayload,
+			"ai_message":   run.aiMessagePayload,
+			"session_id":   payload.SessionID,
+		}
+		if run.Response.Prompt != nil {
+			completedData["prompt"] = run.Response.Prompt
+		}
+		if run.Response.Cached {
+			completedData["cached"] = true
+		}
+		_, err := tw.eventPublisherService.PublishChatMessageEvent(
+			ctx,
+			models.EventTypeChatWorkflowCompleted,
+			responseMessage.ID.Hex(),
+			&payload.SessionID,
+			completedData,
+		)
+		if err != nil {
+			if atomic {
+				return fmt.Errorf("failed to publish workflow completed event: %w", err)
+			}
+			tw.logger.Error("Failed to publish workflow completed event", zap.Error(err))
+		}
+		return nil
+	})
+	if err != nil {
+		tw.logger.Error("Failed to store AI response", zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// publishChatOutcome announces a handover when the escalation policy, or a zero confidence
+// score without one, says a human should take over the session.
+func (tw *TaskWorker) publishChatOutcome(ctx context.Context, run *ChatWorkflowRun) error {
+	if run.Payload.SuggestionMode {
+		return nil
+	}
+	payload := run.Payload
+
+	handover := run.Response.Confidence == 0
+	var escalation *service.EscalationDecision
+	if tw.escalationService != nil {
+		var escalationErr error
+		escalation, escalationErr = tw.escalationService.RecordAnswer(ctx, run.Message.SessionID, run.Response.Confidence)
+		if escalationErr != nil {
+			tw.logger.Warn("Escalation policy failed", zap.Error(escalationErr))
+		}
+		// Keep the zero-confidence fallback only when the policy couldn't be evaluated at all
+		if escalation != nil || escalationErr == nil {
+			handover = escalation != nil
+		}
+	}
+	if handover {
+		// A resumed run didn't build the payloads in this attempt
+		if run.userMessagePayload == nil {
+			run.userMessagePayload, run.aiMessagePayload = tw.chatWorkflowPayloads(ctx, payload.MessageID, run.ResponseMessage.ID.Hex())
+		}
+		handoverData := map[string]interface{}{
+			"user_message": run.userMessagePayload,
+			"ai_message":   run.aiMessagePayload,
+			"session_id":   payload.SessionID,
+		}
+		if escalation != nil {
+			handoverData["escalation"] = escalation
+		}
+		if run.Response.Prompt != nil {
+			handoverData["prompt"] = run.Response.Prompt
+		}
+		// Reuse the payloads we already created for consistency
+		_, err := tw.eventPublisherService.PublishChatMessageEvent(
+			ctx,
+			models.EventTypeChatWorkflowHandover,
+			run.ResponseMessage.ID.Hex(),
+			&payload.SessionID,
+			handoverData,
+		)
+		if err != nil {
+			tw.logger.Error("Failed to publish handover event", zap.Error(err))
+		}
+	}
+	if escalation != nil {
+		tw.publishWorkflowEscalated(ctx, payload.MessageID, payload.SessionID, escalation)
+	}
+	return nil
+}
+
+// chatWorkflowPayloads builds the payloads of the user's message and the AI's reply, falling back
+// to their IDs.
+func (tw *TaskWorker) chatWorkflowPayloads(ctx context.Context, messageID, responseMessageID string) (map[string]interface{}, map[string]interface{}) {
+	userMessagePayload, err := tw.payloadService.CreateChatMessagePayload(ctx, messageID)
+	if err != nil {
+		tw.logger.Error("Failed to create user message payload", zap.Error(err))
+		userMessagePayload = map[string]interface{}{"id": messageID}
+	}
+
+	aiMessagePayload, err := tw.payloadService.CreateChatMessagePayload(ctx, responseMessageID)
+	if err != nil {
+		tw.logger.Error("Failed to create AI message payload", zap.Error(err))
+		aiMessagePayload = map[string]interface{}{"id": responseMessageID}
+	}
+	return userMessagePayload, aiMessagePayload
+}
@@ -3,21 +3,72 @@ package tasks
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
 	"github.com/fraiday-org/api-service/internal/config"
 	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 )
 
-// TaskClient wraps RabbitMQ connection for task enqueueing
+var (
+	// ErrTaskNotConfirmed is returned when the broker rejects a published task or doesn't confirm
+	// it in time
+	ErrTaskNotConfirmed = errors.New("task was not confirmed by the broker")
+	// ErrTaskUnroutable is returned when no queue takes a published task
+	ErrTaskUnroutable = errors.New("task could not be routed to a queue")
+	// ErrTaskRetrying wraps the publish errors of tasks that are kept to be published again, so
+	// callers that can live with a late task can tell them from lost ones
+	ErrTaskRetrying = errors.New("task buffered to be published again")
+)
+
+// returnBuffer is how many returned tasks a channel holds until publishers collect them
+const returnBuffer = 256
+
+// batchQueue takes the long-running jobs, such as reports, maintenance, simulations and imports,
+// so they don't hold up the chat workflows on CeleryDefaultQueue
+const batchQueue = "default"
+
+// TaskClient wraps RabbitMQ connection for task enqueueing. Tasks are published as mandatory on a
+// channel in confirm mode, so publishing only succeeds once the broker has queued the task.
 type TaskClient struct {
-	conn    *amqp.Connection
+	conn   *amqp.Connection
+	logger *zap.Logger
+	cfg    *config.Config
+
+	// mu guards channel, which is reopened when the broker closes it
+	mu      sync.Mutex
+	channel *publishChannel
+
+	// returned holds the message IDs of tasks returned as unroutable until their publishers look
+	returnedMu sync.Mutex
+	returned   map[string]bool
+
+	// retries holds unconfirmed tasks to publish again
+	retryMu sync.Mutex
+	retries []*publishRetry
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// publishChannel is a channel in confirm mode with the returns of its mandatory publishes
+type publishChannel struct {
 	channel *amqp.Channel
-	logger  *zap.Logger
-	cfg     *config.Config
+	returns chan amqp.Return
+}
+
+// publishRetry is an unconfirmed task waiting to be published again
+type publishRetry struct {
+	exchange   string
+	routingKey string
+	taskType   string
+	publishing amqp.Publishing
+	attempts   int
+	next       time.Time
 }
 
 // NewTaskClient creates a new task client
@@ -27,18 +78,15 @@ func NewTaskClient(rabbitMQURL string, logger *zap.Logger, cfg *config.Config) (
 		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
 	}
 
-	channel, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to open channel: %w", err)
-	}
-
 	client := &TaskClient{
-		conn:    conn,
-		channel: channel,
-		logger:  logger,
-		cfg:     cfg,
+		conn:     conn,
+		logger:   logger,
+		cfg:      cfg,
+		returned: make(map[string]bool),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
 	}
+	go client.retryLoop()
 
 	// Declare queues
 	if err := client.declareQueues(); err != nil {
@@ -51,34 +99,74 @@ func NewTaskClient(rabbitMQURL string, logger *zap.Logger, cfg *config.Config) (
 
 // declareQueues declares all required queues
 func (tc *TaskClient) declareQueues() error {
+	pc, err := tc.publishChannel()
+	if err != nil {
+		return err
+	}
+
 	queues := []string{
 		tc.cfg.CeleryDefaultQueue,
 		tc.cfg.CeleryEventsQueue,
-		"default",
-	}
-
-	for _, queue := range queues {
-		_, err := tc.channel.QueueDeclare(
-			queue, // name
-			true,  // durable
-			false, // delete when unused
-			false, // exclusive
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			return fmt.Errorf("failed to declare queue %s: %w", queue, err)
-		}
+		batchQueue,
 	}
+	return declareTaskQueues(pc.channel, tc.cfg, queues)
+}
 
-	return nil
+// publishChannel returns the channel tasks are published on, opening it in confirm mode if the
+// broker closed it.
+func (tc *TaskClient) publishChannel() (*publishChannel, error) {
+	tc.mu.Lock()
+	defer tc.mu.Unlock()
+
+	if tc.channel != nil && !tc.channel.channel.IsClosed() {
+		return tc.channel, nil
+	}
+	channel, err := tc.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open channel: %w", err)
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+	tc.channel = &publishChannel{
+		channel: channel,
+		returns: channel.NotifyReturn(make(chan amqp.Return, returnBuffer)),
+	}
+	return tc.channel, nil
 }
 
-// Close closes the task client
+// QueueDepth returns how many tasks are ready in queueName. The queue is declared passively on a
+// channel of its own, since the broker closes the channel if the queue doesn't exist.
+func (tc *TaskClient) QueueDepth(queueName string) (int, error) {
+	channel, err := tc.conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open channel: %w", err)
+	}
+	defer channel.Close()
+
+	queue, err := channel.QueueDeclarePassive(queueName, true, false, false, false, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to inspect queue %s: %w", queueName, err)
+	}
+	return queue.Messages, nil
+}
+
+// Close stops retrying buffered tasks, which are lost, and closes the task client
 func (tc *TaskClient) Close() error {
+	close(tc.stop)
+	<-tc.done
+	tc.retryMu.Lock()
+	if len(tc.retries) > 0 {
+		tc.logger.Warn("Dropping unconfirmed tasks on close", zap.Int("count", len(tc.retries)))
+	}
+	tc.retryMu.Unlock()
+
+	tc.mu.Lock()
 	if tc.channel != nil {
-		tc.channel.Close()
+		tc.channel.channel.Close()
 	}
+	tc.mu.Unlock()
 	if tc.conn != nil {
 		return tc.conn.Close()
 	}
@@ -125,57 +213,342 @@ type DeliverToProcessorPayload struct {
 	DeliveryID  string                 `json:"delivery_id"`
 }
 
+// CSATTriggerPayload represents the payload for csat_trigger tasks
+type CSATTriggerPayload struct {
+	SessionID string `json:"session_id"`
+	Type      string `json:"type"`
+}
+
+// CSATReminderPayload represents the payload for csat_reminder tasks
+type CSATReminderPayload struct {
+	CSATSessionID string `json:"csat_session_id"`
+	QuestionIndex int    `json:"question_index"`
+}
+
+// CSATExpiryPayload represents the payload for csat_expiry tasks
+type CSATExpiryPayload struct {
+	CSATSessionID string `json:"csat_session_id"`
+}
+
+// RepairActionPayload represents the payload for repair_action tasks
+type RepairActionPayload struct {
+	ActionID string `json:"action_id"`
+}
+
+// ScheduledMessagePayload represents the payload for scheduled_message tasks
+type ScheduledMessagePayload struct {
+	ScheduledMessageID string `json:"scheduled_message_id"`
+}
+
+// SessionRecapPayload represents the payload for session_recap tasks
+type SessionRecapPayload struct {
+	SessionID string `json:"session_id"`
+}
+
+// ReportGenerationPayload represents the payload for report_generation tasks
+type ReportGenerationPayload struct {
+	ReportID    string `json:"report_id"`
+	PeriodStart string `json:"period_start"`
+	PeriodEnd   string `json:"period_end"`
+}
+
+// MaintenancePayload represents the payload for maintenance tasks
+type MaintenancePayload struct {
+	Job string `json:"job"`
+}
+
+// SimulationPayload represents the payload for simulation tasks
+type SimulationPayload struct {
+	SimulationID string `json:"simulation_id"`
+}
+
+// MessageImportPayload represents the payload for message_import tasks
+type MessageImportPayload struct {
+	ImportID string `json:"import_id"`
+}
+
 // publishTask publishes a task to the specified queue
 func (tc *TaskClient) publishTask(ctx context.Context, queueName, taskType string, payload interface{}) error {
-	// Create message with Celery-compatible format
-	message := map[string]interface{}{
-		"id":      fmt.Sprintf("%d", time.Now().UnixNano()),
-		"task":    taskType,
-		"args":    []interface{}{},
-		"kwargs":  payload,
-		"retries": 0,
-		"eta":     nil,
-		"expires": nil,
-	}
-
-	messageBytes, err := json.Marshal(message)
+	exchange, routingKey := taskRoute(tc.cfg, queueName)
+	return tc.publishTaskAt(ctx, exchange, routingKey, taskType, payload, time.Now())
+}
+
+// publishTaskAt publishes a task that workers can pick up from availableAt on, which is later
+// than now for delayed tasks. Workers measure queue lag from it.
+func (tc *TaskClient) publishTaskAt(ctx context.Context, exchange, routingKey, taskType string, payload interface{}, availableAt time.Time) error {
+	// Handlers take the payload's fields as keyword arguments
+	var kwargs map[string]interface{}
+	payloadBytes, err := json.Marshal(payload)
+	if err == nil {
+		err = json.Unmarshal(payloadBytes, &kwargs)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	err = tc.channel.PublishWithContext(
-		ctx,
-		"",        // exchange
-		queueName, // routing key
-		false,     // mandatory
-		false,     // immediate
-		amqp.Publishing{
-			ContentType:  "application/json",
-			DeliveryMode: amqp.Persistent, // make message persistent
-			Body:         messageBytes,
-			Headers: amqp.Table{
-				"task": taskType,
-				"id":   message["id"],
-			},
-		},
-	)
+	headers := amqp.Table{}
+	telemetry.MarkAvailableAt(headers, availableAt)
+	ctx, span := telemetry.StartPublish(ctx, routingKey, taskType, headers)
+	defer span.End()
 
+	publishing, err := encodeCeleryMessage(celeryTask{
+		ID:     fmt.Sprintf("%d", time.Now().UnixNano()),
+		Task:   taskType,
+		Kwargs: kwargs,
+	}, tc.cfg.CeleryMessageProtocol, headers)
 	if err != nil {
+		return err
+	}
+	err = tc.publish(ctx, exchange, routingKey, publishing)
+
+	if err != nil {
+		telemetry.RecordError(span, err)
+		telemetry.ObserveTaskPublishFailure(taskType, publishFailureReason(err))
+		// Retrying can't route a task no queue takes
+		if !errors.Is(err, ErrTaskUnroutable) && tc.bufferRetry(exchange, routingKey, taskType, publishing) {
+			err = fmt.Errorf("%w: %w", ErrTaskRetrying, err)
+		}
 		tc.logger.Error("Failed to publish task", 
-			zap.String("queue", queueName),
+			zap.String("exchange", exchange),
+			zap.String("queue", routingKey),
 			zap.String("task_type", taskType),
 			zap.Error(err))
 		return fmt.Errorf("failed to publish task: %w", err)
 	}
 
 	tc.logger.Info("Published task",
-		zap.String("task_id", message["id"].(string)),
-		zap.String("queue", queueName),
+		zap.String("task_id", publishing.MessageId),
+		zap.String("exchange", exchange),
+		zap.String("queue", routingKey),
 		zap.String("task_type", taskType))
 
 	return nil
 }
 
+// publish publishes a task to exchange under routingKey and waits for the broker to confirm it.
+func (tc *TaskClient) publish(ctx context.Context, exchange, routingKey string, publishing amqp.Publishing) error {
+	pc, err := tc.publishChannel()
+	if err != nil {
+		return err
+	}
+	confirm, err := pc.channel.PublishWithDeferredConfirmWithContext(
+		ctx,
+		exchange,
+		routingKey,
+		true,  // mandatory
+		false, // immediate
+		publishing,
+	)
+	if err != nil {
+		return err
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, tc.cfg.TaskPublishConfirmTimeout)
+	defer cancel()
+	acked, err := confirm.WaitContext(waitCtx)
+	// The broker acks unroutable tasks too, after returning them
+	if tc.wasReturned(pc, publishing.MessageId) {
+		return fmt.Errorf("%w: %s", ErrTaskUnroutable, routingKey)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrTaskNotConfirmed, err)
+	}
+	if !acked {
+		return ErrTaskNotConfirmed
+	}
+	return nil
+}
+
+// wasReturned reports whether the broker returned the task with messageID. The broker sends a
+// return before the confirm of the same task, so once the confirm arrived the return is waiting on
+// pc, where it is collected along with those of other publishers.
+func (tc *TaskClient) wasReturned(pc *publishChannel, messageID string) bool {
+	tc.returnedMu.Lock()
+	defer tc.returnedMu.Unlock()
+
+collect:
+	for {
+		select {
+		case ret, ok := <-pc.returns:
+			if !ok {
+				break collect
+			}
+			tc.returned[ret.MessageId] = true
+		default:
+			break collect
+		}
+	}
+	returned := tc.returned[messageID]
+	delete(tc.returned, messageID)
+	return returned
+}
+
+// publishFailureReason is the metrics label of a publish error
+func publishFailureReason(err error) string {
+	switch {
+	case errors.Is(err, ErrTaskUnroutable):
+		return "unroutable"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrTaskNotConfirmed):
+		return "nack"
+	default:
+		return "error"
+	}
+}
+
+// bufferRetry keeps an unconfirmed task to publish it again, unless the retry buffer is full.
+func (tc *TaskClient) bufferRetry(exchange, routingKey, taskType string, publishing amqp.Publishing) bool {
+	if tc.cfg.TaskPublishRetryBuffer == 0 {
+		return false
+	}
+	tc.retryMu.Lock()
+	defer tc.retryMu.Unlock()
+
+	if len(tc.retries) >= tc.cfg.TaskPublishRetryBuffer {
+		telemetry.ObserveTaskPublishRetry("dropped")
+		return false
+	}
+	tc.retries = append(tc.retries, &publishRetry{
+		exchange:   exchange,
+		routingKey: routingKey,
+		taskType:   taskType,
+		publishing: publishing,
+		next:       time.Now().Add(retryBackoff(0)),
+	})
+	telemetry.SetTaskPublishRetryBuffer(len(tc.retries))
+	return true
+}
+
+// retryBackoff is how long a buffered task waits after its attempts-th retry: 1s, doubling up to
+// 30s.
+func retryBackoff(attempts int) time.Duration {
+	return min(time.Second<<attempts, 30*time.Second)
+}
+
+// retryLoop publishes buffered tasks again once their backoff has passed, until Close.
+func (tc *TaskClient) retryLoop() {
+	defer close(tc.done)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-tc.stop:
+			return
+		case <-ticker.C:
+			tc.retryDue()
+		}
+	}
+}
+
+// retryDue publishes the buffered tasks that are due, and buffers those that fail again until
+// they run out of retries.
+func (tc *TaskClient) retryDue() {
+	now := time.Now()
+	tc.retryMu.Lock()
+	var due []*publishRetry
+	waiting := tc.retries[:0]
+	for _, retry := range tc.retries {
+		if retry.next.After(now) {
+			waiting = append(waiting, retry)
+		} else {
+			due = append(due, retry)
+		}
+	}
+	tc.retries = waiting
+	tc.retryMu.Unlock()
+
+	var failed []*publishRetry
+publish:
+	for i, retry := range due {
+		select {
+		case <-tc.stop:
+			// Close is waiting, so the rest stay buffered
+			failed = append(failed, due[i:]...)
+			break publish
+		default:
+		}
+
+		err := tc.publish(context.Background(), retry.exchange, retry.routingKey, retry.publishing)
+		retry.attempts++
+		switch {
+		case err == nil:
+			telemetry.ObserveTaskPublishRetry("confirmed")
+			tc.logger.Info("Published buffered task",
+				zap.String("task_id", retry.publishing.MessageId),
+				zap.String("queue", retry.routingKey),
+				zap.String("task_type", retry.taskType),
+				zap.Int("attempts", retry.attempts))
+		case errors.Is(err, ErrTaskUnroutable) || retry.attempts >= tc.cfg.TaskPublishMaxRetries:
+			telemetry.ObserveTaskPublishRetry("dropped")
+			tc.logger.Error("Dropping unconfirmed task",
+				zap.String("task_id", retry.publishing.MessageId),
+				zap.String("queue", retry.routingKey),
+				zap.String("task_type", retry.taskType),
+				zap.Int("attempts", retry.attempts),
+				zap.Error(err))
+		default:
+			telemetry.ObserveTaskPublishRetry("failed")
+			retry.next = time.Now().Add(retryBackoff(retry.attempts))
+			failed = append(failed, retry)
+		}
+	}
+
+	tc.retryMu.Lock()
+	tc.retries = append(tc.retries, failed...)
+	telemetry.SetTaskPublishRetryBuffer(len(tc.retries))
+	tc.retryMu.Unlock()
+}
+
+// publishDelayedTask publishes a task that becomes visible on queueName after delay.
+// RabbitMQ has no native delayed delivery, so the message is parked in a TTL queue that
+// dead-letters back into the target queue (same approach as worker retries). Tasks share one
+// queue per delay bucket, so a sweep enqueueing many tasks with the same delay declares one
+// queue rather than one per task. The queue is durable and the message persistent, so a broker
+// restart doesn't lose the task.
+func (tc *TaskClient) publishDelayedTask(ctx context.Context, queueName, taskType string, payload interface{}, delay time.Duration) error {
+	if delay <= 0 {
+		return tc.publishTask(ctx, queueName, taskType, payload)
+	}
+
+	pc, err := tc.publishChannel()
+	if err != nil {
+		return err
+	}
+	exchange, routingKey := taskRoute(tc.cfg, queueName)
+	delay = delayBucket(delay)
+	delayedQueueName := fmt.Sprintf("%s_delayed_%ds", queueName, int64(delay/time.Second))
+	_, err = pc.channel.QueueDeclare(
+		delayedQueueName,
+		true,  // durable, so the task outlives a broker restart
+		false, // delete when unused
+		false, // not exclusive
+		false, // no-wait
+		amqp.Table{
+			"x-message-ttl": int64(delay.Milliseconds()),
+			// Every declare restarts the expiry, so the queue outlives the last task parked in it
+			"x-expires":                 int64((delay + time.Minute).Milliseconds()),
+			"x-dead-letter-exchange":    exchange,
+			"x-dead-letter-routing-key": routingKey,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare delayed queue: %w", err)
+	}
+
+	return tc.publishTaskAt(ctx, "", delayedQueueName, taskType, payload, time.Now().Add(delay))
+}
+
+// delayBucket rounds delay up to the delay queue it waits in: to the second under a minute and
+// to the minute beyond, so tasks run at most that late.
+func delayBucket(delay time.Duration) time.Duration {
+	if delay < time.Minute {
+		return ((delay + time.Second - 1) / time.Second) * time.Second
+	}
+	return ((delay + time.Minute - 1) / time.Minute) * time.Minute
+}
+
 // EnqueueChatWorkflow enqueues a chat workflow task
 func (tc *TaskClient) EnqueueChatWorkflow(ctx context.Context, messageID, sessionID string) error {
 	payload := ChatWorkflowPayload{
@@ -245,3 +618,98 @@ func (tc *TaskClient) EnqueueDeliverToProcessor(ctx context.Context, processorID
 
 	return tc.publishTask(ctx, tc.cfg.CeleryEventsQueue, TypeDeliverToProcessor, payload)
 }
+
+// EnqueueCSATTrigger publishes a csat_trigger task, optionally delayed to spread bulk sweeps over time
+func (tc *TaskClient) EnqueueCSATTrigger(ctx context.Context, sessionID, csatType string, delay time.Duration) error {
+	payload := CSATTriggerPayload{
+		SessionID: sessionID,
+		Type:      csatType,
+	}
+
+	return tc.publishDelayedTask(ctx, tc.cfg.CeleryDefaultQueue, TypeCSATTrigger, payload, delay)
+}
+
+// EnqueueCSATReminder schedules a reminder for the survey question at questionIndex
+func (tc *TaskClient) EnqueueCSATReminder(ctx context.Context, csatSessionID string, questionIndex int, delay time.Duration) error {
+	payload := CSATReminderPayload{
+		CSATSessionID: csatSessionID,
+		QuestionIndex: questionIndex,
+	}
+
+	return tc.publishDelayedTask(ctx, tc.cfg.CeleryDefaultQueue, TypeCSATReminder, payload, delay)
+}
+
+// EnqueueCSATExpiry schedules the expiry check for a CSAT survey
+func (tc *TaskClient) EnqueueCSATExpiry(ctx context.Context, csatSessionID string, delay time.Duration) error {
+	payload := CSATExpiryPayload{
+		CSATSessionID: csatSessionID,
+	}
+
+	return tc.publishDelayedTask(ctx, tc.cfg.CeleryDefaultQueue, TypeCSATExpiry, payload, delay)
+}
+
+// EnqueueRepairAction publishes a repair_action task for a recorded repair action
+func (tc *TaskClient) EnqueueRepairAction(ctx context.Context, actionID string) error {
+	payload := RepairActionPayload{
+		ActionID: actionID,
+	}
+
+	return tc.publishTask(ctx, tc.cfg.CeleryDefaultQueue, TypeRepairAction, payload)
+}
+
+// EnqueueScheduledMessage publishes a scheduled_message task that fires once delay has elapsed
+func (tc *TaskClient) EnqueueScheduledMessage(ctx context.Context, scheduledMessageID string, delay time.Duration) error {
+	payload := ScheduledMessagePayload{
+		ScheduledMessageID: scheduledMessageID,
+	}
+
+	return tc.publishDelayedTask(ctx, tc.cfg.CeleryDefaultQueue, TypeScheduledMessage, payload, delay)
+}
+
+// EnqueueSessionRecap publishes a session_recap task that summarizes a closed session
+func (tc *TaskClient) EnqueueSessionRecap(ctx context.Context, sessionID string) error {
+	payload := SessionRecapPayload{
+		SessionID: sessionID,
+	}
+
+	return tc.publishTask(ctx, tc.cfg.CeleryDefaultQueue, TypeSessionRecap, payload)
+}
+
+// EnqueueReportGeneration publishes a report_generation task that builds and delivers a scheduled
+// report over start to end
+func (tc *TaskClient) EnqueueReportGeneration(ctx context.Context, scheduleID string, start, end time.Time) error {
+	payload := ReportGenerationPayload{
+		ReportID:    scheduleID,
+		PeriodStart: start.UTC().Format(time.RFC3339),
+		PeriodEnd:   end.UTC().Format(time.RFC3339),
+	}
+
+	return tc.publishTask(ctx, batchQueue, TypeReportGeneration, payload)
+}
+
+// EnqueueMaintenance publishes a maintenance task that runs the named maintenance job
+func (tc *TaskClient) EnqueueMaintenance(ctx context.Context, job string) error {
+	payload := MaintenancePayload{
+		Job: job,
+	}
+
+	return tc.publishTask(ctx, batchQueue, TypeMaintenance, payload)
+}
+
+// EnqueueSimulation publishes a simulation task that runs the simulation's script
+func (tc *TaskClient) EnqueueSimulation(ctx context.Context, simulationID string) error {
+	payload := SimulationPayload{
+		SimulationID: simulationID,
+	}
+
+	return tc.publishTask(ctx, batchQueue, TypeSimulation, payload)
+}
+
+// EnqueueMessageImport publishes a message_import task that stores the rows of an import file
+func (tc *TaskClient) EnqueueMessageImport(ctx context.Context, importID string) error {
+	payload := MessageImportPayload{
+		ImportID: importID,
+	}
+
+	return tc.publishTask(ctx, batchQueue, TypeMessageImport, payload)
+}
@@ -4,18 +4,18 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	"go.uber.org/zap"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"github.com/fraiday-org/api-service/internal/config"
+	"github.com/fraiday-org/api-service/internal/egress"
 	"github.com/fraiday-org/api-service/internal/models"
+	"github.com/fraiday-org/api-service/internal/secrets"
 	"github.com/fraiday-org/api-service/internal/service"
+	"github.com/fraiday-org/api-service/internal/telemetry"
 )
 
 const (
@@ -24,6 +24,16 @@ const (
 	TypeEventProcessor       = "event_processor"
 	TypeProcessEvent         = "process_event"
 	TypeDeliverToProcessor   = "deliver_to_processor"
+	TypeCSATTrigger          = "csat_trigger"
+	TypeCSATReminder         = "csat_reminder"
+	TypeCSATExpiry           = "csat_expiry"
+	TypeRepairAction         = "repair_action"
+	TypeScheduledMessage     = "scheduled_message"
+	TypeSessionRecap         = "session_recap"
+	TypeReportGeneration     = "report_generation"
+	TypeMaintenance          = "maintenance"
+	TypeSimulation           = "simulation"
+	TypeMessageImport        = "message_import"
 )
 
 // TaskWorker wraps RabbitMQ connection for task processing
@@ -37,6 +47,28 @@ type TaskWorker struct {
 	processorDispatchService  *service.ProcessorDispatchService
 	payloadService            *service.PayloadService
 	chatMessageService        *service.ChatMessageService
+	csatService               *service.CSATService
+	repairService             *service.RepairService
+	scheduledMessageService   *service.ScheduledMessageService
+	postProcessingService     *service.PostProcessingService
+	chatSessionRecapService   *service.ChatSessionRecapService
+	deliveryFailureService    *service.DeliveryFailureService
+	intentService             *service.IntentService
+	moderationService         *service.ModerationService
+	escalationService         *service.EscalationService
+	suggestionService         *service.ChatMessageSuggestionService
+	suggestionBatcher         *service.SuggestionBatcher
+	usageService              *service.UsageService
+	reportService             *service.ReportService
+	maintenanceService        *service.MaintenanceService
+	simulationService         *service.SimulationService
+	messageImportService      *service.MessageImportService
+	chatWorkflowHooks         map[ChatWorkflowHookPoint][]ChatWorkflowHook
+	workflowStateService      *service.ChatWorkflowStateService
+	aiConfigService           *service.ClientAIConfigService
+	answerCacheService        *service.AnswerCacheService
+	promptTemplateService     *service.PromptTemplateService
+	experimentService         *service.ExperimentService
 	taskClient                *TaskClient
 	queues                    []string
 	concurrency               int
@@ -44,6 +76,9 @@ type TaskWorker struct {
 	wg                        sync.WaitGroup
 	ctx                       context.Context
 	cancel                    context.CancelFunc
+	stopping                  chan struct{} // closed by Stop so consumers take no new messages
+	stopOnce                  sync.Once
+	done                      chan struct{} // closed once every consumer has exited
 }
 
 // NewTaskWorker creates a new task worker
@@ -71,13 +106,22 @@ func NewTaskWorker(rabbitMQURL string, logger *zap.Logger, aiURL, aiToken string
 		return nil, fmt.Errorf("failed to set QoS: %w", err)
 	}
 
-	ctx, cancel := context.WithCancel(context.Background())
-
 	// Initialize AI service
 	aiService := service.NewAIService(logger, aiURL, aiToken)
+	// Slack AI workflow URLs are chosen by clients, so they can only reach the hosts webhooks may;
+	// without a valid policy private networks stay blocked
+	if policy, err := egress.NewPolicy(cfg.DispatchAllowedHosts, cfg.DispatchDeniedHosts, cfg.DispatchAllowPrivateNetworks); err == nil {
+		aiService.SetEgressPolicy(policy)
+	}
 	
 	// Initialize ProcessorDispatchService
 	processorDispatchService := service.NewProcessorDispatchService(logger, conn)
+	processorDispatchService.SandboxSinkURL = cfg.SandboxWebhookSinkURL
+	if err := processorDispatchService.Configure(cfg); err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to configure webhook dispatch: %w", err)
+	}
 	
 	// Initialize TaskClient for enqueueing tasks
 	taskClient, err := NewTaskClient(rabbitMQURL, logger, cfg)
@@ -86,7 +130,9 @@ func NewTaskWorker(rabbitMQURL string, logger *zap.Logger, aiURL, aiToken string
 		return nil, fmt.Errorf("failed to create task client: %w", err)
 	}
 
-	return &TaskWorker{
+	ctx, cancel := context.WithCancel(context.Background())
+
+	tw := &TaskWorker{
 		conn:                     conn,
 		channel:                  channel,
 		logger:                   logger,
@@ -97,12 +143,22 @@ func NewTaskWorker(rabbitMQURL string, logger *zap.Logger, aiURL, aiToken string
 		payloadService:           payloadService,
 		chatMessageService:       chatMessageService,
 		taskClient:               taskClient,
-		queues:                   []string{cfg.CeleryDefaultQueue, cfg.CeleryEventsQueue, "default"},
+		queues:                   []string{cfg.CeleryDefaultQueue, cfg.CeleryEventsQueue, batchQueue},
 		concurrency:              10,
 		cfg:                      cfg,
 		ctx:                      ctx,
 		cancel:                   cancel,
-	}, nil
+		stopping:                 make(chan struct{}),
+		done:                     make(chan struct{}),
+	}
+	tw.addBuiltinChatWorkflowHooks()
+	return tw, nil
+}
+
+// SetChatWorkflowStateService enables checkpoints of chat workflows, so retries resume where the
+// failed attempt stopped
+func (tw *TaskWorker) SetChatWorkflowStateService(workflowStateService *service.ChatWorkflowStateService) {
+	tw.workflowStateService = workflowStateService
 }
 
 // SetQueues sets the queues to process
@@ -115,25 +171,153 @@ func (tw *TaskWorker) SetConcurrency(concurrency int) {
 	tw.concurrency = concurrency
 }
 
+// SetCSATService enables csat_trigger, csat_reminder and csat_expiry task handling
+func (tw *TaskWorker) SetCSATService(csatService *service.CSATService) {
+	tw.csatService = csatService
+}
+
+// SetRepairService enables repair_action task handling
+func (tw *TaskWorker) SetRepairService(repairService *service.RepairService) {
+	tw.repairService = repairService
+}
+
+// SetScheduledMessageService enables scheduled_message task handling
+func (tw *TaskWorker) SetScheduledMessageService(scheduledMessageService *service.ScheduledMessageService) {
+	tw.scheduledMessageService = scheduledMessageService
+}
+
+// SetPostProcessingService enables client post-processing hooks on AI responses
+func (tw *TaskWorker) SetPostProcessingService(postProcessingService *service.PostProcessingService) {
+	tw.postProcessingService = postProcessingService
+}
+
+// SetChatSessionRecapService enables session_recap task handling
+func (tw *TaskWorker) SetChatSessionRecapService(chatSessionRecapService *service.ChatSessionRecapService) {
+	tw.chatSessionRecapService = chatSessionRecapService
+}
+
+// SetReportService enables report_generation task handling
+func (tw *TaskWorker) SetReportService(reportService *service.ReportService) {
+	tw.reportService = reportService
+}
+
+// SetMaintenanceService enables maintenance task handling
+func (tw *TaskWorker) SetMaintenanceService(maintenanceService *service.MaintenanceService) {
+	tw.maintenanceService = maintenanceService
+}
+
+// SetSimulationService enables simulation task handling
+func (tw *TaskWorker) SetSimulationService(simulationService *service.SimulationService) {
+	tw.simulationService = simulationService
+}
+
+// SetMessageImportService enables message_import task handling
+func (tw *TaskWorker) SetMessageImportService(messageImportService *service.MessageImportService) {
+	tw.messageImportService = messageImportService
+}
+
+// SetDeliveryFailureService enables failure reporting for AI messages whose delivery was abandoned
+func (tw *TaskWorker) SetDeliveryFailureService(deliveryFailureService *service.DeliveryFailureService) {
+	tw.deliveryFailureService = deliveryFailureService
+}
+
+// SetIntentService enables intent classification and routing in the chat workflow
+func (tw *TaskWorker) SetIntentService(intentService *service.IntentService) {
+	tw.intentService = intentService
+}
+
+// SetModerationService enables moderation of AI responses in the chat workflow
+func (tw *TaskWorker) SetModerationService(moderationService *service.ModerationService) {
+	tw.moderationService = moderationService
+}
+
+// SetEscalationService enables escalation policies in the chat workflow
+func (tw *TaskWorker) SetEscalationService(escalationService *service.EscalationService) {
+	tw.escalationService = escalationService
+}
+
+// SetChatMessageSuggestionService enables recording suggestions for agent review
+func (tw *TaskWorker) SetChatMessageSuggestionService(suggestionService *service.ChatMessageSuggestionService) {
+	tw.suggestionService = suggestionService
+}
+
+// SetSuggestionBatcher sends suggestion requests to the AI service in per-client batches
+func (tw *TaskWorker) SetSuggestionBatcher(suggestionBatcher *service.SuggestionBatcher) {
+	tw.suggestionBatcher = suggestionBatcher
+}
+
+// SetUsageService meters AI calls and webhook deliveries per client and enforces AI call quotas
+func (tw *TaskWorker) SetUsageService(usageService *service.UsageService) {
+	tw.usageService = usageService
+}
+
+// SetClientAIConfigService sends each client's AI model and prompt config with its AI requests
+func (tw *TaskWorker) SetClientAIConfigService(aiConfigService *service.ClientAIConfigService) {
+	tw.aiConfigService = aiConfigService
+}
+
+// SetAnswerCacheService answers repeated questions from cached AI answers for clients that enable it
+func (tw *TaskWorker) SetAnswerCacheService(answerCacheService *service.AnswerCacheService) {
+	tw.answerCacheService = answerCacheService
+}
+
+// SetPromptTemplateService renders each client's published prompt templates into the chat workflow
+func (tw *TaskWorker) SetPromptTemplateService(promptTemplateService *service.PromptTemplateService) {
+	tw.promptTemplateService = promptTemplateService
+}
+
+// SetExperimentService applies the variant of each client's running experiment to its sessions
+func (tw *TaskWorker) SetExperimentService(experimentService *service.ExperimentService) {
+	tw.experimentService = experimentService
+}
+
+// SetSlackService enables delivery to slack processors
+func (tw *TaskWorker) SetSlackService(slackService *service.SlackService) {
+	tw.processorDispatchService.Slack = slackService
+}
+
+// SetWhatsAppService enables delivery to whatsapp processors
+func (tw *TaskWorker) SetWhatsAppService(whatsAppService *service.WhatsAppService) {
+	tw.processorDispatchService.WhatsApp = whatsAppService
+}
+
+// SetTeamsService enables delivery to teams processors
+func (tw *TaskWorker) SetTeamsService(teamsService *service.TeamsService) {
+	tw.processorDispatchService.Teams = teamsService
+}
+
+// SetEmailService enables delivery to email processors
+func (tw *TaskWorker) SetEmailService(emailService *service.EmailService) {
+	tw.processorDispatchService.Email = emailService
+}
+
+// SetSMSService enables delivery to sms processors
+func (tw *TaskWorker) SetSMSService(smsService *service.SMSService) {
+	tw.processorDispatchService.SMS = smsService
+}
+
+// SetSunshineService enables delivery to sunshine processors
+func (tw *TaskWorker) SetSunshineService(sunshineService *service.SunshineService) {
+	tw.processorDispatchService.Sunshine = sunshineService
+}
+
+// SetChannelCapabilityService enables degrading messages for webhook and amqp processors
+func (tw *TaskWorker) SetChannelCapabilityService(channelCapabilityService *service.ChannelCapabilityService) {
+	tw.processorDispatchService.Capabilities = channelCapabilityService
+}
+
+// SetSecretResolver enables secret:// references in webhook and amqp processor configs
+func (tw *TaskWorker) SetSecretResolver(resolver *secrets.Resolver) {
+	tw.processorDispatchService.SecretResolver = resolver
+}
+
 // declareQueues declares all required queues
 func (tw *TaskWorker) declareQueues() error {
-	for _, queue := range tw.queues {
-		_, err := tw.channel.QueueDeclare(
-			queue, // name
-			true,  // durable
-			false, // delete when unused
-			false, // exclusive
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			return fmt.Errorf("failed to declare queue %s: %w", queue, err)
-		}
-	}
-	return nil
+	return declareTaskQueues(tw.channel, tw.cfg, tw.queues)
 }
 
-// Start starts the task worker
+// Start declares the queues and launches the consumers. It does not block;
+// use Done to observe the consumers exiting and Stop to shut them down.
 func (tw *TaskWorker) Start() error {
 	tw.logger.Info("Starting task worker", 
 		zap.Strings("queues", tw.queues),
@@ -152,33 +336,48 @@ func (tw *TaskWorker) Start() error {
 		}
 	}
 
-	// Handle shutdown signals
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
-
-	select {
-	case <-c:
-		tw.logger.Info("Shutdown signal received")
-		tw.Stop()
-	case <-tw.ctx.Done():
-		tw.logger.Info("Context cancelled")
-	}
+	go func() {
+		tw.wg.Wait()
+		close(tw.done)
+	}()
 
-	tw.wg.Wait()
-	tw.logger.Info("Task worker stopped")
 	return nil
 }
 
-// Stop stops the task worker
-func (tw *TaskWorker) Stop() {
-	tw.logger.Info("Stopping task worker")
-	tw.cancel()
-	if tw.channel != nil {
-		tw.channel.Close()
-	}
-	if tw.conn != nil {
-		tw.conn.Close()
-	}
+// Done returns a channel that is closed once every consumer has exited,
+// either after Stop or because the broker connection was lost.
+func (tw *TaskWorker) Done() <-chan struct{} {
+	return tw.done
+}
+
+// Stop stops taking new messages, waits for in-flight tasks to finish until ctx
+// expires, then cancels whatever is still running and closes the connections.
+func (tw *TaskWorker) Stop(ctx context.Context) error {
+	var err error
+	tw.stopOnce.Do(func() {
+		tw.logger.Info("Stopping task worker")
+		close(tw.stopping)
+
+		select {
+		case <-tw.done:
+		case <-ctx.Done():
+			tw.logger.Warn("Timed out waiting for in-flight tasks, cancelling them")
+			err = ctx.Err()
+		}
+
+		tw.cancel()
+		if tw.taskClient != nil {
+			tw.taskClient.Close()
+		}
+		if tw.channel != nil {
+			tw.channel.Close()
+		}
+		if tw.conn != nil {
+			tw.conn.Close()
+		}
+		tw.logger.Info("Task worker stopped")
+	})
+	return err
 }
 
 // consumeQueue consumes messages from a specific queue
@@ -207,8 +406,19 @@ func (tw *TaskWorker) consumeQueue(queueName string, workerID int) {
 		zap.Int("worker_id", workerID))
 
 	for {
+		// Check stopping on its own first so a ready message never wins the race against shutdown;
+		// unacked messages left in the buffer are requeued by the broker when the channel closes
+		select {
+		case <-tw.stopping:
+			tw.logger.Info("Worker stopping", 
+				zap.String("queue", queueName),
+				zap.Int("worker_id", workerID))
+			return
+		default:
+		}
+
 		select {
-		case <-tw.ctx.Done():
+		case <-tw.stopping:
 			tw.logger.Info("Worker stopping", 
 				zap.String("queue", queueName),
 				zap.Int("worker_id", workerID))
@@ -230,10 +440,10 @@ func (tw *TaskWorker) consumeQueue(queueName string, workerID int) {
 func (tw *TaskWorker) processMessage(msg amqp.Delivery, queueName string, workerID int) {
 	start := time.Now()
 
-	// Parse Celery message format
-	var celeryMsg map[string]interface{}
-	if err := json.Unmarshal(msg.Body, &celeryMsg); err != nil {
-		tw.logger.Error("Failed to unmarshal message", 
+	// Parse Celery message format, in either protocol
+	task, err := decodeCeleryMessage(msg)
+	if err != nil {
+		tw.logger.Error("Failed to decode message", 
 			zap.String("queue", queueName),
 			zap.Int("worker_id", workerID),
 			zap.Error(err))
@@ -241,65 +451,85 @@ func (tw *TaskWorker) processMessage(msg amqp.Delivery, queueName string, worker
 		return
 	}
 
-	taskType, ok := celeryMsg["task"].(string)
-	if !ok {
-		tw.logger.Error("Missing or invalid task type", 
-			zap.String("queue", queueName),
-			zap.Int("worker_id", workerID))
-		msg.Nack(false, false)
+	taskType := task.Task
+	taskID := task.ID
+	kwargs := task.Kwargs
+
+	// Honour the expiry and countdown Python publishers may set, as a Celery worker would
+	if task.Expires != nil && start.After(*task.Expires) {
+		tw.logger.Warn("Discarding expired task",
+			zap.String("task_id", taskID),
+			zap.String("task_type", taskType),
+			zap.Time("expires", *task.Expires))
+		msg.Ack(false)
+		return
+	}
+	if task.ETA != nil && task.ETA.After(start) {
+		tw.deferTask(msg, queueName, task, task.ETA.Sub(start))
 		return
 	}
 
-	taskID, _ := celeryMsg["id"].(string)
-	kwargs, _ := celeryMsg["kwargs"].(map[string]interface{})
+	telemetry.ObserveQueueLag(queueName, taskType, msg.Headers, start)
 
-	tw.logger.Info("Processing task", 
+	// The task continues the trace of whoever enqueued it, and logs under their request ID
+	ctx, span := telemetry.StartConsume(tw.ctx, queueName, taskType, msg.Headers)
+	defer span.End()
+	logger := telemetry.Logger(ctx, tw.logger)
+
+	logger.Info("Processing task", 
 		zap.String("task_id", taskID),
 		zap.String("task_type", taskType),
 		zap.String("queue", queueName),
 		zap.Int("worker_id", workerID))
 
 	// Process the task
-	err := tw.handleTask(tw.ctx, taskType, kwargs)
+	err = tw.handleTask(ctx, taskType, kwargs)
+	telemetry.ObserveTask(taskType, time.Since(start), err)
 
 	if err != nil {
-		tw.logger.Error("Task processing failed", 
+		telemetry.RecordError(span, err)
+		logger.Error("Task processing failed", 
 			zap.String("task_id", taskID),
 			zap.String("task_type", taskType),
 			zap.Duration("duration", time.Since(start)),
 			zap.Error(err))
 		
 		// Check retry count and handle exponential backoff for delivery tasks
-		retries, _ := celeryMsg["retries"].(float64)
-		maxRetries := 3
+		retries := task.Retries
+		retryPolicy := models.DefaultDeliveryRetryPolicy()
+		maxRetries := retryPolicy.MaxRetries
 		
 		// For deliver_to_processor tasks, use exponential backoff retry logic
-		if taskType == TypeDeliverToProcessor && retries < float64(maxRetries) {
+		if taskType == TypeDeliverToProcessor && retries < maxRetries {
 			// Calculate countdown for exponential backoff: 60s, 120s, 240s
-			countdown := time.Duration(60 * (1 << int(retries))) * time.Second
+			countdown := retryPolicy.Backoff(retries)
 			
-			tw.logger.Info("Scheduling retry with exponential backoff",
+			logger.Info("Scheduling retry with exponential backoff",
 				zap.String("task_id", taskID),
 				zap.String("task_type", taskType),
-				zap.Int("retry", int(retries)+1),
+				zap.Int("retry", retries+1),
 				zap.Int("max_retries", maxRetries),
 				zap.Duration("countdown", countdown))
 			
 			// For exponential backoff, we need to publish a delayed task
 			// Since RabbitMQ doesn't natively support delayed messages, we'll use TTL + DLX
-			tw.scheduleRetry(msg, taskType, kwargs, int(retries)+1, countdown)
+			tw.scheduleRetry(ctx, task, countdown)
+			tw.recordNextRetry(kwargs, time.Now().Add(countdown), retryPolicy)
 			msg.Ack(false) // Ack the original message
-		} else if retries < float64(maxRetries) {
+		} else if retries < maxRetries {
 			msg.Nack(false, true) // Requeue for immediate retry for other task types
 		} else {
-			tw.logger.Error("All retries exhausted, sending to DLQ",
+			logger.Error("All retries exhausted, sending to DLQ",
 				zap.String("task_id", taskID),
 				zap.String("task_type", taskType),
-				zap.Int("retries", int(retries)))
+				zap.Int("retries", retries))
+			if taskType == TypeDeliverToProcessor {
+				tw.reportExhaustedDelivery(kwargs, err)
+			}
 			msg.Nack(false, false) // Don't requeue, send to DLQ
 		}
 	} else {
-		tw.logger.Info("Task completed successfully", 
+		logger.Info("Task completed successfully", 
 			zap.String("task_id", taskID),
 			zap.String("task_type", taskType),
 			zap.Duration("duration", time.Since(start)))
@@ -307,58 +537,40 @@ func (tw *TaskWorker) processMessage(msg amqp.Delivery, queueName string, worker
 	}
 }
 
-// scheduleRetry schedules a task for retry with exponential backoff
-func (tw *TaskWorker) scheduleRetry(originalMsg amqp.Delivery, taskType string, kwargs map[string]interface{}, retryCount int, countdown time.Duration) {
-	// Create retry message with updated retry count
-	message := map[string]interface{}{
-		"id":      fmt.Sprintf("%d", time.Now().UnixNano()),
-		"task":    taskType,
-		"args":    []interface{}{},
-		"kwargs":  kwargs,
-		"retries": retryCount,
-		"eta":     nil,
-		"expires": nil,
-	}
+// scheduleRetry schedules a task for retry with exponential backoff. The retry keeps the task's
+// ID, as Celery's retries do, and stays in the trace of ctx.
+func (tw *TaskWorker) scheduleRetry(ctx context.Context, task *celeryTask, countdown time.Duration) {
+	retry := *task
+	retry.Retries++
+	retry.ETA = nil
 
-	messageBytes, err := json.Marshal(message)
+	headers := amqp.Table{}
+	telemetry.MarkAvailableAt(headers, time.Now().Add(countdown))
+	publishing, err := encodeCeleryMessage(retry, tw.cfg.CeleryMessageProtocol, headers)
 	if err != nil {
 		tw.logger.Error("Failed to marshal retry message", zap.Error(err))
 		return
 	}
 
-	// Create a temporary queue with TTL for delayed execution
-	delayedQueueName := fmt.Sprintf("events_delayed_%d", time.Now().UnixNano())
-	
-	// Declare temporary queue with TTL and DLX pointing back to events queue
-	_, err = tw.channel.QueueDeclare(
-		delayedQueueName,
-		false, // not durable (temporary)
-		true,  // delete when unused
-		false, // not exclusive
-		false, // no-wait
-		amqp.Table{
-			"x-message-ttl":             int64(countdown.Milliseconds()),
-			"x-dead-letter-exchange":    "",
-			"x-dead-letter-routing-key": tw.cfg.CeleryEventsQueue,
-		},
-	)
+	delayedQueueName, err := tw.declareDelayedQueue(tw.cfg.CeleryEventsQueue, countdown)
 	if err != nil {
 		tw.logger.Error("Failed to declare delayed queue", zap.Error(err))
 		return
 	}
 
+	_, span := telemetry.StartPublish(ctx, delayedQueueName, retry.Task, headers)
+	defer span.End()
+
 	// Publish message to delayed queue
 	err = tw.channel.Publish(
 		"",               // exchange
 		delayedQueueName, // routing key (queue name)
 		false,            // mandatory
 		false,            // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        messageBytes,
-		},
+		publishing,
 	)
 	if err != nil {
+		telemetry.RecordError(span, err)
 		tw.logger.Error("Failed to publish retry message", zap.Error(err))
 		return
 	}
@@ -366,7 +578,93 @@ func (tw *TaskWorker) scheduleRetry(originalMsg amqp.Delivery, taskType string,
 	tw.logger.Info("Scheduled retry message",
 		zap.String("queue", delayedQueueName),
 		zap.Duration("delay", countdown),
-		zap.Int("retry_count", retryCount))
+		zap.Int("retry_count", retry.Retries))
+}
+
+// deferTask moves a task whose ETA is still ahead to a delayed queue that hands it back to
+// queueName once it is due. The message is republished unchanged, so its protocol and headers
+// survive; it is requeued if it can't be deferred.
+func (tw *TaskWorker) deferTask(msg amqp.Delivery, queueName string, task *celeryTask, delay time.Duration) {
+	delayedQueueName, err := tw.declareDelayedQueue(queueName, delay)
+	if err == nil {
+		err = tw.channel.Publish("", delayedQueueName, false, false, amqp.Publishing{
+			Headers:         msg.Headers,
+			ContentType:     msg.ContentType,
+			ContentEncoding: msg.ContentEncoding,
+			DeliveryMode:    msg.DeliveryMode,
+			Priority:        msg.Priority,
+			CorrelationId:   msg.CorrelationId,
+			ReplyTo:         msg.ReplyTo,
+			MessageId:       msg.MessageId,
+			Body:            msg.Body,
+		})
+	}
+	if err != nil {
+		tw.logger.Error("Failed to defer task until its ETA",
+			zap.String("task_id", task.ID),
+			zap.String("task_type", task.Task),
+			zap.Error(err))
+		msg.Nack(false, true)
+		return
+	}
+
+	tw.logger.Info("Deferred task until its ETA",
+		zap.String("task_id", task.ID),
+		zap.String("task_type", task.Task),
+		zap.Time("eta", *task.ETA))
+	msg.Ack(false)
+}
+
+// declareDelayedQueue declares a temporary queue whose messages are routed to queueName, through
+// the task exchange if one is configured, after delay. RabbitMQ has no delayed messages, so
+// delays are a TTL and a dead-letter route.
+func (tw *TaskWorker) declareDelayedQueue(queueName string, delay time.Duration) (string, error) {
+	exchange, routingKey := taskRoute(tw.cfg, queueName)
+	delayedQueueName := fmt.Sprintf("%s_delayed_%d", queueName, time.Now().UnixNano())
+	_, err := tw.channel.QueueDeclare(
+		delayedQueueName,
+		false, // not durable (temporary)
+		true,  // delete when unused
+		false, // not exclusive
+		false, // no-wait
+		amqp.Table{
+			"x-message-ttl":             int64(delay.Milliseconds()),
+			"x-dead-letter-exchange":    exchange,
+			"x-dead-letter-routing-key": routingKey,
+		},
+	)
+	return delayedQueueName, err
+}
+
+// reportExhaustedDelivery marks the delivered message as failed and notifies its client once no retries remain
+func (tw *TaskWorker) reportExhaustedDelivery(kwargs map[string]interface{}, cause error) {
+	if tw.deliveryFailureService == nil {
+		return
+	}
+	processorID, _ := kwargs["processor_id"].(string)
+	deliveryID, _ := kwargs["delivery_id"].(string)
+	eventData, _ := kwargs["event_data"].(map[string]interface{})
+
+	if err := tw.deliveryFailureService.HandleExhausted(tw.ctx, processorID, deliveryID, eventData, cause.Error()); err != nil {
+		tw.logger.Warn("Failed to report exhausted delivery",
+			zap.String("delivery_id", deliveryID),
+			zap.Error(err))
+	}
+}
+
+// recordNextRetry stores the scheduled retry time on the delivery so it shows up in the event status API
+func (tw *TaskWorker) recordNextRetry(kwargs map[string]interface{}, nextRetryAt time.Time, policy models.DeliveryRetryPolicy) {
+	deliveryID, _ := kwargs["delivery_id"].(string)
+	if deliveryID == "" || tw.eventPublisherService == nil {
+		return
+	}
+
+	err := tw.eventPublisherService.EventDeliveryTrackingService.ScheduleRetry(tw.ctx, deliveryID, nextRetryAt, policy)
+	if err != nil {
+		tw.logger.Warn("Failed to record next retry time",
+			zap.String("delivery_id", deliveryID),
+			zap.Error(err))
+	}
 }
 
 // handleTask routes tasks to appropriate handlers
@@ -382,255 +680,64 @@ func (tw *TaskWorker) handleTask(ctx context.Context, taskType string, kwargs ma
 		return tw.HandleProcessEvent(ctx, kwargs)
 	case TypeDeliverToProcessor:
 		return tw.HandleDeliverToProcessor(ctx, kwargs)
+	case TypeCSATTrigger:
+		return tw.HandleCSATTrigger(ctx, kwargs)
+	case TypeCSATReminder:
+		return tw.HandleCSATReminder(ctx, kwargs)
+	case TypeCSATExpiry:
+		return tw.HandleCSATExpiry(ctx, kwargs)
+	case TypeRepairAction:
+		return tw.HandleRepairAction(ctx, kwargs)
+	case TypeScheduledMessage:
+		return tw.HandleScheduledMessage(ctx, kwargs)
+	case TypeSessionRecap:
+		return tw.HandleSessionRecap(ctx, kwargs)
+	case TypeReportGeneration:
+		return tw.HandleReportGeneration(ctx, kwargs)
+	case TypeMaintenance:
+		return tw.HandleMaintenance(ctx, kwargs)
+	case TypeSimulation:
+		return tw.HandleSimulation(ctx, kwargs)
+	case TypeMessageImport:
+		return tw.HandleMessageImport(ctx, kwargs)
 	default:
 		return fmt.Errorf("unknown task type: %s", taskType)
 	}
 }
 
-// HandleChatWorkflow handles chat workflow tasks
-func (tw *TaskWorker) HandleChatWorkflow(ctx context.Context, kwargs map[string]interface{}) error {
-	// Parse payload
-	payloadBytes, err := json.Marshal(kwargs)
-	if err != nil {
-		return fmt.Errorf("failed to marshal kwargs: %w", err)
+// attachReplyChain adds the reply chain of a threaded reply to the AI context so the
+// prompt keeps the replied-to conversation together instead of only the flat session history
+func (tw *TaskWorker) attachReplyChain(ctx context.Context, sessionContext map[string]interface{}, message *service.ChatMessage) {
+	if message.ParentMessageID == nil {
+		return
 	}
 
-	var payload ChatWorkflowPayload
-	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-		return fmt.Errorf("failed to unmarshal chat workflow payload: %w", err)
+	chain, err := tw.databaseService.GetReplyChain(ctx, message)
+	if err != nil {
+		tw.logger.Warn("Failed to build reply chain, continuing without it",
+			zap.String("message_id", message.ID.Hex()),
+			zap.Error(err))
+		return
 	}
 
-	tw.logger.Info("Processing chat workflow task",
-		zap.String("message_id", payload.MessageID),
-		zap.String("session_id", payload.SessionID))
+	sessionContext["parent_message_id"] = message.ParentMessageID.Hex()
+	sessionContext["reply_chain"] = chain
+}
 
-	// Implement chat workflow logic equivalent to Python Celery task
-	// This mirrors the generate_ai_response_task from Python backend
-	
-	// 1. Publish processing event
-	_, err = tw.eventPublisherService.PublishChatMessageEvent(
-		ctx,
-		models.EventTypeChatWorkflowProcessing,
-		payload.MessageID,
-		&payload.SessionID,
-		map[string]interface{}{
-			"status":     "ai_processing_started",
-			"session_id": payload.SessionID,
-		},
-	)
+// attachContactHistory adds recaps of the sender's earlier sessions to the AI context, for clients
+// that opted in, so a returning user doesn't have to repeat themselves.
+func (tw *TaskWorker) attachContactHistory(ctx context.Context, sessionContext map[string]interface{}, message *service.ChatMessage) {
+	history, err := tw.databaseService.GetContactHistory(ctx, message)
 	if err != nil {
-		tw.logger.Error("Failed to publish processing event", zap.Error(err))
-		// Don't return error, continue with processing
-	}
-	
-	// 2. Process AI request
-	tw.logger.Info("Processing AI request",
-		zap.String("message_id", payload.MessageID),
-		zap.String("session_id", payload.SessionID))
-	
-	// Get message content and context from database
-	message, err := tw.databaseService.GetChatMessage(ctx, payload.MessageID)
-	if err != nil {
-		tw.logger.Error("Failed to get message from database", zap.Error(err))
-		
-		// Publish error event with detailed error information (matching Python)
-		_, publishErr := tw.eventPublisherService.PublishChatMessageEvent(
-			ctx,
-			models.EventTypeChatWorkflowError,
-			payload.MessageID,
-			&payload.SessionID,
-			map[string]interface{}{
-				"error":      fmt.Sprintf("%+v", err), // Include stack trace
-				"session_id": payload.SessionID,
-				"stage":      "message_retrieval",
-			},
-		)
-		if publishErr != nil {
-			tw.logger.Error("Failed to publish error event", zap.Error(publishErr))
-		}
-		
-		return fmt.Errorf("failed to get message: %w", err)
-	}
-	
-	sessionContext, err := tw.databaseService.GetSessionContext(ctx, payload.SessionID)
-	if err != nil {
-		tw.logger.Warn("Failed to get session context, using minimal context", zap.Error(err))
-		sessionContext = map[string]interface{}{"session_id": payload.SessionID}
-	}
-	
-	var aiResponse *service.AIResponse
-	
-	if payload.SuggestionMode {
-		aiResponse, err = tw.aiService.GenerateSuggestions(ctx, payload.MessageID, payload.SessionID, message.Text, sessionContext)
-	} else {
-		aiResponse, err = tw.aiService.GenerateChatResponse(ctx, payload.MessageID, payload.SessionID, message.Text, sessionContext)
-	}
-	
-	if err != nil {
-		tw.logger.Error("Failed to process AI request", zap.Error(err))
-		
-		// Publish error event with detailed error information (matching Python)
-		_, publishErr := tw.eventPublisherService.PublishChatMessageEvent(
-			ctx,
-			models.EventTypeChatWorkflowError,
-			payload.MessageID,
-			&payload.SessionID,
-			map[string]interface{}{
-				"error":      fmt.Sprintf("%+v", err), // Include stack trace
-				"session_id": payload.SessionID,
-				"stage":      "ai_processing",
-			},
-		)
-		if publishErr != nil {
-			tw.logger.Error("Failed to publish error event", zap.Error(publishErr))
-		}
-		
-		return fmt.Errorf("AI processing failed: %w", err)
-	}
-	
-	tw.logger.Info("AI response received",
-		zap.String("message_id", aiResponse.MessageID),
-		zap.String("response_length", fmt.Sprintf("%d", len(aiResponse.Response))))
-	
-	// Save AI response to database (matching Python implementation)
-	// Handle different response formats (Slack/Sunshine vs regular AI service)
-	var responseText string
-	var confidenceScore float64
-	var attachments []models.Attachment
-	var closeSession bool
-	var answerData interface{}
-
-	if aiResponse.Result != nil {
-		// Slack/Sunshine format - data is in Result field
-		responseText = aiResponse.Result.Text
-		confidenceScore = aiResponse.Result.ConfidenceScore
-		attachments = convertAIAttachments(aiResponse.Result.Attachments)
-		if aiResponse.Result.Metadata != nil {
-			if closeSessionVal, ok := aiResponse.Result.Metadata["close_session"].(bool); ok {
-				closeSession = closeSessionVal
-			}
-		}
-		answerData = aiResponse.Result.Data
-	} else {
-		// Regular AI service format - data is in Data field
-		responseText = aiResponse.Data.Answer.AnswerText
-		confidenceScore = aiResponse.Data.ConfidenceScore
-		attachments = convertAIAttachments(aiResponse.Data.Answer.Attachments)
-		closeSession = aiResponse.Metadata.CloseSession
-		answerData = aiResponse.Data.Answer.AnswerData
-	}
-
-	responseMessage := &models.ChatMessage{
-		Text:        responseText,                      // Use extracted text
-		Sender:      "fraiday-bot",                    // Add sender field (BOT_SENDER_NAME equivalent)
-		SenderName:  "fraiday-bot",                    // Add sender name field
-		SenderType:  "assistant",
-		SessionID:   message.SessionID,
-		Category:    models.MessageCategoryMessage,
-		Confidence:  confidenceScore,                   // Use extracted confidence score
-		Attachments: attachments,                       // Use converted attachments
-		Config: map[string]interface{}{
-			"ai_response": true,
-			"original_message_id": payload.MessageID,
-		},
-		Data: map[string]interface{}{
-			"close_session": closeSession,
-			"meta_data":     answerData, // Add metadata like Python
-		},
+		tw.logger.Warn("Failed to get contact history, continuing without it",
+			zap.String("message_id", message.ID.Hex()),
+			zap.Error(err))
+		return
 	}
-	
-	// Use ChatMessageService to create the message (this will publish chat_message_created event)
-	if err := tw.chatMessageService.CreateChatMessage(ctx, responseMessage); err != nil {
-		tw.logger.Error("Failed to save AI response to database", zap.Error(err))
-		// Don't return error here as the AI processing was successful
+	if len(history) > 0 {
+		sessionContext["contact_id"] = message.Contact.Hex()
+		sessionContext["contact_history"] = history
 	}
-	
-	// 3. Generate response based on message configuration
-	// Check if suggestion mode is enabled
-	if payload.SuggestionMode {
-		// Create suggestion entity
-		tw.logger.Info("Creating chat suggestion",
-			zap.String("message_id", payload.MessageID))
-		
-		// Publish suggestion created event with full payload (matching Python)
-		suggestionPayload, err := tw.payloadService.CreateChatSuggestionPayload(ctx, responseMessage.ID.Hex())
-		if err != nil {
-			tw.logger.Error("Failed to create suggestion payload", zap.Error(err))
-			suggestionPayload = map[string]interface{}{
-				"id":         responseMessage.ID.Hex(),
-				"message_id": payload.MessageID,
-				"session_id": payload.SessionID,
-				"content":    aiResponse.Response,
-			}
-		}
-
-		_, err = tw.eventPublisherService.PublishChatSuggestionEvent(
-			ctx,
-			models.EventTypeChatSuggestionCreated,
-			responseMessage.ID.Hex(),
-			&payload.MessageID,
-			suggestionPayload,
-		)
-		if err != nil {
-			tw.logger.Error("Failed to publish suggestion created event", zap.Error(err))
-		}
-	} else {
-		// Create chat message response
-		tw.logger.Info("Creating chat message response",
-			zap.String("message_id", payload.MessageID))
-		
-		// Publish workflow completed event with full message payloads (matching Python)
-		userMessagePayload, err := tw.payloadService.CreateChatMessagePayload(ctx, payload.MessageID)
-		if err != nil {
-			tw.logger.Error("Failed to create user message payload", zap.Error(err))
-			userMessagePayload = map[string]interface{}{"id": payload.MessageID}
-		}
-
-		aiMessagePayload, err := tw.payloadService.CreateChatMessagePayload(ctx, responseMessage.ID.Hex())
-		if err != nil {
-			tw.logger.Error("Failed to create AI message payload", zap.Error(err))
-			aiMessagePayload = map[string]interface{}{"id": responseMessage.ID.Hex()}
-		}
-
-		_, err = tw.eventPublisherService.PublishChatMessageEvent(
-			ctx,
-			models.EventTypeChatWorkflowCompleted,
-			responseMessage.ID.Hex(),
-			&payload.SessionID,
-			map[string]interface{}{
-				"user_message": userMessagePayload,
-				"ai_message":   aiMessagePayload,
-				"session_id":   payload.SessionID,
-			},
-		)
-		if err != nil {
-			tw.logger.Error("Failed to publish workflow completed event", zap.Error(err))
-		}
-		
-		// Check for handover scenario (confidence_score = 0)
-		if confidenceScore == 0 {
-			// Reuse the payloads we already created for consistency
-			_, err = tw.eventPublisherService.PublishChatMessageEvent(
-				ctx,
-				models.EventTypeChatWorkflowHandover,
-				responseMessage.ID.Hex(),
-				&payload.SessionID,
-				map[string]interface{}{
-					"user_message": userMessagePayload,
-					"ai_message":   aiMessagePayload,
-					"session_id":   payload.SessionID,
-				},
-			)
-			if err != nil {
-				tw.logger.Error("Failed to publish handover event", zap.Error(err))
-			}
-		}
-	}
-	
-	tw.logger.Info("Completed chat workflow task",
-		zap.String("message_id", payload.MessageID))
-
-	return nil
 }
 
 // HandleSuggestionWorkflow handles suggestion workflow tasks
@@ -655,22 +762,53 @@ func (tw *TaskWorker) HandleSuggestionWorkflow(ctx context.Context, kwargs map[s
 	if err != nil {
 		return fmt.Errorf("failed to get message: %w", err)
 	}
+	if optedOut, err := tw.databaseService.ContactOptedOut(ctx, message, models.ConsentAIProcessing); err != nil {
+		return fmt.Errorf("failed to check contact consent: %w", err)
+	} else if optedOut {
+		tw.publishWorkflowOptedOut(ctx, payload.MessageID, payload.SessionID, models.ConsentAIProcessing)
+		return nil
+	}
 
 	// 2. Get session context
 	sessionContext, err := tw.databaseService.GetSessionContext(ctx, payload.SessionID)
 	if err != nil {
 		return fmt.Errorf("failed to get session context: %w", err)
 	}
+	tw.attachReplyChain(ctx, sessionContext, message)
+	tw.attachContactHistory(ctx, sessionContext, message)
 
-	// 3. Generate suggestions using AI service
-	aiResponse, err := tw.aiService.GenerateSuggestions(ctx, payload.MessageID, payload.SessionID, message.Text, sessionContext)
-	if err != nil {
-		return fmt.Errorf("failed to generate suggestions: %w", err)
+	// 3. Generate suggestions using AI service (stubbed for sandbox sessions)
+	var aiResponse *service.AIResponse
+	if tw.databaseService.IsSandboxSession(ctx, payload.SessionID) {
+		aiResponse = tw.aiService.SandboxResponse(payload.MessageID, payload.SessionID, message.Text)
+	} else {
+		usageClient, err := tw.checkAIQuota(ctx, message.SessionID)
+		if err != nil {
+			tw.publishWorkflowQuotaExceeded(ctx, payload.MessageID, payload.SessionID, err)
+			return nil
+		}
+		aiResponse, err = tw.generateSuggestions(ctx, message, payload, sessionContext)
+		if err != nil {
+			return fmt.Errorf("failed to generate suggestions: %w", err)
+		}
+		tw.recordAIUsage(ctx, usageClient, aiResponse)
+	}
+
+	suggestionText := aiResponse.Response
+	if tw.postProcessingService != nil {
+		draft, err := tw.postProcessingService.Process(ctx, message.SessionID, payload.MessageID, message.Text, true, &service.PostProcessingDraft{
+			Text: suggestionText,
+		})
+		if err != nil {
+			tw.publishWorkflowVetoed(ctx, payload.MessageID, payload.SessionID, err)
+			return nil
+		}
+		suggestionText = draft.Text
 	}
 
 	// 4. Save AI response as a new message
 	suggestionMessage := &service.ChatMessage{
-		Text:       aiResponse.Response,
+		Text:       suggestionText,
 		SenderType: "assistant",
 		SessionID:  message.SessionID,
 		Category:   models.MessageCategoryMessage,
@@ -697,6 +835,63 @@ func (tw *TaskWorker) HandleSuggestionWorkflow(ctx context.Context, kwargs map[s
 }
 
 
+// generateSuggestions asks the AI service for suggestions, batched with other suggestion tasks of
+// the same client when a batcher is set.
+func (tw *TaskWorker) generateSuggestions(ctx context.Context, message *service.ChatMessage, payload SuggestionWorkflowPayload, sessionContext map[string]interface{}) (*service.AIResponse, error) {
+	modelConfig := tw.clientAIConfig(ctx, message.SessionID)
+	if tw.suggestionBatcher != nil {
+		session, err := tw.databaseService.GetChatSessionByID(ctx, message.SessionID.Hex())
+		if err == nil && session.Client != nil {
+			return tw.suggestionBatcher.GenerateSuggestions(ctx, session.Client.Hex(), payload.MessageID, payload.SessionID, message.Text, sessionContext, modelConfig)
+		}
+		tw.logger.Warn("Could not resolve client of suggestion task, sending it unbatched",
+			zap.String("message_id", payload.MessageID), zap.Error(err))
+	}
+	return tw.aiService.GenerateSuggestions(ctx, payload.MessageID, payload.SessionID, message.Text, sessionContext, modelConfig)
+}
+
+// clientAIConfig returns the model config of the session's client, or nil to use the AI
+// service's defaults.
+func (tw *TaskWorker) clientAIConfig(ctx context.Context, sessionID primitive.ObjectID) *models.ClientAIConfig {
+	if tw.aiConfigService == nil {
+		return nil
+	}
+	return tw.aiConfigService.ForSession(ctx, sessionID)
+}
+
+// checkAIQuota returns the client of a session for metering its AI calls, or an error wrapping
+// service.ErrQuotaExceeded when the client has no AI calls left this month. Sessions whose client
+// can't be resolved are neither metered nor limited.
+func (tw *TaskWorker) checkAIQuota(ctx context.Context, sessionID primitive.ObjectID) (primitive.ObjectID, error) {
+	if tw.usageService == nil {
+		return primitive.NilObjectID, nil
+	}
+	clientID, err := tw.usageService.SessionClient(ctx, sessionID)
+	if err != nil {
+		tw.logger.Warn("Could not resolve client of session, not metering AI call",
+			zap.String("session_id", sessionID.Hex()), zap.Error(err))
+		return primitive.NilObjectID, nil
+	}
+	return clientID, tw.usageService.Allow(ctx, clientID, models.UsageMetricAICalls)
+}
+
+// recordUsage meters counts for a client; the zero ObjectID records nothing.
+func (tw *TaskWorker) recordUsage(ctx context.Context, clientID primitive.ObjectID, counts map[string]int64) {
+	if tw.usageService != nil && !clientID.IsZero() {
+		tw.usageService.Record(ctx, clientID, counts)
+	}
+}
+
+// recordAIUsage meters an AI call for a client, with the tokens it used when the AI service
+// reported them.
+func (tw *TaskWorker) recordAIUsage(ctx context.Context, clientID primitive.ObjectID, response *service.AIResponse) {
+	counts := map[string]int64{models.UsageMetricAICalls: 1}
+	if response.Usage != nil {
+		counts[models.UsageMetricAIPromptTokens] = response.Usage.PromptTokens
+		counts[models.UsageMetricAICompletionTokens] = response.Usage.CompletionTokens
+	}
+	tw.recordUsage(ctx, clientID, counts)
+}
 
 // HandleEventProcessor handles event processor tasks
 // This mirrors the process_event task from Python backend
@@ -797,16 +992,15 @@ func (tw *TaskWorker) HandleProcessEvent(ctx context.Context, kwargs map[string]
 		return nil // This is not an error - just skip processing
 	}
 
-	// Prepare event data for dispatching (matching Python logic)
-	dispatchData := map[string]interface{}{
-		"event_id":    payload.EventID,
-		"event_type":  payload.EventType,
-		"entity_type": payload.EntityType,
-		"entity_id":   payload.EntityID,
-		"parent_id":   payload.ParentID,
-		"data":        payload.Data,
-		"timestamp":   event.CreatedAt.Format(time.RFC3339),
-		"client_id":   clientID,
+	// Prepare event data for dispatching: the event's envelope, with the data as published rather
+	// than as decoded from MongoDB
+	envelope := event.Envelope()
+	envelope.Data = payload.Data
+	dispatchData := envelope.Map()
+	dispatchData["timestamp"] = event.CreatedAt.Format(time.RFC3339)
+	dispatchData["client_id"] = clientID
+	if tw.isSandboxEvent(ctx, clientObjID, payload.EntityType, payload.EntityID) {
+		dispatchData["sandbox"] = true
 	}
 
 	// For each processor, create a delivery record and dispatch in a separate task
@@ -877,14 +1071,15 @@ func (tw *TaskWorker) HandleDeliverToProcessor(ctx context.Context, kwargs map[s
 		return fmt.Errorf("failed to unmarshal deliver to processor payload: %w", err)
 	}
 
-	tw.logger.Info("Processing deliver_to_processor task",
+	logger := telemetry.Logger(ctx, tw.logger)
+	logger.Info("Processing deliver_to_processor task",
 		zap.String("processor_id", payload.ProcessorID),
 		zap.String("delivery_id", payload.DeliveryID))
 
 	// Get the processor
 	processor, err := tw.eventPublisherService.EventProcessorConfigService.GetProcessorByID(ctx, payload.ProcessorID)
 	if err != nil {
-		tw.logger.Error("Processor not found", 
+		logger.Error("Processor not found", 
 			zap.String("processor_id", payload.ProcessorID), 
 			zap.Error(err))
 
@@ -898,14 +1093,20 @@ func (tw *TaskWorker) HandleDeliverToProcessor(ctx context.Context, kwargs map[s
 			map[string]interface{}{"error": fmt.Sprintf("Processor %s not found", payload.ProcessorID)},
 		)
 		if recordErr != nil {
-			tw.logger.Error("Failed to record attempt", zap.Error(recordErr))
+			logger.Error("Failed to record attempt", zap.Error(recordErr))
 		}
 
 		return fmt.Errorf("processor not found: %w", err)
 	}
 
-	// Try to dispatch
-	result := tw.processorDispatchService.DispatchToProcessor(ctx, processor, payload.EventData)
+	// Try to dispatch; sandbox events never reach the processor's real endpoint
+	var result service.ProcessorDispatchResult
+	sandbox, _ := payload.EventData["sandbox"].(bool)
+	if sandbox {
+		result = tw.processorDispatchService.DispatchToSandboxSink(ctx, processor, payload.EventData)
+	} else {
+		result = tw.processorDispatchService.DispatchToProcessor(ctx, processor, payload.EventData)
+	}
 
 	// Record the attempt
 	attempt, err := tw.eventPublisherService.EventDeliveryTrackingService.RecordAttempt(
@@ -922,12 +1123,15 @@ func (tw *TaskWorker) HandleDeliverToProcessor(ctx context.Context, kwargs map[s
 		map[string]interface{}{"error": result.ErrorMessage},
 	)
 	if err != nil {
-		tw.logger.Error("Failed to record delivery attempt", zap.Error(err))
+		logger.Error("Failed to record delivery attempt", zap.Error(err))
 		// Continue processing even if we can't record the attempt
 	}
 
 	if result.Success {
-		tw.logger.Info("Successfully delivered to processor",
+		if !sandbox {
+			tw.recordUsage(ctx, processor.ClientID, map[string]int64{models.UsageMetricWebhookDeliveries: 1})
+		}
+		logger.Info("Successfully delivered to processor",
 			zap.String("processor_id", payload.ProcessorID),
 			zap.String("delivery_id", payload.DeliveryID),
 			zap.Int("attempt", int(attempt.AttemptNumber)))
@@ -935,7 +1139,7 @@ func (tw *TaskWorker) HandleDeliverToProcessor(ctx context.Context, kwargs map[s
 	}
 
 	// If delivery failed, return error to trigger retry mechanism
-	tw.logger.Error("Failed to deliver to processor",
+	logger.Error("Failed to deliver to processor",
 		zap.String("processor_id", payload.ProcessorID),
 		zap.String("delivery_id", payload.DeliveryID),
 		zap.String("error", result.ErrorMessage),
@@ -944,6 +1148,289 @@ func (tw *TaskWorker) HandleDeliverToProcessor(ctx context.Context, kwargs map[s
 	return fmt.Errorf("delivery failed: %s", result.ErrorMessage)
 }
 
+// HandleCSATTrigger handles csat_trigger tasks enqueued by bulk CSAT sweeps.
+// Trigger failures are logged rather than returned: they are almost always permanent
+// (survey already active, configuration disabled) and requeueing would only repeat them.
+func (tw *TaskWorker) HandleCSATTrigger(ctx context.Context, kwargs map[string]interface{}) error {
+	payloadBytes, err := json.Marshal(kwargs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kwargs: %w", err)
+	}
+
+	var payload CSATTriggerPayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal csat trigger payload: %w", err)
+	}
+
+	if tw.csatService == nil {
+		tw.logger.Error("CSAT service not configured, dropping csat_trigger task",
+			zap.String("session_id", payload.SessionID))
+		return nil
+	}
+
+	session, err := tw.csatService.TriggerCSATSurveyBySessionID(ctx, payload.SessionID, payload.Type)
+	if err != nil {
+		tw.logger.Warn("Failed to trigger CSAT survey",
+			zap.String("session_id", payload.SessionID),
+			zap.String("type", payload.Type),
+			zap.Error(err))
+		return nil
+	}
+
+	tw.logger.Info("Triggered CSAT survey",
+		zap.String("session_id", payload.SessionID),
+		zap.String("csat_session_id", session.ID.Hex()))
+
+	return nil
+}
+
+// HandleCSATReminder re-sends the question a CSAT survey is waiting on. Reminders for a question
+// that has since been answered, or for a finished survey, are dropped by the service.
+func (tw *TaskWorker) HandleCSATReminder(ctx context.Context, kwargs map[string]interface{}) error {
+	payloadBytes, err := json.Marshal(kwargs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kwargs: %w", err)
+	}
+
+	var payload CSATReminderPayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal csat reminder payload: %w", err)
+	}
+
+	if tw.csatService == nil {
+		tw.logger.Error("CSAT service not configured, dropping csat_reminder task",
+			zap.String("csat_session_id", payload.CSATSessionID))
+		return nil
+	}
+
+	return tw.csatService.SendReminder(ctx, payload.CSATSessionID, payload.QuestionIndex)
+}
+
+// HandleCSATExpiry closes a CSAT survey that is still unfinished once its expiry window has passed.
+func (tw *TaskWorker) HandleCSATExpiry(ctx context.Context, kwargs map[string]interface{}) error {
+	payloadBytes, err := json.Marshal(kwargs)
+	if err != nil {
+		return fmt.Errorf("failed to marshal kwargs: %w", err)
+	}
+
+	var payload CSATExpiryPayload
+	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
+		return fmt.Errorf("failed to unmarshal csat expiry payload: %w", err)
+	}
+
+	if tw.csatService == nil {
+		tw.logger.Error("CSAT service not configured, dropping csat_expiry task",
+			zap.String("csat_session_id", payload.CSATSessionID))
+		return nil
+	}
+
+	return tw.csatService.ExpireSurvey(ctx, payload.CSATSessionID)
+}
+
+// HandleRepairAction runs an operator repair action recorded by the admin API.
+// The outcome is persisted on the action itself, so failures are not requeued.
+func (tw *TaskWorker) HandleRepairAction(ctx context.Context, kwargs map[string]interface{}) error {
+	actionID, _ := kwargs["action_id"].(string)
+	if actionID == "" {
+		return fmt.Errorf("missing action_id in repair_action task")
+	}
+
+	if tw.repairService == nil {
+		tw.logger.Error("Repair service not configured, dropping repair_action task",
+			zap.String("action_id", actionID))
+		return nil
+	}
+
+	if err := tw.repairService.RunAction(ctx, actionID); err != nil {
+		tw.logger.Error("Repair action failed",
+			zap.String("action_id", actionID),
+			zap.Error(err))
+		return nil
+	}
+
+	tw.logger.Info("Repair action completed", zap.String("action_id", actionID))
+	return nil
+}
+
+// HandleScheduledMessage sends a scheduled message once it is due.
+// A failed send is recorded on the scheduled message, so it is not requeued.
+func (tw *TaskWorker) HandleScheduledMessage(ctx context.Context, kwargs map[string]interface{}) error {
+	scheduledMessageID, _ := kwargs["scheduled_message_id"].(string)
+	if scheduledMessageID == "" {
+		return fmt.Errorf("missing scheduled_message_id in scheduled_message task")
+	}
+
+	if tw.scheduledMessageService == nil {
+		tw.logger.Error("Scheduled message service not configured, dropping scheduled_message task",
+			zap.String("scheduled_message_id", scheduledMessageID))
+		return nil
+	}
+
+	if err := tw.scheduledMessageService.Deliver(ctx, scheduledMessageID); err != nil {
+		tw.logger.Error("Failed to send scheduled message",
+			zap.String("scheduled_message_id", scheduledMessageID),
+			zap.Error(err))
+		return nil
+	}
+
+	tw.logger.Info("Processed scheduled message", zap.String("scheduled_message_id", scheduledMessageID))
+	return nil
+}
+
+// HandleSessionRecap summarizes a closed session's transcript.
+// Failures are logged rather than requeued; the recap endpoint can regenerate it.
+func (tw *TaskWorker) HandleSessionRecap(ctx context.Context, kwargs map[string]interface{}) error {
+	sessionID, _ := kwargs["session_id"].(string)
+	if sessionID == "" {
+		return fmt.Errorf("missing session_id in session_recap task")
+	}
+
+	if tw.chatSessionRecapService == nil {
+		tw.logger.Error("Chat session recap service not configured, dropping session_recap task",
+			zap.String("session_id", sessionID))
+		return nil
+	}
+
+	recap, err := tw.chatSessionRecapService.SummarizeSession(ctx, sessionID)
+	if err != nil {
+		tw.logger.Error("Failed to generate session recap",
+			zap.String("session_id", sessionID),
+			zap.Error(err))
+		return nil
+	}
+	if recap == nil {
+		tw.logger.Info("Skipped recap for session without messages", zap.String("session_id", sessionID))
+		return nil
+	}
+
+	tw.logger.Info("Generated session recap",
+		zap.String("session_id", sessionID),
+		zap.String("recap_id", recap.ID.Hex()))
+	return nil
+}
+
+// HandleReportGeneration builds a scheduled report over its period and delivers it.
+// The outcome is recorded on the report schedule, so a failed delivery is not requeued.
+func (tw *TaskWorker) HandleReportGeneration(ctx context.Context, kwargs map[string]interface{}) error {
+	reportID, _ := kwargs["report_id"].(string)
+	if reportID == "" {
+		return fmt.Errorf("missing report_id in report_generation task")
+	}
+	periodStart, _ := kwargs["period_start"].(string)
+	periodEnd, _ := kwargs["period_end"].(string)
+	start, err := time.Parse(time.RFC3339, periodStart)
+	if err != nil {
+		return fmt.Errorf("invalid period_start in report_generation task: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, periodEnd)
+	if err != nil {
+		return fmt.Errorf("invalid period_end in report_generation task: %w", err)
+	}
+
+	if tw.reportService == nil {
+		tw.logger.Error("Report service not configured, dropping report_generation task",
+			zap.String("report_id", reportID))
+		return nil
+	}
+
+	if err := tw.reportService.Deliver(ctx, reportID, start, end); err != nil {
+		tw.logger.Error("Failed to deliver report",
+			zap.String("report_id", reportID),
+			zap.Error(err))
+		return nil
+	}
+
+	tw.logger.Info("Delivered report",
+		zap.String("report_id", reportID),
+		zap.Time("period_start", start),
+		zap.Time("period_end", end))
+	return nil
+}
+
+// HandleMaintenance runs a maintenance job enqueued by the scheduler
+func (tw *TaskWorker) HandleMaintenance(ctx context.Context, kwargs map[string]interface{}) error {
+	job, _ := kwargs["job"].(string)
+	if job == "" {
+		return fmt.Errorf("missing job in maintenance task")
+	}
+
+	if tw.maintenanceService == nil {
+		tw.logger.Error("Maintenance service not configured, dropping maintenance task",
+			zap.String("job", job))
+		return nil
+	}
+
+	changed, err := tw.maintenanceService.Run(ctx, service.MaintenanceJob(job))
+	if err != nil {
+		tw.logger.Error("Maintenance job failed",
+			zap.String("job", job),
+			zap.Int("changed", changed),
+			zap.Error(err))
+		return nil
+	}
+
+	tw.logger.Info("Ran maintenance job",
+		zap.String("job", job),
+		zap.Int("changed", changed))
+	return nil
+}
+
+// HandleSimulation replays a simulation's script through the chat workflow, step by step. The
+// outcome is recorded on the simulation, so a failed run is not requeued.
+func (tw *TaskWorker) HandleSimulation(ctx context.Context, kwargs map[string]interface{}) error {
+	simulationID, _ := kwargs["simulation_id"].(string)
+	if simulationID == "" {
+		return fmt.Errorf("missing simulation_id in simulation task")
+	}
+
+	if tw.simulationService == nil {
+		tw.logger.Error("Simulation service not configured, dropping simulation task",
+			zap.String("simulation_id", simulationID))
+		return nil
+	}
+
+	workflow := func(ctx context.Context, messageID, sessionID string) error {
+		return tw.HandleChatWorkflow(ctx, map[string]interface{}{
+			"message_id": messageID,
+			"session_id": sessionID,
+		})
+	}
+	if err := tw.simulationService.Run(ctx, simulationID, workflow); err != nil {
+		tw.logger.Error("Simulation failed",
+			zap.String("simulation_id", simulationID),
+			zap.Error(err))
+		return nil
+	}
+
+	tw.logger.Info("Ran simulation", zap.String("simulation_id", simulationID))
+	return nil
+}
+
+// HandleMessageImport stores the rows of an import file. Progress and the outcome are recorded on
+// the import, so a failed import is not requeued.
+func (tw *TaskWorker) HandleMessageImport(ctx context.Context, kwargs map[string]interface{}) error {
+	importID, _ := kwargs["import_id"].(string)
+	if importID == "" {
+		return fmt.Errorf("missing import_id in message_import task")
+	}
+
+	if tw.messageImportService == nil {
+		tw.logger.Error("Message import service not configured, dropping message_import task",
+			zap.String("import_id", importID))
+		return nil
+	}
+
+	if err := tw.messageImportService.Run(ctx, importID); err != nil {
+		tw.logger.Error("Message import failed",
+			zap.String("import_id", importID),
+			zap.Error(err))
+		return nil
+	}
+
+	tw.logger.Info("Ran message import", zap.String("import_id", importID))
+	return nil
+}
+
 // getClientIDForEntity determines client_id for different entity types
 // This mirrors the _get_client_id_for_entity function from Python backend
 func (tw *TaskWorker) getClientIDForEntity(ctx context.Context, entityType, entityID string) (string, error) {
@@ -1047,71 +1534,211 @@ func (tw *TaskWorker) getClientIDForEntity(ctx context.Context, entityType, enti
 		// Recursively resolve client ID through CSAT session
 		return tw.getClientIDForEntity(ctx, string(models.EntityTypeCSATSession), csatResponse.CSATSession.Hex())
 
+	case string(models.EntityTypeClient):
+		// Client events are keyed by the client's ObjectID
+		return entityID, nil
+
 	default:
 		return "", fmt.Errorf("unsupported entity type: %s", entityType)
 	}
 }
 
-// convertAIAttachments converts AI service attachments to ChatMessage attachments
-func convertAIAttachments(aiAttachments []service.AIAttachment) []models.Attachment {
-	if len(aiAttachments) == 0 {
-		return nil
+// publishWorkflowRouted records that intent routing handled a message without an AI answer
+func (tw *TaskWorker) publishWorkflowRouted(ctx context.Context, messageID, sessionID string, message *service.ChatMessage, action models.IntentAction) {
+	tw.logger.Info("Message routed by intent, skipping AI response",
+		zap.String("message_id", messageID),
+		zap.String("intent", message.Intent),
+		zap.String("action", string(action)))
+
+	_, err := tw.eventPublisherService.PublishChatMessageEvent(
+		ctx,
+		models.EventTypeChatWorkflowRouted,
+		messageID,
+		&sessionID,
+		map[string]interface{}{
+			"session_id": sessionID,
+			"intent":     message.Intent,
+			"confidence": message.IntentConfidence,
+			"action":     action,
+		},
+	)
+	if err != nil {
+		tw.logger.Error("Failed to publish routed event", zap.Error(err))
 	}
+}
 
-	attachments := make([]models.Attachment, len(aiAttachments))
-	for i, aiAttachment := range aiAttachments {
-		attachment := models.Attachment{
-			Type:     aiAttachment.Type,
-			FileName: aiAttachment.FileName,
-			FileURL:  aiAttachment.FileURL,
-			FileType: aiAttachment.FileType,
-		}
+// publishWorkflowVetoed records that a post-processing hook dropped the AI response to a message
+func (tw *TaskWorker) publishWorkflowVetoed(ctx context.Context, messageID, sessionID string, reason error) {
+	tw.logger.Info("AI response vetoed by post-processing hook",
+		zap.String("message_id", messageID),
+		zap.Error(reason))
 
-		// Handle carousel data if present
-		if aiAttachment.Type == "carousel" && len(aiAttachment.Carousel.Items) > 0 {
-			// Convert carousel data to map[string]interface{} to match expected format
-			carouselData := make(map[string]interface{})
-			
-			// Convert carousel items to interface{} slice
-			items := make([]interface{}, len(aiAttachment.Carousel.Items))
-			for j, item := range aiAttachment.Carousel.Items {
-				itemData := map[string]interface{}{
-					"title":       item.Title,
-					"description": item.Description,
-				}
-				
-				// Add optional fields if present
-				if item.MediaURL != "" {
-					itemData["media_url"] = item.MediaURL
-				}
-				if item.MediaType != "" {
-					itemData["media_type"] = item.MediaType
-				}
-				if item.DefaultActionURL != "" {
-					itemData["default_action_url"] = item.DefaultActionURL
-				}
-				if len(item.Buttons) > 0 {
-					itemData["buttons"] = item.Buttons
-				}
-				
-				items[j] = itemData
-			}
-			
-			carouselData["items"] = items
-			attachment.Carousel = carouselData
-		}
+	_, err := tw.eventPublisherService.PublishChatMessageEvent(
+		ctx,
+		models.EventTypeChatWorkflowVetoed,
+		messageID,
+		&sessionID,
+		map[string]interface{}{
+			"session_id": sessionID,
+			"reason":     reason.Error(),
+		},
+	)
+	if err != nil {
+		tw.logger.Error("Failed to publish vetoed event", zap.Error(err))
+	}
+}
 
-		// Handle top-level buttons if present (for non-carousel attachments)
-		if len(aiAttachment.Buttons) > 0 && aiAttachment.Type != "carousel" {
-			if attachment.Carousel == nil {
-				attachment.Carousel = make(map[string]interface{})
-			}
-			attachment.Carousel["buttons"] = aiAttachment.Buttons
+// publishWorkflowQuotaExceeded records that a message got no AI response because its client
+// used up its AI calls for the month
+func (tw *TaskWorker) publishWorkflowQuotaExceeded(ctx context.Context, messageID, sessionID string, reason error) {
+	tw.logger.Info("AI call quota exceeded, skipping AI response",
+		zap.String("message_id", messageID),
+		zap.Error(reason))
+
+	_, err := tw.eventPublisherService.PublishChatMessageEvent(
+		ctx,
+		models.EventTypeChatWorkflowQuotaExceeded,
+		messageID,
+		&sessionID,
+		map[string]interface{}{
+			"session_id": sessionID,
+			"reason":     reason.Error(),
+		},
+	)
+	if err != nil {
+		tw.logger.Error("Failed to publish quota exceeded event", zap.Error(err))
+	}
+}
+
+// publishWorkflowOptedOut records that the AI skipped a message whose contact opted out of purpose
+func (tw *TaskWorker) publishWorkflowOptedOut(ctx context.Context, messageID, sessionID, purpose string) {
+	tw.logger.Info("Contact opted out, skipping AI response",
+		zap.String("message_id", messageID),
+		zap.String("purpose", purpose))
+
+	_, err := tw.eventPublisherService.PublishChatMessageEvent(
+		ctx,
+		models.EventTypeChatWorkflowOptedOut,
+		messageID,
+		&sessionID,
+		map[string]interface{}{
+			"session_id": sessionID,
+			"purpose":    purpose,
+		},
+	)
+	if err != nil {
+		tw.logger.Error("Failed to publish opted out event", zap.Error(err))
+	}
+}
+
+// publishWorkflowEscalated records that an escalation policy handed a session to a human
+func (tw *TaskWorker) publishWorkflowEscalated(ctx context.Context, messageID, sessionID string, escalation *service.EscalationDecision) {
+	tw.logger.Info("Session escalated",
+		zap.String("message_id", messageID),
+		zap.String("trigger", escalation.Trigger),
+		zap.Bool("handover", escalation.Handover))
+
+	_, err := tw.eventPublisherService.PublishChatMessageEvent(
+		ctx,
+		models.EventTypeChatWorkflowEscalated,
+		messageID,
+		&sessionID,
+		map[string]interface{}{
+			"session_id":   sessionID,
+			"trigger":      escalation.Trigger,
+			"keyword":      escalation.Keyword,
+			"confidence":   escalation.Confidence,
+			"streak":       escalation.Streak,
+			"target_queue": escalation.TargetQueue,
+			"handover":     escalation.Handover,
+		},
+	)
+	if err != nil {
+		tw.logger.Error("Failed to publish escalated event", zap.Error(err))
+	}
+}
+
+// publishWorkflowModerated records that moderation suppressed the AI response to a message
+func (tw *TaskWorker) publishWorkflowModerated(ctx context.Context, messageID, sessionID string, verdict *service.ModerationVerdict, handoverErr error) {
+	tw.logger.Warn("AI response blocked by moderation",
+		zap.String("message_id", messageID),
+		zap.String("rule", verdict.Rule),
+		zap.String("match", verdict.Match),
+		zap.String("reason", verdict.Reason))
+	if handoverErr != nil {
+		tw.logger.Error("Failed to hand over moderated session", zap.Error(handoverErr))
+	}
+
+	_, err := tw.eventPublisherService.PublishChatMessageEvent(
+		ctx,
+		models.EventTypeChatWorkflowModerated,
+		messageID,
+		&sessionID,
+		map[string]interface{}{
+			"session_id": sessionID,
+			"rule":       verdict.Rule,
+			"match":      verdict.Match,
+			"reason":     verdict.Reason,
+			"handover":   handoverErr == nil,
+		},
+	)
+	if err != nil {
+		tw.logger.Error("Failed to publish moderated event", zap.Error(err))
+	}
+}
+
+// isSandboxEvent reports whether an event belongs to a sandbox client or to a session created in sandbox mode
+func (tw *TaskWorker) isSandboxEvent(ctx context.Context, clientID primitive.ObjectID, entityType, entityID string) bool {
+	if tw.databaseService.IsSandboxClient(ctx, clientID) {
+		return true
+	}
+
+	sessionID := entityID
+	switch entityType {
+	case string(models.EntityTypeChatMessage):
+		message, err := tw.databaseService.GetChatMessage(ctx, entityID)
+		if err != nil {
+			return false
 		}
+		sessionID = message.SessionID.Hex()
+	case string(models.EntityTypeChatSession):
+	default:
+		return false
+	}
+
+	session, err := tw.databaseService.GetChatSessionByID(ctx, sessionID)
+	return err == nil && session.Test
+}
 
-		attachments[i] = attachment
+// validAIAttachments returns the attachments of an AI response that can be shown, dropping
+// those that don't fit their type.
+func (tw *TaskWorker) validAIAttachments(attachments []models.Attachment) []models.Attachment {
+	var valid []models.Attachment
+	for _, attachment := range attachments {
+		if err := attachment.Validate(); err != nil {
+			tw.logger.Warn("Dropping invalid AI attachment", zap.String("type", attachment.Type), zap.Error(err))
+			continue
+		}
+		valid = append(valid, attachment)
 	}
+	return valid
+}
 
-	return attachments
+// validAICitations returns the sources of an AI response that can be linked to, dropping the
+// rest and any beyond models.MaxCitations.
+func (tw *TaskWorker) validAICitations(sources []models.Citation) []models.Citation {
+	var valid []models.Citation
+	for _, citation := range sources {
+		if err := citation.Validate(); err != nil {
+			tw.logger.Warn("Dropping invalid AI citation", zap.String("url", citation.URL), zap.Error(err))
+			continue
+		}
+		if len(valid) == models.MaxCitations {
+			tw.logger.Warn("Dropping AI citations over the limit", zap.Int("sources", len(sources)))
+			break
+		}
+		valid = append(valid, citation)
+	}
+	return valid
 }
 
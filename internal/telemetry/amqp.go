@@ -0,0 +1,66 @@
+package telemetry
+
+import (
+	"context"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"go.opentelemetry.io/otel"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// amqpHeaders lets the propagator read and write trace context in AMQP message headers.
+type amqpHeaders amqp.Table
+
+func (h amqpHeaders) Get(key string) string {
+	value, _ := h[key].(string)
+	return value
+}
+
+func (h amqpHeaders) Set(key, value string) {
+	h[key] = value
+}
+
+func (h amqpHeaders) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// StartPublish starts a producer span for message being published to destination and writes its
+// trace context and request ID into headers, so the consumer of the message continues the trace.
+func StartPublish(ctx context.Context, destination, message string, headers amqp.Table) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, "publish "+message,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationTypePublish,
+			semconv.MessagingDestinationName(destination),
+		),
+	)
+	otel.GetTextMapPropagator().Inject(ctx, amqpHeaders(headers))
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		headers[requestIDTaskHeader] = requestID
+	}
+	return ctx, span
+}
+
+// StartConsume starts a consumer span for task received from queue, as a child of the span that
+// published it when headers carry trace context. The returned context carries the request ID of
+// the publisher, if any.
+func StartConsume(ctx context.Context, queue, task string, headers amqp.Table) (context.Context, trace.Span) {
+	if headers != nil {
+		ctx = otel.GetTextMapPropagator().Extract(ctx, amqpHeaders(headers))
+		ctx = WithRequestID(ctx, amqpHeaders(headers).Get(requestIDTaskHeader))
+	}
+	return Tracer().Start(ctx, "process "+task,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			semconv.MessagingSystemRabbitmq,
+			semconv.MessagingOperationTypeDeliver,
+			semconv.MessagingDestinationName(queue),
+		),
+	)
+}
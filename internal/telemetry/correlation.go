@@ -0,0 +1,59 @@
+package telemetry
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// RequestIDHeader carries the request ID on HTTP requests and responses, including webhook
+	// deliveries made on behalf of a request
+	RequestIDHeader = "X-Request-ID"
+	// requestIDTaskHeader carries the request ID on tasks published to RabbitMQ
+	requestIDTaskHeader = "request_id"
+	// maxRequestIDLength bounds request IDs accepted from callers
+	maxRequestIDLength = 128
+)
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying requestID, which follows the work it starts into tasks,
+// events and outbound calls.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or "".
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// ValidRequestID reports whether a request ID sent by a caller is safe to reuse: short, and made
+// only of letters, digits and "-_.:", so it can't forge log lines or headers.
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range requestID {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Logger returns logger with a request_id field when ctx carries a request ID.
+func Logger(ctx context.Context, logger *zap.Logger) *zap.Logger {
+	if requestID := RequestIDFromContext(ctx); requestID != "" {
+		return logger.With(zap.String("request_id", requestID))
+	}
+	return logger
+}
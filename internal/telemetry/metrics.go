@@ -0,0 +1,205 @@
+package telemetry
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// AvailableAtHeader is the AMQP header holding when a task became available to workers, in Unix
+// milliseconds; for delayed tasks that is when the delay ends. Workers use it to measure queue lag.
+const AvailableAtHeader = "available_at"
+
+// Domain metrics, served with the HTTP metrics of MetricsService by /metrics on the API and on
+// the worker's metrics port.
+var (
+	taskProcessingTime = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "task_processing_time_seconds",
+			Help:    "Task processing time in seconds",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+		},
+		[]string{"task_type", "status"},
+	)
+	taskQueueLag = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "task_queue_lag_seconds",
+			Help:    "Time a task waited in its queue before a worker picked it up",
+			Buckets: []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 60, 300},
+		},
+		[]string{"queue", "task_type"},
+	)
+	aiResponseTime = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ai_response_time_seconds",
+			Help:    "AI service response time in seconds",
+			Buckets: []float64{0.1, 0.5, 1.0, 2.0, 5.0, 10.0, 30.0, 60.0},
+		},
+		[]string{"operation", "status"},
+	)
+	eventDeliveryAttempts = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "event_delivery_attempts_total",
+			Help: "Event delivery attempts to processors",
+		},
+		[]string{"processor_id", "status"},
+	)
+	webhookConnections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "webhook_connections_total",
+			Help: "Webhook requests by whether they reused a pooled connection and by protocol",
+		},
+		[]string{"reused", "protocol"},
+	)
+	amqpBatchSize = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "amqp_batch_size",
+			Help:    "Events per batch published to batched AMQP processors",
+			Buckets: []float64{1, 2, 5, 10, 25, 50, 100, 250, 500, 1000},
+		},
+		[]string{"status"},
+	)
+	taskQueueSize = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "task_queue_size",
+			Help: "Number of tasks in queue",
+		},
+		[]string{"queue"},
+	)
+	workflowBackpressure = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "workflow_backpressure_total",
+			Help: "Workflow tasks delayed or shed because their queue was saturated",
+		},
+		[]string{"task_type", "mode"},
+	)
+	taskPublishFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "task_publish_failures_total",
+			Help: "Task publishes the broker didn't confirm, by reason",
+		},
+		[]string{"task_type", "reason"},
+	)
+	taskPublishRetries = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "task_publish_retries_total",
+			Help: "Buffered task publishes tried again, by outcome",
+		},
+		[]string{"outcome"},
+	)
+	taskPublishRetryBuffer = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "task_publish_retry_buffer",
+			Help: "Unconfirmed tasks waiting in memory to be published again",
+		},
+	)
+	messageDedupHits = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "message_dedup_hits_total",
+			Help: "Inbound messages dropped as redeliveries of a stored message",
+		},
+		[]string{"channel_type"},
+	)
+	mongoCommandDuration = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "mongo_command_duration_seconds",
+			Help:    "MongoDB command duration in seconds",
+			Buckets: []float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 5},
+		},
+		[]string{"command", "collection", "status"},
+	)
+)
+
+func statusLabel(ok bool) string {
+	if ok {
+		return "success"
+	}
+	return "error"
+}
+
+// ObserveTask records how long a task of taskType took and whether it failed.
+func ObserveTask(taskType string, duration time.Duration, err error) {
+	taskProcessingTime.WithLabelValues(taskType, statusLabel(err == nil)).Observe(duration.Seconds())
+}
+
+// MarkAvailableAt records in headers when the task becomes available to workers.
+func MarkAvailableAt(headers amqp.Table, at time.Time) {
+	headers[AvailableAtHeader] = at.UnixMilli()
+}
+
+// ObserveQueueLag records how long a task waited in queue, when its headers say when it became
+// available. Tasks from publishers that don't set the header are skipped.
+func ObserveQueueLag(queue, taskType string, headers amqp.Table, now time.Time) {
+	var availableAt int64
+	switch v := headers[AvailableAtHeader].(type) {
+	case int64:
+		availableAt = v
+	case string:
+		availableAt, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if availableAt <= 0 {
+		return
+	}
+	lag := now.Sub(time.UnixMilli(availableAt))
+	if lag < 0 {
+		lag = 0
+	}
+	taskQueueLag.WithLabelValues(queue, taskType).Observe(lag.Seconds())
+}
+
+// ObserveAIRequest records the latency and outcome of a call to the AI service.
+func ObserveAIRequest(operation string, duration time.Duration, err error) {
+	aiResponseTime.WithLabelValues(operation, statusLabel(err == nil)).Observe(duration.Seconds())
+}
+
+// ObserveDeliveryAttempt counts an attempt to deliver an event to a processor.
+func ObserveDeliveryAttempt(processorID string, success bool) {
+	eventDeliveryAttempts.WithLabelValues(processorID, statusLabel(success)).Inc()
+}
+
+// ObserveWebhookConnection counts a webhook request answered over a new or reused connection.
+func ObserveWebhookConnection(reused bool, protocol string) {
+	webhookConnections.WithLabelValues(strconv.FormatBool(reused), protocol).Inc()
+}
+
+// ObserveAMQPBatch records the size of a batch published to an AMQP processor and whether the
+// broker confirmed all of it.
+func ObserveAMQPBatch(size int, success bool) {
+	amqpBatchSize.WithLabelValues(statusLabel(success)).Observe(float64(size))
+}
+
+// SetTaskQueueSize records how many tasks are ready in queue.
+func SetTaskQueueSize(queue string, size int) {
+	taskQueueSize.WithLabelValues(queue).Set(float64(size))
+}
+
+// ObserveWorkflowBackpressure counts a workflow task of taskType that backpressure degraded: mode
+// is delayed or suggestions_shed.
+func ObserveWorkflowBackpressure(taskType, mode string) {
+	workflowBackpressure.WithLabelValues(taskType, mode).Inc()
+}
+
+// ObserveTaskPublishFailure counts a publish of taskType the broker didn't confirm: reason is
+// nack, timeout, unroutable or error.
+func ObserveTaskPublishFailure(taskType, reason string) {
+	taskPublishFailures.WithLabelValues(taskType, reason).Inc()
+}
+
+// ObserveTaskPublishRetry counts a buffered task published again: outcome is confirmed, failed or
+// dropped, once it runs out of retries or doesn't fit in the buffer.
+func ObserveTaskPublishRetry(outcome string) {
+	taskPublishRetries.WithLabelValues(outcome).Inc()
+}
+
+// SetTaskPublishRetryBuffer records how many unconfirmed tasks are waiting to be published again.
+func SetTaskPublishRetryBuffer(size int) {
+	taskPublishRetryBuffer.Set(float64(size))
+}
+
+// ObserveMessageDedup counts an inbound message on channelType that was already stored.
+func ObserveMessageDedup(channelType string) {
+	messageDedupHits.WithLabelValues(channelType).Inc()
+}
@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// mongoCommand is a MongoDB command in flight.
+type mongoCommand struct {
+	name       string
+	collection string
+	start      time.Time
+	span       trace.Span // nil outside a trace
+}
+
+// MongoMonitor returns a command monitor that times every MongoDB command and records a client
+// span for those run within a trace. Commands outside one, such as those of background sweeps,
+// get no span, so they don't each start a trace of their own.
+func MongoMonitor() *event.CommandMonitor {
+	var commands sync.Map // request ID -> *mongoCommand
+
+	end := func(requestID int64, failure string) {
+		value, ok := commands.LoadAndDelete(requestID)
+		if !ok {
+			return
+		}
+		cmd := value.(*mongoCommand)
+		mongoCommandDuration.WithLabelValues(cmd.name, cmd.collection, statusLabel(failure == "")).Observe(time.Since(cmd.start).Seconds())
+		if cmd.span == nil {
+			return
+		}
+		if failure != "" {
+			cmd.span.RecordError(errors.New(failure))
+			cmd.span.SetStatus(codes.Error, failure)
+		}
+		cmd.span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			cmd := &mongoCommand{name: evt.CommandName, start: time.Now()}
+			// The first element of most commands names their collection, as in {find: "users"}
+			if element, err := evt.Command.IndexErr(0); err == nil {
+				cmd.collection, _ = element.Value().StringValueOK()
+			}
+			if trace.SpanContextFromContext(ctx).IsValid() {
+				name := evt.CommandName
+				if cmd.collection != "" {
+					name += " " + cmd.collection
+				}
+				_, cmd.span = Tracer().Start(ctx, name,
+					trace.WithSpanKind(trace.SpanKindClient),
+					trace.WithAttributes(
+						semconv.DBSystemMongoDB,
+						semconv.DBNamespace(evt.DatabaseName),
+						semconv.DBCollectionName(cmd.collection),
+						semconv.DBOperationName(evt.CommandName),
+					),
+				)
+			}
+			commands.Store(evt.RequestID, cmd)
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			end(evt.RequestID, "")
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			end(evt.RequestID, evt.Failure)
+		},
+	}
+}
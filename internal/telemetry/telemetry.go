@@ -0,0 +1,63 @@
+// Package telemetry sets up OpenTelemetry tracing and carries trace context across HTTP
+// requests, RabbitMQ tasks, MongoDB commands and outbound HTTP calls. It also holds the
+// Prometheus metrics recorded outside the HTTP layer, such as task, AI and MongoDB timings.
+package telemetry
+
+import (
+	"context"
+
+	"github.com/fraiday-org/api-service/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/fraiday-org/api-service"
+
+// Tracer returns the tracer for the service's own spans.
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// RecordError marks span as failed with err.
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Setup installs the W3C trace context propagator and, when cfg has an OTLP endpoint, a tracer
+// provider exporting spans to it over OTLP/HTTP as serviceName. The exporter, sampler and
+// resource also honour the other standard OTEL_* variables, so OTEL_SERVICE_NAME overrides
+// serviceName. Without an endpoint spans are not recorded, but incoming trace context is still
+// passed on. The returned function flushes spans that are still buffered.
+func Setup(ctx context.Context, cfg *config.Config, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.OTelExporterEndpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(serviceName),
+			semconv.ServiceVersion(cfg.Version),
+			semconv.DeploymentEnvironment(cfg.AppEnv),
+		),
+		resource.WithFromEnv(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
@@ -0,0 +1,140 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a compiled path into a decoded JSON document. It supports the subset of JSONPath
+// needed to pick fields out of webhook payloads:
+//
+//	$.message.text        child members
+//	$['user name']        bracketed member names
+//	$.items[0] $.items[-1] array indexes, negative from the end
+//	$.items[*].url $.a.*  wildcards over array elements or object members
+type JSONPath struct {
+	raw   string
+	steps []pathStep
+}
+
+type pathStep struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// CompileJSONPath parses path, which must start at the root "$".
+func CompileJSONPath(path string) (*JSONPath, error) {
+	rest, ok := strings.CutPrefix(strings.TrimSpace(path), "$")
+	if !ok {
+		return nil, fmt.Errorf("jsonpath %q must start with $", path)
+	}
+	p := &JSONPath{raw: path}
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if name == "" {
+				return nil, fmt.Errorf("jsonpath %q has an empty member name", path)
+			}
+			p.steps = append(p.steps, pathStep{key: name, wildcard: name == "*"})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("jsonpath %q has an unclosed bracket", path)
+			}
+			inner := strings.TrimSpace(rest[1:end])
+			step, err := bracketStep(inner)
+			if err != nil {
+				return nil, fmt.Errorf("jsonpath %q: %w", path, err)
+			}
+			p.steps = append(p.steps, step)
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("jsonpath %q: unexpected %q", path, rest[0])
+		}
+	}
+	return p, nil
+}
+
+func bracketStep(inner string) (pathStep, error) {
+	if inner == "*" {
+		return pathStep{wildcard: true}, nil
+	}
+	if len(inner) >= 2 && (inner[0] == '\'' || inner[0] == '"') && inner[len(inner)-1] == inner[0] {
+		return pathStep{key: inner[1 : len(inner)-1]}, nil
+	}
+	index, err := strconv.Atoi(inner)
+	if err != nil {
+		return pathStep{}, fmt.Errorf("invalid subscript [%s]", inner)
+	}
+	return pathStep{index: index, isIndex: true}, nil
+}
+
+// String returns the path as it was written.
+func (p *JSONPath) String() string {
+	return p.raw
+}
+
+// Find returns every value the path matches in doc, in document order for arrays.
+func (p *JSONPath) Find(doc interface{}) []interface{} {
+	current := []interface{}{doc}
+	for _, step := range p.steps {
+		var next []interface{}
+		for _, node := range current {
+			next = append(next, step.apply(node)...)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// First returns the first value the path matches in doc.
+func (p *JSONPath) First(doc interface{}) (interface{}, bool) {
+	matches := p.Find(doc)
+	if len(matches) == 0 {
+		return nil, false
+	}
+	return matches[0], true
+}
+
+func (s pathStep) apply(node interface{}) []interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if s.wildcard {
+			values := make([]interface{}, 0, len(n))
+			for _, v := range n {
+				values = append(values, v)
+			}
+			return values
+		}
+		if v, ok := n[s.key]; ok && !s.isIndex {
+			return []interface{}{v}
+		}
+	case []interface{}:
+		if s.wildcard {
+			return n
+		}
+		if s.isIndex {
+			i := s.index
+			if i < 0 {
+				i += len(n)
+			}
+			if i >= 0 && i < len(n) {
+				return []interface{}{n[i]}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Package utils provides encryption for secrets stored with client configuration.
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// sealedPrefix marks values sealed by a SecretBox and versions their format
+const sealedPrefix = "enc:v1:"
+
+// ErrNoEncryptionKey is returned when secrets must be stored but ENCRYPTION_KEY isn't set.
+var ErrNoEncryptionKey = errors.New("ENCRYPTION_KEY is not configured")
+
+// SecretBox encrypts secrets with AES-256-GCM under a key derived from the deployment's
+// ENCRYPTION_KEY, so they aren't readable from a database dump.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates a SecretBox for key.
+func NewSecretBox(key string) (*SecretBox, error) {
+	if key == "" {
+		return nil, ErrNoEncryptionKey
+	}
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts plaintext, returning a value Open can decrypt.
+func (b *SecretBox) Seal(plaintext string) (string, error) {
+	nonce := make([]byte, b.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := b.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return sealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value sealed by Seal under the same key.
+func (b *SecretBox) Open(sealed string) (string, error) {
+	if !strings.HasPrefix(sealed, sealedPrefix) {
+		return "", errors.New("value is not sealed")
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil || len(raw) < b.aead.NonceSize() {
+		return "", errors.New("sealed value is malformed")
+	}
+	nonce, ciphertext := raw[:b.aead.NonceSize()], raw[b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errors.New("sealed value can't be decrypted with this key")
+	}
+	return string(plaintext), nil
+}